@@ -0,0 +1,117 @@
+// Package main imports opportunities from a CSV or JSON Lines file, the command line counterpart of
+// POST /api/v1/opportunities/import.
+package main
+
+import (
+	"backend/internal/bulkimport"
+	"backend/internal/db"
+	"backend/internal/db/adapters/mysql"
+	"backend/internal/db/repositories"
+	"backend/internal/geo"
+	"backend/internal/service/media"
+	"backend/internal/service/opportunity"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"github.com/google/uuid"
+	"os"
+)
+
+// main validates every row and prints the report as JSON, exiting with 1 if any row failed. Nothing is
+// saved without -commit
+func main() {
+	file := flag.String("file", "", "CSV or JSON Lines file to import")
+	format := flag.String("format", "", "csv or jsonl, detected from the file extension if not set")
+	author := flag.String("author", "", "UUID of the user posting the opportunities")
+	organisation := flag.String("organisation", "", "UUID of the organisation to post for")
+	commit := flag.Bool("commit", false, "save the opportunities rather than only validating them")
+	flag.Parse()
+
+	authorUUID, err := uuid.Parse(*author)
+	if *file == "" || err != nil {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	parsedFormat, err := bulkimport.ParseFormat(*format, *file, "")
+	if err != nil {
+		fail(err)
+	}
+
+	reader, err := os.Open(*file)
+	if err != nil {
+		fail(err)
+	}
+	defer reader.Close()
+
+	rows, rowErrors, err := bulkimport.Parse(parsedFormat, reader)
+	if err != nil {
+		fail(err)
+	}
+
+	report, err := importService().ImportOpportunities(context.Background(), rows, rowErrors, authorUUID, *organisation, !*commit)
+	if err != nil {
+		fail(err)
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(report); err != nil {
+		fail(err)
+	}
+	if report.Failed > 0 {
+		os.Exit(1)
+	}
+}
+
+// importService connects to the same database as the API server
+func importService() *opportunity.OpportunityService {
+	//TODO: maybe load from config
+	container := mysql.Container{}
+	config := mysql.Configurations{
+		Authentication: &db.AuthenticationConfigurations{
+			Host:     "localhost",
+			Port:     3306,
+			Username: "root",
+			Password: "password",
+		},
+		DatabaseName: "mydatabase",
+	}
+
+	if err := container.Connect(config); err != nil {
+		fail(err)
+	}
+	repo := &mysql.Repository{Database: &container}
+
+	// Media is only checked, never served, so URLs don't need to outlive the command
+	media.Configure(nil, media.NewEphemeralSigner(""))
+
+	repository, err := repositories.NewOpportunityRepository(repo)
+	if err != nil {
+		fail(err)
+	}
+	organisationRepository, err := repositories.NewOrganisationRepository(repo)
+	if err != nil {
+		fail(err)
+	}
+	applicationRepository, err := repositories.NewApplicationRepository(repo)
+	if err != nil {
+		fail(err)
+	}
+	searchRepository, err := repositories.NewSearchRepository(repo)
+	if err != nil {
+		fail(err)
+	}
+	mediaRepository, err := repositories.NewMediaRepository(repo)
+	if err != nil {
+		fail(err)
+	}
+
+	return opportunity.NewOpportunityService(repository, organisationRepository, applicationRepository, searchRepository, geo.DefaultGazetteer(), media.NewMediaService(mediaRepository))
+}
+
+func fail(err error) {
+	fmt.Fprintln(os.Stderr, err)
+	os.Exit(1)
+}
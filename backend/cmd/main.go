@@ -2,14 +2,32 @@
 package main
 
 import (
+	"backend/internal/blob"
 	"backend/internal/db"
 	"backend/internal/db/adapters/mysql"
 	"backend/internal/db/adapters/neo4j"
+	"backend/internal/db/adapters/redis"
+	"backend/internal/db/repositories"
+	"backend/internal/events"
 	"backend/internal/handlers"
+	"backend/internal/outbox"
+	"backend/internal/queue"
+	"backend/internal/security"
+	"backend/internal/security/oidc"
+	"backend/internal/service/media"
+	"backend/internal/utils/concurrency"
+	"context"
+	"errors"
 	"fmt"
 	"github.com/go-chi/chi"
 	log "github.com/sirupsen/logrus"
 	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
 )
 
 // main is the main entry point of the backend API.
@@ -29,6 +47,7 @@ func main() {
 			Password: "password",
 		},
 		DatabaseName: "mydatabase",
+		Timeouts:     databaseTimeouts(),
 	}
 
 	err := container.Connect(config)
@@ -60,14 +79,264 @@ func main() {
 		Database: &neoContainer,
 	}
 
+	// JWT signing keys
+	loadSigningKeys()
+
+	// University SSO
+	registerSSOProviders()
+
+	// Uploaded media
+	configureMedia()
+
+	// Redis, optional
+	redisRepo := connectRedis()
+
+	// Scheduled jobs, before the routes add theirs
+	if redisRepo != nil {
+		concurrency.DefaultScheduler.SetLocker(redisRepo)
+	}
+
+	// Domain events, before the routes subscribe to them
+	events.Configure()
+
+	// Background jobs, before the routes add their handlers
+	jobQueue := configureJobQueue(&repo, redisRepo)
+
 	// Setup router
 	router := chi.NewRouter()
 	log.SetReportCaller(true)
 
 	handlers.Handler(router, &repo, &neoRepo)
 
-	err = http.ListenAndServe(":8080", router)
+	jobQueue.Start()
+	err = concurrency.AddJob(jobQueue.PurgeJob(7 * 24 * time.Hour))
+	if err != nil {
+		log.Error("Unable to schedule purging completed jobs: ", err)
+	}
+
+	// Neo4j projection of MySQL
+	startOutboxRelay(&repo, &neoRepo)
+
+	server := &http.Server{Addr: ":8080", Handler: router}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	go func() {
+		err := server.ListenAndServe()
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			panic(err)
+		}
+	}()
+
+	<-ctx.Done()
+	log.Info("Shutting down")
+	shutdown(server, jobQueue)
+
+	if redisRepo != nil {
+		redisRepo.Database.Close()
+	}
+	neoContainer.Close()
+	container.Close()
+}
+
+// shutdownTimeout is how long requests and jobs in progress get to finish when shutting down
+const shutdownTimeout = 30 * time.Second
+
+// shutdown stops taking requests, then lets the requests, background jobs, scheduled jobs and event subscribers in
+// progress finish
+func shutdown(server *http.Server, jobQueue *queue.Queue) {
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	if err := server.Shutdown(ctx); err != nil {
+		log.Error("Unable to finish requests in progress: ", err)
+	}
+	if err := jobQueue.Shutdown(ctx); err != nil {
+		log.Error("Unable to finish background jobs in progress: ", err)
+	}
+	if err := concurrency.DefaultScheduler.Stop(ctx); err != nil {
+		log.Error("Unable to finish scheduled jobs in progress: ", err)
+	}
+	if err := events.Default.Shutdown(ctx); err != nil {
+		log.Error("Unable to finish event subscribers in progress: ", err)
+	}
+}
+
+// databaseTimeouts reads DB_QUERY_TIMEOUT, DB_EXEC_TIMEOUT, DB_TRANSACTION_TIMEOUT and DB_SCHEMA_TIMEOUT as
+// durations e.g. "3s". Unset timeouts use mysql.DefaultTimeouts, negative ones disable the timeout
+func databaseTimeouts() mysql.Timeouts {
+	parse := func(name string) time.Duration {
+		value := os.Getenv(name)
+		if value == "" {
+			return 0
+		}
+		timeout, err := time.ParseDuration(value)
+		if err != nil {
+			panic(fmt.Errorf("%s: %w", name, err))
+		}
+		return timeout
+	}
+
+	return mysql.Timeouts{
+		Query:       parse("DB_QUERY_TIMEOUT"),
+		Exec:        parse("DB_EXEC_TIMEOUT"),
+		Transaction: parse("DB_TRANSACTION_TIMEOUT"),
+		Schema:      parse("DB_SCHEMA_TIMEOUT"),
+	}
+}
+
+// registerSSOProviders registers the OIDC identity provider configured through the environment (if any)
+func registerSSOProviders() {
+	issuer := os.Getenv("OIDC_ISSUER")
+	if issuer == "" {
+		return
+	}
+
+	name := os.Getenv("OIDC_PROVIDER_NAME")
+	if name == "" {
+		name = "university"
+	}
+
+	var allowedDomains []string
+	for _, domain := range strings.Split(os.Getenv("OIDC_ALLOWED_DOMAINS"), ",") {
+		if domain = strings.TrimSpace(domain); domain != "" {
+			allowedDomains = append(allowedDomains, domain)
+		}
+	}
+
+	_, err := oidc.RegisterProvider(oidc.ProviderConfig{
+		Name:           name,
+		Issuer:         issuer,
+		ClientID:       os.Getenv("OIDC_CLIENT_ID"),
+		ClientSecret:   os.Getenv("OIDC_CLIENT_SECRET"),
+		RedirectURL:    os.Getenv("OIDC_REDIRECT_URL"),
+		AllowedDomains: allowedDomains,
+	})
+	if err != nil {
+		log.Errorf("Unable to register SSO provider %s: %s", name, err)
+	}
+}
+
+// loadSigningKeys loads the JWT keyring from JWT_KEYS_DIR. Without it an ephemeral key is used
+func loadSigningKeys() {
+	if issuer := os.Getenv("JWT_ISSUER"); issuer != "" {
+		security.TokenIssuer = issuer
+	}
+
+	if audience := os.Getenv("JWT_AUDIENCE"); audience != "" {
+		security.TokenAudience = audience
+	}
+
+	directory := os.Getenv("JWT_KEYS_DIR")
+	if directory == "" {
+		return
+	}
+
+	keyring, err := security.LoadKeyring(directory)
+	if err != nil {
+		panic(err)
+	}
+
+	security.SetKeyring(keyring)
+}
+
+// connectRedis connects to the Redis server at REDIS_HOST, REDIS_PORT (defaults to 6379) with REDIS_PASSWORD. It
+// shares scheduled jobs between instances, so each run happens on only one of them. Without REDIS_HOST it returns nil
+// and every instance runs every job
+func connectRedis() *redis.Repository {
+	host := os.Getenv("REDIS_HOST")
+	if host == "" {
+		return nil
+	}
+
+	port := 6379
+	if value := os.Getenv("REDIS_PORT"); value != "" {
+		var err error
+		if port, err = strconv.Atoi(value); err != nil {
+			panic(fmt.Errorf("REDIS_PORT: %w", err))
+		}
+	}
+
+	container := redis.Container{}
+	err := container.Connect(redis.NewConfigurations(db.AuthenticationConfigurations{
+		Host:     host,
+		Port:     port,
+		Password: os.Getenv("REDIS_PASSWORD"),
+	}, 0))
+	if err != nil {
+		panic(err)
+	}
+
+	return &redis.Repository{Database: container}
+}
+
+// startOutboxRelay backfills the matches made before the outbox, then publishes the outbox to Neo4j every second
+func startOutboxRelay(repo *mysql.Repository, neoRepo *neo4j.Repository) {
+	store, err := repositories.NewOutboxRepository(repo)
+	if err != nil {
+		panic(err)
+	}
+
+	graph := repositories.NewGraphRepository(neoRepo)
+
+	// Matches made before the outbox are only in Neo4j until they're copied into MySQL, which happens once
+	added, err := outbox.BackfillMatches(context.Background(), store, graph)
+	if err != nil {
+		log.Error("Unable to backfill matches from Neo4j: ", err)
+	} else if added > 0 {
+		log.Infof("Backfilled %d matches from Neo4j", added)
+	}
+
+	relay := outbox.NewRelay(store)
+	outbox.Project(relay, graph)
+
+	if err := concurrency.AddJob(relay.Job(time.Second)); err != nil {
+		log.Error("Unable to schedule publishing the outbox: ", err)
+	}
+}
+
+// configureJobQueue keeps background jobs in MySQL, or in Redis when JOB_QUEUE is redis
+func configureJobQueue(repo *mysql.Repository, redisRepo *redis.Repository) *queue.Queue {
+	var store queue.Store
+	var err error
+
+	switch backend := os.Getenv("JOB_QUEUE"); backend {
+	case "", "mysql":
+		store, err = repositories.NewJobRepository(repo)
+	case "redis":
+		if redisRepo == nil {
+			panic(fmt.Errorf("JOB_QUEUE: redis needs REDIS_HOST"))
+		}
+		store, err = redis.NewJobStore(redisRepo)
+	default:
+		err = fmt.Errorf("JOB_QUEUE: unknown queue %s, expected mysql or redis", backend)
+	}
+	if err != nil {
+		panic(err)
+	}
+
+	return queue.Configure(store, queue.Config{})
+}
+
+// configureMedia stores uploads in MEDIA_DIR (defaults to ./media) and signs download URLs with MEDIA_SIGNING_KEY.
+// Without a signing key an ephemeral one is used and download URLs stop working on restart
+func configureMedia() {
+	directory := os.Getenv("MEDIA_DIR")
+	if directory == "" {
+		directory = "media"
+	}
+
+	store, err := blob.NewLocalStore(directory)
 	if err != nil {
 		panic(err)
 	}
+
+	baseURL := strings.TrimSuffix(os.Getenv("PUBLIC_URL"), "/")
+
+	signer := media.NewEphemeralSigner(baseURL)
+	if key := os.Getenv("MEDIA_SIGNING_KEY"); key != "" {
+		signer = media.NewSigner([]byte(key), baseURL, media.DefaultURLLifetime)
+	}
+
+	media.Configure(store, signer)
 }
@@ -0,0 +1,88 @@
+// Package main compares the Neo4j graph with MySQL, the users, opportunities, likes and matches it should project,
+// and repairs the drift with -repair.
+package main
+
+import (
+	"backend/internal/db"
+	"backend/internal/db/adapters/mysql"
+	"backend/internal/db/adapters/neo4j"
+	"backend/internal/db/repositories"
+	"backend/internal/outbox"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+)
+
+// main prints the drift as JSON, exiting with 1 if there was any. Nothing is changed without -repair
+func main() {
+	repair := flag.Bool("repair", false, "bring the graph in line with MySQL rather than only reporting the drift")
+	flag.Parse()
+
+	source, graph := connect()
+	ctx := context.Background()
+
+	// The API server backfills on starting, but MySQL mustn't be taken as the truth about matches until it has
+	if _, err := outbox.BackfillMatches(ctx, source, graph); err != nil {
+		fail(err)
+	}
+
+	drift, err := outbox.Reconcile(ctx, source, graph, *repair)
+	if drift != nil {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(drift); err != nil {
+			fail(err)
+		}
+	}
+	if err != nil {
+		fail(err)
+	}
+	if !drift.Empty() && !*repair {
+		os.Exit(1)
+	}
+}
+
+// connect connects to the same databases as the API server
+func connect() (*repositories.OutboxRepository, *repositories.GraphRepository) {
+	//TODO: maybe load from config
+	container := mysql.Container{}
+	config := mysql.Configurations{
+		Authentication: &db.AuthenticationConfigurations{
+			Host:     "localhost",
+			Port:     3306,
+			Username: "root",
+			Password: "password",
+		},
+		DatabaseName: "mydatabase",
+	}
+	if err := container.Connect(config); err != nil {
+		fail(err)
+	}
+
+	neoContainer := neo4j.Container{}
+	neoConfig := neo4j.Configurations{
+		Authentication: &db.URIConfigurations{
+			AuthConfig: db.AuthenticationConfigurations{
+				Username: "neo4j",
+				Password: "password",
+			},
+			URI: "bolt://localhost:7687",
+		},
+	}
+	if err := neoContainer.Connect(neoConfig); err != nil {
+		fail(err)
+	}
+
+	source, err := repositories.NewOutboxRepository(&mysql.Repository{Database: &container})
+	if err != nil {
+		fail(err)
+	}
+	return source, repositories.NewGraphRepository(&neo4j.Repository{Database: &neoContainer})
+}
+
+func fail(err error) {
+	fmt.Fprintln(os.Stderr, err)
+	os.Exit(1)
+}
@@ -0,0 +1,77 @@
+package blob
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const key = "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"
+
+func TestLocalStoreRoundTrip(t *testing.T) {
+	root := t.TempDir()
+	store, err := NewLocalStore(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	if _, err := store.Open(ctx, key); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected not found, got %v", err)
+	}
+
+	if err := store.Put(ctx, key, strings.NewReader("test"), 4, "text/plain"); err != nil {
+		t.Fatal(err)
+	}
+	// Putting the same key again is a no-op
+	if err := store.Put(ctx, key, strings.NewReader("test"), 4, "text/plain"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := os.Stat(filepath.Join(root, "9f", "86", key)); err != nil {
+		t.Fatalf("expected the blob to be sharded: %v", err)
+	}
+
+	reader, err := store.Open(ctx, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	content, _ := io.ReadAll(reader)
+	reader.Close()
+	if string(content) != "test" {
+		t.Fatalf("unexpected content %q", content)
+	}
+
+	if err := store.Delete(ctx, key); err != nil {
+		t.Fatal(err)
+	}
+	if exists, _ := store.Exists(ctx, key); exists {
+		t.Fatal("expected the blob to be deleted")
+	}
+}
+
+func TestLocalStoreRejectsShortWrites(t *testing.T) {
+	store, err := NewLocalStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := store.Put(context.Background(), key, strings.NewReader("tes"), 4, ""); err == nil {
+		t.Fatal("expected a size mismatch error")
+	}
+	if exists, _ := store.Exists(context.Background(), key); exists {
+		t.Fatal("a short write must not be stored")
+	}
+}
+
+func TestValidateKeyRejectsPaths(t *testing.T) {
+	for _, invalid := range []string{"", "ab", "../../etc/passwd", "ABCDEF", "abcd/ef"} {
+		if ValidateKey(invalid) == nil {
+			t.Errorf("expected %q to be rejected", invalid)
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package blob
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// LocalStore keeps blobs on the local filesystem under root, sharded into directories by the first
+// two bytes of the key so no directory gets too large, e.g. ab/cd/abcd1234...
+type LocalStore struct {
+	root string
+}
+
+// NewLocalStore creates root if it doesn't exist
+func NewLocalStore(root string) (*LocalStore, error) {
+	if err := os.MkdirAll(filepath.Join(root, "tmp"), 0o750); err != nil {
+		return nil, err
+	}
+	return &LocalStore{root: root}, nil
+}
+
+// Put writes to a temporary file first and renames it into place, so a blob is never seen half written
+func (store *LocalStore) Put(ctx context.Context, key string, content io.Reader, size int64, _ string) error {
+	if err := ValidateKey(key); err != nil {
+		return err
+	}
+
+	if exists, err := store.Exists(ctx, key); err != nil || exists {
+		return err
+	}
+
+	file, err := os.CreateTemp(filepath.Join(store.root, "tmp"), "upload-*")
+	if err != nil {
+		return err
+	}
+	// Removing after the rename fails harmlessly
+	defer os.Remove(file.Name())
+
+	written, err := io.Copy(file, content)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	if written != size {
+		return fmt.Errorf("blob %s: wrote %d bytes, expected %d", key, written, size)
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	path := store.path(key)
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return err
+	}
+	return os.Rename(file.Name(), path)
+}
+
+func (store *LocalStore) Open(_ context.Context, key string) (io.ReadCloser, error) {
+	if err := ValidateKey(key); err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(store.path(key))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	return file, err
+}
+
+func (store *LocalStore) Exists(_ context.Context, key string) (bool, error) {
+	if err := ValidateKey(key); err != nil {
+		return false, err
+	}
+
+	_, err := os.Stat(store.path(key))
+	if errors.Is(err, fs.ErrNotExist) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (store *LocalStore) Delete(_ context.Context, key string) error {
+	if err := ValidateKey(key); err != nil {
+		return err
+	}
+
+	err := os.Remove(store.path(key))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+func (store *LocalStore) path(key string) string {
+	return filepath.Join(store.root, key[0:2], key[2:4], key)
+}
@@ -0,0 +1,60 @@
+package blob
+
+import (
+	"context"
+	"io"
+)
+
+// S3Client is the part of an S3 compatible API the store uses, so any client (AWS SDK, MinIO, R2...)
+// can be plugged in with a small adapter
+type S3Client interface {
+	PutObject(ctx context.Context, bucket string, key string, body io.Reader, size int64, contentType string) error
+	// GetObject must return ErrNotFound if the object doesn't exist
+	GetObject(ctx context.Context, bucket string, key string) (io.ReadCloser, error)
+	// HeadObject reports whether the object exists
+	HeadObject(ctx context.Context, bucket string, key string) (bool, error)
+	DeleteObject(ctx context.Context, bucket string, key string) error
+}
+
+// S3Store keeps blobs in an S3 compatible bucket, under prefix if set
+type S3Store struct {
+	client S3Client
+	bucket string
+	prefix string
+}
+
+func NewS3Store(client S3Client, bucket string, prefix string) *S3Store {
+	return &S3Store{client: client, bucket: bucket, prefix: prefix}
+}
+
+func (store *S3Store) Put(ctx context.Context, key string, content io.Reader, size int64, contentType string) error {
+	if err := ValidateKey(key); err != nil {
+		return err
+	}
+
+	if exists, err := store.Exists(ctx, key); err != nil || exists {
+		return err
+	}
+	return store.client.PutObject(ctx, store.bucket, store.prefix+key, content, size, contentType)
+}
+
+func (store *S3Store) Open(ctx context.Context, key string) (io.ReadCloser, error) {
+	if err := ValidateKey(key); err != nil {
+		return nil, err
+	}
+	return store.client.GetObject(ctx, store.bucket, store.prefix+key)
+}
+
+func (store *S3Store) Exists(ctx context.Context, key string) (bool, error) {
+	if err := ValidateKey(key); err != nil {
+		return false, err
+	}
+	return store.client.HeadObject(ctx, store.bucket, store.prefix+key)
+}
+
+func (store *S3Store) Delete(ctx context.Context, key string) error {
+	if err := ValidateKey(key); err != nil {
+		return err
+	}
+	return store.client.DeleteObject(ctx, store.bucket, store.prefix+key)
+}
@@ -0,0 +1,41 @@
+// Package blob stores uploaded files. Blobs are immutable and keyed by their content hash, so storing
+// the same content twice is a no-op and a key always refers to the same bytes
+package blob
+
+import (
+	"context"
+	"errors"
+	"io"
+)
+
+// ErrNotFound is returned when opening a blob that isn't stored
+var ErrNotFound = errors.New("blob not found")
+
+// ErrInvalidKey is returned for keys that aren't lowercase hex, e.g. a sha256 digest
+var ErrInvalidKey = errors.New("invalid blob key")
+
+// Store is where blobs are kept. Implementations must be safe for concurrent use
+type Store interface {
+	// Put stores size bytes of content under key, doing nothing if the key is already stored
+	Put(ctx context.Context, key string, content io.Reader, size int64, contentType string) error
+	// Open returns ErrNotFound if the key isn't stored
+	Open(ctx context.Context, key string) (io.ReadCloser, error)
+	Exists(ctx context.Context, key string) (bool, error)
+	Delete(ctx context.Context, key string) error
+}
+
+// minKeyLength keeps keys long enough to shard on their first two bytes
+const minKeyLength = 4
+
+// ValidateKey checks the key is lowercase hex, so it's safe to use as a path or object name
+func ValidateKey(key string) error {
+	if len(key) < minKeyLength {
+		return ErrInvalidKey
+	}
+	for _, r := range key {
+		if (r < '0' || r > '9') && (r < 'a' || r > 'f') {
+			return ErrInvalidKey
+		}
+	}
+	return nil
+}
@@ -0,0 +1,374 @@
+// Package bulkimport reads opportunities to import from CSV or JSON Lines
+package bulkimport
+
+import (
+	"backend/internal/models"
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+type Format string
+
+const (
+	CSV        Format = "csv"
+	JSONLines  Format = "jsonl"
+	MaxRows           = 1000
+	maxRefSize        = 100
+)
+
+var (
+	ErrUnknownFormat = errors.New("format must be csv or jsonl")
+	ErrTooManyRows   = fmt.Errorf("an import can have at most %d rows", MaxRows)
+)
+
+// Row is an opportunity to create, or update if its ExternalRef was imported before
+type Row struct {
+	// Line is where the row starts in the file, for reporting errors
+	Line    int
+	Request models.CreateOpportunityRequest
+}
+
+// RowError is a row that couldn't be read
+type RowError struct {
+	Line int
+	Err  error
+}
+
+// ParseFormat returns the format named, or the one a file name or content type suggests when name is empty
+func ParseFormat(name string, fileName string, contentType string) (Format, error) {
+	switch strings.ToLower(name) {
+	case "csv":
+		return CSV, nil
+	case "jsonl", "ndjson":
+		return JSONLines, nil
+	case "":
+	default:
+		return "", ErrUnknownFormat
+	}
+
+	switch strings.ToLower(filepath.Ext(fileName)) {
+	case ".csv":
+		return CSV, nil
+	case ".jsonl", ".ndjson":
+		return JSONLines, nil
+	}
+
+	mediaType := strings.TrimSpace(strings.Split(contentType, ";")[0])
+	switch strings.ToLower(mediaType) {
+	case "text/csv":
+		return CSV, nil
+	case "application/x-ndjson", "application/jsonl", "application/x-jsonlines":
+		return JSONLines, nil
+	}
+	return "", ErrUnknownFormat
+}
+
+// Parse reads every row, rows that can't be read are returned as errors rather than stopping the import.
+// An error is only returned when the file as a whole can't be read
+func Parse(format Format, reader io.Reader) ([]Row, []RowError, error) {
+	switch format {
+	case CSV:
+		return parseCSV(reader)
+	case JSONLines:
+		return parseJSONLines(reader)
+	default:
+		return nil, nil, ErrUnknownFormat
+	}
+}
+
+// parseJSONLines reads a CreateOpportunityRequest per line, blank lines are skipped
+func parseJSONLines(reader io.Reader) ([]Row, []RowError, error) {
+	var rows []Row
+	var rowErrors []RowError
+
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := bytes.TrimSpace(scanner.Bytes())
+		if len(text) == 0 {
+			continue
+		}
+		if len(rows)+len(rowErrors) == MaxRows {
+			return nil, nil, ErrTooManyRows
+		}
+
+		var request models.CreateOpportunityRequest
+		decoder := json.NewDecoder(bytes.NewReader(text))
+		// A misspelt field would otherwise be silently dropped
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&request); err != nil {
+			rowErrors = append(rowErrors, RowError{Line: line, Err: err})
+			continue
+		}
+		rows = append(rows, Row{Line: line, Request: request})
+	}
+	return rows, rowErrors, scanner.Err()
+}
+
+// csvFields sets a CreateOpportunityRequest field from a CSV column, named as in the JSON
+var csvFields = map[string]func(request *models.CreateOpportunityRequest, value string) error{
+	"externalRef": func(request *models.CreateOpportunityRequest, value string) error {
+		request.ExternalRef = value
+		return nil
+	},
+	"title": func(request *models.CreateOpportunityRequest, value string) error {
+		request.Title = value
+		return nil
+	},
+	"description": func(request *models.CreateOpportunityRequest, value string) error {
+		request.Description = value
+		return nil
+	},
+	"location": func(request *models.CreateOpportunityRequest, value string) error {
+		request.Location = value
+		return nil
+	},
+	"type": func(request *models.CreateOpportunityRequest, value string) error {
+		request.Type = value
+		return nil
+	},
+	"points": func(request *models.CreateOpportunityRequest, value string) error {
+		return parseInt(value, &request.Points)
+	},
+	"tags": func(request *models.CreateOpportunityRequest, value string) error {
+		request.Tags = splitList(value)
+		return nil
+	},
+	"media": func(request *models.CreateOpportunityRequest, value string) error {
+		request.MediaIDs = splitList(value)
+		return nil
+	},
+	"latitude": func(request *models.CreateOpportunityRequest, value string) error {
+		return parseFloat(value, &request.Latitude)
+	},
+	"longitude": func(request *models.CreateOpportunityRequest, value string) error {
+		return parseFloat(value, &request.Longitude)
+	},
+	"online": func(request *models.CreateOpportunityRequest, value string) error {
+		return parseBool(value, &request.Online)
+	},
+	"addressLine": func(request *models.CreateOpportunityRequest, value string) error {
+		request.Address.Line = value
+		return nil
+	},
+	"city": func(request *models.CreateOpportunityRequest, value string) error {
+		request.Address.City = value
+		return nil
+	},
+	"region": func(request *models.CreateOpportunityRequest, value string) error {
+		request.Address.Region = value
+		return nil
+	},
+	"postcode": func(request *models.CreateOpportunityRequest, value string) error {
+		request.Address.Postcode = value
+		return nil
+	},
+	"country": func(request *models.CreateOpportunityRequest, value string) error {
+		request.Address.Country = value
+		return nil
+	},
+	"startsAt": func(request *models.CreateOpportunityRequest, value string) error {
+		request.StartsAt = value
+		return nil
+	},
+	"endsAt": func(request *models.CreateOpportunityRequest, value string) error {
+		request.EndsAt = value
+		return nil
+	},
+	"timeZone": func(request *models.CreateOpportunityRequest, value string) error {
+		request.TimeZone = value
+		return nil
+	},
+	"recurrence": func(request *models.CreateOpportunityRequest, value string) error {
+		request.Recurrence = value
+		return nil
+	},
+	"deadline": func(request *models.CreateOpportunityRequest, value string) error {
+		request.Deadline = value
+		return nil
+	},
+	"capacity": func(request *models.CreateOpportunityRequest, value string) error {
+		if value == "" {
+			return nil
+		}
+		var capacity int64
+		if err := parseInt(value, &capacity); err != nil {
+			return err
+		}
+		request.Capacity = &capacity
+		return nil
+	},
+	"draft": func(request *models.CreateOpportunityRequest, value string) error {
+		return parseBool(value, &request.Draft)
+	},
+}
+
+// parseCSV reads a CSV file whose first row names the columns. Column names are matched case insensitively,
+// tags and media are separated by semicolons or commas
+func parseCSV(reader io.Reader) ([]Row, []RowError, error) {
+	csvReader := csv.NewReader(reader)
+	csvReader.FieldsPerRecord = -1
+	csvReader.TrimLeadingSpace = true
+
+	header, err := csvReader.Read()
+	if err == io.EOF {
+		return nil, nil, errors.New("the file is empty")
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	columns, err := csvColumns(header)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var rows []Row
+	var rowErrors []RowError
+	for {
+		record, err := csvReader.Read()
+		if err == io.EOF {
+			break
+		}
+		var parseError *csv.ParseError
+		if errors.As(err, &parseError) {
+			rowErrors = append(rowErrors, RowError{Line: parseError.StartLine, Err: parseError.Err})
+			continue
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		if isBlank(record) {
+			continue
+		}
+		line, _ := csvReader.FieldPos(0)
+		if len(rows)+len(rowErrors) == MaxRows {
+			return nil, nil, ErrTooManyRows
+		}
+
+		if len(record) > len(columns) {
+			rowErrors = append(rowErrors, RowError{Line: line, Err: fmt.Errorf("row has %d values but there are %d columns", len(record), len(columns))})
+			continue
+		}
+
+		var request models.CreateOpportunityRequest
+		var fieldErrors []string
+		for i, value := range record {
+			if err := csvFields[columns[i]](&request, strings.TrimSpace(value)); err != nil {
+				fieldErrors = append(fieldErrors, fmt.Sprintf("%s: %v", columns[i], err))
+			}
+		}
+		if len(fieldErrors) > 0 {
+			rowErrors = append(rowErrors, RowError{Line: line, Err: errors.New(strings.Join(fieldErrors, "; "))})
+			continue
+		}
+		rows = append(rows, Row{Line: line, Request: request})
+	}
+	return rows, rowErrors, nil
+}
+
+// csvColumns returns the field name of each column, rejecting unknown and repeated ones
+func csvColumns(header []string) ([]string, error) {
+	names := make(map[string]string, len(csvFields))
+	for name := range csvFields {
+		names[strings.ToLower(name)] = name
+	}
+
+	columns := make([]string, len(header))
+	seen := map[string]bool{}
+	for i, column := range header {
+		name, ok := names[strings.ToLower(strings.TrimSpace(strings.TrimPrefix(column, "\ufeff")))]
+		if !ok {
+			return nil, fmt.Errorf("unknown column %q", column)
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("column %q is repeated", column)
+		}
+		seen[name] = true
+		columns[i] = name
+	}
+	return columns, nil
+}
+
+// ValidateRef checks the reference a row is matched to previous imports by
+func ValidateRef(ref string) error {
+	if strings.TrimSpace(ref) == "" {
+		return errors.New("externalRef is required")
+	}
+	if len(ref) > maxRefSize {
+		return fmt.Errorf("externalRef can be at most %d characters", maxRefSize)
+	}
+	return nil
+}
+
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.FieldsFunc(value, func(r rune) bool { return r == ';' || r == ',' }) {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func parseInt(value string, target *int64) error {
+	if value == "" {
+		return nil
+	}
+	parsed, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return fmt.Errorf("%q isn't a whole number", value)
+	}
+	*target = parsed
+	return nil
+}
+
+func parseFloat(value string, target **float64) error {
+	if value == "" {
+		return nil
+	}
+	parsed, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return fmt.Errorf("%q isn't a number", value)
+	}
+	*target = &parsed
+	return nil
+}
+
+func parseBool(value string, target *bool) error {
+	switch strings.ToLower(value) {
+	case "":
+		return nil
+	case "yes", "y":
+		*target = true
+		return nil
+	case "no", "n":
+		*target = false
+		return nil
+	}
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		return fmt.Errorf("%q isn't true or false", value)
+	}
+	*target = parsed
+	return nil
+}
+
+func isBlank(record []string) bool {
+	for _, value := range record {
+		if strings.TrimSpace(value) != "" {
+			return false
+		}
+	}
+	return true
+}
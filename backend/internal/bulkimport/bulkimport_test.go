@@ -0,0 +1,93 @@
+package bulkimport
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestParseCSV(t *testing.T) {
+	file := "\ufeffExternalRef,title,description,location,type,points,tags,capacity,online\n" +
+		"beach-1,Beach clean,\"Bring gloves, and a hat\",Brighton,event,10,beach; litter,20,no\n" +
+		"\n" +
+		"tree-1,Tree planting,Spades provided,Leeds,volunteer,lots,,,\n" +
+		"park-1,Park clean,Litter picking,York,event,5,,,maybe\n"
+
+	rows, rowErrors, err := Parse(CSV, strings.NewReader(file))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(rows) != 1 {
+		t.Fatalf("expected one valid row, got %+v", rows)
+	}
+	request := rows[0].Request
+	if rows[0].Line != 2 || request.ExternalRef != "beach-1" || request.Description != "Bring gloves, and a hat" || request.Points != 10 {
+		t.Fatalf("unexpected row %+v", rows[0])
+	}
+	if len(request.Tags) != 2 || request.Tags[1] != "litter" || *request.Capacity != 20 || request.Online {
+		t.Fatalf("unexpected row %+v", request)
+	}
+
+	if len(rowErrors) != 2 || rowErrors[0].Line != 4 || rowErrors[1].Line != 5 {
+		t.Fatalf("expected errors on lines 4 and 5, got %+v", rowErrors)
+	}
+	if !strings.Contains(rowErrors[0].Err.Error(), "points") {
+		t.Fatalf("expected the points column to be named, got %v", rowErrors[0].Err)
+	}
+}
+
+func TestParseCSVRejectsUnknownColumns(t *testing.T) {
+	if _, _, err := Parse(CSV, strings.NewReader("title,colour\nBeach clean,green\n")); err == nil {
+		t.Fatal("expected an unknown column to be rejected")
+	}
+	if _, _, err := Parse(CSV, strings.NewReader("title,Title\n")); err == nil {
+		t.Fatal("expected a repeated column to be rejected")
+	}
+}
+
+func TestParseJSONLines(t *testing.T) {
+	file := `{"externalRef": "beach-1", "title": "Beach clean", "points": 10, "tags": ["beach"]}
+
+{"externalRef": "tree-1", "titel": "Tree planting"}
+not json
+`
+	rows, rowErrors, err := Parse(JSONLines, strings.NewReader(file))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(rows) != 1 || rows[0].Line != 1 || rows[0].Request.Title != "Beach clean" || rows[0].Request.Tags[0] != "beach" {
+		t.Fatalf("unexpected rows %+v", rows)
+	}
+	if len(rowErrors) != 2 || rowErrors[0].Line != 3 || rowErrors[1].Line != 4 {
+		t.Fatalf("expected a misspelt field and invalid JSON to fail, got %+v", rowErrors)
+	}
+}
+
+func TestParseLimitsRows(t *testing.T) {
+	file := strings.Repeat("{}\n", MaxRows+1)
+	if _, _, err := Parse(JSONLines, strings.NewReader(file)); !errors.Is(err, ErrTooManyRows) {
+		t.Fatalf("expected ErrTooManyRows, got %v", err)
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	for _, test := range []struct {
+		name, fileName, contentType string
+		expected                    Format
+	}{
+		{"CSV", "", "", CSV},
+		{"", "opportunities.jsonl", "", JSONLines},
+		{"", "", "text/csv; charset=utf-8", CSV},
+		{"", "", "application/x-ndjson", JSONLines},
+	} {
+		if format, err := ParseFormat(test.name, test.fileName, test.contentType); err != nil || format != test.expected {
+			t.Errorf("ParseFormat(%q, %q, %q) = %q %v, expected %q", test.name, test.fileName, test.contentType, format, err, test.expected)
+		}
+	}
+
+	if _, err := ParseFormat("", "", "application/json"); !errors.Is(err, ErrUnknownFormat) {
+		t.Fatalf("expected ErrUnknownFormat, got %v", err)
+	}
+}
@@ -0,0 +1,24 @@
+package mysql
+
+import (
+	"backend/internal/utils/concurrency"
+	"context"
+)
+
+// DefaultConcurrency is how many calls made with Async run at once on a container that doesn't configure it
+const DefaultConcurrency = 16
+
+// Async runs fn on the container's thread pool and returns a future of its result, so independent queries can run
+// in parallel. At most the container's Concurrency calls run at once and the rest queue, fn isn't run if ctx is done
+// before its turn comes. fn must not wait on other futures from Async or a full pool would wait on itself.
+//
+// Within WithTx fn runs straight away on the caller's goroutine, a transaction can only run one statement at a time
+func Async[T any](ctx context.Context, r *Repository, fn func(ctx context.Context) (T, error)) *concurrency.Future[T] {
+	pool := r.Database.GetThreadPool()
+	if pool == nil || ambientTx(ctx) != nil {
+		value, err := fn(ctx)
+		return concurrency.Resolved(value, err)
+	}
+
+	return concurrency.SubmitFuture(ctx, pool, fn)
+}
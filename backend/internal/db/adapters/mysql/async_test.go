@@ -0,0 +1,97 @@
+package mysql
+
+import (
+	"backend/internal/utils/concurrency"
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+)
+
+func newAsyncRepository(t *testing.T, limit int) *Repository {
+	repo, _ := newRecorderRepository(t)
+	repo.Database.pool = concurrency.NewThreadPool(limit, limit*4)
+	repo.Database.pool.Start()
+	return repo
+}
+
+func TestAsyncBoundsConcurrency(t *testing.T) {
+	repo := newAsyncRepository(t, 2)
+
+	var running, peak atomic.Int32
+	release := make(chan struct{})
+	started := make(chan struct{}, 5)
+
+	futures := make([]*concurrency.Future[int], 5)
+	for i := range futures {
+		futures[i] = Async(context.Background(), repo, func(context.Context) (int, error) {
+			now := running.Add(1)
+			for seen := peak.Load(); now > seen && !peak.CompareAndSwap(seen, now); seen = peak.Load() {
+			}
+			started <- struct{}{}
+			<-release
+			running.Add(-1)
+			return i, nil
+		})
+	}
+
+	// The pool is full once two calls have started
+	<-started
+	<-started
+	close(release)
+	for i, future := range futures {
+		if value, err := future.Get(context.Background()); value != i || err != nil {
+			t.Fatalf("expected %d, got %d, %v", i, value, err)
+		}
+	}
+	if peak.Load() > 2 {
+		t.Fatalf("expected at most 2 calls at once, got %d", peak.Load())
+	}
+}
+
+func TestAsyncSkipsCancelledCalls(t *testing.T) {
+	repo := newAsyncRepository(t, 1)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	future := Async(ctx, repo, func(context.Context) (int, error) {
+		t.Error("expected fn not to run")
+		return 0, nil
+	})
+	if _, err := future.Get(context.Background()); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+}
+
+func TestAsyncRecoversPanics(t *testing.T) {
+	repo := newAsyncRepository(t, 1)
+
+	future := Async(context.Background(), repo, func(context.Context) (int, error) { panic("failed") })
+	if _, err := future.Get(context.Background()); err == nil {
+		t.Fatal("expected the panic as an error")
+	}
+}
+
+func TestAsyncRunsInlineInTransactions(t *testing.T) {
+	repo := newAsyncRepository(t, 1)
+
+	err := repo.WithTx(context.Background(), func(tx *Tx) error {
+		future := Async(tx.Context(), repo, func(ctx context.Context) (bool, error) {
+			return ambientTx(ctx) == tx, nil
+		})
+		select {
+		case <-future.Done():
+		default:
+			t.Error("expected fn to have run before Async returned")
+		}
+		joined, err := future.Get(tx.Context())
+		if !joined {
+			t.Error("expected fn to run in the transaction")
+		}
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,226 @@
+package mysql
+
+import (
+	"database/sql"
+	"fmt"
+	"github.com/google/uuid"
+	"reflect"
+	"sync"
+	"time"
+)
+
+// Rows are mapped onto structs by `db` struct tags naming the column, e.g. `db:"postedByUUID"`. The same tags bind
+// the struct as parameters with ColumnsOf, so one struct describes a row both ways.
+//
+// A pointer field is nil when the column is NULL, so nullable columns don't need sql.NullString and friends. Fields
+// of embedded structs are mapped as if they were the outer struct's. Columns without a field are skipped, so
+// SELECT * keeps working as columns are added. A join can return the same column name more than once, e.g. the id
+// of each table, and the nth column of a name goes to the nth field tagged with it
+
+const columnTag = "db"
+
+// fieldIndexes caches the field indexes of each struct type by column name
+var fieldIndexes sync.Map
+
+// fieldsOf returns the index of every tagged field of the struct type by column name, in declaration order
+func fieldsOf(structType reflect.Type) map[string][][]int {
+	if cached, ok := fieldIndexes.Load(structType); ok {
+		return cached.(map[string][][]int)
+	}
+
+	fields := map[string][][]int{}
+	var walk func(structType reflect.Type, parent []int)
+	walk = func(structType reflect.Type, parent []int) {
+		for i := range structType.NumField() {
+			field := structType.Field(i)
+			index := append(append([]int{}, parent...), i)
+
+			name, tagged := field.Tag.Lookup(columnTag)
+			if !tagged && field.Anonymous && field.Type.Kind() == reflect.Struct {
+				walk(field.Type, index)
+				continue
+			}
+			if name == "" || name == "-" || !field.IsExported() {
+				continue
+			}
+			fields[name] = append(fields[name], index)
+		}
+	}
+	walk(structType, nil)
+
+	fieldIndexes.Store(structType, fields)
+	return fields
+}
+
+// ScanAll reads every row into a T and closes the rows. A query cancelled or timed out part way through returns
+// its context's error
+func ScanAll[T any](rows *sql.Rows) ([]T, error) {
+	defer rows.Close()
+
+	destinations, err := destinationsFor[T](rows)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]T, 0)
+	for rows.Next() {
+		var result T
+		if err := rows.Scan(destinations(&result)...); err != nil {
+			return nil, err
+		}
+		results = append(results, result)
+	}
+	return results, rows.Err()
+}
+
+// ScanOne reads the first row into a T and closes the rows, nil if there are none
+func ScanOne[T any](rows *sql.Rows) (*T, error) {
+	defer rows.Close()
+
+	destinations, err := destinationsFor[T](rows)
+	if err != nil {
+		return nil, err
+	}
+
+	if !rows.Next() {
+		return nil, rows.Err()
+	}
+
+	var result T
+	if err := rows.Scan(destinations(&result)...); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// destinationsFor matches the columns of rows to fields of T, returning a function giving the pointers to scan a
+// row into
+func destinationsFor[T any](rows *sql.Rows) (func(*T) []any, error) {
+	structType := reflect.TypeFor[T]()
+	if structType.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("can't scan rows into %s, only into structs", structType)
+	}
+
+	names, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+
+	fields := fieldsOf(structType)
+	seen := map[string]int{}
+	indexes := make([][]int, len(names))
+	for i, name := range names {
+		if candidates := fields[name]; seen[name] < len(candidates) {
+			indexes[i] = candidates[seen[name]]
+		}
+		seen[name]++
+	}
+
+	return func(result *T) []any {
+		value := reflect.ValueOf(result).Elem()
+		destinations := make([]any, len(indexes))
+		for i, index := range indexes {
+			if index == nil {
+				destinations[i] = new(any)
+				continue
+			}
+			destinations[i] = value.FieldByIndex(index).Addr().Interface()
+		}
+		return destinations
+	}, nil
+}
+
+// OneToMany assembles parents from joined rows, which repeat the parent's columns for every child. Rows with the
+// same key share the parent newParent makes from the first of them, and add is called with each row to attach its
+// children. Parents are returned in the order the rows first had them
+func OneToMany[R any, K comparable, P any](rows []R, key func(*R) K, newParent func(*R) P, add func(*P, *R)) []P {
+	parents := make([]P, 0)
+	positions := map[K]int{}
+
+	for i := range rows {
+		row := &rows[i]
+		position, ok := positions[key(row)]
+		if !ok {
+			position = len(parents)
+			positions[key(row)] = position
+			parents = append(parents, newParent(row))
+		}
+		add(&parents[position], row)
+	}
+	return parents
+}
+
+// AppendDistinct appends the child unless one with the same key is already there. Joining two lists of children
+// repeats each child for every one of the other list
+func AppendDistinct[T any, K comparable](children []T, child T, key func(T) K) []T {
+	for _, existing := range children {
+		if key(existing) == key(child) {
+			return children
+		}
+	}
+	return append(children, child)
+}
+
+// ColumnsOf returns the named columns of a struct tagged for scanning, to bind it as parameters in that order.
+// Fields tagged with the same name more than once bind the first
+func ColumnsOf(value any, names ...string) ([]Column, error) {
+	structValue := reflect.Indirect(reflect.ValueOf(value))
+	if structValue.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("can't bind %T, only structs", value)
+	}
+
+	fields := fieldsOf(structValue.Type())
+	columns := make([]Column, 0, len(names))
+	for _, name := range names {
+		indexes := fields[name]
+		if len(indexes) == 0 {
+			return nil, fmt.Errorf("%s has no field for column %s", structValue.Type(), name)
+		}
+
+		field := structValue.FieldByIndex(indexes[0])
+		column := columnOf(name, field.Interface())
+		if column == nil && field.CanAddr() {
+			// Enums such as models.RoleType have String on their pointer
+			column = columnOf(name, field.Addr().Interface())
+		}
+		if column == nil {
+			return nil, fmt.Errorf("can't bind column %s of type %s", name, field.Type())
+		}
+		columns = append(columns, column)
+	}
+	return columns, nil
+}
+
+// columnOf makes the Column for a field's value, nil pointers are NULL. nil if the type can't be bound
+func columnOf(name string, value any) Column {
+	switch value := value.(type) {
+	case uuid.UUID:
+		return NewUUIDColumn(name, value)
+	case *uuid.UUID:
+		return NewNullableUUIDColumn(name, value)
+	case string:
+		return NewVarcharColumn(name, value)
+	case *string:
+		return NewNullableVarcharColumn(name, value)
+	case int:
+		return NewIntegerColumn(name, int64(value))
+	case int64:
+		return NewIntegerColumn(name, value)
+	case *int64:
+		return NewNullableIntegerColumn(name, value)
+	case float64:
+		return NewDoubleColumn(name, value)
+	case *float64:
+		return NewNullableDoubleColumn(name, value)
+	case bool:
+		return NewBoolColumn(name, value)
+	case time.Time:
+		return NewDateTimeColumn(name, value)
+	case *time.Time:
+		return NewNullableDateTimeColumn(name, value)
+	case interface{ String() string }:
+		// Enums are stored as their names
+		return NewVarcharColumn(name, value.String())
+	}
+	return nil
+}
@@ -0,0 +1,113 @@
+package mysql
+
+import (
+	"database/sql/driver"
+	"github.com/google/uuid"
+	"testing"
+)
+
+type embedded struct {
+	Title string `db:"title"`
+}
+
+type parentRow struct {
+	embedded
+	ID    int64     `db:"id"`
+	UUID  uuid.UUID `db:"uuid"`
+	Score *float64  `db:"score"`
+	// The second id column is the child's
+	ChildID   *int64  `db:"id"`
+	ChildName *string `db:"name"`
+}
+
+func scanRows(t *testing.T, columns []string, values ...[]driver.Value) []parentRow {
+	t.Helper()
+
+	repo, r := newRecorderRepository(t)
+	r.columns, r.values = columns, values
+
+	rows, err := repo.Database.database.Query("SELECT")
+	if err != nil {
+		t.Fatal(err)
+	}
+	results, err := ScanAll[parentRow](rows)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return results
+}
+
+func TestScanAllMapsColumnsByName(t *testing.T) {
+	id := uuid.New()
+	results := scanRows(t, []string{"score", "uuid", "id", "unknown", "title", "id", "name"},
+		[]driver.Value{nil, []byte(id.String()), int64(1), "skipped", []byte("Beach clean"), int64(7), []byte("gloves")},
+		[]driver.Value{1.5, []byte(id.String()), int64(1), nil, []byte("Beach clean"), nil, nil},
+	)
+
+	if len(results) != 2 {
+		t.Fatalf("expected 2 rows, got %+v", results)
+	}
+	first, second := results[0], results[1]
+	if first.ID != 1 || first.UUID != id || first.Title != "Beach clean" || first.Score != nil {
+		t.Fatalf("unexpected row %+v", first)
+	}
+	if *first.ChildID != 7 || *first.ChildName != "gloves" {
+		t.Fatalf("expected the second id to be the child's, got %+v", first)
+	}
+	if second.Score == nil || *second.Score != 1.5 || second.ChildID != nil || second.ChildName != nil {
+		t.Fatalf("expected NULL columns to leave pointers nil, got %+v", second)
+	}
+}
+
+func TestOneToMany(t *testing.T) {
+	gloves, hat := "gloves", "hat"
+	one, two := int64(1), int64(2)
+	rows := []parentRow{
+		{ID: 2, ChildID: &one, ChildName: &gloves},
+		{ID: 1},
+		{ID: 2, ChildID: &two, ChildName: &hat},
+		{ID: 2, ChildID: &one, ChildName: &gloves},
+	}
+
+	type parent struct {
+		ID       int64
+		Children []string
+	}
+	parents := OneToMany(rows,
+		func(row *parentRow) int64 { return row.ID },
+		func(row *parentRow) parent { return parent{ID: row.ID} },
+		func(parent *parent, row *parentRow) {
+			if row.ChildName != nil {
+				parent.Children = AppendDistinct(parent.Children, *row.ChildName, func(name string) string { return name })
+			}
+		})
+
+	if len(parents) != 2 || parents[0].ID != 2 || parents[1].ID != 1 {
+		t.Fatalf("expected parents in the order first seen, got %+v", parents)
+	}
+	if len(parents[0].Children) != 2 || parents[0].Children[1] != "hat" || parents[1].Children != nil {
+		t.Fatalf("expected each child once, got %+v", parents)
+	}
+}
+
+func TestColumnsOf(t *testing.T) {
+	row := parentRow{embedded: embedded{Title: "Beach clean"}, ID: 3}
+
+	columns, err := ColumnsOf(&row, "title", "score", "id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if columns[0].GetValue() != "Beach clean" || columns[0].GetType() != "VARCHAR" {
+		t.Fatalf("unexpected title column %+v", columns[0])
+	}
+	if columns[1].GetValue() != nil || !columns[1].IsNullable() {
+		t.Fatalf("expected a nil pointer to bind NULL, got %+v", columns[1])
+	}
+	if columns[2].GetValue() != int64(3) {
+		t.Fatalf("expected the first field tagged id, got %+v", columns[2])
+	}
+
+	if _, err := ColumnsOf(row, "colour"); err == nil {
+		t.Fatal("expected a column without a field to be rejected")
+	}
+}
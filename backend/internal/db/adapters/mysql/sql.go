@@ -4,12 +4,14 @@ package mysql
 import (
 	"backend/internal/db"
 	"backend/internal/utils/concurrency"
+	"context"
 	"database/sql"
 	"fmt"
 	_ "github.com/go-sql-driver/mysql"
 	"github.com/google/uuid"
 	"strconv"
 	"strings"
+	"time"
 )
 
 //TODO: Add async capabilities
@@ -18,12 +20,17 @@ import (
 type Container struct {
 	database *sql.DB
 	pool     *concurrency.ThreadPool
+	timeouts Timeouts
 }
 
 // Configurations holds MySQL configuration including auth and DB name.
 type Configurations struct {
 	Authentication *db.AuthenticationConfigurations
 	DatabaseName   string
+	// Timeouts bound each call whose context has no earlier deadline, unset timeouts use DefaultTimeouts
+	Timeouts Timeouts
+	// Concurrency is how many calls made with Async run at once, DefaultConcurrency if unset
+	Concurrency int
 }
 
 // GetAuthenticationConfigurations returns a copy of the authentication config.
@@ -69,19 +76,32 @@ func (sqlDatabase *Container) Connect(config Configurations) error {
 	}
 
 	sqlDatabase.database = database
-	//sqlDatabase.pool = concurrency.NewThreadPool(500, 100)
-	//sqlDatabase.pool.Start()
+	sqlDatabase.timeouts = config.Timeouts
+
+	concurrencyLimit := config.Concurrency
+	if concurrencyLimit <= 0 {
+		concurrencyLimit = DefaultConcurrency
+	}
+	sqlDatabase.pool = concurrency.NewThreadPool(concurrencyLimit, concurrencyLimit*4)
+	sqlDatabase.pool.Start()
 
 	return nil
 }
 
+// NewContainer wraps a database that's already open, such as one with a test driver. It has no thread pool, so it
+// can't be used with Async
+func NewContainer(database *sql.DB, timeouts Timeouts) *Container {
+	return &Container{database: database, timeouts: timeouts}
+}
+
 // GetThreadPool returns the internal thread pool.
 func (sqlDatabase *Container) GetThreadPool() *concurrency.ThreadPool {
 	return sqlDatabase.pool
 }
 
-// Close closes the database connection
+// Close waits for calls queued with Async, then closes the database connection
 func (sqlDatabase *Container) Close() error {
+	concurrency.ShutdownPool(sqlDatabase.pool)
 	return sqlDatabase.database.Close()
 }
 
@@ -118,6 +138,10 @@ type BaseColumn struct {
 	value    interface{}
 	length   int
 	nullable bool
+	// defaultValue and onUpdate are SQL expressions, only used when creating tables
+	defaultValue string
+	onUpdate     string
+	unique       bool
 }
 
 // GetName returns the column name
@@ -193,9 +217,27 @@ func NewIntegerColumn(name string, value int64) *IntegerColumn {
 	}
 }
 
-// NewIntegerColumnForTable creates an IntegerColumn for table definition
-func NewIntegerColumnForTable(name string, nullable bool, autoIncrement bool) *IntegerColumn {
+// NewNullableIntegerColumn creates an IntegerColumn which is NULL when value is nil
+func NewNullableIntegerColumn(name string, value *int64) *IntegerColumn {
+	var columnValue interface{}
+	if value != nil {
+		columnValue = *value
+	}
+
 	return &IntegerColumn{
+		BaseColumn: &BaseColumn{
+			name:     name,
+			value:    columnValue,
+			length:   1,
+			nullable: true,
+		},
+		AutoIncrement: false,
+	}
+}
+
+// NewIntegerColumnForTable creates an IntegerColumn for table definition
+func NewIntegerColumnForTable(name string, nullable bool, autoIncrement bool, options ...ColumnOption) *IntegerColumn {
+	column := &IntegerColumn{
 		BaseColumn: &BaseColumn{
 			name:     name,
 			value:    nil,
@@ -204,6 +246,8 @@ func NewIntegerColumnForTable(name string, nullable bool, autoIncrement bool) *I
 		},
 		AutoIncrement: autoIncrement,
 	}
+	column.apply(options)
+	return column
 }
 
 // GetType returns the MySQL column type
@@ -216,6 +260,59 @@ func (c *IntegerColumn) IsAutoIncrement() bool {
 	return c.AutoIncrement
 }
 
+// DoubleColumn represents a DOUBLE column in a MySQL table
+type DoubleColumn struct {
+	*BaseColumn
+}
+
+// NewDoubleColumn creates a new DoubleColumn with a value
+func NewDoubleColumn(name string, value float64) *DoubleColumn {
+	return &DoubleColumn{
+		BaseColumn: &BaseColumn{
+			name:     name,
+			value:    value,
+			length:   1,
+			nullable: false,
+		},
+	}
+}
+
+// NewNullableDoubleColumn creates a DoubleColumn which is NULL when value is nil
+func NewNullableDoubleColumn(name string, value *float64) *DoubleColumn {
+	var columnValue interface{}
+	if value != nil {
+		columnValue = *value
+	}
+
+	return &DoubleColumn{
+		BaseColumn: &BaseColumn{
+			name:     name,
+			value:    columnValue,
+			length:   1,
+			nullable: true,
+		},
+	}
+}
+
+// NewDoubleColumnForTable creates a DoubleColumn for table definition
+func NewDoubleColumnForTable(name string, nullable bool, options ...ColumnOption) *DoubleColumn {
+	column := &DoubleColumn{
+		BaseColumn: &BaseColumn{
+			name:     name,
+			value:    nil,
+			length:   1,
+			nullable: nullable,
+		},
+	}
+	column.apply(options)
+	return column
+}
+
+// GetType returns the MySQL column type
+func (c *DoubleColumn) GetType() string {
+	return "DOUBLE"
+}
+
 // UUIDColumn represents a UUID stored as VARCHAR(36)
 type UUIDColumn struct {
 	*BaseColumn
@@ -233,9 +330,26 @@ func NewUUIDColumn(name string, value uuid.UUID) *UUIDColumn {
 	}
 }
 
-// NewUUIDColumnForTable creates a UUIDColumn for table definition
-func NewUUIDColumnForTable(name string, nullable bool, length int) *UUIDColumn {
+// NewNullableUUIDColumn creates a UUIDColumn which is NULL when value is nil
+func NewNullableUUIDColumn(name string, value *uuid.UUID) *UUIDColumn {
+	var columnValue interface{}
+	if value != nil {
+		columnValue = *value
+	}
+
 	return &UUIDColumn{
+		BaseColumn: &BaseColumn{
+			name:     name,
+			value:    columnValue,
+			length:   1,
+			nullable: true,
+		},
+	}
+}
+
+// NewUUIDColumnForTable creates a UUIDColumn for table definition
+func NewUUIDColumnForTable(name string, nullable bool, length int, options ...ColumnOption) *UUIDColumn {
+	column := &UUIDColumn{
 		BaseColumn: &BaseColumn{
 			name:     name,
 			value:    nil,
@@ -243,6 +357,8 @@ func NewUUIDColumnForTable(name string, nullable bool, length int) *UUIDColumn {
 			nullable: nullable,
 		},
 	}
+	column.apply(options)
+	return column
 }
 
 // GetType returns the MySQL column type
@@ -268,8 +384,8 @@ func NewBoolColumn(name string, value bool) *BoolColumn {
 }
 
 // NewBoolColumnForTable creates a BoolColumn for table definition
-func NewBoolColumnForTable(name string, nullable bool, length int) *BoolColumn {
-	return &BoolColumn{
+func NewBoolColumnForTable(name string, nullable bool, length int, options ...ColumnOption) *BoolColumn {
+	column := &BoolColumn{
 		BaseColumn: &BaseColumn{
 			name:     name,
 			value:    nil,
@@ -277,6 +393,8 @@ func NewBoolColumnForTable(name string, nullable bool, length int) *BoolColumn {
 			nullable: nullable,
 		},
 	}
+	column.apply(options)
+	return column
 }
 
 // GetType returns the MySQL column type
@@ -301,9 +419,26 @@ func NewVarcharColumn(name string, value string) *VarcharColumn {
 	}
 }
 
-// NewVarcharColumnForTable creates a VarcharColumn for table definition
-func NewVarcharColumnForTable(name string, nullable bool, length int) *VarcharColumn {
+// NewNullableVarcharColumn creates a VarcharColumn which is NULL when value is nil
+func NewNullableVarcharColumn(name string, value *string) *VarcharColumn {
+	var columnValue interface{}
+	if value != nil {
+		columnValue = *value
+	}
+
 	return &VarcharColumn{
+		BaseColumn: &BaseColumn{
+			name:     name,
+			value:    columnValue,
+			length:   1,
+			nullable: true,
+		},
+	}
+}
+
+// NewVarcharColumnForTable creates a VarcharColumn for table definition
+func NewVarcharColumnForTable(name string, nullable bool, length int, options ...ColumnOption) *VarcharColumn {
+	column := &VarcharColumn{
 		BaseColumn: &BaseColumn{
 			name:     name,
 			value:    nil,
@@ -311,6 +446,8 @@ func NewVarcharColumnForTable(name string, nullable bool, length int) *VarcharCo
 			nullable: nullable,
 		},
 	}
+	column.apply(options)
+	return column
 }
 
 // GetType returns the MySQL column type
@@ -335,85 +472,196 @@ func NewTextColumn(name string, value string) *TextColumn {
 	}
 }
 
+// NewTextColumnForTable creates a TextColumn for table definition
+func NewTextColumnForTable(name string, nullable bool, options ...ColumnOption) *TextColumn {
+	column := &TextColumn{
+		BaseColumn: &BaseColumn{
+			name:     name,
+			value:    nil,
+			length:   1,
+			nullable: nullable,
+		},
+	}
+	column.apply(options)
+	return column
+}
+
 // GetType returns the MySQL column type
 func (c *TextColumn) GetType() string {
 	return "TEXT"
 }
 
-// Table defines the schema of a MySQL table
-type Table struct {
-	Name        string
-	Columns     *[]Column
-	PrimaryKeys *[]string
+// DateTimeColumn represents a DATETIME column in a MySQL table
+type DateTimeColumn struct {
+	*BaseColumn
 }
 
-// GetOrCreateTableQuery generates a SQL query to create a table
-func (t *Table) GetOrCreateTableQuery(createIfExists bool) (string, error) {
-	if len(*t.Columns) < 1 {
-		return "", fmt.Errorf("columns has to be greater or equal to 1")
+// NewDateTimeColumn creates a new DateTimeColumn with a value
+func NewDateTimeColumn(name string, value time.Time) *DateTimeColumn {
+	return &DateTimeColumn{
+		BaseColumn: &BaseColumn{
+			name:     name,
+			value:    value,
+			length:   1,
+			nullable: false,
+		},
 	}
+}
 
-	createPrefix := "CREATE TABLE "
-	if !createIfExists {
-		createPrefix += "IF NOT EXISTS "
+// NewNullableDateTimeColumn creates a DateTimeColumn which is NULL when value is nil. Times are stored in UTC
+func NewNullableDateTimeColumn(name string, value *time.Time) *DateTimeColumn {
+	var columnValue interface{}
+	if value != nil {
+		columnValue = value.UTC()
 	}
 
-	sb := strings.Builder{}
-	sb.WriteString(createPrefix)
-	sb.WriteString("`")
-	sb.WriteString(t.Name)
-	sb.WriteString("`(")
+	return &DateTimeColumn{
+		BaseColumn: &BaseColumn{
+			name:     name,
+			value:    columnValue,
+			length:   1,
+			nullable: true,
+		},
+	}
+}
 
-	columns := *t.Columns
+// NewDateTimeColumnForTable creates a DateTimeColumn for table definition
+func NewDateTimeColumnForTable(name string, nullable bool, options ...ColumnOption) *DateTimeColumn {
+	column := &DateTimeColumn{
+		BaseColumn: &BaseColumn{
+			name:     name,
+			value:    nil,
+			length:   1,
+			nullable: nullable,
+		},
+	}
+	column.apply(options)
+	return column
+}
 
-	for _, column := range columns {
-		sb.WriteString(column.GetName())
-		sb.WriteString(" ")
-		sb.WriteString(column.GetType())
-		sb.WriteString(" ")
-
-		columnLength := column.GetLength()
-		if columnLength > 1 {
-			sb.WriteString(fmt.Sprintf("(%d)", columnLength))
-		}
+// GetType returns the MySQL column type
+func (c *DateTimeColumn) GetType() string {
+	return "DATETIME"
+}
 
-		if intColumn, ok := column.(*IntegerColumn); ok {
-			if intColumn.IsAutoIncrement() {
-				if intColumn.IsNullable() {
-					return "", fmt.Errorf("auto incrementing cannot be null")
-				}
-				sb.WriteString(" AUTO_INCREMENT")
-			}
-		}
+// DecimalColumn represents a DECIMAL column in a MySQL table, values are strings so they stay exact
+type DecimalColumn struct {
+	*BaseColumn
+	precision int
+	scale     int
+}
 
-		if !column.IsNullable() {
-			sb.WriteString(" NOT NULL")
-		}
+// NewDecimalColumn creates a new DecimalColumn with a value such as "12.50"
+func NewDecimalColumn(name string, value string) *DecimalColumn {
+	return &DecimalColumn{
+		BaseColumn: &BaseColumn{
+			name:     name,
+			value:    value,
+			length:   1,
+			nullable: false,
+		},
+	}
+}
+
+// NewDecimalColumnForTable creates a DecimalColumn for table definition with precision digits, scale of them after
+// the decimal point
+func NewDecimalColumnForTable(name string, nullable bool, precision int, scale int, options ...ColumnOption) *DecimalColumn {
+	column := &DecimalColumn{
+		BaseColumn: &BaseColumn{
+			name:     name,
+			value:    nil,
+			length:   1,
+			nullable: nullable,
+		},
+		precision: precision,
+		scale:     scale,
+	}
+	column.apply(options)
+	return column
+}
+
+// GetType returns the MySQL column type
+func (c *DecimalColumn) GetType() string {
+	return fmt.Sprintf("DECIMAL(%d,%d)", c.precision, c.scale)
+}
 
-		sb.WriteString(",")
+// EnumColumn represents an ENUM column in a MySQL table
+type EnumColumn struct {
+	*BaseColumn
+	values []string
+}
+
+// NewEnumColumn creates a new EnumColumn with a value
+func NewEnumColumn(name string, value string) *EnumColumn {
+	return &EnumColumn{
+		BaseColumn: &BaseColumn{
+			name:     name,
+			value:    value,
+			length:   1,
+			nullable: false,
+		},
 	}
+}
 
-	primaryKeys := *t.PrimaryKeys
+// NewEnumColumnForTable creates an EnumColumn for table definition which can only hold the given values
+func NewEnumColumnForTable(name string, nullable bool, values []string, options ...ColumnOption) *EnumColumn {
+	column := &EnumColumn{
+		BaseColumn: &BaseColumn{
+			name:     name,
+			value:    nil,
+			length:   1,
+			nullable: nullable,
+		},
+		values: values,
+	}
+	column.apply(options)
+	return column
+}
 
-	if len(primaryKeys) > 0 {
-		sb.WriteString("PRIMARY KEY (")
-		for i, key := range primaryKeys {
-			sb.WriteString(key)
-			if i < len(primaryKeys)-1 {
-				sb.WriteString(", ")
-			}
-		}
-		sb.WriteString(")")
-	} else {
-		// Remove the trailing comma if there are no primary keys
-		query := sb.String()
-		query = query[:len(query)-1]
-		sb = strings.Builder{}
-		sb.WriteString(query)
+// GetType returns the MySQL column type
+func (c *EnumColumn) GetType() string {
+	values := make([]string, 0, len(c.values))
+	for _, value := range c.values {
+		values = append(values, quote(value))
+	}
+	return "ENUM(" + strings.Join(values, ",") + ")"
+}
+
+// JSONColumn represents a JSON column in a MySQL table
+type JSONColumn struct {
+	*BaseColumn
+}
+
+// NewJSONColumn creates a new JSONColumn with an encoded value. It's sent as a string, MySQL refuses JSON sent as
+// binary
+func NewJSONColumn(name string, value []byte) *JSONColumn {
+	return &JSONColumn{
+		BaseColumn: &BaseColumn{
+			name:     name,
+			value:    string(value),
+			length:   1,
+			nullable: false,
+		},
 	}
+}
 
-	sb.WriteString(");")
-	return sb.String(), nil
+// NewJSONColumnForTable creates a JSONColumn for table definition
+func NewJSONColumnForTable(name string, nullable bool, options ...ColumnOption) *JSONColumn {
+	column := &JSONColumn{
+		BaseColumn: &BaseColumn{
+			name:     name,
+			value:    nil,
+			length:   1,
+			nullable: nullable,
+		},
+	}
+	column.apply(options)
+	return column
+}
+
+// GetType returns the MySQL column type
+func (c *JSONColumn) GetType() string {
+	return "JSON"
 }
 
 // InsertOptions represents options for insert execution.
@@ -448,22 +696,57 @@ func NewQueryOptions(OnComplete func(rows *sql.Rows), onError func(err error), c
 	}
 }
 
+// StartTransaction starts a transaction the caller must commit or roll back.
+//
+// Deprecated: use WithTx, which can't leak the transaction and joins the caller's
 func (r *Repository) StartTransaction() (*sql.Tx, error) {
-	return r.Database.database.Begin()
+	return r.StartTransactionContext(context.Background())
+}
+
+// StartTransactionContext starts a transaction which is rolled back if ctx is cancelled or the Transaction timeout
+// passes before it's committed.
+//
+// Deprecated: use WithTx
+func (r *Repository) StartTransactionContext(ctx context.Context) (*sql.Tx, error) {
+	ctx, cancel := r.Database.withTimeout(ctx, Transaction)
+
+	tx, err := r.Database.database.BeginTx(ctx, nil)
+	if err != nil {
+		cancel()
+		return nil, contextError(ctx, err)
+	}
+	return tx, nil
 }
 
 func (r *Repository) AddExecuteTransaction(tx *sql.Tx, query string, columns []Column) (sql.Result, error) {
-	questionMarkCount := strings.Count(query, "?")
-	if questionMarkCount != len(columns) {
-		return nil, fmt.Errorf("invalid amount of columns for query \"%s\"", query)
+	return r.AddExecuteTransactionContext(context.Background(), tx, query, columns)
+}
+
+// AddExecuteTransactionContext runs a statement inside the transaction, the transaction's own deadline still applies
+func (r *Repository) AddExecuteTransactionContext(ctx context.Context, tx *sql.Tx, query string, columns []Column) (sql.Result, error) {
+	args, err := arguments(query, columns)
+	if err != nil {
+		return nil, err
 	}
 
-	args := make([]interface{}, 0)
-	for _, column := range columns {
-		args = append(args, column.GetValue())
+	result, err := tx.ExecContext(ctx, query, args...)
+	return result, contextError(ctx, err)
+}
+
+// AddQueryTransaction runs a query inside the transaction, e.g. a SELECT ... FOR UPDATE
+func (r *Repository) AddQueryTransaction(tx *sql.Tx, query string, columns []Column) (*sql.Rows, error) {
+	return r.AddQueryTransactionContext(context.Background(), tx, query, columns)
+}
+
+// AddQueryTransactionContext runs a query inside the transaction, the transaction's own deadline still applies
+func (r *Repository) AddQueryTransactionContext(ctx context.Context, tx *sql.Tx, query string, columns []Column) (*sql.Rows, error) {
+	args, err := arguments(query, columns)
+	if err != nil {
+		return nil, err
 	}
 
-	return tx.Exec(query, args...)
+	rows, err := tx.QueryContext(ctx, query, args...)
+	return rows, contextError(ctx, err)
 }
 
 func (r *Repository) CommitTransaction(tx *sql.Tx) error {
@@ -472,9 +755,15 @@ func (r *Repository) CommitTransaction(tx *sql.Tx) error {
 
 // ExecuteInsert executes a SQL insert with the given columns and returns the number of affected rows
 func (r *Repository) ExecuteInsert(query string, columns []Column, options InsertOptions) (int64, error) {
-	questionMarkCount := strings.Count(query, "?")
-	if questionMarkCount != len(columns) {
-		return 0, fmt.Errorf("invalid amount of columns for query \"%s\"", query)
+	return r.ExecuteInsertContext(context.Background(), query, columns, options)
+}
+
+// ExecuteInsertContext is ExecuteInsert bounded by ctx and the Exec timeout. With a context from WithTx the
+// statement runs in that transaction
+func (r *Repository) ExecuteInsertContext(ctx context.Context, query string, columns []Column, options InsertOptions) (int64, error) {
+	args, err := arguments(query, columns)
+	if err != nil {
+		return 0, err
 	}
 
 	database := options.connection
@@ -482,22 +771,17 @@ func (r *Repository) ExecuteInsert(query string, columns []Column, options Inser
 		database = r.Database.database
 	}
 
-	stmt, err := database.Prepare(query)
-	if err != nil {
-		if options.OnError != nil {
-			options.OnError(err)
-		}
-		return 0, err
-	}
-	defer stmt.Close()
+	ctx, cancel := r.Database.withTimeout(ctx, Exec)
+	defer cancel()
 
-	args := make([]interface{}, 0)
-	for _, column := range columns {
-		args = append(args, column.GetValue())
+	var result sql.Result
+	if tx := ambientTx(ctx); tx != nil && options.connection == nil {
+		result, err = tx.ExecContext(ctx, query, args...)
+	} else {
+		result, err = execStatement(ctx, database, query, args)
 	}
-
-	result, err := stmt.Exec(args...)
 	if err != nil {
+		err = contextError(ctx, err)
 		if options.OnError != nil {
 			options.OnError(err)
 		}
@@ -521,10 +805,18 @@ func (r *Repository) ExecuteInsert(query string, columns []Column, options Inser
 
 // ExecuteQuery executes a SQL query and returns the result rows.
 func (r *Repository) ExecuteQuery(query string, columns []Column, options QueryOptions) (*sql.Rows, error) {
+	return r.ExecuteQueryContext(context.Background(), query, columns, options)
+}
+
+// ExecuteQueryContext is ExecuteQuery bounded by ctx and the Query timeout. The deadline covers reading the rows,
+// once it passes the rows are closed and rows.Err() returns context.DeadlineExceeded. With a context from WithTx
+// the query runs in that transaction
+func (r *Repository) ExecuteQueryContext(ctx context.Context, query string, columns []Column, options QueryOptions) (*sql.Rows, error) {
+	var args []interface{}
 	if columns != nil {
-		questionMarkCount := strings.Count(query, "?")
-		if questionMarkCount != len(columns) {
-			return nil, fmt.Errorf("invalid amount of columns for query \"%s\"", query)
+		var err error
+		if args, err = arguments(query, columns); err != nil {
+			return nil, err
 		}
 	}
 
@@ -536,23 +828,18 @@ func (r *Repository) ExecuteQuery(query string, columns []Column, options QueryO
 		database = r.Database.database
 	}
 
-	stmt, err := database.Prepare(query)
-	if err != nil {
-		if options.OnError != nil {
-			options.OnError(err)
-		}
-		return nil, err
-	}
-	defer stmt.Close()
+	ctx, cancel := r.Database.withTimeout(ctx, Query)
 
-	args := make([]interface{}, 0)
-	if columns != nil {
-		for _, column := range columns {
-			args = append(args, column.GetValue())
-		}
+	var rows *sql.Rows
+	var err error
+	if tx := ambientTx(ctx); tx != nil && options.Connection == nil {
+		rows, err = tx.QueryContext(ctx, query, args...)
+	} else {
+		rows, err = queryStatement(ctx, database, query, args)
 	}
-	rows, err := stmt.Query(args...)
 	if err != nil {
+		cancel()
+		err = contextError(ctx, err)
 		if options.OnError != nil {
 			options.OnError(err)
 		}
@@ -565,3 +852,52 @@ func (r *Repository) ExecuteQuery(query string, columns []Column, options QueryO
 
 	return rows, nil
 }
+
+// ExecuteSchema runs a statement creating or altering tables, bounded by the Schema timeout rather than the much
+// shorter Query one
+func (r *Repository) ExecuteSchema(query string) error {
+	if r.Database.database == nil {
+		return nil
+	}
+
+	ctx, cancel := r.Database.withTimeout(context.Background(), Schema)
+	defer cancel()
+
+	_, err := r.Database.database.ExecContext(ctx, query)
+	return contextError(ctx, err)
+}
+
+// arguments returns the values of the columns, checking there's one for each placeholder in the query
+func arguments(query string, columns []Column) ([]interface{}, error) {
+	if strings.Count(query, "?") != len(columns) {
+		return nil, fmt.Errorf("invalid amount of columns for query \"%s\"", query)
+	}
+
+	args := make([]interface{}, 0, len(columns))
+	for _, column := range columns {
+		args = append(args, column.GetValue())
+	}
+	return args, nil
+}
+
+// queryStatement prepares and runs the query outside a transaction. The statement is closed once the rows are
+func queryStatement(ctx context.Context, database *sql.DB, query string, args []interface{}) (*sql.Rows, error) {
+	stmt, err := database.PrepareContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	return stmt.QueryContext(ctx, args...)
+}
+
+// execStatement prepares and runs the statement outside a transaction
+func execStatement(ctx context.Context, database *sql.DB, query string, args []interface{}) (sql.Result, error) {
+	stmt, err := database.PrepareContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	return stmt.ExecContext(ctx, args...)
+}
@@ -0,0 +1,531 @@
+package mysql
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Tables can be declared with Table rather than written as SQL. GetOrCreateTableQuery creates the table, and
+// SyncTable brings an existing table up to date by comparing it with information_schema. Syncing only adds and
+// changes, columns and indexes no longer declared are left for a hand written migration so data isn't dropped by
+// mistake
+
+// ColumnOption sets part of a column's definition that's only used when creating tables
+type ColumnOption func(*BaseColumn)
+
+// Default sets the column's DEFAULT to an SQL expression, e.g. "CURRENT_TIMESTAMP" or "'UTC'"
+func Default(expression string) ColumnOption {
+	return func(column *BaseColumn) {
+		column.defaultValue = expression
+	}
+}
+
+// OnUpdate sets the value the column takes whenever the row is updated, e.g. "CURRENT_TIMESTAMP"
+func OnUpdate(expression string) ColumnOption {
+	return func(column *BaseColumn) {
+		column.onUpdate = expression
+	}
+}
+
+// Unique gives the column a UNIQUE constraint of its own
+func Unique() ColumnOption {
+	return func(column *BaseColumn) {
+		column.unique = true
+	}
+}
+
+func (c *BaseColumn) apply(options []ColumnOption) {
+	for _, option := range options {
+		option(c)
+	}
+}
+
+func (c *BaseColumn) base() *BaseColumn {
+	return c
+}
+
+// baseOf returns the column's BaseColumn, which columns defined outside this package don't have
+func baseOf(column Column) *BaseColumn {
+	if based, ok := column.(interface{ base() *BaseColumn }); ok {
+		return based.base()
+	}
+	return &BaseColumn{}
+}
+
+// ReferentialAction is what happens to rows referencing a deleted row
+type ReferentialAction string
+
+const (
+	// Restrict refuses to delete a row that's referenced, the default
+	Restrict ReferentialAction = "RESTRICT"
+	// Cascade deletes the rows referencing it too
+	Cascade ReferentialAction = "CASCADE"
+	// SetNull sets the referencing columns to NULL
+	SetNull ReferentialAction = "SET NULL"
+)
+
+// Index is a secondary index, Unique makes it a UNIQUE constraint. MySQL names it when Name is empty
+type Index struct {
+	Name    string
+	Columns []string
+	Unique  bool
+}
+
+// ForeignKey references another table's rows. MySQL names it when Name is empty
+type ForeignKey struct {
+	Name              string
+	Columns           []string
+	ReferencedTable   string
+	ReferencedColumns []string
+	OnDelete          ReferentialAction
+}
+
+// Table defines the schema of a MySQL table
+type Table struct {
+	Name        string
+	Columns     *[]Column
+	PrimaryKeys *[]string
+	Indexes     []Index
+	ForeignKeys []ForeignKey
+}
+
+// GetOrCreateTableQuery generates a SQL query to create the table, which does nothing if the table exists when
+// ifNotExists is set
+func (t *Table) GetOrCreateTableQuery(ifNotExists bool) (string, error) {
+	if t.Columns == nil || len(*t.Columns) < 1 {
+		return "", fmt.Errorf("columns has to be greater or equal to 1")
+	}
+
+	createPrefix := "CREATE TABLE "
+	if ifNotExists {
+		createPrefix += "IF NOT EXISTS "
+	}
+
+	definitions := make([]string, 0, len(*t.Columns)+len(t.Indexes)+len(t.ForeignKeys)+1)
+	for _, column := range *t.Columns {
+		definition, err := columnDefinition(column)
+		if err != nil {
+			return "", err
+		}
+		if baseOf(column).unique {
+			definition += " UNIQUE"
+		}
+		definitions = append(definitions, definition)
+	}
+
+	if t.PrimaryKeys != nil && len(*t.PrimaryKeys) > 0 {
+		definitions = append(definitions, "PRIMARY KEY ("+identifiers(*t.PrimaryKeys)+")")
+	}
+	for _, index := range t.Indexes {
+		definitions = append(definitions, indexDefinition(index))
+	}
+	for _, foreignKey := range t.ForeignKeys {
+		definitions = append(definitions, foreignKeyDefinition(foreignKey))
+	}
+
+	return createPrefix + identifier(t.Name) + "(" + strings.Join(definitions, ", ") + ");", nil
+}
+
+// sizedTypes take their length in brackets, the length of other types is part of GetType or not needed
+var sizedTypes = map[string]bool{"VARCHAR": true, "CHAR": true}
+
+// columnType is the column's type with its length
+func columnType(column Column) string {
+	columnType := column.GetType()
+	if length := column.GetLength(); length > 1 && sizedTypes[columnType] {
+		columnType += fmt.Sprintf("(%d)", length)
+	}
+	return columnType
+}
+
+// columnDefinition is the column as written in CREATE TABLE and ALTER TABLE, without its UNIQUE constraint
+func columnDefinition(column Column) (string, error) {
+	sb := strings.Builder{}
+	sb.WriteString(identifier(column.GetName()))
+	sb.WriteString(" ")
+	sb.WriteString(columnType(column))
+
+	if !column.IsNullable() {
+		sb.WriteString(" NOT NULL")
+	}
+
+	if intColumn, ok := column.(*IntegerColumn); ok && intColumn.IsAutoIncrement() {
+		if intColumn.IsNullable() {
+			return "", fmt.Errorf("auto incrementing cannot be null")
+		}
+		sb.WriteString(" AUTO_INCREMENT")
+	}
+
+	base := baseOf(column)
+	if base.defaultValue != "" {
+		sb.WriteString(" DEFAULT ")
+		sb.WriteString(base.defaultValue)
+	}
+	if base.onUpdate != "" {
+		sb.WriteString(" ON UPDATE ")
+		sb.WriteString(base.onUpdate)
+	}
+	return sb.String(), nil
+}
+
+func indexDefinition(index Index) string {
+	definition := "INDEX "
+	if index.Unique {
+		definition = "UNIQUE INDEX "
+	}
+	if index.Name != "" {
+		definition += identifier(index.Name) + " "
+	}
+	return definition + "(" + identifiers(index.Columns) + ")"
+}
+
+func foreignKeyDefinition(foreignKey ForeignKey) string {
+	definition := ""
+	if foreignKey.Name != "" {
+		definition = "CONSTRAINT " + identifier(foreignKey.Name) + " "
+	}
+	definition += "FOREIGN KEY (" + identifiers(foreignKey.Columns) + ") REFERENCES " +
+		identifier(foreignKey.ReferencedTable) + "(" + identifiers(foreignKey.ReferencedColumns) + ")"
+	if foreignKey.OnDelete != "" {
+		definition += " ON DELETE " + string(foreignKey.OnDelete)
+	}
+	return definition
+}
+
+func identifier(name string) string {
+	return "`" + strings.ReplaceAll(name, "`", "``") + "`"
+}
+
+func identifiers(names []string) string {
+	quoted := make([]string, 0, len(names))
+	for _, name := range names {
+		quoted = append(quoted, identifier(name))
+	}
+	return strings.Join(quoted, ", ")
+}
+
+// quote makes a string literal
+func quote(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", "''") + "'"
+}
+
+// ColumnSchema is a column as information_schema describes it
+type ColumnSchema struct {
+	Name     string  `db:"name"`
+	Type     string  `db:"type"`
+	Nullable bool    `db:"nullable"`
+	Default  *string `db:"defaultValue"`
+	// Extra has auto_increment and on update
+	Extra string `db:"extra"`
+}
+
+// IndexSchema is an index as information_schema describes it
+type IndexSchema struct {
+	Name    string
+	Columns []string
+	Unique  bool
+}
+
+// ForeignKeySchema is a foreign key as information_schema describes it
+type ForeignKeySchema struct {
+	Name              string
+	Columns           []string
+	ReferencedTable   string
+	ReferencedColumns []string
+	DeleteRule        string
+}
+
+// TableSchema is a table as it exists in the database, it has no columns if the table doesn't exist
+type TableSchema struct {
+	Columns     []ColumnSchema
+	Indexes     []IndexSchema
+	ForeignKeys []ForeignKeySchema
+}
+
+const describeColumnsQuery = `
+SELECT COLUMN_NAME AS name, COLUMN_TYPE AS type, IS_NULLABLE = 'YES' AS nullable, COLUMN_DEFAULT AS defaultValue,
+       EXTRA AS extra
+FROM information_schema.COLUMNS
+WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ?
+ORDER BY ORDINAL_POSITION`
+
+const describeIndexesQuery = `
+SELECT INDEX_NAME AS name, NON_UNIQUE = 0 AS isUnique, COLUMN_NAME AS columnName
+FROM information_schema.STATISTICS
+WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ? AND INDEX_NAME <> 'PRIMARY'
+ORDER BY INDEX_NAME, SEQ_IN_INDEX`
+
+const describeForeignKeysQuery = `
+SELECT kcu.CONSTRAINT_NAME AS name, kcu.COLUMN_NAME AS columnName, kcu.REFERENCED_TABLE_NAME AS referencedTable,
+       kcu.REFERENCED_COLUMN_NAME AS referencedColumn, rc.DELETE_RULE AS deleteRule
+FROM information_schema.KEY_COLUMN_USAGE kcu
+INNER JOIN information_schema.REFERENTIAL_CONSTRAINTS rc
+  ON rc.CONSTRAINT_SCHEMA = kcu.CONSTRAINT_SCHEMA AND rc.CONSTRAINT_NAME = kcu.CONSTRAINT_NAME
+WHERE kcu.TABLE_SCHEMA = DATABASE() AND kcu.TABLE_NAME = ?
+ORDER BY kcu.CONSTRAINT_NAME, kcu.ORDINAL_POSITION`
+
+// DescribeTable reads the table's columns, indexes and foreign keys from information_schema
+func (r *Repository) DescribeTable(name string) (*TableSchema, error) {
+	table := NewVarcharColumn("table", name)
+	schema := &TableSchema{}
+
+	rows, err := r.ExecuteQuery(describeColumnsQuery, []Column{table}, QueryOptions{})
+	if err != nil {
+		return nil, err
+	}
+	if schema.Columns, err = ScanAll[ColumnSchema](rows); err != nil {
+		return nil, err
+	}
+
+	type indexRow struct {
+		Name       string `db:"name"`
+		Unique     bool   `db:"isUnique"`
+		ColumnName string `db:"columnName"`
+	}
+	rows, err = r.ExecuteQuery(describeIndexesQuery, []Column{table}, QueryOptions{})
+	if err != nil {
+		return nil, err
+	}
+	indexRows, err := ScanAll[indexRow](rows)
+	if err != nil {
+		return nil, err
+	}
+	schema.Indexes = OneToMany(indexRows,
+		func(row *indexRow) string { return row.Name },
+		func(row *indexRow) IndexSchema { return IndexSchema{Name: row.Name, Unique: row.Unique} },
+		func(index *IndexSchema, row *indexRow) { index.Columns = append(index.Columns, row.ColumnName) })
+
+	type foreignKeyRow struct {
+		Name             string `db:"name"`
+		ColumnName       string `db:"columnName"`
+		ReferencedTable  string `db:"referencedTable"`
+		ReferencedColumn string `db:"referencedColumn"`
+		DeleteRule       string `db:"deleteRule"`
+	}
+	rows, err = r.ExecuteQuery(describeForeignKeysQuery, []Column{table}, QueryOptions{})
+	if err != nil {
+		return nil, err
+	}
+	foreignKeyRows, err := ScanAll[foreignKeyRow](rows)
+	if err != nil {
+		return nil, err
+	}
+	schema.ForeignKeys = OneToMany(foreignKeyRows,
+		func(row *foreignKeyRow) string { return row.Name },
+		func(row *foreignKeyRow) ForeignKeySchema {
+			return ForeignKeySchema{Name: row.Name, ReferencedTable: row.ReferencedTable, DeleteRule: row.DeleteRule}
+		},
+		func(foreignKey *ForeignKeySchema, row *foreignKeyRow) {
+			foreignKey.Columns = append(foreignKey.Columns, row.ColumnName)
+			foreignKey.ReferencedColumns = append(foreignKey.ReferencedColumns, row.ReferencedColumn)
+		})
+
+	return schema, nil
+}
+
+// SyncTable creates the table, or adds and changes the columns, indexes and foreign keys of the existing table to
+// match the declaration
+func (r *Repository) SyncTable(t *Table) error {
+	schema, err := r.DescribeTable(t.Name)
+	if err != nil {
+		return err
+	}
+
+	statements, err := t.Diff(schema)
+	if err != nil {
+		return err
+	}
+
+	for _, statement := range statements {
+		if err := r.ExecuteSchema(statement); err != nil {
+			return fmt.Errorf("syncing %s: %w", t.Name, err)
+		}
+	}
+	return nil
+}
+
+// Diff returns the statements that bring the existing table up to date with the declaration, in the order they
+// must run. A table without columns doesn't exist and is created
+func (t *Table) Diff(existing *TableSchema) ([]string, error) {
+	if existing == nil || len(existing.Columns) == 0 {
+		query, err := t.GetOrCreateTableQuery(true)
+		if err != nil {
+			return nil, err
+		}
+		return []string{query}, nil
+	}
+
+	alter := "ALTER TABLE " + identifier(t.Name) + " "
+	var statements []string
+
+	existingColumns := map[string]ColumnSchema{}
+	for _, column := range existing.Columns {
+		existingColumns[strings.ToLower(column.Name)] = column
+	}
+
+	indexes := t.Indexes
+	for _, column := range *t.Columns {
+		definition, err := columnDefinition(column)
+		if err != nil {
+			return nil, err
+		}
+
+		current, exists := existingColumns[strings.ToLower(column.GetName())]
+		switch {
+		case !exists:
+			statements = append(statements, alter+"ADD COLUMN "+definition)
+		case !columnMatches(column, current):
+			statements = append(statements, alter+"MODIFY COLUMN "+definition)
+		}
+
+		if baseOf(column).unique {
+			indexes = append(indexes, Index{Columns: []string{column.GetName()}, Unique: true})
+		}
+	}
+
+	for _, index := range indexes {
+		if current := findIndex(existing.Indexes, index); current != nil {
+			if current.Unique == index.Unique && sameNames(current.Columns, index.Columns) {
+				continue
+			}
+			statements = append(statements, alter+"DROP INDEX "+identifier(current.Name))
+		}
+		statements = append(statements, alter+"ADD "+indexDefinition(index))
+	}
+
+	for _, foreignKey := range t.ForeignKeys {
+		if current := findForeignKey(existing.ForeignKeys, foreignKey); current != nil {
+			if deleteRule(current.DeleteRule) == deleteRule(string(foreignKey.OnDelete)) {
+				continue
+			}
+			// A constraint can't be dropped and added by the same ALTER TABLE
+			statements = append(statements, alter+"DROP FOREIGN KEY "+identifier(current.Name))
+		}
+		statements = append(statements, alter+"ADD "+foreignKeyDefinition(foreignKey))
+	}
+
+	return statements, nil
+}
+
+// findIndex returns the existing index with the declared index's name, or one on the same columns if it's unnamed
+func findIndex(indexes []IndexSchema, index Index) *IndexSchema {
+	for i, current := range indexes {
+		if index.Name != "" && strings.EqualFold(current.Name, index.Name) {
+			return &indexes[i]
+		}
+		if index.Name == "" && current.Unique == index.Unique && sameNames(current.Columns, index.Columns) {
+			return &indexes[i]
+		}
+	}
+	return nil
+}
+
+// findForeignKey returns the existing foreign key with the declared key's name, or the one referencing the same
+// columns if it's unnamed
+func findForeignKey(foreignKeys []ForeignKeySchema, foreignKey ForeignKey) *ForeignKeySchema {
+	for i, current := range foreignKeys {
+		if foreignKey.Name != "" {
+			if strings.EqualFold(current.Name, foreignKey.Name) {
+				return &foreignKeys[i]
+			}
+			continue
+		}
+		if sameNames(current.Columns, foreignKey.Columns) && strings.EqualFold(current.ReferencedTable, foreignKey.ReferencedTable) &&
+			sameNames(current.ReferencedColumns, foreignKey.ReferencedColumns) {
+			return &foreignKeys[i]
+		}
+	}
+	return nil
+}
+
+// deleteRule treats MySQL's NO ACTION as the RESTRICT it is, and no action given as the default
+func deleteRule(rule string) string {
+	rule = strings.ToUpper(rule)
+	if rule == "" || rule == "NO ACTION" {
+		return string(Restrict)
+	}
+	return rule
+}
+
+func sameNames(a []string, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !strings.EqualFold(a[i], b[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// displayWidth is the width older MySQL versions show on integer types, e.g. int(11). BOOL is tinyint(1)
+var displayWidth = regexp.MustCompile(`^(smallint|mediumint|int|bigint)\(\d+\)`)
+
+// columnMatches reports whether the existing column has the declared type, nullability, default and ON UPDATE
+func columnMatches(column Column, current ColumnSchema) bool {
+	if normaliseType(columnType(column)) != normaliseType(displayWidth.ReplaceAllString(current.Type, "$1")) {
+		return false
+	}
+	if column.IsNullable() != current.Nullable {
+		return false
+	}
+
+	base := baseOf(column)
+	if normaliseDefault(base.defaultValue) != normaliseDefault(valueOrNull(current.Default)) {
+		return false
+	}
+
+	extra := strings.ToUpper(current.Extra)
+	hasOnUpdate := strings.Contains(extra, "ON UPDATE")
+	if base.onUpdate == "" {
+		return !hasOnUpdate
+	}
+	return hasOnUpdate && strings.Contains(extra, "ON UPDATE "+strings.ToUpper(base.onUpdate))
+}
+
+// normaliseType lower cases the type name but not the ENUM values, and spells BOOL the way MySQL stores it
+func normaliseType(columnType string) string {
+	name, rest, _ := strings.Cut(columnType, "(")
+	name = strings.ToLower(name)
+	if rest != "" {
+		rest = "(" + rest
+	}
+	if name == "bool" || name == "boolean" {
+		name = "tinyint(1)"
+	}
+	return name + rest
+}
+
+// normaliseDefault compares defaults as information_schema shows them, literals without quotes, expressions
+// without their brackets and booleans as numbers
+func normaliseDefault(value string) string {
+	value = strings.TrimSpace(value)
+	if len(value) >= 2 && strings.HasPrefix(value, "'") && strings.HasSuffix(value, "'") {
+		value = strings.ReplaceAll(value[1:len(value)-1], "''", "'")
+	}
+	for len(value) >= 2 && strings.HasPrefix(value, "(") && strings.HasSuffix(value, ")") {
+		value = value[1 : len(value)-1]
+	}
+
+	switch upper := strings.ToUpper(strings.ReplaceAll(value, " ", "")); upper {
+	case "NULL":
+		return ""
+	case "TRUE":
+		return "1"
+	case "FALSE":
+		return "0"
+	case "CURRENT_TIMESTAMP()", "NOW()":
+		return "CURRENT_TIMESTAMP"
+	default:
+		return upper
+	}
+}
+
+func valueOrNull(value *string) string {
+	if value == nil {
+		return "NULL"
+	}
+	return *value
+}
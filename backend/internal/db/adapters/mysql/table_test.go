@@ -0,0 +1,129 @@
+package mysql
+
+import (
+	"strings"
+	"testing"
+)
+
+func eventsTable() *Table {
+	return &Table{
+		Name: "EventsTable",
+		Columns: &[]Column{
+			NewIntegerColumnForTable("id", false, true),
+			NewUUIDColumnForTable("uuid", false, 36, Unique()),
+			NewEnumColumnForTable("kind", false, []string{"talk", "o'clock"}),
+			NewDecimalColumnForTable("price", true, 10, 2),
+			NewJSONColumnForTable("details", true),
+			NewBoolColumnForTable("approved", false, 1, Default("FALSE")),
+			NewVarcharColumnForTable("timeZone", false, 64, Default("'UTC'")),
+			NewDateTimeColumnForTable("updatedAt", true, Default("CURRENT_TIMESTAMP"), OnUpdate("CURRENT_TIMESTAMP")),
+			NewUUIDColumnForTable("organisationUUID", true, 36),
+		},
+		PrimaryKeys: &[]string{"id"},
+		Indexes:     []Index{{Name: "idx_events_kind", Columns: []string{"kind", "price"}}},
+		ForeignKeys: []ForeignKey{{
+			Columns:           []string{"organisationUUID"},
+			ReferencedTable:   "OrganisationTable",
+			ReferencedColumns: []string{"uuid"},
+			OnDelete:          Cascade,
+		}},
+	}
+}
+
+func TestGetOrCreateTableQuery(t *testing.T) {
+	query, err := eventsTable().GetOrCreateTableQuery(true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "CREATE TABLE IF NOT EXISTS `EventsTable`(`id` INT NOT NULL AUTO_INCREMENT, " +
+		"`uuid` VARCHAR(36) NOT NULL UNIQUE, `kind` ENUM('talk','o''clock') NOT NULL, `price` DECIMAL(10,2), " +
+		"`details` JSON, `approved` BOOL NOT NULL DEFAULT FALSE, `timeZone` VARCHAR(64) NOT NULL DEFAULT 'UTC', " +
+		"`updatedAt` DATETIME DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP, `organisationUUID` VARCHAR(36), " +
+		"PRIMARY KEY (`id`), INDEX `idx_events_kind` (`kind`, `price`), " +
+		"FOREIGN KEY (`organisationUUID`) REFERENCES `OrganisationTable`(`uuid`) ON DELETE CASCADE);"
+	if query != expected {
+		t.Fatalf("expected\n%s\ngot\n%s", expected, query)
+	}
+
+	if query, _ := eventsTable().GetOrCreateTableQuery(false); !strings.HasPrefix(query, "CREATE TABLE `EventsTable`") {
+		t.Fatalf("expected no IF NOT EXISTS, got %s", query)
+	}
+}
+
+// eventsSchema is eventsTable as information_schema describes it once created
+func eventsSchema() *TableSchema {
+	zero, utc, now := "0", "UTC", "CURRENT_TIMESTAMP"
+	return &TableSchema{
+		Columns: []ColumnSchema{
+			{Name: "id", Type: "int(11)", Extra: "auto_increment"},
+			{Name: "uuid", Type: "varchar(36)"},
+			{Name: "kind", Type: "enum('talk','o''clock')"},
+			{Name: "price", Type: "decimal(10,2)", Nullable: true},
+			{Name: "details", Type: "json", Nullable: true},
+			{Name: "approved", Type: "tinyint(1)", Default: &zero},
+			{Name: "timeZone", Type: "varchar(64)", Default: &utc},
+			{Name: "updatedAt", Type: "datetime", Nullable: true, Default: &now,
+				Extra: "DEFAULT_GENERATED on update CURRENT_TIMESTAMP"},
+			{Name: "organisationUUID", Type: "varchar(36)", Nullable: true},
+		},
+		Indexes: []IndexSchema{
+			{Name: "uuid", Columns: []string{"uuid"}, Unique: true},
+			{Name: "idx_events_kind", Columns: []string{"kind", "price"}},
+		},
+		ForeignKeys: []ForeignKeySchema{{
+			Name:              "EventsTable_ibfk_1",
+			Columns:           []string{"organisationUUID"},
+			ReferencedTable:   "OrganisationTable",
+			ReferencedColumns: []string{"uuid"},
+			DeleteRule:        "CASCADE",
+		}},
+	}
+}
+
+func TestDiffCreatesMissingTable(t *testing.T) {
+	statements, err := eventsTable().Diff(&TableSchema{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(statements) != 1 || !strings.HasPrefix(statements[0], "CREATE TABLE IF NOT EXISTS") {
+		t.Fatalf("expected the table to be created, got %q", statements)
+	}
+}
+
+func TestDiffUpToDateTable(t *testing.T) {
+	statements, err := eventsTable().Diff(eventsSchema())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(statements) != 0 {
+		t.Fatalf("expected nothing to change, got %q", statements)
+	}
+}
+
+func TestDiffAltersChanges(t *testing.T) {
+	schema := eventsSchema()
+	// timeZone was shorter, price didn't exist and nothing was updated on update
+	schema.Columns[6].Type = "varchar(32)"
+	schema.Columns = append(schema.Columns[:3], schema.Columns[4:]...)
+	schema.Columns[6].Extra = "DEFAULT_GENERATED"
+	schema.Indexes = schema.Indexes[:1]
+	schema.ForeignKeys[0].DeleteRule = "NO ACTION"
+
+	statements, err := eventsTable().Diff(schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{
+		"ALTER TABLE `EventsTable` ADD COLUMN `price` DECIMAL(10,2)",
+		"ALTER TABLE `EventsTable` MODIFY COLUMN `timeZone` VARCHAR(64) NOT NULL DEFAULT 'UTC'",
+		"ALTER TABLE `EventsTable` MODIFY COLUMN `updatedAt` DATETIME DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP",
+		"ALTER TABLE `EventsTable` ADD INDEX `idx_events_kind` (`kind`, `price`)",
+		"ALTER TABLE `EventsTable` DROP FOREIGN KEY `EventsTable_ibfk_1`",
+		"ALTER TABLE `EventsTable` ADD FOREIGN KEY (`organisationUUID`) REFERENCES `OrganisationTable`(`uuid`) ON DELETE CASCADE",
+	}
+	if strings.Join(statements, "\n") != strings.Join(expected, "\n") {
+		t.Fatalf("expected\n%s\ngot\n%s", strings.Join(expected, "\n"), strings.Join(statements, "\n"))
+	}
+}
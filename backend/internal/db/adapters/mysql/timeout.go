@@ -0,0 +1,78 @@
+package mysql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Operation is a class of database call, each class has its own default timeout
+type Operation int
+
+const (
+	// Query is a statement returning rows, bounded until the rows are read
+	Query Operation = iota
+	// Exec is an insert, update or delete outside a transaction
+	Exec
+	// Transaction bounds everything from starting a transaction to committing it
+	Transaction
+	// Schema is creating or altering tables when repositories start
+	Schema
+)
+
+// Timeouts are the default deadlines of each Operation, used unless the caller's context has an earlier one.
+// A zero timeout uses the default and a negative one disables it
+type Timeouts struct {
+	Query       time.Duration
+	Exec        time.Duration
+	Transaction time.Duration
+	Schema      time.Duration
+}
+
+// DefaultTimeouts are used for any timeout left unset
+var DefaultTimeouts = Timeouts{
+	Query:       5 * time.Second,
+	Exec:        5 * time.Second,
+	Transaction: 15 * time.Second,
+	Schema:      2 * time.Minute,
+}
+
+// For returns the timeout of the operation, 0 if it has none
+func (timeouts Timeouts) For(operation Operation) time.Duration {
+	var timeout, fallback time.Duration
+	switch operation {
+	case Query:
+		timeout, fallback = timeouts.Query, DefaultTimeouts.Query
+	case Exec:
+		timeout, fallback = timeouts.Exec, DefaultTimeouts.Exec
+	case Transaction:
+		timeout, fallback = timeouts.Transaction, DefaultTimeouts.Transaction
+	case Schema:
+		timeout, fallback = timeouts.Schema, DefaultTimeouts.Schema
+	}
+
+	if timeout == 0 {
+		return fallback
+	}
+	return max(timeout, 0)
+}
+
+// withTimeout bounds ctx by the operation's timeout. Calls returning rows or a transaction can't cancel the context
+// when they return, as it's still in use, so only cancel on failure and leave the deadline to release it
+func (sqlDatabase *Container) withTimeout(ctx context.Context, operation Operation) (context.Context, context.CancelFunc) {
+	timeout := sqlDatabase.timeouts.For(operation)
+	if timeout == 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
+// contextError makes a call that failed because its context was cancelled or timed out match context.Canceled or
+// context.DeadlineExceeded with errors.Is, the driver often reports it as a broken connection instead
+func contextError(ctx context.Context, err error) error {
+	if err == nil || ctx.Err() == nil || errors.Is(err, ctx.Err()) {
+		return err
+	}
+	return fmt.Errorf("%w: %v", ctx.Err(), err)
+}
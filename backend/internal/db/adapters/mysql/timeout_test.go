@@ -0,0 +1,58 @@
+package mysql
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestTimeoutsFor(t *testing.T) {
+	timeouts := Timeouts{Query: time.Second, Exec: -1}
+
+	if timeout := timeouts.For(Query); timeout != time.Second {
+		t.Fatalf("expected the configured query timeout, got %s", timeout)
+	}
+	if timeout := timeouts.For(Exec); timeout != 0 {
+		t.Fatalf("expected a negative timeout to disable it, got %s", timeout)
+	}
+	if timeout := timeouts.For(Schema); timeout != DefaultTimeouts.Schema {
+		t.Fatalf("expected the default schema timeout, got %s", timeout)
+	}
+}
+
+func TestWithTimeoutKeepsEarlierDeadline(t *testing.T) {
+	container := &Container{timeouts: Timeouts{Query: time.Hour}}
+
+	parent, cancelParent := context.WithTimeout(context.Background(), time.Minute)
+	defer cancelParent()
+	parentDeadline, _ := parent.Deadline()
+
+	ctx, cancel := container.withTimeout(parent, Query)
+	defer cancel()
+	if deadline, _ := ctx.Deadline(); !deadline.Equal(parentDeadline) {
+		t.Fatalf("expected the caller's earlier deadline %s, got %s", parentDeadline, deadline)
+	}
+
+	container.timeouts.Query = -1
+	if ctx, _ := container.withTimeout(context.Background(), Query); ctx != context.Background() {
+		t.Fatal("expected no deadline when the timeout is disabled")
+	}
+}
+
+func TestContextError(t *testing.T) {
+	driverError := errors.New("invalid connection")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	if err := contextError(ctx, driverError); err != driverError {
+		t.Fatalf("expected errors to pass through while the context is live, got %v", err)
+	}
+
+	cancel()
+	if err := contextError(ctx, driverError); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected a cancelled call to match context.Canceled, got %v", err)
+	}
+	if err := contextError(ctx, nil); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+}
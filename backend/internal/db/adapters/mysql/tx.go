@@ -0,0 +1,182 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	driver "github.com/go-sql-driver/mysql"
+	log "github.com/sirupsen/logrus"
+	"math/rand/v2"
+	"time"
+)
+
+// Transactions are run with WithTx. The transaction travels in the context it passes on, so repository calls made
+// with that context join it, and a WithTx inside another runs in a savepoint rather than its own transaction.
+// AfterCommit defers work with side effects outside the database, like publishing events, until the changes it
+// reports on have been committed
+
+const (
+	// maxTxAttempts is how many times a transaction that deadlocked is run before giving up
+	maxTxAttempts = 3
+	// retryBackoff is how long to wait before the first retry, growing with each attempt
+	retryBackoff = 20 * time.Millisecond
+)
+
+// MySQL errors after which the transaction can be run again
+const (
+	// errLockDeadlock is ER_LOCK_DEADLOCK, MySQL's serialisation failure. The transaction has been rolled back
+	errLockDeadlock = 1213
+	// errLockWaitTimeout is ER_LOCK_WAIT_TIMEOUT
+	errLockWaitTimeout = 1205
+)
+
+type txKey struct{}
+
+// Tx is the transaction a WithTx function runs in
+type Tx struct {
+	*sql.Tx
+	ctx context.Context
+	// savepoints numbers the savepoints of nested WithTx calls so their names are unique
+	savepoints *int
+	// afterCommit is run once the transaction has been committed
+	afterCommit *[]func(ctx context.Context)
+}
+
+// Context carries the transaction, repository calls made with it join the transaction
+func (tx *Tx) Context() context.Context {
+	return tx.ctx
+}
+
+// AfterCommit runs fn once the transaction has been committed, with the context WithTx was called with. Nothing is
+// run if the transaction or the savepoint fn was added in is rolled back, or if it's run again after a deadlock
+func (tx *Tx) AfterCommit(fn func(ctx context.Context)) {
+	*tx.afterCommit = append(*tx.afterCommit, fn)
+}
+
+// AfterCommit runs fn after the transaction ctx was made by has been committed, or straight away outside WithTx
+func AfterCommit(ctx context.Context, fn func(ctx context.Context)) {
+	if tx := ambientTx(ctx); tx != nil {
+		tx.AfterCommit(fn)
+		return
+	}
+	fn(ctx)
+}
+
+// ambientTx returns the transaction ctx was made by, nil outside WithTx
+func ambientTx(ctx context.Context) *Tx {
+	tx, _ := ctx.Value(txKey{}).(*Tx)
+	return tx
+}
+
+// IsRetryable reports whether the error is a deadlock or lock wait timeout, after which rerunning the whole
+// transaction may succeed
+func IsRetryable(err error) bool {
+	var mysqlError *driver.MySQLError
+	return errors.As(err, &mysqlError) && (mysqlError.Number == errLockDeadlock || mysqlError.Number == errLockWaitTimeout)
+}
+
+// WithTx runs fn in a transaction, committing if it returns nil and rolling back if it returns an error or panics.
+// A transaction that deadlocks is run again, so fn must only change the database. Called with the context of
+// another WithTx, fn runs in a savepoint of that transaction and an error only undoes fn's own changes
+func (r *Repository) WithTx(ctx context.Context, fn func(tx *Tx) error) error {
+	if outer := ambientTx(ctx); outer != nil {
+		return withSavepoint(outer, fn)
+	}
+
+	for attempt := 1; ; attempt++ {
+		afterCommit, err := r.runTx(ctx, fn)
+		if err == nil {
+			runAfterCommit(ctx, afterCommit)
+			return nil
+		}
+		if !IsRetryable(err) || attempt == maxTxAttempts {
+			return err
+		}
+
+		// Jitter stops the transactions that deadlocked retrying in lockstep
+		backoff := time.Duration(attempt)*retryBackoff + rand.N(retryBackoff)
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(backoff):
+		}
+	}
+}
+
+// runTx runs fn in a transaction, returning what's to be run after the commit
+func (r *Repository) runTx(ctx context.Context, fn func(tx *Tx) error) ([]func(ctx context.Context), error) {
+	ctx, cancel := r.Database.withTimeout(ctx, Transaction)
+	defer cancel()
+
+	sqlTx, err := r.Database.database.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, contextError(ctx, err)
+	}
+
+	tx := &Tx{Tx: sqlTx, savepoints: new(int), afterCommit: new([]func(ctx context.Context))}
+	tx.ctx = context.WithValue(ctx, txKey{}, tx)
+
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			sqlTx.Rollback()
+			panic(recovered)
+		}
+	}()
+
+	if err := fn(tx); err != nil {
+		sqlTx.Rollback()
+		return nil, contextError(ctx, err)
+	}
+	if err := sqlTx.Commit(); err != nil {
+		return nil, contextError(ctx, err)
+	}
+	return *tx.afterCommit, nil
+}
+
+// runAfterCommit runs the functions in the order they were added. The transaction has been committed, so a panic is
+// logged rather than reported as its failure
+func runAfterCommit(ctx context.Context, afterCommit []func(ctx context.Context)) {
+	for _, fn := range afterCommit {
+		func() {
+			defer func() {
+				if recovered := recover(); recovered != nil {
+					log.Errorf("Panic after commit: %v", recovered)
+				}
+			}()
+			fn(ctx)
+		}()
+	}
+}
+
+func withSavepoint(tx *Tx, fn func(tx *Tx) error) error {
+	*tx.savepoints++
+	name := fmt.Sprintf("sp_%d", *tx.savepoints)
+
+	if _, err := tx.ExecContext(tx.ctx, "SAVEPOINT "+name); err != nil {
+		return contextError(tx.ctx, err)
+	}
+
+	// What fn added to run after the commit is dropped with its changes
+	afterCommit := len(*tx.afterCommit)
+	rollback := func() {
+		tx.ExecContext(tx.ctx, "ROLLBACK TO SAVEPOINT "+name)
+		*tx.afterCommit = (*tx.afterCommit)[:afterCommit]
+	}
+
+	// A deadlock rolls back the whole transaction and its savepoints, so failing to return to one isn't reported
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			rollback()
+			panic(recovered)
+		}
+	}()
+
+	if err := fn(tx); err != nil {
+		rollback()
+		return err
+	}
+
+	_, err := tx.ExecContext(tx.ctx, "RELEASE SAVEPOINT "+name)
+	return contextError(tx.ctx, err)
+}
@@ -0,0 +1,220 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	mysqlDriver "github.com/go-sql-driver/mysql"
+	"io"
+	"strings"
+	"testing"
+)
+
+// recorder is a database/sql driver that records the statements run on it instead of running them
+type recorder struct {
+	statements []string
+	// deadlocks is how many statements containing "deadlock" fail before one succeeds
+	deadlocks int
+	// columns and values are what every query returns
+	columns []string
+	values  [][]driver.Value
+}
+
+func (r *recorder) Connect(context.Context) (driver.Conn, error) { return r, nil }
+func (r *recorder) Driver() driver.Driver                        { return nil }
+func (r *recorder) Prepare(string) (driver.Stmt, error)          { return nil, errors.New("not supported") }
+func (r *recorder) Close() error                                 { return nil }
+func (r *recorder) Begin() (driver.Tx, error) {
+	return r.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+func (r *recorder) BeginTx(context.Context, driver.TxOptions) (driver.Tx, error) {
+	r.statements = append(r.statements, "BEGIN")
+	return recorderTx{r}, nil
+}
+
+func (r *recorder) ExecContext(_ context.Context, query string, _ []driver.NamedValue) (driver.Result, error) {
+	r.statements = append(r.statements, query)
+	if strings.Contains(query, "deadlock") && r.deadlocks > 0 {
+		r.deadlocks--
+		return nil, &mysqlDriver.MySQLError{Number: errLockDeadlock, Message: "Deadlock found"}
+	}
+	return driver.RowsAffected(1), nil
+}
+
+func (r *recorder) QueryContext(_ context.Context, query string, _ []driver.NamedValue) (driver.Rows, error) {
+	r.statements = append(r.statements, query)
+	return &recorderRows{columns: r.columns, values: r.values}, nil
+}
+
+type recorderRows struct {
+	columns []string
+	values  [][]driver.Value
+}
+
+func (rows *recorderRows) Columns() []string { return rows.columns }
+func (rows *recorderRows) Close() error      { return nil }
+
+func (rows *recorderRows) Next(destination []driver.Value) error {
+	if len(rows.values) == 0 {
+		return io.EOF
+	}
+	copy(destination, rows.values[0])
+	rows.values = rows.values[1:]
+	return nil
+}
+
+type recorderTx struct{ r *recorder }
+
+func (tx recorderTx) Commit() error {
+	tx.r.statements = append(tx.r.statements, "COMMIT")
+	return nil
+}
+
+func (tx recorderTx) Rollback() error {
+	tx.r.statements = append(tx.r.statements, "ROLLBACK")
+	return nil
+}
+
+func newRecorderRepository(t *testing.T) (*Repository, *recorder) {
+	r := &recorder{}
+	database := sql.OpenDB(r)
+	database.SetMaxOpenConns(1)
+	t.Cleanup(func() { database.Close() })
+	return &Repository{Database: &Container{database: database}}, r
+}
+
+func expectStatements(t *testing.T, r *recorder, expected ...string) {
+	t.Helper()
+	if strings.Join(r.statements, "; ") != strings.Join(expected, "; ") {
+		t.Fatalf("expected %q, got %q", expected, r.statements)
+	}
+}
+
+func TestWithTxCommitsAndRollsBack(t *testing.T) {
+	repo, r := newRecorderRepository(t)
+
+	err := repo.WithTx(context.Background(), func(tx *Tx) error {
+		_, err := tx.ExecContext(tx.Context(), "UPDATE a")
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectStatements(t, r, "BEGIN", "UPDATE a", "COMMIT")
+
+	r.statements = nil
+	failed := errors.New("failed")
+	if err := repo.WithTx(context.Background(), func(tx *Tx) error { return failed }); err != failed {
+		t.Fatalf("expected fn's error, got %v", err)
+	}
+	expectStatements(t, r, "BEGIN", "ROLLBACK")
+}
+
+func TestWithTxRollsBackOnPanic(t *testing.T) {
+	repo, r := newRecorderRepository(t)
+
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expected the panic to be passed on")
+		}
+		expectStatements(t, r, "BEGIN", "ROLLBACK")
+	}()
+	repo.WithTx(context.Background(), func(tx *Tx) error { panic("failed") })
+}
+
+func TestWithTxNestsInSavepoints(t *testing.T) {
+	repo, r := newRecorderRepository(t)
+
+	err := repo.WithTx(context.Background(), func(tx *Tx) error {
+		if err := repo.WithTx(tx.Context(), func(*Tx) error { return nil }); err != nil {
+			return err
+		}
+		if err := repo.WithTx(tx.Context(), func(*Tx) error { return errors.New("failed") }); err == nil {
+			t.Error("expected the savepoint's error")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectStatements(t, r, "BEGIN", "SAVEPOINT sp_1", "RELEASE SAVEPOINT sp_1",
+		"SAVEPOINT sp_2", "ROLLBACK TO SAVEPOINT sp_2", "COMMIT")
+}
+
+func TestWithTxRetriesDeadlocks(t *testing.T) {
+	repo, r := newRecorderRepository(t)
+	r.deadlocks = 1
+
+	// Only the outermost WithTx retries, a deadlock in a savepoint has already rolled back the transaction
+	err := repo.WithTx(context.Background(), func(tx *Tx) error {
+		return repo.WithTx(tx.Context(), func(tx *Tx) error {
+			_, err := tx.ExecContext(tx.Context(), "UPDATE deadlock")
+			return err
+		})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectStatements(t, r, "BEGIN", "SAVEPOINT sp_1", "UPDATE deadlock", "ROLLBACK TO SAVEPOINT sp_1", "ROLLBACK",
+		"BEGIN", "SAVEPOINT sp_1", "UPDATE deadlock", "RELEASE SAVEPOINT sp_1", "COMMIT")
+
+	r.statements, r.deadlocks = nil, maxTxAttempts
+	err = repo.WithTx(context.Background(), func(tx *Tx) error {
+		_, err := tx.ExecContext(tx.Context(), "UPDATE deadlock")
+		return err
+	})
+	if !IsRetryable(err) || len(r.statements) != 3*maxTxAttempts {
+		t.Fatalf("expected to give up after %d attempts, got %v after %q", maxTxAttempts, err, r.statements)
+	}
+}
+
+func TestAfterCommitRunsOnceCommitted(t *testing.T) {
+	repo, r := newRecorderRepository(t)
+	r.deadlocks = 1
+
+	var ran []string
+	record := func(name string) func(context.Context) {
+		return func(ctx context.Context) {
+			if ambientTx(ctx) != nil {
+				t.Errorf("expected %s to run outside the transaction", name)
+			}
+			ran = append(ran, name)
+		}
+	}
+
+	err := repo.WithTx(context.Background(), func(tx *Tx) error {
+		tx.AfterCommit(record("outer"))
+		repo.WithTx(tx.Context(), func(tx *Tx) error {
+			AfterCommit(tx.Context(), record("released"))
+			return nil
+		})
+		repo.WithTx(tx.Context(), func(tx *Tx) error {
+			AfterCommit(tx.Context(), record("rolled back"))
+			return errors.New("failed")
+		})
+		if len(ran) != 0 {
+			t.Errorf("expected nothing to run before the commit, got %q", ran)
+		}
+		// Deadlocks the first attempt, whose functions mustn't run
+		_, err := tx.ExecContext(tx.Context(), "UPDATE deadlock")
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(ran, ", ") != "outer, released" {
+		t.Fatalf("expected the committed attempt's functions to run once, got %q", ran)
+	}
+
+	ran = nil
+	repo.WithTx(context.Background(), func(tx *Tx) error {
+		tx.AfterCommit(record("failed"))
+		return errors.New("failed")
+	})
+	AfterCommit(context.Background(), record("outside"))
+	if strings.Join(ran, ", ") != "outside" {
+		t.Fatalf("expected only the function outside a transaction to run, got %q", ran)
+	}
+}
@@ -50,6 +50,7 @@ func (neoDatabase *Container) GetThreadPool() *concurrency.ThreadPool {
 }
 
 func (neoDatabase *Container) Close() error {
+	concurrency.ShutdownPool(neoDatabase.pool)
 	return neoDatabase.database.Close()
 }
 
@@ -193,6 +194,36 @@ func (repo *Repository) RemoveRelation(nodeA *Node, nodeB *Node, relation string
 	return err
 }
 
+// Run runs the query with parameters in a write transaction, returning each record's values by key
+func (repo *Repository) Run(query string, parameters map[string]any) ([]map[string]any, error) {
+	session := repo.createSession()
+	defer session.Close()
+
+	result, err := session.WriteTransaction(func(tx neo4j.Transaction) (interface{}, error) {
+		records, err := tx.Run(query, parameters)
+		if err != nil {
+			return nil, err
+		}
+
+		var rows []map[string]any
+		for records.Next() {
+			record := records.Record()
+			row := make(map[string]any, len(record.Keys))
+			for i, key := range record.Keys {
+				row[key] = record.Values[i]
+			}
+			rows = append(rows, row)
+		}
+		return rows, records.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	rows, _ := result.([]map[string]any)
+	return rows, nil
+}
+
 func (repo *Repository) executeQuery(session neo4j.Session, query string) ([]*Node, error) {
 	result, err := session.WriteTransaction(func(tx neo4j.Transaction) (interface{}, error) {
 		records, err := tx.Run(query, nil)
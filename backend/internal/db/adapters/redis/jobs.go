@@ -0,0 +1,250 @@
+package redis
+
+import (
+	"backend/internal/queue"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"strconv"
+	"time"
+)
+
+// jobsPrefix starts the keys of the job queue. Each job is a hash at queue:job:<id>, queue:ready orders the jobs
+// waiting or leased by when they're next due, queue:completed orders the completed ones by when they completed,
+// queue:key:<key> holds an idempotency key and queue:dead lists the dead letters
+const jobsPrefix = "queue:"
+
+// purgeBatch is how many completed jobs each purge script deletes
+const purgeBatch = 1000
+
+var enqueueJobScript = redis.NewScript(`
+local prefix = ARGV[1]
+local key = ARGV[2]
+if key ~= "" and not redis.call("SET", prefix .. "key:" .. key, "", "NX") then
+	return 0
+end
+local id = redis.call("INCR", prefix .. "id")
+if key ~= "" then
+	redis.call("SET", prefix .. "key:" .. key, id)
+end
+redis.call("HSET", prefix .. "job:" .. id, "type", ARGV[3], "payload", ARGV[4], "key", key, "attempts", 0,
+	"maxAttempts", ARGV[5], "runAt", ARGV[6])
+redis.call("ZADD", prefix .. "ready", ARGV[6], id)
+return id
+`)
+
+// claimJobsScript returns the id then fields of each job it leases
+var claimJobsScript = redis.NewScript(`
+local prefix = ARGV[1]
+local ids = redis.call("ZRANGEBYSCORE", prefix .. "ready", "-inf", ARGV[2], "LIMIT", 0, ARGV[3])
+local jobs = {}
+for _, id in ipairs(ids) do
+	local job = prefix .. "job:" .. id
+	redis.call("HINCRBY", job, "attempts", 1)
+	redis.call("HSET", job, "lease", ARGV[5])
+	redis.call("ZADD", prefix .. "ready", ARGV[4], id)
+	table.insert(jobs, id)
+	table.insert(jobs, redis.call("HGETALL", job))
+end
+return jobs
+`)
+
+var completeJobScript = redis.NewScript(`
+local prefix = ARGV[1]
+local job = prefix .. "job:" .. ARGV[2]
+if redis.call("HGET", job, "lease") ~= ARGV[3] then
+	return 0
+end
+redis.call("HDEL", job, "lease", "lastError")
+redis.call("ZREM", prefix .. "ready", ARGV[2])
+redis.call("ZADD", prefix .. "completed", ARGV[4], ARGV[2])
+return 1
+`)
+
+var retryJobScript = redis.NewScript(`
+local prefix = ARGV[1]
+local job = prefix .. "job:" .. ARGV[2]
+if redis.call("HGET", job, "lease") ~= ARGV[3] then
+	return 0
+end
+redis.call("HDEL", job, "lease")
+redis.call("HSET", job, "runAt", ARGV[4], "lastError", ARGV[5])
+redis.call("ZADD", prefix .. "ready", ARGV[4], ARGV[2])
+return 1
+`)
+
+// deadLetterJobScript deletes the job and its key, like the MySQL queue, and pushes the dead letter it's given
+var deadLetterJobScript = redis.NewScript(`
+local prefix = ARGV[1]
+local job = prefix .. "job:" .. ARGV[2]
+if redis.call("HGET", job, "lease") ~= ARGV[3] then
+	return 0
+end
+local key = redis.call("HGET", job, "key")
+if key and key ~= "" then
+	redis.call("DEL", prefix .. "key:" .. key)
+end
+redis.call("DEL", job)
+redis.call("ZREM", prefix .. "ready", ARGV[2])
+redis.call("LPUSH", prefix .. "dead", ARGV[4])
+return 1
+`)
+
+var purgeJobsScript = redis.NewScript(`
+local prefix = ARGV[1]
+local ids = redis.call("ZRANGEBYSCORE", prefix .. "completed", "-inf", "(" .. ARGV[2], "LIMIT", 0, ARGV[3])
+for _, id in ipairs(ids) do
+	local job = prefix .. "job:" .. id
+	local key = redis.call("HGET", job, "key")
+	if key and key ~= "" then
+		redis.call("DEL", prefix .. "key:" .. key)
+	end
+	redis.call("DEL", job)
+	redis.call("ZREM", prefix .. "completed", id)
+end
+return #ids
+`)
+
+// JobStore keeps the background job queue in Redis. Implements queue.Store
+type JobStore struct {
+	client *redis.Client
+}
+
+// NewJobStore Creates a job store on the repository's connection
+func NewJobStore(repo *Repository) (*JobStore, error) {
+	if repo.Database.redis == nil {
+		return nil, fmt.Errorf("client is nill")
+	}
+	return &JobStore{client: repo.Database.redis}, nil
+}
+
+// deadLetter is an entry of queue:dead
+type deadLetter struct {
+	ID       int64           `json:"id"`
+	Type     string          `json:"type"`
+	Payload  json.RawMessage `json:"payload"`
+	Key      string          `json:"key,omitempty"`
+	Attempts int             `json:"attempts"`
+	Error    string          `json:"error"`
+	FailedAt time.Time       `json:"failedAt"`
+}
+
+// Enqueue adds the job, or returns false if its idempotency key is taken
+func (store *JobStore) Enqueue(ctx context.Context, job queue.Job) (bool, error) {
+	id, err := enqueueJobScript.Run(ctx, store.client, nil, jobsPrefix, job.Key, job.Type, job.Payload,
+		job.MaxAttempts, job.RunAt.UnixMilli()).Int64()
+	return id != 0, err
+}
+
+// Claim leases the due jobs, the script runs atomically so no job is claimed twice
+func (store *JobStore) Claim(ctx context.Context, now time.Time, limit int, lease time.Duration) ([]queue.Job, error) {
+	token := uuid.NewString()
+	result, err := claimJobsScript.Run(ctx, store.client, nil, jobsPrefix, now.UnixMilli(), limit,
+		now.Add(lease).UnixMilli(), token).Slice()
+	if err != nil {
+		return nil, err
+	}
+
+	jobs := make([]queue.Job, 0, len(result)/2)
+	for i := 0; i+1 < len(result); i += 2 {
+		job, err := parseJob(result[i], result[i+1])
+		if err != nil {
+			// Claimed again once the lease passes
+			return nil, err
+		}
+		job.Lease = token
+		jobs = append(jobs, job)
+	}
+	return jobs, nil
+}
+
+// parseJob reads a job from its id and the fields of its hash
+func parseJob(id any, fields any) (queue.Job, error) {
+	values, ok := fields.([]any)
+	if !ok {
+		return queue.Job{}, fmt.Errorf("unexpected job fields %T", fields)
+	}
+
+	hash := map[string]string{}
+	for i := 0; i+1 < len(values); i += 2 {
+		hash[fmt.Sprint(values[i])] = fmt.Sprint(values[i+1])
+	}
+
+	job := queue.Job{Type: hash["type"], Payload: []byte(hash["payload"]), Key: hash["key"], LastError: hash["lastError"]}
+	var err error
+	if job.ID, err = strconv.ParseInt(fmt.Sprint(id), 10, 64); err != nil {
+		return queue.Job{}, fmt.Errorf("invalid job id %v", id)
+	}
+	if job.Attempts, err = strconv.Atoi(hash["attempts"]); err != nil {
+		return queue.Job{}, fmt.Errorf("job %d: invalid attempts: %w", job.ID, err)
+	}
+	if job.MaxAttempts, err = strconv.Atoi(hash["maxAttempts"]); err != nil {
+		return queue.Job{}, fmt.Errorf("job %d: invalid max attempts: %w", job.ID, err)
+	}
+	runAt, err := strconv.ParseInt(hash["runAt"], 10, 64)
+	if err != nil {
+		return queue.Job{}, fmt.Errorf("job %d: invalid run at: %w", job.ID, err)
+	}
+	job.RunAt = time.UnixMilli(runAt)
+	return job, nil
+}
+
+// Complete marks the job done, keeping it until purged
+func (store *JobStore) Complete(ctx context.Context, job queue.Job) error {
+	return leased(completeJobScript.Run(ctx, store.client, nil, jobsPrefix, job.ID, job.Lease,
+		time.Now().UnixMilli()).Int())
+}
+
+// Retry releases the job to run again at runAt
+func (store *JobStore) Retry(ctx context.Context, job queue.Job, runAt time.Time, reason string) error {
+	return leased(retryJobScript.Run(ctx, store.client, nil, jobsPrefix, job.ID, job.Lease, runAt.UnixMilli(),
+		reason).Int())
+}
+
+// DeadLetter moves the job to queue:dead
+func (store *JobStore) DeadLetter(ctx context.Context, job queue.Job, reason string) error {
+	payload := json.RawMessage(job.Payload)
+	if !json.Valid(payload) {
+		payload, _ = json.Marshal(string(job.Payload))
+	}
+
+	record, err := json.Marshal(deadLetter{
+		ID:       job.ID,
+		Type:     job.Type,
+		Payload:  payload,
+		Key:      job.Key,
+		Attempts: job.Attempts,
+		Error:    reason,
+		FailedAt: time.Now(),
+	})
+	if err != nil {
+		return err
+	}
+
+	return leased(deadLetterJobScript.Run(ctx, store.client, nil, jobsPrefix, job.ID, job.Lease, record).Int())
+}
+
+// PurgeCompleted deletes the jobs completed before the time
+func (store *JobStore) PurgeCompleted(ctx context.Context, before time.Time) (int64, error) {
+	var purged int64
+	for {
+		count, err := purgeJobsScript.Run(ctx, store.client, nil, jobsPrefix, before.UnixMilli(), purgeBatch).Int64()
+		purged += count
+		if err != nil || count < purgeBatch {
+			return purged, err
+		}
+	}
+}
+
+// leased turns a script's result into queue.ErrLeaseLost when the job's lease didn't match
+func leased(updated int, err error) error {
+	if err != nil {
+		return err
+	}
+	if updated == 0 {
+		return queue.ErrLeaseLost
+	}
+	return nil
+}
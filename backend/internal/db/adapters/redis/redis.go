@@ -6,8 +6,11 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"github.com/google/uuid"
 	"github.com/redis/go-redis/v9"
+	log "github.com/sirupsen/logrus"
 	"strconv"
+	"time"
 )
 
 // Configurations Make sure Configurations implements db.DatabaseConfigurations
@@ -16,6 +19,11 @@ type Configurations struct {
 	DatabaseIndex   int
 }
 
+// NewConfigurations Creates the configuration for connecting to the Redis server
+func NewConfigurations(authentication db.AuthenticationConfigurations, databaseIndex int) Configurations {
+	return Configurations{authentications: authentication, DatabaseIndex: databaseIndex}
+}
+
 // GetAuthenticationConfigurations This method ensures Configurations implements db.DatabaseConfigurations
 func (config Configurations) GetAuthenticationConfigurations() db.AuthenticationConfigurations {
 	return config.authentications
@@ -75,8 +83,9 @@ func (redisDatabase *Container) Connect(config Configurations) error {
 	return nil
 }
 
-// Close Closes the Redis client connection
+// Close Waits for queued inserts and fetches, then closes the Redis client connection
 func (redisDatabase *Container) Close() error {
+	concurrency.ShutdownPool(redisDatabase.pool)
 	return redisDatabase.redis.Close()
 }
 
@@ -91,16 +100,22 @@ type Repository struct {
 
 // Insert Adds a new key-value pair to Redis asynchronously
 func (repo *Repository) Insert(Key string, Value string, callback *concurrency.Callback[string]) {
-	repo.Database.pool.Submit(func() {
+	err := repo.Database.pool.Submit(func() {
 		repo.insertIntoDb(Key, Value, callback)
 	})
+	if err != nil {
+		callback.Error(err)
+	}
 }
 
 // Fetch Retrieves a value by key from Redis asynchronously
 func (repo *Repository) Fetch(Key string, callback *concurrency.Callback[string]) {
-	repo.Database.pool.Submit(func() {
+	err := repo.Database.pool.Submit(func() {
 		repo.fetchFromDb(Key, callback)
 	})
+	if err != nil {
+		callback.Error(err)
+	}
 }
 
 // fetchFromDb Helper method for fetching data from Redis
@@ -149,6 +164,36 @@ func (repo *Repository) insertIntoDb(Key string, Value string, callback *concurr
 	callback.Success(Value)
 }
 
+// unlockScript deletes the lock only if it still holds the caller's token, so a holder whose lock expired can't
+// release the next holder's
+var unlockScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0
+`)
+
+// Lock Takes the lock for up to ttl, acquired is false if someone else holds it. Implements concurrency.Locker
+func (repo *Repository) Lock(ctx context.Context, key string, ttl time.Duration) (unlock func(), acquired bool, err error) {
+	client := repo.Database.redis
+	if client == nil {
+		return nil, false, fmt.Errorf("client is nill")
+	}
+
+	token := uuid.NewString()
+	acquired, err = client.SetNX(ctx, key, token, ttl).Result()
+	if err != nil || !acquired {
+		return nil, false, err
+	}
+
+	return func() {
+		if err := unlockScript.Run(context.Background(), client, []string{key}, token).Err(); err != nil {
+			// The lock expires by itself
+			log.Errorf("Unable to release lock %s: %s", key, err)
+		}
+	}, true, nil
+}
+
 // setupClient Sets up the Redis client for the repository
 func setupClient[T any](repo *Repository, callback *concurrency.Callback[T]) (*redis.Client, error) {
 	database := repo.Database
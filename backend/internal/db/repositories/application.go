@@ -0,0 +1,427 @@
+package repositories
+
+import (
+	"backend/internal/db/adapters/mysql"
+	"backend/internal/models"
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+	"math"
+	"time"
+)
+
+// Applications to opportunities. Every change locks the opportunity row first so concurrent applications
+// can't overfill it, and any places freed up are given to the waitlist in the same transaction
+
+// ErrApplicationsClosed is returned when applying to an opportunity that's closed, expired or not yet approved
+var ErrApplicationsClosed = errors.New("opportunity is closed to applications")
+
+const CreateOpportunityApplicationsTableQuery = `
+CREATE TABLE IF NOT EXISTS OpportunityApplicationsTable(
+    opportunityUUID VARCHAR(36) NOT NULL,
+    userUUID VARCHAR(36) NOT NULL,
+    status ENUM('confirmed', 'waitlisted', 'cancelled') NOT NULL,
+    appliedAt DATETIME(6) NOT NULL,
+    PRIMARY KEY (opportunityUUID, userUUID),
+    FOREIGN KEY (opportunityUUID) REFERENCES OpportunitiesTable(uuid) ON DELETE CASCADE,
+    FOREIGN KEY (userUUID) REFERENCES UserTable(uuid) ON DELETE CASCADE
+);`
+
+const CreateApplicationStatusIndex = "CREATE INDEX idx_application_status ON OpportunityApplicationsTable(opportunityUUID, status, appliedAt);"
+
+const LockOpportunityForApplicationQuery = `
+SELECT approved, capacity, closedAt, expiresAt
+FROM OpportunitiesTable
+WHERE uuid = ?
+FOR UPDATE
+`
+
+const GetApplicationStatusQuery = `
+SELECT status, appliedAt FROM OpportunityApplicationsTable
+WHERE opportunityUUID = ? AND userUUID = ?
+FOR UPDATE
+`
+
+const CountConfirmedApplicationsQuery = `
+SELECT COUNT(*) FROM OpportunityApplicationsTable
+WHERE opportunityUUID = ? AND status = 'confirmed'
+`
+
+const WaitlistPositionQuery = `
+SELECT COUNT(*) FROM OpportunityApplicationsTable
+WHERE opportunityUUID = ? AND status = 'waitlisted' AND appliedAt <= ?
+`
+
+// Reapplying after cancelling goes to the back of the queue
+const UpsertApplicationQuery = `
+INSERT INTO OpportunityApplicationsTable(opportunityUUID, userUUID, status, appliedAt)
+VALUES (?, ?, ?, ?)
+ON DUPLICATE KEY UPDATE status = VALUES(status), appliedAt = VALUES(appliedAt)
+`
+
+const CancelApplicationQuery = `
+UPDATE OpportunityApplicationsTable
+SET status = 'cancelled'
+WHERE opportunityUUID = ? AND userUUID = ?
+`
+
+const ConfirmApplicationQuery = `
+UPDATE OpportunityApplicationsTable
+SET status = 'confirmed'
+WHERE opportunityUUID = ? AND userUUID = ?
+`
+
+const GetNextWaitlistedQuery = `
+SELECT userUUID FROM OpportunityApplicationsTable
+WHERE opportunityUUID = ? AND status = 'waitlisted'
+ORDER BY appliedAt, userUUID
+LIMIT ?
+FOR UPDATE
+`
+
+const NotifyWaitlistPromotedQuery = `
+INSERT IGNORE INTO NotificationsTable(uuid, userUUID, notificationType, message, referenceUUID)
+SELECT UUID(), ?, ?, CONCAT('A place opened up for you on ', title), uuid
+FROM OpportunitiesTable
+WHERE uuid = ?
+`
+
+const GetApplicationsQuery = `
+SELECT oa.opportunityUUID, oa.userUUID, ut.username, oa.status, oa.appliedAt
+FROM OpportunityApplicationsTable oa
+INNER JOIN UserTable ut
+    ON ut.uuid = oa.userUUID
+WHERE oa.opportunityUUID = ? AND oa.status != 'cancelled'
+ORDER BY oa.status, oa.appliedAt, oa.userUUID
+`
+
+type ApplicationRepository struct {
+	*BaseRepository
+}
+
+// NewApplicationRepository initializes a new ApplicationRepository instance
+func NewApplicationRepository(db *mysql.Repository) (*ApplicationRepository, error) {
+	ar := &ApplicationRepository{}
+	baseRepo, err := InitRepository(ar, db)
+
+	if err != nil {
+		return nil, err
+	}
+	ar.BaseRepository = baseRepo
+	return ar, nil
+}
+
+// CreateTablesQuery returns a list of SQL queries needed to create necessary tables for applications
+func (_ *ApplicationRepository) CreateTablesQuery() *[]string {
+	return &[]string{CreateOpportunityApplicationsTableQuery}
+}
+
+// CreateIndexesQuery returns a list of SQL queries needed to create necessary indexes for applications
+func (_ *ApplicationRepository) CreateIndexesQuery() *[]string {
+	return &[]string{CreateApplicationStatusIndex}
+}
+
+// Apply confirms the user if there's space, otherwise waitlists them. Applying again while confirmed or
+// waitlisted returns the existing application. Returns sql.ErrNoRows if the opportunity doesn't exist
+func (repo *ApplicationRepository) Apply(ctx context.Context, opportunityUUID uuid.UUID, userUUID uuid.UUID, now time.Time) (*models.ApplicationModel, error) {
+	container := repo.Repository
+
+	var application *models.ApplicationModel
+	err := container.WithTx(ctx, func(tx *mysql.Tx) error {
+		capacity, err := repo.lockOpportunity(tx.Context(), tx.Tx, opportunityUUID, now, true)
+		if err != nil {
+			return err
+		}
+
+		// Built afresh each attempt, a deadlocked attempt mustn't leave its status behind
+		application = &models.ApplicationModel{OpportunityUUID: opportunityUUID, UserUUID: userUUID}
+
+		status, appliedAt, err := repo.getStatus(tx.Context(), tx.Tx, opportunityUUID, userUUID)
+		if err != nil {
+			return err
+		}
+
+		if status != nil && *status != models.ApplicationCancelled {
+			application.Status, application.AppliedAt = *status, appliedAt
+		} else {
+			confirmed, err := repo.countConfirmed(tx.Context(), tx.Tx, opportunityUUID)
+			if err != nil {
+				return err
+			}
+
+			application.Status = models.ApplicationConfirmed
+			if capacity != nil && confirmed >= *capacity {
+				application.Status = models.ApplicationWaitlisted
+			}
+			application.AppliedAt = now.UTC()
+
+			_, err = container.AddExecuteTransactionContext(tx.Context(), tx.Tx, UpsertApplicationQuery, []mysql.Column{
+				mysql.NewUUIDColumn("opportunityUUID", opportunityUUID),
+				mysql.NewUUIDColumn("userUUID", userUUID),
+				mysql.NewVarcharColumn("status", application.Status.String()),
+				mysql.NewDateTimeColumn("appliedAt", application.AppliedAt),
+			})
+			if err != nil {
+				log.Error(err)
+				return err
+			}
+		}
+
+		if application.Status == models.ApplicationWaitlisted {
+			if application.WaitlistPosition, err = repo.waitlistPosition(tx.Context(), tx.Tx, opportunityUUID, application.AppliedAt); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return application, nil
+}
+
+// Cancel cancels the users application, giving their place to the next on the waitlist.
+// Returns sql.ErrNoRows if the user has no active application
+func (repo *ApplicationRepository) Cancel(ctx context.Context, opportunityUUID uuid.UUID, userUUID uuid.UUID) error {
+	container := repo.Repository
+
+	return container.WithTx(ctx, func(tx *mysql.Tx) error {
+		capacity, err := repo.lockOpportunity(tx.Context(), tx.Tx, opportunityUUID, time.Now(), false)
+		if err != nil {
+			return err
+		}
+
+		status, _, err := repo.getStatus(tx.Context(), tx.Tx, opportunityUUID, userUUID)
+		if err != nil {
+			return err
+		}
+		if status == nil || *status == models.ApplicationCancelled {
+			return sql.ErrNoRows
+		}
+
+		_, err = container.AddExecuteTransactionContext(tx.Context(), tx.Tx, CancelApplicationQuery, []mysql.Column{
+			mysql.NewUUIDColumn("opportunityUUID", opportunityUUID),
+			mysql.NewUUIDColumn("userUUID", userUUID),
+		})
+		if err != nil {
+			log.Error(err)
+			return err
+		}
+
+		return repo.promote(tx.Context(), tx.Tx, opportunityUUID, capacity)
+	})
+}
+
+// FillFromWaitlist confirms waitlisted applicants while there's space, e.g. after the capacity is raised
+func (repo *ApplicationRepository) FillFromWaitlist(ctx context.Context, opportunityUUID uuid.UUID) error {
+	container := repo.Repository
+
+	return container.WithTx(ctx, func(tx *mysql.Tx) error {
+		capacity, err := repo.lockOpportunity(tx.Context(), tx.Tx, opportunityUUID, time.Now(), false)
+		if err != nil {
+			return err
+		}
+
+		return repo.promote(tx.Context(), tx.Tx, opportunityUUID, capacity)
+	})
+}
+
+// GetApplications returns the confirmed then waitlisted applications, in the order they'll be promoted
+func (repo *ApplicationRepository) GetApplications(ctx context.Context, opportunityUUID uuid.UUID) ([]models.ApplicationModel, error) {
+	columns := []mysql.Column{
+		mysql.NewUUIDColumn("opportunityUUID", opportunityUUID),
+	}
+
+	rows, err := repo.Repository.ExecuteQueryContext(ctx, GetApplicationsQuery, columns, mysql.QueryOptions{})
+	if err != nil {
+		log.Error(err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var applications []models.ApplicationModel
+	var position int64
+
+	for rows.Next() {
+		var application models.ApplicationModel
+		var statusName string
+
+		err := rows.Scan(&application.OpportunityUUID, &application.UserUUID, &application.Username, &statusName, &application.AppliedAt)
+		if err != nil {
+			log.Error(err)
+			return nil, err
+		}
+
+		application.Status, err = models.ParseApplicationStatus(statusName)
+		if err != nil {
+			return nil, err
+		}
+
+		if application.Status == models.ApplicationWaitlisted {
+			position++
+			application.WaitlistPosition = position
+		}
+
+		applications = append(applications, application)
+	}
+
+	return applications, nil
+}
+
+// lockOpportunity locks the opportunity row for the rest of the transaction and returns its capacity.
+// When applying it also checks the opportunity is open
+func (repo *ApplicationRepository) lockOpportunity(ctx context.Context, transaction *sql.Tx, opportunityUUID uuid.UUID, now time.Time, applying bool) (*int64, error) {
+	rows, err := repo.Repository.AddQueryTransactionContext(ctx, transaction, LockOpportunityForApplicationQuery, []mysql.Column{
+		mysql.NewUUIDColumn("uuid", opportunityUUID),
+	})
+	if err != nil {
+		log.Error(err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		return nil, sql.ErrNoRows
+	}
+
+	var approved bool
+	var capacity sql.NullInt64
+	var closedAt, expiresAt sql.NullTime
+
+	if err := rows.Scan(&approved, &capacity, &closedAt, &expiresAt); err != nil {
+		log.Error(err)
+		return nil, err
+	}
+
+	if applying && (!approved || closedAt.Valid || (expiresAt.Valid && !expiresAt.Time.After(now))) {
+		return nil, ErrApplicationsClosed
+	}
+
+	if !capacity.Valid {
+		return nil, nil
+	}
+	return &capacity.Int64, nil
+}
+
+// getStatus returns the users current application status, nil if they've never applied
+func (repo *ApplicationRepository) getStatus(ctx context.Context, transaction *sql.Tx, opportunityUUID uuid.UUID, userUUID uuid.UUID) (*models.ApplicationStatus, time.Time, error) {
+	rows, err := repo.Repository.AddQueryTransactionContext(ctx, transaction, GetApplicationStatusQuery, []mysql.Column{
+		mysql.NewUUIDColumn("opportunityUUID", opportunityUUID),
+		mysql.NewUUIDColumn("userUUID", userUUID),
+	})
+	if err != nil {
+		log.Error(err)
+		return nil, time.Time{}, err
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		return nil, time.Time{}, nil
+	}
+
+	var statusName string
+	var appliedAt time.Time
+	if err := rows.Scan(&statusName, &appliedAt); err != nil {
+		log.Error(err)
+		return nil, time.Time{}, err
+	}
+
+	status, err := models.ParseApplicationStatus(statusName)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	return &status, appliedAt, nil
+}
+
+func (repo *ApplicationRepository) countConfirmed(ctx context.Context, transaction *sql.Tx, opportunityUUID uuid.UUID) (int64, error) {
+	return repo.count(ctx, transaction, CountConfirmedApplicationsQuery, []mysql.Column{
+		mysql.NewUUIDColumn("opportunityUUID", opportunityUUID),
+	})
+}
+
+func (repo *ApplicationRepository) waitlistPosition(ctx context.Context, transaction *sql.Tx, opportunityUUID uuid.UUID, appliedAt time.Time) (int64, error) {
+	return repo.count(ctx, transaction, WaitlistPositionQuery, []mysql.Column{
+		mysql.NewUUIDColumn("opportunityUUID", opportunityUUID),
+		mysql.NewDateTimeColumn("appliedAt", appliedAt),
+	})
+}
+
+func (repo *ApplicationRepository) count(ctx context.Context, transaction *sql.Tx, query string, columns []mysql.Column) (int64, error) {
+	rows, err := repo.Repository.AddQueryTransactionContext(ctx, transaction, query, columns)
+	if err != nil {
+		log.Error(err)
+		return 0, err
+	}
+	defer rows.Close()
+
+	var count int64
+	if rows.Next() {
+		if err := rows.Scan(&count); err != nil {
+			return 0, err
+		}
+	}
+	return count, nil
+}
+
+// promote confirms waitlisted applicants, earliest first, until the opportunity is full and notifies them
+func (repo *ApplicationRepository) promote(ctx context.Context, transaction *sql.Tx, opportunityUUID uuid.UUID, capacity *int64) error {
+	container := repo.Repository
+
+	spaces := int64(math.MaxInt32)
+	if capacity != nil {
+		confirmed, err := repo.countConfirmed(ctx, transaction, opportunityUUID)
+		if err != nil {
+			return err
+		}
+		spaces = *capacity - confirmed
+	}
+
+	if spaces <= 0 {
+		return nil
+	}
+
+	rows, err := container.AddQueryTransactionContext(ctx, transaction, GetNextWaitlistedQuery, []mysql.Column{
+		mysql.NewUUIDColumn("opportunityUUID", opportunityUUID),
+		mysql.NewIntegerColumn("limit", spaces),
+	})
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+
+	var promoted []uuid.UUID
+	for rows.Next() {
+		var userUUID uuid.UUID
+		if err := rows.Scan(&userUUID); err != nil {
+			rows.Close()
+			return err
+		}
+		promoted = append(promoted, userUUID)
+	}
+	rows.Close()
+
+	for _, userUUID := range promoted {
+		_, err := container.AddExecuteTransactionContext(ctx, transaction, ConfirmApplicationQuery, []mysql.Column{
+			mysql.NewUUIDColumn("opportunityUUID", opportunityUUID),
+			mysql.NewUUIDColumn("userUUID", userUUID),
+		})
+		if err != nil {
+			log.Error(err)
+			return err
+		}
+
+		_, err = container.AddExecuteTransactionContext(ctx, transaction, NotifyWaitlistPromotedQuery, []mysql.Column{
+			mysql.NewUUIDColumn("userUUID", userUUID),
+			mysql.NewVarcharColumn("notificationType", models.NotificationWaitlistPromoted),
+			mysql.NewUUIDColumn("uuid", opportunityUUID),
+		})
+		if err != nil {
+			log.Error(err)
+			return err
+		}
+	}
+
+	return nil
+}
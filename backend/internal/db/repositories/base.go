@@ -6,7 +6,6 @@ import (
 )
 
 //TODO: implement caching layer
-//TODO: make async
 
 // SQLRepository Interface for repositories that require SQL table creation queries
 type SQLRepository interface {
@@ -14,6 +13,35 @@ type SQLRepository interface {
 	CreateIndexesQuery() *[]string
 }
 
+// MigratingRepository is implemented by repositories that need to alter tables which already exist on older databases
+// e.g. adding a column. Migrations are run after tables are created and must be safe to rerun
+type MigratingRepository interface {
+	MigrationQueries() *[]string
+}
+
+// DeclaringRepository is implemented by repositories that declare tables with mysql.Table rather than SQL. The tables
+// are created after CreateTablesQuery, so they can reference its tables, and existing ones are brought up to date
+type DeclaringRepository interface {
+	Tables() []*mysql.Table
+}
+
+// alreadyAppliedErrors are the MySQL errors returned when rerunning a migration that was already applied
+var alreadyAppliedErrors = []string{
+	"Duplicate column name",
+	"Duplicate key name",
+	"Duplicate foreign key constraint name",
+	"errno: 121",
+}
+
+func isAlreadyApplied(err error) bool {
+	for _, message := range alreadyAppliedErrors {
+		if strings.Contains(err.Error(), message) {
+			return true
+		}
+	}
+	return false
+}
+
 // BaseRepository Struct that holds a reference to a MySQL Repository
 type BaseRepository struct {
 	Repository *mysql.Repository
@@ -32,11 +60,30 @@ func InitRepository(repo SQLRepository, container *mysql.Repository) (*BaseRepos
 
 	// Execute SQL query for each create table query
 	for _, query := range createTableQueries {
-		rows, err := repository.ExecuteQuery(query, make([]mysql.Column, 0), mysql.QueryOptions{})
-		if err != nil {
+		if err := repository.ExecuteSchema(query); err != nil {
 			return nil, err
 		}
-		rows.Close()
+	}
+
+	if declaring, ok := repo.(DeclaringRepository); ok {
+		for _, table := range declaring.Tables() {
+			if err := repository.SyncTable(table); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	//Alter existing tables
+
+	if migrating, ok := repo.(MigratingRepository); ok {
+		for _, query := range *migrating.MigrationQueries() {
+			if err := repository.ExecuteSchema(query); err != nil {
+				if isAlreadyApplied(err) {
+					continue
+				}
+				return nil, err
+			}
+		}
 	}
 
 	//Manage indexes
@@ -44,7 +91,7 @@ func InitRepository(repo SQLRepository, container *mysql.Repository) (*BaseRepos
 	createTableIndexes := *repo.CreateIndexesQuery()
 
 	for _, query := range createTableIndexes {
-		_, err := repository.ExecuteQuery(query, nil, mysql.QueryOptions{})
+		err := repository.ExecuteSchema(query)
 		if err != nil {
 
 			if strings.Contains(err.Error(), "Duplicate key name") {
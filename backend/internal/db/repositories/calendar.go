@@ -0,0 +1,250 @@
+package repositories
+
+import (
+	"backend/internal/db/adapters/mysql"
+	"backend/internal/models"
+	"context"
+	"database/sql"
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+	"time"
+)
+
+// Calendar subscription feeds. Feeds are authenticated by a token in the URL since calendar apps can't send
+// headers, only its hash is stored. Deleting an opportunity cascades to its applications, so a cancellation
+// is kept for each confirmed applicant letting their feed tell calendar apps to remove the event
+
+// CancellationRetention is how long cancellations of deleted opportunities stay in feeds
+const CancellationRetention = 30 * 24 * time.Hour
+
+const CreateCalendarFeedTokensTableQuery = `
+CREATE TABLE IF NOT EXISTS CalendarFeedTokensTable(
+    userUUID VARCHAR(36) NOT NULL PRIMARY KEY,
+    tokenHash VARCHAR(64) NOT NULL UNIQUE,
+    createdAt DATETIME DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (userUUID) REFERENCES UserTable(uuid) ON DELETE CASCADE
+);`
+
+const CreateCalendarCancellationsTableQuery = `
+CREATE TABLE IF NOT EXISTS CalendarCancellationsTable(
+    userUUID VARCHAR(36) NOT NULL,
+    opportunityUUID VARCHAR(36) NOT NULL,
+    title VARCHAR(100) NOT NULL,
+    location VARCHAR(100),
+    startsAt DATETIME NOT NULL,
+    endsAt DATETIME NULL,
+    timeZone VARCHAR(64) NOT NULL DEFAULT 'UTC',
+    recurrence VARCHAR(255) NULL,
+    sequence INT NOT NULL DEFAULT 0,
+    createdAt DATETIME NOT NULL,
+    cancelledAt DATETIME NOT NULL,
+    PRIMARY KEY (userUUID, opportunityUUID),
+    FOREIGN KEY (userUUID) REFERENCES UserTable(uuid) ON DELETE CASCADE
+);`
+
+const CreateCalendarCancelledAtIndex = "CREATE INDEX idx_calendar_cancelled_at ON CalendarCancellationsTable(cancelledAt);"
+
+const UpsertCalendarFeedTokenQuery = `
+INSERT INTO CalendarFeedTokensTable(userUUID, tokenHash) VALUES (?, ?)
+ON DUPLICATE KEY UPDATE tokenHash = VALUES(tokenHash), createdAt = CURRENT_TIMESTAMP
+`
+
+const DeleteCalendarFeedTokenQuery = "DELETE FROM CalendarFeedTokensTable WHERE userUUID = ?"
+
+const GetCalendarFeedUserQuery = "SELECT userUUID FROM CalendarFeedTokensTable WHERE tokenHash = ?"
+
+// Opportunities without set times aren't events so they're left out of feeds
+const RecordCalendarCancellationsQuery = `
+INSERT INTO CalendarCancellationsTable(userUUID, opportunityUUID, title, location, startsAt, endsAt,
+    timeZone, recurrence, sequence, createdAt, cancelledAt)
+SELECT oa.userUUID, ot.uuid, ot.title, ot.location, ot.startsAt, ot.endsAt,
+    ot.timeZone, ot.recurrence, ot.sequence + 1, ot.createdAt, ?
+FROM OpportunitiesTable ot
+INNER JOIN OpportunityApplicationsTable oa
+    ON oa.opportunityUUID = ot.uuid AND oa.status = 'confirmed'
+WHERE ot.uuid = ? AND ot.startsAt IS NOT NULL
+ON DUPLICATE KEY UPDATE title = VALUES(title), location = VALUES(location), startsAt = VALUES(startsAt),
+    endsAt = VALUES(endsAt), timeZone = VALUES(timeZone), recurrence = VALUES(recurrence),
+    sequence = VALUES(sequence), cancelledAt = VALUES(cancelledAt)
+`
+
+const PruneCalendarCancellationsQuery = "DELETE FROM CalendarCancellationsTable WHERE cancelledAt < ?"
+
+// Recurring opportunities stay in the feed, one-off ones drop out once they've been over for the retention period
+const GetCalendarEntriesQuery = `
+SELECT oa.opportunityUUID, oa.status = 'cancelled'
+FROM OpportunityApplicationsTable oa
+INNER JOIN OpportunitiesTable ot
+    ON ot.uuid = oa.opportunityUUID
+WHERE oa.userUUID = ? AND oa.status IN ('confirmed', 'cancelled')
+    AND ot.approved = TRUE AND ot.startsAt IS NOT NULL
+    AND (ot.recurrence IS NOT NULL OR COALESCE(ot.endsAt, ot.startsAt) > ?)
+`
+
+const GetCalendarCancellationsQuery = `
+SELECT opportunityUUID, title, location, startsAt, endsAt, timeZone, recurrence, sequence, createdAt, cancelledAt
+FROM CalendarCancellationsTable
+WHERE userUUID = ? AND cancelledAt > ?
+`
+
+type CalendarRepository struct {
+	*BaseRepository
+}
+
+// NewCalendarRepository initializes a new CalendarRepository instance
+func NewCalendarRepository(db *mysql.Repository) (*CalendarRepository, error) {
+	cr := &CalendarRepository{}
+	baseRepo, err := InitRepository(cr, db)
+
+	if err != nil {
+		return nil, err
+	}
+	cr.BaseRepository = baseRepo
+	return cr, nil
+}
+
+// CreateTablesQuery returns a list of SQL queries needed to create necessary tables for calendar feeds
+func (_ *CalendarRepository) CreateTablesQuery() *[]string {
+	return &[]string{CreateCalendarFeedTokensTableQuery, CreateCalendarCancellationsTableQuery}
+}
+
+// CreateIndexesQuery returns a list of SQL queries needed to create necessary indexes for calendar feeds
+func (_ *CalendarRepository) CreateIndexesQuery() *[]string {
+	return &[]string{CreateCalendarCancelledAtIndex}
+}
+
+// SetFeedToken sets the users feed token, replacing any previous one
+func (repo *CalendarRepository) SetFeedToken(ctx context.Context, userUUID uuid.UUID, tokenHash string) error {
+	columns := []mysql.Column{
+		mysql.NewUUIDColumn("userUUID", userUUID),
+		mysql.NewVarcharColumn("tokenHash", tokenHash),
+	}
+
+	_, err := repo.Repository.ExecuteInsertContext(ctx, UpsertCalendarFeedTokenQuery, columns, mysql.InsertOptions{})
+	if err != nil {
+		log.Error(err)
+	}
+	return err
+}
+
+// DeleteFeedToken revokes the users feed
+func (repo *CalendarRepository) DeleteFeedToken(ctx context.Context, userUUID uuid.UUID) error {
+	columns := []mysql.Column{
+		mysql.NewUUIDColumn("userUUID", userUUID),
+	}
+
+	_, err := repo.Repository.ExecuteInsertContext(ctx, DeleteCalendarFeedTokenQuery, columns, mysql.InsertOptions{})
+	if err != nil {
+		log.Error(err)
+	}
+	return err
+}
+
+// GetFeedUser returns the user the feed token belongs to, nil if it doesn't belong to anyone
+func (repo *CalendarRepository) GetFeedUser(ctx context.Context, tokenHash string) (*uuid.UUID, error) {
+	columns := []mysql.Column{
+		mysql.NewVarcharColumn("tokenHash", tokenHash),
+	}
+
+	rows, err := repo.Repository.ExecuteQueryContext(ctx, GetCalendarFeedUserQuery, columns, mysql.QueryOptions{})
+	if err != nil {
+		log.Error(err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		return nil, rows.Err()
+	}
+
+	var userUUID uuid.UUID
+	if err := rows.Scan(&userUUID); err != nil {
+		log.Error(err)
+		return nil, err
+	}
+	return &userUUID, nil
+}
+
+// GetEntries returns the opportunities in the users feed. Cancelled applications are included so
+// calendar apps remove them, as are opportunities that finished up to since
+func (repo *CalendarRepository) GetEntries(ctx context.Context, userUUID uuid.UUID, since time.Time) ([]models.CalendarEntryModel, error) {
+	columns := []mysql.Column{
+		mysql.NewUUIDColumn("userUUID", userUUID),
+		mysql.NewDateTimeColumn("since", since.UTC()),
+	}
+
+	rows, err := repo.Repository.ExecuteQueryContext(ctx, GetCalendarEntriesQuery, columns, mysql.QueryOptions{})
+	if err != nil {
+		log.Error(err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []models.CalendarEntryModel
+	for rows.Next() {
+		var entry models.CalendarEntryModel
+		if err := rows.Scan(&entry.OpportunityUUID, &entry.Cancelled); err != nil {
+			log.Error(err)
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
+
+// GetCancellations returns the users deleted opportunities cancelled after since
+func (repo *CalendarRepository) GetCancellations(ctx context.Context, userUUID uuid.UUID, since time.Time) ([]models.CalendarCancellationModel, error) {
+	columns := []mysql.Column{
+		mysql.NewUUIDColumn("userUUID", userUUID),
+		mysql.NewDateTimeColumn("since", since.UTC()),
+	}
+
+	rows, err := repo.Repository.ExecuteQueryContext(ctx, GetCalendarCancellationsQuery, columns, mysql.QueryOptions{})
+	if err != nil {
+		log.Error(err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var cancellations []models.CalendarCancellationModel
+	for rows.Next() {
+		var cancellation models.CalendarCancellationModel
+		var location, recurrence sql.NullString
+		var endsAt sql.NullTime
+
+		err := rows.Scan(&cancellation.OpportunityUUID, &cancellation.Title, &location, &cancellation.StartsAt, &endsAt,
+			&cancellation.TimeZone, &recurrence, &cancellation.Sequence, &cancellation.CreatedAt, &cancellation.CancelledAt)
+		if err != nil {
+			log.Error(err)
+			return nil, err
+		}
+
+		cancellation.Location, cancellation.Recurrence = location.String, recurrence.String
+		if endsAt.Valid {
+			cancellation.EndsAt = &endsAt.Time
+		}
+		cancellations = append(cancellations, cancellation)
+	}
+	return cancellations, rows.Err()
+}
+
+// recordCancellations keeps a cancellation for each confirmed applicant of the opportunity about to be deleted,
+// and prunes ones past the retention period. It must run in the same transaction as the delete
+func recordCancellations(ctx context.Context, container *mysql.Repository, transaction *sql.Tx, opportunityUUID uuid.UUID, now time.Time) error {
+	_, err := container.AddExecuteTransactionContext(ctx, transaction, RecordCalendarCancellationsQuery, []mysql.Column{
+		mysql.NewDateTimeColumn("cancelledAt", now.UTC()),
+		mysql.NewUUIDColumn("uuid", opportunityUUID),
+	})
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+
+	_, err = container.AddExecuteTransactionContext(ctx, transaction, PruneCalendarCancellationsQuery, []mysql.Column{
+		mysql.NewDateTimeColumn("cancelledAt", now.Add(-CancellationRetention).UTC()),
+	})
+	if err != nil {
+		log.Error(err)
+	}
+	return err
+}
@@ -0,0 +1,154 @@
+package repositories
+
+import (
+	"backend/internal/db/adapters/neo4j"
+	"backend/internal/outbox"
+	"context"
+	"fmt"
+	"github.com/google/uuid"
+)
+
+// The graph projects users, opportunities, likes and matches from MySQL. Labels are lower case like those of the
+// query builder, and a match is a MATCH relation each way as CreateRelation made them
+
+const MergeUserNodeQuery = "MERGE (:user {uuid: $uuid})"
+
+const DeleteUserNodeQuery = "MATCH (u:user {uuid: $uuid}) DETACH DELETE u"
+
+const MergeOpportunityNodeQuery = "MERGE (:opportunity {uuid: $uuid})"
+
+const DeleteOpportunityNodeQuery = "MATCH (o:opportunity {uuid: $uuid}) DETACH DELETE o"
+
+const MergeLikeRelationQuery = `
+MERGE (u:user {uuid: $user})
+MERGE (o:opportunity {uuid: $opportunity})
+MERGE (u)-[:LIKED]->(o)
+`
+
+const DeleteLikeRelationQuery = "MATCH (:user {uuid: $user})-[l:LIKED]->(:opportunity {uuid: $opportunity}) DELETE l"
+
+const MergeMatchRelationQuery = `
+MERGE (a:user {uuid: $user})
+MERGE (b:user {uuid: $matched})
+MERGE (a)-[:MATCH]->(b)
+MERGE (b)-[:MATCH]->(a)
+`
+
+const DeleteMatchRelationQuery = "MATCH (:user {uuid: $user})-[m:MATCH]-(:user {uuid: $matched}) DELETE m"
+
+const GetUserNodesQuery = "MATCH (u:user) RETURN u.uuid AS a"
+
+const GetOpportunityNodesQuery = "MATCH (o:opportunity) RETURN o.uuid AS a"
+
+const GetLikeRelationsQuery = "MATCH (u:user)-[:LIKED]->(o:opportunity) RETURN u.uuid AS a, o.uuid AS b"
+
+const GetMatchRelationsQuery = "MATCH (a:user)-[:MATCH]->(b:user) RETURN a.uuid AS a, b.uuid AS b"
+
+// GraphRepository is the Neo4j projection of MySQL. Implements outbox.Graph
+type GraphRepository struct {
+	Repository *neo4j.Repository
+}
+
+func NewGraphRepository(db *neo4j.Repository) *GraphRepository {
+	return &GraphRepository{Repository: db}
+}
+
+func (graph *GraphRepository) run(query string, parameters map[string]any) error {
+	_, err := graph.Repository.Run(query, parameters)
+	return err
+}
+
+func (graph *GraphRepository) MergeUser(_ context.Context, userUUID uuid.UUID) error {
+	return graph.run(MergeUserNodeQuery, map[string]any{"uuid": userUUID.String()})
+}
+
+func (graph *GraphRepository) DeleteUser(_ context.Context, userUUID uuid.UUID) error {
+	return graph.run(DeleteUserNodeQuery, map[string]any{"uuid": userUUID.String()})
+}
+
+func (graph *GraphRepository) MergeOpportunity(_ context.Context, opportunityUUID uuid.UUID) error {
+	return graph.run(MergeOpportunityNodeQuery, map[string]any{"uuid": opportunityUUID.String()})
+}
+
+func (graph *GraphRepository) DeleteOpportunity(_ context.Context, opportunityUUID uuid.UUID) error {
+	return graph.run(DeleteOpportunityNodeQuery, map[string]any{"uuid": opportunityUUID.String()})
+}
+
+func (graph *GraphRepository) MergeLike(_ context.Context, userUUID uuid.UUID, opportunityUUID uuid.UUID) error {
+	return graph.run(MergeLikeRelationQuery, map[string]any{"user": userUUID.String(), "opportunity": opportunityUUID.String()})
+}
+
+func (graph *GraphRepository) DeleteLike(_ context.Context, userUUID uuid.UUID, opportunityUUID uuid.UUID) error {
+	return graph.run(DeleteLikeRelationQuery, map[string]any{"user": userUUID.String(), "opportunity": opportunityUUID.String()})
+}
+
+func (graph *GraphRepository) MergeMatch(_ context.Context, userUUID uuid.UUID, matchedUUID uuid.UUID) error {
+	return graph.run(MergeMatchRelationQuery, map[string]any{"user": userUUID.String(), "matched": matchedUUID.String()})
+}
+
+func (graph *GraphRepository) DeleteMatch(_ context.Context, userUUID uuid.UUID, matchedUUID uuid.UUID) error {
+	return graph.run(DeleteMatchRelationQuery, map[string]any{"user": userUUID.String(), "matched": matchedUUID.String()})
+}
+
+// Snapshot reads every node and relation of the projection. Nodes whose uuid isn't a UUID are left out
+func (graph *GraphRepository) Snapshot(_ context.Context) (*outbox.Snapshot, error) {
+	snapshot := &outbox.Snapshot{}
+
+	users, err := graph.pairs(GetUserNodesQuery)
+	if err != nil {
+		return nil, err
+	}
+	for _, user := range users {
+		snapshot.Users = append(snapshot.Users, user.A)
+	}
+
+	opportunities, err := graph.pairs(GetOpportunityNodesQuery)
+	if err != nil {
+		return nil, err
+	}
+	for _, opportunity := range opportunities {
+		snapshot.Opportunities = append(snapshot.Opportunities, opportunity.A)
+	}
+
+	if snapshot.Likes, err = graph.pairs(GetLikeRelationsQuery); err != nil {
+		return nil, err
+	}
+
+	matches, err := graph.pairs(GetMatchRelationsQuery)
+	if err != nil {
+		return nil, err
+	}
+	seen := map[outbox.Pair]bool{}
+	for _, match := range matches {
+		pair := outbox.NewPair(match.A, match.B)
+		if !seen[pair] {
+			seen[pair] = true
+			snapshot.Matches = append(snapshot.Matches, pair)
+		}
+	}
+
+	return snapshot, nil
+}
+
+// pairs runs a query returning the uuids a and optionally b, skipping rows that aren't UUIDs
+func (graph *GraphRepository) pairs(query string) ([]outbox.Pair, error) {
+	rows, err := graph.Repository.Run(query, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	pairs := make([]outbox.Pair, 0, len(rows))
+	for _, row := range rows {
+		var pair outbox.Pair
+		var errA, errB error
+		pair.A, errA = uuid.Parse(fmt.Sprint(row["a"]))
+		if b, ok := row["b"]; ok {
+			pair.B, errB = uuid.Parse(fmt.Sprint(b))
+		}
+		if errA != nil || errB != nil {
+			continue
+		}
+		pairs = append(pairs, pair)
+	}
+	return pairs, nil
+}
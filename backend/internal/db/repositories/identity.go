@@ -0,0 +1,177 @@
+package repositories
+
+import (
+	"backend/internal/db/adapters/mysql"
+	"backend/internal/models"
+	"context"
+	"database/sql"
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+	"time"
+)
+
+//Links SSO (OIDC) identities to users. A user can have one identity per provider
+
+const CreateUserIdentityTableQuery = `
+CREATE TABLE IF NOT EXISTS UserIdentityTable(
+    provider VARCHAR(50) NOT NULL,
+    subject VARCHAR(255) NOT NULL,
+    userUUID VARCHAR(36) NOT NULL,
+    email VARCHAR(255),
+    linkedAt DATETIME DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (provider, subject),
+    UNIQUE (provider, userUUID),
+    FOREIGN KEY (userUUID) REFERENCES UserTable(uuid) ON DELETE CASCADE
+);`
+
+const InsertUserIdentityQuery = `
+INSERT INTO UserIdentityTable(provider, subject, userUUID, email) VALUES (?,?,?,?)
+`
+
+const GetUserIdentityQuery = `
+SELECT provider, subject, userUUID, email, linkedAt FROM UserIdentityTable
+WHERE provider = ? AND subject = ?
+`
+
+const GetUserIdentitiesByUserQuery = `
+SELECT provider, subject, userUUID, email, linkedAt FROM UserIdentityTable
+WHERE userUUID = ?
+`
+
+const DeleteUserIdentityQuery = `
+DELETE FROM UserIdentityTable WHERE provider = ? AND userUUID = ?
+`
+
+// IdentityRepository stores the external identities linked to users
+type IdentityRepository struct {
+	*BaseRepository
+}
+
+// NewIdentityRepository initializes a new IdentityRepository instance
+func NewIdentityRepository(db *mysql.Repository) (*IdentityRepository, error) {
+	ir := &IdentityRepository{}
+	baseRepo, err := InitRepository(ir, db)
+
+	if err != nil {
+		return nil, err
+	}
+	ir.BaseRepository = baseRepo
+	return ir, nil
+}
+
+// CreateTablesQuery returns a list of SQL queries needed to create necessary tables for identity linking
+func (_ *IdentityRepository) CreateTablesQuery() *[]string {
+	return &[]string{CreateUserIdentityTableQuery}
+}
+
+// CreateIndexesQuery returns a list of SQL queries needed to create necessary indexes for identity linking
+func (_ *IdentityRepository) CreateIndexesQuery() *[]string {
+	return &[]string{}
+}
+
+// LinkIdentity links the given provider identity to a user
+func (repo *IdentityRepository) LinkIdentity(ctx context.Context, identity *models.UserIdentityModel) error {
+	container := repo.Repository
+
+	columns := []mysql.Column{
+		mysql.NewVarcharColumn("provider", identity.Provider),
+		mysql.NewVarcharColumn("subject", identity.Subject),
+		mysql.NewUUIDColumn("userUUID", identity.UserUUID),
+		mysql.NewVarcharColumn("email", identity.Email),
+	}
+
+	_, err := container.ExecuteInsertContext(ctx, InsertUserIdentityQuery, columns, mysql.InsertOptions{})
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+
+	return nil
+}
+
+// UnlinkIdentity removes a users identity for the provider
+func (repo *IdentityRepository) UnlinkIdentity(ctx context.Context, provider string, userUUID uuid.UUID) error {
+	container := repo.Repository
+
+	columns := []mysql.Column{
+		mysql.NewVarcharColumn("provider", provider),
+		mysql.NewUUIDColumn("userUUID", userUUID),
+	}
+
+	_, err := container.ExecuteInsertContext(ctx, DeleteUserIdentityQuery, columns, mysql.InsertOptions{})
+	return err
+}
+
+// GetIdentity returns the identity for the provider subject or nil if it isn't linked
+func (repo *IdentityRepository) GetIdentity(ctx context.Context, provider string, subject string) (*models.UserIdentityModel, error) {
+	container := repo.Repository
+
+	columns := []mysql.Column{
+		mysql.NewVarcharColumn("provider", provider),
+		mysql.NewVarcharColumn("subject", subject),
+	}
+
+	rows, err := container.ExecuteQueryContext(ctx, GetUserIdentityQuery, columns, mysql.QueryOptions{})
+	if err != nil {
+		log.Error(err)
+		return nil, err
+	}
+
+	identities, err := getIdentities(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(identities) == 0 {
+		return nil, nil
+	}
+
+	return &identities[0], nil
+}
+
+// GetIdentitiesByUser returns every identity linked to the user
+func (repo *IdentityRepository) GetIdentitiesByUser(ctx context.Context, userUUID uuid.UUID) ([]models.UserIdentityModel, error) {
+	container := repo.Repository
+
+	columns := []mysql.Column{
+		mysql.NewUUIDColumn("userUUID", userUUID),
+	}
+
+	rows, err := container.ExecuteQueryContext(ctx, GetUserIdentitiesByUserQuery, columns, mysql.QueryOptions{})
+	if err != nil {
+		log.Error(err)
+		return nil, err
+	}
+
+	return getIdentities(rows)
+}
+
+func getIdentities(rows *sql.Rows) ([]models.UserIdentityModel, error) {
+	defer rows.Close()
+
+	var identities []models.UserIdentityModel
+
+	for rows.Next() {
+		var provider string
+		var subject string
+		var userUUID uuid.UUID
+		var email sql.NullString
+		var linkedAt time.Time
+
+		err := rows.Scan(&provider, &subject, &userUUID, &email, &linkedAt)
+		if err != nil {
+			log.Error(err)
+			return nil, err
+		}
+
+		identities = append(identities, models.UserIdentityModel{
+			Provider: provider,
+			Subject:  subject,
+			UserUUID: userUUID,
+			Email:    email.String,
+			LinkedAt: linkedAt,
+		})
+	}
+
+	return identities, nil
+}
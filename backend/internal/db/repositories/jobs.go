@@ -0,0 +1,269 @@
+package repositories
+
+import (
+	"backend/internal/db/adapters/mysql"
+	"backend/internal/queue"
+	"context"
+	"github.com/google/uuid"
+	"strings"
+	"time"
+)
+
+// Jobs of the background queue. A claimed job's runAt is moved to when its lease ends, so a job whose worker died
+// is simply due again then. Completed jobs are kept until purged so their idempotency keys stay taken
+
+var jobsTable = &mysql.Table{
+	Name: "JobsTable",
+	Columns: &[]mysql.Column{
+		mysql.NewIntegerColumnForTable("id", false, true),
+		mysql.NewVarcharColumnForTable("type", false, 100),
+		mysql.NewJSONColumnForTable("payload", false),
+		mysql.NewVarcharColumnForTable("idempotencyKey", true, 191, mysql.Unique()),
+		mysql.NewIntegerColumnForTable("attempts", false, false, mysql.Default("0")),
+		mysql.NewIntegerColumnForTable("maxAttempts", false, false),
+		mysql.NewDateTimeColumnForTable("runAt", false),
+		mysql.NewVarcharColumnForTable("leaseToken", true, 36),
+		mysql.NewTextColumnForTable("lastError", true),
+		mysql.NewDateTimeColumnForTable("createdAt", false, mysql.Default("CURRENT_TIMESTAMP")),
+		mysql.NewDateTimeColumnForTable("completedAt", true),
+	},
+	PrimaryKeys: &[]string{"id"},
+	Indexes:     []mysql.Index{{Name: "idx_jobs_due", Columns: []string{"completedAt", "runAt"}}},
+}
+
+var jobDeadLettersTable = &mysql.Table{
+	Name: "JobDeadLettersTable",
+	Columns: &[]mysql.Column{
+		mysql.NewIntegerColumnForTable("id", false, true),
+		mysql.NewIntegerColumnForTable("jobID", false, false),
+		mysql.NewVarcharColumnForTable("type", false, 100),
+		mysql.NewJSONColumnForTable("payload", false),
+		mysql.NewVarcharColumnForTable("idempotencyKey", true, 191),
+		mysql.NewIntegerColumnForTable("attempts", false, false),
+		mysql.NewTextColumnForTable("error", true),
+		mysql.NewDateTimeColumnForTable("failedAt", false, mysql.Default("CURRENT_TIMESTAMP")),
+	},
+	PrimaryKeys: &[]string{"id"},
+}
+
+const InsertJobQuery = `
+INSERT INTO JobsTable (type, payload, idempotencyKey, maxAttempts, runAt) VALUES (?, ?, ?, ?, ?)
+`
+
+const ClaimJobsQuery = `
+SELECT id, type, payload, idempotencyKey, attempts, maxAttempts, runAt, lastError FROM JobsTable
+WHERE completedAt IS NULL AND runAt <= ?
+ORDER BY runAt, id
+LIMIT ?
+FOR UPDATE SKIP LOCKED
+`
+
+// LeaseJobsQuery is completed with a placeholder for each claimed job's id
+const LeaseJobsQuery = `
+UPDATE JobsTable SET attempts = attempts + 1, runAt = ?, leaseToken = ? WHERE id IN (%s)
+`
+
+const CompleteJobQuery = `
+UPDATE JobsTable SET completedAt = ?, leaseToken = NULL, lastError = NULL WHERE id = ? AND leaseToken = ?
+`
+
+const RetryJobQuery = `
+UPDATE JobsTable SET runAt = ?, leaseToken = NULL, lastError = ? WHERE id = ? AND leaseToken = ?
+`
+
+const InsertJobDeadLetterQuery = `
+INSERT INTO JobDeadLettersTable (jobID, type, payload, idempotencyKey, attempts, error) VALUES (?, ?, ?, ?, ?, ?)
+`
+
+const DeleteLeasedJobQuery = "DELETE FROM JobsTable WHERE id = ? AND leaseToken = ?"
+
+const PurgeCompletedJobsQuery = "DELETE FROM JobsTable WHERE completedAt < ?"
+
+// maxJobError is how much of a failed job's error is kept
+const maxJobError = 4000
+
+// JobRepository stores the background job queue in MySQL. Implements queue.Store
+type JobRepository struct {
+	*BaseRepository
+}
+
+func NewJobRepository(db *mysql.Repository) (*JobRepository, error) {
+	repo := &JobRepository{}
+	baseRepo, err := InitRepository(repo, db)
+	if err != nil {
+		return nil, err
+	}
+	repo.BaseRepository = baseRepo
+	return repo, nil
+}
+
+func (_ *JobRepository) CreateTablesQuery() *[]string {
+	return &[]string{}
+}
+
+func (_ *JobRepository) CreateIndexesQuery() *[]string {
+	return &[]string{}
+}
+
+// Tables returns the tables declared with mysql.Table
+func (_ *JobRepository) Tables() []*mysql.Table {
+	return []*mysql.Table{jobsTable, jobDeadLettersTable}
+}
+
+type jobRow struct {
+	ID          int64     `db:"id"`
+	Type        string    `db:"type"`
+	Payload     []byte    `db:"payload"`
+	Key         *string   `db:"idempotencyKey"`
+	Attempts    int64     `db:"attempts"`
+	MaxAttempts int64     `db:"maxAttempts"`
+	RunAt       time.Time `db:"runAt"`
+	LastError   *string   `db:"lastError"`
+}
+
+// Enqueue adds the job, or returns false if its idempotency key is taken
+func (repo *JobRepository) Enqueue(ctx context.Context, job queue.Job) (bool, error) {
+	var key *string
+	if job.Key != "" {
+		key = &job.Key
+	}
+
+	_, err := repo.Repository.ExecuteInsertContext(ctx, InsertJobQuery, []mysql.Column{
+		mysql.NewVarcharColumn("type", job.Type),
+		mysql.NewJSONColumn("payload", job.Payload),
+		mysql.NewNullableVarcharColumn("idempotencyKey", key),
+		mysql.NewIntegerColumn("maxAttempts", int64(job.MaxAttempts)),
+		mysql.NewDateTimeColumn("runAt", job.RunAt),
+	}, mysql.InsertOptions{})
+	if err != nil && strings.Contains(err.Error(), duplicateEntryError) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// EnqueuesInTransaction marks the store as a queue.TransactionalStore, Enqueue runs in the ambient transaction
+func (_ *JobRepository) EnqueuesInTransaction() {}
+
+// Claim leases the due jobs, skipping any another worker is claiming at the same moment
+func (repo *JobRepository) Claim(ctx context.Context, now time.Time, limit int, lease time.Duration) ([]queue.Job, error) {
+	container := repo.Repository
+	var jobs []queue.Job
+
+	err := container.WithTx(ctx, func(tx *mysql.Tx) error {
+		jobs = nil
+
+		rows, err := container.AddQueryTransactionContext(tx.Context(), tx.Tx, ClaimJobsQuery, []mysql.Column{
+			mysql.NewDateTimeColumn("runAt", now),
+			mysql.NewIntegerColumn("limit", int64(limit)),
+		})
+		if err != nil {
+			return err
+		}
+		claimed, err := mysql.ScanAll[jobRow](rows)
+		if err != nil || len(claimed) == 0 {
+			return err
+		}
+
+		leaseToken := uuid.NewString()
+		columns := []mysql.Column{
+			mysql.NewDateTimeColumn("runAt", now.Add(lease)),
+			mysql.NewVarcharColumn("leaseToken", leaseToken),
+		}
+		for _, row := range claimed {
+			columns = append(columns, mysql.NewIntegerColumn("id", row.ID))
+			jobs = append(jobs, queue.Job{
+				ID:          row.ID,
+				Type:        row.Type,
+				Payload:     row.Payload,
+				Key:         valueOf(row.Key),
+				Attempts:    int(row.Attempts) + 1,
+				MaxAttempts: int(row.MaxAttempts),
+				RunAt:       row.RunAt,
+				Lease:       leaseToken,
+				LastError:   valueOf(row.LastError),
+			})
+		}
+
+		placeholders := strings.TrimSuffix(strings.Repeat("?,", len(claimed)), ",")
+		_, err = container.AddExecuteTransactionContext(tx.Context(), tx.Tx, strings.Replace(LeaseJobsQuery, "%s", placeholders, 1), columns)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return jobs, nil
+}
+
+// Complete marks the job done, keeping it until purged
+func (repo *JobRepository) Complete(ctx context.Context, job queue.Job) error {
+	return repo.updateLeased(ctx, CompleteJobQuery, job, mysql.NewDateTimeColumn("completedAt", time.Now()))
+}
+
+// Retry releases the job to run again at runAt
+func (repo *JobRepository) Retry(ctx context.Context, job queue.Job, runAt time.Time, reason string) error {
+	return repo.updateLeased(ctx, RetryJobQuery, job, mysql.NewDateTimeColumn("runAt", runAt),
+		mysql.NewTextColumn("lastError", truncateError(reason)))
+}
+
+// updateLeased runs an update of the job with the columns then the job's id and lease, ErrLeaseLost if the job
+// has since been claimed again
+func (repo *JobRepository) updateLeased(ctx context.Context, query string, job queue.Job, columns ...mysql.Column) error {
+	columns = append(columns, mysql.NewIntegerColumn("id", job.ID), mysql.NewVarcharColumn("leaseToken", job.Lease))
+	updated, err := repo.Repository.ExecuteInsertContext(ctx, query, columns, mysql.InsertOptions{})
+	if err != nil {
+		return err
+	}
+	if updated == 0 {
+		return queue.ErrLeaseLost
+	}
+	return nil
+}
+
+// DeadLetter moves the job to JobDeadLettersTable
+func (repo *JobRepository) DeadLetter(ctx context.Context, job queue.Job, reason string) error {
+	container := repo.Repository
+
+	return container.WithTx(ctx, func(tx *mysql.Tx) error {
+		deleted, err := container.AddExecuteTransactionContext(tx.Context(), tx.Tx, DeleteLeasedJobQuery, []mysql.Column{
+			mysql.NewIntegerColumn("id", job.ID),
+			mysql.NewVarcharColumn("leaseToken", job.Lease),
+		})
+		if err != nil {
+			return err
+		}
+		if count, err := deleted.RowsAffected(); err != nil || count == 0 {
+			if err == nil {
+				err = queue.ErrLeaseLost
+			}
+			return err
+		}
+
+		var key *string
+		if job.Key != "" {
+			key = &job.Key
+		}
+		_, err = container.AddExecuteTransactionContext(tx.Context(), tx.Tx, InsertJobDeadLetterQuery, []mysql.Column{
+			mysql.NewIntegerColumn("jobID", job.ID),
+			mysql.NewVarcharColumn("type", job.Type),
+			mysql.NewJSONColumn("payload", job.Payload),
+			mysql.NewNullableVarcharColumn("idempotencyKey", key),
+			mysql.NewIntegerColumn("attempts", int64(job.Attempts)),
+			mysql.NewTextColumn("error", truncateError(reason)),
+		})
+		return err
+	})
+}
+
+// PurgeCompleted deletes the jobs completed before the time
+func (repo *JobRepository) PurgeCompleted(ctx context.Context, before time.Time) (int64, error) {
+	return repo.Repository.ExecuteInsertContext(ctx, PurgeCompletedJobsQuery, []mysql.Column{
+		mysql.NewDateTimeColumn("completedAt", before),
+	}, mysql.InsertOptions{})
+}
+
+func truncateError(reason string) string {
+	if len(reason) <= maxJobError {
+		return reason
+	}
+	return strings.ToValidUTF8(reason[:maxJobError], "")
+}
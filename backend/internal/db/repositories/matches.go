@@ -1,49 +1,104 @@
 package repositories
 
 import (
+	"backend/internal/db/adapters/mysql"
 	"backend/internal/db/adapters/neo4j"
+	"backend/internal/events"
+	"backend/internal/outbox"
+	"context"
+	"errors"
 	"fmt"
 	"github.com/google/uuid"
-	log "github.com/sirupsen/logrus"
+	"strings"
 )
 
+// Matches are recorded in MySQL and projected into Neo4j through the outbox, where they're read from. Each pair is
+// stored once, in the order of outbox.NewPair
+
+var matchesTable = &mysql.Table{
+	Name: "MatchesTable",
+	Columns: &[]mysql.Column{
+		mysql.NewUUIDColumnForTable("userUUID", false, 36),
+		mysql.NewUUIDColumnForTable("matchedUUID", false, 36),
+		mysql.NewDateTimeColumnForTable("createdAt", false, mysql.Default("CURRENT_TIMESTAMP")),
+	},
+	PrimaryKeys: &[]string{"userUUID", "matchedUUID"},
+	ForeignKeys: []mysql.ForeignKey{{
+		Columns:           []string{"userUUID"},
+		ReferencedTable:   "UserTable",
+		ReferencedColumns: []string{"uuid"},
+		OnDelete:          mysql.Cascade,
+	}, {
+		Columns:           []string{"matchedUUID"},
+		ReferencedTable:   "UserTable",
+		ReferencedColumns: []string{"uuid"},
+		OnDelete:          mysql.Cascade,
+	}},
+}
+
+const InsertMatchQuery = "INSERT INTO MatchesTable (userUUID, matchedUUID) VALUES (?, ?)"
+
+// ErrSelfMatch is returned when matching a user with themselves
+var ErrSelfMatch = errors.New("a user can't match with themselves")
+
 type MatchesRepository struct {
 	Repository *neo4j.Repository
+	records    *BaseRepository
 }
 
-func NewMatchesRepository(db *neo4j.Repository) (*MatchesRepository, error) {
+func NewMatchesRepository(db *neo4j.Repository, sql *mysql.Repository) (*MatchesRepository, error) {
 	ur := &MatchesRepository{}
 	ur.Repository = db
+
+	records, err := InitRepository(ur, sql)
+	if err != nil {
+		return nil, err
+	}
+	ur.records = records
 	return ur, nil
 }
 
-func (matches *MatchesRepository) CreateMatch(uuid1 string, uuid2 string) error {
+func (_ *MatchesRepository) CreateTablesQuery() *[]string {
+	return &[]string{}
+}
 
-	node1 := neo4j.NewNode()
-	node1.AddProperty("uuid", uuid1)
-	node1.SetLabel("User")
-	node2 := neo4j.NewNode()
-	node2.AddProperty("uuid", uuid2)
-	node2.SetLabel("User")
+func (_ *MatchesRepository) CreateIndexesQuery() *[]string {
+	return &[]string{}
+}
 
-	n1, err := matches.Repository.CreateNode(node1)
-	if err != nil {
-		log.Error(err)
-		return err
-	}
+// Tables returns the tables declared with mysql.Table
+func (_ *MatchesRepository) Tables() []*mysql.Table {
+	return []*mysql.Table{matchesTable, outboxTable}
+}
 
-	n2, err := matches.Repository.CreateNode(node2)
-	if err != nil {
-		log.Error(err)
-		return err
+// CreateMatch records the match, which reaches Neo4j once the outbox relay publishes it, and publishes
+// events.MatchCreated once committed. Matching a pair again does nothing
+func (matches *MatchesRepository) CreateMatch(ctx context.Context, userUUID uuid.UUID, matchedUUID uuid.UUID) error {
+	if userUUID == matchedUUID {
+		return ErrSelfMatch
 	}
 
-	err = matches.Repository.CreateRelation(n1, n2, "MATCH", true)
-	if err != nil {
-		return err
-	}
+	container := matches.records.Repository
+	pair := outbox.NewPair(userUUID, matchedUUID)
 
-	return nil
+	return container.WithTx(ctx, func(tx *mysql.Tx) error {
+		_, err := container.AddExecuteTransactionContext(tx.Context(), tx.Tx, InsertMatchQuery, []mysql.Column{
+			mysql.NewUUIDColumn("userUUID", pair.A),
+			mysql.NewUUIDColumn("matchedUUID", pair.B),
+		})
+		if err != nil && strings.Contains(err.Error(), duplicateEntryError) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if err := recordEvents(tx.Context(), container, tx.Tx, outbox.NewUsersMatched(userUUID, matchedUUID)); err != nil {
+			return err
+		}
+		events.Default.Publish(tx.Context(), events.MatchCreated{UserUUID: userUUID, MatchedUUID: matchedUUID})
+		return nil
+	})
 }
 
 func (matches *MatchesRepository) GetMatches(userID string) (*[]uuid.UUID, error) {
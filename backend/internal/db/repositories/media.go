@@ -0,0 +1,307 @@
+package repositories
+
+import (
+	"backend/internal/db/adapters/mysql"
+	"backend/internal/models"
+	"context"
+	"fmt"
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+	"strings"
+	"time"
+)
+
+// Uploaded media. The blob itself lives in the blob store under its sha256, this is who uploaded it and what it is
+
+const CreateMediaTableQuery = `
+CREATE TABLE IF NOT EXISTS MediaTable(
+    uuid VARCHAR(36) NOT NULL PRIMARY KEY,
+    sha256 CHAR(64) NOT NULL,
+    contentType VARCHAR(100) NOT NULL,
+    mediaType VARCHAR(50) NOT NULL,
+    size BIGINT NOT NULL,
+    uploadedByUUID VARCHAR(36) NOT NULL,
+    createdAt DATETIME DEFAULT CURRENT_TIMESTAMP
+);`
+
+const CreateMediaHashIndex = "CREATE INDEX idx_media_sha256 ON MediaTable(sha256, uploadedByUUID);"
+
+// The EXIF orientation of uploaded images, the stored file has its EXIF stripped so variants are rotated from this
+const AddMediaOrientationColumnQuery = "ALTER TABLE MediaTable ADD COLUMN orientation TINYINT NOT NULL DEFAULT 1"
+
+// Resized copies of images, a row per variant tracks whether it has been made yet. Ready variants are stored in the
+// blob store under their own sha256
+const CreateMediaVariantsTableQuery = `
+CREATE TABLE IF NOT EXISTS MediaVariantsTable(
+    mediaUUID VARCHAR(36) NOT NULL,
+    variant VARCHAR(20) NOT NULL,
+    status VARCHAR(10) NOT NULL,
+    sha256 CHAR(64) NULL,
+    contentType VARCHAR(100) NULL,
+    width INT NULL,
+    height INT NULL,
+    size BIGINT NULL,
+    attempts INT NOT NULL DEFAULT 0,
+    error VARCHAR(255) NULL,
+    updatedAt DATETIME DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (mediaUUID, variant),
+    FOREIGN KEY (mediaUUID) REFERENCES MediaTable(uuid) ON DELETE CASCADE
+);`
+
+const CreateMediaVariantsStatusIndex = "CREATE INDEX idx_media_variants_status ON MediaVariantsTable(status);"
+
+const InsertMediaQuery = `
+INSERT INTO MediaTable(uuid, sha256, contentType, mediaType, size, uploadedByUUID, createdAt, orientation)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+`
+
+const GetMediaQuery = `
+SELECT uuid, sha256, contentType, mediaType, size, uploadedByUUID, createdAt, orientation
+FROM MediaTable
+WHERE uuid IN (%s)
+`
+
+const GetMediaByHashQuery = `
+SELECT uuid, sha256, contentType, mediaType, size, uploadedByUUID, createdAt, orientation
+FROM MediaTable
+WHERE sha256 = ? AND uploadedByUUID = ?
+LIMIT 1
+`
+
+const InsertMediaVariantsQuery = "INSERT IGNORE INTO MediaVariantsTable(mediaUUID, variant, status) VALUES %s"
+
+const GetMediaVariantsQuery = `
+SELECT mediaUUID, variant, status, COALESCE(sha256, ''), COALESCE(contentType, ''), COALESCE(width, 0),
+       COALESCE(height, 0), COALESCE(size, 0), attempts, COALESCE(error, '')
+FROM MediaVariantsTable
+WHERE mediaUUID IN (%s)
+`
+
+const SetMediaVariantReadyQuery = `
+UPDATE MediaVariantsTable
+SET status = 'ready', sha256 = ?, contentType = ?, width = ?, height = ?, size = ?, error = NULL, updatedAt = ?
+WHERE mediaUUID = ? AND variant = ?
+`
+
+const SetMediaVariantFailedQuery = `
+UPDATE MediaVariantsTable
+SET status = 'failed', attempts = attempts + 1, error = ?, updatedAt = ?
+WHERE mediaUUID = ? AND variant = ?
+`
+
+// Media with variants still to make, pending ones were interrupted by a restart
+const GetUnprocessedMediaQuery = `
+SELECT DISTINCT m.uuid, m.sha256, m.contentType, m.mediaType, m.size, m.uploadedByUUID, m.createdAt, m.orientation
+FROM MediaTable m
+JOIN MediaVariantsTable v ON v.mediaUUID = m.uuid
+WHERE v.status = 'pending' OR (v.status = 'failed' AND v.attempts < ?)
+`
+
+// maxVariantError is the length of MediaVariantsTable.error
+const maxVariantError = 255
+
+type MediaRepository struct {
+	*BaseRepository
+}
+
+// NewMediaRepository initializes a new MediaRepository instance
+func NewMediaRepository(db *mysql.Repository) (*MediaRepository, error) {
+	mr := &MediaRepository{}
+	baseRepo, err := InitRepository(mr, db)
+
+	if err != nil {
+		return nil, err
+	}
+	mr.BaseRepository = baseRepo
+	return mr, nil
+}
+
+// CreateTablesQuery returns a list of SQL queries needed to create necessary tables for media
+func (_ *MediaRepository) CreateTablesQuery() *[]string {
+	return &[]string{CreateMediaTableQuery, CreateMediaVariantsTableQuery}
+}
+
+// MigrationQueries adds the columns added since MediaTable was created
+func (_ *MediaRepository) MigrationQueries() *[]string {
+	return &[]string{AddMediaOrientationColumnQuery}
+}
+
+// CreateIndexesQuery returns a list of SQL queries needed to create necessary indexes for media
+func (_ *MediaRepository) CreateIndexesQuery() *[]string {
+	return &[]string{CreateMediaHashIndex, CreateMediaVariantsStatusIndex}
+}
+
+func (repo *MediaRepository) CreateMedia(ctx context.Context, media *models.MediaFileModel) error {
+	columns := []mysql.Column{
+		mysql.NewUUIDColumn("uuid", media.UUID),
+		mysql.NewVarcharColumn("sha256", media.SHA256),
+		mysql.NewVarcharColumn("contentType", media.ContentType),
+		mysql.NewVarcharColumn("mediaType", media.Type.String()),
+		mysql.NewIntegerColumn("size", media.Size),
+		mysql.NewUUIDColumn("uploadedByUUID", media.UploadedByUUID),
+		mysql.NewDateTimeColumn("createdAt", media.CreatedAt),
+		mysql.NewIntegerColumn("orientation", int64(max(media.Orientation, 1))),
+	}
+
+	_, err := repo.Repository.ExecuteInsertContext(ctx, InsertMediaQuery, columns, mysql.InsertOptions{})
+	if err != nil {
+		log.Error(err)
+	}
+	return err
+}
+
+// GetMedia returns the media that exist out of mediaUUIDs, in no particular order
+func (repo *MediaRepository) GetMedia(ctx context.Context, mediaUUIDs ...uuid.UUID) ([]models.MediaFileModel, error) {
+	if len(mediaUUIDs) == 0 {
+		return nil, nil
+	}
+
+	var columns []mysql.Column
+	for _, mediaUUID := range mediaUUIDs {
+		columns = append(columns, mysql.NewUUIDColumn("uuid", mediaUUID))
+	}
+
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(mediaUUIDs)), ",")
+	return repo.query(ctx, fmt.Sprintf(GetMediaQuery, placeholders), columns)
+}
+
+// GetMediaByHash returns the users earlier upload of the same content, nil if they haven't uploaded it
+func (repo *MediaRepository) GetMediaByHash(ctx context.Context, sha256 string, userUUID uuid.UUID) (*models.MediaFileModel, error) {
+	media, err := repo.query(ctx, GetMediaByHashQuery, []mysql.Column{
+		mysql.NewVarcharColumn("sha256", sha256),
+		mysql.NewUUIDColumn("uploadedByUUID", userUUID),
+	})
+	if err != nil || len(media) == 0 {
+		return nil, err
+	}
+	return &media[0], nil
+}
+
+func (repo *MediaRepository) query(ctx context.Context, query string, columns []mysql.Column) ([]models.MediaFileModel, error) {
+	rows, err := repo.Repository.ExecuteQueryContext(ctx, query, columns, mysql.QueryOptions{})
+	if err != nil {
+		log.Error(err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var media []models.MediaFileModel
+	for rows.Next() {
+		var file models.MediaFileModel
+		var mediaType string
+
+		err := rows.Scan(&file.UUID, &file.SHA256, &file.ContentType, &mediaType, &file.Size, &file.UploadedByUUID, &file.CreatedAt,
+			&file.Orientation)
+		if err != nil {
+			log.Error(err)
+			return nil, err
+		}
+
+		if file.Type, err = models.ParseMediaType(mediaType); err != nil {
+			return nil, err
+		}
+		media = append(media, file)
+	}
+	return media, nil
+}
+
+// CreateVariants adds pending variants to the media, variants it already has are left as they are
+func (repo *MediaRepository) CreateVariants(ctx context.Context, mediaUUID uuid.UUID, names []string) error {
+	if len(names) == 0 {
+		return nil
+	}
+
+	var columns []mysql.Column
+	for _, name := range names {
+		columns = append(columns,
+			mysql.NewUUIDColumn("mediaUUID", mediaUUID),
+			mysql.NewVarcharColumn("variant", name),
+			mysql.NewVarcharColumn("status", string(models.VariantPending)),
+		)
+	}
+
+	values := strings.TrimSuffix(strings.Repeat("(?, ?, ?),", len(names)), ",")
+	_, err := repo.Repository.ExecuteInsertContext(ctx, fmt.Sprintf(InsertMediaVariantsQuery, values), columns, mysql.InsertOptions{})
+	if err != nil {
+		log.Error(err)
+	}
+	return err
+}
+
+// GetVariants returns the variants of each of the media, media without variants aren't in the map
+func (repo *MediaRepository) GetVariants(ctx context.Context, mediaUUIDs ...uuid.UUID) (map[uuid.UUID][]models.MediaVariantModel, error) {
+	variants := map[uuid.UUID][]models.MediaVariantModel{}
+	if len(mediaUUIDs) == 0 {
+		return variants, nil
+	}
+
+	var columns []mysql.Column
+	for _, mediaUUID := range mediaUUIDs {
+		columns = append(columns, mysql.NewUUIDColumn("mediaUUID", mediaUUID))
+	}
+
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(mediaUUIDs)), ",")
+	rows, err := repo.Repository.ExecuteQueryContext(ctx, fmt.Sprintf(GetMediaVariantsQuery, placeholders), columns, mysql.QueryOptions{})
+	if err != nil {
+		log.Error(err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var variant models.MediaVariantModel
+		err := rows.Scan(&variant.MediaUUID, &variant.Name, &variant.Status, &variant.SHA256, &variant.ContentType,
+			&variant.Width, &variant.Height, &variant.Size, &variant.Attempts, &variant.Error)
+		if err != nil {
+			log.Error(err)
+			return nil, err
+		}
+		variants[variant.MediaUUID] = append(variants[variant.MediaUUID], variant)
+	}
+	return variants, nil
+}
+
+// SetVariantReady records the stored variant
+func (repo *MediaRepository) SetVariantReady(ctx context.Context, variant *models.MediaVariantModel) error {
+	columns := []mysql.Column{
+		mysql.NewVarcharColumn("sha256", variant.SHA256),
+		mysql.NewVarcharColumn("contentType", variant.ContentType),
+		mysql.NewIntegerColumn("width", int64(variant.Width)),
+		mysql.NewIntegerColumn("height", int64(variant.Height)),
+		mysql.NewIntegerColumn("size", variant.Size),
+		mysql.NewDateTimeColumn("updatedAt", time.Now().UTC()),
+		mysql.NewUUIDColumn("mediaUUID", variant.MediaUUID),
+		mysql.NewVarcharColumn("variant", variant.Name),
+	}
+
+	_, err := repo.Repository.ExecuteInsertContext(ctx, SetMediaVariantReadyQuery, columns, mysql.InsertOptions{})
+	if err != nil {
+		log.Error(err)
+	}
+	return err
+}
+
+// SetVariantFailed records why the variant couldn't be made, counting the attempt
+func (repo *MediaRepository) SetVariantFailed(ctx context.Context, mediaUUID uuid.UUID, name string, reason string) error {
+	if len(reason) > maxVariantError {
+		reason = reason[:maxVariantError]
+	}
+
+	columns := []mysql.Column{
+		mysql.NewVarcharColumn("error", reason),
+		mysql.NewDateTimeColumn("updatedAt", time.Now().UTC()),
+		mysql.NewUUIDColumn("mediaUUID", mediaUUID),
+		mysql.NewVarcharColumn("variant", name),
+	}
+
+	_, err := repo.Repository.ExecuteInsertContext(ctx, SetMediaVariantFailedQuery, columns, mysql.InsertOptions{})
+	if err != nil {
+		log.Error(err)
+	}
+	return err
+}
+
+// GetUnprocessedMedia returns media with pending variants, or failed variants that have had fewer than maxAttempts
+func (repo *MediaRepository) GetUnprocessedMedia(ctx context.Context, maxAttempts int) ([]models.MediaFileModel, error) {
+	return repo.query(ctx, GetUnprocessedMediaQuery, []mysql.Column{mysql.NewIntegerColumn("attempts", int64(maxAttempts))})
+}
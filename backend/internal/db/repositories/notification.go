@@ -0,0 +1,140 @@
+package repositories
+
+import (
+	"backend/internal/db/adapters/mysql"
+	"backend/internal/models"
+	"context"
+	"database/sql"
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+)
+
+// In app notifications. Unique on (user, type, reference) so the same event never notifies twice
+
+const CreateNotificationsTableQuery = `
+CREATE TABLE IF NOT EXISTS NotificationsTable(
+    uuid VARCHAR(36) PRIMARY KEY,
+    userUUID VARCHAR(36) NOT NULL,
+    notificationType VARCHAR(50) NOT NULL,
+    message TEXT NOT NULL,
+    referenceUUID VARCHAR(36) NULL,
+    createdAt DATETIME DEFAULT CURRENT_TIMESTAMP,
+    readAt DATETIME NULL,
+    UNIQUE (userUUID, notificationType, referenceUUID),
+    FOREIGN KEY (userUUID) REFERENCES UserTable(uuid) ON DELETE CASCADE
+);`
+
+const CreateNotificationUserIndex = "CREATE INDEX idx_notification_user_created ON NotificationsTable(userUUID, createdAt);"
+
+// NotifyOrganisationFollowersQuery fans a new opportunity out to every follower who asked to be notified
+const NotifyOrganisationFollowersQuery = `
+INSERT IGNORE INTO NotificationsTable(uuid, userUUID, notificationType, message, referenceUUID)
+SELECT UUID(), f.userUUID, ?, CONCAT(o.name, ' posted a new opportunity: ', ot.title), ot.uuid
+FROM OpportunitiesTable ot
+INNER JOIN OrganisationTable o
+    ON o.uuid = ot.organisationUUID
+INNER JOIN OrganisationFollowersTable f
+    ON f.organisationUUID = ot.organisationUUID AND f.notify = TRUE
+WHERE ot.uuid = ?
+`
+
+const GetNotificationsQuery = `
+SELECT uuid, userUUID, notificationType, message, referenceUUID, createdAt, readAt
+FROM NotificationsTable
+WHERE userUUID = ?
+ORDER BY createdAt DESC
+LIMIT ?
+`
+
+const MarkNotificationReadQuery = `
+UPDATE NotificationsTable
+SET readAt = CURRENT_TIMESTAMP
+WHERE uuid = ? AND userUUID = ? AND readAt IS NULL
+`
+
+type NotificationRepository struct {
+	*BaseRepository
+}
+
+// NewNotificationRepository initializes a new NotificationRepository instance
+func NewNotificationRepository(db *mysql.Repository) (*NotificationRepository, error) {
+	nr := &NotificationRepository{}
+	baseRepo, err := InitRepository(nr, db)
+
+	if err != nil {
+		return nil, err
+	}
+	nr.BaseRepository = baseRepo
+	return nr, nil
+}
+
+// CreateTablesQuery returns a list of SQL queries needed to create necessary tables for notifications
+func (_ *NotificationRepository) CreateTablesQuery() *[]string {
+	return &[]string{CreateNotificationsTableQuery}
+}
+
+// CreateIndexesQuery returns a list of SQL queries needed to create necessary indexes for notifications
+func (_ *NotificationRepository) CreateIndexesQuery() *[]string {
+	return &[]string{CreateNotificationUserIndex}
+}
+
+// NotifyOrganisationFollowers notifies followers of the opportunities organisation. Does nothing if it has no organisation
+func (repo *NotificationRepository) NotifyOrganisationFollowers(ctx context.Context, opportunityUUID uuid.UUID) (int64, error) {
+	columns := []mysql.Column{
+		mysql.NewVarcharColumn("notificationType", models.NotificationNewOpportunity),
+		mysql.NewUUIDColumn("opportunityUUID", opportunityUUID),
+	}
+
+	return repo.Repository.ExecuteInsertContext(ctx, NotifyOrganisationFollowersQuery, columns, mysql.InsertOptions{})
+}
+
+// GetNotifications returns the users most recent notifications
+func (repo *NotificationRepository) GetNotifications(ctx context.Context, userUUID uuid.UUID, limit int64) ([]models.NotificationModel, error) {
+	columns := []mysql.Column{
+		mysql.NewUUIDColumn("userUUID", userUUID),
+		mysql.NewIntegerColumn("limit", limit),
+	}
+
+	rows, err := repo.Repository.ExecuteQueryContext(ctx, GetNotificationsQuery, columns, mysql.QueryOptions{})
+	if err != nil {
+		log.Error(err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	notifications := []models.NotificationModel{}
+
+	for rows.Next() {
+		var notification models.NotificationModel
+		var referenceUUID uuid.NullUUID
+		var readAt sql.NullTime
+
+		err = rows.Scan(&notification.UUID, &notification.UserUUID, &notification.NotificationType, &notification.Message,
+			&referenceUUID, &notification.CreatedAt, &readAt)
+		if err != nil {
+			log.Error(err)
+			return nil, err
+		}
+
+		notification.ReferenceUUID = nullableUUID(referenceUUID)
+		if readAt.Valid {
+			read := readAt.Time
+			notification.ReadAt = &read
+		}
+
+		notifications = append(notifications, notification)
+	}
+
+	return notifications, nil
+}
+
+// MarkRead marks the notification read. Returns false if it doesn't belong to the user or was already read
+func (repo *NotificationRepository) MarkRead(ctx context.Context, notificationUUID uuid.UUID, userUUID uuid.UUID) (bool, error) {
+	columns := []mysql.Column{
+		mysql.NewUUIDColumn("uuid", notificationUUID),
+		mysql.NewUUIDColumn("userUUID", userUUID),
+	}
+
+	affected, err := repo.Repository.ExecuteInsertContext(ctx, MarkNotificationReadQuery, columns, mysql.InsertOptions{})
+	return affected > 0, err
+}
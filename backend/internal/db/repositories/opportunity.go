@@ -2,7 +2,14 @@ package repositories
 
 import (
 	"backend/internal/db/adapters/mysql"
+	"backend/internal/events"
+	"backend/internal/geo"
 	"backend/internal/models"
+	"backend/internal/outbox"
+	"backend/internal/schedule"
+	"backend/internal/taxonomy"
+	"backend/internal/utils/concurrency"
+	"context"
 	"database/sql"
 	"fmt"
 	"github.com/google/uuid"
@@ -20,6 +27,8 @@ import (
 //Use auto_increment id for easy lookups when doing index based searching. I.e look for posts between ID=12 and ID=20
 
 const CreateOpportunityPostedByIndex = "CREATE INDEX idx_opportunity_posted_by ON OpportunitiesTable(postedByUUID);"
+const CreateOpportunityCoordinatesIndex = "CREATE SPATIAL INDEX sp_opportunity_coordinates ON OpportunitiesTable(coordinates);"
+const CreateOpportunityExpiresIndex = "CREATE INDEX idx_opportunity_expires ON OpportunitiesTable(closedAt, expiresAt);"
 
 const InsertOpportunityQuery = `
 INSERT INTO OpportunitiesTable (
@@ -29,19 +38,45 @@ INSERT INTO OpportunitiesTable (
 	points,
     location,
     opportunityType,
-    postedByUUID
-) VALUES (?, ?, ?, ?, ?, ?, ?);
+    postedByUUID,
+    organisationUUID,
+    latitude,
+    longitude,
+    addressLine,
+    city,
+    region,
+    postcode,
+    country,
+    isOnline,
+    coordinates,
+    startsAt,
+    endsAt,
+    timeZone,
+    recurrence,
+    deadline,
+    capacity,
+    expiresAt,
+    draft
+) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ST_GeomFromText(?, 4326), ?, ?, ?, ?, ?, ?, ?, ?);
 `
 
 const UpdateOpportunityQuery = `
 UPDATE OpportunitiesTable
-SET title = ?, description = ?, points = ?, location = ?, opportunityType = ?
+SET -- Calendar subscribers only pick up a new time if the sequence increases, so bump it when the schedule changes.
+    -- This is assigned first so it compares against the old schedule
+    sequence = sequence + IF(startsAt <=> ? AND endsAt <=> ? AND timeZone = ? AND recurrence <=> ?, 0, 1),
+    title = ?, description = ?, points = ?, location = ?, opportunityType = ?,
+    latitude = ?, longitude = ?, addressLine = ?, city = ?, region = ?, postcode = ?, country = ?, isOnline = ?,
+    coordinates = ST_GeomFromText(?, 4326),
+    startsAt = ?, endsAt = ?, timeZone = ?, recurrence = ?, deadline = ?, capacity = ?, expiresAt = ?,
+    -- Moving the deadline or end later reopens an opportunity that was auto-closed. MySQL assigns left to right
+    closedAt = IF(expiresAt IS NULL OR expiresAt > ?, NULL, closedAt)
 WHERE uuid = ?`
 
 const UpdateApproveOpporunityQuery = `
 UPDATE OpportunitiesTable 
 SET approved = ? 
-WHERE uuid = ?
+WHERE uuid = ? AND NOT (approved <=> ?)
 `
 
 // TODO Extract things i need don't want all
@@ -67,6 +102,17 @@ LEFT JOIN TagsTable
 WHERE OpportunitiesTable.postedByUUID IN (%s);
 `
 
+const GetOpportunityByOrganisationIDQuery = `
+SELECT * FROM OpportunitiesTable
+LEFT JOIN OpportunityMediaTable 
+  ON OpportunitiesTable.uuid = OpportunityMediaTable.opportunityUUID
+LEFT JOIN OpportunityTagsTable 
+  ON OpportunitiesTable.uuid = OpportunityTagsTable.opportunityUUID
+LEFT JOIN TagsTable 
+  ON OpportunityTagsTable.tagID = TagsTable.id
+WHERE OpportunitiesTable.organisationUUID IN (%s);
+`
+
 const GetOpportunityByFromQuery = `
 WITH limited_opportunities AS (
   SELECT * FROM OpportunitiesTable
@@ -79,6 +125,7 @@ WITH limited_opportunities AS (
     SELECT opportunityUUID FROM OpportunityDislikesTable WHERE userUUID = ?
   )
   AND approved = TRUE
+  AND closedAt IS NULL AND (expiresAt IS NULL OR expiresAt > ?)
   ORDER BY id ASC
   LIMIT ? -- Second parameter: limit
 )
@@ -95,6 +142,55 @@ LEFT JOIN TagsTable tt
   ON ott.tagID = tt.id;
 `
 
+// Structured locations, added to existing databases by these migrations.
+// coordinates can't be NULL to be spatially indexed so opportunities without a location store POINT(0 0) and a NULL latitude
+
+var opportunityLocationMigrations = []string{
+	"ALTER TABLE OpportunitiesTable ADD COLUMN latitude DOUBLE NULL",
+	"ALTER TABLE OpportunitiesTable ADD COLUMN longitude DOUBLE NULL",
+	"ALTER TABLE OpportunitiesTable ADD COLUMN addressLine VARCHAR(255) NULL",
+	"ALTER TABLE OpportunitiesTable ADD COLUMN city VARCHAR(100) NULL",
+	"ALTER TABLE OpportunitiesTable ADD COLUMN region VARCHAR(100) NULL",
+	"ALTER TABLE OpportunitiesTable ADD COLUMN postcode VARCHAR(20) NULL",
+	"ALTER TABLE OpportunitiesTable ADD COLUMN country CHAR(2) NULL",
+	"ALTER TABLE OpportunitiesTable ADD COLUMN isOnline BOOL NOT NULL DEFAULT FALSE",
+	"ALTER TABLE OpportunitiesTable ADD COLUMN coordinates POINT NOT NULL SRID 4326 DEFAULT (ST_SRID(POINT(0, 0), 4326))",
+}
+
+// Scheduling. Times are UTC, timeZone is the IANA zone they're shown and repeated in.
+// expiresAt is computed on save so closing and hiding expired opportunities doesn't need to expand recurrences in SQL
+
+var opportunityScheduleMigrations = []string{
+	"ALTER TABLE OpportunitiesTable ADD COLUMN startsAt DATETIME NULL",
+	"ALTER TABLE OpportunitiesTable ADD COLUMN endsAt DATETIME NULL",
+	"ALTER TABLE OpportunitiesTable ADD COLUMN timeZone VARCHAR(64) NOT NULL DEFAULT 'UTC'",
+	"ALTER TABLE OpportunitiesTable ADD COLUMN recurrence VARCHAR(255) NULL",
+	"ALTER TABLE OpportunitiesTable ADD COLUMN deadline DATETIME NULL",
+	"ALTER TABLE OpportunitiesTable ADD COLUMN capacity INT NULL",
+	"ALTER TABLE OpportunitiesTable ADD COLUMN expiresAt DATETIME NULL",
+	"ALTER TABLE OpportunitiesTable ADD COLUMN closedAt DATETIME NULL",
+	// sequence is the iCalendar SEQUENCE, increased whenever the schedule changes
+	"ALTER TABLE OpportunitiesTable ADD COLUMN sequence INT NOT NULL DEFAULT 0",
+}
+
+// Drafts are saved but not submitted for review
+const AddOpportunityDraftColumnQuery = "ALTER TABLE OpportunitiesTable ADD COLUMN draft BOOL NOT NULL DEFAULT FALSE"
+
+const SubmitOpportunityDraftQuery = "UPDATE OpportunitiesTable SET draft = FALSE WHERE uuid = ?"
+
+// Uploaded media is attached by its MediaTable uuid, mediaURL is only set on rows added before uploads were stored
+const AddOpportunityMediaUUIDColumnQuery = "ALTER TABLE OpportunityMediaTable ADD COLUMN mediaUUID VARCHAR(36) NULL"
+
+// openOpportunityCondition hides closed opportunities, including expired ones the closer hasn't got to yet.
+// {t} is the table alias and the parameter is the current time
+const openOpportunityCondition = "{t}.closedAt IS NULL AND ({t}.expiresAt IS NULL OR {t}.expiresAt > ?)"
+
+const CloseExpiredOpportunitiesQuery = `
+UPDATE OpportunitiesTable
+SET closedAt = ?
+WHERE closedAt IS NULL AND expiresAt <= ?
+`
+
 const DeleteOpportunityQuery = "DELETE FROM OpportunitiesTable WHERE uuid = ?"
 
 // Tracks opportunities a user has liked
@@ -195,8 +291,8 @@ const GetOpportunitiesByTagQuery = "SELECT opportunityUUID FROM OpportunityTagsT
 
 // Attatching images to opportunity
 
-const InsertOpportunityMediaQuery = "INSERT IGNORE INTO OpportunityMediaTable (opportunityUUID, mediaURL, mediaType) VALUES (?,?,?)"
-const GetOpportunityMediaQuery = "SELECT mediaURL, mediaType FROM OpportunityMediaTable WHERE opportunityUUID = ?"
+const InsertOpportunityMediaQuery = "INSERT IGNORE INTO OpportunityMediaTable (opportunityUUID, mediaURL, mediaType, mediaUUID) VALUES (?,?,?,?)"
+const GetOpportunityMediaQuery = "SELECT mediaURL, mediaType, mediaUUID FROM OpportunityMediaTable WHERE opportunityUUID = ? ORDER BY id"
 const DeleteOpportunityMediaQuery = `
 DELETE FROM OpportunityMediaTable WHERE opportunityUUID = ?
 `
@@ -217,34 +313,46 @@ func NewOpportunityRepository(db *mysql.Repository) (*OpportunityRepository, err
 }
 
 func (_ *OpportunityRepository) CreateTablesQuery() *[]string {
-	var queries []string
+	queries := []string{CreateOpportunityRevisionsTableQuery}
 	return &queries //Was for in code creation
 }
 
+// MigrationQueries adds the columns added since OpportunitiesTable was created.
+// organisationUUID is also added by OrganisationRepository, whichever runs first adds it so the column order is always the same
+func (_ *OpportunityRepository) MigrationQueries() *[]string {
+	queries := []string{AddOpportunityOrganisationColumnQuery}
+	queries = append(queries, opportunityLocationMigrations...)
+	queries = append(queries, opportunityScheduleMigrations...)
+	queries = append(queries, AddOpportunityMediaUUIDColumnQuery, AddOpportunityDraftColumnQuery)
+	return &queries
+}
+
 // CreateIndexesQuery returns a list of SQL queries needed to create necessary indexes for user management
 func (_ *OpportunityRepository) CreateIndexesQuery() *[]string {
-	return &[]string{CreateOpportunityPostedByIndex, CreateOpportunityLikedIndex, CreateOpportunityDislikedIndex, CreateTagIndex}
+	return &[]string{CreateOpportunityPostedByIndex, CreateOpportunityLikedIndex, CreateOpportunityDislikedIndex, CreateTagIndex,
+		CreateOpportunityCoordinatesIndex, CreateOpportunityExpiresIndex}
 }
 
-func (repo *OpportunityRepository) UpdateOpportunityStatus(opportunityUUID uuid.UUID, status bool) error {
+// UpdateOpportunityStatus approves or unapproves the opportunity, returning whether it wasn't already
+func (repo *OpportunityRepository) UpdateOpportunityStatus(ctx context.Context, opportunityUUID uuid.UUID, status bool) (bool, error) {
 	container := repo.Repository
 
 	columns := []mysql.Column{
 		mysql.NewBoolColumn("approved", status),
 		mysql.NewUUIDColumn("uuid", opportunityUUID),
+		mysql.NewBoolColumn("current", status),
 	}
 
-	_, err := container.ExecuteQuery(UpdateApproveOpporunityQuery, columns, mysql.QueryOptions{})
+	changed, err := container.ExecuteInsertContext(ctx, UpdateApproveOpporunityQuery, columns, mysql.InsertOptions{})
 	if err != nil {
 		log.Error(err)
-		return err
+		return false, err
 	}
 
-	return nil
-
+	return changed > 0, nil
 }
 
-func (repo *OpportunityRepository) CreateOpportunity(model *models.OpportunityModel) error {
+func (repo *OpportunityRepository) CreateOpportunity(ctx context.Context, model *models.OpportunityModel) error {
 
 	container := repo.Repository
 
@@ -256,92 +364,176 @@ func (repo *OpportunityRepository) CreateOpportunity(model *models.OpportunityMo
 		mysql.NewVarcharColumn("location", model.Location),
 		mysql.NewVarcharColumn("opportunityType", model.OpportunityType),
 		mysql.NewUUIDColumn("postedByUUID", model.PostedByUUID),
+		mysql.NewNullableUUIDColumn("organisationUUID", model.OrganisationUUID),
 	}
+	columns = append(columns, locationColumns(model)...)
+	columns = append(columns, scheduleColumns(model)...)
+	columns = append(columns, mysql.NewBoolColumn("draft", model.Draft))
+
+	return container.WithTx(ctx, func(tx *mysql.Tx) error {
+		_, err := container.AddExecuteTransactionContext(tx.Context(), tx.Tx, InsertOpportunityQuery, columns)
+		if err != nil {
+			log.Error(err)
+			return err
+		}
+
+		if err := handlePostTags(tx.Context(), tx.Tx, model, container); err != nil {
+			return err
+		}
+
+		if err := handlePostMedia(tx.Context(), tx.Tx, model, container); err != nil {
+			return err
+		}
+
+		if model.ExternalRef != "" {
+			if err := insertExternalRef(tx.Context(), container, tx.Tx, model); err != nil {
+				return err
+			}
+		}
+
+		if err := recordRevision(tx.Context(), container, tx.Tx, model, model.PostedByUUID, time.Now(), nil); err != nil {
+			return err
+		}
+
+		return recordEvents(tx.Context(), container, tx.Tx, outbox.NewOpportunityCreated(model.UUID))
+	})
+}
 
-	transaction, err := container.StartTransaction()
+// SubmitDraft submits the draft for review
+func (repo *OpportunityRepository) SubmitDraft(ctx context.Context, opportunityUUID uuid.UUID) error {
+	_, err := repo.Repository.ExecuteInsertContext(ctx, SubmitOpportunityDraftQuery, []mysql.Column{
+		mysql.NewUUIDColumn("uuid", opportunityUUID),
+	}, mysql.InsertOptions{})
 	if err != nil {
 		log.Error(err)
-		return err
 	}
+	return err
+}
 
-	defer transaction.Rollback()
+// locationColumns returns the structured location columns in the order they're written
+func locationColumns(model *models.OpportunityModel) []mysql.Column {
+	var latitude, longitude *float64
+	point := geo.Coordinates{}
+	if model.Coordinates != nil {
+		latitude, longitude = &model.Coordinates.Latitude, &model.Coordinates.Longitude
+		point = *model.Coordinates
+	}
 
-	_, err = container.AddExecuteTransaction(transaction, InsertOpportunityQuery, columns)
-	if err != nil {
-		log.Error(err)
-		return err
+	return []mysql.Column{
+		mysql.NewNullableDoubleColumn("latitude", latitude),
+		mysql.NewNullableDoubleColumn("longitude", longitude),
+		mysql.NewVarcharColumn("addressLine", model.Address.Line),
+		mysql.NewVarcharColumn("city", model.Address.City),
+		mysql.NewVarcharColumn("region", model.Address.Region),
+		mysql.NewVarcharColumn("postcode", model.Address.Postcode),
+		mysql.NewVarcharColumn("country", model.Address.Country),
+		mysql.NewBoolColumn("isOnline", model.IsOnline),
+		mysql.NewVarcharColumn("coordinates", point.WKT()),
 	}
+}
 
-	err = handlePostTags(transaction, model, container)
-	if err != nil {
-		return err
+// scheduleColumns returns the scheduling columns in the order they're written, closedAt is only changed by closing
+func scheduleColumns(model *models.OpportunityModel) []mysql.Column {
+	var recurrence *string
+	if model.Recurrence != "" {
+		recurrence = &model.Recurrence
 	}
 
-	err = handlePostMedia(transaction, model, container)
-	if err != nil {
-		return err
+	timeZone := model.TimeZone
+	if timeZone == "" {
+		timeZone = "UTC"
+	}
+
+	return []mysql.Column{
+		mysql.NewNullableDateTimeColumn("startsAt", model.StartsAt),
+		mysql.NewNullableDateTimeColumn("endsAt", model.EndsAt),
+		mysql.NewVarcharColumn("timeZone", timeZone),
+		mysql.NewNullableVarcharColumn("recurrence", recurrence),
+		mysql.NewNullableDateTimeColumn("deadline", model.Deadline),
+		mysql.NewNullableIntegerColumn("capacity", model.Capacity),
+		mysql.NewNullableDateTimeColumn("expiresAt", model.ExpiresAt),
+	}
+}
+
+// CloseExpired closes every opportunity that expired at or before now, returning how many were closed
+func (repo *OpportunityRepository) CloseExpired(ctx context.Context, now time.Time) (int64, error) {
+	columns := []mysql.Column{
+		mysql.NewDateTimeColumn("closedAt", now.UTC()),
+		mysql.NewDateTimeColumn("expiresAt", now.UTC()),
 	}
 
-	return container.CommitTransaction(transaction)
+	return repo.Repository.ExecuteInsertContext(ctx, CloseExpiredOpportunitiesQuery, columns, mysql.InsertOptions{})
+}
 
+// UpdateOpportunity saves the edit as a new revision by the author
+func (repo *OpportunityRepository) UpdateOpportunity(ctx context.Context, model *models.OpportunityModel, authorUUID uuid.UUID) error {
+	return repo.updateOpportunity(ctx, model, authorUUID, nil)
 }
 
-func (repo *OpportunityRepository) UpdateOpportunity(model *models.OpportunityModel) error {
+// RollbackOpportunity saves the model, an old revision restored by the author, as a new revision
+func (repo *OpportunityRepository) RollbackOpportunity(ctx context.Context, model *models.OpportunityModel, authorUUID uuid.UUID, revision int64) error {
+	return repo.updateOpportunity(ctx, model, authorUUID, &revision)
+}
+
+func (repo *OpportunityRepository) updateOpportunity(ctx context.Context, model *models.OpportunityModel, authorUUID uuid.UUID, rolledBackFrom *int64) error {
 	container := repo.Repository
 
 	uuidColumn := mysql.NewUUIDColumn("uuid", model.UUID)
 
-	columns := []mysql.Column{
+	scheduling := scheduleColumns(model)
+
+	// startsAt, endsAt, timeZone and recurrence decide whether the sequence changes
+	columns := append([]mysql.Column{}, scheduling[:4]...)
+	columns = append(columns,
 		mysql.NewVarcharColumn("title", model.Title),
 		mysql.NewVarcharColumn("description", model.Description),
 		mysql.NewIntegerColumn("points", model.Points),
 		mysql.NewVarcharColumn("location", model.Location),
 		mysql.NewVarcharColumn("opportunityType", model.OpportunityType),
-		uuidColumn,
-	}
+	)
+	columns = append(columns, locationColumns(model)...)
+	columns = append(columns, scheduling...)
+	columns = append(columns, mysql.NewDateTimeColumn("now", time.Now().UTC()), uuidColumn)
 
-	transaction, err := container.StartTransaction()
-	if err != nil {
-		log.Error(err)
-		return err
-	}
+	return container.WithTx(ctx, func(tx *mysql.Tx) error {
+		if err := lockOpportunity(tx.Context(), container, tx.Tx, model.UUID); err != nil {
+			return err
+		}
 
-	defer transaction.Rollback()
-	_, err = container.AddExecuteTransaction(transaction, UpdateOpportunityQuery, columns)
-	if err != nil {
-		log.Error(err)
-		return err
-	}
+		_, err := container.AddExecuteTransactionContext(tx.Context(), tx.Tx, UpdateOpportunityQuery, columns)
+		if err != nil {
+			log.Error(err)
+			return err
+		}
 
-	//Delete old tags
+		//Delete old tags
 
-	_, err = container.AddExecuteTransaction(transaction, DeleteOpportunityTagsQuery, []mysql.Column{uuidColumn})
-	if err != nil {
-		log.Error(err)
-		return err
-	}
+		_, err = container.AddExecuteTransactionContext(tx.Context(), tx.Tx, DeleteOpportunityTagsQuery, []mysql.Column{uuidColumn})
+		if err != nil {
+			log.Error(err)
+			return err
+		}
 
-	_, err = container.AddExecuteTransaction(transaction, DeleteOpportunityMediaQuery, []mysql.Column{uuidColumn})
-	if err != nil {
-		log.Error(err)
-		return err
-	}
+		_, err = container.AddExecuteTransactionContext(tx.Context(), tx.Tx, DeleteOpportunityMediaQuery, []mysql.Column{uuidColumn})
+		if err != nil {
+			log.Error(err)
+			return err
+		}
 
-	//Handle new shit
-	err = handlePostTags(transaction, model, container)
-	if err != nil {
-		return err
-	}
+		//Handle new shit
+		if err := handlePostTags(tx.Context(), tx.Tx, model, container); err != nil {
+			return err
+		}
 
-	err = handlePostMedia(transaction, model, container)
-	if err != nil {
-		return err
-	}
+		if err := handlePostMedia(tx.Context(), tx.Tx, model, container); err != nil {
+			return err
+		}
 
-	return container.CommitTransaction(transaction)
+		return recordRevision(tx.Context(), container, tx.Tx, model, authorUUID, time.Now(), rolledBackFrom)
+	})
 }
 
-func handlePostMedia(transaction *sql.Tx, model *models.OpportunityModel, container *mysql.Repository) error {
+func handlePostMedia(ctx context.Context, transaction *sql.Tx, model *models.OpportunityModel, container *mysql.Repository) error {
 	images := *model.Media
 
 	if len(images) < 1 {
@@ -352,11 +544,12 @@ func handlePostMedia(transaction *sql.Tx, model *models.OpportunityModel, contai
 
 		columns := []mysql.Column{
 			mysql.NewUUIDColumn("opportunityUUID", model.UUID),
-			mysql.NewTextColumn("mediaURL", image.URL),
+			mysql.NewTextColumn("mediaURL", storedMediaURL(image)),
 			mysql.NewVarcharColumn("mediaType", image.Type.String()),
+			mysql.NewNullableUUIDColumn("mediaUUID", image.ID),
 		}
 
-		_, err := container.AddExecuteTransaction(transaction, InsertOpportunityMediaQuery, columns)
+		_, err := container.AddExecuteTransactionContext(ctx, transaction, InsertOpportunityMediaQuery, columns)
 		if err != nil {
 			log.Error(err)
 			return err
@@ -367,22 +560,31 @@ func handlePostMedia(transaction *sql.Tx, model *models.OpportunityModel, contai
 
 }
 
-func handlePostTags(transaction *sql.Tx, postModel *models.OpportunityModel, container *mysql.Repository) error {
+func handlePostTags(ctx context.Context, transaction *sql.Tx, postModel *models.OpportunityModel, container *mysql.Repository) error {
 	tags := *postModel.Tags
 	var columns []mysql.Column
 	if len(tags) < 1 {
 		return nil
 	}
 
-	//Insert tags
+	//Insert tags. Names that resolve to the same tag, e.g. a tag and its synonym, are only added once
+	resolved := make([]models.TagModel, 0, len(tags))
+	seen := map[int64]bool{}
 	for _, tag := range tags {
+		if taxonomy.Normalise(tag.TagName) == "" {
+			continue
+		}
 
-		tagName := tag.TagName
-
-		tag, err := GetTagModelByName(tagName, container, true)
+		tag, err := ResolveTag(ctx, container, transaction, tag.TagName, true)
 		if err != nil {
 			return err
 		}
+		if seen[tag.ID] {
+			continue
+		}
+		seen[tag.ID] = true
+		resolved = append(resolved, *tag)
+
 		//Insert tagID and postID into table
 
 		columns = []mysql.Column{
@@ -390,17 +592,20 @@ func handlePostTags(transaction *sql.Tx, postModel *models.OpportunityModel, con
 			mysql.NewIntegerColumn("tagID", tag.ID),
 		}
 
-		_, err = container.AddExecuteTransaction(transaction, CreateOpportunityTagsQuery, columns)
+		_, err = container.AddExecuteTransactionContext(ctx, transaction, CreateOpportunityTagsQuery, columns)
 		if err != nil {
 			log.Error(err)
 			return err
 		}
 
 	}
+
+	// Callers get the tags as stored
+	*postModel.Tags = resolved
 	return nil
 }
 
-func (repo *OpportunityRepository) GetOpportunity(opportunityUUIDs ...*uuid.UUID) (*[]models.OpportunityModel, error) {
+func (repo *OpportunityRepository) GetOpportunity(ctx context.Context, opportunityUUIDs ...*uuid.UUID) (*[]models.OpportunityModel, error) {
 
 	if len(opportunityUUIDs) == 0 {
 		return nil, nil
@@ -419,7 +624,7 @@ func (repo *OpportunityRepository) GetOpportunity(opportunityUUIDs ...*uuid.UUID
 		columns = append(columns, mysql.NewUUIDColumn("uuid", *uID))
 	}
 
-	rows, err := container.ExecuteQuery(query, columns, mysql.QueryOptions{})
+	rows, err := container.ExecuteQueryContext(ctx, query, columns, mysql.QueryOptions{})
 	if err != nil {
 		return nil, err
 	}
@@ -434,7 +639,7 @@ func (repo *OpportunityRepository) GetOpportunity(opportunityUUIDs ...*uuid.UUID
 	return opportunity, nil
 }
 
-func (repo *OpportunityRepository) GetOpportunityByAuthor(authorUUIDs ...*uuid.UUID) (*[]models.OpportunityModel, error) {
+func (repo *OpportunityRepository) GetOpportunityByAuthor(ctx context.Context, authorUUIDs ...*uuid.UUID) (*[]models.OpportunityModel, error) {
 
 	if len(authorUUIDs) == 0 {
 		return nil, nil
@@ -453,7 +658,7 @@ func (repo *OpportunityRepository) GetOpportunityByAuthor(authorUUIDs ...*uuid.U
 		columns = append(columns, mysql.NewUUIDColumn("uuid", *uID))
 	}
 
-	rows, err := container.ExecuteQuery(query, columns, mysql.QueryOptions{})
+	rows, err := container.ExecuteQueryContext(ctx, query, columns, mysql.QueryOptions{})
 	if err != nil {
 		return nil, err
 	}
@@ -468,11 +673,45 @@ func (repo *OpportunityRepository) GetOpportunityByAuthor(authorUUIDs ...*uuid.U
 	return opportunity, nil
 }
 
-func (repo *OpportunityRepository) GetOpportunitiesByTag(tagName string) (*[]models.OpportunityModel, error) {
+func (repo *OpportunityRepository) GetOpportunityByOrganisation(ctx context.Context, organisationUUIDs ...*uuid.UUID) (*[]models.OpportunityModel, error) {
+
+	if len(organisationUUIDs) == 0 {
+		return nil, nil
+	}
 
 	container := repo.Repository
 
-	tag, err := GetTagModelByName(tagName, container, false)
+	var columns []mysql.Column
+
+	placeholders := strings.Repeat("?,", len(organisationUUIDs))
+	placeholders = placeholders[:len(placeholders)-1]
+
+	query := fmt.Sprintf(GetOpportunityByOrganisationIDQuery, placeholders)
+
+	for _, uID := range organisationUUIDs {
+		columns = append(columns, mysql.NewUUIDColumn("organisationUUID", *uID))
+	}
+
+	rows, err := container.ExecuteQueryContext(ctx, query, columns, mysql.QueryOptions{})
+	if err != nil {
+		return nil, err
+	}
+	opportunity, _, err := getOpportunity(rows)
+	if err != nil {
+		return nil, err
+	}
+	if opportunity == nil {
+		return nil, nil
+	}
+
+	return opportunity, nil
+}
+
+func (repo *OpportunityRepository) GetOpportunitiesByTag(ctx context.Context, tagName string) (*[]models.OpportunityModel, error) {
+
+	container := repo.Repository
+
+	tag, err := GetTagModelByName(ctx, tagName, container, false)
 	if err != nil {
 		return nil, err
 	}
@@ -485,7 +724,11 @@ func (repo *OpportunityRepository) GetOpportunitiesByTag(tagName string) (*[]mod
 		mysql.NewIntegerColumn("tagID", tag.ID),
 	}
 
-	rows, err := container.ExecuteQuery(GetOpportunitiesByTagQuery, columns, mysql.QueryOptions{})
+	rows, err := container.ExecuteQueryContext(ctx, GetOpportunitiesByTagQuery, columns, mysql.QueryOptions{})
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
 
 	var opportunities []*uuid.UUID
 
@@ -501,8 +744,11 @@ func (repo *OpportunityRepository) GetOpportunitiesByTag(tagName string) (*[]mod
 		opportunities = append(opportunities, &opportunityUUID)
 
 	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
 
-	opportunity, err := repo.GetOpportunity(opportunities...)
+	opportunity, err := repo.GetOpportunity(ctx, opportunities...)
 	if err != nil {
 		return nil, err
 	}
@@ -511,16 +757,17 @@ func (repo *OpportunityRepository) GetOpportunitiesByTag(tagName string) (*[]mod
 
 }
 
-func (repo *OpportunityRepository) GetOpportunitiesFrom(from int64, limit int64, userUUID uuid.UUID) (*[]models.OpportunityModel, int64, error) {
+func (repo *OpportunityRepository) GetOpportunitiesFrom(ctx context.Context, from int64, limit int64, userUUID uuid.UUID) (*[]models.OpportunityModel, int64, error) {
 	container := repo.Repository
 
 	columns := []mysql.Column{
 		mysql.NewIntegerColumn("from", from),
 		mysql.NewUUIDColumn("uuid", userUUID),
 		mysql.NewUUIDColumn("uuid", userUUID),
+		mysql.NewDateTimeColumn("now", time.Now().UTC()),
 		mysql.NewIntegerColumn("limit", limit),
 	}
-	rows, err := container.ExecuteQuery(GetOpportunityByFromQuery, columns, mysql.QueryOptions{})
+	rows, err := container.ExecuteQueryContext(ctx, GetOpportunityByFromQuery, columns, mysql.QueryOptions{})
 
 	if err != nil {
 		return nil, 0, err
@@ -529,33 +776,79 @@ func (repo *OpportunityRepository) GetOpportunitiesFrom(from int64, limit int64,
 	return getOpportunity(rows)
 }
 
-func (repo *OpportunityRepository) LikeOpportunity(userUUID, opportunityUUID uuid.UUID) error {
-	return repo.insertAction(userUUID, opportunityUUID, AddOpportunityLikeQuery)
+// GetOpportunitiesFiltered returns a page of approved opportunities matching the filter
+func (repo *OpportunityRepository) GetOpportunitiesFiltered(ctx context.Context, filter *models.OpportunityFilter) (*[]models.OpportunityModel, error) {
+	query, columns, err := compileFilter(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := repo.Repository.ExecuteQueryContext(ctx, query, columns, mysql.QueryOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	opportunities, _, err := getOpportunity(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	if opportunities == nil {
+		return &[]models.OpportunityModel{}, nil
+	}
+
+	return opportunities, nil
+}
+
+func (repo *OpportunityRepository) LikeOpportunity(ctx context.Context, userUUID, opportunityUUID uuid.UUID) error {
+	return repo.recordedAction(ctx, userUUID, opportunityUUID, AddOpportunityLikeQuery,
+		outbox.NewOpportunityLiked(userUUID, opportunityUUID),
+		events.OpportunityLiked{UserUUID: userUUID, OpportunityUUID: opportunityUUID})
 }
 
-func (repo *OpportunityRepository) DeleteLikeOpportunity(userUUID, opportunityUUID uuid.UUID) error {
-	return repo.deleteAction(userUUID, opportunityUUID, RemoveOpportunityLikesQuery)
+func (repo *OpportunityRepository) DeleteLikeOpportunity(ctx context.Context, userUUID, opportunityUUID uuid.UUID) error {
+	return repo.recordedAction(ctx, userUUID, opportunityUUID, RemoveOpportunityLikesQuery,
+		outbox.NewOpportunityUnliked(userUUID, opportunityUUID), nil)
 }
 
-func (repo *OpportunityRepository) DislikeOpportunity(userUUID, opportunityUUID uuid.UUID) error {
-	return repo.insertAction(userUUID, opportunityUUID, AddOpportunityDisLikeQuery)
+func (repo *OpportunityRepository) DislikeOpportunity(ctx context.Context, userUUID, opportunityUUID uuid.UUID) error {
+	return repo.insertAction(ctx, userUUID, opportunityUUID, AddOpportunityDisLikeQuery)
 }
 
-func (repo *OpportunityRepository) DeleteDislikeOpportunity(userUUID, opportunityUUID uuid.UUID) error {
-	return repo.deleteAction(userUUID, opportunityUUID, RemoveOpportunityDisLikesQuery)
+func (repo *OpportunityRepository) DeleteDislikeOpportunity(ctx context.Context, userUUID, opportunityUUID uuid.UUID) error {
+	return repo.deleteAction(ctx, userUUID, opportunityUUID, RemoveOpportunityDisLikesQuery)
 }
-func (repo *OpportunityRepository) insertAction(userUUID, opportunityUUID uuid.UUID, query string) error {
+func (repo *OpportunityRepository) insertAction(ctx context.Context, userUUID, opportunityUUID uuid.UUID, query string) error {
 	columns := buildUUIDColumnsAction(userUUID, opportunityUUID)
-	_, err := repo.Repository.ExecuteInsert(query, columns, mysql.InsertOptions{})
+	_, err := repo.Repository.ExecuteInsertContext(ctx, query, columns, mysql.InsertOptions{})
 	return err
 }
 
-func (repo *OpportunityRepository) deleteAction(userUUID, opportunityUUID uuid.UUID, query string) error {
+func (repo *OpportunityRepository) deleteAction(ctx context.Context, userUUID, opportunityUUID uuid.UUID, query string) error {
 	columns := buildUUIDColumnsAction(userUUID, opportunityUUID)
-	_, err := repo.Repository.ExecuteQuery(query, columns, mysql.QueryOptions{})
+	_, err := repo.Repository.ExecuteQueryContext(ctx, query, columns, mysql.QueryOptions{})
 	return err
 }
 
+// recordedAction runs a like or unlike, recording it for the graph in the same transaction and publishing the
+// domain event, if any, once committed
+func (repo *OpportunityRepository) recordedAction(ctx context.Context, userUUID, opportunityUUID uuid.UUID, query string,
+	event outbox.Event, published any) error {
+	container := repo.Repository
+	return container.WithTx(ctx, func(tx *mysql.Tx) error {
+		if _, err := container.AddExecuteTransactionContext(tx.Context(), tx.Tx, query, buildUUIDColumnsAction(userUUID, opportunityUUID)); err != nil {
+			return err
+		}
+		if err := recordEvents(tx.Context(), container, tx.Tx, event); err != nil {
+			return err
+		}
+		if published != nil {
+			events.Default.Publish(tx.Context(), published)
+		}
+		return nil
+	})
+}
+
 func buildUUIDColumnsAction(userUUID, opportunityUUID uuid.UUID) []mysql.Column {
 	return []mysql.Column{
 		mysql.NewUUIDColumn("userUUID", userUUID),
@@ -563,115 +856,163 @@ func buildUUIDColumnsAction(userUUID, opportunityUUID uuid.UUID) []mysql.Column
 	}
 }
 
-func getOpportunity(rows *sql.Rows) (*[]models.OpportunityModel, int64, error) {
-	type seenData struct {
-		media map[string]bool // use media URL as unique key
-		tags  map[int64]bool  // use tag ID as unique key
+// opportunityRow is a row of an opportunity joined with one of its media and one of its tags. The first id column is
+// the opportunity's, the media's and tag's ids aren't needed
+type opportunityRow struct {
+	ID               int64      `db:"id"`
+	UUID             uuid.UUID  `db:"uuid"`
+	Title            string     `db:"title"`
+	Description      string     `db:"description"`
+	Points           int64      `db:"points"`
+	Location         string     `db:"location"`
+	OpportunityType  string     `db:"opportunityType"`
+	PostedByUUID     uuid.UUID  `db:"postedByUUID"`
+	CreatedAt        time.Time  `db:"createdAt"`
+	UpdatedAt        time.Time  `db:"updatedAt"`
+	Approved         bool       `db:"approved"`
+	OrganisationUUID *uuid.UUID `db:"organisationUUID"`
+	Latitude         *float64   `db:"latitude"`
+	Longitude        *float64   `db:"longitude"`
+	AddressLine      *string    `db:"addressLine"`
+	City             *string    `db:"city"`
+	Region           *string    `db:"region"`
+	Postcode         *string    `db:"postcode"`
+	Country          *string    `db:"country"`
+	IsOnline         bool       `db:"isOnline"`
+	StartsAt         *time.Time `db:"startsAt"`
+	EndsAt           *time.Time `db:"endsAt"`
+	TimeZone         string     `db:"timeZone"`
+	Recurrence       *string    `db:"recurrence"`
+	Deadline         *time.Time `db:"deadline"`
+	Capacity         *int64     `db:"capacity"`
+	ExpiresAt        *time.Time `db:"expiresAt"`
+	ClosedAt         *time.Time `db:"closedAt"`
+	Sequence         int64      `db:"sequence"`
+	Draft            bool       `db:"draft"`
+
+	MediaURL  *string    `db:"mediaURL"`
+	MediaType *string    `db:"mediaType"`
+	MediaUUID *uuid.UUID `db:"mediaUUID"`
+
+	TagID   *int64  `db:"tagID"`
+	TagName *string `db:"tagName"`
+}
+
+// model is the opportunity without its media and tags
+func (row *opportunityRow) model() models.OpportunityModel {
+	model := models.OpportunityModel{
+		UUID:             row.UUID,
+		Title:            row.Title,
+		Description:      row.Description,
+		Points:           row.Points,
+		Location:         row.Location,
+		OpportunityType:  row.OpportunityType,
+		PostedByUUID:     row.PostedByUUID,
+		CreatedAt:        row.CreatedAt,
+		UpdatedAt:        row.UpdatedAt,
+		Approved:         row.Approved,
+		OrganisationUUID: row.OrganisationUUID,
+		Address: geo.Address{
+			Line:     valueOf(row.AddressLine),
+			City:     valueOf(row.City),
+			Region:   valueOf(row.Region),
+			Postcode: valueOf(row.Postcode),
+			Country:  valueOf(row.Country),
+		},
+		IsOnline:   row.IsOnline,
+		StartsAt:   localTime(row.StartsAt, row.TimeZone),
+		EndsAt:     localTime(row.EndsAt, row.TimeZone),
+		TimeZone:   row.TimeZone,
+		Recurrence: valueOf(row.Recurrence),
+		Deadline:   localTime(row.Deadline, row.TimeZone),
+		Capacity:   row.Capacity,
+		ExpiresAt:  localTime(row.ExpiresAt, row.TimeZone),
+		ClosedAt:   localTime(row.ClosedAt, row.TimeZone),
+		Sequence:   row.Sequence,
+		Draft:      row.Draft,
+		Tags:       &[]models.TagModel{},
+		Media:      &[]models.MediaModel{},
+	}
+	if row.Latitude != nil && row.Longitude != nil {
+		model.Coordinates = &geo.Coordinates{Latitude: *row.Latitude, Longitude: *row.Longitude}
 	}
+	return model
+}
 
-	opportunities := map[uuid.UUID]*models.OpportunityModel{}
-	seen := map[uuid.UUID]*seenData{}
+// addChildren adds the row's media and tag to the opportunity unless an earlier row already had them
+func (row *opportunityRow) addChildren(opportunity *models.OpportunityModel) {
+	if row.MediaURL != nil && row.MediaType != nil {
+		if media, err := opportunityMedia(*row.MediaURL, *row.MediaType, row.MediaUUID); err == nil {
+			*opportunity.Media = mysql.AppendDistinct(*opportunity.Media, *media, func(media models.MediaModel) string {
+				key := media.URL
+				if media.ID != nil {
+					key += media.ID.String()
+				}
+				return key
+			})
+		}
+	}
 
-	var lastIDSeen int64
+	if row.TagID != nil && row.TagName != nil {
+		tag := models.TagModel{ID: *row.TagID, TagName: *row.TagName}
+		*opportunity.Tags = mysql.AppendDistinct(*opportunity.Tags, tag, func(tag models.TagModel) int64 {
+			return tag.ID
+		})
+	}
+}
 
-	for rows.Next() {
-		// Main opportunity fields
-		var id int64
-		var opportunityUUID uuid.UUID
-		var title, description, location, opportunityType string
-		var points int64
-		var postedByUUID uuid.UUID
-		var createdAt, updatedAt time.Time
-		var approved bool
-
-		// Media
-		var mediaID sql.Null[int64]
-		var mediaOpportunityUUID uuid.UUID
-		var mediaURL sql.NullString
-		var mediaType sql.NullString
-
-		// Tag
-		var tagID sql.NullInt64
-		var tagOpportunityUUID uuid.UUID
-		var tagName sql.NullString
-
-		err := rows.Scan(&id, &opportunityUUID, &title, &description, &points,
-			&location, &opportunityType, &postedByUUID,
-			&createdAt, &updatedAt, &approved,
-			&mediaID, &mediaOpportunityUUID, &mediaURL, &mediaType,
-			&tagOpportunityUUID, &tagID, &tagID, &tagName)
+// getOpportunity reads and closes the rows. A query cancelled or timed out part way through returns its context's error
+func getOpportunity(rows *sql.Rows) (*[]models.OpportunityModel, int64, error) {
+	opportunityRows, err := mysql.ScanAll[opportunityRow](rows)
+	if err != nil {
+		return nil, 0, err
+	}
 
-		if err != nil {
-			return nil, 0, err
-		}
-		lastIDSeen = id
-
-		// Initialize opportunity and tracking
-		if _, exists := opportunities[opportunityUUID]; !exists {
-			opportunities[opportunityUUID] = &models.OpportunityModel{
-				UUID:            opportunityUUID,
-				Title:           title,
-				Description:     description,
-				Points:          points,
-				Location:        location,
-				OpportunityType: opportunityType,
-				PostedByUUID:    postedByUUID,
-				CreatedAt:       createdAt,
-				UpdatedAt:       updatedAt,
-				Approved:        approved,
-				Tags:            &[]models.TagModel{},
-				Media:           &[]models.MediaModel{},
-			}
-			seen[opportunityUUID] = &seenData{
-				media: map[string]bool{},
-				tags:  map[int64]bool{},
-			}
-		}
+	if len(opportunityRows) == 0 {
+		return nil, 0, nil
+	}
 
-		opportunity := opportunities[opportunityUUID]
-		tracker := seen[opportunityUUID]
-
-		// Deduplicate and add media
-		if mediaURL.Valid && mediaType.Valid {
-			url := mediaURL.String
-			if !tracker.media[url] {
-				mType, err := models.ParseMediaType(mediaType.String)
-				if err == nil {
-					*opportunity.Media = append(*opportunity.Media, models.MediaModel{
-						Type: mType,
-						URL:  url,
-					})
-					tracker.media[url] = true
-				}
-			}
-		}
+	opportunities := mysql.OneToMany(opportunityRows,
+		func(row *opportunityRow) uuid.UUID { return row.UUID },
+		(*opportunityRow).model,
+		func(opportunity *models.OpportunityModel, row *opportunityRow) { row.addChildren(opportunity) })
 
-		// Deduplicate and add tags
-		if tagName.Valid && tagID.Valid {
-			id := tagID.Int64
-			if !tracker.tags[id] {
-				*opportunity.Tags = append(*opportunity.Tags, models.TagModel{
-					ID:      id,
-					TagName: tagName.String,
-				})
-				tracker.tags[id] = true
-			}
-		}
+	return &opportunities, opportunityRows[len(opportunityRows)-1].ID, nil
+}
+
+// localTime returns the stored UTC time in the opportunities timezone, so it's shown as the poster wrote it
+func localTime(value *time.Time, timeZone string) *time.Time {
+	if value == nil {
+		return nil
 	}
 
-	if len(opportunities) == 0 {
-		return nil, 0, nil
+	location, err := schedule.LoadLocation(timeZone)
+	if err != nil {
+		location = time.UTC
 	}
 
-	opportunitiesSlice := make([]models.OpportunityModel, 0, len(opportunities))
-	for _, opp := range opportunities {
-		opportunitiesSlice = append(opportunitiesSlice, *opp)
+	local := value.In(location)
+	return &local
+}
+
+// valueOf is the value of a column that may be NULL, the zero value if it is
+func valueOf[T any](value *T) T {
+	if value == nil {
+		var zero T
+		return zero
 	}
+	return *value
+}
 
-	return &opportunitiesSlice, lastIDSeen, nil
+func nullableUUID(value uuid.NullUUID) *uuid.UUID {
+	if !value.Valid {
+		return nil
+	}
+	return &value.UUID
 }
 
-func (repo *OpportunityRepository) GetOpportunityTags(opportunityUUID uuid.UUID) *[]models.TagModel {
+// GetOpportunityTags returns the opportunity's tags, nil if it has none
+func (repo *OpportunityRepository) GetOpportunityTags(ctx context.Context, opportunityUUID uuid.UUID) (*[]models.TagModel, error) {
 
 	var tags []models.TagModel
 
@@ -681,40 +1022,44 @@ func (repo *OpportunityRepository) GetOpportunityTags(opportunityUUID uuid.UUID)
 		mysql.NewUUIDColumn("opportunityUUID", opportunityUUID),
 	}
 
-	rows, err := container.ExecuteQuery(GetOpportunityTagsQuery, columns, mysql.QueryOptions{})
+	rows, err := container.ExecuteQueryContext(ctx, GetOpportunityTagsQuery, columns, mysql.QueryOptions{})
 	if err != nil {
 		log.Error(err)
-		return nil
+		return nil, err
 	}
 
+	var tagIDs []int64
 	for rows.Next() {
-
 		var tagID int64
-
-		err := rows.Scan(&tagID)
-		if err != nil {
+		if err := rows.Scan(&tagID); err != nil {
+			rows.Close()
 			log.Error(err)
-			return nil
+			return nil, err
 		}
+		tagIDs = append(tagIDs, tagID)
+	}
+	rows.Close()
 
-		//Fetch tagName
+	//Fetch tagName
 
+	for _, tagID := range tagIDs {
 		columns := []mysql.Column{
 			mysql.NewIntegerColumn("id", tagID),
 		}
 
-		rows, err := container.ExecuteQuery(GetTagByID, columns, mysql.QueryOptions{})
+		rows, err := container.ExecuteQueryContext(ctx, GetTagByID, columns, mysql.QueryOptions{})
 		if err != nil {
 			log.Error(err)
-			return nil
+			return nil, err
 		}
 		var tagName string
 		if rows.Next() {
-			err := rows.Scan(&tagName)
-			if err != nil {
-				log.Error(err)
-				return nil
-			}
+			err = rows.Scan(&tagName)
+		}
+		rows.Close()
+		if err != nil {
+			log.Error(err)
+			return nil, err
 		}
 
 		tag := models.TagModel{
@@ -726,13 +1071,21 @@ func (repo *OpportunityRepository) GetOpportunityTags(opportunityUUID uuid.UUID)
 	}
 
 	if len(tags) == 0 {
-		return nil
+		return nil, nil
 	}
 
-	return &tags
+	return &tags, nil
 }
 
-func (repo *OpportunityRepository) DeleteOpportunity(opportunityUUID uuid.UUID) error {
+// GetOpportunityTagsAsync is GetOpportunityTags run on the database's thread pool
+func (repo *OpportunityRepository) GetOpportunityTagsAsync(ctx context.Context, opportunityUUID uuid.UUID) *concurrency.Future[*[]models.TagModel] {
+	return mysql.Async(ctx, repo.Repository, func(ctx context.Context) (*[]models.TagModel, error) {
+		return repo.GetOpportunityTags(ctx, opportunityUUID)
+	})
+}
+
+// DeleteOpportunity deletes the opportunity, keeping a cancellation for its applicants' calendar feeds
+func (repo *OpportunityRepository) DeleteOpportunity(ctx context.Context, opportunityUUID uuid.UUID) error {
 
 	container := repo.Repository
 
@@ -740,125 +1093,84 @@ func (repo *OpportunityRepository) DeleteOpportunity(opportunityUUID uuid.UUID)
 		mysql.NewUUIDColumn("uuid", opportunityUUID),
 	}
 
-	_, err := container.ExecuteQuery(DeleteOpportunityQuery, columns, mysql.QueryOptions{})
-	if err != nil {
-		return err
-	}
+	return container.WithTx(ctx, func(tx *mysql.Tx) error {
+		if err := recordCancellations(tx.Context(), container, tx.Tx, opportunityUUID, time.Now()); err != nil {
+			return err
+		}
 
-	return nil
+		if _, err := container.AddExecuteTransactionContext(tx.Context(), tx.Tx, DeleteOpportunityQuery, columns); err != nil {
+			return err
+		}
 
+		return recordEvents(tx.Context(), container, tx.Tx, outbox.NewOpportunityDeleted(opportunityUUID))
+	})
 }
 
-func (repo *OpportunityRepository) GetOpportunityMedia(opportunityUUID uuid.UUID) *[]models.MediaModel {
-
+// GetOpportunityMedia returns the media attached to the opportunity in the order they were attached, nil if none are
+func (repo *OpportunityRepository) GetOpportunityMedia(ctx context.Context, opportunityUUID uuid.UUID) (*[]models.MediaModel, error) {
 	container := repo.Repository
 
-	var media []models.MediaModel
-
 	columns := []mysql.Column{
 		mysql.NewUUIDColumn("opportunityUUID", opportunityUUID),
 	}
 
-	rows, err := container.ExecuteQuery(GetOpportunityMediaQuery, columns, mysql.QueryOptions{})
+	rows, err := container.ExecuteQueryContext(ctx, GetOpportunityMediaQuery, columns, mysql.QueryOptions{})
 	if err != nil {
-		return nil
+		log.Error(err)
+		return nil, err
 	}
 
-	for rows.Next() {
-
-		var mediaURL string
-		var mediaType string
-
-		err := rows.Scan(&mediaURL, &mediaType)
-		if err != nil {
-			log.Error(err)
-			return nil
-		}
+	// Only the media columns are selected
+	mediaRows, err := mysql.ScanAll[opportunityRow](rows)
+	if err != nil {
+		log.Error(err)
+		return nil, err
+	}
 
-		parsedMediaType, err := models.ParseMediaType(mediaType)
+	var media []models.MediaModel
+	for _, row := range mediaRows {
+		model, err := opportunityMedia(*row.MediaURL, *row.MediaType, row.MediaUUID)
 		if err != nil {
 			log.Error(err)
-			return nil
-		}
-
-		model := models.MediaModel{
-			Type: parsedMediaType,
-			URL:  mediaURL,
+			return nil, err
 		}
 
-		media = append(media, model)
-
+		media = append(media, *model)
 	}
 
 	if len(media) == 0 {
-		return nil
+		return nil, nil
 	}
 
-	return &media
-
+	return &media, nil
 }
 
-func GetTagModelByName(tagName string, container *mysql.Repository, createIfNotExist bool) (*models.TagModel, error) {
-	columns := []mysql.Column{
-		mysql.NewVarcharColumn("tagName", tagName),
-	}
-	query := fmt.Sprintf(GetTagByName, "?")
-	rows, err := container.ExecuteQuery(query, columns, mysql.QueryOptions{})
-
-	if err != nil {
-		return nil, err
-	}
-
-	if !rows.Next() {
-
-		//Tag doesn't exist
-
-		if !createIfNotExist {
-			return nil, nil
-		}
-
-		columns = []mysql.Column{
-			mysql.NewVarcharColumn("tagName", tagName),
-		}
-		var tag models.TagModel
-		_, err := container.ExecuteInsert(CreateTagQuery, columns, mysql.InsertOptions{
-			OnComplete: func(result sql.Result) {
-				tagID, err := result.LastInsertId()
-				if err != nil {
-					log.Error(err)
-				}
-
-				tag = models.TagModel{
-					ID:      tagID,
-					TagName: tagName,
-				}
-
-			},
-		})
-		if err != nil {
-			return nil, err
-		}
+// GetOpportunityMediaAsync is GetOpportunityMedia run on the database's thread pool
+func (repo *OpportunityRepository) GetOpportunityMediaAsync(ctx context.Context, opportunityUUID uuid.UUID) *concurrency.Future[*[]models.MediaModel] {
+	return mysql.Async(ctx, repo.Repository, func(ctx context.Context) (*[]models.MediaModel, error) {
+		return repo.GetOpportunityMedia(ctx, opportunityUUID)
+	})
+}
 
-		return &tag, nil
+// GetTagModelByName returns the tag the name refers to, see ResolveTag
+func GetTagModelByName(ctx context.Context, tagName string, container *mysql.Repository, createIfNotExist bool) (*models.TagModel, error) {
+	if !createIfNotExist {
+		return ResolveTag(ctx, container, nil, tagName, false)
 	}
 
-	var tagID int64
-
-	err = rows.Scan(&tagID, &tagName)
+	var tag *models.TagModel
+	err := container.WithTx(ctx, func(tx *mysql.Tx) error {
+		var err error
+		tag, err = ResolveTag(tx.Context(), container, tx.Tx, tagName, true)
+		return err
+	})
 	if err != nil {
-		log.Error(err)
 		return nil, err
 	}
-
-	model := models.TagModel{
-		ID:      tagID,
-		TagName: tagName,
-	}
-
-	return &model, nil
+	return tag, nil
 }
 
-func (repo *OpportunityRepository) GetOpportunityByLikes(opportunityUUID uuid.UUID, from int64, limit int64) (*[]*models.StudentInfoModel, int64, error) {
+func (repo *OpportunityRepository) GetOpportunityByLikes(ctx context.Context, opportunityUUID uuid.UUID, from int64, limit int64) (*[]*models.StudentInfoModel, int64, error) {
 	container := repo.Repository
 
 	columns := []mysql.Column{
@@ -867,10 +1179,11 @@ func (repo *OpportunityRepository) GetOpportunityByLikes(opportunityUUID uuid.UU
 		mysql.NewIntegerColumn("limit", limit),
 	}
 
-	rows, err := container.ExecuteQuery(GetOpportunityLikesByOpportunityIDQuery, columns, mysql.QueryOptions{})
+	rows, err := container.ExecuteQueryContext(ctx, GetOpportunityLikesByOpportunityIDQuery, columns, mysql.QueryOptions{})
 	if err != nil {
 		return nil, 0, err
 	}
+	defer rows.Close()
 
 	var students []*models.StudentInfoModel
 	var lastRow int64
@@ -898,6 +1211,9 @@ func (repo *OpportunityRepository) GetOpportunityByLikes(opportunityUUID uuid.UU
 		lastRow = rowID
 
 	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
 
 	if len(students) == 0 {
 		return nil, 0, nil
@@ -906,3 +1222,25 @@ func (repo *OpportunityRepository) GetOpportunityByLikes(opportunityUUID uuid.UU
 	return &students, lastRow, nil
 
 }
+
+// opportunityMedia builds an opportunity's media from its row. Stored media gets its URL when it's signed
+func opportunityMedia(mediaURL string, mediaType string, mediaUUID *uuid.UUID) (*models.MediaModel, error) {
+	parsedMediaType, err := models.ParseMediaType(mediaType)
+	if err != nil {
+		return nil, err
+	}
+
+	media := &models.MediaModel{Type: parsedMediaType, URL: mediaURL}
+	if mediaUUID != nil {
+		media.ID, media.URL = mediaUUID, ""
+	}
+	return media, nil
+}
+
+// storedMediaURL is what's saved in mediaURL, signed URLs expire so only media without an ID keeps its URL
+func storedMediaURL(media models.MediaModel) string {
+	if media.ID != nil {
+		return ""
+	}
+	return media.URL
+}
@@ -0,0 +1,184 @@
+package repositories
+
+import (
+	"backend/internal/db/adapters/mysql"
+	"backend/internal/geo"
+	"backend/internal/models"
+	"strings"
+	"time"
+)
+
+// Filtered listings page over the opportunities first, then join media and tags onto just that page.
+// Only fixed SQL fragments are concatenated, every value from the filter is bound as a parameter
+
+const filteredOpportunitiesQuery = `
+WITH filtered AS (
+  SELECT ot.* FROM OpportunitiesTable ot
+  WHERE %s
+  ORDER BY %s
+  LIMIT ? OFFSET ?
+)
+SELECT f.*, omt.*, ott.*, tt.*
+FROM filtered f
+LEFT JOIN OpportunityMediaTable omt
+  ON f.uuid = omt.opportunityUUID
+LEFT JOIN OpportunityTagsTable ott
+  ON f.uuid = ott.opportunityUUID
+LEFT JOIN TagsTable tt
+  ON ott.tagID = tt.id
+ORDER BY %s
+`
+
+const filterTagsAnyCondition = `ot.uuid IN (
+    SELECT ott.opportunityUUID FROM OpportunityTagsTable ott
+    INNER JOIN TagsTable tt ON tt.id = ott.tagID
+    WHERE tt.tagName IN (%s))`
+
+const filterTagsAllCondition = `ot.uuid IN (
+    SELECT ott.opportunityUUID FROM OpportunityTagsTable ott
+    INNER JOIN TagsTable tt ON tt.id = ott.tagID
+    WHERE tt.tagName IN (%s)
+    GROUP BY ott.opportunityUUID
+    HAVING COUNT(DISTINCT tt.id) = ?)`
+
+// compileFilter builds the query and its parameters for the filter
+func compileFilter(filter *models.OpportunityFilter) (string, []mysql.Column, error) {
+	conditions := []string{"ot.approved = TRUE"}
+	var columns []mysql.Column
+
+	if len(filter.Tags) > 0 {
+		condition := filterTagsAnyCondition
+		if filter.TagMatch == models.TagMatchAll {
+			condition = filterTagsAllCondition
+		}
+
+		conditions = append(conditions, strings.Replace(condition, "%s", placeholders(len(filter.Tags)), 1))
+		for _, tag := range filter.Tags {
+			columns = append(columns, mysql.NewVarcharColumn("tagName", tag))
+		}
+		if filter.TagMatch == models.TagMatchAll {
+			columns = append(columns, mysql.NewIntegerColumn("tagCount", int64(len(filter.Tags))))
+		}
+	}
+
+	if len(filter.Types) > 0 {
+		conditions = append(conditions, "ot.opportunityType IN ("+placeholders(len(filter.Types))+")")
+		for _, opportunityType := range filter.Types {
+			columns = append(columns, mysql.NewVarcharColumn("opportunityType", opportunityType))
+		}
+	}
+
+	if filter.MinPoints != nil {
+		conditions = append(conditions, "ot.points >= ?")
+		columns = append(columns, mysql.NewIntegerColumn("points", *filter.MinPoints))
+	}
+
+	if filter.MaxPoints != nil {
+		conditions = append(conditions, "ot.points <= ?")
+		columns = append(columns, mysql.NewIntegerColumn("points", *filter.MaxPoints))
+	}
+
+	if filter.CreatedAfter != nil {
+		conditions = append(conditions, "ot.createdAt >= ?")
+		columns = append(columns, mysql.NewDateTimeColumn("createdAt", *filter.CreatedAfter))
+	}
+
+	if filter.CreatedBefore != nil {
+		conditions = append(conditions, "ot.createdAt < ?")
+		columns = append(columns, mysql.NewDateTimeColumn("createdAt", *filter.CreatedBefore))
+	}
+
+	if filter.UpdatedAfter != nil {
+		conditions = append(conditions, "ot.updatedAt >= ?")
+		columns = append(columns, mysql.NewDateTimeColumn("updatedAt", *filter.UpdatedAfter))
+	}
+
+	if filter.UpdatedBefore != nil {
+		conditions = append(conditions, "ot.updatedAt < ?")
+		columns = append(columns, mysql.NewDateTimeColumn("updatedAt", *filter.UpdatedBefore))
+	}
+
+	if filter.OrganisationUUID != nil {
+		conditions = append(conditions, "ot.organisationUUID = ?")
+		columns = append(columns, mysql.NewUUIDColumn("organisationUUID", *filter.OrganisationUUID))
+	}
+
+	if filter.Online != nil {
+		conditions = append(conditions, "ot.isOnline = ?")
+		columns = append(columns, mysql.NewBoolColumn("isOnline", *filter.Online))
+	}
+
+	// Opportunities without a location store POINT(0 0), the latitude check keeps them out of area searches
+	if filter.Within != nil {
+		conditions = append(conditions, "ot.latitude IS NOT NULL AND MBRContains(ST_GeomFromText(?, 4326), ot.coordinates)")
+		columns = append(columns, mysql.NewVarcharColumn("within", filter.Within.WKT()))
+	}
+
+	if filter.Near != nil && filter.RadiusKm != nil {
+		// The bounding box lets the spatial index narrow the rows before the exact distance is checked
+		box := geo.BoundingBoxAround(*filter.Near, *filter.RadiusKm)
+		conditions = append(conditions,
+			"ot.latitude IS NOT NULL AND MBRContains(ST_GeomFromText(?, 4326), ot.coordinates)",
+			"ST_Distance_Sphere(ot.coordinates, ST_GeomFromText(?, 4326)) <= ?",
+		)
+		columns = append(columns,
+			mysql.NewVarcharColumn("near", box.WKT()),
+			mysql.NewVarcharColumn("near", filter.Near.WKT()),
+			mysql.NewDoubleColumn("radius", *filter.RadiusKm*1000),
+		)
+	}
+
+	conditions = append(conditions, strings.ReplaceAll(openOpportunityCondition, "{t}", "ot"))
+	columns = append(columns, mysql.NewDateTimeColumn("now", time.Now().UTC()))
+
+	order, orderColumns, err := filterOrder(filter)
+	if err != nil {
+		return "", nil, err
+	}
+
+	// The order is used inside the CTE and again outside it, so its parameters are bound twice
+	columns = append(columns, orderColumns...)
+	columns = append(columns,
+		mysql.NewIntegerColumn("limit", filter.Limit),
+		mysql.NewIntegerColumn("offset", filter.Offset),
+	)
+	columns = append(columns, orderColumns...)
+
+	query := strings.Replace(filteredOpportunitiesQuery, "%s", strings.Join(conditions, "\n  AND "), 1)
+	query = strings.Replace(query, "%s", strings.ReplaceAll(order, "{t}", "ot"), 1)
+	query = strings.Replace(query, "%s", strings.ReplaceAll(order, "{t}", "f"), 1)
+
+	return query, columns, nil
+}
+
+// filterOrder returns the ORDER BY clause with {t} in place of the table alias, and its parameters.
+// id breaks ties so pages are stable
+func filterOrder(filter *models.OpportunityFilter) (string, []mysql.Column, error) {
+	direction := "DESC"
+	if filter.Ascending {
+		direction = "ASC"
+	}
+
+	switch filter.Sort {
+	case models.SortNewest:
+		return "{t}.createdAt " + direction + ", {t}.id " + direction, nil, nil
+	case models.SortPoints:
+		return "{t}.points " + direction + ", {t}.id DESC", nil, nil
+	default:
+		if filter.Near == nil {
+			return "", nil, &models.FilterError{Parameter: "sort", Message: "sorting by distance needs lat and lng"}
+		}
+
+		// Nearest first by default, opportunities without a location always come last
+		direction = "ASC"
+		if filter.Ascending {
+			direction = "DESC"
+		}
+		order := "{t}.latitude IS NULL, ST_Distance_Sphere({t}.coordinates, ST_GeomFromText(?, 4326)) " + direction + ", {t}.id DESC"
+		return order, []mysql.Column{mysql.NewVarcharColumn("near", filter.Near.WKT())}, nil
+	}
+}
+
+func placeholders(count int) string {
+	return strings.TrimSuffix(strings.Repeat("?,", count), ",")
+}
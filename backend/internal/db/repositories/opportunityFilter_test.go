@@ -0,0 +1,130 @@
+package repositories
+
+import (
+	"backend/internal/geo"
+	"backend/internal/models"
+	"errors"
+	"github.com/google/uuid"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCompileFilterBindsEveryValue(t *testing.T) {
+	minPoints := int64(20)
+	createdAfter := time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC)
+	organisation := uuid.New()
+
+	filter := &models.OpportunityFilter{
+		Tags:             []string{"recycling", "beach'); DROP TABLE OpportunitiesTable; --"},
+		TagMatch:         models.TagMatchAll,
+		Types:            []string{"volunteer"},
+		MinPoints:        &minPoints,
+		CreatedAfter:     &createdAfter,
+		OrganisationUUID: &organisation,
+		Sort:             models.SortNewest,
+		Limit:            10,
+		Offset:           20,
+	}
+
+	query, columns, err := compileFilter(filter)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if strings.Contains(query, "DROP TABLE") || strings.Contains(query, "%s") {
+		t.Fatalf("values must not be interpolated into the query:\n%s", query)
+	}
+
+	if placeholders := strings.Count(query, "?"); placeholders != len(columns) {
+		t.Fatalf("expected %d placeholders got %d", len(columns), placeholders)
+	}
+
+	// tags, tag count, type, points, createdAt, organisation, limit, offset
+	expected := []interface{}{"recycling", filter.Tags[1], int64(2), "volunteer", int64(20), createdAfter}
+	for i, value := range expected {
+		if columns[i].GetValue() != value {
+			t.Errorf("column %d: expected %v got %v", i, value, columns[i].GetValue())
+		}
+	}
+
+	if limit := columns[len(columns)-2].GetValue(); limit != int64(10) {
+		t.Errorf("expected limit 10 got %v", limit)
+	}
+
+	for _, fragment := range []string{"HAVING COUNT(DISTINCT tt.id) = ?", "ot.points >= ?", "ot.organisationUUID = ?",
+		"ORDER BY ot.createdAt DESC, ot.id DESC", "ORDER BY f.createdAt DESC, f.id DESC"} {
+		if !strings.Contains(query, fragment) {
+			t.Errorf("expected query to contain %q", fragment)
+		}
+	}
+}
+
+func TestCompileFilterAnyTagsAndPointsSort(t *testing.T) {
+	filter := &models.OpportunityFilter{Tags: []string{"a", "b"}, Sort: models.SortPoints, Ascending: true, Limit: 5}
+
+	query, columns, err := compileFilter(filter)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if strings.Contains(query, "HAVING") {
+		t.Fatal("any should not require every tag")
+	}
+	if !strings.Contains(query, "ORDER BY ot.points ASC") {
+		t.Fatalf("expected points ordering:\n%s", query)
+	}
+	if len(columns) != 5 {
+		t.Fatalf("expected 2 tags + now + limit + offset, got %d columns", len(columns))
+	}
+	if !strings.Contains(query, "ot.closedAt IS NULL AND (ot.expiresAt IS NULL OR ot.expiresAt > ?)") {
+		t.Fatalf("expected closed opportunities to be hidden:\n%s", query)
+	}
+}
+
+func TestCompileFilterDistanceNeedsLocation(t *testing.T) {
+	_, _, err := compileFilter(&models.OpportunityFilter{Sort: models.SortDistance, Limit: 5})
+
+	var filterError *models.FilterError
+	if !errors.As(err, &filterError) || filterError.Parameter != "sort" {
+		t.Fatalf("expected a sort FilterError, got %v", err)
+	}
+}
+
+func TestCompileFilterRadiusAndDistanceSort(t *testing.T) {
+	radius := 10.0
+	online := false
+	filter := &models.OpportunityFilter{
+		Near:     &geo.Coordinates{Latitude: 53.8008, Longitude: -1.5491},
+		RadiusKm: &radius,
+		Online:   &online,
+		Sort:     models.SortDistance,
+		Limit:    5,
+	}
+
+	query, columns, err := compileFilter(filter)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if placeholders := strings.Count(query, "?"); placeholders != len(columns) {
+		t.Fatalf("expected %d placeholders got %d", len(columns), placeholders)
+	}
+
+	for _, fragment := range []string{"ot.isOnline = ?", "MBRContains(ST_GeomFromText(?, 4326), ot.coordinates)",
+		"ST_Distance_Sphere(ot.coordinates, ST_GeomFromText(?, 4326)) <= ?",
+		"ORDER BY ot.latitude IS NULL, ST_Distance_Sphere(ot.coordinates, ST_GeomFromText(?, 4326)) ASC",
+		"ORDER BY f.latitude IS NULL, ST_Distance_Sphere(f.coordinates, ST_GeomFromText(?, 4326)) ASC"} {
+		if !strings.Contains(query, fragment) {
+			t.Errorf("expected query to contain %q", fragment)
+		}
+	}
+
+	// Radius is bound in meters, and the last parameter is the outer ORDER BY point
+	if radius := columns[3].GetValue(); radius != 10000.0 {
+		t.Errorf("expected radius 10000 got %v", radius)
+	}
+	if point := columns[len(columns)-1].GetValue(); point != "POINT(53.8008 -1.5491)" {
+		t.Errorf("expected the order point last got %v", point)
+	}
+}
@@ -0,0 +1,114 @@
+package repositories
+
+import (
+	"backend/internal/db/adapters/mysql"
+	"backend/internal/models"
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+	"strings"
+)
+
+// External references map an importer's own IDs to opportunities, so importing the same sheet again updates
+// rather than duplicates. References belong to the organisation posting, or the author when posting for themselves
+
+// ErrExternalRefExists is returned when creating an opportunity with a reference the owner already used
+var ErrExternalRefExists = errors.New("externalRef is already used by another opportunity")
+
+var opportunityExternalRefsTable = &mysql.Table{
+	Name: "OpportunityExternalRefsTable",
+	Columns: &[]mysql.Column{
+		mysql.NewUUIDColumnForTable("ownerUUID", false, 36),
+		mysql.NewVarcharColumnForTable("externalRef", false, 100),
+		mysql.NewUUIDColumnForTable("opportunityUUID", false, 36, mysql.Unique()),
+	},
+	PrimaryKeys: &[]string{"ownerUUID", "externalRef"},
+	ForeignKeys: []mysql.ForeignKey{{
+		Columns:           []string{"opportunityUUID"},
+		ReferencedTable:   "OpportunitiesTable",
+		ReferencedColumns: []string{"uuid"},
+		OnDelete:          mysql.Cascade,
+	}},
+}
+
+// Tables returns the tables declared with mysql.Table
+func (_ *OpportunityRepository) Tables() []*mysql.Table {
+	return []*mysql.Table{opportunityExternalRefsTable, outboxTable}
+}
+
+const InsertOpportunityExternalRefQuery = `
+INSERT INTO OpportunityExternalRefsTable(ownerUUID, externalRef, opportunityUUID) VALUES (?, ?, ?)
+`
+
+const GetOpportunityByExternalRefQuery = `
+SELECT opportunityUUID FROM OpportunityExternalRefsTable WHERE ownerUUID = ? AND externalRef = ?
+`
+
+// GetOpportunityByExternalRef returns the opportunity imported with the reference, nil if there isn't one
+func (repo *OpportunityRepository) GetOpportunityByExternalRef(ctx context.Context, ownerUUID uuid.UUID, externalRef string) (*uuid.UUID, error) {
+	rows, err := repo.Repository.ExecuteQueryContext(ctx, GetOpportunityByExternalRefQuery, []mysql.Column{
+		mysql.NewUUIDColumn("ownerUUID", ownerUUID),
+		mysql.NewVarcharColumn("externalRef", externalRef),
+	}, mysql.QueryOptions{})
+	if err != nil {
+		log.Error(err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		return nil, rows.Err()
+	}
+	var opportunityUUID uuid.UUID
+	if err := rows.Scan(&opportunityUUID); err != nil {
+		log.Error(err)
+		return nil, err
+	}
+	return &opportunityUUID, nil
+}
+
+// errValidated rolls back ValidateTags' transaction once every tag resolved
+var errValidated = errors.New("tags are valid")
+
+// ValidateTags checks the names could be used as tags, the tags that would be created are rolled back
+func (repo *OpportunityRepository) ValidateTags(ctx context.Context, names []string) error {
+	container := repo.Repository
+
+	err := container.WithTx(ctx, func(tx *mysql.Tx) error {
+		for _, name := range names {
+			if _, err := ResolveTag(tx.Context(), container, tx.Tx, name, true); err != nil {
+				return err
+			}
+		}
+		return errValidated
+	})
+	if errors.Is(err, errValidated) {
+		return nil
+	}
+	return err
+}
+
+// ExternalRefOwner is who an opportunity's external reference belongs to
+func ExternalRefOwner(model *models.OpportunityModel) uuid.UUID {
+	if model.OrganisationUUID != nil {
+		return *model.OrganisationUUID
+	}
+	return model.PostedByUUID
+}
+
+func insertExternalRef(ctx context.Context, container *mysql.Repository, transaction *sql.Tx, model *models.OpportunityModel) error {
+	_, err := container.AddExecuteTransactionContext(ctx, transaction, InsertOpportunityExternalRefQuery, []mysql.Column{
+		mysql.NewUUIDColumn("ownerUUID", ExternalRefOwner(model)),
+		mysql.NewVarcharColumn("externalRef", model.ExternalRef),
+		mysql.NewUUIDColumn("opportunityUUID", model.UUID),
+	})
+	if err != nil && strings.Contains(err.Error(), duplicateEntryError) {
+		return ErrExternalRefExists
+	}
+	if err != nil {
+		log.Error(err)
+	}
+	return err
+}
@@ -0,0 +1,752 @@
+package repositories
+
+import (
+	"backend/internal/db/adapters/mysql"
+	"backend/internal/models"
+	"context"
+	"database/sql"
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+	"time"
+)
+
+//Organisations own opportunities. Recruiters are members of an organisation with a role (owner, editor, viewer)
+
+const CreateOrganisationTableQuery = `
+CREATE TABLE IF NOT EXISTS OrganisationTable(
+    uuid VARCHAR(36) PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,
+    description TEXT,
+    logoURL TEXT,
+    website VARCHAR(255),
+    verificationStatus ENUM('pending', 'verified', 'rejected') NOT NULL DEFAULT 'pending',
+    createdAt DATETIME DEFAULT CURRENT_TIMESTAMP
+);`
+
+const CreateOrganisationMembersTableQuery = `
+CREATE TABLE IF NOT EXISTS OrganisationMembersTable(
+    organisationUUID VARCHAR(36) NOT NULL,
+    userUUID VARCHAR(36) NOT NULL,
+    role ENUM('viewer', 'editor', 'owner') NOT NULL,
+    joinedAt DATETIME DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (organisationUUID, userUUID),
+    FOREIGN KEY (organisationUUID) REFERENCES OrganisationTable(uuid) ON DELETE CASCADE,
+    FOREIGN KEY (userUUID) REFERENCES UserTable(uuid) ON DELETE CASCADE
+);`
+
+const CreateOrganisationInvitesTableQuery = `
+CREATE TABLE IF NOT EXISTS OrganisationInvitesTable(
+    uuid VARCHAR(36) PRIMARY KEY,
+    organisationUUID VARCHAR(36) NOT NULL,
+    email VARCHAR(255) NOT NULL,
+    role ENUM('viewer', 'editor', 'owner') NOT NULL,
+    invitedByUUID VARCHAR(36) NOT NULL,
+    tokenHash VARCHAR(64) NOT NULL UNIQUE,
+    expiresAt DATETIME NOT NULL,
+    acceptedAt DATETIME NULL,
+    FOREIGN KEY (organisationUUID) REFERENCES OrganisationTable(uuid) ON DELETE CASCADE,
+    FOREIGN KEY (invitedByUUID) REFERENCES UserTable(uuid) ON DELETE CASCADE
+);`
+
+const CreateOrganisationFollowersTableQuery = `
+CREATE TABLE IF NOT EXISTS OrganisationFollowersTable(
+    organisationUUID VARCHAR(36) NOT NULL,
+    userUUID VARCHAR(36) NOT NULL,
+    notify BOOL NOT NULL DEFAULT FALSE,
+    followedAt DATETIME DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (organisationUUID, userUUID),
+    FOREIGN KEY (organisationUUID) REFERENCES OrganisationTable(uuid) ON DELETE CASCADE,
+    FOREIGN KEY (userUUID) REFERENCES UserTable(uuid) ON DELETE CASCADE
+);`
+
+const CreateOrganisationReviewsTableQuery = `
+CREATE TABLE IF NOT EXISTS OrganisationReviewsTable(
+    organisationUUID VARCHAR(36) NOT NULL,
+    userUUID VARCHAR(36) NOT NULL,
+    rating TINYINT NOT NULL,
+    comment TEXT,
+    createdAt DATETIME DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    PRIMARY KEY (organisationUUID, userUUID),
+    FOREIGN KEY (organisationUUID) REFERENCES OrganisationTable(uuid) ON DELETE CASCADE,
+    FOREIGN KEY (userUUID) REFERENCES UserTable(uuid) ON DELETE CASCADE
+);`
+
+// Opportunities are owned by an organisation, postedByUUID stays as the author
+const AddOpportunityOrganisationColumnQuery = `
+ALTER TABLE OpportunitiesTable ADD COLUMN organisationUUID VARCHAR(36) NULL
+`
+
+const AddOpportunityOrganisationForeignKeyQuery = `
+ALTER TABLE OpportunitiesTable ADD CONSTRAINT fk_opportunity_organisation
+FOREIGN KEY (organisationUUID) REFERENCES OrganisationTable(uuid) ON DELETE CASCADE
+`
+
+const CreateOrganisationMemberUserIndex = "CREATE INDEX idx_organisation_member_user ON OrganisationMembersTable(userUUID);"
+const CreateOrganisationFollowerUserIndex = "CREATE INDEX idx_organisation_follower_user ON OrganisationFollowersTable(userUUID);"
+
+const InsertOrganisationQuery = `
+INSERT INTO OrganisationTable(uuid, name, description, logoURL, website) VALUES (?,?,?,?,?)
+`
+
+const UpdateOrganisationQuery = `
+UPDATE OrganisationTable
+SET name = ?, description = ?, logoURL = ?, website = ?
+WHERE uuid = ?
+`
+
+const UpdateOrganisationVerificationQuery = `
+UPDATE OrganisationTable
+SET verificationStatus = ?
+WHERE uuid = ?
+`
+
+const GetOrganisationQuery = `
+SELECT uuid, name, description, logoURL, website, verificationStatus, createdAt FROM OrganisationTable
+WHERE uuid = ?
+`
+
+const GetOrganisationsByMemberQuery = `
+SELECT o.uuid, o.name, o.description, o.logoURL, o.website, o.verificationStatus, o.createdAt, om.role
+FROM OrganisationMembersTable om
+INNER JOIN OrganisationTable o
+    ON o.uuid = om.organisationUUID
+WHERE om.userUUID = ?
+`
+
+const InsertOrganisationMemberQuery = `
+INSERT INTO OrganisationMembersTable(organisationUUID, userUUID, role) VALUES (?,?,?)
+ON DUPLICATE KEY UPDATE role = VALUES(role)
+`
+
+const UpdateOrganisationMemberRoleQuery = `
+UPDATE OrganisationMembersTable
+SET role = ?
+WHERE organisationUUID = ? AND userUUID = ?
+`
+
+const DeleteOrganisationMemberQuery = `
+DELETE FROM OrganisationMembersTable WHERE organisationUUID = ? AND userUUID = ?
+`
+
+const GetOrganisationMemberQuery = `
+SELECT om.organisationUUID, om.userUUID, ut.username, om.role, om.joinedAt
+FROM OrganisationMembersTable om
+INNER JOIN UserTable ut
+    ON ut.uuid = om.userUUID
+WHERE om.organisationUUID = ? AND om.userUUID = ?
+`
+
+const GetOrganisationMembersQuery = `
+SELECT om.organisationUUID, om.userUUID, ut.username, om.role, om.joinedAt
+FROM OrganisationMembersTable om
+INNER JOIN UserTable ut
+    ON ut.uuid = om.userUUID
+WHERE om.organisationUUID = ?
+`
+
+const CountOrganisationOwnersQuery = `
+SELECT COUNT(*) FROM OrganisationMembersTable WHERE organisationUUID = ? AND role = 'owner'
+`
+
+const InsertOrganisationInviteQuery = `
+INSERT INTO OrganisationInvitesTable(uuid, organisationUUID, email, role, invitedByUUID, tokenHash, expiresAt)
+VALUES (?,?,?,?,?,?,?)
+`
+
+const GetOrganisationInviteByTokenQuery = `
+SELECT uuid, organisationUUID, email, role, invitedByUUID, tokenHash, expiresAt, acceptedAt
+FROM OrganisationInvitesTable
+WHERE tokenHash = ?
+`
+
+const AcceptOrganisationInviteQuery = `
+UPDATE OrganisationInvitesTable
+SET acceptedAt = CURRENT_TIMESTAMP
+WHERE uuid = ? AND acceptedAt IS NULL
+`
+
+// Followers
+
+const InsertOrganisationFollowerQuery = `
+INSERT INTO OrganisationFollowersTable(organisationUUID, userUUID, notify) VALUES (?,?,?)
+ON DUPLICATE KEY UPDATE notify = VALUES(notify)
+`
+
+const DeleteOrganisationFollowerQuery = `
+DELETE FROM OrganisationFollowersTable WHERE organisationUUID = ? AND userUUID = ?
+`
+
+const GetFollowedOrganisationsQuery = `
+SELECT o.uuid, o.name, o.description, o.logoURL, o.website, o.verificationStatus, o.createdAt
+FROM OrganisationFollowersTable f
+INNER JOIN OrganisationTable o
+    ON o.uuid = f.organisationUUID
+WHERE f.userUUID = ?
+`
+
+// Public profile
+
+const GetOrganisationImpactQuery = `
+SELECT
+    (SELECT COUNT(*) FROM OpportunitiesTable WHERE organisationUUID = ? AND approved = TRUE),
+    (SELECT COALESCE(SUM(points), 0) FROM OpportunitiesTable WHERE organisationUUID = ? AND approved = TRUE),
+    (SELECT COUNT(DISTINCT olt.userUUID)
+        FROM OpportunityLikesTable olt
+        INNER JOIN OpportunitiesTable ot
+            ON ot.uuid = olt.opportunityUUID
+        WHERE ot.organisationUUID = ?),
+    (SELECT COUNT(*) FROM OrganisationFollowersTable WHERE organisationUUID = ?)
+`
+
+// Reviews
+
+const InsertOrganisationReviewQuery = `
+INSERT INTO OrganisationReviewsTable(organisationUUID, userUUID, rating, comment) VALUES (?,?,?,?)
+ON DUPLICATE KEY UPDATE rating = VALUES(rating), comment = VALUES(comment)
+`
+
+const GetOrganisationReviewSummaryQuery = `
+SELECT COUNT(*), COALESCE(AVG(rating), 0) FROM OrganisationReviewsTable WHERE organisationUUID = ?
+`
+
+const GetOrganisationReviewsQuery = `
+SELECT r.organisationUUID, r.userUUID, ut.username, r.rating, r.comment, r.createdAt
+FROM OrganisationReviewsTable r
+INNER JOIN UserTable ut
+    ON ut.uuid = r.userUUID
+WHERE r.organisationUUID = ?
+ORDER BY r.createdAt DESC
+LIMIT ?
+`
+
+type OrganisationRepository struct {
+	*BaseRepository
+}
+
+// NewOrganisationRepository initializes a new OrganisationRepository instance
+func NewOrganisationRepository(db *mysql.Repository) (*OrganisationRepository, error) {
+	or := &OrganisationRepository{}
+	baseRepo, err := InitRepository(or, db)
+
+	if err != nil {
+		return nil, err
+	}
+	or.BaseRepository = baseRepo
+	return or, nil
+}
+
+// CreateTablesQuery returns a list of SQL queries needed to create necessary tables for organisations
+func (_ *OrganisationRepository) CreateTablesQuery() *[]string {
+	return &[]string{CreateOrganisationTableQuery, CreateOrganisationMembersTableQuery, CreateOrganisationInvitesTableQuery,
+		CreateOrganisationFollowersTableQuery, CreateOrganisationReviewsTableQuery}
+}
+
+// MigrationQueries links opportunities to organisations on databases created before organisations existed
+func (_ *OrganisationRepository) MigrationQueries() *[]string {
+	return &[]string{AddOpportunityOrganisationColumnQuery, AddOpportunityOrganisationForeignKeyQuery}
+}
+
+// CreateIndexesQuery returns a list of SQL queries needed to create necessary indexes for organisations
+func (_ *OrganisationRepository) CreateIndexesQuery() *[]string {
+	return &[]string{CreateOrganisationMemberUserIndex, CreateOrganisationFollowerUserIndex}
+}
+
+// CreateOrganisation creates the organisation with the given user as its owner
+func (repo *OrganisationRepository) CreateOrganisation(ctx context.Context, model *models.OrganisationModel, ownerUUID uuid.UUID) error {
+	container := repo.Repository
+
+	return container.WithTx(ctx, func(tx *mysql.Tx) error {
+		columns := []mysql.Column{
+			mysql.NewUUIDColumn("uuid", model.UUID),
+			mysql.NewVarcharColumn("name", model.Name),
+			mysql.NewTextColumn("description", model.Description),
+			mysql.NewTextColumn("logoURL", model.LogoURL),
+			mysql.NewVarcharColumn("website", model.Website),
+		}
+
+		_, err := container.AddExecuteTransactionContext(tx.Context(), tx.Tx, InsertOrganisationQuery, columns)
+		if err != nil {
+			log.Error(err)
+			return err
+		}
+
+		owner := models.OrganisationOwner
+		columns = []mysql.Column{
+			mysql.NewUUIDColumn("organisationUUID", model.UUID),
+			mysql.NewUUIDColumn("userUUID", ownerUUID),
+			mysql.NewVarcharColumn("role", owner.String()),
+		}
+
+		_, err = container.AddExecuteTransactionContext(tx.Context(), tx.Tx, InsertOrganisationMemberQuery, columns)
+		if err != nil {
+			log.Error(err)
+			return err
+		}
+		return nil
+	})
+}
+
+// UpdateOrganisation updates the organisations profile
+func (repo *OrganisationRepository) UpdateOrganisation(ctx context.Context, model *models.OrganisationModel) error {
+	columns := []mysql.Column{
+		mysql.NewVarcharColumn("name", model.Name),
+		mysql.NewTextColumn("description", model.Description),
+		mysql.NewTextColumn("logoURL", model.LogoURL),
+		mysql.NewVarcharColumn("website", model.Website),
+		mysql.NewUUIDColumn("uuid", model.UUID),
+	}
+
+	_, err := repo.Repository.ExecuteInsertContext(ctx, UpdateOrganisationQuery, columns, mysql.InsertOptions{})
+	return err
+}
+
+// UpdateVerificationStatus sets whether the organisation has been verified by an admin
+func (repo *OrganisationRepository) UpdateVerificationStatus(ctx context.Context, organisationUUID uuid.UUID, status models.VerificationStatus) error {
+	columns := []mysql.Column{
+		mysql.NewVarcharColumn("verificationStatus", status.String()),
+		mysql.NewUUIDColumn("uuid", organisationUUID),
+	}
+
+	_, err := repo.Repository.ExecuteInsertContext(ctx, UpdateOrganisationVerificationQuery, columns, mysql.InsertOptions{})
+	return err
+}
+
+// GetOrganisation returns the organisation or nil if it doesn't exist
+func (repo *OrganisationRepository) GetOrganisation(ctx context.Context, organisationUUID uuid.UUID) (*models.OrganisationModel, error) {
+	columns := []mysql.Column{
+		mysql.NewUUIDColumn("uuid", organisationUUID),
+	}
+
+	rows, err := repo.Repository.ExecuteQueryContext(ctx, GetOrganisationQuery, columns, mysql.QueryOptions{})
+	if err != nil {
+		log.Error(err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		return nil, nil
+	}
+
+	return scanOrganisation(rows)
+}
+
+// GetOrganisationsByMember returns every organisation the user is a member of along with their role
+func (repo *OrganisationRepository) GetOrganisationsByMember(ctx context.Context, userUUID uuid.UUID) ([]models.OrganisationMembershipModel, error) {
+	columns := []mysql.Column{
+		mysql.NewUUIDColumn("userUUID", userUUID),
+	}
+
+	rows, err := repo.Repository.ExecuteQueryContext(ctx, GetOrganisationsByMemberQuery, columns, mysql.QueryOptions{})
+	if err != nil {
+		log.Error(err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var memberships []models.OrganisationMembershipModel
+
+	for rows.Next() {
+		var roleName string
+		organisation, err := scanOrganisation(rows, &roleName)
+		if err != nil {
+			return nil, err
+		}
+
+		role, err := models.ParseOrganisationRole(roleName)
+		if err != nil {
+			return nil, err
+		}
+
+		memberships = append(memberships, models.OrganisationMembershipModel{
+			Organisation: *organisation,
+			Role:         role,
+		})
+	}
+
+	return memberships, nil
+}
+
+// scanOrganisation scans an organisation row, extra is scanned into after the organisation columns
+func scanOrganisation(rows *sql.Rows, extra ...interface{}) (*models.OrganisationModel, error) {
+	var organisation models.OrganisationModel
+	var description sql.NullString
+	var logoURL sql.NullString
+	var website sql.NullString
+	var status string
+
+	destinations := []interface{}{&organisation.UUID, &organisation.Name, &description, &logoURL, &website, &status, &organisation.CreatedAt}
+	destinations = append(destinations, extra...)
+
+	err := rows.Scan(destinations...)
+	if err != nil {
+		log.Error(err)
+		return nil, err
+	}
+
+	verificationStatus, err := models.ParseVerificationStatus(status)
+	if err != nil {
+		return nil, err
+	}
+
+	organisation.Description = description.String
+	organisation.LogoURL = logoURL.String
+	organisation.Website = website.String
+	organisation.VerificationStatus = verificationStatus
+
+	return &organisation, nil
+}
+
+// AddMember adds a user to the organisation, or changes their role if they're already a member
+func (repo *OrganisationRepository) AddMember(ctx context.Context, organisationUUID uuid.UUID, userUUID uuid.UUID, role models.OrganisationRole) error {
+	columns := []mysql.Column{
+		mysql.NewUUIDColumn("organisationUUID", organisationUUID),
+		mysql.NewUUIDColumn("userUUID", userUUID),
+		mysql.NewVarcharColumn("role", role.String()),
+	}
+
+	_, err := repo.Repository.ExecuteInsertContext(ctx, InsertOrganisationMemberQuery, columns, mysql.InsertOptions{})
+	return err
+}
+
+// UpdateMemberRole changes an existing members role
+func (repo *OrganisationRepository) UpdateMemberRole(ctx context.Context, organisationUUID uuid.UUID, userUUID uuid.UUID, role models.OrganisationRole) error {
+	columns := []mysql.Column{
+		mysql.NewVarcharColumn("role", role.String()),
+		mysql.NewUUIDColumn("organisationUUID", organisationUUID),
+		mysql.NewUUIDColumn("userUUID", userUUID),
+	}
+
+	_, err := repo.Repository.ExecuteInsertContext(ctx, UpdateOrganisationMemberRoleQuery, columns, mysql.InsertOptions{})
+	return err
+}
+
+// RemoveMember removes a user from the organisation
+func (repo *OrganisationRepository) RemoveMember(ctx context.Context, organisationUUID uuid.UUID, userUUID uuid.UUID) error {
+	columns := []mysql.Column{
+		mysql.NewUUIDColumn("organisationUUID", organisationUUID),
+		mysql.NewUUIDColumn("userUUID", userUUID),
+	}
+
+	_, err := repo.Repository.ExecuteInsertContext(ctx, DeleteOrganisationMemberQuery, columns, mysql.InsertOptions{})
+	return err
+}
+
+// GetMember returns the users membership of the organisation or nil if they aren't a member
+func (repo *OrganisationRepository) GetMember(ctx context.Context, organisationUUID uuid.UUID, userUUID uuid.UUID) (*models.OrganisationMemberModel, error) {
+	columns := []mysql.Column{
+		mysql.NewUUIDColumn("organisationUUID", organisationUUID),
+		mysql.NewUUIDColumn("userUUID", userUUID),
+	}
+
+	rows, err := repo.Repository.ExecuteQueryContext(ctx, GetOrganisationMemberQuery, columns, mysql.QueryOptions{})
+	if err != nil {
+		log.Error(err)
+		return nil, err
+	}
+
+	members, err := getMembers(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(members) == 0 {
+		return nil, nil
+	}
+
+	return &members[0], nil
+}
+
+// GetMembers returns every member of the organisation
+func (repo *OrganisationRepository) GetMembers(ctx context.Context, organisationUUID uuid.UUID) ([]models.OrganisationMemberModel, error) {
+	columns := []mysql.Column{
+		mysql.NewUUIDColumn("organisationUUID", organisationUUID),
+	}
+
+	rows, err := repo.Repository.ExecuteQueryContext(ctx, GetOrganisationMembersQuery, columns, mysql.QueryOptions{})
+	if err != nil {
+		log.Error(err)
+		return nil, err
+	}
+
+	return getMembers(rows)
+}
+
+func getMembers(rows *sql.Rows) ([]models.OrganisationMemberModel, error) {
+	defer rows.Close()
+
+	var members []models.OrganisationMemberModel
+
+	for rows.Next() {
+		var member models.OrganisationMemberModel
+		var roleName string
+
+		err := rows.Scan(&member.OrganisationUUID, &member.UserUUID, &member.Username, &roleName, &member.JoinedAt)
+		if err != nil {
+			log.Error(err)
+			return nil, err
+		}
+
+		member.Role, err = models.ParseOrganisationRole(roleName)
+		if err != nil {
+			return nil, err
+		}
+
+		members = append(members, member)
+	}
+
+	return members, nil
+}
+
+// CountOwners returns how many owners the organisation has, used to stop the last owner leaving
+func (repo *OrganisationRepository) CountOwners(ctx context.Context, organisationUUID uuid.UUID) (int64, error) {
+	columns := []mysql.Column{
+		mysql.NewUUIDColumn("organisationUUID", organisationUUID),
+	}
+
+	rows, err := repo.Repository.ExecuteQueryContext(ctx, CountOrganisationOwnersQuery, columns, mysql.QueryOptions{})
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	var count int64
+	if rows.Next() {
+		err = rows.Scan(&count)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return count, nil
+}
+
+// CreateInvite stores an invite to the organisation
+func (repo *OrganisationRepository) CreateInvite(ctx context.Context, invite *models.OrganisationInviteModel) error {
+	columns := []mysql.Column{
+		mysql.NewUUIDColumn("uuid", invite.UUID),
+		mysql.NewUUIDColumn("organisationUUID", invite.OrganisationUUID),
+		mysql.NewVarcharColumn("email", invite.Email),
+		mysql.NewVarcharColumn("role", invite.Role.String()),
+		mysql.NewUUIDColumn("invitedByUUID", invite.InvitedByUUID),
+		mysql.NewVarcharColumn("tokenHash", invite.TokenHash),
+		mysql.NewDateTimeColumn("expiresAt", invite.ExpiresAt),
+	}
+
+	_, err := repo.Repository.ExecuteInsertContext(ctx, InsertOrganisationInviteQuery, columns, mysql.InsertOptions{})
+	return err
+}
+
+// GetInviteByTokenHash returns the invite with the given token hash or nil
+func (repo *OrganisationRepository) GetInviteByTokenHash(ctx context.Context, tokenHash string) (*models.OrganisationInviteModel, error) {
+	columns := []mysql.Column{
+		mysql.NewVarcharColumn("tokenHash", tokenHash),
+	}
+
+	rows, err := repo.Repository.ExecuteQueryContext(ctx, GetOrganisationInviteByTokenQuery, columns, mysql.QueryOptions{})
+	if err != nil {
+		log.Error(err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		return nil, nil
+	}
+
+	var invite models.OrganisationInviteModel
+	var roleName string
+	var acceptedAt sql.NullTime
+
+	err = rows.Scan(&invite.UUID, &invite.OrganisationUUID, &invite.Email, &roleName, &invite.InvitedByUUID,
+		&invite.TokenHash, &invite.ExpiresAt, &acceptedAt)
+	if err != nil {
+		log.Error(err)
+		return nil, err
+	}
+
+	invite.Role, err = models.ParseOrganisationRole(roleName)
+	if err != nil {
+		return nil, err
+	}
+
+	if acceptedAt.Valid {
+		accepted := acceptedAt.Time
+		invite.AcceptedAt = &accepted
+	}
+
+	return &invite, nil
+}
+
+// AcceptInvite marks the invite accepted and adds the user to the organisation in one transaction
+func (repo *OrganisationRepository) AcceptInvite(ctx context.Context, invite *models.OrganisationInviteModel, userUUID uuid.UUID) error {
+	container := repo.Repository
+
+	return container.WithTx(ctx, func(tx *mysql.Tx) error {
+		result, err := container.AddExecuteTransactionContext(tx.Context(), tx.Tx, AcceptOrganisationInviteQuery, []mysql.Column{
+			mysql.NewUUIDColumn("uuid", invite.UUID),
+		})
+		if err != nil {
+			log.Error(err)
+			return err
+		}
+
+		// Someone else accepted it first
+		if affected, err := result.RowsAffected(); err != nil || affected == 0 {
+			return sql.ErrNoRows
+		}
+
+		columns := []mysql.Column{
+			mysql.NewUUIDColumn("organisationUUID", invite.OrganisationUUID),
+			mysql.NewUUIDColumn("userUUID", userUUID),
+			mysql.NewVarcharColumn("role", invite.Role.String()),
+		}
+
+		_, err = container.AddExecuteTransactionContext(tx.Context(), tx.Tx, InsertOrganisationMemberQuery, columns)
+		if err != nil {
+			log.Error(err)
+			return err
+		}
+
+		now := time.Now()
+		invite.AcceptedAt = &now
+		return nil
+	})
+}
+
+// Follow follows the organisation, notify is whether the user wants to be notified of new opportunities
+func (repo *OrganisationRepository) Follow(ctx context.Context, organisationUUID uuid.UUID, userUUID uuid.UUID, notify bool) error {
+	columns := []mysql.Column{
+		mysql.NewUUIDColumn("organisationUUID", organisationUUID),
+		mysql.NewUUIDColumn("userUUID", userUUID),
+		mysql.NewBoolColumn("notify", notify),
+	}
+
+	_, err := repo.Repository.ExecuteInsertContext(ctx, InsertOrganisationFollowerQuery, columns, mysql.InsertOptions{})
+	return err
+}
+
+func (repo *OrganisationRepository) Unfollow(ctx context.Context, organisationUUID uuid.UUID, userUUID uuid.UUID) error {
+	columns := []mysql.Column{
+		mysql.NewUUIDColumn("organisationUUID", organisationUUID),
+		mysql.NewUUIDColumn("userUUID", userUUID),
+	}
+
+	_, err := repo.Repository.ExecuteInsertContext(ctx, DeleteOrganisationFollowerQuery, columns, mysql.InsertOptions{})
+	return err
+}
+
+// GetFollowedOrganisations returns every organisation the user follows
+func (repo *OrganisationRepository) GetFollowedOrganisations(ctx context.Context, userUUID uuid.UUID) ([]models.OrganisationModel, error) {
+	columns := []mysql.Column{
+		mysql.NewUUIDColumn("userUUID", userUUID),
+	}
+
+	rows, err := repo.Repository.ExecuteQueryContext(ctx, GetFollowedOrganisationsQuery, columns, mysql.QueryOptions{})
+	if err != nil {
+		log.Error(err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var organisations []models.OrganisationModel
+
+	for rows.Next() {
+		organisation, err := scanOrganisation(rows)
+		if err != nil {
+			return nil, err
+		}
+		organisations = append(organisations, *organisation)
+	}
+
+	return organisations, nil
+}
+
+// GetImpact returns the totals shown on the organisations public profile
+func (repo *OrganisationRepository) GetImpact(ctx context.Context, organisationUUID uuid.UUID) (*models.OrganisationImpactModel, error) {
+	columns := []mysql.Column{
+		mysql.NewUUIDColumn("organisationUUID", organisationUUID),
+		mysql.NewUUIDColumn("organisationUUID", organisationUUID),
+		mysql.NewUUIDColumn("organisationUUID", organisationUUID),
+		mysql.NewUUIDColumn("organisationUUID", organisationUUID),
+	}
+
+	rows, err := repo.Repository.ExecuteQueryContext(ctx, GetOrganisationImpactQuery, columns, mysql.QueryOptions{})
+	if err != nil {
+		log.Error(err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var impact models.OrganisationImpactModel
+	if rows.Next() {
+		err = rows.Scan(&impact.OpportunitiesPosted, &impact.PointsOffered, &impact.StudentsEngaged, &impact.Followers)
+		if err != nil {
+			log.Error(err)
+			return nil, err
+		}
+	}
+
+	return &impact, nil
+}
+
+// AddReview adds the users review of the organisation, replacing any review they already left
+func (repo *OrganisationRepository) AddReview(ctx context.Context, review *models.OrganisationReviewModel) error {
+	columns := []mysql.Column{
+		mysql.NewUUIDColumn("organisationUUID", review.OrganisationUUID),
+		mysql.NewUUIDColumn("userUUID", review.UserUUID),
+		mysql.NewIntegerColumn("rating", review.Rating),
+		mysql.NewTextColumn("comment", review.Comment),
+	}
+
+	_, err := repo.Repository.ExecuteInsertContext(ctx, InsertOrganisationReviewQuery, columns, mysql.InsertOptions{})
+	return err
+}
+
+// GetReviews returns the review count, average rating and the most recent limit reviews
+func (repo *OrganisationRepository) GetReviews(ctx context.Context, organisationUUID uuid.UUID, limit int64) (*models.OrganisationReviewsModel, error) {
+	columns := []mysql.Column{
+		mysql.NewUUIDColumn("organisationUUID", organisationUUID),
+	}
+
+	rows, err := repo.Repository.ExecuteQueryContext(ctx, GetOrganisationReviewSummaryQuery, columns, mysql.QueryOptions{})
+	if err != nil {
+		log.Error(err)
+		return nil, err
+	}
+
+	summary := models.OrganisationReviewsModel{Recent: []models.OrganisationReviewModel{}}
+	if rows.Next() {
+		err = rows.Scan(&summary.Count, &summary.AverageRating)
+	}
+	rows.Close()
+	if err != nil {
+		log.Error(err)
+		return nil, err
+	}
+
+	columns = append(columns, mysql.NewIntegerColumn("limit", limit))
+
+	rows, err = repo.Repository.ExecuteQueryContext(ctx, GetOrganisationReviewsQuery, columns, mysql.QueryOptions{})
+	if err != nil {
+		log.Error(err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var review models.OrganisationReviewModel
+		var comment sql.NullString
+
+		err = rows.Scan(&review.OrganisationUUID, &review.UserUUID, &review.Username, &review.Rating, &comment, &review.CreatedAt)
+		if err != nil {
+			log.Error(err)
+			return nil, err
+		}
+
+		review.Comment = comment.String
+		summary.Recent = append(summary.Recent, review)
+	}
+
+	return &summary, nil
+}
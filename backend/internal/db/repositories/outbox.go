@@ -0,0 +1,327 @@
+package repositories
+
+import (
+	"backend/internal/db/adapters/mysql"
+	"backend/internal/outbox"
+	"context"
+	"database/sql"
+	"github.com/google/uuid"
+	"time"
+)
+
+// The outbox holds events recorded with the writes that caused them until the relay has published them to Neo4j.
+// Published events are deleted. Every repository recording events declares the table, so it exists whichever of them
+// is set up first
+
+var outboxTable = &mysql.Table{
+	Name: "OutboxTable",
+	Columns: &[]mysql.Column{
+		mysql.NewIntegerColumnForTable("id", false, true),
+		mysql.NewVarcharColumnForTable("aggregateType", false, 50),
+		mysql.NewVarcharColumnForTable("aggregateID", false, 100),
+		mysql.NewVarcharColumnForTable("type", false, 100),
+		mysql.NewJSONColumnForTable("payload", false),
+		mysql.NewIntegerColumnForTable("attempts", false, false, mysql.Default("0")),
+		mysql.NewDateTimeColumnForTable("nextAttemptAt", false, mysql.Default("CURRENT_TIMESTAMP")),
+		mysql.NewTextColumnForTable("lastError", true),
+		mysql.NewDateTimeColumnForTable("createdAt", false, mysql.Default("CURRENT_TIMESTAMP")),
+	},
+	PrimaryKeys: &[]string{"id"},
+	Indexes: []mysql.Index{
+		{Name: "idx_outbox_aggregate", Columns: []string{"aggregateType", "aggregateID", "id"}},
+		{Name: "idx_outbox_due", Columns: []string{"nextAttemptAt"}},
+	},
+}
+
+// dataMigrationsTable records the one-off data migrations that have been run, by name
+var dataMigrationsTable = &mysql.Table{
+	Name: "DataMigrationsTable",
+	Columns: &[]mysql.Column{
+		mysql.NewVarcharColumnForTable("name", false, 100),
+		mysql.NewDateTimeColumnForTable("completedAt", false, mysql.Default("CURRENT_TIMESTAMP")),
+	},
+	PrimaryKeys: &[]string{"name"},
+}
+
+// matchesBackfill names the copy of the graph's matches into MatchesTable in DataMigrationsTable
+const matchesBackfill = "backfill-matches"
+
+const InsertOutboxEventQuery = `
+INSERT INTO OutboxTable (aggregateType, aggregateID, type, payload) VALUES (?, ?, ?, ?)
+`
+
+// GetPendingOutboxEventsQuery leaves out events behind one of their aggregate that's waiting to be retried
+const GetPendingOutboxEventsQuery = `
+SELECT o.id, o.aggregateType, o.aggregateID, o.type, o.payload, o.attempts, o.createdAt FROM OutboxTable o
+WHERE o.nextAttemptAt <= ?
+  AND NOT EXISTS (
+    SELECT 1 FROM OutboxTable earlier
+    WHERE earlier.aggregateType = o.aggregateType
+      AND earlier.aggregateID = o.aggregateID
+      AND earlier.id < o.id
+      AND earlier.nextAttemptAt > ?
+  )
+ORDER BY o.id
+LIMIT ?
+`
+
+const DeleteOutboxEventQuery = "DELETE FROM OutboxTable WHERE id = ?"
+
+const FailOutboxEventQuery = `
+UPDATE OutboxTable SET attempts = attempts + 1, nextAttemptAt = ?, lastError = ? WHERE id = ?
+`
+
+const GetPendingAggregatesQuery = "SELECT DISTINCT aggregateType, aggregateID FROM OutboxTable"
+
+const GetDataMigrationQuery = "SELECT COUNT(*) AS completed FROM DataMigrationsTable WHERE name = ?"
+
+const CompleteDataMigrationQuery = "INSERT INTO DataMigrationsTable (name) VALUES (?) ON DUPLICATE KEY UPDATE name = name"
+
+// BackfillMatchQuery adds a match unless it's already recorded or one of its users has since been deleted
+const BackfillMatchQuery = `
+INSERT INTO MatchesTable (userUUID, matchedUUID)
+SELECT ?, ? FROM DUAL
+WHERE EXISTS (SELECT 1 FROM UserTable WHERE uuid = ?)
+  AND EXISTS (SELECT 1 FROM UserTable WHERE uuid = ?)
+ON DUPLICATE KEY UPDATE userUUID = userUUID
+`
+
+const GetProjectedUsersQuery = "SELECT uuid AS a FROM UserTable"
+
+const GetProjectedOpportunitiesQuery = "SELECT uuid AS a FROM OpportunitiesTable"
+
+const GetProjectedLikesQuery = "SELECT userUUID AS a, opportunityUUID AS b FROM OpportunityLikesTable"
+
+const GetProjectedMatchesQuery = "SELECT userUUID AS a, matchedUUID AS b FROM MatchesTable"
+
+// OutboxRepository reads the outbox for the relay and the MySQL side of the projection for reconciling. Implements
+// outbox.Store, outbox.Source and outbox.MatchRecords
+type OutboxRepository struct {
+	*BaseRepository
+}
+
+func NewOutboxRepository(db *mysql.Repository) (*OutboxRepository, error) {
+	repo := &OutboxRepository{}
+	baseRepo, err := InitRepository(repo, db)
+	if err != nil {
+		return nil, err
+	}
+	repo.BaseRepository = baseRepo
+	return repo, nil
+}
+
+func (_ *OutboxRepository) CreateTablesQuery() *[]string {
+	return &[]string{}
+}
+
+func (_ *OutboxRepository) CreateIndexesQuery() *[]string {
+	return &[]string{}
+}
+
+// Tables returns the tables declared with mysql.Table
+func (_ *OutboxRepository) Tables() []*mysql.Table {
+	return []*mysql.Table{outboxTable, dataMigrationsTable}
+}
+
+// recordEvents adds the events to the outbox in the transaction of the write that caused them
+func recordEvents(ctx context.Context, container *mysql.Repository, transaction *sql.Tx, events ...outbox.Event) error {
+	for _, event := range events {
+		_, err := container.AddExecuteTransactionContext(ctx, transaction, InsertOutboxEventQuery, []mysql.Column{
+			mysql.NewVarcharColumn("aggregateType", event.Aggregate.Type),
+			mysql.NewVarcharColumn("aggregateID", event.Aggregate.ID),
+			mysql.NewVarcharColumn("type", event.Type),
+			mysql.NewJSONColumn("payload", event.Payload),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type outboxRow struct {
+	ID            int64     `db:"id"`
+	AggregateType string    `db:"aggregateType"`
+	AggregateID   string    `db:"aggregateID"`
+	Type          string    `db:"type"`
+	Payload       []byte    `db:"payload"`
+	Attempts      int64     `db:"attempts"`
+	CreatedAt     time.Time `db:"createdAt"`
+}
+
+// Pending returns the events due to be published, oldest first
+func (repo *OutboxRepository) Pending(ctx context.Context, now time.Time, limit int) ([]outbox.Event, error) {
+	rows, err := repo.Repository.ExecuteQueryContext(ctx, GetPendingOutboxEventsQuery, []mysql.Column{
+		mysql.NewDateTimeColumn("nextAttemptAt", now),
+		mysql.NewDateTimeColumn("earlierNextAttemptAt", now),
+		mysql.NewIntegerColumn("limit", int64(limit)),
+	}, mysql.QueryOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	pending, err := mysql.ScanAll[outboxRow](rows)
+	if err != nil {
+		return nil, err
+	}
+
+	events := make([]outbox.Event, 0, len(pending))
+	for _, row := range pending {
+		events = append(events, outbox.Event{
+			ID:        row.ID,
+			Aggregate: outbox.Aggregate{Type: row.AggregateType, ID: row.AggregateID},
+			Type:      row.Type,
+			Payload:   row.Payload,
+			Attempts:  int(row.Attempts),
+			CreatedAt: row.CreatedAt,
+		})
+	}
+	return events, nil
+}
+
+// Published deletes the event
+func (repo *OutboxRepository) Published(ctx context.Context, event outbox.Event) error {
+	_, err := repo.Repository.ExecuteInsertContext(ctx, DeleteOutboxEventQuery, []mysql.Column{
+		mysql.NewIntegerColumn("id", event.ID),
+	}, mysql.InsertOptions{})
+	return err
+}
+
+// Failed holds the event back until retryAt
+func (repo *OutboxRepository) Failed(ctx context.Context, event outbox.Event, retryAt time.Time, reason string) error {
+	_, err := repo.Repository.ExecuteInsertContext(ctx, FailOutboxEventQuery, []mysql.Column{
+		mysql.NewDateTimeColumn("nextAttemptAt", retryAt),
+		mysql.NewTextColumn("lastError", truncateError(reason)),
+		mysql.NewIntegerColumn("id", event.ID),
+	}, mysql.InsertOptions{})
+	return err
+}
+
+// PendingAggregates returns the aggregates with events in the outbox
+func (repo *OutboxRepository) PendingAggregates(ctx context.Context) (map[outbox.Aggregate]bool, error) {
+	rows, err := repo.Repository.ExecuteQueryContext(ctx, GetPendingAggregatesQuery, []mysql.Column{}, mysql.QueryOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	aggregates, err := mysql.ScanAll[struct {
+		Type string `db:"aggregateType"`
+		ID   string `db:"aggregateID"`
+	}](rows)
+	if err != nil {
+		return nil, err
+	}
+
+	pending := make(map[outbox.Aggregate]bool, len(aggregates))
+	for _, aggregate := range aggregates {
+		pending[outbox.Aggregate{Type: aggregate.Type, ID: aggregate.ID}] = true
+	}
+	return pending, nil
+}
+
+// MatchesBackfilled reports whether the graph's matches have been copied into MatchesTable
+func (repo *OutboxRepository) MatchesBackfilled(ctx context.Context) (bool, error) {
+	rows, err := repo.Repository.ExecuteQueryContext(ctx, GetDataMigrationQuery, []mysql.Column{
+		mysql.NewVarcharColumn("name", matchesBackfill),
+	}, mysql.QueryOptions{})
+	if err != nil {
+		return false, err
+	}
+
+	migrations, err := mysql.ScanAll[struct {
+		Completed int64 `db:"completed"`
+	}](rows)
+	if err != nil {
+		return false, err
+	}
+	return len(migrations) == 1 && migrations[0].Completed > 0, nil
+}
+
+// BackfillMatches adds the matches MatchesTable doesn't have and marks the backfill done, in one transaction
+func (repo *OutboxRepository) BackfillMatches(ctx context.Context, matches []outbox.Pair) (int64, error) {
+	container := repo.Repository
+
+	var added int64
+	err := container.WithTx(ctx, func(tx *mysql.Tx) error {
+		added = 0
+		for _, match := range matches {
+			pair := outbox.NewPair(match.A, match.B)
+			inserted, err := container.ExecuteInsertContext(tx.Context(), BackfillMatchQuery, []mysql.Column{
+				mysql.NewUUIDColumn("userUUID", pair.A),
+				mysql.NewUUIDColumn("matchedUUID", pair.B),
+				mysql.NewUUIDColumn("userExists", pair.A),
+				mysql.NewUUIDColumn("matchedExists", pair.B),
+			}, mysql.InsertOptions{})
+			if err != nil {
+				return err
+			}
+			added += inserted
+		}
+
+		_, err := container.ExecuteInsertContext(tx.Context(), CompleteDataMigrationQuery, []mysql.Column{
+			mysql.NewVarcharColumn("name", matchesBackfill),
+		}, mysql.InsertOptions{})
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+	return added, nil
+}
+
+// projectedRow is a node's uuid a, or a relation's uuids a and b
+type projectedRow struct {
+	A uuid.UUID `db:"a"`
+	B uuid.UUID `db:"b"`
+}
+
+// Snapshot reads every user, opportunity, like and match
+func (repo *OutboxRepository) Snapshot(ctx context.Context) (*outbox.Snapshot, error) {
+	snapshot := &outbox.Snapshot{}
+
+	users, err := repo.pairs(ctx, GetProjectedUsersQuery)
+	if err != nil {
+		return nil, err
+	}
+	for _, user := range users {
+		snapshot.Users = append(snapshot.Users, user.A)
+	}
+
+	opportunities, err := repo.pairs(ctx, GetProjectedOpportunitiesQuery)
+	if err != nil {
+		return nil, err
+	}
+	for _, opportunity := range opportunities {
+		snapshot.Opportunities = append(snapshot.Opportunities, opportunity.A)
+	}
+
+	if snapshot.Likes, err = repo.pairs(ctx, GetProjectedLikesQuery); err != nil {
+		return nil, err
+	}
+	matches, err := repo.pairs(ctx, GetProjectedMatchesQuery)
+	if err != nil {
+		return nil, err
+	}
+	for _, match := range matches {
+		snapshot.Matches = append(snapshot.Matches, outbox.NewPair(match.A, match.B))
+	}
+	return snapshot, nil
+}
+
+func (repo *OutboxRepository) pairs(ctx context.Context, query string) ([]outbox.Pair, error) {
+	rows, err := repo.Repository.ExecuteQueryContext(ctx, query, []mysql.Column{}, mysql.QueryOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	projected, err := mysql.ScanAll[projectedRow](rows)
+	if err != nil {
+		return nil, err
+	}
+
+	pairs := make([]outbox.Pair, 0, len(projected))
+	for _, row := range projected {
+		pairs = append(pairs, outbox.Pair{A: row.A, B: row.B})
+	}
+	return pairs, nil
+}
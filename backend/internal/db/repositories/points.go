@@ -3,6 +3,7 @@ package repositories
 import (
 	"backend/internal/db/adapters/mysql"
 	"backend/internal/models"
+	"context"
 	"github.com/google/uuid"
 	log "github.com/sirupsen/logrus"
 	"strings"
@@ -60,7 +61,7 @@ func (_ *PointsRepository) CreateIndexesQuery() *[]string {
 	return &[]string{}
 }
 
-func (repo *PointsRepository) IncrementPoints(userUUID uuid.UUID, points int64) error {
+func (repo *PointsRepository) IncrementPoints(ctx context.Context, userUUID uuid.UUID, points int64) error {
 
 	container := repo.Repository
 
@@ -69,7 +70,7 @@ func (repo *PointsRepository) IncrementPoints(userUUID uuid.UUID, points int64)
 		mysql.NewIntegerColumn("points", points),
 	}
 
-	_, err := container.ExecuteQuery(IncrementPointsQuery, columns, mysql.QueryOptions{})
+	_, err := container.ExecuteQueryContext(ctx, IncrementPointsQuery, columns, mysql.QueryOptions{})
 	if err != nil {
 		return err
 	}
@@ -78,7 +79,7 @@ func (repo *PointsRepository) IncrementPoints(userUUID uuid.UUID, points int64)
 
 }
 
-func (repo *PointsRepository) DecrementPoints(userUUID uuid.UUID, points int64) error {
+func (repo *PointsRepository) DecrementPoints(ctx context.Context, userUUID uuid.UUID, points int64) error {
 
 	container := repo.Repository
 
@@ -87,7 +88,7 @@ func (repo *PointsRepository) DecrementPoints(userUUID uuid.UUID, points int64)
 		mysql.NewIntegerColumn("points", points),
 	}
 
-	_, err := container.ExecuteQuery(IncrementPointsQuery, columns, mysql.QueryOptions{})
+	_, err := container.ExecuteQueryContext(ctx, IncrementPointsQuery, columns, mysql.QueryOptions{})
 	if err != nil {
 		return err
 	}
@@ -96,7 +97,7 @@ func (repo *PointsRepository) DecrementPoints(userUUID uuid.UUID, points int64)
 
 }
 
-func (repo *PointsRepository) GetPoints(userUUIDs ...uuid.UUID) (*[]models.StudentModel, error) {
+func (repo *PointsRepository) GetPoints(ctx context.Context, userUUIDs ...uuid.UUID) (*[]models.StudentModel, error) {
 
 	container := repo.Repository
 
@@ -112,7 +113,7 @@ func (repo *PointsRepository) GetPoints(userUUIDs ...uuid.UUID) (*[]models.Stude
 
 	}
 
-	rows, err := container.ExecuteQuery(GetPointsQuery, columns, mysql.QueryOptions{})
+	rows, err := container.ExecuteQueryContext(ctx, GetPointsQuery, columns, mysql.QueryOptions{})
 	if err != nil {
 		return nil, err
 	}
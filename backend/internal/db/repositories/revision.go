@@ -0,0 +1,201 @@
+package repositories
+
+import (
+	"backend/internal/db/adapters/mysql"
+	"backend/internal/models"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+	"time"
+)
+
+// Opportunity revisions. Every create, update and rollback adds a snapshot of the opportunity in the same
+// transaction as the change. Rows are only ever inserted, a rollback restores an old snapshot as a new revision.
+// authorUUID has no foreign key so the history outlives its authors
+
+const CreateOpportunityRevisionsTableQuery = `
+CREATE TABLE IF NOT EXISTS OpportunityRevisionsTable(
+    opportunityUUID VARCHAR(36) NOT NULL,
+    revision INT NOT NULL,
+    authorUUID VARCHAR(36) NOT NULL,
+    createdAt DATETIME NOT NULL,
+    rolledBackFrom INT NULL,
+    snapshot JSON NOT NULL,
+    PRIMARY KEY (opportunityUUID, revision),
+    FOREIGN KEY (opportunityUUID) REFERENCES OpportunitiesTable(uuid) ON DELETE CASCADE
+);`
+
+// The opportunity row is locked by the caller so revision numbers can't be taken twice
+const InsertOpportunityRevisionQuery = `
+INSERT INTO OpportunityRevisionsTable(opportunityUUID, revision, authorUUID, createdAt, rolledBackFrom, snapshot)
+SELECT ?, COALESCE(MAX(revision), 0) + 1, ?, ?, ?, ? FROM OpportunityRevisionsTable WHERE opportunityUUID = ?
+`
+
+const CountOpportunityRevisionsQuery = "SELECT COUNT(*) FROM OpportunityRevisionsTable WHERE opportunityUUID = ?"
+
+const GetOpportunityRevisionsQuery = `
+SELECT opportunityUUID, revision, authorUUID, createdAt, rolledBackFrom, snapshot
+FROM OpportunityRevisionsTable
+WHERE opportunityUUID = ? %s
+ORDER BY revision
+`
+
+const LockOpportunityQuery = "SELECT updatedAt, postedByUUID FROM OpportunitiesTable WHERE uuid = ? FOR UPDATE"
+
+// GetOpportunityAnyStatusQuery is GetOpportunityByIDQuery without hiding unapproved opportunities
+const GetOpportunityAnyStatusQuery = `
+SELECT * FROM OpportunitiesTable
+LEFT JOIN OpportunityMediaTable
+  ON OpportunitiesTable.uuid = OpportunityMediaTable.opportunityUUID
+LEFT JOIN OpportunityTagsTable
+  ON OpportunitiesTable.uuid = OpportunityTagsTable.opportunityUUID
+LEFT JOIN TagsTable
+  ON OpportunityTagsTable.tagID = TagsTable.id
+WHERE OpportunitiesTable.uuid = ?;
+`
+
+// GetOpportunityAnyStatus returns the opportunity whether or not it's approved or a draft, nil if it doesn't exist
+func (repo *OpportunityRepository) GetOpportunityAnyStatus(ctx context.Context, opportunityUUID uuid.UUID) (*models.OpportunityModel, error) {
+	rows, err := repo.Repository.ExecuteQueryContext(ctx, GetOpportunityAnyStatusQuery, []mysql.Column{
+		mysql.NewUUIDColumn("uuid", opportunityUUID),
+	}, mysql.QueryOptions{})
+	if err != nil {
+		log.Error(err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	opportunities, _, err := getOpportunity(rows)
+	if err != nil || opportunities == nil {
+		return nil, err
+	}
+	return &(*opportunities)[0], nil
+}
+
+// GetRevisions returns every revision of the opportunity, oldest first
+func (repo *OpportunityRepository) GetRevisions(ctx context.Context, opportunityUUID uuid.UUID) ([]models.OpportunityRevisionModel, error) {
+	return repo.queryRevisions(ctx, fmt.Sprintf(GetOpportunityRevisionsQuery, ""), []mysql.Column{
+		mysql.NewUUIDColumn("opportunityUUID", opportunityUUID),
+	})
+}
+
+// GetRevision returns the revision, nil if the opportunity doesn't have it
+func (repo *OpportunityRepository) GetRevision(ctx context.Context, opportunityUUID uuid.UUID, revision int64) (*models.OpportunityRevisionModel, error) {
+	revisions, err := repo.queryRevisions(ctx, fmt.Sprintf(GetOpportunityRevisionsQuery, "AND revision = ?"), []mysql.Column{
+		mysql.NewUUIDColumn("opportunityUUID", opportunityUUID),
+		mysql.NewIntegerColumn("revision", revision),
+	})
+	if err != nil || len(revisions) == 0 {
+		return nil, err
+	}
+	return &revisions[0], nil
+}
+
+func (repo *OpportunityRepository) queryRevisions(ctx context.Context, query string, columns []mysql.Column) ([]models.OpportunityRevisionModel, error) {
+	rows, err := repo.Repository.ExecuteQueryContext(ctx, query, columns, mysql.QueryOptions{})
+	if err != nil {
+		log.Error(err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	revisions := make([]models.OpportunityRevisionModel, 0)
+	for rows.Next() {
+		var revision models.OpportunityRevisionModel
+		var rolledBackFrom sql.NullInt64
+		var snapshot []byte
+
+		if err := rows.Scan(&revision.OpportunityUUID, &revision.Revision, &revision.AuthorUUID, &revision.CreatedAt,
+			&rolledBackFrom, &snapshot); err != nil {
+			log.Error(err)
+			return nil, err
+		}
+		if err := json.Unmarshal(snapshot, &revision.Snapshot); err != nil {
+			log.Error(err)
+			return nil, err
+		}
+		if rolledBackFrom.Valid {
+			revision.RolledBackFrom = &rolledBackFrom.Int64
+		}
+		revisions = append(revisions, revision)
+	}
+	return revisions, rows.Err()
+}
+
+// lockOpportunity locks the opportunity for the rest of the transaction. Opportunities saved before revisions were
+// recorded get their current state as the first revision, so the version before the edit isn't lost
+func lockOpportunity(ctx context.Context, container *mysql.Repository, transaction *sql.Tx, opportunityUUID uuid.UUID) error {
+	uuidColumn := mysql.NewUUIDColumn("uuid", opportunityUUID)
+
+	rows, err := container.AddQueryTransactionContext(ctx, transaction, LockOpportunityQuery, []mysql.Column{uuidColumn})
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+	var updatedAt time.Time
+	var postedByUUID uuid.UUID
+	found := rows.Next()
+	if found {
+		err = rows.Scan(&updatedAt, &postedByUUID)
+	}
+	rows.Close()
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+	if !found {
+		return nil
+	}
+
+	rows, err = container.AddQueryTransactionContext(ctx, transaction, CountOpportunityRevisionsQuery, []mysql.Column{uuidColumn})
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+	var count int64
+	if rows.Next() {
+		err = rows.Scan(&count)
+	}
+	rows.Close()
+	if err != nil || count > 0 {
+		return err
+	}
+
+	rows, err = container.AddQueryTransactionContext(ctx, transaction, GetOpportunityAnyStatusQuery, []mysql.Column{uuidColumn})
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+	current, _, err := getOpportunity(rows)
+	rows.Close()
+	if err != nil || current == nil {
+		return err
+	}
+
+	return recordRevision(ctx, container, transaction, &(*current)[0], postedByUUID, updatedAt, nil)
+}
+
+// recordRevision adds the opportunity as it is now as its next revision
+func recordRevision(ctx context.Context, container *mysql.Repository, transaction *sql.Tx, model *models.OpportunityModel,
+	authorUUID uuid.UUID, at time.Time, rolledBackFrom *int64) error {
+	snapshot, err := json.Marshal(model.Snapshot())
+	if err != nil {
+		return err
+	}
+
+	_, err = container.AddExecuteTransactionContext(ctx, transaction, InsertOpportunityRevisionQuery, []mysql.Column{
+		mysql.NewUUIDColumn("opportunityUUID", model.UUID),
+		mysql.NewUUIDColumn("authorUUID", authorUUID),
+		mysql.NewDateTimeColumn("createdAt", at.UTC()),
+		mysql.NewNullableIntegerColumn("rolledBackFrom", rolledBackFrom),
+		mysql.NewTextColumn("snapshot", string(snapshot)),
+		mysql.NewUUIDColumn("opportunityUUID", model.UUID),
+	})
+	if err != nil {
+		log.Error(err)
+	}
+	return err
+}
@@ -0,0 +1,250 @@
+package repositories
+
+import (
+	"backend/internal/db/adapters/mysql"
+	"backend/internal/search"
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Full text search over opportunities using MySQL FULLTEXT indexes.
+// MySQL has no typo tolerance so query terms are expanded with similar words from a vocabulary built from the tables
+
+const AddOpportunityFullTextIndexQuery = `
+ALTER TABLE OpportunitiesTable ADD FULLTEXT INDEX ft_opportunity (title, description, location)
+`
+
+// A title only index so title matches can be weighted above description matches
+const AddOpportunityTitleFullTextIndexQuery = `
+ALTER TABLE OpportunitiesTable ADD FULLTEXT INDEX ft_opportunity_title (title)
+`
+
+const AddTagFullTextIndexQuery = `
+ALTER TABLE TagsTable ADD FULLTEXT INDEX ft_tag (tagName)
+`
+
+const AddOrganisationFullTextIndexQuery = `
+ALTER TABLE OrganisationTable ADD FULLTEXT INDEX ft_organisation (name)
+`
+
+// SearchOpportunitiesQuery fields are weighted the same as the in memory index. %s is openOpportunityCondition
+const SearchOpportunitiesQuery = `
+SELECT ot.uuid, ot.title, ot.description, COALESCE(ot.location, ''), COALESCE(o.name, ''),
+       COALESCE(GROUP_CONCAT(DISTINCT tt.tagName SEPARATOR ', '), ''),
+       MATCH(ot.title, ot.description, ot.location) AGAINST (? IN BOOLEAN MODE)
+         + 3 * MATCH(ot.title) AGAINST (? IN BOOLEAN MODE)
+         + 2 * COALESCE(MAX(MATCH(tt.tagName) AGAINST (? IN BOOLEAN MODE)), 0)
+         + 2 * COALESCE(MATCH(o.name) AGAINST (? IN BOOLEAN MODE), 0) AS score
+FROM OpportunitiesTable ot
+LEFT JOIN OpportunityTagsTable ott
+  ON ot.uuid = ott.opportunityUUID
+LEFT JOIN TagsTable tt
+  ON ott.tagID = tt.id
+LEFT JOIN OrganisationTable o
+  ON o.uuid = ot.organisationUUID
+WHERE ot.approved = TRUE AND %s
+GROUP BY ot.uuid
+HAVING score > 0
+ORDER BY score DESC
+LIMIT ?
+`
+
+const GetSearchVocabularyQuery = `
+SELECT title, description, COALESCE(location, '') FROM OpportunitiesTable WHERE approved = TRUE
+UNION ALL
+SELECT tagName, '', '' FROM TagsTable
+UNION ALL
+SELECT name, '', '' FROM OrganisationTable
+`
+
+// vocabularyTTL is how often the vocabulary used for typo correction is rebuilt from the tables
+const vocabularyTTL = 10 * time.Minute
+
+// maxExpansions is how many similar words each query term is expanded to
+const maxExpansions = 5
+
+var _ search.SearchIndex = (*SearchRepository)(nil)
+
+type SearchRepository struct {
+	*BaseRepository
+
+	mutex        sync.Mutex
+	vocabulary   *search.Vocabulary
+	vocabularyAt time.Time
+}
+
+// NewSearchRepository initializes a new SearchRepository instance
+func NewSearchRepository(db *mysql.Repository) (*SearchRepository, error) {
+	sr := &SearchRepository{}
+	baseRepo, err := InitRepository(sr, db)
+
+	if err != nil {
+		return nil, err
+	}
+	sr.BaseRepository = baseRepo
+	return sr, nil
+}
+
+func (_ *SearchRepository) CreateTablesQuery() *[]string {
+	return &[]string{}
+}
+
+// MigrationQueries adds the FULLTEXT indexes to the existing tables
+func (_ *SearchRepository) MigrationQueries() *[]string {
+	return &[]string{AddOpportunityFullTextIndexQuery, AddOpportunityTitleFullTextIndexQuery, AddTagFullTextIndexQuery,
+		AddOrganisationFullTextIndexQuery}
+}
+
+func (_ *SearchRepository) CreateIndexesQuery() *[]string {
+	return &[]string{}
+}
+
+// Index MySQL keeps the FULLTEXT index up to date itself, only the vocabulary needs the new words
+func (repo *SearchRepository) Index(ctx context.Context, doc search.Document) error {
+	vocabulary := repo.getVocabulary(ctx)
+	for _, text := range doc.Fields() {
+		vocabulary.Add(text)
+	}
+	return nil
+}
+
+// Remove nothing to do, removed words leave the vocabulary on the next rebuild
+func (repo *SearchRepository) Remove(_ context.Context, _ uuid.UUID) error {
+	return nil
+}
+
+func (repo *SearchRepository) Search(ctx context.Context, query search.Query) ([]search.Hit, error) {
+	queryTerms := search.Tokenize(query.Text)
+	if len(queryTerms) == 0 {
+		return []search.Hit{}, nil
+	}
+
+	against := repo.buildBooleanQuery(ctx, queryTerms)
+
+	limit := query.Limit
+	if limit <= 0 {
+		limit = search.DefaultLimit
+	}
+
+	columns := []mysql.Column{
+		mysql.NewVarcharColumn("query", against),
+		mysql.NewVarcharColumn("query", against),
+		mysql.NewVarcharColumn("query", against),
+		mysql.NewVarcharColumn("query", against),
+		mysql.NewDateTimeColumn("now", time.Now().UTC()),
+		mysql.NewIntegerColumn("limit", int64(limit)),
+	}
+
+	// Closed opportunities aren't found
+	searchQuery := fmt.Sprintf(SearchOpportunitiesQuery, strings.ReplaceAll(openOpportunityCondition, "{t}", "ot"))
+	rows, err := repo.Repository.ExecuteQueryContext(ctx, searchQuery, columns, mysql.QueryOptions{})
+	if err != nil {
+		log.Error(err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	hits := []search.Hit{}
+
+	for rows.Next() {
+		var doc search.Document
+		var tags string
+		var score float64
+
+		err = rows.Scan(&doc.UUID, &doc.Title, &doc.Description, &doc.Location, &doc.Organisation, &tags, &score)
+		if err != nil {
+			log.Error(err)
+			return nil, err
+		}
+
+		if tags != "" {
+			doc.Tags = strings.Split(tags, ", ")
+		}
+
+		hits = append(hits, search.Hit{
+			UUID:       doc.UUID,
+			Score:      score,
+			Highlights: search.HighlightDocument(&doc, queryTerms),
+		})
+	}
+
+	return hits, rows.Err()
+}
+
+// buildBooleanQuery turns each query term into "term* similar1 similar2". Terms only contain letters and digits
+// so none of the boolean mode operators can be injected
+func (repo *SearchRepository) buildBooleanQuery(ctx context.Context, queryTerms []string) string {
+	vocabulary := repo.getVocabulary(ctx)
+
+	var parts []string
+	for _, term := range queryTerms {
+		parts = append(parts, term+"*")
+
+		expansions := 0
+		for _, match := range vocabulary.Matches(term) {
+			if expansions == maxExpansions {
+				break
+			}
+			if strings.HasPrefix(match.Term, term) {
+				continue // already covered by term*
+			}
+			parts = append(parts, match.Term)
+			expansions++
+		}
+	}
+
+	return strings.Join(parts, " ")
+}
+
+func (repo *SearchRepository) getVocabulary(ctx context.Context) *search.Vocabulary {
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+
+	if repo.vocabulary != nil && time.Since(repo.vocabularyAt) < vocabularyTTL {
+		return repo.vocabulary
+	}
+
+	vocabulary, err := repo.loadVocabulary(ctx)
+	if err != nil {
+		log.Error("Failed to load search vocabulary: ", err)
+		if repo.vocabulary != nil {
+			return repo.vocabulary
+		}
+		vocabulary = search.NewVocabulary()
+	}
+
+	repo.vocabulary = vocabulary
+	repo.vocabularyAt = time.Now()
+	return vocabulary
+}
+
+func (repo *SearchRepository) loadVocabulary(ctx context.Context) (*search.Vocabulary, error) {
+	rows, err := repo.Repository.ExecuteQueryContext(ctx, GetSearchVocabularyQuery, nil, mysql.QueryOptions{})
+	if err != nil {
+		return nil, err
+	}
+	if rows == nil {
+		return nil, sql.ErrConnDone
+	}
+	defer rows.Close()
+
+	vocabulary := search.NewVocabulary()
+
+	for rows.Next() {
+		var title, description, location string
+		if err := rows.Scan(&title, &description, &location); err != nil {
+			return nil, err
+		}
+		vocabulary.Add(title)
+		vocabulary.Add(description)
+		vocabulary.Add(location)
+	}
+
+	return vocabulary, nil
+}
@@ -3,6 +3,8 @@ package repositories
 import (
 	"backend/internal/db/adapters/mysql"
 	"backend/internal/models"
+	"backend/internal/taxonomy"
+	"context"
 	"database/sql"
 	"fmt"
 	"github.com/google/uuid"
@@ -107,120 +109,76 @@ func (_ *StudentRepository) CreateIndexesQuery() *[]string {
 	return &[]string{}
 }
 
-func (repo *StudentRepository) UpdateStudentInfo(studentInfo models.StudentInfoModel) error {
+func (repo *StudentRepository) UpdateStudentInfo(ctx context.Context, studentInfo models.StudentInfoModel) error {
 	container := repo.Repository
 
-	transaction, err := container.StartTransaction()
-	if err != nil {
-		return err
-	}
-
-	columns := []mysql.Column{
-		mysql.NewTextColumn("description", studentInfo.Description),
-		mysql.NewTextColumn("profile", studentInfo.ProfilePic),
-		mysql.NewUUIDColumn("uuid", studentInfo.StudentID),
-	}
-
-	_, err = container.AddExecuteTransaction(transaction, UpdateStudentInfoQuery, columns)
-	if err != nil {
-		return err
-	}
-
-	uuidColumn := []mysql.Column{
-		mysql.NewUUIDColumn("uuid", studentInfo.StudentID),
-	}
+	return container.WithTx(ctx, func(tx *mysql.Tx) error {
+		columns := []mysql.Column{
+			mysql.NewTextColumn("description", studentInfo.Description),
+			mysql.NewTextColumn("profile", studentInfo.ProfilePic),
+			mysql.NewUUIDColumn("uuid", studentInfo.StudentID),
+		}
 
-	_, err = container.AddExecuteTransaction(transaction, RemoveStudentTagsLikedQuery, uuidColumn)
-	if err != nil {
-		return err
-	}
+		_, err := container.AddExecuteTransactionContext(tx.Context(), tx.Tx, UpdateStudentInfoQuery, columns)
+		if err != nil {
+			return err
+		}
 
-	_, err = container.AddExecuteTransaction(transaction, RemoveStudentTagsDisLikedQuery, uuidColumn)
-	if err != nil {
-		return err
-	}
+		uuidColumn := []mysql.Column{
+			mysql.NewUUIDColumn("uuid", studentInfo.StudentID),
+		}
 
-	if len(studentInfo.TagsLiked) > 0 {
-		err = updateStudentTagOpinion(container, transaction, studentInfo.StudentID, studentInfo.TagsLiked, InsertStudentTagsLikedQuery)
+		_, err = container.AddExecuteTransactionContext(tx.Context(), tx.Tx, RemoveStudentTagsLikedQuery, uuidColumn)
 		if err != nil {
-			log.Error(err)
 			return err
 		}
-	}
 
-	if len(studentInfo.TagsDisliked) > 0 {
-		err = updateStudentTagOpinion(container, transaction, studentInfo.StudentID, studentInfo.TagsDisliked, InsertStudentTagsDisLikedQuery)
+		_, err = container.AddExecuteTransactionContext(tx.Context(), tx.Tx, RemoveStudentTagsDisLikedQuery, uuidColumn)
 		if err != nil {
-			log.Error(err)
 			return err
 		}
-	}
 
-	err = container.CommitTransaction(transaction)
-	if err != nil {
-		return err
-	}
+		if len(studentInfo.TagsLiked) > 0 {
+			err = updateStudentTagOpinion(tx.Context(), container, tx.Tx, studentInfo.StudentID, studentInfo.TagsLiked, InsertStudentTagsLikedQuery)
+			if err != nil {
+				log.Error(err)
+				return err
+			}
+		}
 
-	return nil
+		if len(studentInfo.TagsDisliked) > 0 {
+			err = updateStudentTagOpinion(tx.Context(), container, tx.Tx, studentInfo.StudentID, studentInfo.TagsDisliked, InsertStudentTagsDisLikedQuery)
+			if err != nil {
+				log.Error(err)
+				return err
+			}
+		}
+
+		return nil
+	})
 }
 
-func updateStudentTagOpinion(container *mysql.Repository, transaction *sql.Tx, userID uuid.UUID, tags []string, query string) error {
+func updateStudentTagOpinion(ctx context.Context, container *mysql.Repository, transaction *sql.Tx, userID uuid.UUID, tags []string, query string) error {
 	if len(tags) == 0 {
 		return nil
 	}
 
-	uniqueTags := make(map[string]bool)
-	for _, tag := range tags {
-		uniqueTags[tag] = true
-	}
-
+	// Names are resolved first so a tag and its synonym are only added once
 	var allTagIDs []int64
-	var allTagNames []string
-
-	// check which tags already exist
-	for tag := range uniqueTags {
-		var tagColumns []mysql.Column
-		tagColumns = append(tagColumns, mysql.NewVarcharColumn("tagName", tag))
+	seen := make(map[int64]bool)
+	for _, name := range tags {
+		if taxonomy.Normalise(name) == "" {
+			continue
+		}
 
-		rows, err := container.ExecuteQuery("SELECT * FROM TagsTable WHERE tagName = ?", tagColumns, mysql.QueryOptions{})
+		tag, err := ResolveTag(ctx, container, transaction, name, true)
 		if err != nil {
 			return err
 		}
 
-		found := false
-		var id int64
-		var name string
-
-		for rows.Next() {
-			found = true
-			err = rows.Scan(&id, &name)
-			if err != nil {
-				rows.Close()
-				return err
-			}
-			allTagIDs = append(allTagIDs, id)
-			allTagNames = append(allTagNames, name)
-		}
-		rows.Close()
-
-		// Create tag if it don't exist
-		if !found {
-			tagColumn := []mysql.Column{
-				mysql.NewVarcharColumn("tagName", tag),
-			}
-
-			result, err := container.AddExecuteTransaction(transaction, CreateTagQuery, tagColumn)
-			if err != nil {
-				return err
-			}
-
-			newTagID, err := result.LastInsertId()
-			if err != nil {
-				return err
-			}
-
-			allTagIDs = append(allTagIDs, newTagID)
-			allTagNames = append(allTagNames, tag)
+		if !seen[tag.ID] {
+			seen[tag.ID] = true
+			allTagIDs = append(allTagIDs, tag.ID)
 		}
 	}
 
@@ -237,7 +195,7 @@ func updateStudentTagOpinion(container *mysql.Repository, transaction *sql.Tx, u
 		}
 
 		finalQuery := fmt.Sprintf(query, strings.Join(placeholders, ", "))
-		_, err := container.AddExecuteTransaction(transaction, finalQuery, tagColumns)
+		_, err := container.AddExecuteTransactionContext(ctx, transaction, finalQuery, tagColumns)
 		if err != nil {
 			return err
 		}
@@ -246,13 +204,13 @@ func updateStudentTagOpinion(container *mysql.Repository, transaction *sql.Tx, u
 	return nil
 }
 
-func (repo *StudentRepository) GetUserInfo(userID uuid.UUID) (*models.StudentInfoModel, error) {
+func (repo *StudentRepository) GetUserInfo(ctx context.Context, userID uuid.UUID) (*models.StudentInfoModel, error) {
 	container := repo.Repository
 	columns := []mysql.Column{
 		mysql.NewUUIDColumn("uuid", userID),
 	}
 
-	rows, err := container.ExecuteQuery(GetStudentInfoQuery, columns, mysql.QueryOptions{})
+	rows, err := container.ExecuteQueryContext(ctx, GetStudentInfoQuery, columns, mysql.QueryOptions{})
 	if err != nil {
 		return nil, err
 	}
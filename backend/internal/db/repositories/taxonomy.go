@@ -0,0 +1,807 @@
+package repositories
+
+import (
+	"backend/internal/db/adapters/mysql"
+	"backend/internal/models"
+	"backend/internal/taxonomy"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"strconv"
+	"strings"
+)
+
+// The tag taxonomy. TagsTable stays a plain id/name list since opportunities are scanned with SELECT *, so
+// categories, approval and synonyms live alongside it. Tags are stored normalised and looked up by name or synonym,
+// tags without a TagDetailsTable row predate moderation and count as approved
+
+var (
+	// ErrTagNotApproved is returned when a tag outside the approved vocabulary is used while it's locked
+	ErrTagNotApproved = errors.New("tag isn't in the approved vocabulary")
+	// ErrTagExists is returned when a name is already used by another tag or synonym
+	ErrTagExists        = errors.New("a tag with that name already exists")
+	ErrTagNotFound      = errors.New("tag not found")
+	ErrCategoryExists   = errors.New("a category with that name already exists")
+	ErrCategoryNotFound = errors.New("category not found")
+)
+
+const CreateTagCategoriesTableQuery = `
+CREATE TABLE IF NOT EXISTS TagCategoriesTable(
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    name VARCHAR(50) UNIQUE NOT NULL,
+    parentID INT NULL,
+    FOREIGN KEY (parentID) REFERENCES TagCategoriesTable(id) ON DELETE SET NULL
+);`
+
+const CreateTagDetailsTableQuery = `
+CREATE TABLE IF NOT EXISTS TagDetailsTable(
+    tagID INT NOT NULL PRIMARY KEY,
+    categoryID INT NULL,
+    approved BOOLEAN NOT NULL DEFAULT FALSE,
+    FOREIGN KEY (tagID) REFERENCES TagsTable(id) ON DELETE CASCADE,
+    FOREIGN KEY (categoryID) REFERENCES TagCategoriesTable(id) ON DELETE SET NULL
+);`
+
+const CreateTagSynonymsTableQuery = `
+CREATE TABLE IF NOT EXISTS TagSynonymsTable(
+    synonym VARCHAR(50) NOT NULL PRIMARY KEY,
+    tagID INT NOT NULL,
+    FOREIGN KEY (tagID) REFERENCES TagsTable(id) ON DELETE CASCADE
+);`
+
+// A single row, id 1
+const CreateTaxonomySettingsTableQuery = `
+CREATE TABLE IF NOT EXISTS TaxonomySettingsTable(
+    id INT NOT NULL PRIMARY KEY,
+    vocabularyLocked BOOLEAN NOT NULL DEFAULT FALSE
+);`
+
+const CreateTagSynonymsTagIndex = "CREATE INDEX idx_tag_synonyms_tag ON TagSynonymsTable(tagID);"
+
+// An exact name match wins over a synonym
+const ResolveTagQuery = `
+SELECT t.id, t.tagName, COALESCE(d.approved, TRUE), 0 AS priority
+FROM TagsTable t
+LEFT JOIN TagDetailsTable d ON d.tagID = t.id
+WHERE t.tagName = ?
+UNION ALL
+SELECT t.id, t.tagName, COALESCE(d.approved, TRUE), 1
+FROM TagSynonymsTable s
+INNER JOIN TagsTable t ON t.id = s.tagID
+LEFT JOIN TagDetailsTable d ON d.tagID = t.id
+WHERE s.synonym = ?
+ORDER BY priority
+LIMIT 1
+`
+
+// Concurrent requests creating the same tag both get its id
+const InsertTagQuery = "INSERT INTO TagsTable (tagName) VALUES (?) ON DUPLICATE KEY UPDATE id = LAST_INSERT_ID(id)"
+
+const InsertUnapprovedTagDetailsQuery = "INSERT IGNORE INTO TagDetailsTable(tagID, approved) VALUES (?, FALSE)"
+
+const UpsertTagDetailsQuery = `
+INSERT INTO TagDetailsTable(tagID, categoryID, approved) VALUES (?, ?, ?)
+ON DUPLICATE KEY UPDATE categoryID = VALUES(categoryID), approved = VALUES(approved)
+`
+
+const GetApprovedTagNamesQuery = `
+SELECT t.tagName FROM TagsTable t
+LEFT JOIN TagDetailsTable d ON d.tagID = t.id
+WHERE COALESCE(d.approved, TRUE)
+`
+
+// Usage counts every reference a merge would rewrite
+const GetTaxonomyTagsQuery = `
+SELECT t.id, t.tagName, d.categoryID, COALESCE(d.approved, TRUE),
+       (SELECT COUNT(*) FROM OpportunityTagsTable ot WHERE ot.tagID = t.id) +
+       (SELECT COUNT(*) FROM UserTagsLiked ul WHERE ul.tagID = t.id) +
+       (SELECT COUNT(*) FROM UserTagsDisLiked ud WHERE ud.tagID = t.id),
+       (SELECT GROUP_CONCAT(s.synonym ORDER BY s.synonym SEPARATOR '\n') FROM TagSynonymsTable s WHERE s.tagID = t.id)
+FROM TagsTable t
+LEFT JOIN TagDetailsTable d ON d.tagID = t.id
+WHERE %s
+ORDER BY t.tagName
+`
+
+const LockTagQuery = "SELECT tagName FROM TagsTable WHERE id = ? FOR UPDATE"
+
+const RenameTagQuery = "UPDATE TagsTable SET tagName = ? WHERE id = ?"
+
+const DeleteTagByIDQuery = "DELETE FROM TagsTable WHERE id = ?"
+
+const InsertTagSynonymQuery = `
+INSERT INTO TagSynonymsTable(synonym, tagID) VALUES (?, ?)
+ON DUPLICATE KEY UPDATE tagID = VALUES(tagID)
+`
+
+const DeleteTagSynonymQuery = "DELETE FROM TagSynonymsTable WHERE synonym = ?"
+
+// Merging rewrites every reference to the source onto the target. Rows that would duplicate one the target already
+// has are skipped, and a student's opinion of the target wins over their opinion of the source
+const (
+	MergeOpportunityTagsQuery = `
+INSERT IGNORE INTO OpportunityTagsTable(opportunityUUID, tagID)
+SELECT opportunityUUID, ? FROM OpportunityTagsTable WHERE tagID = ?
+`
+	MergeTagsLikedQuery = `
+INSERT IGNORE INTO UserTagsLiked(uuid, tagID)
+SELECT uuid, ? FROM UserTagsLiked
+WHERE tagID = ? AND uuid NOT IN (SELECT uuid FROM UserTagsDisLiked WHERE tagID = ?)
+`
+	MergeTagsDislikedQuery = `
+INSERT IGNORE INTO UserTagsDisLiked(uuid, tagID)
+SELECT uuid, ? FROM UserTagsDisLiked
+WHERE tagID = ? AND uuid NOT IN (SELECT uuid FROM UserTagsLiked WHERE tagID = ?)
+`
+	MergeTagSynonymsQuery = "UPDATE TagSynonymsTable SET tagID = ? WHERE tagID = ?"
+)
+
+const GetTagCategoriesQuery = "SELECT id, name, parentID FROM TagCategoriesTable ORDER BY name"
+
+const InsertTagCategoryQuery = "INSERT INTO TagCategoriesTable(name, parentID) VALUES (?, ?)"
+
+const UpdateTagCategoryQuery = "UPDATE TagCategoriesTable SET name = ?, parentID = ? WHERE id = ?"
+
+const LockTagCategoryQuery = "SELECT parentID FROM TagCategoriesTable WHERE id = ? FOR UPDATE"
+
+// A deleted category's children and tags move up to its parent
+const (
+	ReparentTagCategoriesQuery = "UPDATE TagCategoriesTable SET parentID = ? WHERE parentID = ?"
+	RecategoriseTagsQuery      = "UPDATE TagDetailsTable SET categoryID = ? WHERE categoryID = ?"
+	DeleteTagCategoryQuery     = "DELETE FROM TagCategoriesTable WHERE id = ?"
+)
+
+const GetVocabularyLockedQuery = "SELECT vocabularyLocked FROM TaxonomySettingsTable WHERE id = 1"
+
+const SetVocabularyLockedQuery = `
+INSERT INTO TaxonomySettingsTable(id, vocabularyLocked) VALUES (1, ?)
+ON DUPLICATE KEY UPDATE vocabularyLocked = VALUES(vocabularyLocked)
+`
+
+// The texts tag suggestions are learnt from. Only approved opportunities are used, so spam doesn't teach anything
+const GetTaggedOpportunityTextsQuery = `
+SELECT o.title, o.description, GROUP_CONCAT(ot.tagID)
+FROM OpportunitiesTable o
+INNER JOIN OpportunityTagsTable ot ON ot.opportunityUUID = o.uuid
+WHERE o.approved = TRUE
+GROUP BY o.uuid, o.title, o.description
+`
+
+// MySQL error messages for unique and foreign key violations
+const (
+	duplicateEntryError    = "Duplicate entry"
+	foreignKeyFailureError = "foreign key constraint fails"
+)
+
+type TaxonomyRepository struct {
+	*BaseRepository
+}
+
+// NewTaxonomyRepository initializes a new TaxonomyRepository instance
+func NewTaxonomyRepository(db *mysql.Repository) (*TaxonomyRepository, error) {
+	tr := &TaxonomyRepository{}
+	baseRepo, err := InitRepository(tr, db)
+
+	if err != nil {
+		return nil, err
+	}
+	tr.BaseRepository = baseRepo
+	return tr, nil
+}
+
+// CreateTablesQuery returns a list of SQL queries needed to create necessary tables for the taxonomy
+func (_ *TaxonomyRepository) CreateTablesQuery() *[]string {
+	return &[]string{CreateTagCategoriesTableQuery, CreateTagDetailsTableQuery, CreateTagSynonymsTableQuery,
+		CreateTaxonomySettingsTableQuery}
+}
+
+// CreateIndexesQuery returns a list of SQL queries needed to create necessary indexes for the taxonomy
+func (_ *TaxonomyRepository) CreateIndexesQuery() *[]string {
+	return &[]string{CreateTagSynonymsTagIndex}
+}
+
+// ResolveTag returns the tag a client supplied name refers to, by its normalised name or a synonym. With create a
+// tag that doesn't exist is created, unapproved, unless the vocabulary is locked in which case only approved tags
+// can be used. Without create nil is returned for unknown tags. create needs the transaction, it's optional otherwise
+func ResolveTag(ctx context.Context, container *mysql.Repository, transaction *sql.Tx, name string, create bool) (*models.TagModel, error) {
+	normalised, err := taxonomy.Validate(name)
+	if err != nil {
+		if !create {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("%w: %q", err, name)
+	}
+
+	tag, approved, err := findTag(ctx, container, transaction, normalised)
+	if err != nil || !create {
+		return tag, err
+	}
+
+	locked, err := vocabularyLocked(ctx, container, transaction)
+	if err != nil {
+		return nil, err
+	}
+
+	if tag != nil {
+		if locked && !approved {
+			return nil, notApproved(ctx, container, transaction, normalised)
+		}
+		return tag, nil
+	}
+
+	if locked {
+		return nil, notApproved(ctx, container, transaction, normalised)
+	}
+
+	result, err := container.AddExecuteTransactionContext(ctx, transaction, InsertTagQuery, []mysql.Column{
+		mysql.NewVarcharColumn("tagName", normalised),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	tagID, err := result.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+
+	// New tags wait for an admin to approve them
+	_, err = container.AddExecuteTransactionContext(ctx, transaction, InsertUnapprovedTagDetailsQuery, []mysql.Column{
+		mysql.NewIntegerColumn("tagID", tagID),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.TagModel{ID: tagID, TagName: normalised}, nil
+}
+
+// IsTagError reports whether err is about a tag name the client sent, its message is safe to return
+func IsTagError(err error) bool {
+	return errors.Is(err, ErrTagNotApproved) || errors.Is(err, taxonomy.ErrNameTooLong) || errors.Is(err, taxonomy.ErrEmptyName)
+}
+
+// findTag looks up a normalised name, returning nil if there's no tag or synonym with it
+func findTag(ctx context.Context, container *mysql.Repository, transaction *sql.Tx, name string) (*models.TagModel, bool, error) {
+	rows, err := queryInTransaction(ctx, container, transaction, ResolveTagQuery, []mysql.Column{
+		mysql.NewVarcharColumn("tagName", name),
+		mysql.NewVarcharColumn("synonym", name),
+	})
+	if err != nil {
+		log.Error(err)
+		return nil, false, err
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		return nil, false, rows.Err()
+	}
+
+	var tag models.TagModel
+	var approved bool
+	var priority int
+	if err := rows.Scan(&tag.ID, &tag.TagName, &approved, &priority); err != nil {
+		log.Error(err)
+		return nil, false, err
+	}
+	return &tag, approved, nil
+}
+
+func vocabularyLocked(ctx context.Context, container *mysql.Repository, transaction *sql.Tx) (bool, error) {
+	rows, err := queryInTransaction(ctx, container, transaction, GetVocabularyLockedQuery, nil)
+	if err != nil {
+		log.Error(err)
+		return false, err
+	}
+	defer rows.Close()
+
+	locked := false
+	if rows.Next() {
+		if err := rows.Scan(&locked); err != nil {
+			return false, err
+		}
+	}
+	return locked, rows.Err()
+}
+
+// notApproved returns ErrTagNotApproved suggesting similar approved tags, e.g. for a typo
+func notApproved(ctx context.Context, container *mysql.Repository, transaction *sql.Tx, name string) error {
+	rows, err := queryInTransaction(ctx, container, transaction, GetApprovedTagNamesQuery, nil)
+	if err != nil {
+		return fmt.Errorf("%w: %q", ErrTagNotApproved, name)
+	}
+	defer rows.Close()
+
+	var vocabulary []string
+	for rows.Next() {
+		var tagName string
+		if err := rows.Scan(&tagName); err == nil {
+			vocabulary = append(vocabulary, tagName)
+		}
+	}
+
+	suggestions := taxonomy.Suggest(name, vocabulary)
+	if len(suggestions) == 0 {
+		return fmt.Errorf("%w: %q", ErrTagNotApproved, name)
+	}
+
+	quoted := make([]string, len(suggestions))
+	for i, suggestion := range suggestions {
+		quoted[i] = strconv.Quote(suggestion)
+	}
+	return fmt.Errorf("%w: %q, did you mean %s", ErrTagNotApproved, name, strings.Join(quoted, " or "))
+}
+
+func queryInTransaction(ctx context.Context, container *mysql.Repository, transaction *sql.Tx, query string,
+	columns []mysql.Column) (*sql.Rows, error) {
+	if transaction != nil {
+		return container.AddQueryTransactionContext(ctx, transaction, query, columns)
+	}
+	return container.ExecuteQueryContext(ctx, query, columns, mysql.QueryOptions{})
+}
+
+// GetTags returns the tags with their synonyms and usage, only the approved or unapproved ones if approved is set
+func (repo *TaxonomyRepository) GetTags(ctx context.Context, approved *bool) ([]models.TaxonomyTagModel, error) {
+	condition := "TRUE"
+	var columns []mysql.Column
+	if approved != nil {
+		condition = "COALESCE(d.approved, TRUE) = ?"
+		columns = append(columns, mysql.NewBoolColumn("approved", *approved))
+	}
+	return repo.queryTags(ctx, condition, columns)
+}
+
+// GetTag returns the tag, ErrTagNotFound if it doesn't exist
+func (repo *TaxonomyRepository) GetTag(ctx context.Context, tagID int64) (*models.TaxonomyTagModel, error) {
+	tags, err := repo.queryTags(ctx, "t.id = ?", []mysql.Column{mysql.NewIntegerColumn("id", tagID)})
+	if err != nil {
+		return nil, err
+	}
+	if len(tags) == 0 {
+		return nil, ErrTagNotFound
+	}
+	return &tags[0], nil
+}
+
+func (repo *TaxonomyRepository) queryTags(ctx context.Context, condition string, columns []mysql.Column) ([]models.TaxonomyTagModel, error) {
+	rows, err := repo.Repository.ExecuteQueryContext(ctx, fmt.Sprintf(GetTaxonomyTagsQuery, condition), columns, mysql.QueryOptions{})
+	if err != nil {
+		log.Error(err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	tags := make([]models.TaxonomyTagModel, 0)
+	for rows.Next() {
+		var tag models.TaxonomyTagModel
+		var categoryID sql.NullInt64
+		var synonyms sql.NullString
+
+		if err := rows.Scan(&tag.ID, &tag.TagName, &categoryID, &tag.Approved, &tag.Usage, &synonyms); err != nil {
+			log.Error(err)
+			return nil, err
+		}
+
+		if categoryID.Valid {
+			tag.CategoryID = &categoryID.Int64
+		}
+		if synonyms.Valid && synonyms.String != "" {
+			tag.Synonyms = strings.Split(synonyms.String, "\n")
+		}
+		tags = append(tags, tag)
+	}
+	return tags, rows.Err()
+}
+
+// CreateTag adds an approved tag, ErrTagExists if the name is already a tag or synonym
+func (repo *TaxonomyRepository) CreateTag(ctx context.Context, name string, categoryID *int64) (int64, error) {
+	container := repo.Repository
+
+	var tagID int64
+	err := container.WithTx(ctx, func(tx *mysql.Tx) error {
+		existing, _, err := findTag(tx.Context(), container, tx.Tx, name)
+		if err != nil {
+			return err
+		}
+		if existing != nil {
+			return fmt.Errorf("%w: %q", ErrTagExists, existing.TagName)
+		}
+
+		result, err := container.AddExecuteTransactionContext(tx.Context(), tx.Tx, CreateTagQuery, []mysql.Column{
+			mysql.NewVarcharColumn("tagName", name),
+		})
+		if err != nil {
+			return duplicateTag(err, name)
+		}
+
+		if tagID, err = result.LastInsertId(); err != nil {
+			return err
+		}
+
+		return upsertTagDetails(tx.Context(), container, tx.Tx, tagID, categoryID, true)
+	})
+	if err != nil {
+		return 0, err
+	}
+	return tagID, nil
+}
+
+// UpdateTag renames, categorises and approves the tag. A renamed tag keeps its old name as a synonym so anything
+// still sending it gets the new tag
+func (repo *TaxonomyRepository) UpdateTag(ctx context.Context, tagID int64, name string, categoryID *int64, approved bool) error {
+	container := repo.Repository
+
+	return container.WithTx(ctx, func(tx *mysql.Tx) error {
+		currentName, err := lockTag(tx.Context(), container, tx.Tx, tagID)
+		if err != nil {
+			return err
+		}
+
+		if name != currentName {
+			existing, _, err := findTag(tx.Context(), container, tx.Tx, name)
+			if err != nil {
+				return err
+			}
+			if existing != nil && existing.ID != tagID {
+				return fmt.Errorf("%w: %q, merge the tags instead", ErrTagExists, existing.TagName)
+			}
+
+			// The new name may have been one of the tag's own synonyms
+			if _, err := container.AddExecuteTransactionContext(tx.Context(), tx.Tx, DeleteTagSynonymQuery, []mysql.Column{
+				mysql.NewVarcharColumn("synonym", name),
+			}); err != nil {
+				return err
+			}
+
+			if _, err := container.AddExecuteTransactionContext(tx.Context(), tx.Tx, RenameTagQuery, []mysql.Column{
+				mysql.NewVarcharColumn("tagName", name),
+				mysql.NewIntegerColumn("id", tagID),
+			}); err != nil {
+				return duplicateTag(err, name)
+			}
+
+			if err := addSynonym(tx.Context(), container, tx.Tx, currentName, tagID); err != nil {
+				return err
+			}
+		}
+
+		if err := upsertTagDetails(tx.Context(), container, tx.Tx, tagID, categoryID, approved); err != nil {
+			return err
+		}
+		return nil
+	})
+}
+
+// DeleteTag removes the tag from every opportunity and student, e.g. to reject an inappropriate tag
+func (repo *TaxonomyRepository) DeleteTag(ctx context.Context, tagID int64) error {
+	affected, err := repo.Repository.ExecuteInsertContext(ctx, DeleteTagByIDQuery, []mysql.Column{
+		mysql.NewIntegerColumn("id", tagID),
+	}, mysql.InsertOptions{})
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+	if affected == 0 {
+		return ErrTagNotFound
+	}
+	return nil
+}
+
+// AddSynonym makes the normalised synonym resolve to the tag, ErrTagExists if it's another tag's name or synonym
+func (repo *TaxonomyRepository) AddSynonym(ctx context.Context, tagID int64, synonym string) error {
+	container := repo.Repository
+
+	return container.WithTx(ctx, func(tx *mysql.Tx) error {
+		if _, err := lockTag(tx.Context(), container, tx.Tx, tagID); err != nil {
+			return err
+		}
+
+		existing, _, err := findTag(tx.Context(), container, tx.Tx, synonym)
+		if err != nil {
+			return err
+		}
+		if existing != nil {
+			if existing.ID == tagID {
+				return nil
+			}
+			return fmt.Errorf("%w: %q, merge the tags instead", ErrTagExists, existing.TagName)
+		}
+
+		if err := addSynonym(tx.Context(), container, tx.Tx, synonym, tagID); err != nil {
+			return err
+		}
+		return nil
+	})
+}
+
+// RemoveSynonym stops the synonym resolving to its tag
+func (repo *TaxonomyRepository) RemoveSynonym(ctx context.Context, synonym string) error {
+	affected, err := repo.Repository.ExecuteInsertContext(ctx, DeleteTagSynonymQuery, []mysql.Column{
+		mysql.NewVarcharColumn("synonym", synonym),
+	}, mysql.InsertOptions{})
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+	if affected == 0 {
+		return ErrTagNotFound
+	}
+	return nil
+}
+
+// MergeTags moves everything tagged with the source onto the target and deletes the source. The source's name and
+// synonyms become synonyms of the target, so clients still sending them get the target
+func (repo *TaxonomyRepository) MergeTags(ctx context.Context, sourceID int64, targetID int64) error {
+	container := repo.Repository
+
+	return container.WithTx(ctx, func(tx *mysql.Tx) error {
+		// Locked in id order so two merges of the same pair in opposite directions can't deadlock
+		first, second := min(sourceID, targetID), max(sourceID, targetID)
+		names := map[int64]string{}
+		for _, tagID := range []int64{first, second} {
+			name, err := lockTag(tx.Context(), container, tx.Tx, tagID)
+			if err != nil {
+				return err
+			}
+			names[tagID] = name
+		}
+
+		source, target := mysql.NewIntegerColumn("source", sourceID), mysql.NewIntegerColumn("target", targetID)
+		merges := []struct {
+			query   string
+			columns []mysql.Column
+		}{
+			{MergeOpportunityTagsQuery, []mysql.Column{target, source}},
+			{MergeTagsLikedQuery, []mysql.Column{target, source, target}},
+			{MergeTagsDislikedQuery, []mysql.Column{target, source, target}},
+			{MergeTagSynonymsQuery, []mysql.Column{target, source}},
+		}
+		for _, merge := range merges {
+			if _, err := container.AddExecuteTransactionContext(tx.Context(), tx.Tx, merge.query, merge.columns); err != nil {
+				log.Error(err)
+				return err
+			}
+		}
+
+		// Deleting the source cascades to its old references
+		if _, err := container.AddExecuteTransactionContext(tx.Context(), tx.Tx, DeleteTagByIDQuery, []mysql.Column{source}); err != nil {
+			log.Error(err)
+			return err
+		}
+
+		if err := addSynonym(tx.Context(), container, tx.Tx, names[sourceID], targetID); err != nil {
+			return err
+		}
+		return nil
+	})
+}
+
+// lockTag locks the tag's row for the rest of the transaction, returning its name
+func lockTag(ctx context.Context, container *mysql.Repository, transaction *sql.Tx, tagID int64) (string, error) {
+	rows, err := container.AddQueryTransactionContext(ctx, transaction, LockTagQuery, []mysql.Column{
+		mysql.NewIntegerColumn("id", tagID),
+	})
+	if err != nil {
+		log.Error(err)
+		return "", err
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		return "", ErrTagNotFound
+	}
+
+	var name string
+	err = rows.Scan(&name)
+	return name, err
+}
+
+func addSynonym(ctx context.Context, container *mysql.Repository, transaction *sql.Tx, synonym string, tagID int64) error {
+	_, err := container.AddExecuteTransactionContext(ctx, transaction, InsertTagSynonymQuery, []mysql.Column{
+		mysql.NewVarcharColumn("synonym", synonym),
+		mysql.NewIntegerColumn("tagID", tagID),
+	})
+	if err != nil {
+		log.Error(err)
+	}
+	return err
+}
+
+func upsertTagDetails(ctx context.Context, container *mysql.Repository, transaction *sql.Tx, tagID int64, categoryID *int64, approved bool) error {
+	_, err := container.AddExecuteTransactionContext(ctx, transaction, UpsertTagDetailsQuery, []mysql.Column{
+		mysql.NewIntegerColumn("tagID", tagID),
+		mysql.NewNullableIntegerColumn("categoryID", categoryID),
+		mysql.NewBoolColumn("approved", approved),
+	})
+	if err != nil {
+		log.Error(err)
+		if strings.Contains(err.Error(), foreignKeyFailureError) {
+			return ErrCategoryNotFound
+		}
+	}
+	return err
+}
+
+// duplicateTag turns a unique key violation into ErrTagExists
+func duplicateTag(err error, name string) error {
+	if strings.Contains(err.Error(), duplicateEntryError) {
+		return fmt.Errorf("%w: %q", ErrTagExists, name)
+	}
+	log.Error(err)
+	return err
+}
+
+// GetTaggedOpportunityTexts returns the title and description of each approved opportunity with its tag ids
+func (repo *TaxonomyRepository) GetTaggedOpportunityTexts(ctx context.Context) ([]taxonomy.TaggedText, error) {
+	rows, err := repo.Repository.ExecuteQueryContext(ctx, GetTaggedOpportunityTextsQuery, nil, mysql.QueryOptions{})
+	if err != nil {
+		log.Error(err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	texts := make([]taxonomy.TaggedText, 0)
+	for rows.Next() {
+		var title, description, tagIDs string
+		if err := rows.Scan(&title, &description, &tagIDs); err != nil {
+			log.Error(err)
+			return nil, err
+		}
+
+		text := taxonomy.TaggedText{Text: title + "\n" + description}
+		for _, tagID := range strings.Split(tagIDs, ",") {
+			if id, err := strconv.ParseInt(tagID, 10, 64); err == nil {
+				text.TagIDs = append(text.TagIDs, id)
+			}
+		}
+		texts = append(texts, text)
+	}
+	return texts, rows.Err()
+}
+
+// GetCategories returns every category as a flat list, ordered by name
+func (repo *TaxonomyRepository) GetCategories(ctx context.Context) ([]models.TagCategoryModel, error) {
+	rows, err := repo.Repository.ExecuteQueryContext(ctx, GetTagCategoriesQuery, nil, mysql.QueryOptions{})
+	if err != nil {
+		log.Error(err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	categories := make([]models.TagCategoryModel, 0)
+	for rows.Next() {
+		var category models.TagCategoryModel
+		var parentID sql.NullInt64
+		if err := rows.Scan(&category.ID, &category.Name, &parentID); err != nil {
+			log.Error(err)
+			return nil, err
+		}
+		if parentID.Valid {
+			category.ParentID = &parentID.Int64
+		}
+		categories = append(categories, category)
+	}
+	return categories, rows.Err()
+}
+
+func (repo *TaxonomyRepository) CreateCategory(ctx context.Context, name string, parentID *int64) (int64, error) {
+	var categoryID int64
+	_, err := repo.Repository.ExecuteInsertContext(ctx, InsertTagCategoryQuery, []mysql.Column{
+		mysql.NewVarcharColumn("name", name),
+		mysql.NewNullableIntegerColumn("parentID", parentID),
+	}, mysql.InsertOptions{
+		OnComplete: func(result sql.Result) {
+			categoryID, _ = result.LastInsertId()
+		},
+	})
+	if err != nil {
+		return 0, categoryError(err, name)
+	}
+	return categoryID, nil
+}
+
+// UpdateCategory renames and moves the category, the caller checks the move doesn't create a cycle
+func (repo *TaxonomyRepository) UpdateCategory(ctx context.Context, categoryID int64, name string, parentID *int64) error {
+	affected, err := repo.Repository.ExecuteInsertContext(ctx, UpdateTagCategoryQuery, []mysql.Column{
+		mysql.NewVarcharColumn("name", name),
+		mysql.NewNullableIntegerColumn("parentID", parentID),
+		mysql.NewIntegerColumn("id", categoryID),
+	}, mysql.InsertOptions{})
+	if err != nil {
+		return categoryError(err, name)
+	}
+	if affected == 0 {
+		// Also 0 when nothing changed, so check it exists
+		categories, err := repo.GetCategories(ctx)
+		if err != nil {
+			return err
+		}
+		for _, category := range categories {
+			if category.ID == categoryID {
+				return nil
+			}
+		}
+		return ErrCategoryNotFound
+	}
+	return nil
+}
+
+// DeleteCategory deletes the category, moving its subcategories and tags up to its parent
+func (repo *TaxonomyRepository) DeleteCategory(ctx context.Context, categoryID int64) error {
+	container := repo.Repository
+
+	return container.WithTx(ctx, func(tx *mysql.Tx) error {
+		rows, err := container.AddQueryTransactionContext(tx.Context(), tx.Tx, LockTagCategoryQuery, []mysql.Column{
+			mysql.NewIntegerColumn("id", categoryID),
+		})
+		if err != nil {
+			log.Error(err)
+			return err
+		}
+
+		found := rows.Next()
+		var parentID sql.NullInt64
+		if found {
+			err = rows.Scan(&parentID)
+		}
+		rows.Close()
+		if err != nil {
+			return err
+		}
+		if !found {
+			return ErrCategoryNotFound
+		}
+
+		var newParent *int64
+		if parentID.Valid {
+			newParent = &parentID.Int64
+		}
+
+		category := mysql.NewIntegerColumn("id", categoryID)
+		for _, query := range []string{ReparentTagCategoriesQuery, RecategoriseTagsQuery} {
+			if _, err := container.AddExecuteTransactionContext(tx.Context(), tx.Tx, query, []mysql.Column{
+				mysql.NewNullableIntegerColumn("parentID", newParent), category,
+			}); err != nil {
+				log.Error(err)
+				return err
+			}
+		}
+
+		if _, err := container.AddExecuteTransactionContext(tx.Context(), tx.Tx, DeleteTagCategoryQuery, []mysql.Column{category}); err != nil {
+			log.Error(err)
+			return err
+		}
+		return nil
+	})
+}
+
+func categoryError(err error, name string) error {
+	switch {
+	case strings.Contains(err.Error(), duplicateEntryError):
+		return fmt.Errorf("%w: %q", ErrCategoryExists, name)
+	case strings.Contains(err.Error(), foreignKeyFailureError):
+		return ErrCategoryNotFound
+	}
+	log.Error(err)
+	return err
+}
+
+func (repo *TaxonomyRepository) GetSettings(ctx context.Context) (*models.TaxonomySettingsModel, error) {
+	locked, err := vocabularyLocked(ctx, repo.Repository, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &models.TaxonomySettingsModel{VocabularyLocked: locked}, nil
+}
+
+func (repo *TaxonomyRepository) UpdateSettings(ctx context.Context, settings *models.TaxonomySettingsModel) error {
+	_, err := repo.Repository.ExecuteInsertContext(ctx, SetVocabularyLockedQuery, []mysql.Column{
+		mysql.NewBoolColumn("vocabularyLocked", settings.VocabularyLocked),
+	}, mysql.InsertOptions{})
+	if err != nil {
+		log.Error(err)
+	}
+	return err
+}
@@ -3,7 +3,8 @@ package repositories
 import (
 	"backend/internal/db/adapters/mysql"
 	"backend/internal/models"
-	"database/sql"
+	"backend/internal/outbox"
+	"context"
 	"fmt"
 	"github.com/google/uuid"
 	log "github.com/sirupsen/logrus"
@@ -85,8 +86,14 @@ func (_ *UserRepository) CreateIndexesQuery() *[]string {
 	return &[]string{}
 }
 
-// AddUser inserts a new user into the UserTable
-func (repo *UserRepository) AddUser(userModel *models.UserModel, options mysql.InsertOptions) error {
+// Tables returns the tables declared with mysql.Table
+func (_ *UserRepository) Tables() []*mysql.Table {
+	return []*mysql.Table{outboxTable}
+}
+
+// AddUser inserts a new user into the UserTable, recording it for the graph. With a context from WithTx the user is
+// added in that transaction, and options.OnComplete is called once it has been committed
+func (repo *UserRepository) AddUser(ctx context.Context, userModel *models.UserModel, options mysql.InsertOptions) error {
 	container := repo.Repository
 
 	columns := []mysql.Column{
@@ -98,28 +105,36 @@ func (repo *UserRepository) AddUser(userModel *models.UserModel, options mysql.I
 		mysql.NewVarcharColumn("role", userModel.Role.String()),
 	}
 
-	_, err := container.ExecuteInsert(AddUserToTableQuery, columns, options)
-	if err != nil {
-		return err
-	}
-	if userModel.Role != models.Student {
-		return nil
-	}
+	err := container.WithTx(ctx, func(tx *mysql.Tx) error {
+		result, err := container.AddExecuteTransactionContext(tx.Context(), tx.Tx, AddUserToTableQuery, columns)
+		if err != nil {
+			return err
+		}
+		if options.OnComplete != nil {
+			tx.AfterCommit(func(context.Context) {
+				options.OnComplete(result)
+			})
+		}
 
-	columns = []mysql.Column{
-		mysql.NewUUIDColumn("uuid", userModel.UUID),
-	}
-	_, err = container.ExecuteInsert(CreateStudentInfoQuery, columns, mysql.InsertOptions{})
+		if userModel.Role == models.Student {
+			_, err = container.AddExecuteTransactionContext(tx.Context(), tx.Tx, CreateStudentInfoQuery, []mysql.Column{
+				mysql.NewUUIDColumn("uuid", userModel.UUID),
+			})
+			if err != nil {
+				return err
+			}
+		}
 
-	if err != nil {
-		return err
+		return recordEvents(tx.Context(), container, tx.Tx, outbox.NewUserCreated(userModel.UUID))
+	})
+	if err != nil && options.OnError != nil {
+		options.OnError(err)
 	}
-
-	return nil
+	return err
 }
 
 // GetUserByID retrieves a user by UUID from the database
-func (repo *UserRepository) GetUserByID(userUUID ...uuid.UUID) (*[]models.RawUserRow, error) {
+func (repo *UserRepository) GetUserByID(ctx context.Context, userUUID ...uuid.UUID) (*[]models.RawUserRow, error) {
 	container := repo.Repository
 	placeholders := strings.Repeat("?,", len(userUUID))
 	placeholders = placeholders[:len(placeholders)-1]
@@ -130,53 +145,16 @@ func (repo *UserRepository) GetUserByID(userUUID ...uuid.UUID) (*[]models.RawUse
 		columns = append(columns, mysql.NewUUIDColumn("uuid", uID))
 	}
 
-	rows, err := container.ExecuteQuery(query, columns, mysql.QueryOptions{})
-	defer rows.Close()
+	rows, err := container.ExecuteQueryContext(ctx, query, columns, mysql.QueryOptions{})
 	if err != nil {
 		log.Error(err)
 		return nil, err
 	}
 
-	var userRows []models.RawUserRow
-
-	for rows.Next() {
-		var uid uuid.UUID
-		var username string
-		var email string
-		var hashedPassword string
-		var salt string
-		var roleName string
-
-		//Recruiter related things
-		var organisationName sql.NullString
-		var applicationStatus sql.NullBool
-		var points sql.NullInt64
-
-		err := rows.Scan(&uid, &username, &email, &hashedPassword, &salt, &roleName, &organisationName, &applicationStatus, &points)
-		if err != nil {
-			log.Error(err)
-			return nil, err
-		}
-
-		roleType, err := models.ParseRoleType(roleName)
-		if err != nil {
-			log.Error(err)
-			return nil, err
-		}
-
-		user := models.RawUserRow{
-			UUID:              uid,
-			Username:          username,
-			Email:             email,
-			HashedPassword:    hashedPassword,
-			Salt:              salt,
-			Role:              roleType,
-			OrganisationName:  organisationName,
-			ApplicationStatus: applicationStatus,
-			Points:            points,
-		}
-
-		userRows = append(userRows, user)
+	userRows, err := mysql.ScanAll[models.RawUserRow](rows)
+	if err != nil {
+		log.Error(err)
+		return nil, err
 	}
 
 	if len(userRows) == 0 {
@@ -187,77 +165,33 @@ func (repo *UserRepository) GetUserByID(userUUID ...uuid.UUID) (*[]models.RawUse
 }
 
 // GetUserByName retrieves a user by username from the database
-func (repo *UserRepository) GetUserByName(username string, options mysql.QueryOptions) (*models.RawUserRow, error) {
+func (repo *UserRepository) GetUserByName(ctx context.Context, username string, options mysql.QueryOptions) (*models.RawUserRow, error) {
 	container := repo.Repository
 
 	columns := []mysql.Column{
 		mysql.NewVarcharColumn("username", username),
 	}
 
-	rows, err := container.ExecuteQuery(GetUserByUsernameFromTableQuery, columns, options)
-	defer rows.Close()
+	rows, err := container.ExecuteQueryContext(ctx, GetUserByUsernameFromTableQuery, columns, options)
 	if err != nil {
 		log.Error(err)
 		return nil, err
 	}
-	return getUser(rows)
+	return mysql.ScanOne[models.RawUserRow](rows)
 }
 
 // GetUserByEmail retrieves a user by email from the database
-func (repo *UserRepository) GetUserByEmail(email string, options mysql.QueryOptions) (*models.RawUserRow, error) {
+func (repo *UserRepository) GetUserByEmail(ctx context.Context, email string, options mysql.QueryOptions) (*models.RawUserRow, error) {
 	container := repo.Repository
 
 	columns := []mysql.Column{
 		mysql.NewVarcharColumn("email", email),
 	}
 
-	rows, err := container.ExecuteQuery(GetUserByEmailFromTableQuery, columns, options)
-	defer rows.Close()
+	rows, err := container.ExecuteQueryContext(ctx, GetUserByEmailFromTableQuery, columns, options)
 	if err != nil {
 		log.Error(err)
 		return nil, err
 	}
-	return getUser(rows)
-}
-
-func getUser(rows *sql.Rows) (*models.RawUserRow, error) {
-	for rows.Next() {
-		var uid uuid.UUID
-		var username string
-		var email string
-		var hashedPassword string
-		var salt string
-		var roleName string
-
-		//Recruiter related things
-		var organisationName sql.NullString
-		var applicationStatus sql.NullBool
-		var points sql.NullInt64
-
-		err := rows.Scan(&uid, &username, &email, &hashedPassword, &salt, &roleName, &organisationName, &applicationStatus, &points)
-		if err != nil {
-			return nil, err
-		}
-
-		roleType, err := models.ParseRoleType(roleName)
-		if err != nil {
-			log.Error(err)
-			return nil, err
-		}
-
-		user := models.RawUserRow{
-			UUID:              uid,
-			Username:          username,
-			Email:             email,
-			HashedPassword:    hashedPassword,
-			Salt:              salt,
-			Role:              roleType,
-			OrganisationName:  organisationName,
-			ApplicationStatus: applicationStatus,
-			Points:            points,
-		}
-
-		return &user, nil
-	}
-	return nil, nil
+	return mysql.ScanOne[models.RawUserRow](rows)
 }
@@ -0,0 +1,27 @@
+package events
+
+import (
+	"github.com/google/uuid"
+)
+
+// The domain's events, published once the change they report has been committed
+
+// OpportunityApproved is published when an admin approves an opportunity, which makes it visible to students
+type OpportunityApproved struct {
+	OpportunityUUID uuid.UUID
+}
+
+// OpportunityLiked is published when a student likes an opportunity
+type OpportunityLiked struct {
+	UserUUID        uuid.UUID
+	OpportunityUUID uuid.UUID
+}
+
+// MatchCreated is published when two users are matched, not when a pair already matched is matched again
+type MatchCreated struct {
+	UserUUID    uuid.UUID
+	MatchedUUID uuid.UUID
+}
+
+// TagsChanged is published when tags, or the opportunities and students using them, have changed
+type TagsChanged struct{}
@@ -0,0 +1,200 @@
+// Package events lets the rest of the application react to what happens in the domain, like an opportunity being
+// approved or two users matching, without the code that made it happen knowing who's interested.
+//
+// An event is a value of its own type, and subscribers are called with the events of the type they subscribed to.
+// A synchronous subscriber runs on the publishing goroutine, an asynchronous one on the bus's thread pool. Events
+// published inside mysql.WithTx are only delivered once the transaction has been committed, and not at all if it's
+// rolled back. A subscriber's error or panic is logged and doesn't stop the event reaching the others
+package events
+
+import (
+	"backend/internal/db/adapters/mysql"
+	"backend/internal/utils/concurrency"
+	"context"
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"reflect"
+	"runtime/debug"
+	"sync"
+	"time"
+)
+
+// Defaults for a new bus
+const (
+	DefaultWorkers = 4
+	DefaultBuffer  = 100
+	DefaultTimeout = 30 * time.Second
+)
+
+// Option configures a Bus
+type Option func(bus *Bus)
+
+// WithWorkers sets how many asynchronous subscribers run at once and how many events queue for them. A full queue
+// runs the subscriber on the publishing goroutine rather than dropping the event
+func WithWorkers(workers int, buffer int) Option {
+	return func(bus *Bus) {
+		bus.workers, bus.buffer = workers, buffer
+	}
+}
+
+// WithTimeout bounds how long an asynchronous subscriber may take with an event
+func WithTimeout(timeout time.Duration) Option {
+	return func(bus *Bus) {
+		bus.timeout = timeout
+	}
+}
+
+// subscriber handles the events of one type
+type subscriber struct {
+	name   string
+	async  bool
+	handle func(ctx context.Context, event any) error
+}
+
+// Bus delivers published events to their subscribers. A nil Bus drops them
+type Bus struct {
+	workers int
+	buffer  int
+	timeout time.Duration
+	pool    *concurrency.ThreadPool
+
+	mutex       sync.RWMutex
+	subscribers map[reflect.Type][]*subscriber
+	// observers see every event delivered, see Observe
+	observers []func(event any)
+}
+
+// Default is the application's bus, set by Configure. Events published before then are dropped
+var Default *Bus
+
+// Configure sets Default to a new bus
+func Configure(options ...Option) *Bus {
+	Default = NewBus(options...)
+	return Default
+}
+
+// NewBus returns a bus ready for publishing, Shutdown stops it
+func NewBus(options ...Option) *Bus {
+	bus := &Bus{
+		workers:     DefaultWorkers,
+		buffer:      DefaultBuffer,
+		timeout:     DefaultTimeout,
+		subscribers: map[reflect.Type][]*subscriber{},
+	}
+	for _, option := range options {
+		option(bus)
+	}
+
+	bus.pool = concurrency.NewThreadPool(bus.workers, bus.buffer, concurrency.WithPolicy(concurrency.CallerRuns))
+	bus.pool.Start()
+	return bus
+}
+
+// Subscribe calls handle with each event of type T on the goroutine that published it, before Publish returns or,
+// for events published in a transaction, before WithTx returns. The name identifies the subscriber in the logs.
+// The returned function unsubscribes
+func Subscribe[T any](bus *Bus, name string, handle func(ctx context.Context, event T) error) func() {
+	return bus.subscribe(reflect.TypeFor[T](), &subscriber{name: name, handle: typed(handle)})
+}
+
+// SubscribeAsync calls handle with each event of type T on the bus's thread pool, with a context that outlives the
+// publisher's but is bounded by the bus's timeout. The returned function unsubscribes
+func SubscribeAsync[T any](bus *Bus, name string, handle func(ctx context.Context, event T) error) func() {
+	return bus.subscribe(reflect.TypeFor[T](), &subscriber{name: name, async: true, handle: typed(handle)})
+}
+
+func typed[T any](handle func(ctx context.Context, event T) error) func(ctx context.Context, event any) error {
+	return func(ctx context.Context, event any) error {
+		return handle(ctx, event.(T))
+	}
+}
+
+func (bus *Bus) subscribe(eventType reflect.Type, added *subscriber) func() {
+	bus.mutex.Lock()
+	defer bus.mutex.Unlock()
+	bus.subscribers[eventType] = append(bus.subscribers[eventType], added)
+
+	return func() {
+		bus.mutex.Lock()
+		defer bus.mutex.Unlock()
+
+		subscribers := bus.subscribers[eventType]
+		for i, subscriber := range subscribers {
+			if subscriber == added {
+				// Copied so a delivery ranging over the old slice isn't disturbed
+				bus.subscribers[eventType] = append(subscribers[:i:i], subscribers[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+// Observe calls observe with every event the bus delivers, before its subscribers. Events published in a
+// transaction are observed once it has been committed
+func (bus *Bus) Observe(observe func(event any)) {
+	bus.mutex.Lock()
+	defer bus.mutex.Unlock()
+	bus.observers = append(bus.observers, observe)
+}
+
+// Publish delivers the event to the subscribers of its type. Inside mysql.WithTx that's deferred until the
+// transaction has been committed
+func (bus *Bus) Publish(ctx context.Context, event any) {
+	if bus == nil {
+		return
+	}
+	mysql.AfterCommit(ctx, func(ctx context.Context) {
+		bus.deliver(ctx, event)
+	})
+}
+
+func (bus *Bus) deliver(ctx context.Context, event any) {
+	bus.mutex.RLock()
+	subscribers := bus.subscribers[reflect.TypeOf(event)]
+	observers := bus.observers
+	bus.mutex.RUnlock()
+
+	for _, observe := range observers {
+		observe(event)
+	}
+
+	for _, subscriber := range subscribers {
+		if !subscriber.async {
+			bus.run(ctx, subscriber, event)
+			continue
+		}
+
+		// The publisher's request may well be over before the subscriber runs
+		detached := context.WithoutCancel(ctx)
+		err := bus.pool.SubmitContext(detached, func(ctx context.Context) {
+			ctx, cancel := context.WithTimeout(ctx, bus.timeout)
+			defer cancel()
+			bus.run(ctx, subscriber, event)
+		})
+		if err != nil {
+			log.Warnf("Dropped %T for %s: %v", event, subscriber.name, err)
+		}
+	}
+}
+
+// run calls the subscriber, logging rather than passing on its error or panic so the other subscribers still run
+func (bus *Bus) run(ctx context.Context, subscriber *subscriber, event any) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			log.Errorf("%s panicked handling %T: %v\n%s", subscriber.name, event, recovered, debug.Stack())
+		}
+	}()
+
+	if err := subscriber.handle(ctx, event); err != nil {
+		log.Error(fmt.Errorf("%s failed handling %T: %w", subscriber.name, event, err))
+	}
+}
+
+// Shutdown waits for the asynchronous subscribers to finish, giving up when ctx is done. Events published after
+// are only delivered to synchronous subscribers
+func (bus *Bus) Shutdown(ctx context.Context) error {
+	if bus == nil {
+		return nil
+	}
+	return bus.pool.Shutdown(ctx)
+}
@@ -0,0 +1,106 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"github.com/google/uuid"
+	"sync"
+	"testing"
+	"time"
+)
+
+func shutdown(t *testing.T, bus *Bus) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := bus.Shutdown(ctx); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSubscribersAreIsolated(t *testing.T) {
+	bus := NewBus()
+	defer shutdown(t, bus)
+
+	var received []string
+	Subscribe(bus, "failing", func(context.Context, OpportunityLiked) error {
+		received = append(received, "failing")
+		return errors.New("failed")
+	})
+	Subscribe(bus, "panicking", func(context.Context, OpportunityLiked) error {
+		received = append(received, "panicking")
+		panic("failed")
+	})
+	Subscribe(bus, "last", func(context.Context, OpportunityLiked) error {
+		received = append(received, "last")
+		return nil
+	})
+	Subscribe(bus, "other type", func(context.Context, MatchCreated) error {
+		received = append(received, "other type")
+		return nil
+	})
+
+	bus.Publish(context.Background(), OpportunityLiked{UserUUID: uuid.New(), OpportunityUUID: uuid.New()})
+
+	if len(received) != 3 || received[0] != "failing" || received[1] != "panicking" || received[2] != "last" {
+		t.Fatalf("expected every subscriber of the type to run in order, got %q", received)
+	}
+}
+
+func TestAsyncSubscribersOutliveThePublisher(t *testing.T) {
+	bus := NewBus(WithWorkers(1, 1), WithTimeout(time.Second))
+
+	release := make(chan struct{})
+	var mutex sync.Mutex
+	var received []MatchCreated
+	SubscribeAsync(bus, "slow", func(ctx context.Context, event MatchCreated) error {
+		<-release
+		if ctx.Err() != nil {
+			t.Error("expected the context to outlive the publisher's")
+		}
+		if _, ok := ctx.Deadline(); !ok {
+			t.Error("expected the bus's timeout")
+		}
+		mutex.Lock()
+		defer mutex.Unlock()
+		received = append(received, event)
+		return nil
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	event := MatchCreated{UserUUID: uuid.New(), MatchedUUID: uuid.New()}
+	bus.Publish(ctx, event)
+	cancel()
+
+	close(release)
+	shutdown(t, bus)
+	if len(received) != 1 || received[0] != event {
+		t.Fatalf("expected the event once, got %v", received)
+	}
+}
+
+func TestUnsubscribe(t *testing.T) {
+	bus := NewBus()
+	defer shutdown(t, bus)
+
+	count := 0
+	unsubscribe := Subscribe(bus, "counter", func(context.Context, OpportunityApproved) error {
+		count++
+		return nil
+	})
+	bus.Publish(context.Background(), OpportunityApproved{})
+	unsubscribe()
+	bus.Publish(context.Background(), OpportunityApproved{})
+
+	if count != 1 {
+		t.Fatalf("expected one event before unsubscribing, got %d", count)
+	}
+}
+
+func TestNilBusDropsEvents(t *testing.T) {
+	var bus *Bus
+	bus.Publish(context.Background(), OpportunityApproved{})
+	if err := bus.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+}
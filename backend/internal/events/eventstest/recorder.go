@@ -0,0 +1,69 @@
+// Package eventstest records the events published on a bus, for tests to check what was published
+package eventstest
+
+import (
+	"backend/internal/events"
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Recorder records the events delivered by a bus, for tests to check what was published
+type Recorder struct {
+	mutex  sync.Mutex
+	events []any
+}
+
+// Record starts recording the events the bus delivers. Events published in a transaction are recorded once it has
+// been committed
+func Record(bus *events.Bus) *Recorder {
+	recorder := &Recorder{}
+	bus.Observe(func(event any) {
+		recorder.mutex.Lock()
+		defer recorder.mutex.Unlock()
+		recorder.events = append(recorder.events, event)
+	})
+	return recorder
+}
+
+// RecordDefault replaces events.Default with a new bus for the rest of the test and records its events
+func RecordDefault(t testing.TB) *Recorder {
+	t.Helper()
+
+	previous := events.Default
+	bus := events.NewBus()
+	events.Default = bus
+	t.Cleanup(func() {
+		events.Default = previous
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		bus.Shutdown(ctx)
+	})
+	return Record(bus)
+}
+
+// Events returns the events recorded so far in the order they were delivered
+func (recorder *Recorder) Events() []any {
+	recorder.mutex.Lock()
+	defer recorder.mutex.Unlock()
+	return append([]any(nil), recorder.events...)
+}
+
+// Reset forgets the events recorded so far
+func (recorder *Recorder) Reset() {
+	recorder.mutex.Lock()
+	defer recorder.mutex.Unlock()
+	recorder.events = nil
+}
+
+// Recorded returns the recorded events of type T
+func Recorded[T any](recorder *Recorder) []T {
+	var recorded []T
+	for _, event := range recorder.Events() {
+		if typed, ok := event.(T); ok {
+			recorded = append(recorded, typed)
+		}
+	}
+	return recorded
+}
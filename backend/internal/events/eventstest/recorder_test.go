@@ -0,0 +1,29 @@
+package eventstest
+
+import (
+	"backend/internal/events"
+	"context"
+	"github.com/google/uuid"
+	"testing"
+)
+
+func TestRecordDefault(t *testing.T) {
+	recorder := RecordDefault(t)
+
+	approved := events.OpportunityApproved{OpportunityUUID: uuid.New()}
+	matched := events.MatchCreated{UserUUID: uuid.New(), MatchedUUID: uuid.New()}
+	events.Default.Publish(context.Background(), approved)
+	events.Default.Publish(context.Background(), matched)
+
+	if events := recorder.Events(); len(events) != 2 || events[0] != approved || events[1] != matched {
+		t.Fatalf("expected both events in order, got %v", events)
+	}
+	if recorded := Recorded[events.MatchCreated](recorder); len(recorded) != 1 || recorded[0] != matched {
+		t.Fatalf("expected the match, got %v", recorded)
+	}
+
+	recorder.Reset()
+	if len(recorder.Events()) != 0 {
+		t.Fatal("expected no events after Reset")
+	}
+}
@@ -0,0 +1,116 @@
+package geo
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+)
+
+// earthRadiusKm is the mean radius used by MySQL's ST_Distance_Sphere, so distances match the database
+const earthRadiusKm = 6370.986
+
+// SRID is WGS 84, MySQL uses latitude-longitude axis order for it
+const SRID = 4326
+
+type Coordinates struct {
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+}
+
+// Validate checks the coordinates are on the globe
+func (c Coordinates) Validate() error {
+	if math.IsNaN(c.Latitude) || c.Latitude < -90 || c.Latitude > 90 {
+		return errors.New("latitude must be between -90 and 90")
+	}
+	if math.IsNaN(c.Longitude) || c.Longitude < -180 || c.Longitude > 180 {
+		return errors.New("longitude must be between -180 and 180")
+	}
+	return nil
+}
+
+// WKT returns the point as well known text in SRID 4326 axis order (latitude first)
+func (c Coordinates) WKT() string {
+	return fmt.Sprintf("POINT(%s %s)", formatFloat(c.Latitude), formatFloat(c.Longitude))
+}
+
+// BoundingBox is a latitude/longitude rectangle. Boxes crossing the antimeridian aren't supported
+type BoundingBox struct {
+	MinLatitude  float64 `json:"minLatitude"`
+	MinLongitude float64 `json:"minLongitude"`
+	MaxLatitude  float64 `json:"maxLatitude"`
+	MaxLongitude float64 `json:"maxLongitude"`
+}
+
+func (box BoundingBox) Validate() error {
+	for _, corner := range []Coordinates{{box.MinLatitude, box.MinLongitude}, {box.MaxLatitude, box.MaxLongitude}} {
+		if err := corner.Validate(); err != nil {
+			return err
+		}
+	}
+	if box.MinLatitude > box.MaxLatitude || box.MinLongitude > box.MaxLongitude {
+		return errors.New("minimum must not be greater than maximum")
+	}
+	return nil
+}
+
+// Contains returns whether the coordinates are inside the box, edges included
+func (box BoundingBox) Contains(c Coordinates) bool {
+	return c.Latitude >= box.MinLatitude && c.Latitude <= box.MaxLatitude &&
+		c.Longitude >= box.MinLongitude && c.Longitude <= box.MaxLongitude
+}
+
+// WKT returns the box as a closed polygon in SRID 4326 axis order
+func (box BoundingBox) WKT() string {
+	minLat, minLng := formatFloat(box.MinLatitude), formatFloat(box.MinLongitude)
+	maxLat, maxLng := formatFloat(box.MaxLatitude), formatFloat(box.MaxLongitude)
+	return fmt.Sprintf("POLYGON((%s %s, %s %s, %s %s, %s %s, %s %s))",
+		minLat, minLng, maxLat, minLng, maxLat, maxLng, minLat, maxLng, minLat, minLng)
+}
+
+// BoundingBoxAround returns a box containing every point within radiusKm of the centre, clamped to the globe.
+// It's used to narrow a radius search with the spatial index before checking the exact distance
+func BoundingBoxAround(centre Coordinates, radiusKm float64) BoundingBox {
+	latitudeDelta := degrees(radiusKm / earthRadiusKm)
+
+	// Longitude degrees shrink towards the poles, a circle containing a pole covers every longitude
+	longitudeDelta := 180.0
+	if centre.Latitude+latitudeDelta < 90 && centre.Latitude-latitudeDelta > -90 {
+		// Widest point of the circle is at the latitude where it's tangent to a meridian
+		ratio := math.Sin(radians(latitudeDelta)) / math.Cos(radians(centre.Latitude))
+		if ratio < 1 {
+			longitudeDelta = degrees(math.Asin(ratio))
+		}
+	}
+
+	return BoundingBox{
+		MinLatitude:  math.Max(-90, centre.Latitude-latitudeDelta),
+		MinLongitude: math.Max(-180, centre.Longitude-longitudeDelta),
+		MaxLatitude:  math.Min(90, centre.Latitude+latitudeDelta),
+		MaxLongitude: math.Min(180, centre.Longitude+longitudeDelta),
+	}
+}
+
+// DistanceKm returns the great circle distance between a and b
+func DistanceKm(a Coordinates, b Coordinates) float64 {
+	latitudeA, latitudeB := radians(a.Latitude), radians(b.Latitude)
+	latitudeDelta := latitudeB - latitudeA
+	longitudeDelta := radians(b.Longitude - a.Longitude)
+
+	h := math.Sin(latitudeDelta/2)*math.Sin(latitudeDelta/2) +
+		math.Cos(latitudeA)*math.Cos(latitudeB)*math.Sin(longitudeDelta/2)*math.Sin(longitudeDelta/2)
+
+	return 2 * earthRadiusKm * math.Asin(math.Min(1, math.Sqrt(h)))
+}
+
+func radians(degrees float64) float64 {
+	return degrees * math.Pi / 180
+}
+
+func degrees(radians float64) float64 {
+	return radians * 180 / math.Pi
+}
+
+func formatFloat(value float64) string {
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
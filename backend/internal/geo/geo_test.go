@@ -0,0 +1,124 @@
+package geo
+
+import (
+	"context"
+	"errors"
+	"math"
+	"strings"
+	"testing"
+)
+
+var (
+	leeds      = Coordinates{53.8008, -1.5491}
+	manchester = Coordinates{53.4808, -2.2426}
+)
+
+func TestDistanceKm(t *testing.T) {
+	distance := DistanceKm(leeds, manchester)
+	if math.Abs(distance-57.5) > 1 {
+		t.Fatalf("expected about 57.5km between Leeds and Manchester got %f", distance)
+	}
+
+	if DistanceKm(leeds, leeds) != 0 {
+		t.Fatal("expected zero distance to itself")
+	}
+}
+
+func TestBoundingBoxAroundContainsRadius(t *testing.T) {
+	for _, centre := range []Coordinates{leeds, {0, 0}, {-33.8688, 151.2093}, {89.9, 10}} {
+		radius := 25.0
+		box := BoundingBoxAround(centre, radius)
+
+		if err := box.Validate(); err != nil {
+			t.Fatalf("%v: %v", centre, err)
+		}
+
+		// Every point on the circle must be inside the box
+		for bearing := 0.0; bearing < 360; bearing += 15 {
+			point := destination(centre, bearing, radius*0.999)
+			if !box.Contains(point) {
+				t.Fatalf("%v: point %v at bearing %f is outside %v", centre, point, bearing, box)
+			}
+		}
+	}
+}
+
+// destination returns the point distanceKm from start along the bearing
+func destination(start Coordinates, bearing float64, distanceKm float64) Coordinates {
+	angular := distanceKm / earthRadiusKm
+	latitude := radians(start.Latitude)
+	theta := radians(bearing)
+
+	destinationLatitude := math.Asin(math.Sin(latitude)*math.Cos(angular) + math.Cos(latitude)*math.Sin(angular)*math.Cos(theta))
+	destinationLongitude := radians(start.Longitude) + math.Atan2(math.Sin(theta)*math.Sin(angular)*math.Cos(latitude),
+		math.Cos(angular)-math.Sin(latitude)*math.Sin(destinationLatitude))
+
+	longitude := math.Mod(degrees(destinationLongitude)+540, 360) - 180
+	return Coordinates{degrees(destinationLatitude), longitude}
+}
+
+func TestValidate(t *testing.T) {
+	if err := (Coordinates{91, 0}).Validate(); err == nil {
+		t.Fatal("expected latitude error")
+	}
+	if err := (Coordinates{0, -181}).Validate(); err == nil {
+		t.Fatal("expected longitude error")
+	}
+	if err := (BoundingBox{10, 10, 5, 20}).Validate(); err == nil {
+		t.Fatal("expected min > max error")
+	}
+}
+
+func TestWKT(t *testing.T) {
+	if wkt := leeds.WKT(); wkt != "POINT(53.8008 -1.5491)" {
+		t.Fatalf("unexpected point %s", wkt)
+	}
+
+	wkt := BoundingBox{1, 2, 3, 4}.WKT()
+	if wkt != "POLYGON((1 2, 3 2, 3 4, 1 4, 1 2))" {
+		t.Fatalf("unexpected polygon %s", wkt)
+	}
+}
+
+func TestGazetteer(t *testing.T) {
+	gazetteer := DefaultGazetteer()
+
+	cases := map[string]string{
+		"Leeds":                              "Leeds",
+		"Student Union, University of Leeds": "Leeds",
+		"newcastle university campus":        "Newcastle upon Tyne",
+		"Queens University Belfast":          "Belfast",
+		"St. Andrews":                        "St Andrews",
+		"Meet outside the library, UEA":      "Norwich",
+	}
+
+	for query, city := range cases {
+		result, err := gazetteer.Geocode(context.Background(), query)
+		if err != nil {
+			t.Fatalf("%s: %v", query, err)
+		}
+		if result.Address.City != city {
+			t.Errorf("%s: expected %s got %s", query, city, result.Address.City)
+		}
+	}
+
+	if _, err := gazetteer.Geocode(context.Background(), "Atlantis"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound got %v", err)
+	}
+}
+
+func TestLoadGazetteer(t *testing.T) {
+	gazetteer, err := LoadGazetteer(strings.NewReader("Campus|Main Campus,53.8,-1.55,Leeds,England,GB\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := gazetteer.Geocode(context.Background(), "main campus")
+	if err != nil || result.Coordinates.Latitude != 53.8 {
+		t.Fatalf("unexpected result %v %v", result, err)
+	}
+
+	if _, err := LoadGazetteer(strings.NewReader("Campus,100,0,Leeds,England,GB\n")); err == nil {
+		t.Fatal("expected invalid latitude to be rejected")
+	}
+}
@@ -0,0 +1,188 @@
+package geo
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// ErrNotFound is returned when a geocoder doesn't recognise the query
+var ErrNotFound = errors.New("location not found")
+
+type Address struct {
+	Line     string `json:"line,omitempty"`
+	City     string `json:"city,omitempty"`
+	Region   string `json:"region,omitempty"`
+	Postcode string `json:"postcode,omitempty"`
+	// Country is the ISO 3166-1 alpha-2 code
+	Country string `json:"country,omitempty"`
+}
+
+// Query is the text a geocoder searches for
+func (address Address) Query() string {
+	var parts []string
+	for _, part := range []string{address.Line, address.City, address.Region, address.Postcode, address.Country} {
+		if part = strings.TrimSpace(part); part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+type GeocodeResult struct {
+	Coordinates Coordinates
+	Address     Address
+}
+
+// Geocoder turns free text locations into coordinates
+type Geocoder interface {
+	// Geocode returns ErrNotFound if the location isn't recognised
+	Geocode(ctx context.Context, query string) (*GeocodeResult, error)
+}
+
+// Place is a gazetteer entry. Names are every way the place can be written, e.g. "University of Leeds", "Leeds Uni"
+type Place struct {
+	Names       []string
+	Coordinates Coordinates
+	Address     Address
+}
+
+// Gazetteer is an offline geocoder over a fixed list of places.
+// It matches the longest place name contained in the query, so "Student Union, University of Leeds" finds the university
+type Gazetteer struct {
+	places []Place
+	names  map[string]int // normalised name -> index into places
+	// longest is the most words in any name, bounding how many word windows are tried
+	longest int
+}
+
+func NewGazetteer(places []Place) *Gazetteer {
+	gazetteer := &Gazetteer{places: places, names: map[string]int{}}
+
+	for i, place := range places {
+		for _, name := range place.Names {
+			normalised := normalise(name)
+			if normalised == "" {
+				continue
+			}
+			gazetteer.names[normalised] = i
+			gazetteer.longest = max(gazetteer.longest, len(strings.Fields(normalised)))
+		}
+	}
+
+	return gazetteer
+}
+
+// LoadGazetteer reads places from CSV with the columns: names (separated by |), latitude, longitude, city, region, country
+func LoadGazetteer(reader io.Reader) (*Gazetteer, error) {
+	records, err := csv.NewReader(reader).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+
+	var places []Place
+	for line, record := range records {
+		if len(record) != 6 {
+			return nil, fmt.Errorf("gazetteer line %d: expected 6 columns got %d", line+1, len(record))
+		}
+
+		latitude, err := strconv.ParseFloat(strings.TrimSpace(record[1]), 64)
+		if err != nil {
+			return nil, fmt.Errorf("gazetteer line %d: invalid latitude", line+1)
+		}
+
+		longitude, err := strconv.ParseFloat(strings.TrimSpace(record[2]), 64)
+		if err != nil {
+			return nil, fmt.Errorf("gazetteer line %d: invalid longitude", line+1)
+		}
+
+		coordinates := Coordinates{Latitude: latitude, Longitude: longitude}
+		if err := coordinates.Validate(); err != nil {
+			return nil, fmt.Errorf("gazetteer line %d: %w", line+1, err)
+		}
+
+		places = append(places, Place{
+			Names:       strings.Split(record[0], "|"),
+			Coordinates: coordinates,
+			Address: Address{
+				City:    strings.TrimSpace(record[3]),
+				Region:  strings.TrimSpace(record[4]),
+				Country: strings.TrimSpace(record[5]),
+			},
+		})
+	}
+
+	return NewGazetteer(places), nil
+}
+
+func (gazetteer *Gazetteer) Geocode(_ context.Context, query string) (*GeocodeResult, error) {
+	words := strings.Fields(normalise(query))
+
+	// Longest names first so "newcastle university" beats "newcastle"
+	for size := min(gazetteer.longest, len(words)); size > 0; size-- {
+		for start := 0; start+size <= len(words); start++ {
+			if i, ok := gazetteer.names[strings.Join(words[start:start+size], " ")]; ok {
+				place := gazetteer.places[i]
+				return &GeocodeResult{Coordinates: place.Coordinates, Address: place.Address}, nil
+			}
+		}
+	}
+
+	return nil, ErrNotFound
+}
+
+// normalise lowercases and drops punctuation so "St. Andrews" and "st andrews" match
+func normalise(text string) string {
+	var builder strings.Builder
+	for _, r := range strings.ToLower(text) {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			builder.WriteRune(r)
+		case r == '.' || r == '\'':
+		default:
+			builder.WriteRune(' ')
+		}
+	}
+	return strings.Join(strings.Fields(builder.String()), " ")
+}
+
+// DefaultGazetteer covers the UK university cities, used when no gazetteer file is configured
+func DefaultGazetteer() *Gazetteer {
+	return NewGazetteer(defaultPlaces)
+}
+
+var defaultPlaces = []Place{
+	{[]string{"London", "UCL", "University College London", "Imperial College London", "King's College London"}, Coordinates{51.5074, -0.1278}, Address{City: "London", Region: "England", Country: "GB"}},
+	{[]string{"Manchester", "University of Manchester"}, Coordinates{53.4808, -2.2426}, Address{City: "Manchester", Region: "England", Country: "GB"}},
+	{[]string{"Birmingham", "University of Birmingham"}, Coordinates{52.4862, -1.8904}, Address{City: "Birmingham", Region: "England", Country: "GB"}},
+	{[]string{"Leeds", "University of Leeds"}, Coordinates{53.8008, -1.5491}, Address{City: "Leeds", Region: "England", Country: "GB"}},
+	{[]string{"Sheffield", "University of Sheffield"}, Coordinates{53.3811, -1.4701}, Address{City: "Sheffield", Region: "England", Country: "GB"}},
+	{[]string{"Liverpool", "University of Liverpool"}, Coordinates{53.4084, -2.9916}, Address{City: "Liverpool", Region: "England", Country: "GB"}},
+	{[]string{"Bristol", "University of Bristol"}, Coordinates{51.4545, -2.5879}, Address{City: "Bristol", Region: "England", Country: "GB"}},
+	{[]string{"Nottingham", "University of Nottingham"}, Coordinates{52.9548, -1.1581}, Address{City: "Nottingham", Region: "England", Country: "GB"}},
+	{[]string{"Newcastle", "Newcastle upon Tyne", "Newcastle University"}, Coordinates{54.9783, -1.6178}, Address{City: "Newcastle upon Tyne", Region: "England", Country: "GB"}},
+	{[]string{"Leicester", "University of Leicester"}, Coordinates{52.6369, -1.1398}, Address{City: "Leicester", Region: "England", Country: "GB"}},
+	{[]string{"Southampton", "University of Southampton"}, Coordinates{50.9097, -1.4044}, Address{City: "Southampton", Region: "England", Country: "GB"}},
+	{[]string{"Brighton", "University of Sussex", "University of Brighton"}, Coordinates{50.8225, -0.1372}, Address{City: "Brighton", Region: "England", Country: "GB"}},
+	{[]string{"Oxford", "University of Oxford"}, Coordinates{51.7520, -1.2577}, Address{City: "Oxford", Region: "England", Country: "GB"}},
+	{[]string{"Cambridge", "University of Cambridge"}, Coordinates{52.2053, 0.1218}, Address{City: "Cambridge", Region: "England", Country: "GB"}},
+	{[]string{"York", "University of York"}, Coordinates{53.9600, -1.0873}, Address{City: "York", Region: "England", Country: "GB"}},
+	{[]string{"Durham", "Durham University"}, Coordinates{54.7753, -1.5849}, Address{City: "Durham", Region: "England", Country: "GB"}},
+	{[]string{"Exeter", "University of Exeter"}, Coordinates{50.7184, -3.5339}, Address{City: "Exeter", Region: "England", Country: "GB"}},
+	{[]string{"Lancaster", "Lancaster University"}, Coordinates{54.0466, -2.8007}, Address{City: "Lancaster", Region: "England", Country: "GB"}},
+	{[]string{"Norwich", "University of East Anglia", "UEA"}, Coordinates{52.6309, 1.2974}, Address{City: "Norwich", Region: "England", Country: "GB"}},
+	{[]string{"Coventry", "University of Warwick", "Warwick University"}, Coordinates{52.4068, -1.5197}, Address{City: "Coventry", Region: "England", Country: "GB"}},
+	{[]string{"Bath", "University of Bath"}, Coordinates{51.3811, -2.3590}, Address{City: "Bath", Region: "England", Country: "GB"}},
+	{[]string{"Edinburgh", "University of Edinburgh"}, Coordinates{55.9533, -3.1883}, Address{City: "Edinburgh", Region: "Scotland", Country: "GB"}},
+	{[]string{"Glasgow", "University of Glasgow"}, Coordinates{55.8642, -4.2518}, Address{City: "Glasgow", Region: "Scotland", Country: "GB"}},
+	{[]string{"Aberdeen", "University of Aberdeen"}, Coordinates{57.1497, -2.0943}, Address{City: "Aberdeen", Region: "Scotland", Country: "GB"}},
+	{[]string{"St Andrews", "University of St Andrews"}, Coordinates{56.3398, -2.7967}, Address{City: "St Andrews", Region: "Scotland", Country: "GB"}},
+	{[]string{"Cardiff", "Cardiff University"}, Coordinates{51.4816, -3.1791}, Address{City: "Cardiff", Region: "Wales", Country: "GB"}},
+	{[]string{"Swansea", "Swansea University"}, Coordinates{51.6214, -3.9436}, Address{City: "Swansea", Region: "Wales", Country: "GB"}},
+	{[]string{"Belfast", "Queen's University Belfast"}, Coordinates{54.5973, -5.9301}, Address{City: "Belfast", Region: "Northern Ireland", Country: "GB"}},
+}
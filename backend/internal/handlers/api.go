@@ -4,13 +4,20 @@ import (
 	"backend/internal/db/adapters/mysql"
 	"backend/internal/db/adapters/neo4j"
 	"backend/routes/pathapi/v1/match"
+	"backend/routes/pathapi/v1/media"
 
 	"backend/routes/pathapi"
 	"backend/routes/pathapi/v1/auth"
+	"backend/routes/pathapi/v1/calendar"
+	"backend/routes/pathapi/v1/jobs"
+	"backend/routes/pathapi/v1/notifications"
 	"backend/routes/pathapi/v1/opportunities"
+	"backend/routes/pathapi/v1/organisations"
 	"backend/routes/pathapi/v1/root"
 	"backend/routes/pathapi/v1/student"
+	"backend/routes/pathapi/v1/tags"
 	"backend/routes/pathapi/v1/user"
+	"backend/routes/pathapi/wellknown"
 	"github.com/go-chi/chi"
 	chimiddle "github.com/go-chi/chi/middleware"
 	log "github.com/sirupsen/logrus"
@@ -23,15 +30,23 @@ var routeRegistry = map[string]map[string]func() pathapi.PathComponent{
 		"/user":          user.Route,
 		"/auth/signup":   auth.SignupRoute,
 		"/auth/login":    auth.LoginRoute,
+		"/auth/sso":      auth.SSORoute,
 		"/opportunities": opportunities.OpportunityRoute,
 		"/student":       student.Route,
 		"/match":         match.Route,
+		"/organisations": organisations.Route,
+		"/notifications": notifications.Route,
+		"/calendar":      calendar.Route,
+		"/media":         media.Route,
+		"/tags":          tags.Route,
+		"/jobs":          jobs.Route,
 	},
 }
 
 // Handler sets up the routing for the application using the Chi router
 func Handler(r *chi.Mux, sqlRepository *mysql.Repository, neoRepo *neo4j.Repository) {
 	r.Use(chimiddle.StripSlashes)
+	r.Mount("/.well-known", wellknown.Route().SetupComponents(sqlRepository, neoRepo))
 	for version, routes := range routeRegistry {
 		r.Route("/api/"+version, func(v chi.Router) {
 			for path, routeFunc := range routes {
@@ -0,0 +1,113 @@
+package ical
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+	"unicode/utf8"
+)
+
+// maxLineOctets is the longest a content line can be before it's folded, excluding the CRLF
+const maxLineOctets = 75
+
+// contentWriter writes folded CRLF content lines, keeping the first error
+type contentWriter struct {
+	writer  io.Writer
+	written int64
+	err     error
+}
+
+func (lines *contentWriter) property(name string, value string) {
+	lines.line(name + ":" + value)
+}
+
+// dateTime writes a DATE-TIME in UTC, or as local time with a TZID for any other location
+func (lines *contentWriter) dateTime(name string, value time.Time) {
+	if isUTC(value.Location()) {
+		lines.property(name, formatUTC(value))
+		return
+	}
+	lines.property(name+";TZID="+value.Location().String(), value.Format("20060102T150405"))
+}
+
+// line folds the line into chunks of at most 75 octets without splitting a UTF-8 character,
+// each continuation starts with a space. RFC 5545 section 3.1
+func (lines *contentWriter) line(line string) {
+	limit := maxLineOctets
+	for len(line) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(line[cut]) {
+			cut--
+		}
+		lines.write(line[:cut] + "\r\n ")
+		line = line[cut:]
+		// The leading space counts towards the next line's length
+		limit = maxLineOctets - 1
+	}
+	lines.write(line + "\r\n")
+}
+
+func (lines *contentWriter) write(text string) {
+	if lines.err != nil {
+		return
+	}
+	n, err := io.WriteString(lines.writer, text)
+	lines.written += int64(n)
+	lines.err = err
+}
+
+func formatUTC(value time.Time) string {
+	return value.UTC().Format("20060102T150405Z")
+}
+
+func formatInt(value int64) string {
+	return strconv.FormatInt(value, 10)
+}
+
+func formatFloat(value float64) string {
+	return strconv.FormatFloat(value, 'f', 6, 64)
+}
+
+// formatDuration formats a positive duration as an RFC 5545 DURATION, e.g. PT1H30M
+func formatDuration(duration time.Duration) string {
+	seconds := int64(duration / time.Second)
+	days, seconds := seconds/86400, seconds%86400
+	hours, seconds := seconds/3600, seconds%3600
+	minutes, seconds := seconds/60, seconds%60
+
+	value := "P"
+	if days > 0 {
+		value += formatInt(days) + "D"
+	}
+	if hours > 0 || minutes > 0 || seconds > 0 {
+		value += "T"
+		if hours > 0 {
+			value += formatInt(hours) + "H"
+		}
+		if minutes > 0 {
+			value += formatInt(minutes) + "M"
+		}
+		if seconds > 0 {
+			value += formatInt(seconds) + "S"
+		}
+	}
+	if value == "P" {
+		value = "PT0S"
+	}
+	return value
+}
+
+// formatOffset formats a UTC offset in seconds as +hhmm, with seconds only if needed
+func formatOffset(offset int) string {
+	sign := "+"
+	if offset < 0 {
+		sign, offset = "-", -offset
+	}
+
+	hours, minutes, seconds := offset/3600, offset%3600/60, offset%60
+	if seconds != 0 {
+		return fmt.Sprintf("%s%02d%02d%02d", sign, hours, minutes, seconds)
+	}
+	return fmt.Sprintf("%s%02d%02d", sign, hours, minutes)
+}
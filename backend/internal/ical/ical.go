@@ -0,0 +1,209 @@
+// Package ical writes RFC 5545 iCalendar files
+package ical
+
+import (
+	"bytes"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	StatusConfirmed = "CONFIRMED"
+	StatusCancelled = "CANCELLED"
+)
+
+// ContentType is the media type calendars are served as
+const ContentType = "text/calendar; charset=utf-8"
+
+// Calendar is a VCALENDAR of events
+type Calendar struct {
+	// ProductID identifies the product that created the calendar, e.g. -//GreenUni//Opportunities//EN
+	ProductID string
+	// Name is shown by calendar apps for subscribed feeds
+	Name string
+	// RefreshInterval is how often subscribers should refetch, zero to leave it to the client
+	RefreshInterval time.Duration
+	// Stamp is the DTSTAMP of every event, when the calendar was generated. Defaults to now
+	Stamp  time.Time
+	Events []Event
+}
+
+// Event is a VEVENT. UID and Sequence let clients match an update or cancellation to the event they already have
+type Event struct {
+	UID string
+	// Sequence must increase whenever the start, end or recurrence changes, and when the event is cancelled
+	Sequence int64
+	Status   string
+
+	Summary     string
+	Description string
+	Location    string
+	// Geo is the latitude and longitude, nil if unknown
+	Geo *[2]float64
+
+	// Start and End are written in their location, with a VTIMEZONE for it. End is optional
+	Start time.Time
+	End   time.Time
+	// RRule is the recurrence rule value without the RRULE: prefix, UNTIL must be UTC
+	RRule string
+
+	Created      time.Time
+	LastModified time.Time
+}
+
+// Bytes returns the calendar as an iCalendar file
+func (calendar *Calendar) Bytes() []byte {
+	var buffer bytes.Buffer
+	_, _ = calendar.WriteTo(&buffer)
+	return buffer.Bytes()
+}
+
+// WriteTo writes the calendar as an iCalendar file
+func (calendar *Calendar) WriteTo(writer io.Writer) (int64, error) {
+	lines := &contentWriter{writer: writer}
+
+	stamp := calendar.Stamp
+	if stamp.IsZero() {
+		stamp = time.Now()
+	}
+
+	lines.property("BEGIN", "VCALENDAR")
+	lines.property("VERSION", "2.0")
+	lines.property("PRODID", calendar.ProductID)
+	lines.property("CALSCALE", "GREGORIAN")
+	lines.property("METHOD", "PUBLISH")
+	if calendar.Name != "" {
+		lines.property("X-WR-CALNAME", escapeText(calendar.Name))
+	}
+	if calendar.RefreshInterval > 0 {
+		lines.property("REFRESH-INTERVAL;VALUE=DURATION", formatDuration(calendar.RefreshInterval))
+		lines.property("X-PUBLISHED-TTL", formatDuration(calendar.RefreshInterval))
+	}
+
+	for _, timezone := range calendar.timezones(stamp) {
+		timezone.write(lines)
+	}
+
+	for _, event := range calendar.Events {
+		event.write(lines, stamp)
+	}
+
+	lines.property("END", "VCALENDAR")
+	return lines.written, lines.err
+}
+
+func (event *Event) write(lines *contentWriter, stamp time.Time) {
+	lines.property("BEGIN", "VEVENT")
+	lines.property("UID", event.UID)
+	lines.property("DTSTAMP", formatUTC(stamp))
+	lines.property("SEQUENCE", formatInt(event.Sequence))
+
+	lines.dateTime("DTSTART", event.Start)
+	if !event.End.IsZero() {
+		lines.dateTime("DTEND", event.End)
+	}
+	if event.RRule != "" {
+		lines.property("RRULE", event.RRule)
+	}
+
+	lines.property("SUMMARY", escapeText(event.Summary))
+	if event.Description != "" {
+		lines.property("DESCRIPTION", escapeText(event.Description))
+	}
+	if event.Location != "" {
+		lines.property("LOCATION", escapeText(event.Location))
+	}
+	if event.Geo != nil {
+		lines.property("GEO", formatFloat(event.Geo[0])+";"+formatFloat(event.Geo[1]))
+	}
+
+	status := event.Status
+	if status == "" {
+		status = StatusConfirmed
+	}
+	lines.property("STATUS", status)
+	if status == StatusCancelled {
+		// Shown as free so a cancelled event doesn't block the time in the subscriber's calendar
+		lines.property("TRANSP", "TRANSPARENT")
+	}
+
+	if !event.Created.IsZero() {
+		lines.property("CREATED", formatUTC(event.Created))
+	}
+	if !event.LastModified.IsZero() {
+		lines.property("LAST-MODIFIED", formatUTC(event.LastModified))
+	}
+
+	lines.property("END", "VEVENT")
+}
+
+// timezones returns a VTIMEZONE for every location events are written in, covering the years they span
+func (calendar *Calendar) timezones(stamp time.Time) []vtimezone {
+	type span struct {
+		location *time.Location
+		from, to int
+	}
+	spans := map[string]*span{}
+
+	for _, event := range calendar.Events {
+		location := event.Start.Location()
+		if isUTC(location) {
+			continue
+		}
+
+		from, to := event.Start.Year(), max(event.Start.Year(), event.End.Year())
+		// Recurring events carry on past their first occurrence, cover until a year after the calendar was made
+		if event.RRule != "" {
+			to = max(to, stamp.Year()+1)
+		}
+
+		if existing, ok := spans[location.String()]; ok {
+			existing.from, existing.to = min(existing.from, from), max(existing.to, to)
+		} else {
+			spans[location.String()] = &span{location, from, to}
+		}
+	}
+
+	var names []string
+	for name := range spans {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var timezones []vtimezone
+	for _, name := range names {
+		span := spans[name]
+		timezones = append(timezones, newVTimezone(span.location, span.from, span.to))
+	}
+	return timezones
+}
+
+func isUTC(location *time.Location) bool {
+	return location == time.UTC || location.String() == "UTC"
+}
+
+// escapeText escapes a TEXT value, RFC 5545 section 3.3.11
+func escapeText(value string) string {
+	value = strings.ReplaceAll(value, "\r\n", "\n")
+
+	var builder strings.Builder
+	for _, r := range value {
+		switch {
+		case r == '\\':
+			builder.WriteString(`\\`)
+		case r == ';':
+			builder.WriteString(`\;`)
+		case r == ',':
+			builder.WriteString(`\,`)
+		case r == '\n' || r == '\r':
+			builder.WriteString(`\n`)
+		case r < 0x20 && r != '\t', r == 0x7f:
+			// Other control characters aren't allowed in content lines
+		default:
+			builder.WriteRune(r)
+		}
+	}
+	return builder.String()
+}
@@ -0,0 +1,111 @@
+package ical
+
+import (
+	"strings"
+	"testing"
+	"time"
+	_ "time/tzdata"
+	"unicode/utf8"
+)
+
+var stamp = time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+
+func unfold(text string) string {
+	return strings.ReplaceAll(text, "\r\n ", "")
+}
+
+func TestFoldsLongLinesWithoutSplittingCharacters(t *testing.T) {
+	description := strings.Repeat("Litter pick on the beach 🌊🌍 ", 12)
+	calendar := &Calendar{ProductID: "-//Test//EN", Stamp: stamp, Events: []Event{{
+		UID: "a@test", Summary: "Beach clean", Description: description, Start: stamp,
+	}}}
+
+	output := string(calendar.Bytes())
+
+	if !strings.HasSuffix(output, "END:VCALENDAR\r\n") {
+		t.Fatal("expected CRLF line endings")
+	}
+
+	for _, line := range strings.Split(strings.TrimSuffix(output, "\r\n"), "\r\n") {
+		if len(line) > maxLineOctets {
+			t.Fatalf("line is %d octets: %q", len(line), line)
+		}
+		if !utf8.ValidString(line) {
+			t.Fatalf("line splits a character: %q", line)
+		}
+	}
+
+	if !strings.Contains(unfold(output), "DESCRIPTION:"+escapeText(description)+"\r\n") {
+		t.Fatal("expected the description to unfold back to the original")
+	}
+}
+
+func TestEscapeText(t *testing.T) {
+	escaped := escapeText("Bring gloves, bags; and water\\snacks\r\nMeet at 10")
+	if escaped != `Bring gloves\, bags\; and water\\snacks\nMeet at 10` {
+		t.Fatalf("unexpected escaping %s", escaped)
+	}
+}
+
+func TestZonedEventHasTimezoneDefinition(t *testing.T) {
+	london, err := time.LoadLocation("Europe/London")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Date(2025, 7, 5, 10, 0, 0, 0, london)
+	calendar := &Calendar{ProductID: "-//Test//EN", Stamp: stamp, Events: []Event{{
+		UID: "weekly@test", Sequence: 2, Summary: "Litter pick", Start: start, End: start.Add(2 * time.Hour),
+		RRule: "FREQ=WEEKLY;BYDAY=SA",
+	}}}
+
+	output := unfold(string(calendar.Bytes()))
+
+	for _, expected := range []string{
+		"DTSTART;TZID=Europe/London:20250705T100000\r\n",
+		"DTEND;TZID=Europe/London:20250705T120000\r\n",
+		"RRULE:FREQ=WEEKLY;BYDAY=SA\r\n",
+		"SEQUENCE:2\r\n",
+		"DTSTAMP:20250601T120000Z\r\n",
+		"BEGIN:VTIMEZONE\r\nTZID:Europe/London\r\n",
+		// Clocks go forward at 01:00 GMT and back at 02:00 BST
+		"BEGIN:DAYLIGHT\r\nDTSTART:20250330T010000\r\nTZOFFSETFROM:+0000\r\nTZOFFSETTO:+0100\r\nTZNAME:BST\r\n",
+		"BEGIN:STANDARD\r\nDTSTART:20251026T020000\r\nTZOFFSETFROM:+0100\r\nTZOFFSETTO:+0000\r\nTZNAME:GMT\r\n",
+	} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("expected output to contain %q", expected)
+		}
+	}
+
+	// The VTIMEZONE must come before the event that uses it
+	if strings.Index(output, "BEGIN:VTIMEZONE") > strings.Index(output, "BEGIN:VEVENT") {
+		t.Fatal("expected the timezone before the event")
+	}
+}
+
+func TestUTCEventAndCancellation(t *testing.T) {
+	calendar := &Calendar{ProductID: "-//Test//EN", Name: "My opportunities", RefreshInterval: time.Hour, Stamp: stamp,
+		Events: []Event{{UID: "gone@test", Sequence: 3, Status: StatusCancelled, Summary: "Tree planting", Start: stamp}}}
+
+	output := string(calendar.Bytes())
+
+	for _, expected := range []string{"DTSTART:20250601T120000Z\r\n", "STATUS:CANCELLED\r\n", "TRANSP:TRANSPARENT\r\n",
+		"REFRESH-INTERVAL;VALUE=DURATION:PT1H\r\n", "X-WR-CALNAME:My opportunities\r\n"} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("expected output to contain %q", expected)
+		}
+	}
+
+	if strings.Contains(output, "VTIMEZONE") {
+		t.Fatal("UTC events don't need a timezone definition")
+	}
+}
+
+func TestFormatOffsetAndDuration(t *testing.T) {
+	if offset := formatOffset(-(4*3600 + 30*60)); offset != "-0430" {
+		t.Fatalf("unexpected offset %s", offset)
+	}
+	if duration := formatDuration(26*time.Hour + 30*time.Minute); duration != "P1DT2H30M" {
+		t.Fatalf("unexpected duration %s", duration)
+	}
+}
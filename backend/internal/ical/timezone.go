@@ -0,0 +1,78 @@
+package ical
+
+import "time"
+
+// vtimezone is a VTIMEZONE built from Go's zone data. Rather than deriving yearly rules, every transition in the
+// years the events span is listed, which RFC 5545 allows and keeps the definition exact for past rule changes
+type vtimezone struct {
+	id          string
+	observances []observance
+}
+
+// observance is a STANDARD or DAYLIGHT sub-component, the offset in effect from start
+type observance struct {
+	daylight bool
+	// start is the local time of the transition in the offset being transitioned from
+	start      time.Time
+	offsetFrom int
+	offsetTo   int
+	name       string
+}
+
+func newVTimezone(location *time.Location, fromYear int, toYear int) vtimezone {
+	timezone := vtimezone{id: location.String()}
+
+	current := time.Date(fromYear, time.January, 1, 0, 0, 0, 0, location)
+	limit := time.Date(toYear+1, time.January, 1, 0, 0, 0, 0, location)
+
+	// The observance already in effect at the start of the range, so earlier times in it are defined
+	name, offset := current.Zone()
+	timezone.observances = append(timezone.observances, observance{
+		daylight: current.IsDST(), start: current, offsetFrom: offset, offsetTo: offset, name: name,
+	})
+
+	for {
+		_, end := current.ZoneBounds()
+		if end.IsZero() || !end.Before(limit) {
+			break
+		}
+
+		_, previousOffset := current.Zone()
+		next := end.In(location)
+		nextName, nextOffset := next.Zone()
+
+		timezone.observances = append(timezone.observances, observance{
+			daylight:   next.IsDST(),
+			start:      end.In(time.FixedZone("", previousOffset)),
+			offsetFrom: previousOffset,
+			offsetTo:   nextOffset,
+			name:       nextName,
+		})
+		current = next
+	}
+
+	return timezone
+}
+
+func (timezone *vtimezone) write(lines *contentWriter) {
+	lines.property("BEGIN", "VTIMEZONE")
+	lines.property("TZID", timezone.id)
+
+	for _, observance := range timezone.observances {
+		component := "STANDARD"
+		if observance.daylight {
+			component = "DAYLIGHT"
+		}
+
+		lines.property("BEGIN", component)
+		lines.property("DTSTART", observance.start.Format("20060102T150405"))
+		lines.property("TZOFFSETFROM", formatOffset(observance.offsetFrom))
+		lines.property("TZOFFSETTO", formatOffset(observance.offsetTo))
+		if observance.name != "" {
+			lines.property("TZNAME", escapeText(observance.name))
+		}
+		lines.property("END", component)
+	}
+
+	lines.property("END", "VTIMEZONE")
+}
@@ -0,0 +1,328 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+)
+
+// ErrMalformed is returned for images whose structure can't be parsed
+var ErrMalformed = errors.New("malformed image")
+
+// orientationTag is the EXIF tag holding how the camera was rotated, 1 is upright
+const orientationTag = 0x0112
+
+const (
+	markerSOI = 0xd8
+	markerSOS = 0xda
+	markerEOI = 0xd9
+	markerCOM = 0xfe
+	// APPn markers, JFIF, EXIF/XMP, ICC profile and Adobe colour transform
+	markerAPP0  = 0xe0
+	markerAPP1  = 0xe1
+	markerAPP2  = 0xe2
+	markerAPP14 = 0xee
+	markerAPP15 = 0xef
+)
+
+var pngSignature = []byte("\x89PNG\r\n\x1a\n")
+
+// pngMetadataChunks can hold EXIF, GPS, comments or when the picture was taken
+var pngMetadataChunks = map[string]bool{"eXIf": true, "tEXt": true, "iTXt": true, "zTXt": true, "tIME": true}
+
+// webpMetadataChunks hold EXIF and XMP, the VP8X flags say whether they are present
+var webpMetadataChunks = map[string]bool{"EXIF": true, "XMP ": true}
+
+const (
+	webpFlagEXIF = 0x08
+	webpFlagXMP  = 0x04
+)
+
+const (
+	gifExtension   = 0x21
+	gifImage       = 0x2c
+	gifTrailer     = 0x3b
+	gifComment     = 0xfe
+	gifApplication = 0xff
+)
+
+// gifLoopApplications are the application extensions holding the animation loop count, other applications such as
+// XMP are removed
+var gifLoopApplications = map[string]bool{"NETSCAPE2.0": true, "ANIMEXTS1.0": true}
+
+// Orientation returns the EXIF orientation (1-8) of a JPEG, 1 if it doesn't have one
+func Orientation(data []byte) int {
+	orientation := 1
+	_ = walkJPEG(data, func(marker byte, segment []byte) bool {
+		if marker != markerAPP1 || !bytes.HasPrefix(segment, []byte("Exif\x00\x00")) {
+			return true
+		}
+		if value, ok := exifOrientation(segment[6:]); ok {
+			orientation = value
+		}
+		return false
+	})
+	return orientation
+}
+
+// exifOrientation reads the orientation tag from IFD0 of the TIFF structure inside an EXIF segment
+func exifOrientation(tiff []byte) (int, bool) {
+	if len(tiff) < 8 {
+		return 0, false
+	}
+
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 0, false
+	}
+
+	offset := int(order.Uint32(tiff[4:8]))
+	if offset < 8 || offset+2 > len(tiff) {
+		return 0, false
+	}
+
+	entries := int(order.Uint16(tiff[offset:]))
+	for i := 0; i < entries; i++ {
+		entry := offset + 2 + i*12
+		if entry+12 > len(tiff) {
+			return 0, false
+		}
+		if order.Uint16(tiff[entry:]) != orientationTag {
+			continue
+		}
+
+		value := int(order.Uint16(tiff[entry+8:]))
+		if value < 1 || value > 8 {
+			return 0, false
+		}
+		return value, true
+	}
+	return 0, false
+}
+
+// Strip removes metadata such as EXIF (which includes GPS coordinates), XMP and comments from JPEG, PNG, WebP and GIF
+// files without re-encoding them. Other formats are returned as they are
+func Strip(contentType string, data []byte) ([]byte, error) {
+	switch contentType {
+	case "image/jpeg":
+		return stripJPEG(data)
+	case "image/png":
+		return stripPNG(data)
+	case "image/webp":
+		return stripWebP(data)
+	case "image/gif":
+		return stripGIF(data)
+	default:
+		return data, nil
+	}
+}
+
+func stripJPEG(data []byte) ([]byte, error) {
+	stripped := make([]byte, 0, len(data))
+	stripped = append(stripped, 0xff, markerSOI)
+
+	end := 2
+	err := walkJPEG(data, func(marker byte, segment []byte) bool {
+		start := end
+		end = start + 4 + len(segment)
+
+		// Kept segments are needed to display the image correctly
+		keep := marker < markerAPP0 || marker > markerAPP15 || marker == markerAPP0 || marker == markerAPP2 || marker == markerAPP14
+		if marker == markerCOM {
+			keep = false
+		}
+		if keep {
+			stripped = append(stripped, data[start:end]...)
+		}
+
+		if marker == markerSOS {
+			// Everything after the scan header is entropy coded data
+			stripped = append(stripped, data[end:]...)
+			return false
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return stripped, nil
+}
+
+// walkJPEG calls visit with each segment's marker and payload up to and including the start of scan, stopping early
+// if visit returns false
+func walkJPEG(data []byte, visit func(marker byte, segment []byte) bool) error {
+	if len(data) < 2 || data[0] != 0xff || data[1] != markerSOI {
+		return ErrMalformed
+	}
+
+	for offset := 2; ; {
+		if offset+4 > len(data) || data[offset] != 0xff {
+			return ErrMalformed
+		}
+
+		marker := data[offset+1]
+		if marker == markerEOI {
+			return nil
+		}
+
+		length := int(binary.BigEndian.Uint16(data[offset+2:]))
+		if length < 2 || offset+2+length > len(data) {
+			return ErrMalformed
+		}
+
+		if !visit(marker, data[offset+4:offset+2+length]) {
+			return nil
+		}
+		offset += 2 + length
+	}
+}
+
+func stripPNG(data []byte) ([]byte, error) {
+	if !bytes.HasPrefix(data, pngSignature) {
+		return nil, ErrMalformed
+	}
+
+	stripped := make([]byte, 0, len(data))
+	stripped = append(stripped, pngSignature...)
+
+	for offset := len(pngSignature); offset < len(data); {
+		if offset+8 > len(data) {
+			return nil, ErrMalformed
+		}
+
+		// Length, type, data and CRC
+		length := int(binary.BigEndian.Uint32(data[offset:]))
+		end := offset + 12 + length
+		if length < 0 || end > len(data) || end < offset {
+			return nil, ErrMalformed
+		}
+
+		if !pngMetadataChunks[string(data[offset+4:offset+8])] {
+			stripped = append(stripped, data[offset:end]...)
+		}
+		offset = end
+	}
+	return stripped, nil
+}
+
+func stripWebP(data []byte) ([]byte, error) {
+	if len(data) < 12 || string(data[:4]) != "RIFF" || string(data[8:12]) != "WEBP" {
+		return nil, ErrMalformed
+	}
+
+	stripped := make([]byte, 12, len(data))
+	copy(stripped, data[:12])
+
+	for offset := 12; offset < len(data); {
+		if offset+8 > len(data) {
+			return nil, ErrMalformed
+		}
+
+		// FourCC, little endian size and data padded to an even length
+		fourCC := string(data[offset : offset+4])
+		size := int(binary.LittleEndian.Uint32(data[offset+4:]))
+		end := offset + 8 + size + size&1
+		if end > len(data) || end < offset {
+			return nil, ErrMalformed
+		}
+
+		if !webpMetadataChunks[fourCC] {
+			start := len(stripped)
+			stripped = append(stripped, data[offset:end]...)
+			if fourCC == "VP8X" && size > 0 {
+				stripped[start+8] &^= webpFlagEXIF | webpFlagXMP
+			}
+		}
+		offset = end
+	}
+
+	binary.LittleEndian.PutUint32(stripped[4:], uint32(len(stripped)-8))
+	return stripped, nil
+}
+
+func stripGIF(data []byte) ([]byte, error) {
+	// Header, logical screen descriptor and global colour table
+	if len(data) < 13 || (string(data[:6]) != "GIF87a" && string(data[:6]) != "GIF89a") {
+		return nil, ErrMalformed
+	}
+	offset := 13 + colourTableSize(data[10])
+	if offset > len(data) {
+		return nil, ErrMalformed
+	}
+
+	stripped := make([]byte, 0, len(data))
+	stripped = append(stripped, data[:offset]...)
+
+	for offset < len(data) {
+		start := offset
+		switch data[offset] {
+		case gifTrailer:
+			return append(stripped, gifTrailer), nil
+		case gifImage:
+			// Image descriptor, local colour table and LZW minimum code size
+			if offset+11 > len(data) {
+				return nil, ErrMalformed
+			}
+			offset += 11 + colourTableSize(data[offset+9])
+		case gifExtension:
+			offset += 2
+		default:
+			return nil, ErrMalformed
+		}
+
+		end, err := skipSubBlocks(data, offset)
+		if err != nil {
+			return nil, err
+		}
+
+		if data[start] != gifExtension || !gifMetadataExtension(data[start+1:end]) {
+			stripped = append(stripped, data[start:end]...)
+		}
+		offset = end
+	}
+	return nil, ErrMalformed
+}
+
+// colourTableSize returns the length of the colour table a GIF descriptor's packed fields says follows it
+func colourTableSize(fields byte) int {
+	if fields&0x80 == 0 {
+		return 0
+	}
+	return 3 << (fields&0x07 + 1)
+}
+
+// skipSubBlocks returns the offset after the data sub-blocks starting at offset and their terminator
+func skipSubBlocks(data []byte, offset int) (int, error) {
+	for {
+		if offset >= len(data) {
+			return 0, ErrMalformed
+		}
+		size := int(data[offset])
+		offset += 1 + size
+		if size == 0 {
+			return offset, nil
+		}
+	}
+}
+
+// gifMetadataExtension reports whether the extension, starting at its label, is a comment or an application
+// extension other than the loop count
+func gifMetadataExtension(extension []byte) bool {
+	switch extension[0] {
+	case gifComment:
+		return true
+	case gifApplication:
+		// The first sub-block is the application identifier and authentication code
+		if len(extension) < 13 || extension[1] != 11 {
+			return true
+		}
+		return !gifLoopApplications[string(extension[2:13])]
+	default:
+		return false
+	}
+}
@@ -0,0 +1,161 @@
+package imaging
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/draw"
+	_ "image/gif"
+	"image/jpeg"
+	"image/png"
+)
+
+// MaxPixels is the largest image that will be decoded, a small file can otherwise decode to gigabytes
+const MaxPixels = 50_000_000
+
+// JPEGQuality is the quality opaque variants are encoded at
+const JPEGQuality = 82
+
+// ErrTooLarge is returned by Decode for images over MaxPixels
+var ErrTooLarge = errors.New("image has too many pixels")
+
+// ErrUnsupportedFormat is returned by Decode for formats that can't be decoded, e.g. WebP
+var ErrUnsupportedFormat = errors.New("unsupported image format")
+
+// Decode decodes a JPEG, PNG or GIF (first frame) image, checking its size before decoding the pixels
+func Decode(data []byte) (image.Image, error) {
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if errors.Is(err, image.ErrFormat) {
+		return nil, ErrUnsupportedFormat
+	}
+	if err != nil {
+		return nil, err
+	}
+	if config.Width <= 0 || config.Height <= 0 {
+		return nil, ErrMalformed
+	}
+	if config.Width*config.Height > MaxPixels {
+		return nil, ErrTooLarge
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	return img, err
+}
+
+// Orient rotates and flips the image so it's upright, for the given EXIF orientation
+func Orient(img image.Image, orientation int) image.Image {
+	if orientation < 2 || orientation > 8 {
+		return img
+	}
+
+	src := toNRGBA(img)
+	width, height := src.Rect.Dx(), src.Rect.Dy()
+
+	// Orientations 5-8 are rotated by 90 degrees, swapping the width and height
+	dstWidth, dstHeight := width, height
+	if orientation >= 5 {
+		dstWidth, dstHeight = height, width
+	}
+	dst := image.NewNRGBA(image.Rect(0, 0, dstWidth, dstHeight))
+
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			var dx, dy int
+			switch orientation {
+			case 2: // Mirrored
+				dx, dy = width-1-x, y
+			case 3: // Rotated 180
+				dx, dy = width-1-x, height-1-y
+			case 4: // Mirrored vertically
+				dx, dy = x, height-1-y
+			case 5: // Mirrored along the top-left diagonal
+				dx, dy = y, x
+			case 6: // Rotated 90 clockwise
+				dx, dy = height-1-y, x
+			case 7: // Mirrored along the top-right diagonal
+				dx, dy = height-1-y, width-1-x
+			case 8: // Rotated 90 anticlockwise
+				dx, dy = y, width-1-x
+			}
+
+			copy(dst.Pix[dst.PixOffset(dx, dy):dst.PixOffset(dx, dy)+4], src.Pix[src.PixOffset(x, y):src.PixOffset(x, y)+4])
+		}
+	}
+	return dst
+}
+
+// Fit scales the image down to fit within maxWidth x maxHeight keeping its aspect ratio, images that already fit
+// are returned as they are. Each pixel is the average of the pixels it covers, which is sharp enough for downscaling
+func Fit(img image.Image, maxWidth int, maxHeight int) image.Image {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width <= maxWidth && height <= maxHeight {
+		return img
+	}
+
+	dstWidth, dstHeight := maxWidth, height*maxWidth/width
+	if dstHeight > maxHeight {
+		dstWidth, dstHeight = width*maxHeight/height, maxHeight
+	}
+	dstWidth, dstHeight = max(dstWidth, 1), max(dstHeight, 1)
+
+	src := toNRGBA(img)
+	dst := image.NewNRGBA(image.Rect(0, 0, dstWidth, dstHeight))
+
+	for y := 0; y < dstHeight; y++ {
+		y0, y1 := y*height/dstHeight, max((y+1)*height/dstHeight, y*height/dstHeight+1)
+		for x := 0; x < dstWidth; x++ {
+			x0, x1 := x*width/dstWidth, max((x+1)*width/dstWidth, x*width/dstWidth+1)
+
+			// Colour is weighted by alpha so transparent pixels don't darken the edges
+			var r, g, b, a uint64
+			for sy := y0; sy < y1; sy++ {
+				row := src.Pix[src.PixOffset(x0, sy):src.PixOffset(x1, sy)]
+				for i := 0; i < len(row); i += 4 {
+					alpha := uint64(row[i+3])
+					r += uint64(row[i]) * alpha
+					g += uint64(row[i+1]) * alpha
+					b += uint64(row[i+2]) * alpha
+					a += alpha
+				}
+			}
+
+			offset := dst.PixOffset(x, y)
+			if a > 0 {
+				dst.Pix[offset] = uint8(r / a)
+				dst.Pix[offset+1] = uint8(g / a)
+				dst.Pix[offset+2] = uint8(b / a)
+				dst.Pix[offset+3] = uint8(a / uint64((x1-x0)*(y1-y0)))
+			}
+		}
+	}
+	return dst
+}
+
+// Encode encodes opaque images as JPEG and images with transparency as PNG, returning the content type. Encoded
+// images have no metadata
+func Encode(img image.Image) ([]byte, string, error) {
+	var buffer bytes.Buffer
+	if opaque, ok := img.(interface{ Opaque() bool }); ok && !opaque.Opaque() {
+		if err := png.Encode(&buffer, img); err != nil {
+			return nil, "", err
+		}
+		return buffer.Bytes(), "image/png", nil
+	}
+
+	if err := jpeg.Encode(&buffer, img, &jpeg.Options{Quality: JPEGQuality}); err != nil {
+		return nil, "", err
+	}
+	return buffer.Bytes(), "image/jpeg", nil
+}
+
+func toNRGBA(img image.Image) *image.NRGBA {
+	if nrgba, ok := img.(*image.NRGBA); ok && nrgba.Rect.Min == (image.Point{}) {
+		return nrgba
+	}
+
+	bounds := img.Bounds()
+	nrgba := image.NewNRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(nrgba, nrgba.Rect, img, bounds.Min, draw.Src)
+	return nrgba
+}
@@ -0,0 +1,230 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/color/palette"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+// exifSegment builds an APP1 segment with only an orientation tag
+func exifSegment(orientation uint16) []byte {
+	tiff := []byte("II*\x00\x08\x00\x00\x00")
+	tiff = binary.LittleEndian.AppendUint16(tiff, 1)
+	tiff = binary.LittleEndian.AppendUint16(tiff, orientationTag)
+	tiff = binary.LittleEndian.AppendUint16(tiff, 3) // SHORT
+	tiff = binary.LittleEndian.AppendUint32(tiff, 1)
+	tiff = binary.LittleEndian.AppendUint16(tiff, orientation)
+	tiff = append(tiff, 0, 0, 0, 0, 0, 0)
+
+	payload := append([]byte("Exif\x00\x00"), tiff...)
+	segment := []byte{0xff, markerAPP1}
+	segment = binary.BigEndian.AppendUint16(segment, uint16(len(payload)+2))
+	return append(segment, payload...)
+}
+
+func testJPEG(t *testing.T, width int, height int, orientation uint16) []byte {
+	var buffer bytes.Buffer
+	if err := jpeg.Encode(&buffer, image.NewGray(image.Rect(0, 0, width, height)), nil); err != nil {
+		t.Fatal(err)
+	}
+
+	encoded := buffer.Bytes()
+	withExif := append([]byte{0xff, markerSOI}, exifSegment(orientation)...)
+	return append(withExif, encoded[2:]...)
+}
+
+func TestOrientation(t *testing.T) {
+	data := testJPEG(t, 4, 2, 6)
+	if orientation := Orientation(data); orientation != 6 {
+		t.Fatalf("expected orientation 6, got %d", orientation)
+	}
+
+	if orientation := Orientation([]byte("not a jpeg")); orientation != 1 {
+		t.Fatalf("expected orientation 1 for invalid data, got %d", orientation)
+	}
+}
+
+func TestStripJPEG(t *testing.T) {
+	data := testJPEG(t, 4, 2, 6)
+
+	stripped, err := Strip("image/jpeg", data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(stripped, []byte("Exif")) {
+		t.Fatal("expected the EXIF segment to be removed")
+	}
+	if Orientation(stripped) != 1 {
+		t.Fatal("expected no orientation after stripping")
+	}
+
+	img, err := Decode(stripped)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if img.Bounds().Dx() != 4 || img.Bounds().Dy() != 2 {
+		t.Fatalf("unexpected size %v", img.Bounds())
+	}
+
+	if _, err := Strip("image/jpeg", data[:20]); err == nil {
+		t.Fatal("expected truncated data to be rejected")
+	}
+}
+
+func TestStripPNG(t *testing.T) {
+	var buffer bytes.Buffer
+	if err := png.Encode(&buffer, image.NewGray(image.Rect(0, 0, 2, 2))); err != nil {
+		t.Fatal(err)
+	}
+	encoded := buffer.Bytes()
+
+	// A tEXt chunk straight after IHDR, which is 25 bytes after the signature
+	text := []byte("tEXtLocation\x0051.5,-0.1")
+	chunk := binary.BigEndian.AppendUint32(nil, uint32(len(text)-4))
+	chunk = append(chunk, text...)
+	chunk = binary.BigEndian.AppendUint32(chunk, crc32.ChecksumIEEE(text))
+	ihdrEnd := len(pngSignature) + 25
+	data := append(append(append([]byte{}, encoded[:ihdrEnd]...), chunk...), encoded[ihdrEnd:]...)
+
+	stripped, err := Strip("image/png", data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(stripped, encoded) {
+		t.Fatal("expected only the tEXt chunk to be removed")
+	}
+}
+
+// webpChunk builds a RIFF chunk, padding odd lengths
+func webpChunk(fourCC string, payload []byte) []byte {
+	chunk := binary.LittleEndian.AppendUint32([]byte(fourCC), uint32(len(payload)))
+	chunk = append(chunk, payload...)
+	if len(payload)%2 == 1 {
+		chunk = append(chunk, 0)
+	}
+	return chunk
+}
+
+func webpFile(chunks ...[]byte) []byte {
+	body := []byte("WEBP")
+	for _, chunk := range chunks {
+		body = append(body, chunk...)
+	}
+	return append(binary.LittleEndian.AppendUint32([]byte("RIFF"), uint32(len(body))), body...)
+}
+
+func TestStripWebP(t *testing.T) {
+	bitstream := webpChunk("VP8L", []byte{0x2f, 0x01, 0x00, 0x00, 0x00})
+	data := webpFile(
+		webpChunk("VP8X", []byte{webpFlagEXIF | webpFlagXMP, 0, 0, 0, 1, 0, 0, 1, 0, 0}),
+		bitstream,
+		webpChunk("EXIF", []byte("II*\x00GPS")),
+		webpChunk("XMP ", []byte("<x:xmpmeta/>")),
+	)
+
+	stripped, err := Strip("image/webp", data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := webpFile(webpChunk("VP8X", []byte{0, 0, 0, 0, 1, 0, 0, 1, 0, 0}), bitstream)
+	if !bytes.Equal(stripped, expected) {
+		t.Fatal("expected only the EXIF and XMP chunks and their flags to be removed")
+	}
+
+	if _, err := Strip("image/webp", data[:len(data)-3]); err == nil {
+		t.Fatal("expected truncated data to be rejected")
+	}
+}
+
+func TestStripGIF(t *testing.T) {
+	frame := image.NewPaletted(image.Rect(0, 0, 2, 2), palette.Plan9)
+	var buffer bytes.Buffer
+	err := gif.EncodeAll(&buffer, &gif.GIF{Image: []*image.Paletted{frame, frame}, Delay: []int{10, 10}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	encoded := buffer.Bytes()
+
+	// A comment and an XMP application extension straight after the global colour table
+	comment := append([]byte{gifExtension, gifComment, 6}, "Secret\x00"...)
+	xmp := append([]byte{gifExtension, gifApplication, 11}, "XMP DataXMP\x04<x/>\x00"...)
+	tableEnd := 13 + colourTableSize(encoded[10])
+	data := append(append(append([]byte{}, encoded[:tableEnd]...), append(comment, xmp...)...), encoded[tableEnd:]...)
+
+	stripped, err := Strip("image/gif", data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(stripped, encoded) {
+		t.Fatal("expected only the comment and XMP extensions to be removed")
+	}
+	if !bytes.Contains(stripped, []byte("NETSCAPE2.0")) {
+		t.Fatal("expected the loop count to be kept")
+	}
+
+	if _, err := Strip("image/gif", data[:len(data)-1]); err == nil {
+		t.Fatal("expected truncated data to be rejected")
+	}
+}
+
+func TestOrient(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 3, 2))
+	img.Set(0, 0, color.NRGBA{R: 255, A: 255})
+
+	// Rotating 90 clockwise moves the top left pixel to the top right
+	rotated := Orient(img, 6)
+	if rotated.Bounds().Dx() != 2 || rotated.Bounds().Dy() != 3 {
+		t.Fatalf("unexpected size %v", rotated.Bounds())
+	}
+	if r, _, _, _ := rotated.At(1, 0).RGBA(); r != 0xffff {
+		t.Fatal("expected the top left pixel to move to the top right")
+	}
+
+	if Orient(img, 1) != image.Image(img) {
+		t.Fatal("expected upright images to be returned as they are")
+	}
+}
+
+func TestFit(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 400, 100))
+	for i := 0; i < len(img.Pix); i += 4 {
+		img.Pix[i], img.Pix[i+3] = 200, 255
+	}
+
+	fitted := Fit(img, 100, 100)
+	if fitted.Bounds().Dx() != 100 || fitted.Bounds().Dy() != 25 {
+		t.Fatalf("unexpected size %v", fitted.Bounds())
+	}
+	if r, _, _, a := fitted.At(50, 10).RGBA(); r>>8 != 200 || a>>8 != 255 {
+		t.Fatal("expected the colour to be kept")
+	}
+
+	if Fit(img, 1000, 1000) != image.Image(img) {
+		t.Fatal("expected images that fit to be returned as they are")
+	}
+}
+
+func TestEncode(t *testing.T) {
+	opaque := image.NewGray(image.Rect(0, 0, 2, 2))
+	if _, contentType, err := Encode(opaque); err != nil || contentType != "image/jpeg" {
+		t.Fatalf("expected a JPEG, got %s %v", contentType, err)
+	}
+
+	transparent := image.NewNRGBA(image.Rect(0, 0, 2, 2))
+	if _, contentType, err := Encode(transparent); err != nil || contentType != "image/png" {
+		t.Fatalf("expected a PNG, got %s %v", contentType, err)
+	}
+}
+
+func TestDecodeUnsupported(t *testing.T) {
+	if _, err := Decode([]byte("RIFF\x00\x00\x00\x00WEBPVP8 ")); err != ErrUnsupportedFormat {
+		t.Fatalf("expected ErrUnsupportedFormat, got %v", err)
+	}
+}
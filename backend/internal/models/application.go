@@ -0,0 +1,74 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/google/uuid"
+	"time"
+)
+
+// ApplicationModel is a user's place on an opportunity. Recurring opportunities are applied to as a whole series
+type ApplicationModel struct {
+	OpportunityUUID uuid.UUID         `json:"opportunityUUID"`
+	UserUUID        uuid.UUID         `json:"userUUID"`
+	Username        string            `json:"username,omitempty"`
+	Status          ApplicationStatus `json:"status"`
+	AppliedAt       time.Time         `json:"appliedAt"`
+	// WaitlistPosition is 1 for the next to be confirmed, only set for waitlisted applications
+	WaitlistPosition int64 `json:"waitlistPosition,omitempty"`
+}
+
+// ApplicationSummaryModel is how full an opportunity is along with its applications
+type ApplicationSummaryModel struct {
+	Capacity     *int64             `json:"capacity,omitempty"`
+	Confirmed    int64              `json:"confirmed"`
+	Waitlisted   int64              `json:"waitlisted"`
+	Applications []ApplicationModel `json:"applications"`
+}
+
+// ApplicationStatus is whether an applicant has a place
+type ApplicationStatus int
+
+const (
+	ApplicationConfirmed ApplicationStatus = iota
+	ApplicationWaitlisted
+	ApplicationCancelled
+)
+
+func (status *ApplicationStatus) String() string {
+	return [...]string{"confirmed", "waitlisted", "cancelled"}[*status]
+}
+
+func ParseApplicationStatus(s string) (ApplicationStatus, error) {
+	switch s {
+
+	case "confirmed":
+		return ApplicationConfirmed, nil
+	case "waitlisted":
+		return ApplicationWaitlisted, nil
+	case "cancelled":
+		return ApplicationCancelled, nil
+	default:
+		return -1, fmt.Errorf("invalid application status: %s", s)
+
+	}
+}
+
+func (status *ApplicationStatus) MarshalJSON() ([]byte, error) {
+	return json.Marshal(status.String())
+}
+
+func (status *ApplicationStatus) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+
+	parsed, err := ParseApplicationStatus(s)
+	if err != nil {
+		return err
+	}
+
+	*status = parsed
+	return nil
+}
@@ -13,3 +13,9 @@ type SignupStatus struct {
 	Message string                `json:"message"`
 	Success bool                  `json:"success"`
 }
+
+type SSORedirect struct {
+	Success          bool   `json:"success"`
+	Message          string `json:"message,omitempty"`
+	AuthorizationURL string `json:"authorizationURL,omitempty"`
+}
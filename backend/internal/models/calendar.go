@@ -0,0 +1,34 @@
+package models
+
+import (
+	"github.com/google/uuid"
+	"time"
+)
+
+// CalendarFeedModel is a user's personal subscription feed. The token is only returned when the feed is created
+type CalendarFeedModel struct {
+	URL string `json:"url"`
+	// WebcalURL opens the feed as a subscription in calendar apps
+	WebcalURL string `json:"webcalURL"`
+}
+
+// CalendarEntryModel is an opportunity in a user's feed, Cancelled if they've cancelled their application
+type CalendarEntryModel struct {
+	OpportunityUUID uuid.UUID
+	Cancelled       bool
+}
+
+// CalendarCancellationModel is what's left of a deleted opportunity so subscribers' calendars can remove it
+type CalendarCancellationModel struct {
+	OpportunityUUID uuid.UUID
+	Title           string
+	Location        string
+	StartsAt        time.Time
+	EndsAt          *time.Time
+	TimeZone        string
+	Recurrence      string
+	// Sequence is one past the opportunity's, so clients apply the cancellation over the event they have
+	Sequence    int64
+	CreatedAt   time.Time
+	CancelledAt time.Time
+}
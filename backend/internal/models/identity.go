@@ -0,0 +1,15 @@
+package models
+
+import (
+	"github.com/google/uuid"
+	"time"
+)
+
+// UserIdentityModel links an external identity provider account to a UserTable row
+type UserIdentityModel struct {
+	Provider string    `json:"provider"`
+	Subject  string    `json:"subject"`
+	UserUUID uuid.UUID `json:"userUUID"`
+	Email    string    `json:"email,omitempty"`
+	LinkedAt time.Time `json:"linkedAt"`
+}
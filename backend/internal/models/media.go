@@ -0,0 +1,47 @@
+package models
+
+import (
+	"github.com/google/uuid"
+	"time"
+)
+
+// MediaFileModel is an uploaded file. The content is stored once per SHA256, however many times it's uploaded
+type MediaFileModel struct {
+	UUID           uuid.UUID `json:"id"`
+	SHA256         string    `json:"sha256"`
+	ContentType    string    `json:"contentType"`
+	Type           MediaType `json:"type"`
+	Size           int64     `json:"size"`
+	UploadedByUUID uuid.UUID `json:"uploadedBy"`
+	CreatedAt      time.Time `json:"createdAt"`
+	// Orientation is the EXIF orientation the image was uploaded with, the stored file has its EXIF removed
+	Orientation int `json:"-"`
+	// URL is a signed download URL, it expires so shouldn't be saved
+	URL string `json:"URL,omitempty"`
+	// Variants are resized copies of images by name, e.g. "thumbnail"
+	Variants map[string]MediaVariantModel `json:"variants,omitempty"`
+}
+
+type MediaVariantStatus string
+
+const (
+	VariantPending MediaVariantStatus = "pending"
+	VariantReady   MediaVariantStatus = "ready"
+	VariantFailed  MediaVariantStatus = "failed"
+)
+
+// MediaVariantModel is a resized, re-encoded copy of an uploaded image, stored under its own SHA256
+type MediaVariantModel struct {
+	MediaUUID   uuid.UUID          `json:"-"`
+	Name        string             `json:"-"`
+	Status      MediaVariantStatus `json:"status"`
+	SHA256      string             `json:"-"`
+	ContentType string             `json:"contentType,omitempty"`
+	Width       int                `json:"width,omitempty"`
+	Height      int                `json:"height,omitempty"`
+	Size        int64              `json:"size,omitempty"`
+	Attempts    int                `json:"-"`
+	Error       string             `json:"-"`
+	// URL is a signed download URL, only set once the variant is ready
+	URL string `json:"URL,omitempty"`
+}
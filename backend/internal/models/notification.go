@@ -0,0 +1,22 @@
+package models
+
+import (
+	"github.com/google/uuid"
+	"time"
+)
+
+// NotificationNewOpportunity is sent to followers when an organisation's opportunity is approved
+const NotificationNewOpportunity = "new_opportunity"
+
+// NotificationWaitlistPromoted is sent when a place opens up for a waitlisted applicant
+const NotificationWaitlistPromoted = "waitlist_promoted"
+
+type NotificationModel struct {
+	UUID             uuid.UUID  `json:"uuid"`
+	UserUUID         uuid.UUID  `json:"userUUID"`
+	NotificationType string     `json:"type"`
+	Message          string     `json:"message"`
+	ReferenceUUID    *uuid.UUID `json:"referenceUUID,omitempty"`
+	CreatedAt        time.Time  `json:"createdAt"`
+	ReadAt           *time.Time `json:"readAt,omitempty"`
+}
@@ -1,6 +1,7 @@
 package models
 
 import (
+	"backend/internal/geo"
 	"encoding/json"
 	"fmt"
 	"github.com/google/uuid"
@@ -15,22 +16,65 @@ type OpportunityModel struct {
 	Location        string    `json:"location"`
 	OpportunityType string    `json:"opportunityType"`
 	PostedByUUID    uuid.UUID `json:"postedByUUID"`
-	CreatedAt       time.Time `json:"createdAt"`
-	UpdatedAt       time.Time `json:"updatedAt"`
-	Approved        bool      `json:"approved"`
+	// OrganisationUUID is the organisation that owns the opportunity, PostedByUUID is the member who wrote it
+	OrganisationUUID *uuid.UUID `json:"organisationUUID,omitempty"`
+	CreatedAt        time.Time  `json:"createdAt"`
+	UpdatedAt        time.Time  `json:"updatedAt"`
+	Approved         bool       `json:"approved"`
+
+	// Coordinates is nil for online opportunities and ones that couldn't be geocoded
+	Coordinates *geo.Coordinates `json:"coordinates,omitempty"`
+	Address     geo.Address      `json:"address"`
+	IsOnline    bool             `json:"isOnline"`
+	// DistanceKm is only set when searching near a location
+	DistanceKm *float64 `json:"distanceKm,omitempty"`
+
+	// StartsAt and EndsAt are the first occurrence, nil for opportunities without set times.
+	// They're stored in UTC and returned in TimeZone
+	StartsAt *time.Time `json:"startsAt,omitempty"`
+	EndsAt   *time.Time `json:"endsAt,omitempty"`
+	TimeZone string     `json:"timeZone"`
+	// Recurrence is an RRULE repeating the first occurrence, e.g. FREQ=WEEKLY;BYDAY=SA
+	Recurrence string `json:"recurrence,omitempty"`
+	// Deadline is when applications close
+	Deadline *time.Time `json:"deadline,omitempty"`
+	// Capacity is the most confirmed participants, nil if unlimited. Applicants past it are waitlisted
+	Capacity *int64 `json:"capacity,omitempty"`
+	// ExpiresAt is the earlier of the deadline and the end of the last occurrence, nil if it never expires
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"`
+	// ClosedAt is set once the opportunity has been closed to applications
+	ClosedAt *time.Time `json:"closedAt,omitempty"`
+	// Sequence increases whenever the schedule changes, it's the iCalendar SEQUENCE
+	Sequence int64 `json:"sequence"`
+	// Draft opportunities aren't submitted for review, so can't be approved until they are
+	Draft bool `json:"draft"`
+	// ExternalRef is only set on opportunities just created with one, see CreateOpportunityRequest
+	ExternalRef string `json:"externalRef,omitempty"`
 
 	Tags  *[]TagModel   `json:"tags"`
 	Media *[]MediaModel `json:"media"`
 }
 
+// OpportunitySearchResult is an opportunity matching a search along with the matched fields highlighted
+type OpportunitySearchResult struct {
+	Opportunity OpportunityModel  `json:"opportunity"`
+	Score       float64           `json:"score"`
+	Highlights  map[string]string `json:"highlights"`
+}
+
 type TagModel struct {
 	ID      int64  `json:"id"`
 	TagName string `json:"tagName"`
 }
 
 type MediaModel struct {
-	Type MediaType `json:"type"`
-	URL  string    `json:"URL"`
+	// ID is the stored media, nil for media added as a URL before uploads were stored
+	ID   *uuid.UUID `json:"id,omitempty"`
+	Type MediaType  `json:"type"`
+	// URL is a signed download URL for stored media, it expires so shouldn't be saved
+	URL string `json:"URL"`
+	// Variants are resized copies of stored images by name, e.g. "thumbnail"
+	Variants map[string]MediaVariantModel `json:"variants,omitempty"`
 }
 
 type MediaType int
@@ -79,6 +123,11 @@ func (mediaType *MediaType) UnmarshalJSON(data []byte) error {
 	return nil
 }
 
+// IsOpen returns whether the opportunity still accepts applications
+func (model *OpportunityModel) IsOpen(now time.Time) bool {
+	return model.ClosedAt == nil && (model.ExpiresAt == nil || model.ExpiresAt.After(now))
+}
+
 type CreateOpportunityStatus struct {
 	*OpportunityModel
 	Success bool
@@ -86,14 +135,56 @@ type CreateOpportunityStatus struct {
 }
 
 type CreateOpportunityRequest struct {
-	UUID        string   `json:"uuid"`
-	Title       string   `json:"title"`
-	Description string   `json:"description"`
-	Location    string   `json:"location"`
-	Type        string   `json:"type"`
-	AuthorUUID  string   `json:"author"`
-	Points      int64    `json:"points"`
-	Tags        []string `json:"tags"`
-	MediaTypes  []string `json:"mediaType"`
-	MediaURLs   []string `json:"mediaURL"`
+	UUID        string `json:"uuid"`
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	Location    string `json:"location"`
+	Type        string `json:"type"`
+	AuthorUUID  string `json:"author"`
+	// OrganisationUUID optional organisation to post on behalf of. Author must be an owner or editor
+	OrganisationUUID string   `json:"organisation"`
+	Points           int64    `json:"points"`
+	Tags             []string `json:"tags"`
+	// MediaIDs are uploaded media, in the order they're shown
+	MediaIDs []string `json:"media"`
+
+	// Latitude and Longitude are optional, without them the address or location is geocoded
+	Latitude  *float64    `json:"latitude"`
+	Longitude *float64    `json:"longitude"`
+	Address   geo.Address `json:"address"`
+	Online    bool        `json:"online"`
+
+	// StartsAt, EndsAt and Deadline are RFC 3339 or local times (2025-06-01T10:00) in TimeZone
+	StartsAt   string `json:"startsAt"`
+	EndsAt     string `json:"endsAt"`
+	TimeZone   string `json:"timeZone"`
+	Recurrence string `json:"recurrence"`
+	Deadline   string `json:"deadline"`
+	Capacity   *int64 `json:"capacity"`
+
+	// Draft saves a new opportunity without submitting it for review. It's ignored when updating
+	Draft bool `json:"draft"`
+	// ExternalRef is the poster's own ID for the opportunity, importing the same ref again updates it
+	ExternalRef string `json:"externalRef"`
+}
+
+// ImportRowResult is what happened to a row of a bulk import
+type ImportRowResult struct {
+	// Line is where the row starts in the file
+	Line        int    `json:"line"`
+	ExternalRef string `json:"externalRef,omitempty"`
+	// Action is "create" or "update", what was done or in a dry run what would be done. Empty if the row failed
+	Action          string     `json:"action,omitempty"`
+	OpportunityUUID *uuid.UUID `json:"opportunityUUID,omitempty"`
+	Errors          []string   `json:"errors,omitempty"`
+}
+
+// ImportReportModel reports every row of a bulk import. In a dry run nothing is saved and the counts are what
+// would have been created and updated
+type ImportReportModel struct {
+	DryRun  bool              `json:"dryRun"`
+	Created int               `json:"created"`
+	Updated int               `json:"updated"`
+	Failed  int               `json:"failed"`
+	Rows    []ImportRowResult `json:"rows"`
 }
@@ -0,0 +1,84 @@
+package models
+
+import (
+	"backend/internal/geo"
+	"fmt"
+	"github.com/google/uuid"
+	"time"
+)
+
+// TagMatch is whether an opportunity needs any or all of the filtered tags
+type TagMatch int
+
+const (
+	TagMatchAny TagMatch = iota
+	TagMatchAll
+)
+
+// OpportunitySort is the order of a filtered opportunity listing
+type OpportunitySort int
+
+const (
+	SortNewest OpportunitySort = iota
+	SortPoints
+	SortDistance
+)
+
+func (sort *OpportunitySort) String() string {
+	return [...]string{"newest", "points", "distance"}[*sort]
+}
+
+func ParseOpportunitySort(s string) (OpportunitySort, error) {
+	switch s {
+
+	case "newest":
+		return SortNewest, nil
+	case "points":
+		return SortPoints, nil
+	case "distance":
+		return SortDistance, nil
+	default:
+		return -1, fmt.Errorf("invalid sort: %s", s)
+
+	}
+}
+
+// OpportunityFilter every set field narrows the listing, nil/empty fields are ignored
+type OpportunityFilter struct {
+	Tags     []string
+	TagMatch TagMatch
+	Types    []string
+
+	MinPoints *int64
+	MaxPoints *int64
+
+	CreatedAfter  *time.Time
+	CreatedBefore *time.Time
+	UpdatedAfter  *time.Time
+	UpdatedBefore *time.Time
+
+	OrganisationUUID *uuid.UUID
+
+	// Near is the point distances are measured from, RadiusKm optionally limits results to within it
+	Near     *geo.Coordinates
+	RadiusKm *float64
+	Within   *geo.BoundingBox
+	Online   *bool
+
+	Sort OpportunitySort
+	// Ascending reverses the sorts natural order (newest first, most points first)
+	Ascending bool
+
+	Limit  int64
+	Offset int64
+}
+
+// FilterError is a validation error for a single query parameter
+type FilterError struct {
+	Parameter string
+	Message   string
+}
+
+func (err *FilterError) Error() string {
+	return fmt.Sprintf("invalid '%s': %s", err.Parameter, err.Message)
+}
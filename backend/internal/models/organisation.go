@@ -0,0 +1,191 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/google/uuid"
+	"time"
+)
+
+type OrganisationModel struct {
+	UUID               uuid.UUID          `json:"uuid"`
+	Name               string             `json:"name"`
+	Description        string             `json:"description"`
+	LogoURL            string             `json:"logoURL,omitempty"`
+	Website            string             `json:"website,omitempty"`
+	VerificationStatus VerificationStatus `json:"verificationStatus"`
+	CreatedAt          time.Time          `json:"createdAt"`
+}
+
+type OrganisationMemberModel struct {
+	OrganisationUUID uuid.UUID        `json:"organisationUUID"`
+	UserUUID         uuid.UUID        `json:"userUUID"`
+	Username         string           `json:"username,omitempty"`
+	Role             OrganisationRole `json:"role"`
+	JoinedAt         time.Time        `json:"joinedAt"`
+}
+
+// OrganisationMembershipModel is an organisation along with the role the user has in it
+type OrganisationMembershipModel struct {
+	Organisation OrganisationModel `json:"organisation"`
+	Role         OrganisationRole  `json:"role"`
+}
+
+type OrganisationInviteModel struct {
+	UUID             uuid.UUID        `json:"uuid"`
+	OrganisationUUID uuid.UUID        `json:"organisationUUID"`
+	Email            string           `json:"email"`
+	Role             OrganisationRole `json:"role"`
+	InvitedByUUID    uuid.UUID        `json:"invitedBy"`
+	TokenHash        string           `json:"-"`
+	ExpiresAt        time.Time        `json:"expiresAt"`
+	AcceptedAt       *time.Time       `json:"acceptedAt,omitempty"`
+}
+
+type CreateOrganisationRequest struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	LogoURL     string `json:"logoURL"`
+	Website     string `json:"website"`
+}
+
+type CreateInviteRequest struct {
+	Email string `json:"email"`
+	Role  string `json:"role"`
+}
+
+// OrganisationProfileModel is the public profile shown to students
+type OrganisationProfileModel struct {
+	Organisation        OrganisationModel        `json:"organisation"`
+	Impact              OrganisationImpactModel  `json:"impact"`
+	ActiveOpportunities []OpportunityModel       `json:"activeOpportunities"`
+	Reviews             OrganisationReviewsModel `json:"reviews"`
+}
+
+// OrganisationImpactModel totals across the organisations approved opportunities
+type OrganisationImpactModel struct {
+	OpportunitiesPosted int64 `json:"opportunitiesPosted"`
+	PointsOffered       int64 `json:"pointsOffered"`
+	StudentsEngaged     int64 `json:"studentsEngaged"`
+	Followers           int64 `json:"followers"`
+}
+
+type OrganisationReviewModel struct {
+	OrganisationUUID uuid.UUID `json:"organisationUUID"`
+	UserUUID         uuid.UUID `json:"userUUID"`
+	Username         string    `json:"username"`
+	Rating           int64     `json:"rating"`
+	Comment          string    `json:"comment"`
+	CreatedAt        time.Time `json:"createdAt"`
+}
+
+// OrganisationReviewsModel is the summary of an organisations reviews along with the most recent ones
+type OrganisationReviewsModel struct {
+	Count         int64                     `json:"count"`
+	AverageRating float64                   `json:"averageRating"`
+	Recent        []OrganisationReviewModel `json:"recent"`
+}
+
+type CreateReviewRequest struct {
+	Rating  int64  `json:"rating"`
+	Comment string `json:"comment"`
+}
+
+// OrganisationRole is the role a member has within an organisation
+type OrganisationRole int
+
+const (
+	OrganisationViewer OrganisationRole = iota
+	OrganisationEditor
+	OrganisationOwner
+)
+
+func (role *OrganisationRole) String() string {
+	return [...]string{"viewer", "editor", "owner"}[*role]
+}
+
+// CanEdit returns whether the role can manage the organisations profile and opportunities
+func (role OrganisationRole) CanEdit() bool {
+	return role >= OrganisationEditor
+}
+
+func ParseOrganisationRole(s string) (OrganisationRole, error) {
+	switch s {
+
+	case "viewer":
+		return OrganisationViewer, nil
+	case "editor":
+		return OrganisationEditor, nil
+	case "owner":
+		return OrganisationOwner, nil
+	default:
+		return -1, fmt.Errorf("invalid organisation role: %s", s)
+
+	}
+}
+
+func (role *OrganisationRole) MarshalJSON() ([]byte, error) {
+	return json.Marshal(role.String())
+}
+
+func (role *OrganisationRole) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+
+	parsed, err := ParseOrganisationRole(s)
+	if err != nil {
+		return err
+	}
+
+	*role = parsed
+	return nil
+}
+
+// VerificationStatus is whether an admin has verified an organisation is who it says it is
+type VerificationStatus int
+
+const (
+	VerificationPending VerificationStatus = iota
+	VerificationVerified
+	VerificationRejected
+)
+
+func (status *VerificationStatus) String() string {
+	return [...]string{"pending", "verified", "rejected"}[*status]
+}
+
+func ParseVerificationStatus(s string) (VerificationStatus, error) {
+	switch s {
+
+	case "pending":
+		return VerificationPending, nil
+	case "verified":
+		return VerificationVerified, nil
+	case "rejected":
+		return VerificationRejected, nil
+	default:
+		return -1, fmt.Errorf("invalid verification status: %s", s)
+
+	}
+}
+
+func (status *VerificationStatus) MarshalJSON() ([]byte, error) {
+	return json.Marshal(status.String())
+}
+
+func (status *VerificationStatus) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+
+	parsed, err := ParseVerificationStatus(s)
+	if err != nil {
+		return err
+	}
+
+	*status = parsed
+	return nil
+}
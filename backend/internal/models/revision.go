@@ -0,0 +1,133 @@
+package models
+
+import (
+	"backend/internal/geo"
+	"github.com/google/uuid"
+	"time"
+)
+
+// OpportunitySnapshot is everything a recruiter can edit about an opportunity, as it was at a revision.
+// Times are UTC so snapshots compare equal whatever zone they were read in
+type OpportunitySnapshot struct {
+	Title           string           `json:"title"`
+	Description     string           `json:"description"`
+	Points          int64            `json:"points"`
+	Location        string           `json:"location"`
+	OpportunityType string           `json:"opportunityType"`
+	Coordinates     *geo.Coordinates `json:"coordinates"`
+	Address         geo.Address      `json:"address"`
+	IsOnline        bool             `json:"isOnline"`
+	StartsAt        *time.Time       `json:"startsAt"`
+	EndsAt          *time.Time       `json:"endsAt"`
+	TimeZone        string           `json:"timeZone"`
+	Recurrence      string           `json:"recurrence"`
+	Deadline        *time.Time       `json:"deadline"`
+	Capacity        *int64           `json:"capacity"`
+	ExpiresAt       *time.Time       `json:"expiresAt"`
+	Tags            []string         `json:"tags"`
+	// Media only has the URL for media added before uploads were stored, stored media is signed when it's shown
+	Media []MediaModel `json:"media"`
+}
+
+// OpportunityRevisionModel is a version of an opportunity. Revisions are never changed, a rollback adds a new one
+type OpportunityRevisionModel struct {
+	OpportunityUUID uuid.UUID `json:"opportunityUUID"`
+	// Revision counts up from 1 for each opportunity
+	Revision   int64     `json:"revision"`
+	AuthorUUID uuid.UUID `json:"authorUUID"`
+	CreatedAt  time.Time `json:"createdAt"`
+	// RolledBackFrom is the revision an admin restored to make this one
+	RolledBackFrom *int64              `json:"rolledBackFrom,omitempty"`
+	Snapshot       OpportunitySnapshot `json:"snapshot"`
+}
+
+// RevisionChange is a field that differs between two revisions. Tags and media are listed as added and removed
+type RevisionChange struct {
+	Field   string   `json:"field"`
+	From    any      `json:"from,omitempty"`
+	To      any      `json:"to,omitempty"`
+	Added   []string `json:"added,omitempty"`
+	Removed []string `json:"removed,omitempty"`
+}
+
+type RevisionDiffModel struct {
+	From    int64            `json:"from"`
+	To      int64            `json:"to"`
+	Changes []RevisionChange `json:"changes"`
+}
+
+// Snapshot returns the editable fields of the opportunity
+func (model *OpportunityModel) Snapshot() OpportunitySnapshot {
+	snapshot := OpportunitySnapshot{
+		Title:           model.Title,
+		Description:     model.Description,
+		Points:          model.Points,
+		Location:        model.Location,
+		OpportunityType: model.OpportunityType,
+		Coordinates:     model.Coordinates,
+		Address:         model.Address,
+		IsOnline:        model.IsOnline,
+		StartsAt:        utc(model.StartsAt),
+		EndsAt:          utc(model.EndsAt),
+		TimeZone:        model.TimeZone,
+		Recurrence:      model.Recurrence,
+		Deadline:        utc(model.Deadline),
+		Capacity:        model.Capacity,
+		ExpiresAt:       utc(model.ExpiresAt),
+		Tags:            []string{},
+		Media:           []MediaModel{},
+	}
+
+	if model.Tags != nil {
+		for _, tag := range *model.Tags {
+			snapshot.Tags = append(snapshot.Tags, tag.TagName)
+		}
+	}
+
+	if model.Media != nil {
+		for _, media := range *model.Media {
+			stored := MediaModel{ID: media.ID, Type: media.Type}
+			if media.ID == nil {
+				stored.URL = media.URL
+			}
+			snapshot.Media = append(snapshot.Media, stored)
+		}
+	}
+	return snapshot
+}
+
+// Restore replaces the opportunity's editable fields with the snapshot's
+func (snapshot *OpportunitySnapshot) Restore(model *OpportunityModel) {
+	model.Title = snapshot.Title
+	model.Description = snapshot.Description
+	model.Points = snapshot.Points
+	model.Location = snapshot.Location
+	model.OpportunityType = snapshot.OpportunityType
+	model.Coordinates = snapshot.Coordinates
+	model.Address = snapshot.Address
+	model.IsOnline = snapshot.IsOnline
+	model.StartsAt = snapshot.StartsAt
+	model.EndsAt = snapshot.EndsAt
+	model.TimeZone = snapshot.TimeZone
+	model.Recurrence = snapshot.Recurrence
+	model.Deadline = snapshot.Deadline
+	model.Capacity = snapshot.Capacity
+	model.ExpiresAt = snapshot.ExpiresAt
+
+	tags := make([]TagModel, len(snapshot.Tags))
+	for i, name := range snapshot.Tags {
+		tags[i] = TagModel{TagName: name}
+	}
+	model.Tags = &tags
+
+	media := append([]MediaModel{}, snapshot.Media...)
+	model.Media = &media
+}
+
+func utc(value *time.Time) *time.Time {
+	if value == nil {
+		return nil
+	}
+	converted := value.UTC()
+	return &converted
+}
@@ -7,7 +7,9 @@ type StudentInfoModel struct {
 	StudentName  string    `json:"studentName"`
 	StudentEmail string    `json:"studentEmail,omitempty"`
 	Description  string    `json:"description,omitempty"`
-	ProfilePic   string    `json:"profilePic,omitempty"`
-	TagsLiked    []string  `json:"tagsLiked,omitempty"`
-	TagsDisliked []string  `json:"tagsDisliked,omitempty"`
+	// ProfilePic is the uploaded media ID of the students picture, ProfilePicURL a signed URL to download it
+	ProfilePic    string   `json:"profilePic,omitempty"`
+	ProfilePicURL string   `json:"profilePicURL,omitempty"`
+	TagsLiked     []string `json:"tagsLiked,omitempty"`
+	TagsDisliked  []string `json:"tagsDisliked,omitempty"`
 }
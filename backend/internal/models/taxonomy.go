@@ -0,0 +1,59 @@
+package models
+
+// TaxonomyTagModel is a tag with everything admins manage about it
+type TaxonomyTagModel struct {
+	ID         int64    `json:"id"`
+	TagName    string   `json:"tagName"`
+	CategoryID *int64   `json:"categoryID,omitempty"`
+	Approved   bool     `json:"approved"`
+	Synonyms   []string `json:"synonyms,omitempty"`
+	// Usage is how many opportunities and students reference the tag
+	Usage int64 `json:"usage"`
+}
+
+type TagCategoryModel struct {
+	ID       int64  `json:"id"`
+	Name     string `json:"name"`
+	ParentID *int64 `json:"parentID,omitempty"`
+	// Children is only set when categories are returned as a tree
+	Children []*TagCategoryModel `json:"children,omitempty"`
+}
+
+type TaxonomySettingsModel struct {
+	// VocabularyLocked stops new tags being created outside of the admin endpoints, only approved tags can be used
+	VocabularyLocked bool `json:"vocabularyLocked"`
+}
+
+type CreateTagRequest struct {
+	TagName    string `json:"tagName"`
+	CategoryID *int64 `json:"categoryID"`
+}
+
+type UpdateTagRequest struct {
+	TagName    string `json:"tagName"`
+	CategoryID *int64 `json:"categoryID"`
+	Approved   *bool  `json:"approved"`
+}
+
+type MergeTagsRequest struct {
+	// Into is the tag that's kept, the merged tag's name becomes its synonym
+	Into int64 `json:"into"`
+}
+
+type TagSynonymRequest struct {
+	Synonym string `json:"synonym"`
+}
+
+type TagCategoryRequest struct {
+	Name     string `json:"name"`
+	ParentID *int64 `json:"parentID"`
+}
+
+// SuggestTagsRequest is a draft opportunity to suggest tags for
+type SuggestTagsRequest struct {
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	// Tags the draft already has aren't suggested
+	Tags  []string `json:"tags"`
+	Limit int      `json:"limit"`
+}
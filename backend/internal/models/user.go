@@ -1,22 +1,22 @@
 package models
 
 import (
-	"database/sql"
 	"encoding/json"
 	"fmt"
 	"github.com/google/uuid"
 )
 
+// RawUserRow is a user joined with their recruiter and student details, only those of their role are set
 type RawUserRow struct {
-	UUID              uuid.UUID
-	Username          string
-	Email             string
-	HashedPassword    string
-	Salt              string
-	Role              RoleType
-	OrganisationName  sql.NullString
-	ApplicationStatus sql.NullBool
-	Points            sql.NullInt64
+	UUID              uuid.UUID `db:"uuid"`
+	Username          string    `db:"username"`
+	Email             string    `db:"email"`
+	HashedPassword    string    `db:"hashed_pass"`
+	Salt              string    `db:"salt"`
+	Role              RoleType  `db:"role"`
+	OrganisationName  *string   `db:"organisationName"`
+	ApplicationStatus *bool     `db:"applicationStatus"`
+	Points            *int64    `db:"points"`
 }
 
 type UserModel struct {
@@ -85,6 +85,27 @@ func ParseRoleType(s string) (RoleType, error) {
 	}
 }
 
+// Scan reads the role from its name in the database
+func (roleType *RoleType) Scan(src any) error {
+	var name string
+	switch src := src.(type) {
+	case string:
+		name = src
+	case []byte:
+		name = string(src)
+	default:
+		return fmt.Errorf("can't scan %T into a role type", src)
+	}
+
+	parsed, err := ParseRoleType(name)
+	if err != nil {
+		return err
+	}
+
+	*roleType = parsed
+	return nil
+}
+
 func (roleType *RoleType) MarshalJSON() ([]byte, error) {
 	return json.Marshal(roleType.String())
 }
@@ -0,0 +1,32 @@
+package outbox
+
+import (
+	"context"
+)
+
+// Matches used to be made in the graph alone. MySQL has only recorded them since the outbox, so the graph's older
+// matches are copied into it once, before the graph can be reconciled against it
+
+// MatchRecords is the MySQL side of the backfill
+type MatchRecords interface {
+	// MatchesBackfilled reports whether the graph's matches have been copied into MySQL
+	MatchesBackfilled(ctx context.Context) (bool, error)
+	// BackfillMatches records the matches MySQL doesn't have, skipping those of users it no longer has, and marks
+	// the backfill done. It returns how many matches were added
+	BackfillMatches(ctx context.Context, matches []Pair) (int64, error)
+}
+
+// BackfillMatches copies the graph's matches into MySQL unless that's been done before, returning how many were
+// added. It must run before the relay publishes and before Reconcile repairs, which won't delete matches until then
+func BackfillMatches(ctx context.Context, records MatchRecords, graph Graph) (int64, error) {
+	done, err := records.MatchesBackfilled(ctx)
+	if err != nil || done {
+		return 0, err
+	}
+
+	snapshot, err := graph.Snapshot(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return records.BackfillMatches(ctx, snapshot.Matches)
+}
@@ -0,0 +1,138 @@
+// Package outbox keeps the Neo4j graph in step with MySQL.
+//
+// Users, opportunities, likes and matches are written to MySQL, and each write records an event in the outbox table
+// in the same transaction, so either both happen or neither does. The Relay then publishes the events to the graph,
+// retrying those that fail. Events of the same aggregate, e.g. the likes of one opportunity, are published in the
+// order they were recorded, and an event that fails holds back the ones after it. Reconcile finds and repairs any
+// drift left by writes made before the outbox or outside it. Matches made in the graph before the outbox existed are
+// copied into MySQL once by BackfillMatches
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/google/uuid"
+	"time"
+)
+
+// Aggregate types
+const (
+	UserAggregate        = "user"
+	OpportunityAggregate = "opportunity"
+	MatchAggregate       = "match"
+)
+
+// Event types
+const (
+	UserCreated        = "user.created"
+	OpportunityCreated = "opportunity.created"
+	OpportunityDeleted = "opportunity.deleted"
+	OpportunityLiked   = "opportunity.liked"
+	OpportunityUnliked = "opportunity.unliked"
+	UsersMatched       = "users.matched"
+)
+
+// Aggregate is what an event happened to, events of one aggregate are published in order
+type Aggregate struct {
+	Type string
+	ID   string
+}
+
+// Event is a change recorded in the outbox
+type Event struct {
+	ID        int64
+	Aggregate Aggregate
+	Type      string
+	Payload   []byte
+	// Attempts counts the failed attempts to publish the event
+	Attempts  int
+	CreatedAt time.Time
+}
+
+// Store is the outbox table
+type Store interface {
+	// Pending returns up to limit unpublished events due at now in the order they were recorded. An event behind one
+	// of the same aggregate that's waiting to be retried isn't due
+	Pending(ctx context.Context, now time.Time, limit int) ([]Event, error)
+	// Published removes the published event
+	Published(ctx context.Context, event Event) error
+	// Failed counts a failed attempt and holds the event back until retryAt
+	Failed(ctx context.Context, event Event, retryAt time.Time, reason string) error
+}
+
+// UserPayload is the payload of user events
+type UserPayload struct {
+	UserUUID uuid.UUID `json:"userUUID"`
+}
+
+// OpportunityPayload is the payload of opportunity events
+type OpportunityPayload struct {
+	OpportunityUUID uuid.UUID `json:"opportunityUUID"`
+}
+
+// LikePayload is the payload of like events
+type LikePayload struct {
+	UserUUID        uuid.UUID `json:"userUUID"`
+	OpportunityUUID uuid.UUID `json:"opportunityUUID"`
+}
+
+// MatchPayload is the payload of match events
+type MatchPayload struct {
+	UserUUID    uuid.UUID `json:"userUUID"`
+	MatchedUUID uuid.UUID `json:"matchedUUID"`
+}
+
+func newEvent(aggregate Aggregate, eventType string, payload any) Event {
+	// The payloads are structs of UUIDs, which always encode
+	data, _ := json.Marshal(payload)
+	return Event{Aggregate: aggregate, Type: eventType, Payload: data}
+}
+
+func NewUserCreated(userUUID uuid.UUID) Event {
+	return newEvent(Aggregate{UserAggregate, userUUID.String()}, UserCreated, UserPayload{userUUID})
+}
+
+func NewOpportunityCreated(opportunityUUID uuid.UUID) Event {
+	return newEvent(Aggregate{OpportunityAggregate, opportunityUUID.String()}, OpportunityCreated,
+		OpportunityPayload{opportunityUUID})
+}
+
+func NewOpportunityDeleted(opportunityUUID uuid.UUID) Event {
+	return newEvent(Aggregate{OpportunityAggregate, opportunityUUID.String()}, OpportunityDeleted,
+		OpportunityPayload{opportunityUUID})
+}
+
+// NewOpportunityLiked is an event of the opportunity, so it's published after the opportunity is created and
+// before it's deleted
+func NewOpportunityLiked(userUUID uuid.UUID, opportunityUUID uuid.UUID) Event {
+	return newEvent(Aggregate{OpportunityAggregate, opportunityUUID.String()}, OpportunityLiked,
+		LikePayload{userUUID, opportunityUUID})
+}
+
+func NewOpportunityUnliked(userUUID uuid.UUID, opportunityUUID uuid.UUID) Event {
+	return newEvent(Aggregate{OpportunityAggregate, opportunityUUID.String()}, OpportunityUnliked,
+		LikePayload{userUUID, opportunityUUID})
+}
+
+func NewUsersMatched(userUUID uuid.UUID, matchedUUID uuid.UUID) Event {
+	pair := NewPair(userUUID, matchedUUID)
+	return newEvent(Aggregate{MatchAggregate, pair.key()}, UsersMatched, MatchPayload{userUUID, matchedUUID})
+}
+
+// Pair is two users or a user and an opportunity
+type Pair struct {
+	A uuid.UUID `json:"a"`
+	B uuid.UUID `json:"b"`
+}
+
+// NewPair orders the users of a match, so either way round is the same pair
+func NewPair(a uuid.UUID, b uuid.UUID) Pair {
+	if b.String() < a.String() {
+		a, b = b, a
+	}
+	return Pair{a, b}
+}
+
+func (pair Pair) key() string {
+	return pair.A.String() + ":" + pair.B.String()
+}
@@ -0,0 +1,415 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"sync"
+	"testing"
+	"time"
+)
+
+// memoryStore is an outbox in memory, holding events back like the MySQL one
+type memoryStore struct {
+	mutex   sync.Mutex
+	nextID  int64
+	events  []Event
+	retryAt map[int64]time.Time
+}
+
+func newMemoryStore(events ...Event) *memoryStore {
+	store := &memoryStore{retryAt: map[int64]time.Time{}}
+	for _, event := range events {
+		store.nextID++
+		event.ID = store.nextID
+		store.events = append(store.events, event)
+	}
+	return store
+}
+
+func (store *memoryStore) Pending(_ context.Context, now time.Time, limit int) ([]Event, error) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	held := map[Aggregate]bool{}
+	var pending []Event
+	for _, event := range store.events {
+		if held[event.Aggregate] {
+			continue
+		}
+		if store.retryAt[event.ID].After(now) {
+			held[event.Aggregate] = true
+			continue
+		}
+		if len(pending) < limit {
+			pending = append(pending, event)
+		}
+	}
+	return pending, nil
+}
+
+func (store *memoryStore) Published(_ context.Context, event Event) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	for i, stored := range store.events {
+		if stored.ID == event.ID {
+			store.events = append(store.events[:i], store.events[i+1:]...)
+			return nil
+		}
+	}
+	return nil
+}
+
+func (store *memoryStore) Failed(_ context.Context, event Event, retryAt time.Time, _ string) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	for i := range store.events {
+		if store.events[i].ID == event.ID {
+			store.events[i].Attempts++
+		}
+	}
+	store.retryAt[event.ID] = retryAt
+	return nil
+}
+
+// retryNow makes the held back events due
+func (store *memoryStore) retryNow() {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	store.retryAt = map[int64]time.Time{}
+}
+
+// recordingGraph records the changes made to it, failing those in fail
+type recordingGraph struct {
+	mutex   sync.Mutex
+	changes []string
+	fail    map[string]bool
+}
+
+func (graph *recordingGraph) apply(change string) error {
+	graph.mutex.Lock()
+	defer graph.mutex.Unlock()
+	if graph.fail[change] {
+		return errors.New("graph unavailable")
+	}
+	graph.changes = append(graph.changes, change)
+	return nil
+}
+
+func (graph *recordingGraph) recorded() []string {
+	graph.mutex.Lock()
+	defer graph.mutex.Unlock()
+	return append([]string(nil), graph.changes...)
+}
+
+func (graph *recordingGraph) MergeUser(_ context.Context, id uuid.UUID) error {
+	return graph.apply("merge user " + id.String())
+}
+
+func (graph *recordingGraph) DeleteUser(_ context.Context, id uuid.UUID) error {
+	return graph.apply("delete user " + id.String())
+}
+
+func (graph *recordingGraph) MergeOpportunity(_ context.Context, id uuid.UUID) error {
+	return graph.apply("merge opportunity " + id.String())
+}
+
+func (graph *recordingGraph) DeleteOpportunity(_ context.Context, id uuid.UUID) error {
+	return graph.apply("delete opportunity " + id.String())
+}
+
+func (graph *recordingGraph) MergeLike(_ context.Context, user uuid.UUID, opportunity uuid.UUID) error {
+	return graph.apply(fmt.Sprintf("merge like %s %s", user, opportunity))
+}
+
+func (graph *recordingGraph) DeleteLike(_ context.Context, user uuid.UUID, opportunity uuid.UUID) error {
+	return graph.apply(fmt.Sprintf("delete like %s %s", user, opportunity))
+}
+
+func (graph *recordingGraph) MergeMatch(_ context.Context, user uuid.UUID, matched uuid.UUID) error {
+	return graph.apply(fmt.Sprintf("merge match %s %s", user, matched))
+}
+
+func (graph *recordingGraph) DeleteMatch(_ context.Context, user uuid.UUID, matched uuid.UUID) error {
+	return graph.apply(fmt.Sprintf("delete match %s %s", user, matched))
+}
+
+func (graph *recordingGraph) Snapshot(context.Context) (*Snapshot, error) {
+	return &Snapshot{}, nil
+}
+
+func newTestRelay(store Store, graph Graph) *Relay {
+	relay := NewRelay(store, WithBackoff(func(int) time.Duration { return time.Hour }))
+	Project(relay, graph)
+	return relay
+}
+
+// indexOf returns where the change was made, -1 if it wasn't
+func indexOf(changes []string, change string) int {
+	for i, made := range changes {
+		if made == change {
+			return i
+		}
+	}
+	return -1
+}
+
+func TestRelayPublishesEachAggregateInOrder(t *testing.T) {
+	user, first, second := uuid.New(), uuid.New(), uuid.New()
+	store := newMemoryStore(
+		NewOpportunityCreated(first),
+		NewOpportunityCreated(second),
+		NewOpportunityLiked(user, first),
+		NewOpportunityLiked(user, second),
+		NewOpportunityUnliked(user, first),
+		NewOpportunityDeleted(second),
+	)
+	graph := &recordingGraph{}
+
+	published, err := newTestRelay(store, graph).Publish(context.Background())
+	if err != nil || published != 6 {
+		t.Fatalf("expected all 6 events published, got %d %v", published, err)
+	}
+
+	changes := graph.recorded()
+	for _, order := range [][]string{
+		{"merge opportunity " + first.String(), fmt.Sprintf("merge like %s %s", user, first), fmt.Sprintf("delete like %s %s", user, first)},
+		{"merge opportunity " + second.String(), fmt.Sprintf("merge like %s %s", user, second), "delete opportunity " + second.String()},
+	} {
+		for i := 1; i < len(order); i++ {
+			if indexOf(changes, order[i-1]) > indexOf(changes, order[i]) || indexOf(changes, order[i]) < 0 {
+				t.Fatalf("expected %q before %q, got %v", order[i-1], order[i], changes)
+			}
+		}
+	}
+}
+
+func TestFailedEventHoldsBackItsAggregate(t *testing.T) {
+	user, failing, other := uuid.New(), uuid.New(), uuid.New()
+	store := newMemoryStore(
+		NewOpportunityCreated(failing),
+		NewOpportunityLiked(user, failing),
+		NewOpportunityCreated(other),
+	)
+	graph := &recordingGraph{fail: map[string]bool{"merge opportunity " + failing.String(): true}}
+	relay := newTestRelay(store, graph)
+
+	published, err := relay.Publish(context.Background())
+	if err != nil || published != 1 {
+		t.Fatalf("expected only the other aggregate published, got %d %v", published, err)
+	}
+	if changes := graph.recorded(); len(changes) != 1 || changes[0] != "merge opportunity "+other.String() {
+		t.Fatalf("expected the like held back behind the failed create, got %v", changes)
+	}
+
+	// Still held back until the retry is due
+	if published, _ := relay.Publish(context.Background()); published != 0 {
+		t.Fatalf("expected nothing published before the retry, got %d", published)
+	}
+
+	graph.fail = nil
+	store.retryNow()
+	if published, err := relay.Publish(context.Background()); err != nil || published != 2 {
+		t.Fatalf("expected the create then like published on retry, got %d %v", published, err)
+	}
+	changes := graph.recorded()
+	if changes[1] != "merge opportunity "+failing.String() || changes[2] != fmt.Sprintf("merge like %s %s", user, failing) {
+		t.Fatalf("expected the held back events in order, got %v", changes)
+	}
+}
+
+func TestEventWithoutHandlerIsRetried(t *testing.T) {
+	store := newMemoryStore(Event{Aggregate: Aggregate{"user", "1"}, Type: "user.renamed", Payload: []byte("{}")})
+
+	published, err := newTestRelay(store, &recordingGraph{}).Publish(context.Background())
+	if err != nil || published != 0 {
+		t.Fatalf("expected nothing published, got %d %v", published, err)
+	}
+	if store.events[0].Attempts != 1 {
+		t.Fatal("expected the failed attempt to be counted")
+	}
+}
+
+func TestRelayPublishesInBatches(t *testing.T) {
+	var events []Event
+	for range 25 {
+		events = append(events, NewUserCreated(uuid.New()))
+	}
+	store := newMemoryStore(events...)
+
+	relay := NewRelay(store, WithBatchSize(10))
+	Project(relay, &recordingGraph{})
+
+	if published, err := relay.Publish(context.Background()); err != nil || published != 25 {
+		t.Fatalf("expected every batch published, got %d %v", published, err)
+	}
+}
+
+func TestMatchIsTheSameAggregateEitherWayRound(t *testing.T) {
+	a, b := uuid.New(), uuid.New()
+	if NewUsersMatched(a, b).Aggregate != NewUsersMatched(b, a).Aggregate {
+		t.Fatal("expected a match's aggregate not to depend on who matched whom")
+	}
+}
+
+func TestDiffFindsMissingAndExtra(t *testing.T) {
+	kept, missing, extra := uuid.New(), uuid.New(), uuid.New()
+	opportunity := uuid.New()
+
+	source := &Snapshot{
+		Users:         []uuid.UUID{kept, missing},
+		Opportunities: []uuid.UUID{opportunity},
+		Likes:         []Pair{{kept, opportunity}},
+		Matches:       []Pair{NewPair(kept, missing)},
+	}
+	graph := &Snapshot{
+		Users:         []uuid.UUID{kept, extra},
+		Opportunities: []uuid.UUID{opportunity},
+		Likes:         []Pair{{extra, opportunity}},
+	}
+
+	drift := Diff(source, graph, nil)
+	if fmt.Sprint(drift.MissingUsers) != fmt.Sprint([]uuid.UUID{missing}) || fmt.Sprint(drift.ExtraUsers) != fmt.Sprint([]uuid.UUID{extra}) {
+		t.Fatalf("unexpected user drift %v %v", drift.MissingUsers, drift.ExtraUsers)
+	}
+	if len(drift.MissingOpportunities)+len(drift.ExtraOpportunities) != 0 {
+		t.Fatal("expected no opportunity drift")
+	}
+	if len(drift.MissingLikes) != 1 || drift.MissingLikes[0] != (Pair{kept, opportunity}) ||
+		len(drift.ExtraLikes) != 1 || drift.ExtraLikes[0] != (Pair{extra, opportunity}) {
+		t.Fatalf("unexpected like drift %v %v", drift.MissingLikes, drift.ExtraLikes)
+	}
+	if len(drift.MissingMatches) != 1 || drift.Empty() {
+		t.Fatalf("expected the missing match, got %v", drift.MissingMatches)
+	}
+
+	if !Diff(source, source, nil).Empty() {
+		t.Fatal("expected no drift between identical snapshots")
+	}
+}
+
+func TestDiffLeavesPendingAggregatesToTheRelay(t *testing.T) {
+	user, opportunity := uuid.New(), uuid.New()
+	source := &Snapshot{Users: []uuid.UUID{user}, Opportunities: []uuid.UUID{opportunity}, Likes: []Pair{{user, opportunity}}}
+	pending := map[Aggregate]bool{
+		{UserAggregate, user.String()}:               true,
+		{OpportunityAggregate, opportunity.String()}: true,
+	}
+
+	if drift := Diff(source, &Snapshot{}, pending); !drift.Empty() {
+		t.Fatalf("expected pending aggregates to be skipped, got %+v", drift)
+	}
+}
+
+func TestReconcileRepairsDrift(t *testing.T) {
+	missing, extra := uuid.New(), uuid.New()
+	graph := &snapshotGraph{snapshot: &Snapshot{Users: []uuid.UUID{extra}}}
+	source := &fixedSource{snapshot: &Snapshot{Users: []uuid.UUID{missing}}}
+
+	drift, err := Reconcile(context.Background(), source, graph, false)
+	if err != nil || drift.Empty() {
+		t.Fatalf("expected drift, got %+v %v", drift, err)
+	}
+	if len(graph.recorded()) != 0 {
+		t.Fatal("expected nothing repaired without being asked")
+	}
+
+	if _, err := Reconcile(context.Background(), source, graph, true); err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(graph.recorded()) != fmt.Sprint([]string{"merge user " + missing.String(), "delete user " + extra.String()}) {
+		t.Fatalf("unexpected repairs %v", graph.recorded())
+	}
+}
+
+func TestRepairCarriesOnPastFailures(t *testing.T) {
+	first, second := uuid.New(), uuid.New()
+	graph := &recordingGraph{fail: map[string]bool{"merge user " + first.String(): true}}
+
+	err := Repair(context.Background(), graph, &Drift{MissingUsers: []uuid.UUID{first, second}})
+	if err == nil {
+		t.Fatal("expected the failure to be returned")
+	}
+	if changes := graph.recorded(); len(changes) != 1 || changes[0] != "merge user "+second.String() {
+		t.Fatalf("expected the second user repaired, got %v", graph.recorded())
+	}
+}
+
+func TestReconcileKeepsExtraMatchesUntilBackfilled(t *testing.T) {
+	kept, missing := NewPair(uuid.New(), uuid.New()), uuid.New()
+	graph := &snapshotGraph{snapshot: &Snapshot{Matches: []Pair{kept}}}
+	source := &fixedSource{snapshot: &Snapshot{Users: []uuid.UUID{missing}}}
+
+	drift, err := Reconcile(context.Background(), source, graph, true)
+	if !errors.Is(err, ErrMatchesNotBackfilled) {
+		t.Fatalf("expected ErrMatchesNotBackfilled, got %v", err)
+	}
+	if len(drift.ExtraMatches) != 1 {
+		t.Fatalf("expected the extra match to be reported, got %+v", drift)
+	}
+	if fmt.Sprint(graph.recorded()) != fmt.Sprint([]string{"merge user " + missing.String()}) {
+		t.Fatalf("expected the rest repaired and the match kept, got %v", graph.recorded())
+	}
+
+	source.backfilled = true
+	graph.changes = nil
+	if _, err := Reconcile(context.Background(), source, graph, true); err != nil {
+		t.Fatal(err)
+	}
+	if indexOf(graph.recorded(), fmt.Sprintf("delete match %s %s", kept.A, kept.B)) < 0 {
+		t.Fatalf("expected the match deleted once backfilled, got %v", graph.recorded())
+	}
+}
+
+func TestBackfillCopiesTheGraphsMatchesOnce(t *testing.T) {
+	recorded, older := NewPair(uuid.New(), uuid.New()), NewPair(uuid.New(), uuid.New())
+	graph := &snapshotGraph{snapshot: &Snapshot{Matches: []Pair{recorded, older}}}
+	source := &fixedSource{snapshot: &Snapshot{Matches: []Pair{recorded}}}
+
+	added, err := BackfillMatches(context.Background(), source, graph)
+	if err != nil || added != 1 {
+		t.Fatalf("expected the older match added, got %d %v", added, err)
+	}
+	if drift, err := Reconcile(context.Background(), source, graph, true); err != nil || !drift.Empty() {
+		t.Fatalf("expected no drift once backfilled, got %+v %v", drift, err)
+	}
+
+	graph.snapshot.Matches = append(graph.snapshot.Matches, NewPair(uuid.New(), uuid.New()))
+	if added, err := BackfillMatches(context.Background(), source, graph); err != nil || added != 0 {
+		t.Fatalf("expected the backfill to run once, got %d %v", added, err)
+	}
+}
+
+type snapshotGraph struct {
+	recordingGraph
+	snapshot *Snapshot
+}
+
+func (graph *snapshotGraph) Snapshot(context.Context) (*Snapshot, error) {
+	return graph.snapshot, nil
+}
+
+type fixedSource struct {
+	snapshot   *Snapshot
+	backfilled bool
+}
+
+func (source *fixedSource) MatchesBackfilled(context.Context) (bool, error) {
+	return source.backfilled, nil
+}
+
+func (source *fixedSource) BackfillMatches(_ context.Context, matches []Pair) (int64, error) {
+	_, missing := difference(source.snapshot.Matches, matches, func(Pair) bool { return true })
+	source.snapshot.Matches = append(source.snapshot.Matches, missing...)
+	source.backfilled = true
+	return int64(len(missing)), nil
+}
+
+func (source *fixedSource) Snapshot(context.Context) (*Snapshot, error) {
+	return source.snapshot, nil
+}
+
+func (source *fixedSource) PendingAggregates(context.Context) (map[Aggregate]bool, error) {
+	return nil, nil
+}
@@ -0,0 +1,55 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/google/uuid"
+)
+
+// Graph is the Neo4j projection of users, opportunities, likes and matches. Every change must be idempotent, so an
+// event published twice or a repair of drift already fixed does no harm
+type Graph interface {
+	MergeUser(ctx context.Context, userUUID uuid.UUID) error
+	DeleteUser(ctx context.Context, userUUID uuid.UUID) error
+	MergeOpportunity(ctx context.Context, opportunityUUID uuid.UUID) error
+	DeleteOpportunity(ctx context.Context, opportunityUUID uuid.UUID) error
+	MergeLike(ctx context.Context, userUUID uuid.UUID, opportunityUUID uuid.UUID) error
+	DeleteLike(ctx context.Context, userUUID uuid.UUID, opportunityUUID uuid.UUID) error
+	MergeMatch(ctx context.Context, userUUID uuid.UUID, matchedUUID uuid.UUID) error
+	DeleteMatch(ctx context.Context, userUUID uuid.UUID, matchedUUID uuid.UUID) error
+	// Snapshot reads the whole projection, matches as ordered pairs
+	Snapshot(ctx context.Context) (*Snapshot, error)
+}
+
+// Project publishes the events to the graph through the relay
+func Project(relay *Relay, graph Graph) {
+	relay.Handle(UserCreated, handle(func(ctx context.Context, payload UserPayload) error {
+		return graph.MergeUser(ctx, payload.UserUUID)
+	}))
+	relay.Handle(OpportunityCreated, handle(func(ctx context.Context, payload OpportunityPayload) error {
+		return graph.MergeOpportunity(ctx, payload.OpportunityUUID)
+	}))
+	relay.Handle(OpportunityDeleted, handle(func(ctx context.Context, payload OpportunityPayload) error {
+		return graph.DeleteOpportunity(ctx, payload.OpportunityUUID)
+	}))
+	relay.Handle(OpportunityLiked, handle(func(ctx context.Context, payload LikePayload) error {
+		return graph.MergeLike(ctx, payload.UserUUID, payload.OpportunityUUID)
+	}))
+	relay.Handle(OpportunityUnliked, handle(func(ctx context.Context, payload LikePayload) error {
+		return graph.DeleteLike(ctx, payload.UserUUID, payload.OpportunityUUID)
+	}))
+	relay.Handle(UsersMatched, handle(func(ctx context.Context, payload MatchPayload) error {
+		return graph.MergeMatch(ctx, payload.UserUUID, payload.MatchedUUID)
+	}))
+}
+
+// handle decodes the event's payload for the handler
+func handle[T any](publish func(ctx context.Context, payload T) error) Handler {
+	return func(ctx context.Context, event Event) error {
+		var payload T
+		if err := json.Unmarshal(event.Payload, &payload); err != nil {
+			return err
+		}
+		return publish(ctx, payload)
+	}
+}
@@ -0,0 +1,164 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"github.com/google/uuid"
+)
+
+// Snapshot is every user, opportunity, like and match in one of the stores
+type Snapshot struct {
+	Users         []uuid.UUID
+	Opportunities []uuid.UUID
+	// Likes pair a user with the opportunity they liked
+	Likes []Pair
+	// Matches pair two users, ordered with NewPair
+	Matches []Pair
+}
+
+// ErrMatchesNotBackfilled is returned by Reconcile when repairing would delete matches from the graph that may only be
+// there because BackfillMatches hasn't copied them into MySQL yet. The rest of the drift is still repaired
+var ErrMatchesNotBackfilled = errors.New("the graph's matches haven't been backfilled into MySQL, extra matches were kept")
+
+// Source is the MySQL side of the projection
+type Source interface {
+	Snapshot(ctx context.Context) (*Snapshot, error)
+	// PendingAggregates returns the aggregates with events still to be published
+	PendingAggregates(ctx context.Context) (map[Aggregate]bool, error)
+	// MatchesBackfilled reports whether the graph's matches have been copied into MySQL, see BackfillMatches
+	MatchesBackfilled(ctx context.Context) (bool, error)
+}
+
+// Drift is what the graph is missing from MySQL, and what it has that MySQL doesn't
+type Drift struct {
+	MissingUsers         []uuid.UUID `json:"missingUsers"`
+	ExtraUsers           []uuid.UUID `json:"extraUsers"`
+	MissingOpportunities []uuid.UUID `json:"missingOpportunities"`
+	ExtraOpportunities   []uuid.UUID `json:"extraOpportunities"`
+	MissingLikes         []Pair      `json:"missingLikes"`
+	ExtraLikes           []Pair      `json:"extraLikes"`
+	MissingMatches       []Pair      `json:"missingMatches"`
+	ExtraMatches         []Pair      `json:"extraMatches"`
+}
+
+// Empty reports whether the graph matches MySQL
+func (drift *Drift) Empty() bool {
+	return len(drift.MissingUsers)+len(drift.ExtraUsers)+len(drift.MissingOpportunities)+
+		len(drift.ExtraOpportunities)+len(drift.MissingLikes)+len(drift.ExtraLikes)+len(drift.MissingMatches)+
+		len(drift.ExtraMatches) == 0
+}
+
+// Reconcile finds the drift between MySQL and the graph, and repairs it if asked. The graph is read before MySQL,
+// so a change made while reading is brought into the graph rather than undone. Aggregates with events still to be
+// published are left to the relay, and extra matches are only deleted once the matches have been backfilled
+func Reconcile(ctx context.Context, source Source, graph Graph, repair bool) (*Drift, error) {
+	graphSnapshot, err := graph.Snapshot(ctx)
+	if err != nil {
+		return nil, err
+	}
+	pending, err := source.PendingAggregates(ctx)
+	if err != nil {
+		return nil, err
+	}
+	sourceSnapshot, err := source.Snapshot(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	drift := Diff(sourceSnapshot, graphSnapshot, pending)
+	if !repair {
+		return drift, nil
+	}
+
+	if len(drift.ExtraMatches) == 0 {
+		return drift, Repair(ctx, graph, drift)
+	}
+	backfilled, err := source.MatchesBackfilled(ctx)
+	if err != nil {
+		return drift, err
+	}
+	if backfilled {
+		return drift, Repair(ctx, graph, drift)
+	}
+
+	kept := *drift
+	kept.ExtraMatches = nil
+	return drift, errors.Join(Repair(ctx, graph, &kept), ErrMatchesNotBackfilled)
+}
+
+// Diff compares the graph with MySQL, leaving out the pending aggregates
+func Diff(source *Snapshot, graph *Snapshot, pending map[Aggregate]bool) *Drift {
+	user := func(id uuid.UUID) bool { return !pending[Aggregate{UserAggregate, id.String()}] }
+	opportunity := func(id uuid.UUID) bool { return !pending[Aggregate{OpportunityAggregate, id.String()}] }
+	like := func(pair Pair) bool { return opportunity(pair.B) }
+	match := func(pair Pair) bool { return !pending[Aggregate{MatchAggregate, pair.key()}] }
+
+	drift := &Drift{}
+	drift.MissingUsers, drift.ExtraUsers = difference(source.Users, graph.Users, user)
+	drift.MissingOpportunities, drift.ExtraOpportunities = difference(source.Opportunities, graph.Opportunities, opportunity)
+	drift.MissingLikes, drift.ExtraLikes = difference(source.Likes, graph.Likes, like)
+	drift.MissingMatches, drift.ExtraMatches = difference(source.Matches, graph.Matches, match)
+	return drift
+}
+
+// difference returns what's only in want and what's only in have, of the items to check
+func difference[T comparable](want []T, have []T, check func(T) bool) (missing []T, extra []T) {
+	wanted := make(map[T]bool, len(want))
+	for _, item := range want {
+		wanted[item] = true
+	}
+	had := make(map[T]bool, len(have))
+	for _, item := range have {
+		had[item] = true
+	}
+
+	for _, item := range want {
+		if !had[item] && check(item) {
+			missing = append(missing, item)
+			had[item] = true
+		}
+	}
+	for _, item := range have {
+		if !wanted[item] && check(item) {
+			extra = append(extra, item)
+			wanted[item] = true
+		}
+	}
+	return missing, extra
+}
+
+// Repair brings the graph in line with MySQL, carrying on past failures and returning them together
+func Repair(ctx context.Context, graph Graph, drift *Drift) error {
+	var errs []error
+	apply := func(err error) {
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	for _, id := range drift.MissingUsers {
+		apply(graph.MergeUser(ctx, id))
+	}
+	for _, id := range drift.MissingOpportunities {
+		apply(graph.MergeOpportunity(ctx, id))
+	}
+	for _, pair := range drift.MissingLikes {
+		apply(graph.MergeLike(ctx, pair.A, pair.B))
+	}
+	for _, pair := range drift.MissingMatches {
+		apply(graph.MergeMatch(ctx, pair.A, pair.B))
+	}
+	for _, pair := range drift.ExtraLikes {
+		apply(graph.DeleteLike(ctx, pair.A, pair.B))
+	}
+	for _, pair := range drift.ExtraMatches {
+		apply(graph.DeleteMatch(ctx, pair.A, pair.B))
+	}
+	for _, id := range drift.ExtraOpportunities {
+		apply(graph.DeleteOpportunity(ctx, id))
+	}
+	for _, id := range drift.ExtraUsers {
+		apply(graph.DeleteUser(ctx, id))
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,191 @@
+package outbox
+
+import (
+	"backend/internal/queue"
+	"backend/internal/utils/concurrency"
+	"context"
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"time"
+)
+
+// storeTimeout bounds each call to the store
+const storeTimeout = 10 * time.Second
+
+// Handler publishes an event, it must be safe to run again for an event it already published
+type Handler func(ctx context.Context, event Event) error
+
+// RelayOption configures a Relay
+type RelayOption func(relay *Relay)
+
+// WithBatchSize sets how many events are read from the outbox at a time, 100 by default
+func WithBatchSize(size int) RelayOption {
+	return func(relay *Relay) {
+		relay.batchSize = size
+	}
+}
+
+// WithWorkers sets how many aggregates are published at once, 4 by default
+func WithWorkers(workers int) RelayOption {
+	return func(relay *Relay) {
+		relay.workers = workers
+	}
+}
+
+// WithBackoff sets how long to wait before retrying an event that failed on the attempt, ExponentialBackoff(1
+// second, 10 minutes) by default
+func WithBackoff(backoff func(attempt int) time.Duration) RelayOption {
+	return func(relay *Relay) {
+		relay.backoff = backoff
+	}
+}
+
+// Relay publishes the events in the outbox with the handlers of their types
+type Relay struct {
+	store     Store
+	handlers  map[string]Handler
+	batchSize int
+	workers   int
+	backoff   func(attempt int) time.Duration
+}
+
+func NewRelay(store Store, options ...RelayOption) *Relay {
+	relay := &Relay{
+		store:     store,
+		handlers:  map[string]Handler{},
+		batchSize: 100,
+		workers:   4,
+		backoff:   queue.ExponentialBackoff(time.Second, 10*time.Minute),
+	}
+	for _, option := range options {
+		option(relay)
+	}
+	return relay
+}
+
+// Handle publishes events of the type with the handler. Handlers are added before the relay runs
+func (relay *Relay) Handle(eventType string, handler Handler) {
+	relay.handlers[eventType] = handler
+}
+
+// Publish publishes the events due until none are left, returning how many were published. Aggregates are
+// published concurrently, each one's events in order
+func (relay *Relay) Publish(ctx context.Context) (int, error) {
+	published := 0
+	for ctx.Err() == nil {
+		storeCtx, cancel := context.WithTimeout(ctx, storeTimeout)
+		events, err := relay.store.Pending(storeCtx, time.Now(), relay.batchSize)
+		cancel()
+		if err != nil {
+			return published, err
+		}
+		if len(events) == 0 {
+			return published, nil
+		}
+
+		count, err := relay.publishBatch(ctx, events)
+		published += count
+		if err != nil || count == 0 || len(events) < relay.batchSize {
+			// The rest failed or are held back behind those that did
+			return published, err
+		}
+	}
+	return published, ctx.Err()
+}
+
+// publishBatch publishes the events of each aggregate in order, stopping at the first that fails
+func (relay *Relay) publishBatch(ctx context.Context, events []Event) (int, error) {
+	var order []Aggregate
+	byAggregate := map[Aggregate][]Event{}
+	for _, event := range events {
+		if _, ok := byAggregate[event.Aggregate]; !ok {
+			order = append(order, event.Aggregate)
+		}
+		byAggregate[event.Aggregate] = append(byAggregate[event.Aggregate], event)
+	}
+
+	pool := concurrency.NewThreadPool(relay.workers, len(order))
+	pool.Start()
+
+	results := make(chan int, len(order))
+	for _, aggregate := range order {
+		aggregateEvents := byAggregate[aggregate]
+		err := pool.SubmitContext(ctx, func(ctx context.Context) {
+			results <- relay.publishAggregate(ctx, aggregateEvents)
+		})
+		if err != nil {
+			results <- 0
+		}
+	}
+
+	err := pool.Shutdown(context.Background())
+	close(results)
+
+	published := 0
+	for count := range results {
+		published += count
+	}
+	return published, err
+}
+
+// publishAggregate publishes the events of one aggregate in order, returning how many were published
+func (relay *Relay) publishAggregate(ctx context.Context, events []Event) int {
+	for i, event := range events {
+		if ctx.Err() != nil {
+			return i
+		}
+
+		err := relay.publish(ctx, event)
+
+		storeCtx, cancel := context.WithTimeout(context.Background(), storeTimeout)
+		if err == nil {
+			err = relay.store.Published(storeCtx, event)
+			cancel()
+			if err != nil {
+				// Published again next time, which handlers allow for
+				log.Errorf("Unable to remove published event %d: %s", event.ID, err)
+				return i
+			}
+			continue
+		}
+
+		attempt := event.Attempts + 1
+		log.Warnf("Unable to publish event %d (%s of %s %s) on attempt %d: %s", event.ID, event.Type,
+			event.Aggregate.Type, event.Aggregate.ID, attempt, err)
+		if err := relay.store.Failed(storeCtx, event, time.Now().Add(relay.backoff(attempt)), err.Error()); err != nil {
+			log.Errorf("Unable to record the failure of event %d: %s", event.ID, err)
+		}
+		cancel()
+		return i
+	}
+	return len(events)
+}
+
+// publish runs the event's handler, returning a panic as an error
+func (relay *Relay) publish(ctx context.Context, event Event) (err error) {
+	handler, ok := relay.handlers[event.Type]
+	if !ok {
+		return fmt.Errorf("no handler for events of type %s", event.Type)
+	}
+
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			err = fmt.Errorf("panicked: %v", recovered)
+		}
+	}()
+	return handler(ctx, event)
+}
+
+// Job publishes the outbox every interval. With a Locker on the scheduler only one instance publishes at a time,
+// which keeps each aggregate's events in order
+func (relay *Relay) Job(interval time.Duration) concurrency.Job {
+	return concurrency.Job{
+		Name:        "publish-outbox",
+		Schedule:    concurrency.Every(interval),
+		Immediately: true,
+		Run: func(ctx context.Context) error {
+			_, err := relay.Publish(ctx)
+			return err
+		},
+	}
+}
@@ -0,0 +1,200 @@
+// Package queue runs background jobs that must survive a restart, such as sending email or syncing data to Neo4j.
+//
+// Jobs are stored before they're run and each is run at least once. A worker leases the jobs it claims, and a job
+// whose lease runs out before it's completed, e.g. because the process crashed, is claimed again. Handlers must
+// therefore cope with running the same job twice. A failed job is retried with exponential backoff until it has
+// used its attempts, then moved to the dead letters
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ErrLeaseLost is returned when completing or retrying a job whose lease ran out and that was claimed again
+var ErrLeaseLost = errors.New("the job's lease ran out and it was claimed again")
+
+// Job is a stored job
+type Job struct {
+	ID      int64
+	Type    string
+	Payload []byte
+	// Key makes enqueueing idempotent, a job with the same key as one enqueued before isn't added. Empty for none
+	Key string
+	// Attempts counts the runs so far, including the one in progress
+	Attempts    int
+	MaxAttempts int
+	RunAt       time.Time
+	// Lease identifies the worker's claim on the job
+	Lease     string
+	LastError string
+}
+
+// Store keeps the jobs
+type Store interface {
+	// Enqueue adds the job, returning false without adding it if a job with the same key was enqueued before
+	Enqueue(ctx context.Context, job Job) (bool, error)
+	// Claim leases up to limit jobs due at now and counts an attempt of each. A job is due again once its lease
+	// has passed without it being completed
+	Claim(ctx context.Context, now time.Time, limit int, lease time.Duration) ([]Job, error)
+	Complete(ctx context.Context, job Job) error
+	// Retry puts the job back to run at runAt
+	Retry(ctx context.Context, job Job, runAt time.Time, reason string) error
+	// DeadLetter moves the job out of the queue into the dead letters
+	DeadLetter(ctx context.Context, job Job, reason string) error
+	// PurgeCompleted forgets jobs completed before the time, after which their keys can be enqueued again
+	PurgeCompleted(ctx context.Context, before time.Time) (int64, error)
+}
+
+// TransactionalStore is a store whose Enqueue joins the mysql.WithTx transaction in its context, so a job enqueued
+// in a transaction is only added if it commits
+type TransactionalStore interface {
+	Store
+	EnqueuesInTransaction()
+}
+
+// Type is a kind of job with a payload of T, the name is stored with each job to find its handler
+type Type[T any] struct {
+	Name string
+}
+
+func NewType[T any](name string) Type[T] {
+	return Type[T]{Name: name}
+}
+
+// EnqueueOption configures an enqueued job
+type EnqueueOption func(job *Job)
+
+// WithKey makes enqueueing idempotent, the job isn't added if one with the key was enqueued before
+func WithKey(key string) EnqueueOption {
+	return func(job *Job) {
+		job.Key = key
+	}
+}
+
+// WithDelay runs the job no sooner than delay from now
+func WithDelay(delay time.Duration) EnqueueOption {
+	return func(job *Job) {
+		job.RunAt = job.RunAt.Add(delay)
+	}
+}
+
+// WithMaxAttempts sets how many times the job is run before it's dead lettered, instead of the queue's MaxAttempts
+func WithMaxAttempts(attempts int) EnqueueOption {
+	return func(job *Job) {
+		job.MaxAttempts = attempts
+	}
+}
+
+// permanentError is an error that running the job again won't fix
+type permanentError struct {
+	error
+}
+
+func (err permanentError) Unwrap() error {
+	return err.error
+}
+
+// Permanent marks a handler's error as one retrying won't fix, e.g. the opportunity no longer exists. The job is
+// dead lettered straight away
+func Permanent(err error) error {
+	return permanentError{err}
+}
+
+// IsPermanent reports whether the error was marked with Permanent
+func IsPermanent(err error) bool {
+	var permanent permanentError
+	return errors.As(err, &permanent)
+}
+
+type handler func(ctx context.Context, payload []byte) error
+
+// Queue enqueues jobs and, once started, runs them with the handlers of their types
+type Queue struct {
+	store  Store
+	config Config
+
+	mutex    sync.RWMutex
+	handlers map[string]handler
+
+	// wake tells the worker a job was enqueued or a worker became free, so it claims without waiting to poll
+	wake chan struct{}
+	// worker is set once started
+	worker *worker
+}
+
+// Default is the application's queue, set by Configure
+var Default *Queue
+
+// Configure sets Default to a queue of jobs kept in the store
+func Configure(store Store, config Config) *Queue {
+	Default = New(store, config)
+	return Default
+}
+
+func New(store Store, config Config) *Queue {
+	return &Queue{
+		store:    store,
+		config:   config.withDefaults(),
+		handlers: map[string]handler{},
+		wake:     make(chan struct{}, 1),
+	}
+}
+
+// Enqueue stores a job of the type to run as soon as a worker is free, or later with WithDelay. It returns false if
+// the job wasn't added because its key had been enqueued before
+func Enqueue[T any](ctx context.Context, queue *Queue, jobType Type[T], payload T, options ...EnqueueOption) (bool, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return false, fmt.Errorf("encoding %s payload: %w", jobType.Name, err)
+	}
+
+	job := Job{Type: jobType.Name, Payload: data, MaxAttempts: queue.config.MaxAttempts, RunAt: time.Now()}
+	for _, option := range options {
+		option(&job)
+	}
+
+	created, err := queue.store.Enqueue(ctx, job)
+	if created && !job.RunAt.After(time.Now()) {
+		queue.notify()
+	}
+	return created, err
+}
+
+// Handle runs jobs of the type with the handler. A payload that can't be decoded is dead lettered
+func Handle[T any](queue *Queue, jobType Type[T], run func(ctx context.Context, payload T) error) {
+	queue.mutex.Lock()
+	defer queue.mutex.Unlock()
+
+	queue.handlers[jobType.Name] = func(ctx context.Context, data []byte) error {
+		var payload T
+		if err := json.Unmarshal(data, &payload); err != nil {
+			return Permanent(fmt.Errorf("decoding %s payload: %w", jobType.Name, err))
+		}
+		return run(ctx, payload)
+	}
+}
+
+// Transactional reports whether jobs enqueued inside mysql.WithTx are only added if the transaction commits. Other
+// stores add them straight away, where a worker may run them before the transaction's changes are visible
+func (queue *Queue) Transactional() bool {
+	_, ok := queue.store.(TransactionalStore)
+	return ok
+}
+
+func (queue *Queue) handler(jobType string) handler {
+	queue.mutex.RLock()
+	defer queue.mutex.RUnlock()
+	return queue.handlers[jobType]
+}
+
+func (queue *Queue) notify() {
+	select {
+	case queue.wake <- struct{}{}:
+	default:
+	}
+}
@@ -0,0 +1,437 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// memoryStore keeps jobs in memory, leasing them like the MySQL and Redis stores
+type memoryStore struct {
+	mutex     sync.Mutex
+	nextID    int64
+	jobs      map[int64]*Job
+	keys      map[string]bool
+	completed map[int64]time.Time
+	dead      []Job
+	leases    int
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{jobs: map[int64]*Job{}, keys: map[string]bool{}, completed: map[int64]time.Time{}}
+}
+
+func (store *memoryStore) Enqueue(_ context.Context, job Job) (bool, error) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	if job.Key != "" {
+		if store.keys[job.Key] {
+			return false, nil
+		}
+		store.keys[job.Key] = true
+	}
+	store.nextID++
+	job.ID = store.nextID
+	store.jobs[job.ID] = &job
+	return true, nil
+}
+
+func (store *memoryStore) Claim(_ context.Context, now time.Time, limit int, lease time.Duration) ([]Job, error) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	var claimed []Job
+	for id := int64(1); id <= store.nextID && len(claimed) < limit; id++ {
+		job, ok := store.jobs[id]
+		if !ok || job.RunAt.After(now) {
+			continue
+		}
+		if _, done := store.completed[id]; done {
+			continue
+		}
+		store.leases++
+		job.Attempts++
+		job.Lease = string(rune('a' + store.leases))
+		job.RunAt = now.Add(lease)
+		claimed = append(claimed, *job)
+	}
+	return claimed, nil
+}
+
+func (store *memoryStore) leased(job Job) (*Job, error) {
+	stored, ok := store.jobs[job.ID]
+	if !ok || stored.Lease != job.Lease {
+		return nil, ErrLeaseLost
+	}
+	return stored, nil
+}
+
+func (store *memoryStore) Complete(_ context.Context, job Job) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	stored, err := store.leased(job)
+	if err != nil {
+		return err
+	}
+	stored.Lease = ""
+	store.completed[job.ID] = time.Now()
+	return nil
+}
+
+func (store *memoryStore) Retry(_ context.Context, job Job, runAt time.Time, reason string) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	stored, err := store.leased(job)
+	if err != nil {
+		return err
+	}
+	stored.Lease = ""
+	stored.RunAt = runAt
+	stored.LastError = reason
+	return nil
+}
+
+func (store *memoryStore) DeadLetter(_ context.Context, job Job, reason string) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	if _, err := store.leased(job); err != nil {
+		return err
+	}
+	delete(store.jobs, job.ID)
+	delete(store.keys, job.Key)
+	job.LastError = reason
+	store.dead = append(store.dead, job)
+	return nil
+}
+
+func (store *memoryStore) PurgeCompleted(_ context.Context, before time.Time) (int64, error) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	var purged int64
+	for id, completedAt := range store.completed {
+		if completedAt.Before(before) {
+			delete(store.keys, store.jobs[id].Key)
+			delete(store.jobs, id)
+			delete(store.completed, id)
+			purged++
+		}
+	}
+	return purged, nil
+}
+
+func (store *memoryStore) state() (completed int, dead []Job) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	return len(store.completed), append([]Job(nil), store.dead...)
+}
+
+type greeting struct {
+	Name string `json:"name"`
+}
+
+var greet = NewType[greeting]("greet")
+
+func newTestQueue(t *testing.T, store Store, config Config) *Queue {
+	t.Helper()
+	if config.PollInterval == 0 {
+		config.PollInterval = 10 * time.Millisecond
+	}
+	if config.Backoff == nil {
+		config.Backoff = func(int) time.Duration { return 0 }
+	}
+	queue := New(store, config)
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		queue.Shutdown(ctx)
+	})
+	return queue
+}
+
+// eventually waits for the condition to hold
+func eventually(t *testing.T, condition func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met in time")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestHandlerReceivesPayload(t *testing.T) {
+	store := newMemoryStore()
+	queue := newTestQueue(t, store, Config{})
+
+	received := make(chan string, 1)
+	Handle(queue, greet, func(_ context.Context, payload greeting) error {
+		received <- payload.Name
+		return nil
+	})
+	queue.Start()
+
+	if _, err := Enqueue(context.Background(), queue, greet, greeting{Name: "Ada"}); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case name := <-received:
+		if name != "Ada" {
+			t.Fatalf("expected Ada, got %s", name)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("job never ran")
+	}
+	eventually(t, func() bool {
+		completed, _ := store.state()
+		return completed == 1
+	})
+}
+
+func TestFailedJobIsRetriedWithBackoff(t *testing.T) {
+	store := newMemoryStore()
+
+	var mutex sync.Mutex
+	var backoffs []int
+	queue := newTestQueue(t, store, Config{Backoff: func(attempt int) time.Duration {
+		mutex.Lock()
+		defer mutex.Unlock()
+		backoffs = append(backoffs, attempt)
+		return time.Millisecond
+	}})
+
+	attempts := 0
+	Handle(queue, greet, func(_ context.Context, _ greeting) error {
+		mutex.Lock()
+		defer mutex.Unlock()
+		attempts++
+		if attempts < 3 {
+			return errors.New("try again")
+		}
+		return nil
+	})
+	queue.Start()
+	Enqueue(context.Background(), queue, greet, greeting{})
+
+	eventually(t, func() bool {
+		completed, _ := store.state()
+		return completed == 1
+	})
+
+	mutex.Lock()
+	defer mutex.Unlock()
+	if attempts != 3 || len(backoffs) != 2 || backoffs[0] != 1 || backoffs[1] != 2 {
+		t.Fatalf("expected 3 attempts backing off after the first two, got %d attempts and backoffs %v", attempts, backoffs)
+	}
+}
+
+func TestJobIsDeadLetteredAfterMaxAttempts(t *testing.T) {
+	store := newMemoryStore()
+	queue := newTestQueue(t, store, Config{MaxAttempts: 3})
+
+	Handle(queue, greet, func(_ context.Context, _ greeting) error {
+		return errors.New("always fails")
+	})
+	queue.Start()
+	Enqueue(context.Background(), queue, greet, greeting{})
+
+	eventually(t, func() bool {
+		_, dead := store.state()
+		return len(dead) == 1
+	})
+	_, dead := store.state()
+	if dead[0].Attempts != 3 || dead[0].LastError != "always fails" {
+		t.Fatalf("expected the dead letter after 3 attempts with the error, got %+v", dead[0])
+	}
+}
+
+func TestPermanentErrorIsDeadLetteredStraightAway(t *testing.T) {
+	store := newMemoryStore()
+	queue := newTestQueue(t, store, Config{})
+
+	Handle(queue, greet, func(_ context.Context, _ greeting) error {
+		return Permanent(errors.New("no such opportunity"))
+	})
+	queue.Start()
+	Enqueue(context.Background(), queue, greet, greeting{})
+
+	eventually(t, func() bool {
+		_, dead := store.state()
+		return len(dead) == 1
+	})
+	_, dead := store.state()
+	if dead[0].Attempts != 1 {
+		t.Fatalf("expected a single attempt, got %d", dead[0].Attempts)
+	}
+}
+
+func TestUndecodablePayloadIsPermanent(t *testing.T) {
+	store := newMemoryStore()
+	queue := newTestQueue(t, store, Config{})
+
+	Handle(queue, greet, func(_ context.Context, _ greeting) error {
+		t.Error("handler ran with an invalid payload")
+		return nil
+	})
+	queue.Start()
+	store.Enqueue(context.Background(), Job{Type: greet.Name, Payload: []byte("not json"), MaxAttempts: 5})
+	queue.notify()
+
+	eventually(t, func() bool {
+		_, dead := store.state()
+		return len(dead) == 1
+	})
+}
+
+func TestPanicIsRetried(t *testing.T) {
+	store := newMemoryStore()
+	queue := newTestQueue(t, store, Config{})
+
+	panicked := false
+	Handle(queue, greet, func(_ context.Context, _ greeting) error {
+		if !panicked {
+			panicked = true
+			panic("boom")
+		}
+		return nil
+	})
+	queue.Start()
+	Enqueue(context.Background(), queue, greet, greeting{})
+
+	eventually(t, func() bool {
+		completed, _ := store.state()
+		return completed == 1
+	})
+}
+
+func TestEnqueueWithKeyIsIdempotent(t *testing.T) {
+	queue := newTestQueue(t, newMemoryStore(), Config{})
+
+	created, err := Enqueue(context.Background(), queue, greet, greeting{}, WithKey("welcome:1"))
+	if err != nil || !created {
+		t.Fatalf("expected the first job to be created, got %v %v", created, err)
+	}
+
+	created, err = Enqueue(context.Background(), queue, greet, greeting{}, WithKey("welcome:1"))
+	if err != nil || created {
+		t.Fatalf("expected the second job with the key to be skipped, got %v %v", created, err)
+	}
+}
+
+func TestDelayedJobWaits(t *testing.T) {
+	store := newMemoryStore()
+	queue := newTestQueue(t, store, Config{})
+
+	ran := make(chan time.Time, 1)
+	Handle(queue, greet, func(_ context.Context, _ greeting) error {
+		ran <- time.Now()
+		return nil
+	})
+	queue.Start()
+
+	enqueued := time.Now()
+	Enqueue(context.Background(), queue, greet, greeting{}, WithDelay(100*time.Millisecond))
+
+	select {
+	case at := <-ran:
+		if at.Sub(enqueued) < 100*time.Millisecond {
+			t.Fatalf("job ran after %s, before its delay", at.Sub(enqueued))
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("job never ran")
+	}
+}
+
+func TestShutdownWaitsForRunningJobs(t *testing.T) {
+	store := newMemoryStore()
+	queue := newTestQueue(t, store, Config{})
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	Handle(queue, greet, func(_ context.Context, _ greeting) error {
+		close(started)
+		<-release
+		return nil
+	})
+	queue.Start()
+	Enqueue(context.Background(), queue, greet, greeting{})
+	<-started
+
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		close(release)
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := queue.Shutdown(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if completed, _ := store.state(); completed != 1 {
+		t.Fatal("expected the running job to complete before shutdown returned")
+	}
+}
+
+func TestShutdownCancelsJobsOnTimeout(t *testing.T) {
+	store := newMemoryStore()
+	queue := newTestQueue(t, store, Config{})
+
+	started := make(chan struct{})
+	cancelled := make(chan struct{})
+	Handle(queue, greet, func(ctx context.Context, _ greeting) error {
+		close(started)
+		<-ctx.Done()
+		close(cancelled)
+		return ctx.Err()
+	})
+	queue.Start()
+	Enqueue(context.Background(), queue, greet, greeting{})
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := queue.Shutdown(ctx); err == nil {
+		t.Fatal("expected shutdown to time out")
+	}
+
+	select {
+	case <-cancelled:
+	case <-time.After(5 * time.Second):
+		t.Fatal("running job wasn't cancelled")
+	}
+}
+
+func TestExpiredLeaseIsClaimedAgain(t *testing.T) {
+	store := newMemoryStore()
+	store.Enqueue(context.Background(), Job{Type: greet.Name, Payload: []byte("{}"), MaxAttempts: 5})
+
+	now := time.Now()
+	first, _ := store.Claim(context.Background(), now, 1, time.Minute)
+	if again, _ := store.Claim(context.Background(), now, 1, time.Minute); len(again) != 0 {
+		t.Fatal("expected a leased job not to be claimed again")
+	}
+
+	second, _ := store.Claim(context.Background(), now.Add(2*time.Minute), 1, time.Minute)
+	if len(first) != 1 || len(second) != 1 || second[0].Attempts != 2 {
+		t.Fatalf("expected the job to be claimed again once its lease passed, got %v", second)
+	}
+
+	queue := newTestQueue(t, store, Config{})
+	queue.process(context.Background(), first[0])
+	if completed, _ := store.state(); completed != 0 {
+		t.Fatal("expected the first claim's outcome to be discarded")
+	}
+}
+
+func TestExponentialBackoff(t *testing.T) {
+	backoff := ExponentialBackoff(time.Second, time.Minute)
+	for attempt, expected := range map[int]time.Duration{1: time.Second, 2: 2 * time.Second, 4: 8 * time.Second, 50: time.Minute} {
+		wait := backoff(attempt)
+		if wait < expected || wait > expected+expected/10 {
+			t.Fatalf("attempt %d: expected %s plus up to a tenth, got %s", attempt, expected, wait)
+		}
+	}
+}
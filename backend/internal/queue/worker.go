@@ -0,0 +1,244 @@
+package queue
+
+import (
+	"backend/internal/utils/concurrency"
+	"context"
+	"errors"
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"math/rand/v2"
+	"sync/atomic"
+	"time"
+)
+
+// storeTimeout bounds each call to the store
+const storeTimeout = 10 * time.Second
+
+// Config tunes the queue, unset fields take the defaults
+type Config struct {
+	// Workers is how many jobs run at once, 4 by default
+	Workers int
+	// PollInterval is how often the store is checked for due jobs, 1 second by default. Jobs enqueued by this
+	// instance are picked up straight away
+	PollInterval time.Duration
+	// Lease is how long a run has before the job is claimed again, 5 minutes by default. The handler's context is
+	// cancelled once it passes
+	Lease time.Duration
+	// MaxAttempts is how many times a job is run before it's dead lettered, 10 by default
+	MaxAttempts int
+	// Backoff is how long to wait before retrying a job that failed on the attempt, ExponentialBackoff(10 seconds,
+	// 1 hour) by default
+	Backoff func(attempt int) time.Duration
+}
+
+func (config Config) withDefaults() Config {
+	if config.Workers <= 0 {
+		config.Workers = 4
+	}
+	if config.PollInterval <= 0 {
+		config.PollInterval = time.Second
+	}
+	if config.Lease <= 0 {
+		config.Lease = 5 * time.Minute
+	}
+	if config.MaxAttempts <= 0 {
+		config.MaxAttempts = 10
+	}
+	if config.Backoff == nil {
+		config.Backoff = ExponentialBackoff(10*time.Second, time.Hour)
+	}
+	return config
+}
+
+// ExponentialBackoff doubles the wait after each attempt from base up to limit, adding up to a tenth again so jobs
+// that failed together don't retry together
+func ExponentialBackoff(base time.Duration, limit time.Duration) func(attempt int) time.Duration {
+	return func(attempt int) time.Duration {
+		backoff := base
+		for i := 1; i < attempt && backoff < limit; i++ {
+			backoff *= 2
+		}
+		backoff = min(backoff, limit)
+		return backoff + rand.N(backoff/10+1)
+	}
+}
+
+// worker claims due jobs and runs them on a thread pool
+type worker struct {
+	pool     *concurrency.ThreadPool
+	inFlight atomic.Int64
+	// ctx is the context of every run, cancelled if shutting down runs out of time
+	ctx    context.Context
+	cancel context.CancelFunc
+	stop   chan struct{}
+	// stopped is closed once the worker has stopped claiming
+	stopped chan struct{}
+}
+
+// Start starts running jobs. Handlers can be added before or after
+func (queue *Queue) Start() {
+	queue.mutex.Lock()
+	defer queue.mutex.Unlock()
+	if queue.worker != nil {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	queue.worker = &worker{
+		pool:    concurrency.NewThreadPool(queue.config.Workers, queue.config.Workers),
+		ctx:     ctx,
+		cancel:  cancel,
+		stop:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+	queue.worker.pool.Start()
+	go queue.claim(queue.worker)
+}
+
+// Shutdown stops claiming jobs and waits for those running to finish. If ctx is done first the runs are cancelled,
+// and their jobs are run again once their leases pass
+func (queue *Queue) Shutdown(ctx context.Context) error {
+	queue.mutex.Lock()
+	worker := queue.worker
+	queue.mutex.Unlock()
+	if worker == nil {
+		return nil
+	}
+
+	select {
+	case <-worker.stop:
+	default:
+		close(worker.stop)
+	}
+	<-worker.stopped
+
+	err := worker.pool.Shutdown(ctx)
+	if err != nil {
+		worker.cancel()
+		log.Warnf("Cancelled %d jobs still running at shutdown", worker.inFlight.Load())
+	}
+	return err
+}
+
+// claim claims as many jobs as there are free workers whenever woken or the poll interval passes, until stopped
+func (queue *Queue) claim(worker *worker) {
+	defer close(worker.stopped)
+
+	ticker := time.NewTicker(queue.config.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		free := int64(queue.config.Workers) - worker.inFlight.Load()
+		if free > 0 {
+			claimed, err := queue.claimBatch(worker, int(free))
+			if err != nil {
+				log.Error("Unable to claim jobs: ", err)
+			}
+			// There may be more due, carry on once a worker is free
+			if claimed == int(free) {
+				select {
+				case <-worker.stop:
+					return
+				default:
+					continue
+				}
+			}
+		}
+
+		select {
+		case <-worker.stop:
+			return
+		case <-queue.wake:
+		case <-ticker.C:
+		}
+	}
+}
+
+func (queue *Queue) claimBatch(worker *worker, limit int) (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), storeTimeout)
+	defer cancel()
+
+	jobs, err := queue.store.Claim(ctx, time.Now(), limit, queue.config.Lease)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, job := range jobs {
+		worker.inFlight.Add(1)
+		err := worker.pool.SubmitContext(worker.ctx, func(ctx context.Context) {
+			defer func() {
+				worker.inFlight.Add(-1)
+				queue.notify()
+			}()
+			queue.process(ctx, job)
+		})
+		if err != nil {
+			// Claimed again once the lease passes
+			worker.inFlight.Add(-1)
+			log.Errorf("Unable to run job %d: %s", job.ID, err)
+		}
+	}
+	return len(jobs), nil
+}
+
+// process runs the job and records the outcome
+func (queue *Queue) process(ctx context.Context, job Job) {
+	err := queue.run(ctx, job)
+
+	storeCtx, cancel := context.WithTimeout(context.Background(), storeTimeout)
+	defer cancel()
+
+	var storeErr error
+	switch {
+	case err == nil:
+		storeErr = queue.store.Complete(storeCtx, job)
+	case IsPermanent(err) || job.Attempts >= job.MaxAttempts:
+		log.Errorf("Job %d (%s) failed for good after %d attempts: %s", job.ID, job.Type, job.Attempts, err)
+		storeErr = queue.store.DeadLetter(storeCtx, job, err.Error())
+	default:
+		log.Warnf("Job %d (%s) failed on attempt %d, retrying: %s", job.ID, job.Type, job.Attempts, err)
+		storeErr = queue.store.Retry(storeCtx, job, time.Now().Add(queue.config.Backoff(job.Attempts)), err.Error())
+	}
+
+	if errors.Is(storeErr, ErrLeaseLost) {
+		log.Warnf("Job %d (%s) took longer than its lease and was claimed again", job.ID, job.Type)
+	} else if storeErr != nil {
+		// Claimed again once the lease passes
+		log.Errorf("Unable to record the outcome of job %d: %s", job.ID, storeErr)
+	}
+}
+
+// run runs the job's handler within its lease, returning a panic as an error
+func (queue *Queue) run(ctx context.Context, job Job) (err error) {
+	handler := queue.handler(job.Type)
+	if handler == nil {
+		// Possibly enqueued by a newer version, another instance may be able to run it
+		return fmt.Errorf("no handler for jobs of type %s", job.Type)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, queue.config.Lease)
+	defer cancel()
+
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			err = fmt.Errorf("panicked: %v", recovered)
+		}
+	}()
+	return handler(ctx, job.Payload)
+}
+
+// PurgeJob forgets jobs completed more than retention ago every day. Until then their keys can't be enqueued again
+func (queue *Queue) PurgeJob(retention time.Duration) concurrency.Job {
+	return concurrency.Job{
+		Name:     "purge-completed-jobs",
+		Schedule: concurrency.MustParseCron("@daily"),
+		Jitter:   time.Hour,
+		Run: func(ctx context.Context) error {
+			purged, err := queue.store.PurgeCompleted(ctx, time.Now().Add(-retention))
+			if purged > 0 {
+				log.Infof("Purged %d completed jobs", purged)
+			}
+			return err
+		},
+	}
+}
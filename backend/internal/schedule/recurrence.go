@@ -0,0 +1,170 @@
+package schedule
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type Frequency int
+
+const (
+	Daily Frequency = iota
+	Weekly
+	Monthly
+)
+
+func (frequency Frequency) String() string {
+	return [...]string{"DAILY", "WEEKLY", "MONTHLY"}[frequency]
+}
+
+func ParseFrequency(s string) (Frequency, error) {
+	switch s {
+
+	case "DAILY":
+		return Daily, nil
+	case "WEEKLY":
+		return Weekly, nil
+	case "MONTHLY":
+		return Monthly, nil
+	default:
+		return -1, fmt.Errorf("unsupported frequency: %s", s)
+
+	}
+}
+
+// maxCount bounds COUNT so a rule can't describe an unreasonable number of occurrences
+const maxCount = 1000
+
+// Recurrence is the subset of an RFC 5545 RRULE opportunities can repeat on, e.g. FREQ=WEEKLY;BYDAY=SA;COUNT=10
+type Recurrence struct {
+	Frequency Frequency
+	// Interval is how many days, weeks or months between repeats, at least 1
+	Interval int
+	// Count is the total number of occurrences including the first, 0 if unbounded
+	Count int
+	// Until is the last time an occurrence can start, nil if unbounded
+	Until *time.Time
+	// ByDay are the weekdays a weekly rule repeats on, the start's weekday if empty
+	ByDay []time.Weekday
+}
+
+var weekdayCodes = map[string]time.Weekday{
+	"SU": time.Sunday, "MO": time.Monday, "TU": time.Tuesday, "WE": time.Wednesday,
+	"TH": time.Thursday, "FR": time.Friday, "SA": time.Saturday,
+}
+
+// ParseRecurrence parses an RRULE, with or without the "RRULE:" prefix
+func ParseRecurrence(rule string) (*Recurrence, error) {
+	rule = strings.TrimPrefix(strings.TrimSpace(rule), "RRULE:")
+	if rule == "" {
+		return nil, errors.New("empty recurrence rule")
+	}
+
+	recurrence := &Recurrence{Interval: 1}
+	hasFrequency := false
+
+	for _, part := range strings.Split(rule, ";") {
+		name, value, ok := strings.Cut(part, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid rule part: %s", part)
+		}
+
+		switch strings.ToUpper(name) {
+		case "FREQ":
+			frequency, err := ParseFrequency(strings.ToUpper(value))
+			if err != nil {
+				return nil, err
+			}
+			recurrence.Frequency = frequency
+			hasFrequency = true
+
+		case "INTERVAL":
+			interval, err := strconv.Atoi(value)
+			if err != nil || interval < 1 {
+				return nil, errors.New("INTERVAL must be a positive integer")
+			}
+			recurrence.Interval = interval
+
+		case "COUNT":
+			count, err := strconv.Atoi(value)
+			if err != nil || count < 1 || count > maxCount {
+				return nil, fmt.Errorf("COUNT must be between 1 and %d", maxCount)
+			}
+			recurrence.Count = count
+
+		case "UNTIL":
+			until, err := parseUntil(value)
+			if err != nil {
+				return nil, err
+			}
+			recurrence.Until = &until
+
+		case "BYDAY":
+			for _, code := range strings.Split(strings.ToUpper(value), ",") {
+				weekday, ok := weekdayCodes[code]
+				if !ok {
+					return nil, fmt.Errorf("invalid BYDAY: %s", code)
+				}
+				recurrence.ByDay = append(recurrence.ByDay, weekday)
+			}
+
+		default:
+			return nil, fmt.Errorf("unsupported rule part: %s", name)
+		}
+	}
+
+	if !hasFrequency {
+		return nil, errors.New("FREQ is required")
+	}
+	if recurrence.Count > 0 && recurrence.Until != nil {
+		return nil, errors.New("COUNT and UNTIL can't both be set")
+	}
+	if len(recurrence.ByDay) > 0 && recurrence.Frequency != Weekly {
+		return nil, errors.New("BYDAY is only supported with FREQ=WEEKLY")
+	}
+
+	return recurrence, nil
+}
+
+// parseUntil accepts the RFC 5545 UTC form (20250131T235959Z) or a date (end of that day UTC)
+func parseUntil(value string) (time.Time, error) {
+	if until, err := time.Parse("20060102T150405Z", value); err == nil {
+		return until, nil
+	}
+	if until, err := time.Parse("20060102", value); err == nil {
+		return until.Add(24*time.Hour - time.Second), nil
+	}
+	return time.Time{}, errors.New("UNTIL must be a UTC date-time like 20250131T235959Z")
+}
+
+// String formats the rule as an RRULE value, the same rule always formats the same way
+func (recurrence *Recurrence) String() string {
+	parts := []string{"FREQ=" + recurrence.Frequency.String()}
+
+	if recurrence.Interval > 1 {
+		parts = append(parts, "INTERVAL="+strconv.Itoa(recurrence.Interval))
+	}
+	if recurrence.Count > 0 {
+		parts = append(parts, "COUNT="+strconv.Itoa(recurrence.Count))
+	}
+	if recurrence.Until != nil {
+		parts = append(parts, "UNTIL="+recurrence.Until.UTC().Format("20060102T150405Z"))
+	}
+	if len(recurrence.ByDay) > 0 {
+		var codes []string
+		for _, weekday := range sortedWeekdays(recurrence.ByDay) {
+			codes = append(codes, strings.ToUpper(weekday.String()[:2]))
+		}
+		parts = append(parts, "BYDAY="+strings.Join(codes, ","))
+	}
+
+	return strings.Join(parts, ";")
+}
+
+// Bounded returns whether the rule eventually stops repeating
+func (recurrence *Recurrence) Bounded() bool {
+	return recurrence.Count > 0 || recurrence.Until != nil
+}
@@ -0,0 +1,224 @@
+package schedule
+
+import (
+	"errors"
+	"sort"
+	"time"
+
+	// Timezones are validated against the embedded database so they don't depend on the host having zoneinfo
+	_ "time/tzdata"
+)
+
+// maxPeriods stops expansion of rules that would otherwise run for centuries, e.g. a daily rule until 9999
+const maxPeriods = 50000
+
+// Schedule is when an opportunity happens. Start and End are expanded in Location so a weekly 10:00 event
+// stays at 10:00 local time either side of a daylight saving change
+type Schedule struct {
+	Start time.Time
+	// End is zero when the opportunity has no end time
+	End        time.Time
+	Location   *time.Location
+	Recurrence *Recurrence
+}
+
+type Occurrence struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+}
+
+// LoadLocation loads an IANA timezone such as Europe/London, an empty name is UTC
+func LoadLocation(name string) (*time.Location, error) {
+	if name == "" {
+		return time.UTC, nil
+	}
+	location, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, errors.New("unknown timezone: " + name)
+	}
+	return location, nil
+}
+
+// Validate checks the schedule describes at least one occurrence
+func (schedule Schedule) Validate() error {
+	if schedule.Start.IsZero() {
+		return errors.New("start time is required")
+	}
+	if !schedule.End.IsZero() && !schedule.End.After(schedule.Start) {
+		return errors.New("end time must be after the start time")
+	}
+	if recurrence := schedule.Recurrence; recurrence != nil && recurrence.Until != nil && recurrence.Until.Before(schedule.Start) {
+		return errors.New("recurrence ends before the first occurrence")
+	}
+	return nil
+}
+
+// Occurrences returns up to limit occurrences overlapping [from, to), earliest first
+func (schedule Schedule) Occurrences(from time.Time, to time.Time, limit int) []Occurrence {
+	var occurrences []Occurrence
+
+	schedule.each(from, func(occurrence Occurrence) bool {
+		if !occurrence.Start.Before(to) {
+			return false
+		}
+
+		overlaps := occurrence.End.After(from) || (occurrence.End.Equal(occurrence.Start) && !occurrence.Start.Before(from))
+		if overlaps {
+			occurrences = append(occurrences, occurrence)
+		}
+		return len(occurrences) < limit
+	})
+
+	return occurrences
+}
+
+// Last returns the final occurrence, nil if the schedule repeats forever
+func (schedule Schedule) Last() *Occurrence {
+	if schedule.Recurrence != nil && !schedule.Recurrence.Bounded() {
+		return nil
+	}
+
+	var last *Occurrence
+	schedule.each(time.Time{}, func(occurrence Occurrence) bool {
+		last = &occurrence
+		return true
+	})
+	return last
+}
+
+// each calls yield with every occurrence in order until it returns false. Periods ending before from are skipped
+// when they can be, COUNT rules always start from the first period as every occurrence counts towards it
+func (schedule Schedule) each(from time.Time, yield func(Occurrence) bool) {
+	location := schedule.location()
+	start := schedule.Start.In(location)
+
+	var duration time.Duration
+	if !schedule.End.IsZero() {
+		duration = schedule.End.Sub(schedule.Start)
+	}
+
+	recurrence := schedule.Recurrence
+	if recurrence == nil {
+		yield(Occurrence{Start: start, End: start.Add(duration)})
+		return
+	}
+
+	period := 0
+	if recurrence.Count == 0 {
+		period = schedule.periodsBefore(from.Add(-duration).In(location))
+	}
+
+	emitted := 0
+	for ; period < maxPeriods; period++ {
+		for _, date := range schedule.dates(start, period) {
+			occurrenceStart := time.Date(date.Year(), date.Month(), date.Day(),
+				start.Hour(), start.Minute(), start.Second(), start.Nanosecond(), location)
+
+			if occurrenceStart.Before(start) {
+				continue
+			}
+			if recurrence.Until != nil && occurrenceStart.After(*recurrence.Until) {
+				return
+			}
+			if recurrence.Count > 0 && emitted >= recurrence.Count {
+				return
+			}
+
+			emitted++
+			if !yield(Occurrence{Start: occurrenceStart, End: occurrenceStart.Add(duration)}) {
+				return
+			}
+		}
+	}
+}
+
+// dates returns the dates occurrences fall on in the period, in order. Only the year, month and day are used
+func (schedule Schedule) dates(start time.Time, period int) []time.Time {
+	recurrence := schedule.Recurrence
+	step := period * recurrence.Interval
+
+	switch recurrence.Frequency {
+	case Daily:
+		return []time.Time{civilDate(start.Year(), start.Month(), start.Day()+step)}
+
+	case Weekly:
+		weekdays := recurrence.ByDay
+		if len(weekdays) == 0 {
+			weekdays = []time.Weekday{start.Weekday()}
+		}
+
+		monday := civilDate(start.Year(), start.Month(), start.Day()-daysSinceMonday(start.Weekday())+7*step)
+		var dates []time.Time
+		for _, weekday := range sortedWeekdays(weekdays) {
+			dates = append(dates, monday.AddDate(0, 0, daysSinceMonday(weekday)))
+		}
+		return dates
+
+	default:
+		// Months without the start's day are skipped, as RFC 5545 does, rather than moving to the last day
+		first := civilDate(start.Year(), start.Month()+time.Month(step), 1)
+		date := civilDate(first.Year(), first.Month(), start.Day())
+		if date.Month() != first.Month() {
+			return nil
+		}
+		return []time.Time{date}
+	}
+}
+
+// periodsBefore returns how many whole periods pass before the one containing t, less one to allow for
+// occurrences that started in the previous period and are still running
+func (schedule Schedule) periodsBefore(t time.Time) int {
+	start := schedule.Start.In(schedule.location())
+	if !t.After(start) {
+		return 0
+	}
+
+	var units int
+	switch schedule.Recurrence.Frequency {
+	case Daily:
+		units = daysBetween(start, t)
+	case Weekly:
+		units = (daysBetween(start, t) + daysSinceMonday(start.Weekday()) - daysSinceMonday(t.Weekday())) / 7
+	default:
+		units = (t.Year()-start.Year())*12 + int(t.Month()-start.Month())
+	}
+
+	return max(0, units/schedule.Recurrence.Interval-1)
+}
+
+func (schedule Schedule) location() *time.Location {
+	if schedule.Location == nil {
+		return time.UTC
+	}
+	return schedule.Location
+}
+
+func civilDate(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+// daysBetween counts calendar days from a to b, ignoring the time of day
+func daysBetween(a time.Time, b time.Time) int {
+	return int((civilDate(b.Year(), b.Month(), b.Day()).Unix() - civilDate(a.Year(), a.Month(), a.Day()).Unix()) / 86400)
+}
+
+// daysSinceMonday weeks start on Monday, the RFC 5545 default WKST
+func daysSinceMonday(weekday time.Weekday) int {
+	return (int(weekday) + 6) % 7
+}
+
+func sortedWeekdays(weekdays []time.Weekday) []time.Weekday {
+	sorted := append([]time.Weekday{}, weekdays...)
+	sort.Slice(sorted, func(i, j int) bool {
+		return daysSinceMonday(sorted[i]) < daysSinceMonday(sorted[j])
+	})
+
+	// Drop duplicates so BYDAY=SA,SA doesn't double up
+	unique := sorted[:0]
+	for i, weekday := range sorted {
+		if i == 0 || weekday != sorted[i-1] {
+			unique = append(unique, weekday)
+		}
+	}
+	return unique
+}
@@ -0,0 +1,170 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+)
+
+func mustLocation(t *testing.T, name string) *time.Location {
+	location, err := LoadLocation(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return location
+}
+
+func mustRecurrence(t *testing.T, rule string) *Recurrence {
+	recurrence, err := ParseRecurrence(rule)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return recurrence
+}
+
+func TestParseRecurrenceRoundTrip(t *testing.T) {
+	cases := map[string]string{
+		"FREQ=WEEKLY;BYDAY=SA":                     "FREQ=WEEKLY;BYDAY=SA",
+		"RRULE:freq=weekly;byday=we,mo;interval=2": "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,WE",
+		"FREQ=DAILY;COUNT=5":                       "FREQ=DAILY;COUNT=5",
+		"FREQ=MONTHLY;UNTIL=20250630T000000Z":      "FREQ=MONTHLY;UNTIL=20250630T000000Z",
+	}
+
+	for rule, expected := range cases {
+		if formatted := mustRecurrence(t, rule).String(); formatted != expected {
+			t.Errorf("%s: expected %s got %s", rule, expected, formatted)
+		}
+	}
+}
+
+func TestParseRecurrenceRejectsUnsupported(t *testing.T) {
+	for _, rule := range []string{"", "INTERVAL=2", "FREQ=YEARLY", "FREQ=DAILY;BYDAY=MO", "FREQ=DAILY;COUNT=2;UNTIL=20250101",
+		"FREQ=WEEKLY;BYDAY=XX", "FREQ=DAILY;INTERVAL=0", "FREQ=DAILY;BYSETPOS=1", "FREQ=DAILY;COUNT=100000"} {
+		if _, err := ParseRecurrence(rule); err == nil {
+			t.Errorf("%s: expected an error", rule)
+		}
+	}
+}
+
+func TestWeeklyKeepsLocalTimeAcrossDaylightSaving(t *testing.T) {
+	london := mustLocation(t, "Europe/London")
+
+	// Saturday litter pick at 10:00, the clocks go forward on 30 March 2025
+	schedule := Schedule{
+		Start:      time.Date(2025, 3, 22, 10, 0, 0, 0, london),
+		End:        time.Date(2025, 3, 22, 12, 0, 0, 0, london),
+		Location:   london,
+		Recurrence: mustRecurrence(t, "FREQ=WEEKLY;BYDAY=SA"),
+	}
+
+	occurrences := schedule.Occurrences(schedule.Start, schedule.Start.AddDate(0, 0, 21), 10)
+	if len(occurrences) != 3 {
+		t.Fatalf("expected 3 occurrences got %d", len(occurrences))
+	}
+
+	for _, occurrence := range occurrences {
+		local := occurrence.Start.In(london)
+		if local.Weekday() != time.Saturday || local.Hour() != 10 || occurrence.End.Sub(occurrence.Start) != 2*time.Hour {
+			t.Errorf("unexpected occurrence %v - %v", occurrence.Start, occurrence.End)
+		}
+	}
+
+	// 10:00 GMT then 10:00 BST, an hour apart in UTC
+	if occurrences[1].Start.UTC().Hour() != 10 || occurrences[2].Start.UTC().Hour() != 9 {
+		t.Fatalf("expected the UTC hour to change with daylight saving, got %v and %v", occurrences[1].Start.UTC(), occurrences[2].Start.UTC())
+	}
+}
+
+func TestWeeklyByDayAndInterval(t *testing.T) {
+	// Wednesday 2 April 2025, every other week on Monday and Wednesday
+	schedule := Schedule{
+		Start:      time.Date(2025, 4, 2, 18, 0, 0, 0, time.UTC),
+		Recurrence: mustRecurrence(t, "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,WE;COUNT=4"),
+	}
+
+	occurrences := schedule.Occurrences(schedule.Start, schedule.Start.AddDate(1, 0, 0), 10)
+
+	// The Monday before the start is skipped, and COUNT includes the first occurrence
+	expected := []string{"2025-04-02", "2025-04-14", "2025-04-16", "2025-04-28"}
+	if len(occurrences) != len(expected) {
+		t.Fatalf("expected %d occurrences got %d", len(expected), len(occurrences))
+	}
+	for i, date := range expected {
+		if occurrences[i].Start.Format(time.DateOnly) != date {
+			t.Errorf("occurrence %d: expected %s got %s", i, date, occurrences[i].Start.Format(time.DateOnly))
+		}
+	}
+
+	if last := schedule.Last(); last == nil || last.Start.Format(time.DateOnly) != "2025-04-28" {
+		t.Fatalf("unexpected last occurrence %v", last)
+	}
+}
+
+func TestMonthlySkipsShortMonths(t *testing.T) {
+	schedule := Schedule{
+		Start:      time.Date(2025, 1, 31, 9, 0, 0, 0, time.UTC),
+		Recurrence: mustRecurrence(t, "FREQ=MONTHLY;COUNT=3"),
+	}
+
+	occurrences := schedule.Occurrences(schedule.Start, schedule.Start.AddDate(1, 0, 0), 10)
+	expected := []string{"2025-01-31", "2025-03-31", "2025-05-31"}
+
+	for i, date := range expected {
+		if occurrences[i].Start.Format(time.DateOnly) != date {
+			t.Errorf("occurrence %d: expected %s got %s", i, date, occurrences[i].Start.Format(time.DateOnly))
+		}
+	}
+}
+
+func TestOccurrencesWindowSkipsAhead(t *testing.T) {
+	schedule := Schedule{
+		Start:      time.Date(2020, 1, 1, 9, 0, 0, 0, time.UTC),
+		End:        time.Date(2020, 1, 1, 17, 0, 0, 0, time.UTC),
+		Recurrence: mustRecurrence(t, "FREQ=DAILY"),
+	}
+
+	// An occurrence already running at the start of the window is included
+	from := time.Date(2025, 6, 10, 12, 0, 0, 0, time.UTC)
+	occurrences := schedule.Occurrences(from, from.AddDate(0, 0, 2), 10)
+
+	if len(occurrences) != 3 || occurrences[0].Start.Format(time.DateOnly) != "2025-06-10" {
+		t.Fatalf("unexpected occurrences %v", occurrences)
+	}
+
+	if schedule.Last() != nil {
+		t.Fatal("unbounded schedules have no last occurrence")
+	}
+}
+
+func TestUntilAndSingleOccurrence(t *testing.T) {
+	schedule := Schedule{
+		Start:      time.Date(2025, 5, 1, 9, 0, 0, 0, time.UTC),
+		Recurrence: mustRecurrence(t, "FREQ=DAILY;INTERVAL=3;UNTIL=20250510T090000Z"),
+	}
+
+	// 1, 4, 7 and 10 May, UNTIL is inclusive
+	if last := schedule.Last(); last == nil || last.Start.Day() != 10 {
+		t.Fatalf("unexpected last occurrence %v", last)
+	}
+
+	single := Schedule{Start: schedule.Start, End: schedule.Start.Add(time.Hour)}
+	if occurrences := single.Occurrences(schedule.Start.Add(2*time.Hour), schedule.Start.AddDate(0, 0, 1), 10); len(occurrences) != 0 {
+		t.Fatalf("expected the finished occurrence to be outside the window, got %v", occurrences)
+	}
+	if last := single.Last(); last == nil || !last.End.Equal(single.End) {
+		t.Fatalf("unexpected last occurrence %v", last)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	start := time.Date(2025, 5, 1, 9, 0, 0, 0, time.UTC)
+
+	if err := (Schedule{Start: start, End: start}).Validate(); err == nil {
+		t.Fatal("expected end before start to be rejected")
+	}
+	if err := (Schedule{Start: start, Recurrence: mustRecurrence(t, "FREQ=DAILY;UNTIL=20250101")}).Validate(); err == nil {
+		t.Fatal("expected until before start to be rejected")
+	}
+	if _, err := LoadLocation("Mars/Olympus_Mons"); err == nil {
+		t.Fatal("expected unknown timezone to be rejected")
+	}
+}
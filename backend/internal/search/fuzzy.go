@@ -0,0 +1,200 @@
+package search
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// Match qualities, an exact match ranks above a prefix match which ranks above a typo
+const (
+	exactMatch  = 1.0
+	prefixMatch = 0.7
+	typoMatch   = 0.5
+)
+
+// minPrefixLength query terms shorter than this only match exactly, "a" shouldn't match every word
+const minPrefixLength = 2
+
+// MaxEdits is how many typos are tolerated for a term of the given length
+func MaxEdits(term string) int {
+	length := utf8.RuneCountInString(term)
+	switch {
+	case length < 4:
+		return 0
+	case length < 8:
+		return 1
+	default:
+		return 2
+	}
+}
+
+// MatchQuality returns how well the indexed term matches the query term, 0 if it doesn't
+func MatchQuality(queryTerm string, term string) float64 {
+	if queryTerm == term {
+		return exactMatch
+	}
+
+	if utf8.RuneCountInString(queryTerm) >= minPrefixLength && strings.HasPrefix(term, queryTerm) {
+		return prefixMatch
+	}
+
+	maxEdits := MaxEdits(queryTerm)
+	if maxEdits == 0 {
+		return 0
+	}
+
+	// A typo in the part typed so far, e.g. "recyc" matching "recycling" via "recyl"
+	candidate := term
+	if queryLength, termLength := utf8.RuneCountInString(queryTerm), utf8.RuneCountInString(term); termLength > queryLength+maxEdits {
+		candidate = string([]rune(term)[:queryLength])
+	}
+
+	distance := EditDistance(queryTerm, candidate, maxEdits)
+	if distance > maxEdits {
+		return 0
+	}
+
+	return typoMatch / float64(distance)
+}
+
+// EditDistance returns the optimal string alignment distance (Levenshtein plus transpositions) between a and b.
+// It stops early and returns max+1 once the distance is known to be greater than max
+func EditDistance(a string, b string, max int) int {
+	ra, rb := []rune(a), []rune(b)
+
+	if diff := len(ra) - len(rb); diff > max || -diff > max {
+		return max + 1
+	}
+
+	previousPrevious := make([]int, len(rb)+1)
+	previous := make([]int, len(rb)+1)
+	current := make([]int, len(rb)+1)
+
+	for j := range previous {
+		previous[j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		current[0] = i
+		rowMin := current[0]
+
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+
+			if i > 1 && j > 1 && ra[i-1] == rb[j-2] && ra[i-2] == rb[j-1] {
+				current[j] = min(current[j], previousPrevious[j-2]+1)
+			}
+
+			rowMin = min(rowMin, current[j])
+		}
+
+		if rowMin > max {
+			return max + 1
+		}
+
+		previousPrevious, previous, current = previous, current, previousPrevious
+	}
+
+	return previous[len(rb)]
+}
+
+// Vocabulary is the set of terms that have been indexed, used to find which terms a query term could mean
+type Vocabulary struct {
+	mutex  sync.RWMutex
+	terms  map[string]int
+	sorted []string
+}
+
+func NewVocabulary() *Vocabulary {
+	return &Vocabulary{terms: map[string]int{}}
+}
+
+// Add adds every term in the text
+func (vocabulary *Vocabulary) Add(text string) {
+	vocabulary.mutex.Lock()
+	defer vocabulary.mutex.Unlock()
+
+	for _, term := range Tokenize(text) {
+		if vocabulary.terms[term] == 0 {
+			vocabulary.sorted = nil
+		}
+		vocabulary.terms[term]++
+	}
+}
+
+// Remove removes one occurrence of every term in the text
+func (vocabulary *Vocabulary) Remove(text string) {
+	vocabulary.mutex.Lock()
+	defer vocabulary.mutex.Unlock()
+
+	for _, term := range Tokenize(text) {
+		count, ok := vocabulary.terms[term]
+		if !ok {
+			continue
+		}
+		if count <= 1 {
+			delete(vocabulary.terms, term)
+			vocabulary.sorted = nil
+			continue
+		}
+		vocabulary.terms[term] = count - 1
+	}
+}
+
+// TermMatch is an indexed term that matches a query term
+type TermMatch struct {
+	Term    string
+	Quality float64
+}
+
+// Matches returns every indexed term matching the query term, best first
+func (vocabulary *Vocabulary) Matches(queryTerm string) []TermMatch {
+	vocabulary.mutex.Lock()
+	if vocabulary.sorted == nil {
+		vocabulary.sorted = make([]string, 0, len(vocabulary.terms))
+		for term := range vocabulary.terms {
+			vocabulary.sorted = append(vocabulary.sorted, term)
+		}
+		sort.Strings(vocabulary.sorted)
+	}
+	terms := vocabulary.sorted
+	vocabulary.mutex.Unlock()
+
+	var matches []TermMatch
+	seen := map[string]bool{}
+
+	// Exact and prefix matches are contiguous in the sorted terms
+	if utf8.RuneCountInString(queryTerm) >= minPrefixLength {
+		for i := sort.SearchStrings(terms, queryTerm); i < len(terms) && strings.HasPrefix(terms[i], queryTerm); i++ {
+			matches = append(matches, TermMatch{Term: terms[i], Quality: MatchQuality(queryTerm, terms[i])})
+			seen[terms[i]] = true
+		}
+	} else if i := sort.SearchStrings(terms, queryTerm); i < len(terms) && terms[i] == queryTerm {
+		matches = append(matches, TermMatch{Term: queryTerm, Quality: exactMatch})
+		seen[queryTerm] = true
+	}
+
+	if MaxEdits(queryTerm) > 0 {
+		for _, term := range terms {
+			if seen[term] {
+				continue
+			}
+			if quality := MatchQuality(queryTerm, term); quality > 0 {
+				matches = append(matches, TermMatch{Term: term, Quality: quality})
+			}
+		}
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].Quality > matches[j].Quality
+	})
+
+	return matches
+}
@@ -0,0 +1,108 @@
+package search
+
+import (
+	"html"
+	"strings"
+	"unicode"
+)
+
+const (
+	HighlightStart = "<mark>"
+	HighlightEnd   = "</mark>"
+)
+
+// snippetLength is roughly how many characters of a long field are kept around the first match
+const snippetLength = 160
+
+type span struct {
+	start, end int
+}
+
+// tokenSpans returns the rune offsets of each letter/digit run in the text
+func tokenSpans(text []rune) []span {
+	var spans []span
+	start := -1
+	for i, r := range text {
+		isToken := unicode.IsLetter(r) || unicode.IsDigit(r)
+		if isToken && start < 0 {
+			start = i
+		}
+		if !isToken && start >= 0 {
+			spans = append(spans, span{start, i})
+			start = -1
+		}
+	}
+	if start >= 0 {
+		spans = append(spans, span{start, len(text)})
+	}
+	return spans
+}
+
+// Highlight HTML escapes the text and wraps every word matching one of the query terms in <mark></mark>.
+// Long text is cut down to a snippet around the first match. ok is false if nothing matched
+func Highlight(text string, queryTerms []string) (highlighted string, ok bool) {
+	runes := []rune(text)
+
+	var matched []span
+	for _, token := range tokenSpans(runes) {
+		word := strings.ToLower(string(runes[token.start:token.end]))
+		for _, queryTerm := range queryTerms {
+			if MatchQuality(queryTerm, word) > 0 {
+				matched = append(matched, token)
+				break
+			}
+		}
+	}
+
+	if len(matched) == 0 {
+		return "", false
+	}
+
+	from, to := 0, len(runes)
+	if len(runes) > snippetLength {
+		from = max(0, matched[0].start-snippetLength/4)
+		to = min(len(runes), from+snippetLength)
+		// Don't cut words in half
+		for from > 0 && !unicode.IsSpace(runes[from-1]) {
+			from--
+		}
+		for to < len(runes) && !unicode.IsSpace(runes[to]) {
+			to++
+		}
+	}
+
+	var builder strings.Builder
+	if from > 0 {
+		builder.WriteString("…")
+	}
+
+	position := from
+	for _, token := range matched {
+		if token.start < from || token.end > to {
+			continue
+		}
+		builder.WriteString(html.EscapeString(string(runes[position:token.start])))
+		builder.WriteString(HighlightStart)
+		builder.WriteString(html.EscapeString(string(runes[token.start:token.end])))
+		builder.WriteString(HighlightEnd)
+		position = token.end
+	}
+	builder.WriteString(html.EscapeString(string(runes[position:to])))
+
+	if to < len(runes) {
+		builder.WriteString("…")
+	}
+
+	return builder.String(), true
+}
+
+// HighlightDocument highlights each field of the document that matches the query
+func HighlightDocument(doc *Document, queryTerms []string) map[string]string {
+	highlights := map[string]string{}
+	for field, text := range doc.Fields() {
+		if highlighted, ok := Highlight(text, queryTerms); ok {
+			highlights[field] = highlighted
+		}
+	}
+	return highlights
+}
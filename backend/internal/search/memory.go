@@ -0,0 +1,157 @@
+package search
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"math"
+	"sort"
+	"sync"
+)
+
+// MemoryIndex is an embedded inverted index. Used in tests and when running without MySQL full text search
+type MemoryIndex struct {
+	mutex      sync.RWMutex
+	documents  map[uuid.UUID]Document
+	postings   map[string]map[uuid.UUID]map[string]int // term -> document -> field -> term frequency
+	vocabulary *Vocabulary
+}
+
+func NewMemoryIndex() *MemoryIndex {
+	return &MemoryIndex{
+		documents:  map[uuid.UUID]Document{},
+		postings:   map[string]map[uuid.UUID]map[string]int{},
+		vocabulary: NewVocabulary(),
+	}
+}
+
+func (index *MemoryIndex) Index(_ context.Context, doc Document) error {
+	index.mutex.Lock()
+	defer index.mutex.Unlock()
+
+	index.remove(doc.UUID)
+
+	for field, text := range doc.Fields() {
+		for _, term := range Tokenize(text) {
+			documents, ok := index.postings[term]
+			if !ok {
+				documents = map[uuid.UUID]map[string]int{}
+				index.postings[term] = documents
+			}
+			if documents[doc.UUID] == nil {
+				documents[doc.UUID] = map[string]int{}
+			}
+			documents[doc.UUID][field]++
+		}
+		index.vocabulary.Add(text)
+	}
+
+	index.documents[doc.UUID] = doc
+	return nil
+}
+
+func (index *MemoryIndex) Remove(_ context.Context, opportunityUUID uuid.UUID) error {
+	index.mutex.Lock()
+	defer index.mutex.Unlock()
+
+	index.remove(opportunityUUID)
+	return nil
+}
+
+func (index *MemoryIndex) remove(opportunityUUID uuid.UUID) {
+	doc, ok := index.documents[opportunityUUID]
+	if !ok {
+		return
+	}
+
+	for _, text := range doc.Fields() {
+		for _, term := range Tokenize(text) {
+			if documents, ok := index.postings[term]; ok {
+				delete(documents, opportunityUUID)
+				if len(documents) == 0 {
+					delete(index.postings, term)
+				}
+			}
+		}
+		index.vocabulary.Remove(text)
+	}
+
+	delete(index.documents, opportunityUUID)
+}
+
+// Search scores documents with tf-idf weighted by field and match quality.
+// Every query term that matches something in the index must match the document
+func (index *MemoryIndex) Search(_ context.Context, query Query) ([]Hit, error) {
+	index.mutex.RLock()
+	defer index.mutex.RUnlock()
+
+	queryTerms := uniqueTokens(query.Text)
+	if len(queryTerms) == 0 {
+		return []Hit{}, nil
+	}
+
+	total := float64(len(index.documents))
+	scores := map[uuid.UUID]float64{}
+	var required []map[uuid.UUID]bool
+
+	for _, queryTerm := range queryTerms {
+		matches := index.vocabulary.Matches(queryTerm)
+		if len(matches) == 0 {
+			// Nothing in the index looks like this term, ignore it rather than returning nothing
+			continue
+		}
+
+		matching := map[uuid.UUID]bool{}
+		for _, match := range matches {
+			documents := index.postings[match.Term]
+			idf := math.Log(1 + total/float64(len(documents)))
+
+			for documentUUID, fields := range documents {
+				for field, frequency := range fields {
+					scores[documentUUID] += match.Quality * fieldWeights[field] * (1 + math.Log(float64(frequency))) * idf
+				}
+				matching[documentUUID] = true
+			}
+		}
+		required = append(required, matching)
+	}
+
+	hits := []Hit{}
+	for documentUUID, score := range scores {
+		if !matchesAll(documentUUID, required) {
+			continue
+		}
+
+		doc := index.documents[documentUUID]
+		hits = append(hits, Hit{
+			UUID:       documentUUID,
+			Score:      score,
+			Highlights: HighlightDocument(&doc, queryTerms),
+		})
+	}
+
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].Score != hits[j].Score {
+			return hits[i].Score > hits[j].Score
+		}
+		return hits[i].UUID.String() < hits[j].UUID.String()
+	})
+
+	limit := query.Limit
+	if limit <= 0 {
+		limit = DefaultLimit
+	}
+	if len(hits) > limit {
+		hits = hits[:limit]
+	}
+
+	return hits, nil
+}
+
+func matchesAll(documentUUID uuid.UUID, required []map[uuid.UUID]bool) bool {
+	for _, matching := range required {
+		if !matching[documentUUID] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,93 @@
+package search
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"strings"
+	"unicode"
+)
+
+// Fields that can be searched and highlighted
+const (
+	FieldTitle        = "title"
+	FieldDescription  = "description"
+	FieldLocation     = "location"
+	FieldTags         = "tags"
+	FieldOrganisation = "organisation"
+)
+
+// fieldWeights ranks a match in the title above the same match in the description
+var fieldWeights = map[string]float64{
+	FieldTitle:        3,
+	FieldTags:         2,
+	FieldOrganisation: 2,
+	FieldLocation:     1.5,
+	FieldDescription:  1,
+}
+
+// DefaultLimit is used when a query doesn't set one
+const DefaultLimit = 20
+
+// Document is the searchable view of an opportunity
+type Document struct {
+	UUID         uuid.UUID
+	Title        string
+	Description  string
+	Location     string
+	Tags         []string
+	Organisation string
+}
+
+// Fields returns the text of each searchable field
+func (doc *Document) Fields() map[string]string {
+	return map[string]string{
+		FieldTitle:        doc.Title,
+		FieldDescription:  doc.Description,
+		FieldLocation:     doc.Location,
+		FieldTags:         strings.Join(doc.Tags, ", "),
+		FieldOrganisation: doc.Organisation,
+	}
+}
+
+type Query struct {
+	Text  string
+	Limit int
+}
+
+// Hit is a matching document. Highlights holds the matched fields with matches wrapped in <mark></mark>
+type Hit struct {
+	UUID       uuid.UUID         `json:"uuid"`
+	Score      float64           `json:"score"`
+	Highlights map[string]string `json:"highlights,omitempty"`
+}
+
+// SearchIndex ranks opportunities against a free text query.
+// Implementations must tolerate typos and treat query terms as prefixes
+type SearchIndex interface {
+	// Index adds or replaces the document
+	Index(ctx context.Context, doc Document) error
+	// Remove removes the document, removing an unknown document is not an error
+	Remove(ctx context.Context, opportunityUUID uuid.UUID) error
+	// Search returns hits ordered by descending score, giving up when ctx is done
+	Search(ctx context.Context, query Query) ([]Hit, error)
+}
+
+// Tokenize lowercases the text and splits it into letter/digit runs
+func Tokenize(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// uniqueTokens tokenizes the query dropping duplicate terms
+func uniqueTokens(text string) []string {
+	seen := map[string]bool{}
+	var tokens []string
+	for _, token := range Tokenize(text) {
+		if !seen[token] {
+			seen[token] = true
+			tokens = append(tokens, token)
+		}
+	}
+	return tokens
+}
@@ -0,0 +1,176 @@
+package search
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"strings"
+	"testing"
+)
+
+func newTestIndex(t *testing.T) (*MemoryIndex, map[string]uuid.UUID) {
+	index := NewMemoryIndex()
+	ids := map[string]uuid.UUID{}
+
+	docs := []Document{
+		{Title: "Beach clean up", Description: "Help us collect plastic from the beach", Location: "Brighton", Tags: []string{"ocean", "plastic"}, Organisation: "Surfers Against Sewage"},
+		{Title: "Recycling drive", Description: "Sort recycling at the campus depot", Location: "Leeds", Tags: []string{"recycling"}, Organisation: "Green Campus"},
+		{Title: "Tree planting", Description: "Plant trees along the river, bring gloves. We will also talk about recycling", Location: "York", Tags: []string{"nature"}, Organisation: "Woodland Trust"},
+	}
+
+	for _, doc := range docs {
+		doc.UUID = uuid.New()
+		ids[doc.Title] = doc.UUID
+		if err := index.Index(context.Background(), doc); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return index, ids
+}
+
+func search(t *testing.T, index SearchIndex, text string) []Hit {
+	hits, err := index.Search(context.Background(), Query{Text: text})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return hits
+}
+
+func TestSearchRanksTitleAboveDescription(t *testing.T) {
+	index, ids := newTestIndex(t)
+
+	hits := search(t, index, "recycling")
+	if len(hits) != 2 {
+		t.Fatalf("expected 2 hits got %d", len(hits))
+	}
+	if hits[0].UUID != ids["Recycling drive"] {
+		t.Fatal("expected the title match to rank first")
+	}
+}
+
+func TestSearchPrefix(t *testing.T) {
+	index, ids := newTestIndex(t)
+
+	hits := search(t, index, "plan")
+	if len(hits) == 0 || hits[0].UUID != ids["Tree planting"] {
+		t.Fatalf("expected prefix to match tree planting, got %v", hits)
+	}
+}
+
+func TestSearchTypoTolerance(t *testing.T) {
+	index, ids := newTestIndex(t)
+
+	for _, query := range []string{"recyclign", "reycling", "beahc", "brigthon"} {
+		hits := search(t, index, query)
+		if len(hits) == 0 {
+			t.Fatalf("%s: expected typo to match", query)
+		}
+		if query == "beahc" && hits[0].UUID != ids["Beach clean up"] {
+			t.Fatalf("%s: wrong top hit", query)
+		}
+	}
+
+	// Short terms must match exactly or as a prefix
+	if hits := search(t, index, "yrk"); len(hits) != 0 {
+		t.Fatal("expected no typo tolerance for short terms")
+	}
+}
+
+func TestSearchAllTermsMustMatch(t *testing.T) {
+	index, ids := newTestIndex(t)
+
+	hits := search(t, index, "plastic brighton")
+	if len(hits) != 1 || hits[0].UUID != ids["Beach clean up"] {
+		t.Fatalf("expected only the beach clean up, got %v", hits)
+	}
+
+	// Unknown words are ignored rather than removing every result
+	hits = search(t, index, "plastic zzzzzz")
+	if len(hits) != 1 {
+		t.Fatalf("expected unknown term to be ignored, got %d hits", len(hits))
+	}
+}
+
+func TestSearchOrganisationAndTags(t *testing.T) {
+	index, ids := newTestIndex(t)
+
+	hits := search(t, index, "woodland")
+	if len(hits) != 1 || hits[0].UUID != ids["Tree planting"] {
+		t.Fatal("expected organisation name to be searchable")
+	}
+
+	hits = search(t, index, "ocean")
+	if len(hits) != 1 || hits[0].Highlights[FieldTags] != "<mark>ocean</mark>, plastic" {
+		t.Fatalf("expected tag highlight, got %v", hits)
+	}
+}
+
+func TestSearchRemoveAndReindex(t *testing.T) {
+	index, ids := newTestIndex(t)
+
+	if err := index.Remove(context.Background(), ids["Beach clean up"]); err != nil {
+		t.Fatal(err)
+	}
+	if hits := search(t, index, "beach"); len(hits) != 0 {
+		t.Fatal("expected removed document to not match")
+	}
+
+	err := index.Index(context.Background(), Document{UUID: ids["Recycling drive"], Title: "Litter pick"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hits := search(t, index, "litter"); len(hits) != 1 {
+		t.Fatal("expected reindexed document to match its new title")
+	}
+	if hits := search(t, index, "depot"); len(hits) != 0 {
+		t.Fatal("expected old text to be removed on reindex")
+	}
+}
+
+func TestHighlight(t *testing.T) {
+	highlighted, ok := Highlight("Beach <clean> up at the beach", []string{"beach"})
+	if !ok {
+		t.Fatal("expected match")
+	}
+	expected := "<mark>Beach</mark> &lt;clean&gt; up at the <mark>beach</mark>"
+	if highlighted != expected {
+		t.Fatalf("expected %q got %q", expected, highlighted)
+	}
+
+	if _, ok := Highlight("Tree planting", []string{"beach"}); ok {
+		t.Fatal("expected no match")
+	}
+
+	long := strings.Repeat("filler words here ", 30) + "recycling" + strings.Repeat(" more filler", 30)
+	highlighted, _ = Highlight(long, []string{"recycl"})
+	if !strings.HasPrefix(highlighted, "…") || !strings.HasSuffix(highlighted, "…") {
+		t.Fatalf("expected snippet, got %q", highlighted)
+	}
+	if !strings.Contains(highlighted, "<mark>recycling</mark>") {
+		t.Fatalf("expected snippet to contain match, got %q", highlighted)
+	}
+}
+
+func TestEditDistance(t *testing.T) {
+	cases := []struct {
+		a, b     string
+		expected int
+	}{
+		{"beach", "beach", 0},
+		{"beach", "beahc", 1},
+		{"beach", "bech", 1},
+		{"beach", "peach", 1},
+		{"recycling", "reycling", 1},
+		{"kitten", "sitting", 3},
+	}
+
+	for _, c := range cases {
+		if distance := EditDistance(c.a, c.b, 5); distance != c.expected {
+			t.Errorf("%s %s: expected %d got %d", c.a, c.b, c.expected, distance)
+		}
+	}
+
+	if distance := EditDistance("kitten", "sitting", 1); distance != 2 {
+		t.Errorf("expected early exit to return max+1, got %d", distance)
+	}
+}
@@ -0,0 +1,314 @@
+package security
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// SigningKey is an asymmetric key used to sign and/or verify tokens
+type SigningKey struct {
+	ID      string
+	Method  jwt.SigningMethod
+	Private crypto.Signer // nil for verification only keys
+	Public  crypto.PublicKey
+}
+
+// GenerateSigningKey creates a new key for the given algorithm ("RS256" or "EdDSA")
+func GenerateSigningKey(algorithm string) (*SigningKey, error) {
+	switch algorithm {
+	case "RS256":
+		private, err := rsa.GenerateKey(rand.Reader, 2048)
+		if err != nil {
+			return nil, err
+		}
+		return NewSigningKey(private)
+
+	case "EdDSA":
+		_, private, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, err
+		}
+		return NewSigningKey(private)
+
+	default:
+		return nil, fmt.Errorf("unsupported signing algorithm: %s", algorithm)
+	}
+}
+
+// NewSigningKey wraps a private key. The key id is the RFC 7638 thumbprint of the public key
+func NewSigningKey(private crypto.Signer) (*SigningKey, error) {
+	key, err := newVerificationKey(private.Public())
+	if err != nil {
+		return nil, err
+	}
+	key.Private = private
+	return key, nil
+}
+
+func newVerificationKey(public crypto.PublicKey) (*SigningKey, error) {
+	var method jwt.SigningMethod
+
+	switch public.(type) {
+	case *rsa.PublicKey:
+		method = jwt.SigningMethodRS256
+	case ed25519.PublicKey:
+		method = jwt.SigningMethodEdDSA
+	default:
+		return nil, fmt.Errorf("unsupported key type %T", public)
+	}
+
+	key := &SigningKey{Method: method, Public: public}
+
+	thumbprint, err := key.thumbprint()
+	if err != nil {
+		return nil, err
+	}
+	key.ID = thumbprint
+
+	return key, nil
+}
+
+// JSONWebKey returns the public part of the key as a JWK
+func (key *SigningKey) JSONWebKey() JSONWebKey {
+	jwk := JSONWebKey{
+		KeyID:     key.ID,
+		Use:       "sig",
+		Algorithm: key.Method.Alg(),
+	}
+
+	switch public := key.Public.(type) {
+	case *rsa.PublicKey:
+		jwk.KeyType = "RSA"
+		jwk.N = base64.RawURLEncoding.EncodeToString(public.N.Bytes())
+		jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes())
+	case ed25519.PublicKey:
+		jwk.KeyType = "OKP"
+		jwk.Curve = "Ed25519"
+		jwk.X = base64.RawURLEncoding.EncodeToString(public)
+	}
+
+	return jwk
+}
+
+// thumbprint computes the RFC 7638 JWK thumbprint (members in lexicographic order, no whitespace)
+func (key *SigningKey) thumbprint() (string, error) {
+	jwk := key.JSONWebKey()
+
+	var canonical []byte
+	var err error
+
+	switch jwk.KeyType {
+	case "RSA":
+		canonical, err = json.Marshal(struct {
+			E   string `json:"e"`
+			Kty string `json:"kty"`
+			N   string `json:"n"`
+		}{jwk.E, jwk.KeyType, jwk.N})
+	case "OKP":
+		canonical, err = json.Marshal(struct {
+			Crv string `json:"crv"`
+			Kty string `json:"kty"`
+			X   string `json:"x"`
+		}{jwk.Curve, jwk.KeyType, jwk.X})
+	default:
+		return "", errors.New("unsupported key type")
+	}
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(canonical)
+	return base64.RawURLEncoding.EncodeToString(sum[:]), nil
+}
+
+// Keyring holds the active signing key plus every key still accepted for verification.
+// Rotating means adding a new signing key and keeping the old one until tokens signed with it have expired
+type Keyring struct {
+	mutex        sync.RWMutex
+	signing      *SigningKey
+	verification map[string]*SigningKey
+}
+
+// NewKeyring creates an empty keyring
+func NewKeyring() *Keyring {
+	return &Keyring{verification: make(map[string]*SigningKey)}
+}
+
+// AddKey adds a key to the keyring. If useForSigning the key becomes the one new tokens are signed with
+func (keyring *Keyring) AddKey(key *SigningKey, useForSigning bool) error {
+	if useForSigning && key.Private == nil {
+		return errors.New("signing key must have a private key")
+	}
+
+	keyring.mutex.Lock()
+	defer keyring.mutex.Unlock()
+
+	keyring.verification[key.ID] = key
+	if useForSigning {
+		keyring.signing = key
+	}
+	return nil
+}
+
+// Retire removes a key so tokens signed with it are no longer accepted. The current signing key can't be retired
+func (keyring *Keyring) Retire(kid string) error {
+	keyring.mutex.Lock()
+	defer keyring.mutex.Unlock()
+
+	if keyring.signing != nil && keyring.signing.ID == kid {
+		return errors.New("cannot retire the active signing key")
+	}
+
+	delete(keyring.verification, kid)
+	return nil
+}
+
+// SigningKey returns the key new tokens are signed with
+func (keyring *Keyring) SigningKey() *SigningKey {
+	keyring.mutex.RLock()
+	defer keyring.mutex.RUnlock()
+	return keyring.signing
+}
+
+// VerificationKey returns the key with the given kid or nil
+func (keyring *Keyring) VerificationKey(kid string) *SigningKey {
+	keyring.mutex.RLock()
+	defer keyring.mutex.RUnlock()
+	return keyring.verification[kid]
+}
+
+// JSONWebKeySet returns the public verification keys, served at /.well-known/jwks.json
+func (keyring *Keyring) JSONWebKeySet() JSONWebKeySet {
+	keyring.mutex.RLock()
+	defer keyring.mutex.RUnlock()
+
+	set := JSONWebKeySet{Keys: make([]JSONWebKey, 0, len(keyring.verification))}
+	for _, key := range keyring.verification {
+		set.Keys = append(set.Keys, key.JSONWebKey())
+	}
+
+	sort.Slice(set.Keys, func(i, j int) bool {
+		return set.Keys[i].KeyID < set.Keys[j].KeyID
+	})
+
+	return set
+}
+
+// LoadKeyring loads every PEM key in the directory. Private keys (PKCS8) can sign and verify, public keys (PKIX)
+// only verify. The most recently modified private key is used for signing, so rotating is dropping a new key in the
+// directory, and an old key can be retired by replacing it with its public key and later deleting it.
+func LoadKeyring(directory string) (*Keyring, error) {
+	entries, err := os.ReadDir(directory)
+	if err != nil {
+		return nil, err
+	}
+
+	keyring := NewKeyring()
+
+	var newest *SigningKey
+	var newestModified int64
+
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".pem") {
+			continue
+		}
+
+		path := filepath.Join(directory, entry.Name())
+		key, err := readPEMKey(path)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+
+		err = keyring.AddKey(key, false)
+		if err != nil {
+			return nil, err
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			return nil, err
+		}
+
+		if key.Private != nil && (newest == nil || info.ModTime().UnixNano() > newestModified) {
+			newest = key
+			newestModified = info.ModTime().UnixNano()
+		}
+	}
+
+	if newest == nil {
+		return nil, fmt.Errorf("no private signing key found in %s", directory)
+	}
+
+	err = keyring.AddKey(newest, true)
+	if err != nil {
+		return nil, err
+	}
+
+	return keyring, nil
+}
+
+func readPEMKey(path string) (*SigningKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+
+	switch block.Type {
+	case "PRIVATE KEY":
+		parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		signer, ok := parsed.(crypto.Signer)
+		if !ok {
+			return nil, errors.New("private key cannot sign")
+		}
+		return NewSigningKey(signer)
+
+	case "PUBLIC KEY":
+		parsed, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		return newVerificationKey(parsed)
+
+	default:
+		return nil, fmt.Errorf("unsupported PEM block %s", block.Type)
+	}
+}
+
+// EncodePrivateKeyPEM encodes the private key as PKCS8 PEM so it can be loaded with LoadKeyring
+func EncodePrivateKeyPEM(key *SigningKey) ([]byte, error) {
+	if key.Private == nil {
+		return nil, errors.New("key has no private part")
+	}
+
+	der, err := x509.MarshalPKCS8PrivateKey(key.Private)
+	if err != nil {
+		return nil, err
+	}
+
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), nil
+}
@@ -0,0 +1,352 @@
+package oidc
+
+import (
+	"backend/internal/security"
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// mockProvider is a minimal local OIDC provider: discovery, JWKS and a token endpoint that enforces PKCE
+type mockProvider struct {
+	server   *httptest.Server
+	clientID string
+
+	mutex       sync.Mutex
+	key         *rsa.PrivateKey
+	kid         string
+	codes       map[string]mockAuthorization
+	jwksFetches int
+	claims      jwt.MapClaims
+	algorithms  []string
+}
+
+type mockAuthorization struct {
+	challenge string
+	nonce     string
+}
+
+func newMockProvider(t *testing.T) *mockProvider {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mock := &mockProvider{
+		clientID:   "greenuni",
+		key:        key,
+		kid:        "key-1",
+		codes:      map[string]mockAuthorization{},
+		algorithms: []string{"RS256"},
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(Discovery{
+			Issuer:                mock.server.URL,
+			AuthorizationEndpoint: mock.server.URL + "/authorize",
+			TokenEndpoint:         mock.server.URL + "/token",
+			JWKSURI:               mock.server.URL + "/jwks",
+			SigningAlgorithms:     mock.algorithms,
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		mock.mutex.Lock()
+		defer mock.mutex.Unlock()
+		mock.jwksFetches++
+		json.NewEncoder(w).Encode(security.JSONWebKeySet{Keys: []security.JSONWebKey{{
+			KeyType:   "RSA",
+			KeyID:     mock.kid,
+			Use:       "sig",
+			Algorithm: "RS256",
+			N:         base64.RawURLEncoding.EncodeToString(mock.key.N.Bytes()),
+			E:         base64.RawURLEncoding.EncodeToString(big.NewInt(int64(mock.key.E)).Bytes()),
+		}}})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		mock.mutex.Lock()
+		authorization, ok := mock.codes[r.Form.Get("code")]
+		delete(mock.codes, r.Form.Get("code"))
+		mock.mutex.Unlock()
+
+		if !ok || CodeChallengeS256(r.Form.Get("code_verifier")) != authorization.challenge {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+			return
+		}
+
+		json.NewEncoder(w).Encode(TokenResponse{
+			AccessToken: "access",
+			TokenType:   "Bearer",
+			IDToken:     mock.signIDToken(t, authorization.nonce),
+		})
+	})
+
+	mock.server = httptest.NewServer(mux)
+	t.Cleanup(mock.server.Close)
+	return mock
+}
+
+// authorize simulates the user logging in at the provider and returns the code sent to the redirect URL
+func (mock *mockProvider) authorize(t *testing.T, authURL string) (code string, state string) {
+	parsed, err := url.Parse(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	query := parsed.Query()
+
+	if query.Get("code_challenge_method") != "S256" || query.Get("client_id") != mock.clientID {
+		t.Fatalf("unexpected authorization request %s", authURL)
+	}
+
+	mock.mutex.Lock()
+	defer mock.mutex.Unlock()
+	code = "code-" + query.Get("state")
+	mock.codes[code] = mockAuthorization{challenge: query.Get("code_challenge"), nonce: query.Get("nonce")}
+	return code, query.Get("state")
+}
+
+func (mock *mockProvider) signIDToken(t *testing.T, nonce string) string {
+	mock.mutex.Lock()
+	defer mock.mutex.Unlock()
+
+	claims := jwt.MapClaims{
+		"iss":            mock.server.URL,
+		"aud":            mock.clientID,
+		"sub":            "student-123",
+		"email":          "jane@student.example.ac.uk",
+		"email_verified": true,
+		"nonce":          nonce,
+		"iat":            time.Now().Unix(),
+		"exp":            time.Now().Add(time.Minute).Unix(),
+	}
+	for k, v := range mock.claims {
+		claims[k] = v
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = mock.kid
+	signed, err := token.SignedString(mock.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed
+}
+
+func newTestProvider(t *testing.T, mock *mockProvider) *Provider {
+	provider, err := NewProvider(ProviderConfig{
+		Name:           "university",
+		Issuer:         mock.server.URL,
+		ClientID:       mock.clientID,
+		RedirectURL:    "http://localhost:8080/api/v1/auth/sso/university/callback",
+		AllowedDomains: []string{"example.ac.uk"},
+	}, mock.server.Client())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return provider
+}
+
+// login runs the full authorization code flow against the mock
+func login(t *testing.T, provider *Provider, mock *mockProvider, store *StateStore) (*IDTokenClaims, error) {
+	ctx := context.Background()
+
+	state, loginState, err := store.Begin(provider.Name(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	authURL, err := provider.AuthCodeURL(ctx, state, loginState.Nonce, loginState.CodeVerifier)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	code, returnedState := mock.authorize(t, authURL)
+
+	consumed, err := store.Consume(returnedState, provider.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	token, err := provider.Exchange(ctx, code, consumed.CodeVerifier)
+	if err != nil {
+		return nil, err
+	}
+
+	return provider.ValidateIDToken(ctx, token.IDToken, consumed.Nonce)
+}
+
+func TestAuthorizationCodeFlow(t *testing.T) {
+	mock := newMockProvider(t)
+	provider := newTestProvider(t, mock)
+
+	claims, err := login(t, provider, mock, NewStateStore())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if claims.Subject != "student-123" || claims.Email != "jane@student.example.ac.uk" || !claims.EmailVerified {
+		t.Fatalf("unexpected claims %+v", claims)
+	}
+
+	if !provider.IsTrustedDomain(claims.Email) {
+		t.Fatalf("expected %s to be trusted", claims.Email)
+	}
+}
+
+func TestExchangeRejectsWrongVerifier(t *testing.T) {
+	mock := newMockProvider(t)
+	provider := newTestProvider(t, mock)
+	ctx := context.Background()
+
+	authURL, err := provider.AuthCodeURL(ctx, "state", "nonce", "verifier-one")
+	if err != nil {
+		t.Fatal(err)
+	}
+	code, _ := mock.authorize(t, authURL)
+
+	_, err = provider.Exchange(ctx, code, "verifier-two")
+	if err == nil {
+		t.Fatal("expected exchange with the wrong code verifier to fail")
+	}
+}
+
+func TestValidateIDTokenRejectsBadClaims(t *testing.T) {
+	cases := map[string]jwt.MapClaims{
+		"audience": {"aud": "someone-else"},
+		"issuer":   {"iss": "https://evil.example.com"},
+		"expired":  {"exp": time.Now().Add(-time.Hour).Unix()},
+		"future":   {"iat": time.Now().Add(time.Hour).Unix()},
+	}
+
+	for name, overrides := range cases {
+		t.Run(name, func(t *testing.T) {
+			mock := newMockProvider(t)
+			mock.claims = overrides
+			provider := newTestProvider(t, mock)
+
+			_, err := login(t, provider, mock, NewStateStore())
+			if err == nil {
+				t.Fatal("expected id token to be rejected")
+			}
+		})
+	}
+}
+
+func TestValidateIDTokenRejectsWrongNonce(t *testing.T) {
+	mock := newMockProvider(t)
+	provider := newTestProvider(t, mock)
+
+	token := mock.signIDToken(t, "nonce-a")
+	_, err := provider.ValidateIDToken(context.Background(), token, "nonce-b")
+	if err == nil {
+		t.Fatal("expected nonce mismatch to be rejected")
+	}
+}
+
+func TestValidateIDTokenRejectsUnsupportedAlgorithms(t *testing.T) {
+	mock := newMockProvider(t)
+	mock.algorithms = []string{"HS256"}
+	provider := newTestProvider(t, mock)
+
+	nonce := "nonce-a"
+	_, err := provider.ValidateIDToken(context.Background(), mock.signIDToken(t, nonce), nonce)
+	if err == nil {
+		t.Fatal("expected id token to be rejected when the provider only offers unsupported algorithms")
+	}
+}
+
+func TestJWKSCachedAndRefreshedOnRotation(t *testing.T) {
+	mock := newMockProvider(t)
+	provider := newTestProvider(t, mock)
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		_, err := provider.ValidateIDToken(ctx, mock.signIDToken(t, "n"), "n")
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if mock.jwksFetches != 1 {
+		t.Fatalf("expected jwks to be fetched once, fetched %d times", mock.jwksFetches)
+	}
+
+	// Rotate the provider key, unknown kid should trigger a refetch
+	newKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mock.mutex.Lock()
+	mock.key = newKey
+	mock.kid = "key-2"
+	mock.mutex.Unlock()
+
+	provider.mutex.Lock()
+	provider.keysFetched = time.Now().Add(-time.Minute)
+	provider.mutex.Unlock()
+
+	_, err = provider.ValidateIDToken(ctx, mock.signIDToken(t, "n"), "n")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if mock.jwksFetches != 2 {
+		t.Fatalf("expected jwks to be refetched after rotation, fetched %d times", mock.jwksFetches)
+	}
+}
+
+func TestStateStoreSingleUse(t *testing.T) {
+	store := NewStateStore()
+
+	state, _, err := store.Begin("university", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := store.Consume(state, "other"); err == nil {
+		t.Fatal("expected state for another provider to be rejected")
+	}
+
+	state, _, _ = store.Begin("university", nil)
+	if _, err := store.Consume(state, "university"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Consume(state, "university"); err == nil {
+		t.Fatal("expected state to only be usable once")
+	}
+}
+
+func TestIsTrustedDomain(t *testing.T) {
+	provider, _ := NewProvider(ProviderConfig{
+		Name: "uni", Issuer: "https://idp", ClientID: "c", RedirectURL: "https://cb",
+		AllowedDomains: []string{"example.ac.uk"},
+	}, nil)
+
+	trusted := map[string]bool{
+		"a@example.ac.uk":         true,
+		"a@student.example.ac.uk": true,
+		"a@EXAMPLE.AC.UK":         true,
+		"a@notexample.ac.uk":      false,
+		"a@example.ac.uk.evil.io": false,
+		"no-at-sign":              false,
+	}
+
+	for email, expected := range trusted {
+		if provider.IsTrustedDomain(email) != expected {
+			t.Errorf("IsTrustedDomain(%s) expected %t", email, expected)
+		}
+	}
+}
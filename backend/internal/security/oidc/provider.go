@@ -0,0 +1,395 @@
+// Package oidc implements OpenID Connect authorization-code login (with PKCE) against external identity providers.
+package oidc
+
+import (
+	"backend/internal/security"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// ProviderConfig holds the settings for a single identity provider e.g. a university SSO
+type ProviderConfig struct {
+	Name         string
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	Scopes       []string
+
+	// AllowedDomains are the email domains trusted for auto provisioning student accounts
+	AllowedDomains []string
+}
+
+// Discovery is the subset of the provider metadata document we use
+type Discovery struct {
+	Issuer                string   `json:"issuer"`
+	AuthorizationEndpoint string   `json:"authorization_endpoint"`
+	TokenEndpoint         string   `json:"token_endpoint"`
+	JWKSURI               string   `json:"jwks_uri"`
+	SigningAlgorithms     []string `json:"id_token_signing_alg_values_supported"`
+}
+
+// TokenResponse is the response from the provider token endpoint
+type TokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	IDToken     string `json:"id_token"`
+	ExpiresIn   int64  `json:"expires_in"`
+}
+
+// IDTokenClaims are the validated claims from an ID token
+type IDTokenClaims struct {
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+}
+
+// jwksRefreshInterval how often keys are refetched even if every kid is known
+const jwksRefreshInterval = time.Hour
+
+// jwksMinRefreshInterval stops an unknown kid from hammering the provider
+const jwksMinRefreshInterval = 10 * time.Second
+
+// clockSkew tolerance when checking iat/exp
+const clockSkew = time.Minute
+
+// Provider is an OpenID Connect identity provider. Discovery is done lazily on first use
+type Provider struct {
+	config ProviderConfig
+	client *http.Client
+
+	mutex       sync.Mutex
+	discovery   *Discovery
+	keys        *security.JSONWebKeySet
+	keysFetched time.Time
+}
+
+// NewProvider creates a provider for the given configuration
+func NewProvider(config ProviderConfig, client *http.Client) (*Provider, error) {
+	if config.Name == "" || config.Issuer == "" || config.ClientID == "" || config.RedirectURL == "" {
+		return nil, fmt.Errorf("provider name, issuer, client id and redirect url must be provided")
+	}
+
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+
+	if len(config.Scopes) == 0 {
+		config.Scopes = []string{"openid", "email", "profile"}
+	}
+
+	return &Provider{config: config, client: client}, nil
+}
+
+// Name returns the provider name used in routes
+func (provider *Provider) Name() string {
+	return provider.config.Name
+}
+
+// IsTrustedDomain returns whether the email belongs to one of the providers allowed domains
+func (provider *Provider) IsTrustedDomain(email string) bool {
+	at := strings.LastIndex(email, "@")
+	if at < 0 {
+		return false
+	}
+	domain := strings.ToLower(email[at+1:])
+
+	for _, allowed := range provider.config.AllowedDomains {
+		allowed = strings.ToLower(strings.TrimPrefix(allowed, "@"))
+		if domain == allowed || strings.HasSuffix(domain, "."+allowed) {
+			return true
+		}
+	}
+	return false
+}
+
+// Discover fetches (and caches) the providers metadata document
+func (provider *Provider) Discover(ctx context.Context) (*Discovery, error) {
+	provider.mutex.Lock()
+	defer provider.mutex.Unlock()
+	return provider.discoverLocked(ctx)
+}
+
+func (provider *Provider) discoverLocked(ctx context.Context) (*Discovery, error) {
+	if provider.discovery != nil {
+		return provider.discovery, nil
+	}
+
+	wellKnown := strings.TrimSuffix(provider.config.Issuer, "/") + "/.well-known/openid-configuration"
+
+	var discovery Discovery
+	err := provider.getJSON(ctx, wellKnown, &discovery)
+	if err != nil {
+		return nil, fmt.Errorf("discovery failed: %w", err)
+	}
+
+	if discovery.Issuer != provider.config.Issuer {
+		return nil, fmt.Errorf("issuer mismatch: expected %s got %s", provider.config.Issuer, discovery.Issuer)
+	}
+
+	if discovery.AuthorizationEndpoint == "" || discovery.TokenEndpoint == "" || discovery.JWKSURI == "" {
+		return nil, fmt.Errorf("discovery document missing required endpoints")
+	}
+
+	provider.discovery = &discovery
+	return provider.discovery, nil
+}
+
+// AuthCodeURL builds the URL the user agent is sent to for login
+func (provider *Provider) AuthCodeURL(ctx context.Context, state string, nonce string, codeVerifier string) (string, error) {
+	discovery, err := provider.Discover(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	values := url.Values{}
+	values.Set("response_type", "code")
+	values.Set("client_id", provider.config.ClientID)
+	values.Set("redirect_uri", provider.config.RedirectURL)
+	values.Set("scope", strings.Join(provider.config.Scopes, " "))
+	values.Set("state", state)
+	values.Set("nonce", nonce)
+	values.Set("code_challenge", CodeChallengeS256(codeVerifier))
+	values.Set("code_challenge_method", "S256")
+
+	separator := "?"
+	if strings.Contains(discovery.AuthorizationEndpoint, "?") {
+		separator = "&"
+	}
+
+	return discovery.AuthorizationEndpoint + separator + values.Encode(), nil
+}
+
+// Exchange swaps an authorization code (and its PKCE verifier) for tokens
+func (provider *Provider) Exchange(ctx context.Context, code string, codeVerifier string) (*TokenResponse, error) {
+	discovery, err := provider.Discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", provider.config.RedirectURL)
+	form.Set("client_id", provider.config.ClientID)
+	form.Set("code_verifier", codeVerifier)
+	if provider.config.ClientSecret != "" {
+		form.Set("client_secret", provider.config.ClientSecret)
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, discovery.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	request.Header.Set("Accept", "application/json")
+
+	res, err := provider.client.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(res.Body, 1<<20))
+	if err != nil {
+		return nil, err
+	}
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("token endpoint returned %d: %s", res.StatusCode, string(body))
+	}
+
+	var token TokenResponse
+	err = json.Unmarshal(body, &token)
+	if err != nil {
+		return nil, err
+	}
+
+	if token.IDToken == "" {
+		return nil, errors.New("token response did not include an id_token")
+	}
+
+	return &token, nil
+}
+
+// ValidateIDToken checks the signature and standard claims of an ID token and that it carries the expected nonce
+func (provider *Provider) ValidateIDToken(ctx context.Context, rawToken string, nonce string) (*IDTokenClaims, error) {
+	discovery, err := provider.Discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	algorithms, err := allowedAlgorithms(discovery.SigningAlgorithms)
+	if err != nil {
+		return nil, err
+	}
+
+	// Time based claims are checked below so clock skew can be allowed for
+	parser := jwt.Parser{
+		ValidMethods:         algorithms,
+		SkipClaimsValidation: true,
+	}
+
+	claims := jwt.MapClaims{}
+	_, err = parser.ParseWithClaims(rawToken, claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		return provider.verificationKey(ctx, kid)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("invalid id token: %w", err)
+	}
+
+	if !claims.VerifyIssuer(discovery.Issuer, true) {
+		return nil, errors.New("invalid id token issuer")
+	}
+
+	if !claims.VerifyAudience(provider.config.ClientID, true) {
+		return nil, errors.New("id token not issued for this client")
+	}
+
+	// If there are multiple audiences the authorized party has to be us
+	if audiences, ok := claims["aud"].([]interface{}); ok && len(audiences) > 1 {
+		if azp, _ := claims["azp"].(string); azp != provider.config.ClientID {
+			return nil, errors.New("invalid id token authorized party")
+		}
+	}
+
+	now := time.Now()
+	if !claims.VerifyExpiresAt(now.Add(-clockSkew).Unix(), true) {
+		return nil, errors.New("id token expired")
+	}
+
+	if !claims.VerifyIssuedAt(now.Add(clockSkew).Unix(), true) {
+		return nil, errors.New("id token issued in the future")
+	}
+
+	if tokenNonce, _ := claims["nonce"].(string); tokenNonce != nonce {
+		return nil, errors.New("id token nonce mismatch")
+	}
+
+	subject, _ := claims["sub"].(string)
+	if subject == "" {
+		return nil, errors.New("id token missing subject")
+	}
+
+	result := &IDTokenClaims{Subject: subject}
+	result.Email, _ = claims["email"].(string)
+	result.Name, _ = claims["name"].(string)
+
+	// Some providers send email_verified as a string
+	switch verified := claims["email_verified"].(type) {
+	case bool:
+		result.EmailVerified = verified
+	case string:
+		result.EmailVerified = verified == "true"
+	}
+
+	return result, nil
+}
+
+// verificationKey returns the public key for the given kid, refetching the JWKS if the kid is unknown
+func (provider *Provider) verificationKey(ctx context.Context, kid string) (interface{}, error) {
+	provider.mutex.Lock()
+	defer provider.mutex.Unlock()
+
+	now := time.Now()
+	stale := provider.keys == nil || now.Sub(provider.keysFetched) > jwksRefreshInterval
+
+	if !stale {
+		if key := lookupKey(provider.keys, kid); key != nil {
+			return key.PublicKey()
+		}
+		// Unknown kid most likely means the provider rotated keys
+		stale = now.Sub(provider.keysFetched) > jwksMinRefreshInterval
+	}
+
+	if stale {
+		discovery, err := provider.discoverLocked(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		var keys security.JSONWebKeySet
+		err = provider.getJSON(ctx, discovery.JWKSURI, &keys)
+		if err != nil {
+			return nil, fmt.Errorf("unable to fetch jwks: %w", err)
+		}
+		provider.keys = &keys
+		provider.keysFetched = now
+	}
+
+	key := lookupKey(provider.keys, kid)
+	if key == nil {
+		return nil, fmt.Errorf("no signing key found for kid %q", kid)
+	}
+
+	return key.PublicKey()
+}
+
+// lookupKey finds a key by kid. Tokens without a kid are only accepted when the set has a single key
+func lookupKey(keys *security.JSONWebKeySet, kid string) *security.JSONWebKey {
+	if kid == "" {
+		if len(keys.Keys) == 1 {
+			return &keys.Keys[0]
+		}
+		return nil
+	}
+	return keys.Key(kid)
+}
+
+func (provider *Provider) getJSON(ctx context.Context, endpoint string, target interface{}) error {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Accept", "application/json")
+
+	res, err := provider.client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s returned status %d", endpoint, res.StatusCode)
+	}
+
+	return json.NewDecoder(io.LimitReader(res.Body, 1<<20)).Decode(target)
+}
+
+// allowedAlgorithms filters the advertised algorithms down to asymmetric ones. HS* would let anyone with the client secret forge tokens.
+// It's an error if none are left, the parser accepts any algorithm when it isn't given any
+func allowedAlgorithms(advertised []string) ([]string, error) {
+	supported := map[string]bool{
+		"RS256": true, "RS384": true, "RS512": true,
+		"PS256": true, "PS384": true, "PS512": true,
+		"ES256": true, "ES384": true, "ES512": true,
+		"EdDSA": true,
+	}
+
+	if len(advertised) == 0 {
+		return []string{"RS256"}, nil
+	}
+
+	var allowed []string
+	for _, alg := range advertised {
+		if supported[alg] {
+			allowed = append(allowed, alg)
+		}
+	}
+	if len(allowed) == 0 {
+		return nil, fmt.Errorf("provider doesn't support any allowed id token algorithms, it offers %s", strings.Join(advertised, ", "))
+	}
+	return allowed, nil
+}
@@ -0,0 +1,36 @@
+package oidc
+
+import (
+	"fmt"
+	"sync"
+)
+
+var (
+	registryMutex sync.RWMutex
+	providers     = map[string]*Provider{}
+)
+
+// RegisterProvider adds a configured identity provider so the SSO routes can use it
+func RegisterProvider(config ProviderConfig) (*Provider, error) {
+	provider, err := NewProvider(config, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	registryMutex.Lock()
+	defer registryMutex.Unlock()
+
+	if _, exists := providers[config.Name]; exists {
+		return nil, fmt.Errorf("provider %s already registered", config.Name)
+	}
+
+	providers[config.Name] = provider
+	return provider, nil
+}
+
+// GetProvider returns the registered provider with the given name or nil
+func GetProvider(name string) *Provider {
+	registryMutex.RLock()
+	defer registryMutex.RUnlock()
+	return providers[name]
+}
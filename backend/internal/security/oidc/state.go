@@ -0,0 +1,100 @@
+package oidc
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// loginStateTTL is how long a user has to complete a login at the provider
+const loginStateTTL = 10 * time.Minute
+
+// LoginState is what we remember between sending the user to the provider and the callback
+type LoginState struct {
+	Provider     string
+	Nonce        string
+	CodeVerifier string
+
+	// LinkUserUUID is set when an existing account is linking an SSO identity rather than logging in
+	LinkUserUUID *uuid.UUID
+
+	expiresAt time.Time
+}
+
+// StateStore keeps pending logins keyed by the state parameter. Entries can only be used once
+type StateStore struct {
+	mutex  sync.Mutex
+	states map[string]*LoginState
+}
+
+// NewStateStore creates an empty state store
+func NewStateStore() *StateStore {
+	return &StateStore{states: make(map[string]*LoginState)}
+}
+
+// Begin creates a new pending login for the provider and returns the state key for it
+func (store *StateStore) Begin(provider string, linkUserUUID *uuid.UUID) (string, *LoginState, error) {
+	state, err := randomToken(24)
+	if err != nil {
+		return "", nil, err
+	}
+
+	nonce, err := randomToken(24)
+	if err != nil {
+		return "", nil, err
+	}
+
+	verifier, err := GenerateCodeVerifier()
+	if err != nil {
+		return "", nil, err
+	}
+
+	loginState := &LoginState{
+		Provider:     provider,
+		Nonce:        nonce,
+		CodeVerifier: verifier,
+		LinkUserUUID: linkUserUUID,
+		expiresAt:    time.Now().Add(loginStateTTL),
+	}
+
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	store.purgeExpiredLocked()
+	store.states[state] = loginState
+
+	return state, loginState, nil
+}
+
+// Consume removes and returns the pending login for the state if it exists and hasn't expired
+func (store *StateStore) Consume(state string, provider string) (*LoginState, error) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	loginState, ok := store.states[state]
+	if !ok {
+		return nil, errors.New("unknown or already used login state")
+	}
+	delete(store.states, state)
+
+	if time.Now().After(loginState.expiresAt) {
+		return nil, errors.New("login state expired")
+	}
+
+	if loginState.Provider != provider {
+		return nil, errors.New("login state was issued for a different provider")
+	}
+
+	return loginState, nil
+}
+
+func (store *StateStore) purgeExpiredLocked() {
+	now := time.Now()
+	for key, loginState := range store.states {
+		if now.After(loginState.expiresAt) {
+			delete(store.states, key)
+		}
+	}
+}
@@ -5,16 +5,60 @@ import (
 	"errors"
 	"fmt"
 	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
 	"net/http"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/golang-jwt/jwt/v4"
 )
 
-// TODO: maybe move to env?
-// Private token for signing
-var secretKey = []byte("SUPERSECRETTOKEN")
+// TokenIssuer is the iss claim on tokens we create, and the only issuer we accept
+var TokenIssuer = "greenuni"
+
+// TokenAudience is the aud claim on tokens we create, and the audience we require
+var TokenAudience = "greenuni-api"
+
+// tokenLifetime token expiration time = 3 days
+const tokenLifetime = time.Hour * 72
+
+// tokenClockSkew allowed between instances when checking iat and nbf
+const tokenClockSkew = time.Minute
+
+var (
+	keyringMutex sync.Mutex
+	keyring      *Keyring
+)
+
+// SetKeyring sets the keyring tokens are signed and verified with
+func SetKeyring(k *Keyring) {
+	keyringMutex.Lock()
+	defer keyringMutex.Unlock()
+	keyring = k
+}
+
+// GetKeyring returns the keyring in use. If none was configured an ephemeral key is generated,
+// meaning tokens won't survive a restart
+func GetKeyring() *Keyring {
+	keyringMutex.Lock()
+	defer keyringMutex.Unlock()
+
+	if keyring != nil {
+		return keyring
+	}
+
+	key, err := GenerateSigningKey("EdDSA")
+	if err != nil {
+		panic(err)
+	}
+
+	log.Warn("No JWT signing keys configured, using an ephemeral key")
+
+	keyring = NewKeyring()
+	keyring.AddKey(key, true)
+	return keyring
+}
 
 func ExtractUserInfoFromJWT(r *http.Request) (*models.UserInfoModel, error) {
 	authHeader := r.Header.Get("Authorization")
@@ -31,17 +75,24 @@ func ExtractUserInfoFromJWT(r *http.Request) (*models.UserInfoModel, error) {
 }
 
 func GenerateJWT(model *models.UserInfoModel) (string, error) {
+	key := GetKeyring().SigningKey()
+	now := time.Now()
 
 	claims := jwt.MapClaims{
 		"uuid":     model.UUID,
 		"username": model.Username,
 		"role":     model.Role.String(),
-		"exp":      time.Now().Add(time.Hour * 72).Unix(), // Token expiration time = 3 days
+		"iss":      TokenIssuer,
+		"aud":      TokenAudience,
+		"iat":      now.Unix(),
+		"jti":      uuid.NewString(),
+		"exp":      now.Add(tokenLifetime).Unix(),
 	}
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	token := jwt.NewWithClaims(key.Method, claims)
+	token.Header["kid"] = key.ID
 
-	// Sign the token with the secret key
-	tokenString, err := token.SignedString(secretKey)
+	// Sign the token with the current signing key
+	tokenString, err := token.SignedString(key.Private)
 	if err != nil {
 		return "", err
 	}
@@ -49,44 +100,106 @@ func GenerateJWT(model *models.UserInfoModel) (string, error) {
 }
 
 func parseJWT(tokenStr string) (*models.UserInfoModel, error) {
+	ring := GetKeyring()
+
+	// Time based claims are checked in validateStandardClaims so clock skew between instances is allowed for
+	parser := jwt.Parser{
+		ValidMethods:         []string{jwt.SigningMethodRS256.Alg(), jwt.SigningMethodEdDSA.Alg()},
+		SkipClaimsValidation: true,
+	}
 
-	token, err := jwt.Parse(tokenStr, func(token *jwt.Token) (interface{}, error) {
+	token, err := parser.Parse(tokenStr, func(token *jwt.Token) (interface{}, error) {
+		kid, ok := token.Header["kid"].(string)
+		if !ok {
+			return nil, fmt.Errorf("token has no key id")
+		}
+
+		key := ring.VerificationKey(kid)
+		if key == nil {
+			return nil, fmt.Errorf("unknown signing key")
+		}
 
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+		if token.Method.Alg() != key.Method.Alg() {
 			return nil, fmt.Errorf("unexpected signing method")
 		}
-		return []byte(secretKey), nil
+		return key.Public, nil
 	})
 
 	if err != nil {
 		return nil, err
 	}
 
-	if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
-		uuidStr, ok1 := claims["uuid"].(string)
-		username, ok2 := claims["username"].(string)
-		roleName, ok3 := claims["role"].(string)
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || !token.Valid {
+		return nil, fmt.Errorf("invalid token")
+	}
 
-		if !ok1 || !ok2 || !ok3 {
-			return nil, fmt.Errorf("invalid token claims")
-		}
+	err = validateStandardClaims(claims)
+	if err != nil {
+		return nil, err
+	}
 
-		userUUID, err := uuid.Parse(uuidStr)
-		if err != nil {
-			return nil, err
-		}
+	uuidStr, ok1 := claims["uuid"].(string)
+	username, ok2 := claims["username"].(string)
+	roleName, ok3 := claims["role"].(string)
 
-		roleType, err := models.ParseRoleType(roleName)
-		if err != nil {
-			return nil, err
-		}
+	if !ok1 || !ok2 || !ok3 {
+		return nil, fmt.Errorf("invalid token claims")
+	}
+
+	userUUID, err := uuid.Parse(uuidStr)
+	if err != nil {
+		return nil, err
+	}
+
+	roleType, err := models.ParseRoleType(roleName)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.UserInfoModel{
+		UUID:     userUUID,
+		Username: username,
+		Role:     roleType,
+	}, nil
+}
+
+// validateStandardClaims checks iss, aud, iat, exp and jti
+func validateStandardClaims(claims jwt.MapClaims) error {
+	now := time.Now()
+
+	if !claims.VerifyIssuer(TokenIssuer, true) {
+		return fmt.Errorf("invalid token issuer")
+	}
+
+	if !claims.VerifyAudience(TokenAudience, true) {
+		return fmt.Errorf("invalid token audience")
+	}
+
+	if _, ok := claims["iat"]; !ok {
+		return fmt.Errorf("token missing iat")
+	}
+
+	if !claims.VerifyIssuedAt(now.Add(tokenClockSkew).Unix(), true) {
+		return fmt.Errorf("token issued in the future")
+	}
+
+	if _, ok := claims["exp"]; !ok {
+		return fmt.Errorf("token missing exp")
+	}
+
+	if !claims.VerifyExpiresAt(now.Unix(), true) {
+		return fmt.Errorf("token expired")
+	}
+
+	if !claims.VerifyNotBefore(now.Add(tokenClockSkew).Unix(), false) {
+		return fmt.Errorf("token not valid yet")
+	}
 
-		return &models.UserInfoModel{
-			UUID:     userUUID,
-			Username: username,
-			Role:     roleType,
-		}, nil
+	jti, _ := claims["jti"].(string)
+	if _, err := uuid.Parse(jti); err != nil {
+		return fmt.Errorf("invalid token id")
 	}
 
-	return nil, fmt.Errorf("invalid token")
+	return nil
 }
@@ -0,0 +1,230 @@
+package security
+
+import (
+	"backend/internal/models"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/google/uuid"
+)
+
+func testUser() *models.UserInfoModel {
+	return &models.UserInfoModel{UUID: uuid.New(), Username: "jane", Role: models.Student}
+}
+
+func newTestKeyring(t *testing.T, algorithm string) (*Keyring, *SigningKey) {
+	key, err := GenerateSigningKey(algorithm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ring := NewKeyring()
+	if err := ring.AddKey(key, true); err != nil {
+		t.Fatal(err)
+	}
+	return ring, key
+}
+
+func bearerRequest(token string) *http.Request {
+	request, _ := http.NewRequest(http.MethodGet, "/", nil)
+	request.Header.Set("Authorization", "Bearer "+token)
+	return request
+}
+
+func TestGenerateAndExtract(t *testing.T) {
+	for _, algorithm := range []string{"RS256", "EdDSA"} {
+		t.Run(algorithm, func(t *testing.T) {
+			ring, _ := newTestKeyring(t, algorithm)
+			SetKeyring(ring)
+
+			user := testUser()
+			token, err := GenerateJWT(user)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			info, err := ExtractUserInfoFromJWT(bearerRequest(token))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if info.UUID != user.UUID || info.Username != user.Username || info.Role != user.Role {
+				t.Fatalf("unexpected user info %+v", info)
+			}
+		})
+	}
+}
+
+func TestKeyRotation(t *testing.T) {
+	ring, oldKey := newTestKeyring(t, "EdDSA")
+	SetKeyring(ring)
+
+	oldToken, err := GenerateJWT(testUser())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	newKey, err := GenerateSigningKey("RS256")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ring.AddKey(newKey, true); err != nil {
+		t.Fatal(err)
+	}
+
+	// Tokens from before the rotation still work
+	if _, err := parseJWT(oldToken); err != nil {
+		t.Fatalf("old token rejected during rotation: %s", err)
+	}
+
+	newToken, _ := GenerateJWT(testUser())
+	parsed, _, _ := new(jwt.Parser).ParseUnverified(newToken, jwt.MapClaims{})
+	if parsed.Header["kid"] != newKey.ID {
+		t.Fatalf("expected new tokens to be signed with %s", newKey.ID)
+	}
+
+	if err := ring.Retire(newKey.ID); err == nil {
+		t.Fatal("expected retiring the signing key to fail")
+	}
+
+	if err := ring.Retire(oldKey.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := parseJWT(oldToken); err == nil {
+		t.Fatal("expected token signed with retired key to be rejected")
+	}
+
+	if _, err := parseJWT(newToken); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRejectsInvalidClaims(t *testing.T) {
+	ring, key := newTestKeyring(t, "EdDSA")
+	SetKeyring(ring)
+
+	valid := func() jwt.MapClaims {
+		return jwt.MapClaims{
+			"uuid":     uuid.NewString(),
+			"username": "jane",
+			"role":     "Student",
+			"iss":      TokenIssuer,
+			"aud":      TokenAudience,
+			"iat":      time.Now().Unix(),
+			"exp":      time.Now().Add(time.Hour).Unix(),
+			"jti":      uuid.NewString(),
+		}
+	}
+
+	cases := map[string]func(claims jwt.MapClaims){
+		"issuer":      func(c jwt.MapClaims) { c["iss"] = "someone-else" },
+		"audience":    func(c jwt.MapClaims) { c["aud"] = "another-api" },
+		"missing iat": func(c jwt.MapClaims) { delete(c, "iat") },
+		"future iat":  func(c jwt.MapClaims) { c["iat"] = time.Now().Add(time.Hour).Unix() },
+		"expired":     func(c jwt.MapClaims) { c["exp"] = time.Now().Add(-time.Hour).Unix() },
+		"missing jti": func(c jwt.MapClaims) { delete(c, "jti") },
+	}
+
+	for name, mutate := range cases {
+		t.Run(name, func(t *testing.T) {
+			claims := valid()
+			mutate(claims)
+
+			token := jwt.NewWithClaims(key.Method, claims)
+			token.Header["kid"] = key.ID
+			signed, err := token.SignedString(key.Private)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if _, err := parseJWT(signed); err == nil {
+				t.Fatal("expected token to be rejected")
+			}
+		})
+	}
+}
+
+func TestRejectsHMACTokens(t *testing.T) {
+	ring, key := newTestKeyring(t, "EdDSA")
+	SetKeyring(ring)
+
+	// Classic algorithm confusion, sign with HS256 using the public key as the secret
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"uuid": uuid.NewString(), "username": "jane", "role": "Admin",
+		"iss": TokenIssuer, "aud": TokenAudience, "iat": time.Now().Unix(),
+		"exp": time.Now().Add(time.Hour).Unix(), "jti": uuid.NewString(),
+	})
+	token.Header["kid"] = key.ID
+	signed, err := token.SignedString([]byte(key.JSONWebKey().X))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := parseJWT(signed); err == nil {
+		t.Fatal("expected HMAC signed token to be rejected")
+	}
+}
+
+func TestJSONWebKeySetRoundTrip(t *testing.T) {
+	ring, _ := newTestKeyring(t, "RS256")
+	edKey, _ := GenerateSigningKey("EdDSA")
+	ring.AddKey(edKey, false)
+
+	set := ring.JSONWebKeySet()
+	if len(set.Keys) != 2 {
+		t.Fatalf("expected 2 keys got %d", len(set.Keys))
+	}
+
+	for _, jwk := range set.Keys {
+		public, err := jwk.PublicKey()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		key, err := newVerificationKey(public)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if key.ID != jwk.KeyID {
+			t.Fatalf("thumbprint mismatch %s != %s", key.ID, jwk.KeyID)
+		}
+	}
+}
+
+func TestLoadKeyring(t *testing.T) {
+	directory := t.TempDir()
+
+	oldKey, _ := GenerateSigningKey("RS256")
+	newKey, _ := GenerateSigningKey("EdDSA")
+
+	for i, key := range []*SigningKey{oldKey, newKey} {
+		data, err := EncodePrivateKeyPEM(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		path := filepath.Join(directory, key.Method.Alg()+".pem")
+		if err := os.WriteFile(path, data, 0600); err != nil {
+			t.Fatal(err)
+		}
+		modified := time.Now().Add(time.Duration(i-2) * time.Hour)
+		os.Chtimes(path, modified, modified)
+	}
+
+	ring, err := LoadKeyring(directory)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if ring.SigningKey().ID != newKey.ID {
+		t.Fatal("expected most recent key to be used for signing")
+	}
+
+	if ring.VerificationKey(oldKey.ID) == nil {
+		t.Fatal("expected older key to still verify")
+	}
+}
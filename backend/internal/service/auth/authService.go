@@ -6,6 +6,7 @@ import (
 	"backend/internal/models"
 	"backend/internal/models/auth"
 	"backend/internal/security"
+	"context"
 	"github.com/google/uuid"
 	log "github.com/sirupsen/logrus"
 )
@@ -20,13 +21,13 @@ func NewAuthService(repo *repositories.UserRepository) *Service {
 }
 
 // Signup creates a new user with the given username and password.
-func (service *Service) Signup(username string, password string, email string, role string) *auth.SignupStatus {
+func (service *Service) Signup(ctx context.Context, username string, password string, email string, role string) *auth.SignupStatus {
 
 	if username == "" || password == "" || email == "" || role == "" {
 		return setSignupStatus(nil, "Invalid request format", false)
 	}
 
-	name, err := service.repo.GetUserByName(username, mysql.QueryOptions{})
+	name, err := service.repo.GetUserByName(ctx, username, mysql.QueryOptions{})
 	if err != nil {
 		return setSignupStatus(nil, "error creating account", false)
 	}
@@ -35,7 +36,7 @@ func (service *Service) Signup(username string, password string, email string, r
 		return setSignupStatus(nil, "An account with this name already exists", false)
 	}
 
-	name, err = service.repo.GetUserByEmail(email, mysql.QueryOptions{})
+	name, err = service.repo.GetUserByEmail(ctx, email, mysql.QueryOptions{})
 	if err != nil {
 		return setSignupStatus(nil, "error creating account", false)
 	}
@@ -71,7 +72,7 @@ func (service *Service) Signup(username string, password string, email string, r
 		Role:           parsedRole,
 	}
 
-	err = service.repo.AddUser(user, mysql.InsertOptions{})
+	err = service.repo.AddUser(ctx, user, mysql.InsertOptions{})
 	if err != nil {
 		return setSignupStatus(nil, "error creating account", false)
 	}
@@ -94,13 +95,13 @@ func setSignupStatus(model *models.UserInfoModel, message string, loginSuccess b
 	return &signupStatus
 }
 
-func (service *Service) Login(username string, password string) *auth.LoginStatus {
+func (service *Service) Login(ctx context.Context, username string, password string) *auth.LoginStatus {
 
 	if username == "" || password == "" {
 		return setLoginStatus("invalid request format", false)
 	}
 
-	user, err := service.repo.GetUserByName(username, mysql.QueryOptions{})
+	user, err := service.repo.GetUserByName(ctx, username, mysql.QueryOptions{})
 	if err != nil {
 		return setLoginStatus("internal error occurred whilst fetching user", false)
 	}
@@ -124,6 +125,11 @@ func (service *Service) Login(username string, password string) *auth.LoginStatu
 		Role:     user.Role,
 	}
 
+	return loginSuccess(userInfo)
+}
+
+// loginSuccess creates the JWT for a user and wraps it in a successful login status
+func loginSuccess(userInfo *models.UserInfoModel) *auth.LoginStatus {
 	jwt, err := security.GenerateJWT(userInfo)
 	if err != nil {
 		log.Error(err)
@@ -0,0 +1,237 @@
+package auth
+
+import (
+	"backend/internal/db/adapters/mysql"
+	"backend/internal/db/repositories"
+	"backend/internal/models"
+	"backend/internal/models/auth"
+	"backend/internal/security"
+	"backend/internal/security/oidc"
+	stringutils "backend/internal/utils/string"
+	"context"
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+	"regexp"
+	"strings"
+)
+
+// SSOService handles logins and account linking through OpenID Connect providers
+type SSOService struct {
+	userRepo     *repositories.UserRepository
+	identityRepo *repositories.IdentityRepository
+	states       *oidc.StateStore
+}
+
+// NewSSOService creates a new instance of SSOService.
+func NewSSOService(userRepo *repositories.UserRepository, identityRepo *repositories.IdentityRepository) *SSOService {
+	return &SSOService{
+		userRepo:     userRepo,
+		identityRepo: identityRepo,
+		states:       oidc.NewStateStore(),
+	}
+}
+
+// BeginLogin returns the provider URL to send the user to. linkUserUUID is set when linking to an existing account
+func (service *SSOService) BeginLogin(ctx context.Context, providerName string, linkUserUUID *uuid.UUID) *auth.SSORedirect {
+	provider := oidc.GetProvider(providerName)
+	if provider == nil {
+		return &auth.SSORedirect{Message: "unknown identity provider"}
+	}
+
+	state, loginState, err := service.states.Begin(providerName, linkUserUUID)
+	if err != nil {
+		log.Error(err)
+		return &auth.SSORedirect{Message: "error starting login"}
+	}
+
+	url, err := provider.AuthCodeURL(ctx, state, loginState.Nonce, loginState.CodeVerifier)
+	if err != nil {
+		log.Error(err)
+		return &auth.SSORedirect{Message: "identity provider unavailable"}
+	}
+
+	return &auth.SSORedirect{Success: true, AuthorizationURL: url}
+}
+
+// Callback completes a login started with BeginLogin. It either logs in the linked user, links the identity to the
+// account that started the flow, or provisions a new student account for trusted university domains
+func (service *SSOService) Callback(ctx context.Context, providerName string, state string, code string) *auth.LoginStatus {
+	if state == "" || code == "" {
+		return setLoginStatus("invalid request format", false)
+	}
+
+	provider := oidc.GetProvider(providerName)
+	if provider == nil {
+		return setLoginStatus("unknown identity provider", false)
+	}
+
+	loginState, err := service.states.Consume(state, providerName)
+	if err != nil {
+		return setLoginStatus(err.Error(), false)
+	}
+
+	token, err := provider.Exchange(ctx, code, loginState.CodeVerifier)
+	if err != nil {
+		log.Error(err)
+		return setLoginStatus("unable to complete login with identity provider", false)
+	}
+
+	claims, err := provider.ValidateIDToken(ctx, token.IDToken, loginState.Nonce)
+	if err != nil {
+		log.Error(err)
+		return setLoginStatus("identity provider returned an invalid token", false)
+	}
+
+	identity, err := service.identityRepo.GetIdentity(ctx, providerName, claims.Subject)
+	if err != nil {
+		return setLoginStatus("internal error occurred whilst fetching identity", false)
+	}
+
+	if loginState.LinkUserUUID != nil {
+		return service.link(ctx, providerName, claims, identity, *loginState.LinkUserUUID)
+	}
+
+	if identity != nil {
+		return service.loginLinkedUser(ctx, identity.UserUUID)
+	}
+
+	return service.provision(ctx, provider, claims)
+}
+
+// link attaches the identity to an already logged in account
+func (service *SSOService) link(ctx context.Context, providerName string, claims *oidc.IDTokenClaims, existing *models.UserIdentityModel, userUUID uuid.UUID) *auth.LoginStatus {
+	if existing != nil {
+		if existing.UserUUID == userUUID {
+			return service.loginLinkedUser(ctx, userUUID)
+		}
+		return setLoginStatus("this identity is already linked to another account", false)
+	}
+
+	err := service.identityRepo.LinkIdentity(ctx, &models.UserIdentityModel{
+		Provider: providerName,
+		Subject:  claims.Subject,
+		UserUUID: userUUID,
+		Email:    claims.Email,
+	})
+	if err != nil {
+		// Unique (provider, userUUID) means the account already has an identity for this provider
+		return setLoginStatus("account already linked to this identity provider", false)
+	}
+
+	return service.loginLinkedUser(ctx, userUUID)
+}
+
+// provision creates a student account for a first time SSO login from a trusted domain
+func (service *SSOService) provision(ctx context.Context, provider *oidc.Provider, claims *oidc.IDTokenClaims) *auth.LoginStatus {
+	if claims.Email == "" || !claims.EmailVerified || !provider.IsTrustedDomain(claims.Email) {
+		return setLoginStatus("no account is linked to this identity. Log in with your password and link it from your profile", false)
+	}
+
+	existing, err := service.userRepo.GetUserByEmail(ctx, claims.Email, mysql.QueryOptions{})
+	if err != nil {
+		return setLoginStatus("internal error occurred whilst fetching user", false)
+	}
+
+	// Don't silently take over a password account, the owner has to link it themselves
+	if existing != nil {
+		return setLoginStatus("an account with this email already exists. Log in with your password and link it from your profile", false)
+	}
+
+	username, err := service.availableUsername(ctx, claims.Email)
+	if err != nil {
+		return setLoginStatus("error creating account", false)
+	}
+
+	// SSO only accounts get a random password nobody knows
+	salt, err := security.GenerateSalt(16)
+	if err != nil {
+		return setLoginStatus("error creating account", false)
+	}
+
+	password, err := security.GenerateSalt(32)
+	if err != nil {
+		return setLoginStatus("error creating account", false)
+	}
+
+	hashedPassword, err := security.HashPassword(password, salt)
+	if err != nil {
+		return setLoginStatus("error creating account", false)
+	}
+
+	user := &models.UserModel{
+		UUID:           uuid.New(),
+		Username:       username,
+		Email:          claims.Email,
+		HashedPassword: hashedPassword,
+		Salt:           salt,
+		Role:           models.Student,
+	}
+
+	// An account whose identity couldn't be linked couldn't be logged into, so neither is kept without the other
+	err = service.userRepo.Repository.WithTx(ctx, func(tx *mysql.Tx) error {
+		if err := service.userRepo.AddUser(tx.Context(), user, mysql.InsertOptions{}); err != nil {
+			return err
+		}
+		return service.identityRepo.LinkIdentity(tx.Context(), &models.UserIdentityModel{
+			Provider: provider.Name(),
+			Subject:  claims.Subject,
+			UserUUID: user.UUID,
+			Email:    claims.Email,
+		})
+	})
+	if err != nil {
+		return setLoginStatus("error creating account", false)
+	}
+
+	return loginSuccess(&models.UserInfoModel{
+		UUID:     user.UUID,
+		Username: user.Username,
+		Role:     user.Role,
+	})
+}
+
+func (service *SSOService) loginLinkedUser(ctx context.Context, userUUID uuid.UUID) *auth.LoginStatus {
+	users, err := service.userRepo.GetUserByID(ctx, userUUID)
+	if err != nil {
+		return setLoginStatus("internal error occurred whilst fetching user", false)
+	}
+
+	if users == nil {
+		return setLoginStatus("user doesn't exist", false)
+	}
+
+	user := (*users)[0]
+
+	return loginSuccess(&models.UserInfoModel{
+		UUID:     user.UUID,
+		Username: user.Username,
+		Role:     user.Role,
+	})
+}
+
+var usernameCharacters = regexp.MustCompile(`[^a-zA-Z0-9._-]`)
+
+// availableUsername derives a username from the email local part, adding a suffix if it's taken
+func (service *SSOService) availableUsername(ctx context.Context, email string) (string, error) {
+	base := usernameCharacters.ReplaceAllString(strings.SplitN(email, "@", 2)[0], "")
+	if base == "" {
+		base = "student"
+	}
+	if len(base) > 50 {
+		base = base[:50]
+	}
+
+	candidate := base
+	for attempt := 0; attempt < 5; attempt++ {
+		existing, err := service.userRepo.GetUserByName(ctx, candidate, mysql.QueryOptions{})
+		if err != nil {
+			return "", err
+		}
+		if existing == nil {
+			return candidate, nil
+		}
+		candidate = base + "_" + strings.ToLower(stringutils.GenerateRandomString(4))
+	}
+
+	return base + "_" + uuid.NewString()[:8], nil
+}
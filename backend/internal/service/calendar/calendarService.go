@@ -0,0 +1,261 @@
+package calendar
+
+import (
+	"backend/internal/db/repositories"
+	"backend/internal/ical"
+	"backend/internal/models"
+	"backend/internal/schedule"
+	response "backend/internal/utils/http"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+	"strings"
+	"time"
+)
+
+const productID = "-//GreenUni//Opportunities//EN"
+
+// refreshInterval is how often calendar apps are asked to refetch feeds
+const refreshInterval = time.Hour
+
+// uidDomain makes event UIDs globally unique, they must never change for an opportunity
+const uidDomain = "greenuni"
+
+// Service builds iCalendar files for opportunities and users' subscription feeds
+type Service struct {
+	repo            *repositories.CalendarRepository
+	opportunityRepo *repositories.OpportunityRepository
+}
+
+// NewCalendarService creates a new instance of the calendar Service
+func NewCalendarService(repo *repositories.CalendarRepository, opportunityRepo *repositories.OpportunityRepository) *Service {
+	return &Service{repo: repo, opportunityRepo: opportunityRepo}
+}
+
+// OpportunityCalendar returns a calendar with the single opportunity in it
+func (service *Service) OpportunityCalendar(ctx context.Context, opportunityID string) (*ical.Calendar, *response.Response) {
+	opportunityUUID, err := uuid.Parse(opportunityID)
+	if err != nil {
+		return nil, response.ErrorResponse("Unable to parse opportunity uuid")
+	}
+
+	opportunities, err := service.opportunityRepo.GetOpportunity(ctx, &opportunityUUID)
+	if errorResponse := response.ContextError(err); errorResponse != nil {
+		return nil, errorResponse
+	}
+	if err != nil {
+		log.Error(err)
+		return nil, response.ErrorResponse("Internal error occurred")
+	}
+	if opportunities == nil || len(*opportunities) == 0 {
+		return nil, response.ErrorResponse("Opportunity not found")
+	}
+
+	opportunity := (*opportunities)[0]
+	if opportunity.StartsAt == nil {
+		return nil, response.ErrorResponse("Opportunity has no set times")
+	}
+
+	return &ical.Calendar{ProductID: productID, Events: []ical.Event{opportunityEvent(&opportunity)}}, nil
+}
+
+// CreateFeed gives the user a new feed URL under baseURL, any previous one stops working
+func (service *Service) CreateFeed(ctx context.Context, user *models.UserInfoModel, baseURL string) *response.Response {
+	token, err := generateFeedToken()
+	if err != nil {
+		log.Error(err)
+		return response.ErrorResponse("Internal error occurred")
+	}
+
+	if err := service.repo.SetFeedToken(ctx, user.UUID, hashFeedToken(token)); err != nil {
+		if errorResponse := response.ContextError(err); errorResponse != nil {
+			return errorResponse
+		}
+		return response.ErrorResponse("Internal error occurred")
+	}
+
+	url := fmt.Sprintf("%s/feed/%s.ics", strings.TrimSuffix(baseURL, "/"), token)
+	return response.SuccessResponse(models.CalendarFeedModel{
+		URL:       url,
+		WebcalURL: "webcal://" + strings.TrimPrefix(strings.TrimPrefix(url, "https://"), "http://"),
+	}, "")
+}
+
+// RevokeFeed stops the users feed URL working
+func (service *Service) RevokeFeed(ctx context.Context, user *models.UserInfoModel) *response.Response {
+	if err := service.repo.DeleteFeedToken(ctx, user.UUID); err != nil {
+		if errorResponse := response.ContextError(err); errorResponse != nil {
+			return errorResponse
+		}
+		return response.ErrorResponse("Internal error occurred")
+	}
+	return response.SuccessResponse(nil, "Calendar feed revoked")
+}
+
+// Feed returns the calendar of the opportunities the feed's user has a place on. Opportunities they've
+// cancelled or that were deleted are kept as cancelled events so calendar apps remove them
+func (service *Service) Feed(ctx context.Context, token string) (*ical.Calendar, *response.Response) {
+	userUUID, err := service.repo.GetFeedUser(ctx, hashFeedToken(token))
+	if errorResponse := response.ContextError(err); errorResponse != nil {
+		return nil, errorResponse
+	}
+	if err != nil {
+		return nil, response.ErrorResponse("Internal error occurred")
+	}
+	if userUUID == nil {
+		return nil, response.ErrorResponse("Calendar feed not found")
+	}
+
+	since := time.Now().Add(-repositories.CancellationRetention)
+
+	entries, err := service.repo.GetEntries(ctx, *userUUID, since)
+	if errorResponse := response.ContextError(err); errorResponse != nil {
+		return nil, errorResponse
+	}
+	if err != nil {
+		return nil, response.ErrorResponse("Internal error occurred")
+	}
+
+	cancellations, err := service.repo.GetCancellations(ctx, *userUUID, since)
+	if errorResponse := response.ContextError(err); errorResponse != nil {
+		return nil, errorResponse
+	}
+	if err != nil {
+		return nil, response.ErrorResponse("Internal error occurred")
+	}
+
+	calendar := &ical.Calendar{ProductID: productID, Name: "GreenUni opportunities", RefreshInterval: refreshInterval}
+
+	if len(entries) > 0 {
+		opportunityUUIDs := make([]*uuid.UUID, len(entries))
+		cancelled := map[uuid.UUID]bool{}
+		for i := range entries {
+			opportunityUUIDs[i] = &entries[i].OpportunityUUID
+			cancelled[entries[i].OpportunityUUID] = entries[i].Cancelled
+		}
+
+		opportunities, err := service.opportunityRepo.GetOpportunity(ctx, opportunityUUIDs...)
+		if errorResponse := response.ContextError(err); errorResponse != nil {
+			return nil, errorResponse
+		}
+		if err != nil {
+			log.Error(err)
+			return nil, response.ErrorResponse("Internal error occurred")
+		}
+
+		if opportunities != nil {
+			for i := range *opportunities {
+				event := opportunityEvent(&(*opportunities)[i])
+				if cancelled[(*opportunities)[i].UUID] {
+					event.Status = ical.StatusCancelled
+				}
+				calendar.Events = append(calendar.Events, event)
+			}
+		}
+	}
+
+	for i := range cancellations {
+		calendar.Events = append(calendar.Events, cancellationEvent(&cancellations[i]))
+	}
+
+	return calendar, nil
+}
+
+// opportunityEvent returns the opportunity as an event, it must have set times
+func opportunityEvent(opportunity *models.OpportunityModel) ical.Event {
+	location := loadLocation(opportunity.TimeZone)
+
+	event := ical.Event{
+		UID:          eventUID(opportunity.UUID),
+		Sequence:     opportunity.Sequence,
+		Summary:      opportunity.Title,
+		Description:  eventDescription(opportunity),
+		Location:     eventLocation(opportunity),
+		Start:        opportunity.StartsAt.In(location),
+		RRule:        opportunity.Recurrence,
+		Created:      opportunity.CreatedAt,
+		LastModified: opportunity.UpdatedAt,
+	}
+	if opportunity.EndsAt != nil {
+		event.End = opportunity.EndsAt.In(location)
+	}
+	if opportunity.Coordinates != nil {
+		event.Geo = &[2]float64{opportunity.Coordinates.Latitude, opportunity.Coordinates.Longitude}
+	}
+	return event
+}
+
+// cancellationEvent returns a deleted opportunity as a cancelled event, with the same UID as before it was deleted
+func cancellationEvent(cancellation *models.CalendarCancellationModel) ical.Event {
+	location := loadLocation(cancellation.TimeZone)
+
+	event := ical.Event{
+		UID:          eventUID(cancellation.OpportunityUUID),
+		Sequence:     cancellation.Sequence,
+		Status:       ical.StatusCancelled,
+		Summary:      cancellation.Title,
+		Location:     cancellation.Location,
+		Start:        cancellation.StartsAt.In(location),
+		RRule:        cancellation.Recurrence,
+		Created:      cancellation.CreatedAt,
+		LastModified: cancellation.CancelledAt,
+	}
+	if cancellation.EndsAt != nil {
+		event.End = cancellation.EndsAt.In(location)
+	}
+	return event
+}
+
+func eventUID(opportunityUUID uuid.UUID) string {
+	return opportunityUUID.String() + "@" + uidDomain
+}
+
+func eventDescription(opportunity *models.OpportunityModel) string {
+	description := opportunity.Description
+	if opportunity.Points > 0 {
+		description = strings.TrimSpace(fmt.Sprintf("%s\n\nEarn %d points", description, opportunity.Points))
+	}
+	return description
+}
+
+func eventLocation(opportunity *models.OpportunityModel) string {
+	if address := opportunity.Address.Query(); address != "" {
+		return address
+	}
+	if opportunity.Location != "" {
+		return opportunity.Location
+	}
+	if opportunity.IsOnline {
+		return "Online"
+	}
+	return ""
+}
+
+// loadLocation loads the opportunities timezone, falling back to UTC for one that's no longer known
+func loadLocation(name string) *time.Location {
+	location, err := schedule.LoadLocation(name)
+	if err != nil {
+		log.Error(err)
+		return time.UTC
+	}
+	return location
+}
+
+func generateFeedToken() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// hashFeedToken only the hash is stored so a leaked table can't be used to read users' feeds
+func hashFeedToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
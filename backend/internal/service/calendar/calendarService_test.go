@@ -0,0 +1,60 @@
+package calendar
+
+import (
+	"backend/internal/geo"
+	"backend/internal/ical"
+	"backend/internal/models"
+	"github.com/google/uuid"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestOpportunityEventIsInItsTimeZone(t *testing.T) {
+	starts := time.Date(2025, 7, 5, 9, 0, 0, 0, time.UTC)
+	ends := starts.Add(2 * time.Hour)
+	opportunity := &models.OpportunityModel{
+		UUID:        uuid.MustParse("5b2f8f4e-3c1d-4d5e-9f6a-7b8c9d0e1f2a"),
+		Title:       "Litter pick",
+		Points:      20,
+		Address:     geo.Address{Line: "Pier Head", City: "Liverpool"},
+		Coordinates: &geo.Coordinates{Latitude: 53.4, Longitude: -2.99},
+		StartsAt:    &starts,
+		EndsAt:      &ends,
+		TimeZone:    "Europe/London",
+		Recurrence:  "FREQ=WEEKLY;BYDAY=SA",
+		Sequence:    2,
+	}
+
+	event := opportunityEvent(opportunity)
+
+	if event.UID != "5b2f8f4e-3c1d-4d5e-9f6a-7b8c9d0e1f2a@greenuni" || event.Sequence != 2 {
+		t.Fatalf("unexpected uid %s sequence %d", event.UID, event.Sequence)
+	}
+	if event.Start.Location().String() != "Europe/London" || event.Start.Hour() != 10 {
+		t.Fatalf("expected 10:00 London time, got %v", event.Start)
+	}
+	if event.Geo == nil || event.Location != "Pier Head, Liverpool" {
+		t.Fatalf("unexpected location %q %v", event.Location, event.Geo)
+	}
+	if !strings.Contains(event.Description, "Earn 20 points") {
+		t.Fatalf("unexpected description %q", event.Description)
+	}
+}
+
+func TestCancellationEventKeepsUID(t *testing.T) {
+	opportunityUUID := uuid.New()
+	cancellation := &models.CalendarCancellationModel{
+		OpportunityUUID: opportunityUUID,
+		Title:           "Tree planting",
+		StartsAt:        time.Date(2025, 7, 5, 9, 0, 0, 0, time.UTC),
+		TimeZone:        "Europe/London",
+		Sequence:        3,
+	}
+
+	event := cancellationEvent(cancellation)
+
+	if event.UID != eventUID(opportunityUUID) || event.Status != ical.StatusCancelled || event.Sequence != 3 {
+		t.Fatalf("unexpected cancellation %+v", event)
+	}
+}
@@ -5,6 +5,10 @@ import (
 	"backend/internal/models"
 	"backend/internal/service/user"
 	response "backend/internal/utils/http"
+	"context"
+	"errors"
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
 )
 
 type Service struct {
@@ -17,17 +21,32 @@ func NewMatchesService(repo *repositories.MatchesRepository, repository *reposit
 	return &Service{repo: repo, userRepo: repository}
 }
 
-func (service *Service) Match(uuid1 string, uuid2 string) *response.Response {
+func (service *Service) Match(ctx context.Context, uuid1 string, uuid2 string) *response.Response {
+	userUUID, err := uuid.Parse(uuid1)
+	if err != nil {
+		return response.ErrorResponse("Invalid uuid1")
+	}
+	matchedUUID, err := uuid.Parse(uuid2)
+	if err != nil {
+		return response.ErrorResponse("Invalid uuid2")
+	}
 
-	err := service.repo.CreateMatch(uuid1, uuid2)
+	err = service.repo.CreateMatch(ctx, userUUID, matchedUUID)
+	if errorResponse := response.ContextError(err); errorResponse != nil {
+		return errorResponse
+	}
+	if errors.Is(err, repositories.ErrSelfMatch) {
+		return response.ErrorResponse("Users can't match with themselves")
+	}
 	if err != nil {
+		log.Error(err)
 		return response.ErrorResponse("Internal error occurred")
 	}
 
 	return response.SuccessResponse(nil, "")
 }
 
-func (service *Service) GetMatches(userID string) *response.Response {
+func (service *Service) GetMatches(ctx context.Context, userID string) *response.Response {
 	allIds, err := service.repo.GetMatches(userID)
 
 	if userID == "" {
@@ -42,7 +61,10 @@ func (service *Service) GetMatches(userID string) *response.Response {
 		return response.ErrorResponse("Internal error occurred")
 	}
 
-	id, err := service.userRepo.GetUserByID(*allIds...)
+	id, err := service.userRepo.GetUserByID(ctx, *allIds...)
+	if errorResponse := response.ContextError(err); errorResponse != nil {
+		return errorResponse
+	}
 	if err != nil {
 		return response.ErrorResponse("Interal error occurred")
 	}
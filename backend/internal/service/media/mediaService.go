@@ -0,0 +1,411 @@
+package media
+
+import (
+	"backend/internal/blob"
+	"backend/internal/db/adapters/mysql"
+	"backend/internal/db/repositories"
+	"backend/internal/imaging"
+	"backend/internal/models"
+	"backend/internal/queue"
+	response "backend/internal/utils/http"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// ErrInvalidMedia is wrapped by errors from Resolve that are the clients fault, their message is safe to return
+var ErrInvalidMedia = errors.New("invalid media")
+
+// The store and signer every Service uses, set at startup by Configure
+var (
+	store  blob.Store
+	signer = NewEphemeralSigner("")
+)
+
+// Configure sets where media is stored and how download URLs are signed
+func Configure(mediaStore blob.Store, urlSigner *Signer) {
+	store, signer = mediaStore, urlSigner
+}
+
+// Service stores uploads and resolves the media IDs other requests reference
+type Service struct {
+	repo   *repositories.MediaRepository
+	store  blob.Store
+	signer *Signer
+	// pipeline makes image variants, nil until StartProcessing. Variants of images uploaded without it stay
+	// pending until a service that processes them starts
+	pipeline *Pipeline
+}
+
+// NewMediaService creates a new instance of the media Service using the configured store and signer
+func NewMediaService(repo *repositories.MediaRepository) *Service {
+	return &Service{repo: repo, store: store, signer: signer}
+}
+
+// StartProcessing makes variants of uploaded images on the job queue, at most workers at a time, and enqueues the
+// images left without a job
+func (service *Service) StartProcessing(workers int) {
+	if service.store == nil || queue.Default == nil {
+		return
+	}
+
+	service.pipeline = NewPipeline(service.repo, service.store, queue.Default, workers)
+	if err := service.pipeline.Resume(context.Background()); err != nil {
+		log.Error("Failed to enqueue processing uploaded images: ", err)
+	}
+}
+
+// Upload stores the file, detecting its type from the content. Uploading the same file twice returns the first upload
+func (service *Service) Upload(ctx context.Context, user *models.UserInfoModel, content io.Reader) *response.Response {
+	if service.store == nil {
+		return response.ErrorResponse("Media storage isn't configured")
+	}
+
+	head := make([]byte, sniffLength)
+	n, err := io.ReadFull(content, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		log.Error(err)
+		return response.ErrorResponse("Unable to read file")
+	}
+	if n == 0 {
+		return response.ErrorResponse("File is empty")
+	}
+	head = head[:n]
+
+	contentType, allowed, err := sniff(head)
+	if err != nil {
+		return response.ErrorResponse("File must be a JPEG, PNG, GIF or WebP image or an MP4 or WebM video")
+	}
+
+	// Spooled to disk while hashing, the key isn't known until the whole file has been read
+	spool, err := os.CreateTemp("", "media-*")
+	if err != nil {
+		log.Error(err)
+		return response.ErrorResponse("Internal error occurred")
+	}
+	defer os.Remove(spool.Name())
+	defer spool.Close()
+
+	hash := sha256.New()
+	size, err := io.Copy(io.MultiWriter(spool, hash), io.LimitReader(io.MultiReader(bytes.NewReader(head), content), allowed.maxSize+1))
+	if err != nil {
+		log.Error(err)
+		return response.ErrorResponse("Unable to read file")
+	}
+	if size > allowed.maxSize {
+		return response.ErrorResponse(fmt.Sprintf("File is too large, the limit is %d MB", allowed.maxSize>>20))
+	}
+
+	digest := hex.EncodeToString(hash.Sum(nil))
+
+	if _, err := spool.Seek(0, io.SeekStart); err != nil {
+		log.Error(err)
+		return response.ErrorResponse("Internal error occurred")
+	}
+
+	var stored io.Reader = spool
+	orientation := 1
+	if allowed.mediaType == models.Image {
+		// Images are small enough to strip in memory. The EXIF includes where the photo was taken, so it's removed
+		// before anything is stored and the orientation is kept for the variants
+		data, err := io.ReadAll(spool)
+		if err != nil {
+			log.Error(err)
+			return response.ErrorResponse("Unable to read file")
+		}
+
+		if contentType == "image/jpeg" {
+			orientation = imaging.Orientation(data)
+		}
+		if data, err = imaging.Strip(contentType, data); err != nil {
+			return response.ErrorResponse("Image is corrupt")
+		}
+
+		hash := sha256.Sum256(data)
+		digest, size, stored = hex.EncodeToString(hash[:]), int64(len(data)), bytes.NewReader(data)
+	}
+
+	existing, err := service.repo.GetMediaByHash(ctx, digest, user.UUID)
+	if errorResponse := response.ContextError(err); errorResponse != nil {
+		return errorResponse
+	}
+	if err != nil {
+		return response.ErrorResponse("Internal error occurred")
+	}
+	if existing != nil {
+		service.sign(ctx, existing)
+		return response.SuccessResponse(existing, "")
+	}
+
+	if err := service.store.Put(ctx, digest, stored, size, contentType); err != nil {
+		if errorResponse := response.ContextError(err); errorResponse != nil {
+			return errorResponse
+		}
+		log.Error(err)
+		return response.ErrorResponse("Internal error occurred whilst storing file")
+	}
+
+	media := &models.MediaFileModel{
+		UUID:           uuid.New(),
+		SHA256:         digest,
+		ContentType:    contentType,
+		Type:           allowed.mediaType,
+		Size:           size,
+		UploadedByUUID: user.UUID,
+		CreatedAt:      time.Now().UTC(),
+		Orientation:    orientation,
+	}
+	// The job making the variants is added with them, so an upload isn't left waiting for one that was never queued
+	err = service.repo.Repository.WithTx(ctx, func(tx *mysql.Tx) error {
+		if err := service.repo.CreateMedia(tx.Context(), media); err != nil {
+			return err
+		}
+		if !processable[contentType] {
+			return nil
+		}
+
+		if err := service.repo.CreateVariants(tx.Context(), media.UUID, variantNames()); err != nil {
+			return err
+		}
+		if service.pipeline == nil {
+			return nil
+		}
+		return service.pipeline.Enqueue(tx.Context(), *media)
+	})
+	if errorResponse := response.ContextError(err); errorResponse != nil {
+		return errorResponse
+	}
+	if err != nil {
+		log.Error(err)
+		return response.ErrorResponse("Internal error occurred")
+	}
+
+	service.sign(ctx, media)
+	return response.SuccessResponse(media, "")
+}
+
+// Open verifies a signed download URL and opens the media, or one of its variants if variant isn't empty. The
+// caller must close the content
+func (service *Service) Open(ctx context.Context, mediaID string, variant string, expires string, signature string) (*models.MediaFileModel, io.ReadCloser, *response.Response) {
+	mediaUUID, err := uuid.Parse(mediaID)
+	if err != nil {
+		return nil, nil, response.ErrorResponse("Unable to parse media uuid")
+	}
+
+	if err := service.signer.Verify(mediaUUID, variant, expires, signature, time.Now()); err != nil {
+		return nil, nil, response.ErrorResponse("Download link is invalid or has expired")
+	}
+
+	media, err := service.repo.GetMedia(ctx, mediaUUID)
+	if errorResponse := response.ContextError(err); errorResponse != nil {
+		return nil, nil, errorResponse
+	}
+	if err != nil {
+		return nil, nil, response.ErrorResponse("Internal error occurred")
+	}
+	if len(media) == 0 || service.store == nil {
+		return nil, nil, response.ErrorResponse("Media not found")
+	}
+
+	file := media[0]
+	if variant != "" {
+		variants, err := service.repo.GetVariants(ctx, mediaUUID)
+		if errorResponse := response.ContextError(err); errorResponse != nil {
+			return nil, nil, errorResponse
+		}
+		if err != nil {
+			return nil, nil, response.ErrorResponse("Internal error occurred")
+		}
+
+		found := false
+		for _, stored := range variants[mediaUUID] {
+			if stored.Name == variant && stored.Status == models.VariantReady {
+				file.SHA256, file.ContentType, file.Size, found = stored.SHA256, stored.ContentType, stored.Size, true
+			}
+		}
+		if !found {
+			return nil, nil, response.ErrorResponse("Media not found")
+		}
+	}
+
+	content, err := service.store.Open(ctx, file.SHA256)
+	if errorResponse := response.ContextError(err); errorResponse != nil {
+		return nil, nil, errorResponse
+	}
+	if errors.Is(err, blob.ErrNotFound) {
+		return nil, nil, response.ErrorResponse("Media not found")
+	}
+	if err != nil {
+		log.Error(err)
+		return nil, nil, response.ErrorResponse("Internal error occurred")
+	}
+
+	return &file, content, nil
+}
+
+// Resolve turns media IDs from a request into media, in the order given without duplicates.
+// Users can only reference media they uploaded or that's already in current, e.g. an opportunity's existing media
+func (service *Service) Resolve(ctx context.Context, userUUID uuid.UUID, mediaIDs []string, current []models.MediaModel) ([]models.MediaModel, error) {
+	allowed := map[uuid.UUID]bool{}
+	for _, media := range current {
+		if media.ID != nil {
+			allowed[*media.ID] = true
+		}
+	}
+
+	var mediaUUIDs []uuid.UUID
+	seen := map[uuid.UUID]bool{}
+	for _, mediaID := range mediaIDs {
+		mediaUUID, err := uuid.Parse(mediaID)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s isn't a media ID, upload files to /api/v1/media first", ErrInvalidMedia, mediaID)
+		}
+		if !seen[mediaUUID] {
+			seen[mediaUUID] = true
+			mediaUUIDs = append(mediaUUIDs, mediaUUID)
+		}
+	}
+
+	files, err := service.repo.GetMedia(ctx, mediaUUIDs...)
+	if err != nil {
+		return nil, err
+	}
+
+	found := map[uuid.UUID]models.MediaFileModel{}
+	for _, file := range files {
+		found[file.UUID] = file
+	}
+
+	media := make([]models.MediaModel, 0, len(mediaUUIDs))
+	for _, mediaUUID := range mediaUUIDs {
+		file, ok := found[mediaUUID]
+		if !ok || (file.UploadedByUUID != userUUID && !allowed[mediaUUID]) {
+			return nil, fmt.Errorf("%w: media %s not found", ErrInvalidMedia, mediaUUID)
+		}
+
+		id := file.UUID
+		media = append(media, models.MediaModel{ID: &id, Type: file.Type})
+	}
+	return media, nil
+}
+
+// ResolveProfilePic checks the media ID is an image the user can use, the current picture is always allowed
+func (service *Service) ResolveProfilePic(ctx context.Context, userUUID uuid.UUID, mediaID string, current string) error {
+	if mediaID == "" || mediaID == current {
+		return nil
+	}
+
+	var currentMedia []models.MediaModel
+	if currentUUID, err := uuid.Parse(current); err == nil {
+		currentMedia = append(currentMedia, models.MediaModel{ID: &currentUUID})
+	}
+
+	media, err := service.Resolve(ctx, userUUID, []string{mediaID}, currentMedia)
+	if err != nil {
+		return err
+	}
+	if media[0].Type != models.Image {
+		return fmt.Errorf("%w: profile picture must be an image", ErrInvalidMedia)
+	}
+	return nil
+}
+
+// SignMedia sets the download URLs of stored media and its variants
+func (service *Service) SignMedia(ctx context.Context, media *[]models.MediaModel) {
+	if media == nil {
+		return
+	}
+	service.signAll(ctx, []*[]models.MediaModel{media})
+}
+
+// SignOpportunities sets the download URLs of the opportunities media and its variants
+func (service *Service) SignOpportunities(ctx context.Context, opportunities []models.OpportunityModel) {
+	media := make([]*[]models.MediaModel, 0, len(opportunities))
+	for i := range opportunities {
+		if opportunities[i].Media != nil {
+			media = append(media, opportunities[i].Media)
+		}
+	}
+	service.signAll(ctx, media)
+}
+
+// signAll signs every media in the lists, looking up their variants in one query
+func (service *Service) signAll(ctx context.Context, lists []*[]models.MediaModel) {
+	var mediaUUIDs []uuid.UUID
+	for _, list := range lists {
+		for _, media := range *list {
+			if media.ID != nil && media.Type == models.Image {
+				mediaUUIDs = append(mediaUUIDs, *media.ID)
+			}
+		}
+	}
+
+	variants, err := service.repo.GetVariants(ctx, mediaUUIDs...)
+	if err != nil {
+		// Still usable without variants, clients fall back to the original
+		variants = nil
+	}
+
+	now := time.Now()
+	for _, list := range lists {
+		for i := range *list {
+			media := &(*list)[i]
+			if media.ID == nil {
+				continue
+			}
+			media.URL = service.signer.URL(*media.ID, now)
+			media.Variants = service.signVariants(*media.ID, variants[*media.ID], now)
+		}
+	}
+}
+
+// sign sets the download URLs of an uploaded file and its variants
+func (service *Service) sign(ctx context.Context, media *models.MediaFileModel) {
+	now := time.Now()
+	media.URL = service.signer.URL(media.UUID, now)
+
+	if media.Type != models.Image {
+		return
+	}
+	if variants, err := service.repo.GetVariants(ctx, media.UUID); err == nil {
+		media.Variants = service.signVariants(media.UUID, variants[media.UUID], now)
+	}
+}
+
+// signVariants returns the variants by name, only ready variants have a URL
+func (service *Service) signVariants(mediaUUID uuid.UUID, variants []models.MediaVariantModel, now time.Time) map[string]models.MediaVariantModel {
+	if len(variants) == 0 {
+		return nil
+	}
+
+	signed := make(map[string]models.MediaVariantModel, len(variants))
+	for _, variant := range variants {
+		if variant.Status == models.VariantReady {
+			variant.URL = service.signer.VariantURL(mediaUUID, variant.Name, now)
+		}
+		signed[variant.Name] = variant
+	}
+	return signed
+}
+
+// SignProfilePic sets ProfilePicURL. Pictures set before uploads were stored are URLs, which are kept as they are
+func (service *Service) SignProfilePic(student *models.StudentInfoModel) {
+	if student == nil || student.ProfilePic == "" {
+		return
+	}
+
+	if mediaUUID, err := uuid.Parse(student.ProfilePic); err == nil {
+		student.ProfilePicURL = service.signer.URL(mediaUUID, time.Now())
+	} else if strings.HasPrefix(student.ProfilePic, "https://") || strings.HasPrefix(student.ProfilePic, "http://") {
+		student.ProfilePicURL = student.ProfilePic
+	}
+}
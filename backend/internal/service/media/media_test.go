@@ -0,0 +1,109 @@
+package media
+
+import (
+	"backend/internal/models"
+	"bytes"
+	"errors"
+	"github.com/google/uuid"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSignedURLVerifiesUntilExpiry(t *testing.T) {
+	signer := NewSigner([]byte("secret"), "https://greenuni.example.com", time.Hour)
+	mediaUUID := uuid.New()
+	now := time.Date(2025, 6, 1, 12, 30, 0, 0, time.UTC)
+
+	signed, err := url.Parse(signer.URL(mediaUUID, now))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(signed.String(), "https://greenuni.example.com/api/v1/media/"+mediaUUID.String()+"?") {
+		t.Fatalf("unexpected url %s", signed)
+	}
+
+	expires, signature := signed.Query().Get("expires"), signed.Query().Get("signature")
+
+	if err := signer.Verify(mediaUUID, "", expires, signature, now.Add(time.Hour)); err != nil {
+		t.Fatalf("expected the url to be valid for its lifetime: %v", err)
+	}
+	if err := signer.Verify(mediaUUID, "", expires, signature, now.Add(3*time.Hour)); !errors.Is(err, ErrInvalidSignature) {
+		t.Fatal("expected the url to expire")
+	}
+	if err := signer.Verify(uuid.New(), "", expires, signature, now); !errors.Is(err, ErrInvalidSignature) {
+		t.Fatal("expected the signature to be tied to the media")
+	}
+	if err := signer.Verify(mediaUUID, "", expires+"0", signature, now); !errors.Is(err, ErrInvalidSignature) {
+		t.Fatal("expected a changed expiry to be rejected")
+	}
+	if err := NewSigner([]byte("other"), "", time.Hour).Verify(mediaUUID, "", expires, signature, now); !errors.Is(err, ErrInvalidSignature) {
+		t.Fatal("expected a different key to be rejected")
+	}
+}
+
+func TestSignedURLIsStableWithinWindow(t *testing.T) {
+	signer := NewSigner([]byte("secret"), "", time.Hour)
+	mediaUUID := uuid.New()
+	now := time.Date(2025, 6, 1, 12, 5, 0, 0, time.UTC)
+
+	if signer.URL(mediaUUID, now) != signer.URL(mediaUUID, now.Add(50*time.Minute)) {
+		t.Fatal("expected the same url within the hour so clients can cache it")
+	}
+}
+
+func TestVariantURLIsOnlyValidForItsVariant(t *testing.T) {
+	signer := NewSigner([]byte("secret"), "", time.Hour)
+	mediaUUID := uuid.New()
+	now := time.Date(2025, 6, 1, 12, 5, 0, 0, time.UTC)
+
+	signed, err := url.Parse(signer.VariantURL(mediaUUID, "thumbnail", now))
+	if err != nil {
+		t.Fatal(err)
+	}
+	query := signed.Query()
+	if query.Get("variant") != "thumbnail" {
+		t.Fatalf("unexpected url %s", signed)
+	}
+
+	if err := signer.Verify(mediaUUID, "thumbnail", query.Get("expires"), query.Get("signature"), now); err != nil {
+		t.Fatalf("expected the variant url to be valid: %v", err)
+	}
+	if err := signer.Verify(mediaUUID, "", query.Get("expires"), query.Get("signature"), now); !errors.Is(err, ErrInvalidSignature) {
+		t.Fatal("expected a variant url not to download the original")
+	}
+}
+
+func TestSniffDetectsFromContent(t *testing.T) {
+	png := []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+
+	contentType, allowed, err := sniff(png)
+	if err != nil || contentType != "image/png" || allowed.mediaType != models.Image {
+		t.Fatalf("unexpected %s %v %v", contentType, allowed, err)
+	}
+
+	// An HTML page named .jpg must not be accepted
+	if _, _, err := sniff([]byte("<!DOCTYPE html><script>alert(1)</script>")); !errors.Is(err, ErrUnsupportedType) {
+		t.Fatal("expected html to be rejected")
+	}
+	if _, _, err := sniff(bytes.Repeat([]byte{0}, 64)); !errors.Is(err, ErrUnsupportedType) {
+		t.Fatal("expected unknown binary to be rejected")
+	}
+}
+
+func TestSignProfilePicKeepsLegacyURLs(t *testing.T) {
+	service := &Service{signer: NewSigner([]byte("secret"), "", time.Hour)}
+
+	legacy := &models.StudentInfoModel{ProfilePic: "https://res.cloudinary.com/demo/image/upload/profile.jpg"}
+	service.SignProfilePic(legacy)
+	if legacy.ProfilePicURL != legacy.ProfilePic {
+		t.Fatalf("expected the legacy url to be kept, got %q", legacy.ProfilePicURL)
+	}
+
+	stored := &models.StudentInfoModel{ProfilePic: uuid.NewString()}
+	service.SignProfilePic(stored)
+	if !strings.HasPrefix(stored.ProfilePicURL, "/api/v1/media/"+stored.ProfilePic+"?expires=") {
+		t.Fatalf("expected a signed url, got %q", stored.ProfilePicURL)
+	}
+}
@@ -0,0 +1,221 @@
+package media
+
+import (
+	"backend/internal/blob"
+	"backend/internal/db/adapters/mysql"
+	"backend/internal/db/repositories"
+	"backend/internal/imaging"
+	"backend/internal/models"
+	"backend/internal/queue"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+	"image"
+	"io"
+)
+
+// Variant is a size uploaded images are scaled down to fit within, so clients don't download full camera photos
+type Variant struct {
+	Name      string
+	MaxWidth  int
+	MaxHeight int
+}
+
+// Variants are made for every uploaded image that can be decoded
+var Variants = []Variant{
+	{Name: "thumbnail", MaxWidth: 320, MaxHeight: 320},
+	{Name: "feed", MaxWidth: 1080, MaxHeight: 1350},
+	{Name: "large", MaxWidth: 2048, MaxHeight: 2048},
+}
+
+// MaxAttempts is how many times making an image's variants is tried before its job is dead lettered
+const MaxAttempts = 3
+
+// processable are the content types variants are made from. WebP can't be decoded by the standard library so
+// WebP uploads only have the original
+var processable = map[string]bool{"image/jpeg": true, "image/png": true, "image/gif": true}
+
+// makeVariants is the job making an uploaded image's pending variants
+var makeVariants = queue.NewType[variantsJob]("media.variants")
+
+type variantsJob struct {
+	MediaUUID uuid.UUID `json:"mediaUUID"`
+}
+
+// Pipeline makes the variants of uploaded images as jobs on the job queue, so they survive a restart
+type Pipeline struct {
+	repo  *repositories.MediaRepository
+	store blob.Store
+	queue *queue.Queue
+	// slots bounds how many images are decoded at once, whatever the queue's number of workers
+	slots chan struct{}
+}
+
+// NewPipeline handles the queue's variant jobs, processing an image per worker at a time
+func NewPipeline(repo *repositories.MediaRepository, store blob.Store, jobQueue *queue.Queue, workers int) *Pipeline {
+	pipeline := &Pipeline{repo: repo, store: store, queue: jobQueue, slots: make(chan struct{}, max(workers, 1))}
+	queue.Handle(jobQueue, makeVariants, pipeline.handle)
+	return pipeline
+}
+
+// Enqueue adds a job making the media's pending variants once the WithTx transaction in ctx has been committed. A
+// transactional store adds it in the transaction, other stores after it, so a worker can't run it before the media
+// exists or for an upload that was rolled back
+func (pipeline *Pipeline) Enqueue(ctx context.Context, media models.MediaFileModel) error {
+	if pipeline.queue.Transactional() {
+		return pipeline.enqueue(ctx, media)
+	}
+
+	mysql.AfterCommit(ctx, func(ctx context.Context) {
+		if err := pipeline.enqueue(ctx, media); err != nil {
+			// Picked up by Resume on the next start
+			log.Errorf("Unable to queue making the variants of media %s: %v", media.UUID, err)
+		}
+	})
+	return nil
+}
+
+func (pipeline *Pipeline) enqueue(ctx context.Context, media models.MediaFileModel) error {
+	_, err := queue.Enqueue(ctx, pipeline.queue, makeVariants, variantsJob{MediaUUID: media.UUID},
+		queue.WithKey("media.variants:"+media.UUID.String()), queue.WithMaxAttempts(MaxAttempts))
+	return err
+}
+
+// Resume enqueues jobs for images whose variants were left pending without one, such as those uploaded before
+// variants were made on the job queue. Images that already have a job aren't enqueued again
+func (pipeline *Pipeline) Resume(ctx context.Context) error {
+	media, err := pipeline.repo.GetUnprocessedMedia(ctx, MaxAttempts)
+	if err != nil {
+		return err
+	}
+
+	for _, file := range media {
+		if err := pipeline.Enqueue(ctx, file); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (pipeline *Pipeline) handle(ctx context.Context, job variantsJob) error {
+	select {
+	case pipeline.slots <- struct{}{}:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	defer func() { <-pipeline.slots }()
+
+	media, err := pipeline.repo.GetMedia(ctx, job.MediaUUID)
+	if err != nil {
+		return err
+	}
+	if len(media) == 0 {
+		return queue.Permanent(fmt.Errorf("media %s no longer exists", job.MediaUUID))
+	}
+	return pipeline.process(ctx, media[0])
+}
+
+// process makes the media's pending and failed variants, returning an error for the job to be retried if any failed
+func (pipeline *Pipeline) process(ctx context.Context, media models.MediaFileModel) error {
+	variants, err := pipeline.repo.GetVariants(ctx, media.UUID)
+	if err != nil {
+		return err
+	}
+
+	var todo []models.MediaVariantModel
+	for _, variant := range variants[media.UUID] {
+		if variant.Status == models.VariantPending || (variant.Status == models.VariantFailed && variant.Attempts < MaxAttempts) {
+			todo = append(todo, variant)
+		}
+	}
+	if len(todo) == 0 {
+		return nil
+	}
+
+	img, err := pipeline.load(ctx, media)
+	if err != nil {
+		err = fmt.Errorf("decoding media %s: %w", media.UUID, err)
+		for _, variant := range todo {
+			_ = pipeline.repo.SetVariantFailed(ctx, media.UUID, variant.Name, err.Error())
+		}
+		return err
+	}
+
+	var failed []error
+	for _, variant := range todo {
+		if err := pipeline.makeVariant(ctx, img, &variant); err != nil {
+			err = fmt.Errorf("making the %s variant of media %s: %w", variant.Name, media.UUID, err)
+			_ = pipeline.repo.SetVariantFailed(ctx, media.UUID, variant.Name, err.Error())
+			failed = append(failed, err)
+		}
+	}
+	return errors.Join(failed...)
+}
+
+// load decodes the stored image and turns it upright, the stored file no longer has its EXIF orientation
+func (pipeline *Pipeline) load(ctx context.Context, media models.MediaFileModel) (image.Image, error) {
+	content, err := pipeline.store.Open(ctx, media.SHA256)
+	if err != nil {
+		return nil, err
+	}
+	defer content.Close()
+
+	data, err := io.ReadAll(content)
+	if err != nil {
+		return nil, err
+	}
+
+	img, err := imaging.Decode(data)
+	if err != nil {
+		return nil, err
+	}
+	return imaging.Orient(img, media.Orientation), nil
+}
+
+func (pipeline *Pipeline) makeVariant(ctx context.Context, img image.Image, variant *models.MediaVariantModel) error {
+	spec, ok := findVariant(variant.Name)
+	if !ok {
+		return fmt.Errorf("unknown variant %q", variant.Name)
+	}
+
+	// Re-encoded even when it already fits, which drops any metadata the original still has
+	resized := imaging.Fit(img, spec.MaxWidth, spec.MaxHeight)
+	encoded, contentType, err := imaging.Encode(resized)
+	if err != nil {
+		return err
+	}
+
+	hash := sha256.Sum256(encoded)
+	digest := hex.EncodeToString(hash[:])
+	if err := pipeline.store.Put(ctx, digest, bytes.NewReader(encoded), int64(len(encoded)), contentType); err != nil {
+		return err
+	}
+
+	variant.SHA256 = digest
+	variant.ContentType = contentType
+	variant.Width, variant.Height = resized.Bounds().Dx(), resized.Bounds().Dy()
+	variant.Size = int64(len(encoded))
+	return pipeline.repo.SetVariantReady(ctx, variant)
+}
+
+func findVariant(name string) (Variant, bool) {
+	for _, variant := range Variants {
+		if variant.Name == name {
+			return variant, true
+		}
+	}
+	return Variant{}, false
+}
+
+func variantNames() []string {
+	names := make([]string, len(Variants))
+	for i, variant := range Variants {
+		names[i] = variant.Name
+	}
+	return names
+}
@@ -0,0 +1,152 @@
+package media
+
+import (
+	"backend/internal/blob"
+	"backend/internal/db/adapters/mysql"
+	"backend/internal/db/repositories"
+	"backend/internal/models"
+	"backend/internal/queue"
+	"bytes"
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"github.com/google/uuid"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeDatabase is a database/sql driver that records the statements run on it, failing those containing fail.
+// Queries return no rows
+type fakeDatabase struct {
+	mutex      sync.Mutex
+	statements []string
+	fail       string
+}
+
+func (db *fakeDatabase) Connect(context.Context) (driver.Conn, error) { return db, nil }
+func (db *fakeDatabase) Driver() driver.Driver                        { return nil }
+func (db *fakeDatabase) Close() error                                 { return nil }
+func (db *fakeDatabase) Prepare(query string) (driver.Stmt, error)    { return fakeStmt{db, query}, nil }
+func (db *fakeDatabase) Begin() (driver.Tx, error)                    { return db.record("BEGIN") }
+func (db *fakeDatabase) Commit() error                                { _, err := db.record("COMMIT"); return err }
+func (db *fakeDatabase) Rollback() error                              { _, err := db.record("ROLLBACK"); return err }
+
+func (db *fakeDatabase) record(statement string) (driver.Tx, error) {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+	db.statements = append(db.statements, statement)
+	if db.fail != "" && strings.Contains(statement, db.fail) {
+		return nil, errors.New("failed " + db.fail)
+	}
+	return db, nil
+}
+
+func (db *fakeDatabase) committed() bool {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+	return slices.Contains(db.statements, "COMMIT")
+}
+
+type fakeStmt struct {
+	db    *fakeDatabase
+	query string
+}
+
+func (stmt fakeStmt) Close() error  { return nil }
+func (stmt fakeStmt) NumInput() int { return -1 }
+
+func (stmt fakeStmt) Exec([]driver.Value) (driver.Result, error) {
+	if _, err := stmt.db.record(stmt.query); err != nil {
+		return nil, err
+	}
+	return driver.RowsAffected(1), nil
+}
+
+func (stmt fakeStmt) Query([]driver.Value) (driver.Rows, error) {
+	if _, err := stmt.db.record(stmt.query); err != nil {
+		return nil, err
+	}
+	return noRows{}, nil
+}
+
+type noRows struct{}
+
+func (noRows) Columns() []string         { return nil }
+func (noRows) Close() error              { return nil }
+func (noRows) Next([]driver.Value) error { return io.EOF }
+
+// eagerJobs is a job store that isn't transactional, a job is added as soon as it's enqueued
+type eagerJobs struct {
+	db *fakeDatabase
+	// afterCommit records, for each job enqueued, whether the upload had been committed
+	afterCommit []bool
+}
+
+func (jobs *eagerJobs) Enqueue(_ context.Context, _ queue.Job) (bool, error) {
+	jobs.afterCommit = append(jobs.afterCommit, jobs.db.committed())
+	return true, nil
+}
+
+func (jobs *eagerJobs) Claim(context.Context, time.Time, int, time.Duration) ([]queue.Job, error) {
+	return nil, nil
+}
+func (jobs *eagerJobs) Complete(context.Context, queue.Job) error                 { return nil }
+func (jobs *eagerJobs) Retry(context.Context, queue.Job, time.Time, string) error { return nil }
+func (jobs *eagerJobs) DeadLetter(context.Context, queue.Job, string) error       { return nil }
+func (jobs *eagerJobs) PurgeCompleted(context.Context, time.Time) (int64, error)  { return 0, nil }
+
+func testPNG(t *testing.T, shade uint8) []byte {
+	img := image.NewGray(image.Rect(0, 0, 2, 2))
+	img.Set(0, 0, color.Gray{Y: shade})
+	var buffer bytes.Buffer
+	if err := png.Encode(&buffer, img); err != nil {
+		t.Fatal(err)
+	}
+	return buffer.Bytes()
+}
+
+func TestUploadQueuesVariantsAfterCommitWithoutATransactionalStore(t *testing.T) {
+	db := &fakeDatabase{}
+	database := sql.OpenDB(db)
+	t.Cleanup(func() { database.Close() })
+	repo := &repositories.MediaRepository{BaseRepository: &repositories.BaseRepository{
+		Repository: &mysql.Repository{Database: mysql.NewContainer(database, mysql.Timeouts{})},
+	}}
+
+	store, err := blob.NewLocalStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	jobs := &eagerJobs{db: db}
+	jobQueue := queue.New(jobs, queue.Config{})
+	if jobQueue.Transactional() {
+		t.Fatal("expected the store not to be transactional")
+	}
+	service := &Service{repo: repo, store: store, signer: NewEphemeralSigner(""),
+		pipeline: NewPipeline(repo, store, jobQueue, 1)}
+	user := &models.UserInfoModel{UUID: uuid.New()}
+
+	if res := service.Upload(context.Background(), user, bytes.NewReader(testPNG(t, 1))); !res.Success {
+		t.Fatalf("expected the upload to succeed, got %s", res.Message)
+	}
+	if len(jobs.afterCommit) != 1 || !jobs.afterCommit[0] {
+		t.Fatalf("expected one job enqueued after the upload committed, got %v", jobs.afterCommit)
+	}
+
+	// A rolled back upload doesn't leave a job behind
+	db.fail = "MediaVariantsTable"
+	if res := service.Upload(context.Background(), user, bytes.NewReader(testPNG(t, 2))); res.Success {
+		t.Fatal("expected the upload to fail")
+	}
+	if len(jobs.afterCommit) != 1 {
+		t.Fatalf("expected no job for the rolled back upload, got %v", jobs.afterCommit)
+	}
+}
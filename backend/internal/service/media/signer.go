@@ -0,0 +1,91 @@
+package media
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// DefaultURLLifetime is the least time a signed URL stays valid for
+const DefaultURLLifetime = 24 * time.Hour
+
+// urlWindow rounds expiry times so the same media gets the same URL for a while, letting clients cache it
+const urlWindow = time.Hour
+
+// downloadPath is where media is downloaded from, relative to the signer's base URL
+const downloadPath = "/api/v1/media/"
+
+// ErrInvalidSignature is returned for download URLs that weren't signed by us or have expired
+var ErrInvalidSignature = errors.New("download link is invalid or has expired")
+
+// Signer signs expiring download URLs with HMAC-SHA256, so media can be fetched without a JWT (e.g. by an
+// <Image> component) but only through links the API handed out
+type Signer struct {
+	key []byte
+	// baseURL is prepended to download URLs, empty for URLs relative to the API's host
+	baseURL  string
+	lifetime time.Duration
+}
+
+func NewSigner(key []byte, baseURL string, lifetime time.Duration) *Signer {
+	return &Signer{key: key, baseURL: baseURL, lifetime: lifetime}
+}
+
+// NewEphemeralSigner signs with a random key, so URLs stop working on restart
+func NewEphemeralSigner(baseURL string) *Signer {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		panic(err)
+	}
+	return NewSigner(key, baseURL, DefaultURLLifetime)
+}
+
+// URL returns a download URL for the media valid for at least the signer's lifetime
+func (signer *Signer) URL(mediaUUID uuid.UUID, now time.Time) string {
+	return signer.VariantURL(mediaUUID, "", now)
+}
+
+// VariantURL returns a download URL for a variant of the media, the original if variant is empty
+func (signer *Signer) VariantURL(mediaUUID uuid.UUID, variant string, now time.Time) string {
+	expires := now.Truncate(urlWindow).Add(urlWindow + signer.lifetime).Unix()
+
+	query := ""
+	if variant != "" {
+		query = "variant=" + url.QueryEscape(variant) + "&"
+	}
+	return fmt.Sprintf("%s%s%s?%sexpires=%d&signature=%s", signer.baseURL, downloadPath, mediaUUID, query, expires,
+		signer.signature(mediaUUID, variant, expires))
+}
+
+// Verify checks the expires and signature query parameters of a download URL, a URL for one variant isn't valid
+// for another
+func (signer *Signer) Verify(mediaUUID uuid.UUID, variant string, expires string, signature string, now time.Time) error {
+	expiresUnix, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil {
+		return ErrInvalidSignature
+	}
+
+	expected := signer.signature(mediaUUID, variant, expiresUnix)
+	if !hmac.Equal([]byte(expected), []byte(signature)) || now.Unix() >= expiresUnix {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+func (signer *Signer) signature(mediaUUID uuid.UUID, variant string, expires int64) string {
+	subject := mediaUUID.String()
+	if variant != "" {
+		subject += "/" + variant
+	}
+
+	mac := hmac.New(sha256.New, signer.key)
+	mac.Write([]byte(subject + "\n" + strconv.FormatInt(expires, 10)))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
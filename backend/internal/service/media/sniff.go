@@ -0,0 +1,47 @@
+package media
+
+import (
+	"backend/internal/models"
+	"errors"
+	"net/http"
+)
+
+// sniffLength is how much of a file is needed to detect its type
+const sniffLength = 512
+
+const (
+	maxImageSize = 10 << 20
+	maxVideoSize = 100 << 20
+)
+
+// MaxUploadSize is the largest file that can be uploaded of any type
+const MaxUploadSize = maxVideoSize
+
+// ErrUnsupportedType is returned for files that aren't an allowed image or video
+var ErrUnsupportedType = errors.New("unsupported media type")
+
+type allowedType struct {
+	mediaType models.MediaType
+	maxSize   int64
+}
+
+// allowedTypes are the content types that can be uploaded. The type is detected from the content rather than
+// trusting the client, so an HTML page can't be uploaded as an image and served back from our origin
+var allowedTypes = map[string]allowedType{
+	"image/jpeg": {models.Image, maxImageSize},
+	"image/png":  {models.Image, maxImageSize},
+	"image/gif":  {models.Image, maxImageSize},
+	"image/webp": {models.Image, maxImageSize},
+	"video/mp4":  {models.Video, maxVideoSize},
+	"video/webm": {models.Video, maxVideoSize},
+}
+
+// sniff detects the content type from the start of a file, returning ErrUnsupportedType if it isn't allowed
+func sniff(head []byte) (string, allowedType, error) {
+	contentType := http.DetectContentType(head)
+	allowed, ok := allowedTypes[contentType]
+	if !ok {
+		return "", allowedType{}, ErrUnsupportedType
+	}
+	return contentType, allowed, nil
+}
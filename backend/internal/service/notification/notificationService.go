@@ -0,0 +1,84 @@
+package notification
+
+import (
+	"backend/internal/db/repositories"
+	"backend/internal/events"
+	"backend/internal/models"
+	response "backend/internal/utils/http"
+	"context"
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+	"strconv"
+)
+
+// defaultLimit is how many notifications are returned when no limit is given
+const defaultLimit = 20
+
+// maxLimit caps how many notifications can be fetched at once
+const maxLimit = 100
+
+// Service provides methods for reading a users notifications
+type Service struct {
+	repo *repositories.NotificationRepository
+}
+
+// NewNotificationService creates a new instance of the notification Service
+func NewNotificationService(repo *repositories.NotificationRepository) *Service {
+	return &Service{repo: repo}
+}
+
+// Subscribe notifies an organisation's followers when one of its opportunities is approved. The returned function
+// unsubscribes
+func (service *Service) Subscribe(bus *events.Bus) func() {
+	return events.SubscribeAsync(bus, "notify-followers", service.notifyFollowers)
+}
+
+func (service *Service) notifyFollowers(ctx context.Context, approved events.OpportunityApproved) error {
+	_, err := service.repo.NotifyOrganisationFollowers(ctx, approved.OpportunityUUID)
+	return err
+}
+
+// GetNotifications returns the users most recent notifications, newest first
+func (service *Service) GetNotifications(ctx context.Context, user *models.UserInfoModel, limit string) *response.Response {
+	limitInt := int64(defaultLimit)
+	if limit != "" {
+		parsed, err := strconv.ParseInt(limit, 10, 64)
+		if err != nil || parsed <= 0 {
+			return response.ErrorResponse("limit is not a positive integer")
+		}
+		limitInt = min(parsed, maxLimit)
+	}
+
+	notifications, err := service.repo.GetNotifications(ctx, user.UUID, limitInt)
+	if errorResponse := response.ContextError(err); errorResponse != nil {
+		return errorResponse
+	}
+	if err != nil {
+		return response.ErrorResponse("Internal error occurred")
+	}
+
+	return response.SuccessResponse(notifications, "")
+}
+
+// MarkRead marks one of the users notifications as read
+func (service *Service) MarkRead(ctx context.Context, user *models.UserInfoModel, notificationID string) *response.Response {
+	notificationUUID, err := uuid.Parse(notificationID)
+	if err != nil {
+		return response.ErrorResponse("Unable to parse notification uuid")
+	}
+
+	updated, err := service.repo.MarkRead(ctx, notificationUUID, user.UUID)
+	if errorResponse := response.ContextError(err); errorResponse != nil {
+		return errorResponse
+	}
+	if err != nil {
+		log.Error(err)
+		return response.ErrorResponse("Internal error occurred")
+	}
+
+	if !updated {
+		return response.ErrorResponse("Notification not found or already read")
+	}
+
+	return response.SuccessResponse(nil, "")
+}
@@ -0,0 +1,332 @@
+package opportunity
+
+import (
+	"backend/internal/geo"
+	"backend/internal/models"
+	"backend/internal/taxonomy"
+	"github.com/google/uuid"
+	"math"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Filter query parameters for GET /opportunities, e.g.
+// ?tags=recycling,beach&tagMatch=all&type=volunteer&minPoints=20&sort=newest
+const (
+	ParamTags          = "tags"
+	ParamTagMatch      = "tagMatch"
+	ParamType          = "type"
+	ParamMinPoints     = "minPoints"
+	ParamMaxPoints     = "maxPoints"
+	ParamCreatedAfter  = "createdAfter"
+	ParamCreatedBefore = "createdBefore"
+	ParamUpdatedAfter  = "updatedAfter"
+	ParamUpdatedBefore = "updatedBefore"
+	ParamOrganisation  = "organisation"
+	ParamLatitude      = "lat"
+	ParamLongitude     = "lng"
+	ParamRadius        = "radiusKm"
+	ParamBoundingBox   = "bbox"
+	ParamOnline        = "online"
+	ParamSort          = "sort"
+	ParamOrder         = "order"
+	ParamLimit         = "limit"
+	ParamOffset        = "offset"
+)
+
+// FilterParams are the parameters that make GET /opportunities a filtered listing
+var FilterParams = []string{
+	ParamTags, ParamTagMatch, ParamType, ParamMinPoints, ParamMaxPoints, ParamCreatedAfter, ParamCreatedBefore,
+	ParamUpdatedAfter, ParamUpdatedBefore, ParamOrganisation, ParamLatitude, ParamLongitude, ParamRadius,
+	ParamBoundingBox, ParamOnline, ParamSort, ParamOrder, ParamOffset,
+}
+
+const (
+	defaultFilterLimit = 20
+	maxFilterLimit     = 100
+	// maxFilterValues stops a single parameter from producing a huge IN (...)
+	maxFilterValues = 20
+	maxRadiusKm     = 500
+)
+
+var opportunityTypes = map[string]bool{"event": true, "volunteer": true, "job": true, "issue": true}
+
+// ParseFilter validates the query parameters into a filter. Errors are *models.FilterError naming the parameter
+func ParseFilter(query url.Values) (*models.OpportunityFilter, error) {
+	filter := &models.OpportunityFilter{Limit: defaultFilterLimit}
+	var err error
+
+	if filter.Tags, err = parseList(query, ParamTags); err != nil {
+		return nil, err
+	}
+	filter.Tags = normaliseTags(filter.Tags)
+
+	switch query.Get(ParamTagMatch) {
+	case "", "any":
+		filter.TagMatch = models.TagMatchAny
+	case "all":
+		filter.TagMatch = models.TagMatchAll
+	default:
+		return nil, filterError(ParamTagMatch, "must be 'any' or 'all'")
+	}
+
+	if filter.Types, err = parseList(query, ParamType); err != nil {
+		return nil, err
+	}
+	for _, opportunityType := range filter.Types {
+		if !opportunityTypes[opportunityType] {
+			return nil, filterError(ParamType, "unknown opportunity type '"+opportunityType+"'")
+		}
+	}
+
+	if filter.MinPoints, err = parseInt(query, ParamMinPoints); err != nil {
+		return nil, err
+	}
+	if filter.MaxPoints, err = parseInt(query, ParamMaxPoints); err != nil {
+		return nil, err
+	}
+	if filter.MinPoints != nil && filter.MaxPoints != nil && *filter.MinPoints > *filter.MaxPoints {
+		return nil, filterError(ParamMinPoints, "must not be greater than maxPoints")
+	}
+
+	if filter.CreatedAfter, err = parseTime(query, ParamCreatedAfter); err != nil {
+		return nil, err
+	}
+	if filter.CreatedBefore, err = parseTime(query, ParamCreatedBefore); err != nil {
+		return nil, err
+	}
+	if filter.UpdatedAfter, err = parseTime(query, ParamUpdatedAfter); err != nil {
+		return nil, err
+	}
+	if filter.UpdatedBefore, err = parseTime(query, ParamUpdatedBefore); err != nil {
+		return nil, err
+	}
+
+	if organisation := query.Get(ParamOrganisation); organisation != "" {
+		organisationUUID, err := uuid.Parse(organisation)
+		if err != nil {
+			return nil, filterError(ParamOrganisation, "must be a uuid")
+		}
+		filter.OrganisationUUID = &organisationUUID
+	}
+
+	if filter.Near, err = parseCoordinates(query); err != nil {
+		return nil, err
+	}
+
+	if filter.RadiusKm, err = parseFloat(query, ParamRadius); err != nil {
+		return nil, err
+	} else if filter.RadiusKm != nil {
+		if filter.Near == nil {
+			return nil, filterError(ParamRadius, "needs lat and lng")
+		}
+		if *filter.RadiusKm <= 0 || *filter.RadiusKm > maxRadiusKm {
+			return nil, filterError(ParamRadius, "must be greater than 0 and at most "+strconv.Itoa(maxRadiusKm))
+		}
+	}
+
+	if filter.Within, err = parseBoundingBox(query); err != nil {
+		return nil, err
+	}
+
+	if online := query.Get(ParamOnline); online != "" {
+		value, err := strconv.ParseBool(online)
+		if err != nil {
+			return nil, filterError(ParamOnline, "must be true or false")
+		}
+		filter.Online = &value
+	}
+
+	if sort := query.Get(ParamSort); sort != "" {
+		if filter.Sort, err = models.ParseOpportunitySort(sort); err != nil {
+			return nil, filterError(ParamSort, "must be one of newest, points or distance")
+		}
+	}
+
+	switch query.Get(ParamOrder) {
+	case "", "desc":
+		filter.Ascending = false
+	case "asc":
+		filter.Ascending = true
+	default:
+		return nil, filterError(ParamOrder, "must be 'asc' or 'desc'")
+	}
+
+	if limit, err := parseInt(query, ParamLimit); err != nil {
+		return nil, err
+	} else if limit != nil {
+		if *limit <= 0 || *limit > maxFilterLimit {
+			return nil, filterError(ParamLimit, "must be between 1 and "+strconv.Itoa(maxFilterLimit))
+		}
+		filter.Limit = *limit
+	}
+
+	if offset, err := parseInt(query, ParamOffset); err != nil {
+		return nil, err
+	} else if offset != nil {
+		if *offset < 0 {
+			return nil, filterError(ParamOffset, "must not be negative")
+		}
+		filter.Offset = *offset
+	}
+
+	if filter.Sort == models.SortDistance && filter.Near == nil {
+		return nil, filterError(ParamSort, "sorting by distance needs lat and lng")
+	}
+
+	return filter, nil
+}
+
+// IsFilterQuery returns whether any filter parameter is set
+func IsFilterQuery(query url.Values) bool {
+	for _, param := range FilterParams {
+		if query.Has(param) {
+			return true
+		}
+	}
+	return false
+}
+
+func filterError(param string, message string) *models.FilterError {
+	return &models.FilterError{Parameter: param, Message: message}
+}
+
+// parseList parses a comma separated list, the parameter can also be repeated
+func parseList(query url.Values, param string) ([]string, error) {
+	var values []string
+	seen := map[string]bool{}
+
+	for _, raw := range query[param] {
+		for _, value := range strings.Split(raw, ",") {
+			value = strings.TrimSpace(value)
+			if value == "" || seen[value] {
+				continue
+			}
+			seen[value] = true
+			values = append(values, value)
+		}
+	}
+
+	if len(values) > maxFilterValues {
+		return nil, filterError(param, "at most "+strconv.Itoa(maxFilterValues)+" values are allowed")
+	}
+
+	return values, nil
+}
+
+// normaliseTags puts tags in the form they're stored in, so "Recycling" finds "recycling"
+func normaliseTags(tags []string) []string {
+	normalised := make([]string, 0, len(tags))
+	seen := map[string]bool{}
+	for _, tag := range tags {
+		tag = taxonomy.Normalise(tag)
+		if tag != "" && !seen[tag] {
+			seen[tag] = true
+			normalised = append(normalised, tag)
+		}
+	}
+	return normalised
+}
+
+func parseInt(query url.Values, param string) (*int64, error) {
+	raw := query.Get(param)
+	if raw == "" {
+		return nil, nil
+	}
+
+	value, err := strconv.ParseInt(raw, 10, 64)
+	if err != nil {
+		return nil, filterError(param, "must be an integer")
+	}
+	return &value, nil
+}
+
+// parseTime accepts an RFC 3339 timestamp or a date (midnight UTC)
+func parseTime(query url.Values, param string) (*time.Time, error) {
+	raw := query.Get(param)
+	if raw == "" {
+		return nil, nil
+	}
+
+	for _, layout := range []string{time.RFC3339, time.DateOnly} {
+		if value, err := time.Parse(layout, raw); err == nil {
+			value = value.UTC()
+			return &value, nil
+		}
+	}
+
+	return nil, filterError(param, "must be a date (2006-01-02) or RFC 3339 timestamp")
+}
+
+func parseFloat(query url.Values, param string) (*float64, error) {
+	raw := query.Get(param)
+	if raw == "" {
+		return nil, nil
+	}
+
+	value, err := strconv.ParseFloat(raw, 64)
+	if err != nil || math.IsNaN(value) || math.IsInf(value, 0) {
+		return nil, filterError(param, "must be a number")
+	}
+	return &value, nil
+}
+
+// parseCoordinates parses lat and lng, which must be given together
+func parseCoordinates(query url.Values) (*geo.Coordinates, error) {
+	latitude, err := parseFloat(query, ParamLatitude)
+	if err != nil {
+		return nil, err
+	}
+	longitude, err := parseFloat(query, ParamLongitude)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case latitude == nil && longitude == nil:
+		return nil, nil
+	case latitude == nil:
+		return nil, filterError(ParamLatitude, "must be given with lng")
+	case longitude == nil:
+		return nil, filterError(ParamLongitude, "must be given with lat")
+	}
+
+	coordinates := &geo.Coordinates{Latitude: *latitude, Longitude: *longitude}
+	if *latitude < -90 || *latitude > 90 {
+		return nil, filterError(ParamLatitude, "must be between -90 and 90")
+	}
+	if *longitude < -180 || *longitude > 180 {
+		return nil, filterError(ParamLongitude, "must be between -180 and 180")
+	}
+	return coordinates, nil
+}
+
+// parseBoundingBox parses bbox=minLat,minLng,maxLat,maxLng
+func parseBoundingBox(query url.Values) (*geo.BoundingBox, error) {
+	raw := query.Get(ParamBoundingBox)
+	if raw == "" {
+		return nil, nil
+	}
+
+	parts := strings.Split(raw, ",")
+	if len(parts) != 4 {
+		return nil, filterError(ParamBoundingBox, "must be minLat,minLng,maxLat,maxLng")
+	}
+
+	var values [4]float64
+	for i, part := range parts {
+		value, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+		if err != nil {
+			return nil, filterError(ParamBoundingBox, "must be minLat,minLng,maxLat,maxLng")
+		}
+		values[i] = value
+	}
+
+	box := &geo.BoundingBox{MinLatitude: values[0], MinLongitude: values[1], MaxLatitude: values[2], MaxLongitude: values[3]}
+	if err := box.Validate(); err != nil {
+		return nil, filterError(ParamBoundingBox, err.Error())
+	}
+	return box, nil
+}
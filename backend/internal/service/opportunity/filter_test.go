@@ -0,0 +1,107 @@
+package opportunity
+
+import (
+	"backend/internal/models"
+	"errors"
+	"net/url"
+	"testing"
+)
+
+func TestParseFilter(t *testing.T) {
+	query, _ := url.ParseQuery("tags=recycling,beach&tags=beach&tagMatch=all&type=volunteer&minPoints=20&createdAfter=2025-01-02&sort=points&order=asc&limit=5&offset=10")
+
+	filter, err := ParseFilter(query)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(filter.Tags) != 2 || filter.Tags[0] != "recycling" || filter.Tags[1] != "beach" {
+		t.Fatalf("unexpected tags %v", filter.Tags)
+	}
+	if filter.TagMatch != models.TagMatchAll || len(filter.Types) != 1 || *filter.MinPoints != 20 || filter.MaxPoints != nil {
+		t.Fatalf("unexpected filter %+v", filter)
+	}
+	if filter.CreatedAfter == nil || filter.CreatedAfter.Format("2006-01-02") != "2025-01-02" {
+		t.Fatal("expected createdAfter to be parsed")
+	}
+	if filter.Sort != models.SortPoints || !filter.Ascending || filter.Limit != 5 || filter.Offset != 10 {
+		t.Fatalf("unexpected sort/paging %+v", filter)
+	}
+}
+
+func TestParseFilterDefaults(t *testing.T) {
+	filter, err := ParseFilter(url.Values{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if filter.Sort != models.SortNewest || filter.Ascending || filter.Limit != defaultFilterLimit || filter.TagMatch != models.TagMatchAny {
+		t.Fatalf("unexpected defaults %+v", filter)
+	}
+}
+
+func TestParseFilterErrorsNameParameter(t *testing.T) {
+	cases := map[string]string{
+		"minPoints=lots":                        "minPoints",
+		"minPoints=30&maxPoints=10":             "minPoints",
+		"type=party":                            "type",
+		"tagMatch=some":                         "tagMatch",
+		"createdBefore=yesterday":               "createdBefore",
+		"organisation=not-a-uuid":               "organisation",
+		"sort=random":                           "sort",
+		"order=sideways":                        "order",
+		"limit=0":                               "limit",
+		"offset=-1":                             "offset",
+		"updatedAfter=2025-13-01":               "updatedAfter",
+		"limit=1000":                            "limit",
+		"lat=53.8":                              "lng",
+		"lat=95&lng=0":                          "lat",
+		"lat=53.8&lng=-1.5&radiusKm=0":          "radiusKm",
+		"radiusKm=10":                           "radiusKm",
+		"bbox=1,2,3":                            "bbox",
+		"bbox=10,0,5,1":                         "bbox",
+		"online=maybe":                          "online",
+		"sort=distance":                         "sort",
+		"tags=" + manyValues(maxFilterValues+1): "tags",
+	}
+
+	for raw, param := range cases {
+		query, _ := url.ParseQuery(raw)
+		_, err := ParseFilter(query)
+
+		var filterError *models.FilterError
+		if !errors.As(err, &filterError) {
+			t.Fatalf("%s: expected a FilterError, got %v", raw, err)
+		}
+		if filterError.Parameter != param {
+			t.Errorf("%s: expected parameter %s, got %s", raw, param, filterError.Parameter)
+		}
+	}
+}
+
+func TestParseFilterLocation(t *testing.T) {
+	query, _ := url.ParseQuery("lat=53.8008&lng=-1.5491&radiusKm=25&bbox=53,-2,54,-1&online=false&sort=distance")
+
+	filter, err := ParseFilter(query)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if filter.Near == nil || filter.Near.Latitude != 53.8008 || filter.Near.Longitude != -1.5491 || *filter.RadiusKm != 25 {
+		t.Fatalf("unexpected location %+v", filter)
+	}
+	if filter.Within == nil || filter.Within.MaxLatitude != 54 || filter.Online == nil || *filter.Online {
+		t.Fatalf("unexpected area %+v", filter)
+	}
+	if filter.Sort != models.SortDistance {
+		t.Fatal("expected distance sort")
+	}
+}
+
+func manyValues(count int) string {
+	values := ""
+	for i := 0; i < count; i++ {
+		values += string(rune('a'+i%26)) + string(rune('a'+i/26)) + ","
+	}
+	return values
+}
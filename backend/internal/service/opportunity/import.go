@@ -0,0 +1,212 @@
+package opportunity
+
+import (
+	"backend/internal/bulkimport"
+	"backend/internal/db/repositories"
+	"backend/internal/models"
+	"backend/internal/service/media"
+	"backend/internal/taxonomy"
+	"backend/internal/utils/concurrency"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+	"sort"
+	"strings"
+)
+
+const (
+	ImportCreate = "create"
+	ImportUpdate = "update"
+)
+
+var errInternal = errors.New("Internal error occurred")
+
+// ImportOpportunities creates an opportunity for each row, or updates the one imported before with the same
+// externalRef. Every row is validated and reported on, rows with errors are skipped and the rest saved unless
+// it's a dry run. An error is returned when the import as a whole isn't allowed
+func (service *OpportunityService) ImportOpportunities(ctx context.Context, rows []bulkimport.Row, rowErrors []bulkimport.RowError,
+	authorUUID uuid.UUID, organisationID string, dryRun bool) (*models.ImportReportModel, error) {
+	owner := authorUUID
+	if organisationID != "" {
+		organisationUUID, err := uuid.Parse(organisationID)
+		if err != nil {
+			return nil, errors.New("unable to parse organisation uuid")
+		}
+
+		member, err := service.organisationRepo.GetMember(ctx, organisationUUID, authorUUID)
+		if err != nil {
+			log.Error(err)
+			return nil, errInternal
+		}
+		if member == nil || !member.Role.CanEdit() {
+			return nil, errors.New("Author can't post for this organisation")
+		}
+		owner = organisationUUID
+	}
+
+	report := &models.ImportReportModel{DryRun: dryRun, Rows: make([]models.ImportRowResult, 0, len(rows)+len(rowErrors))}
+	for _, rowError := range rowErrors {
+		report.Rows = append(report.Rows, models.ImportRowResult{Line: rowError.Line, Errors: []string{rowError.Err.Error()}})
+	}
+
+	// lines is where each externalRef was first seen, a sheet can't import the same opportunity twice
+	lines := map[string]int{}
+	for _, row := range rows {
+		request := row.Request
+		request.ExternalRef = strings.TrimSpace(request.ExternalRef)
+		request.AuthorUUID = authorUUID.String()
+		request.OrganisationUUID = organisationID
+
+		result := models.ImportRowResult{Line: row.Line, ExternalRef: request.ExternalRef}
+		if line, ok := lines[request.ExternalRef]; ok && request.ExternalRef != "" {
+			result.Errors = []string{fmt.Sprintf("externalRef is repeated from line %d", line)}
+			report.Rows = append(report.Rows, result)
+			continue
+		}
+		lines[request.ExternalRef] = row.Line
+
+		existing, err := service.importTarget(ctx, owner, request.ExternalRef)
+		if err != nil {
+			return nil, err
+		}
+
+		result.Action = ImportCreate
+		if existing != nil {
+			result.Action = ImportUpdate
+			result.OpportunityUUID = &existing.UUID
+		}
+
+		if result.Errors = service.validateImport(ctx, &request, authorUUID, existing); len(result.Errors) == 0 && !dryRun {
+			result.Errors = service.saveImport(ctx, &request, existing, &result)
+		}
+		report.Rows = append(report.Rows, result)
+	}
+
+	sort.SliceStable(report.Rows, func(i, j int) bool {
+		return report.Rows[i].Line < report.Rows[j].Line
+	})
+	for i := range report.Rows {
+		row := &report.Rows[i]
+		switch {
+		case len(row.Errors) > 0:
+			row.Action = ""
+			report.Failed++
+		case row.Action == ImportCreate:
+			report.Created++
+		case row.Action == ImportUpdate:
+			report.Updated++
+		}
+	}
+	return report, nil
+}
+
+// importTarget returns the opportunity previously imported with the reference, nil if there isn't one
+func (service *OpportunityService) importTarget(ctx context.Context, owner uuid.UUID, externalRef string) (*models.OpportunityModel, error) {
+	if externalRef == "" {
+		return nil, nil
+	}
+
+	opportunityUUID, err := service.repo.GetOpportunityByExternalRef(ctx, owner, externalRef)
+	if err != nil {
+		return nil, errInternal
+	}
+	if opportunityUUID == nil {
+		return nil, nil
+	}
+
+	existing, err := service.repo.GetOpportunityAnyStatus(ctx, *opportunityUUID)
+	if err != nil {
+		return nil, errInternal
+	}
+	return existing, nil
+}
+
+// validateImport returns everything wrong with the row, without saving anything
+func (service *OpportunityService) validateImport(ctx context.Context, request *models.CreateOpportunityRequest,
+	authorUUID uuid.UUID, existing *models.OpportunityModel) []string {
+	var problems []string
+
+	// The media already attached are loaded while the tags are checked
+	attached := concurrency.Resolved[*[]models.MediaModel](nil, nil)
+	if existing != nil {
+		attached = service.repo.GetOpportunityMediaAsync(ctx, existing.UUID)
+	}
+
+	if err := bulkimport.ValidateRef(request.ExternalRef); err != nil {
+		problems = append(problems, err.Error())
+	}
+
+	for field, value := range map[string]string{"title": request.Title, "description": request.Description,
+		"location": request.Location, "type": request.Type} {
+		if strings.TrimSpace(value) == "" {
+			problems = append(problems, field+" is required")
+		}
+	}
+	if request.Type != "" && !opportunityTypes[request.Type] {
+		problems = append(problems, fmt.Sprintf("type must be event, volunteer, job or issue, not %q", request.Type))
+	}
+	if request.Points <= 0 {
+		problems = append(problems, "points must be a positive number")
+	}
+
+	if _, err := explicitCoordinates(request); err != nil {
+		problems = append(problems, err.Error())
+	}
+	if err := applySchedule(request, &models.OpportunityModel{}); err != nil {
+		problems = append(problems, err.Error())
+	}
+
+	var tags []string
+	for _, name := range request.Tags {
+		if taxonomy.Normalise(name) == "" {
+			continue
+		}
+		if _, err := taxonomy.Validate(name); err != nil {
+			problems = append(problems, fmt.Sprintf("tag %q: %v", name, err))
+			continue
+		}
+		tags = append(tags, name)
+	}
+	if err := service.repo.ValidateTags(ctx, tags); repositories.IsTagError(err) {
+		problems = append(problems, err.Error())
+	} else if err != nil {
+		problems = append(problems, errInternal.Error())
+	}
+
+	var current []models.MediaModel
+	if media, err := attached.Get(ctx); err != nil {
+		problems = append(problems, errInternal.Error())
+	} else if media != nil {
+		current = *media
+	}
+	if _, err := service.media.Resolve(ctx, authorUUID, request.MediaIDs, current); errors.Is(err, media.ErrInvalidMedia) {
+		problems = append(problems, err.Error())
+	} else if err != nil {
+		log.Error(err)
+		problems = append(problems, errInternal.Error())
+	}
+
+	sort.Strings(problems)
+	return problems
+}
+
+// saveImport creates or updates the opportunity, returning why it couldn't be saved
+func (service *OpportunityService) saveImport(ctx context.Context, request *models.CreateOpportunityRequest, existing *models.OpportunityModel,
+	result *models.ImportRowResult) []string {
+	if existing == nil {
+		status := service.CreateOpportunity(ctx, *request)
+		if !status.Success {
+			return []string{status.Message}
+		}
+		result.OpportunityUUID = &status.OpportunityModel.UUID
+		return nil
+	}
+
+	request.UUID = existing.UUID.String()
+	if updated := service.UpdateOpportunity(ctx, *request); !updated.Success {
+		return []string{updated.Message}
+	}
+	return nil
+}
@@ -0,0 +1,98 @@
+package opportunity
+
+import (
+	"backend/internal/geo"
+	"backend/internal/models"
+	"context"
+	"errors"
+	log "github.com/sirupsen/logrus"
+	"strings"
+	"time"
+)
+
+// geocodeTimeout bounds how long creating an opportunity waits on the geocoder
+const geocodeTimeout = 5 * time.Second
+
+// locate sets the opportunities structured location from the request. Explicit coordinates are used as given,
+// otherwise the address (or the free text location) is geocoded. Online opportunities and
+// locations the geocoder doesn't recognise are saved without coordinates
+func (service *OpportunityService) locate(request *models.CreateOpportunityRequest, model *models.OpportunityModel) error {
+	model.IsOnline = request.Online
+	model.Address = request.Address
+	model.Address.Country = strings.ToUpper(strings.TrimSpace(model.Address.Country))
+
+	coordinates, err := explicitCoordinates(request)
+	if err != nil {
+		return err
+	}
+	if coordinates != nil {
+		model.Coordinates = coordinates
+		return nil
+	}
+
+	if model.IsOnline || service.geocoder == nil {
+		return nil
+	}
+
+	query := model.Address.Query()
+	if query == "" {
+		query = request.Location
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), geocodeTimeout)
+	defer cancel()
+
+	result, err := service.geocoder.Geocode(ctx, query)
+	if errors.Is(err, geo.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		// A geocoder outage shouldn't stop opportunities being posted, they just won't show in radius searches
+		log.Error(err)
+		return nil
+	}
+
+	model.Coordinates = &result.Coordinates
+	if model.Address.City == "" {
+		model.Address.City = result.Address.City
+	}
+	if model.Address.Region == "" {
+		model.Address.Region = result.Address.Region
+	}
+	if model.Address.Country == "" {
+		model.Address.Country = result.Address.Country
+	}
+
+	return nil
+}
+
+// explicitCoordinates checks the location fields that don't need geocoding, returning the coordinates if given
+func explicitCoordinates(request *models.CreateOpportunityRequest) (*geo.Coordinates, error) {
+	if len(strings.TrimSpace(request.Address.Country)) > 2 {
+		return nil, errors.New("country must be an ISO 3166-1 alpha-2 code")
+	}
+
+	if request.Latitude == nil && request.Longitude == nil {
+		return nil, nil
+	}
+	if request.Latitude == nil || request.Longitude == nil {
+		return nil, errors.New("latitude and longitude must be given together")
+	}
+
+	coordinates := geo.Coordinates{Latitude: *request.Latitude, Longitude: *request.Longitude}
+	if err := coordinates.Validate(); err != nil {
+		return nil, err
+	}
+	return &coordinates, nil
+}
+
+// setDistances fills in how far each opportunity is from the point, those without coordinates are left nil
+func setDistances(opportunities []models.OpportunityModel, from geo.Coordinates) {
+	for i := range opportunities {
+		if opportunities[i].Coordinates == nil {
+			continue
+		}
+		distance := geo.DistanceKm(from, *opportunities[i].Coordinates)
+		opportunities[i].DistanceKm = &distance
+	}
+}
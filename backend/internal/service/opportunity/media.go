@@ -0,0 +1,33 @@
+package opportunity
+
+import (
+	"backend/internal/models"
+	"backend/internal/service/media"
+	"context"
+	"errors"
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+)
+
+// resolveMedia sets the opportunities media from the uploaded media IDs in the request. Media added as URLs
+// before uploads were stored can't be referenced by ID, so it's kept from current
+func (service *OpportunityService) resolveMedia(ctx context.Context, request *models.CreateOpportunityRequest, authorUUID uuid.UUID,
+	current []models.MediaModel, model *models.OpportunityModel) error {
+	resolved, err := service.media.Resolve(ctx, authorUUID, request.MediaIDs, current)
+	if errors.Is(err, media.ErrInvalidMedia) {
+		return err
+	}
+	if err != nil {
+		log.Error(err)
+		return errors.New("Internal error occurred whilst processing media")
+	}
+
+	for _, existing := range current {
+		if existing.ID == nil {
+			resolved = append(resolved, existing)
+		}
+	}
+
+	model.Media = &resolved
+	return nil
+}
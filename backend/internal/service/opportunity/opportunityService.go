@@ -1,27 +1,49 @@
 package opportunity
 
 import (
+	"backend/internal/bulkimport"
+	"backend/internal/db/adapters/mysql"
 	"backend/internal/db/repositories"
+	"backend/internal/events"
+	"backend/internal/geo"
 	"backend/internal/models"
+	"backend/internal/search"
+	"backend/internal/service/media"
 	response "backend/internal/utils/http"
+	"context"
 	"errors"
 	"github.com/google/uuid"
 	log "github.com/sirupsen/logrus"
+	"net/url"
+	"sort"
 	"strconv"
+	"strings"
+	"time"
 )
 
+// maxSearchLimit caps how many search results can be requested at once
+const maxSearchLimit = 50
+
 // OpportunityService provides methods for managing opportunities.
 type OpportunityService struct {
-	repo *repositories.OpportunityRepository
+	repo             *repositories.OpportunityRepository
+	organisationRepo *repositories.OrganisationRepository
+	applicationRepo  *repositories.ApplicationRepository
+	index            search.SearchIndex
+	geocoder         geo.Geocoder
+	media            *media.Service
 }
 
 // NewOpportunityService creates a new instance of OpportunityService.
-func NewOpportunityService(repo *repositories.OpportunityRepository) *OpportunityService {
-	return &OpportunityService{repo: repo}
+func NewOpportunityService(repo *repositories.OpportunityRepository, organisationRepo *repositories.OrganisationRepository,
+	applicationRepo *repositories.ApplicationRepository, index search.SearchIndex, geocoder geo.Geocoder,
+	media *media.Service) *OpportunityService {
+	return &OpportunityService{repo: repo, organisationRepo: organisationRepo, applicationRepo: applicationRepo,
+		index: index, geocoder: geocoder, media: media}
 }
 
 // CreateOpportunity creates a new opportunity with the given details.
-func (service *OpportunityService) CreateOpportunity(request models.CreateOpportunityRequest) *models.CreateOpportunityStatus {
+func (service *OpportunityService) CreateOpportunity(ctx context.Context, request models.CreateOpportunityRequest) *models.CreateOpportunityStatus {
 
 	if request.Title == "" || request.Type == "" || request.Description == "" || request.Location == "" || request.AuthorUUID == "" || request.Points == 0 {
 		return writeStatus(nil, "Invalid request format", false)
@@ -43,45 +65,71 @@ func (service *OpportunityService) CreateOpportunity(request models.CreateOpport
 		Location:        request.Location,
 		OpportunityType: request.Type,
 		PostedByUUID:    postedByUUID,
+		Draft:           request.Draft,
+		ExternalRef:     strings.TrimSpace(request.ExternalRef),
 	}
 
-	var modelTags []models.TagModel
-
-	for _, tag := range request.Tags {
-		model := models.TagModel{
-			TagName: tag,
+	if opportunityModel.ExternalRef != "" {
+		if err := bulkimport.ValidateRef(opportunityModel.ExternalRef); err != nil {
+			return writeStatus(nil, err.Error(), false)
 		}
+	}
 
-		modelTags = append(modelTags, model)
+	if err := service.locate(&request, &opportunityModel); err != nil {
+		return writeStatus(nil, err.Error(), false)
 	}
-	var media []models.MediaModel
 
-	types := request.MediaTypes
-	urls := request.MediaURLs
+	if err := applySchedule(&request, &opportunityModel); err != nil {
+		return writeStatus(nil, err.Error(), false)
+	}
 
-	for i := range types {
+	if request.OrganisationUUID != "" {
+		organisationUUID, err := uuid.Parse(request.OrganisationUUID)
+		if err != nil {
+			return writeStatus(nil, "unable to parse organisation uuid", false)
+		}
 
-		parseMediaType, err := models.ParseMediaType(types[i])
+		member, err := service.organisationRepo.GetMember(ctx, organisationUUID, postedByUUID)
 		if err != nil {
 			log.Error(err)
-			return writeStatus(nil, "Internal error occured whilst processing media type", false)
+			return writeStatus(nil, "Internal error occurred whilst checking organisation", false)
 		}
 
-		media = append(media, models.MediaModel{
-			Type: parseMediaType,
-			URL:  urls[i],
-		})
+		if member == nil || !member.Role.CanEdit() {
+			return writeStatus(nil, "Author can't post for this organisation", false)
+		}
+
+		opportunityModel.OrganisationUUID = &organisationUUID
+	}
+
+	var modelTags []models.TagModel
+
+	for _, tag := range request.Tags {
+		model := models.TagModel{
+			TagName: tag,
+		}
+
+		modelTags = append(modelTags, model)
+	}
+	if err := service.resolveMedia(ctx, &request, postedByUUID, nil, &opportunityModel); err != nil {
+		return writeStatus(nil, err.Error(), false)
 	}
 
 	opportunityModel.Tags = &modelTags
-	opportunityModel.Media = &media
 
-	err = service.repo.CreateOpportunity(&opportunityModel)
+	err = service.repo.CreateOpportunity(ctx, &opportunityModel)
+	if repositories.IsTagError(err) || errors.Is(err, repositories.ErrExternalRefExists) {
+		return writeStatus(nil, err.Error(), false)
+	}
 	if err != nil {
 		log.Error(err)
 		return writeStatus(nil, "Internal error occurred whilst creating opportunity", false)
 	}
 
+	service.indexOpportunity(ctx, &opportunityModel)
+	events.Default.Publish(ctx, events.TagsChanged{})
+	service.media.SignMedia(ctx, opportunityModel.Media)
+
 	return writeStatus(&opportunityModel, "", true)
 }
 
@@ -93,7 +141,7 @@ func writeStatus(model *models.OpportunityModel, message string, success bool) *
 	}
 }
 
-func (service *OpportunityService) UpdateOpportunity(request models.CreateOpportunityRequest) *response.Response {
+func (service *OpportunityService) UpdateOpportunity(ctx context.Context, request models.CreateOpportunityRequest) *response.Response {
 	if request.Title == "" || request.Type == "" || request.Description == "" || request.Location == "" || request.AuthorUUID == "" || request.Points == 0 || request.UUID == "" {
 		return response.ErrorResponse("Invalid request format")
 	}
@@ -103,6 +151,9 @@ func (service *OpportunityService) UpdateOpportunity(request models.CreateOpport
 		return response.ErrorResponse("Unable to parse UUID")
 	}
 
+	// Loaded while the location is geocoded
+	existingMedia := service.repo.GetOpportunityMediaAsync(ctx, opportunityUUID)
+
 	model := &models.OpportunityModel{
 		UUID:            opportunityUUID,
 		Title:           request.Title,
@@ -112,6 +163,14 @@ func (service *OpportunityService) UpdateOpportunity(request models.CreateOpport
 		OpportunityType: request.Type,
 	}
 
+	if err := service.locate(&request, model); err != nil {
+		return response.ErrorResponse(err.Error())
+	}
+
+	if err := applySchedule(&request, model); err != nil {
+		return response.ErrorResponse(err.Error())
+	}
+
 	var modelTags []models.TagModel
 
 	for _, tag := range request.Tags {
@@ -121,37 +180,69 @@ func (service *OpportunityService) UpdateOpportunity(request models.CreateOpport
 
 		modelTags = append(modelTags, model)
 	}
-	var media []models.MediaModel
-
-	types := request.MediaTypes
-	urls := request.MediaURLs
-
-	for i := range types {
+	authorUUID, err := uuid.Parse(request.AuthorUUID)
+	if err != nil {
+		return response.ErrorResponse("unable to parse author uuid")
+	}
 
-		parseMediaType, err := models.ParseMediaType(types[i])
-		if err != nil {
-			log.Error(err)
-			return response.ErrorResponse("Internal error occured whilst processing media type")
-		}
+	var current []models.MediaModel
+	existing, err := existingMedia.Get(ctx)
+	if errorResponse := response.ContextError(err); errorResponse != nil {
+		return errorResponse
+	}
+	if err != nil {
+		// Replacing media that couldn't be loaded would drop them
+		return response.ErrorResponse("Internal error occured")
+	}
+	if existing != nil {
+		current = *existing
+	}
 
-		media = append(media, models.MediaModel{
-			Type: parseMediaType,
-			URL:  urls[i],
-		})
+	if err := service.resolveMedia(ctx, &request, authorUUID, current, model); err != nil {
+		return response.ErrorResponse(err.Error())
 	}
 
 	model.Tags = &modelTags
-	model.Media = &media
 
-	err = service.repo.UpdateOpportunity(model)
+	err = service.repo.Repository.WithTx(ctx, func(tx *mysql.Tx) error {
+		if err := service.repo.UpdateOpportunity(tx.Context(), model, authorUUID); err != nil {
+			return err
+		}
+
+		// A raised or removed capacity gives the waitlist their places
+		return service.fillFromWaitlist(tx.Context(), opportunityUUID)
+	})
+	if repositories.IsTagError(err) {
+		return response.ErrorResponse(err.Error())
+	}
+	if errorResponse := response.ContextError(err); errorResponse != nil {
+		return errorResponse
+	}
 	if err != nil {
 		return response.ErrorResponse("Internal error occured")
 	}
 
+	service.indexOpportunity(ctx, model)
+	events.Default.Publish(ctx, events.TagsChanged{})
+	service.media.SignMedia(ctx, model.Media)
+
 	return response.SuccessResponse(model, "")
 }
 
-func (service *OpportunityService) UpdateStatus(opportunityID string, status string) *response.Response {
+// fillFromWaitlist gives the waitlist the places a change in the transaction freed. Failing to doesn't undo the
+// change, but a deadlock has already rolled back the whole transaction, so it's returned for WithTx to run again
+func (service *OpportunityService) fillFromWaitlist(ctx context.Context, opportunityUUID uuid.UUID) error {
+	err := service.applicationRepo.FillFromWaitlist(ctx, opportunityUUID)
+	if mysql.IsRetryable(err) {
+		return err
+	}
+	if err != nil {
+		log.Error(err)
+	}
+	return nil
+}
+
+func (service *OpportunityService) UpdateStatus(ctx context.Context, opportunityID string, status string) *response.Response {
 
 	if opportunityID == "" {
 		return response.ErrorResponse("Opportunity UUID not provided")
@@ -169,17 +260,43 @@ func (service *OpportunityService) UpdateStatus(opportunityID string, status str
 		return response.ErrorResponse("Unable to parse status")
 	}
 
-	err = service.repo.UpdateOpportunityStatus(opportunityUUID, opportunityStatus)
+	if opportunityStatus {
+		current, err := service.repo.GetOpportunityAnyStatus(ctx, opportunityUUID)
+		if errorResponse := response.ContextError(err); errorResponse != nil {
+			return errorResponse
+		}
+		if err != nil {
+			return response.ErrorResponse("Internal error occurred")
+		}
+		if current == nil {
+			return response.ErrorResponse("Opportunity not found")
+		}
+		if current.Draft {
+			return response.ErrorResponse("Drafts can't be approved until they're submitted")
+		}
+	}
+
+	changed, err := service.repo.UpdateOpportunityStatus(ctx, opportunityUUID, opportunityStatus)
+	if errorResponse := response.ContextError(err); errorResponse != nil {
+		return errorResponse
+	}
 	if err != nil {
 		return response.ErrorResponse("Internal error occurred")
 	}
+	// Tag suggestions are only learnt from approved opportunities
+	events.Default.Publish(ctx, events.TagsChanged{})
+
+	// Approving an opportunity that's already approved isn't news
+	if opportunityStatus && changed {
+		events.Default.Publish(ctx, events.OpportunityApproved{OpportunityUUID: opportunityUUID})
+	}
 
 	return response.SuccessResponse(nil, "")
 }
 
 // GetOpportunity retrieves an opportunity by its UUID.
-func (service *OpportunityService) GetOpportunity(opportunityUUID uuid.UUID) (*models.OpportunityModel, error) {
-	opportunity, err := service.repo.GetOpportunity(&opportunityUUID)
+func (service *OpportunityService) GetOpportunity(ctx context.Context, opportunityUUID uuid.UUID) (*models.OpportunityModel, error) {
+	opportunity, err := service.repo.GetOpportunity(ctx, &opportunityUUID)
 	if err != nil {
 		return nil, err
 	}
@@ -187,10 +304,11 @@ func (service *OpportunityService) GetOpportunity(opportunityUUID uuid.UUID) (*m
 		return nil, nil
 	}
 	model := *opportunity
+	service.media.SignMedia(ctx, model[0].Media)
 	return &model[0], nil
 }
 
-func (service *OpportunityService) GetOpportunitiesByAuthor(authorID string) *response.Response {
+func (service *OpportunityService) GetOpportunitiesByAuthor(ctx context.Context, authorID string) *response.Response {
 	if authorID == "" {
 		return response.ErrorResponse("Author uuid not provided")
 	}
@@ -201,24 +319,163 @@ func (service *OpportunityService) GetOpportunitiesByAuthor(authorID string) *re
 		return response.ErrorResponse("Unable to parse uuid")
 	}
 
-	oppportunities, err := service.repo.GetOpportunityByAuthor(&authorUUID)
+	oppportunities, err := service.repo.GetOpportunityByAuthor(ctx, &authorUUID)
+	if errorResponse := response.ContextError(err); errorResponse != nil {
+		return errorResponse
+	}
 	if err != nil {
 		return response.ErrorResponse("Internal error occured")
 	}
+	if oppportunities != nil {
+		service.media.SignOpportunities(ctx, *oppportunities)
+	}
 
 	return response.SuccessResponse(oppportunities, "")
 }
 
+func (service *OpportunityService) GetOpportunitiesByOrganisation(ctx context.Context, organisationID string) *response.Response {
+	if organisationID == "" {
+		return response.ErrorResponse("Organisation uuid not provided")
+	}
+
+	organisationUUID, err := uuid.Parse(organisationID)
+
+	if err != nil {
+		return response.ErrorResponse("Unable to parse uuid")
+	}
+
+	opportunities, err := service.repo.GetOpportunityByOrganisation(ctx, &organisationUUID)
+	if errorResponse := response.ContextError(err); errorResponse != nil {
+		return errorResponse
+	}
+	if err != nil {
+		return response.ErrorResponse("Internal error occured")
+	}
+	if opportunities != nil {
+		service.media.SignOpportunities(ctx, *opportunities)
+	}
+
+	return response.SuccessResponse(opportunities, "")
+}
+
 // DeleteOpportunity deletes an opportunity by its UUID.
-func (service *OpportunityService) DeleteOpportunity(opportunityUUID uuid.UUID) error {
-	return service.repo.DeleteOpportunity(opportunityUUID)
+func (service *OpportunityService) DeleteOpportunity(ctx context.Context, opportunityUUID uuid.UUID) error {
+	err := service.repo.DeleteOpportunity(ctx, opportunityUUID)
+	if err != nil {
+		return err
+	}
+
+	if err := service.index.Remove(ctx, opportunityUUID); err != nil {
+		log.Error("Failed to remove opportunity from search index: ", err)
+	}
+	events.Default.Publish(ctx, events.TagsChanged{})
+	return nil
+}
+
+// Search returns approved opportunities matching the free text query, most relevant first
+func (service *OpportunityService) Search(ctx context.Context, text string, limit string) *response.Response {
+	if strings.TrimSpace(text) == "" {
+		return response.ErrorResponse("Search query not provided")
+	}
+
+	limitInt := int64(search.DefaultLimit)
+	if limit != "" {
+		parsed, err := strconv.ParseInt(limit, 10, 64)
+		if err != nil || parsed <= 0 {
+			return response.ErrorResponse("limit is not a positive integer")
+		}
+		limitInt = min(parsed, maxSearchLimit)
+	}
+
+	hits, err := service.index.Search(ctx, search.Query{Text: text, Limit: int(limitInt)})
+	if errorResponse := response.ContextError(err); errorResponse != nil {
+		return errorResponse
+	}
+	if err != nil {
+		log.Error(err)
+		return response.ErrorResponse("Internal error occurred")
+	}
+
+	results := []models.OpportunitySearchResult{}
+	if len(hits) == 0 {
+		return response.SuccessResponse(results, "")
+	}
+
+	opportunityUUIDs := make([]*uuid.UUID, len(hits))
+	for i := range hits {
+		opportunityUUIDs[i] = &hits[i].UUID
+	}
+
+	opportunities, err := service.repo.GetOpportunity(ctx, opportunityUUIDs...)
+	if errorResponse := response.ContextError(err); errorResponse != nil {
+		return errorResponse
+	}
+	if err != nil {
+		log.Error(err)
+		return response.ErrorResponse("Internal error occurred")
+	}
+
+	byUUID := map[uuid.UUID]models.OpportunityModel{}
+	if opportunities != nil {
+		service.media.SignOpportunities(ctx, *opportunities)
+		for _, opportunity := range *opportunities {
+			byUUID[opportunity.UUID] = opportunity
+		}
+	}
+
+	// Keep the index's ranking, skipping anything no longer visible or closed
+	now := time.Now()
+	for _, hit := range hits {
+		opportunity, ok := byUUID[hit.UUID]
+		if !ok || !opportunity.IsOpen(now) {
+			continue
+		}
+		results = append(results, models.OpportunitySearchResult{
+			Opportunity: opportunity,
+			Score:       hit.Score,
+			Highlights:  hit.Highlights,
+		})
+	}
+
+	return response.SuccessResponse(results, "")
+}
+
+// indexOpportunity keeps the search index up to date, failures only make search stale so are just logged
+func (service *OpportunityService) indexOpportunity(ctx context.Context, model *models.OpportunityModel) {
+	doc := search.Document{
+		UUID:        model.UUID,
+		Title:       model.Title,
+		Description: model.Description,
+		Location:    model.Location,
+	}
+
+	if model.Tags != nil {
+		for _, tag := range *model.Tags {
+			doc.Tags = append(doc.Tags, tag.TagName)
+		}
+	}
+
+	if model.OrganisationUUID != nil {
+		organisation, err := service.organisationRepo.GetOrganisation(ctx, *model.OrganisationUUID)
+		if err == nil && organisation != nil {
+			doc.Organisation = organisation.Name
+		}
+	}
+
+	if err := service.index.Index(ctx, doc); err != nil {
+		log.Error("Failed to index opportunity: ", err)
+	}
 }
 
-func (service *OpportunityService) GetOpportunitiesByTag(tagName string) (*[]models.OpportunityModel, error) {
-	return service.repo.GetOpportunitiesByTag(tagName)
+func (service *OpportunityService) GetOpportunitiesByTag(ctx context.Context, tagName string) (*[]models.OpportunityModel, error) {
+	opportunities, err := service.repo.GetOpportunitiesByTag(ctx, tagName)
+	if err == nil && opportunities != nil {
+		service.media.SignOpportunities(ctx, *opportunities)
+	}
+	return opportunities, err
 }
 
-func (service *OpportunityService) GetOpportunitiesFrom(from string, limit string, userUUID uuid.UUID) (*[]models.OpportunityModel, int64, error) {
+func (service *OpportunityService) GetOpportunitiesFrom(ctx context.Context, from string, limit string, userUUID uuid.UUID) (*[]models.OpportunityModel, int64, error) {
 
 	var fromInt int64
 	var limitInt int64
@@ -234,10 +491,75 @@ func (service *OpportunityService) GetOpportunitiesFrom(from string, limit strin
 		return nil, 0, errors.New("unable limit parse 'limit' as an integer")
 	}
 
-	return service.repo.GetOpportunitiesFrom(fromInt, limitInt, userUUID)
+	opportunities, lastIndex, err := service.repo.GetOpportunitiesFrom(ctx, fromInt, limitInt, userUUID)
+	if err != nil || opportunities == nil {
+		return opportunities, lastIndex, err
+	}
+	service.media.SignOpportunities(ctx, *opportunities)
+
+	followed, err := service.organisationRepo.GetFollowedOrganisations(ctx, userUUID)
+	if err != nil {
+		// Boosting is best effort, still return the page
+		log.Error(err)
+		return opportunities, lastIndex, nil
+	}
+
+	boostFollowed(*opportunities, followed)
+
+	return opportunities, lastIndex, nil
 }
 
-func (service *OpportunityService) GetOpportunityByLikes(opportunityID string, from string, limit string) *response.Response {
+// boostFollowed moves opportunities from followed organisations to the front of the page.
+// The sort is stable so the feed order is otherwise unchanged, and only reorders within the page so pagination still works
+func boostFollowed(opportunities []models.OpportunityModel, followed []models.OrganisationModel) {
+	if len(followed) == 0 {
+		return
+	}
+
+	following := make(map[uuid.UUID]bool, len(followed))
+	for _, organisation := range followed {
+		following[organisation.UUID] = true
+	}
+
+	isFollowed := func(opportunity models.OpportunityModel) bool {
+		return opportunity.OrganisationUUID != nil && following[*opportunity.OrganisationUUID]
+	}
+
+	sort.SliceStable(opportunities, func(i, j int) bool {
+		return isFollowed(opportunities[i]) && !isFollowed(opportunities[j])
+	})
+}
+
+// GetOpportunitiesFiltered returns a page of opportunities matching the filter query parameters
+func (service *OpportunityService) GetOpportunitiesFiltered(ctx context.Context, query url.Values) *response.Response {
+	filter, err := ParseFilter(query)
+	if err != nil {
+		return response.ErrorResponse(err.Error())
+	}
+
+	opportunities, err := service.repo.GetOpportunitiesFiltered(ctx, filter)
+
+	var filterError *models.FilterError
+	if errors.As(err, &filterError) {
+		return response.ErrorResponse(filterError.Error())
+	}
+	if errorResponse := response.ContextError(err); errorResponse != nil {
+		return errorResponse
+	}
+	if err != nil {
+		log.Error(err)
+		return response.ErrorResponse("Internal error occurred")
+	}
+
+	if filter.Near != nil {
+		setDistances(*opportunities, *filter.Near)
+	}
+	service.media.SignOpportunities(ctx, *opportunities)
+
+	return response.SuccessResponse(opportunities, "")
+}
+
+func (service *OpportunityService) GetOpportunityByLikes(ctx context.Context, opportunityID string, from string, limit string) *response.Response {
 
 	if opportunityID == "" || from == "" || limit == "" {
 		return response.ErrorResponse("must be provided")
@@ -259,7 +581,10 @@ func (service *OpportunityService) GetOpportunityByLikes(opportunityID string, f
 		return response.ErrorResponse("limit is not an integer")
 	}
 
-	likes, lastRow, err := service.repo.GetOpportunityByLikes(opportunityUUID, fromInt, limitInt)
+	likes, lastRow, err := service.repo.GetOpportunityByLikes(ctx, opportunityUUID, fromInt, limitInt)
+	if errorResponse := response.ContextError(err); errorResponse != nil {
+		return errorResponse
+	}
 	if err != nil {
 		log.Error(err)
 		return response.ErrorResponse("Internal error occured")
@@ -268,6 +593,9 @@ func (service *OpportunityService) GetOpportunityByLikes(opportunityID string, f
 	if likes == nil {
 		return response.ErrorResponse("No likes for given opportunity")
 	}
+	for _, student := range *likes {
+		service.media.SignProfilePic(student)
+	}
 
 	model := struct {
 		Likes     *[]*models.StudentInfoModel `json:"likes"`
@@ -277,13 +605,16 @@ func (service *OpportunityService) GetOpportunityByLikes(opportunityID string, f
 	return response.SuccessResponse(model, "")
 }
 
-func (service *OpportunityService) LikeOpportunity(userID, postID string) *response.Response {
+func (service *OpportunityService) LikeOpportunity(ctx context.Context, userID, postID string) *response.Response {
 	userUUID, postUUID, errResp := parseUUIDs(userID, postID)
 	if errResp != nil {
 		return errResp
 	}
 
-	if err := service.repo.LikeOpportunity(userUUID, postUUID); err != nil {
+	if err := service.repo.LikeOpportunity(ctx, userUUID, postUUID); err != nil {
+		if errorResponse := response.ContextError(err); errorResponse != nil {
+			return errorResponse
+		}
 		log.Error(err)
 		return response.ErrorResponse("Internal error occurred")
 	}
@@ -291,13 +622,16 @@ func (service *OpportunityService) LikeOpportunity(userID, postID string) *respo
 	return response.SuccessResponse(nil, "Successfully liked opportunity")
 }
 
-func (service *OpportunityService) DislikeOpportunity(userID, postID string) *response.Response {
+func (service *OpportunityService) DislikeOpportunity(ctx context.Context, userID, postID string) *response.Response {
 	userUUID, postUUID, errResp := parseUUIDs(userID, postID)
 	if errResp != nil {
 		return errResp
 	}
 
-	if err := service.repo.DislikeOpportunity(userUUID, postUUID); err != nil {
+	if err := service.repo.DislikeOpportunity(ctx, userUUID, postUUID); err != nil {
+		if errorResponse := response.ContextError(err); errorResponse != nil {
+			return errorResponse
+		}
 		log.Error(err)
 		return response.ErrorResponse("Internal error occurred")
 	}
@@ -305,13 +639,16 @@ func (service *OpportunityService) DislikeOpportunity(userID, postID string) *re
 	return response.SuccessResponse(nil, "Successfully disliked opportunity")
 }
 
-func (service *OpportunityService) DeleteLikeOpportunity(userID, postID string) *response.Response {
+func (service *OpportunityService) DeleteLikeOpportunity(ctx context.Context, userID, postID string) *response.Response {
 	userUUID, postUUID, errResp := parseUUIDs(userID, postID)
 	if errResp != nil {
 		return errResp
 	}
 
-	if err := service.repo.DeleteLikeOpportunity(userUUID, postUUID); err != nil {
+	if err := service.repo.DeleteLikeOpportunity(ctx, userUUID, postUUID); err != nil {
+		if errorResponse := response.ContextError(err); errorResponse != nil {
+			return errorResponse
+		}
 		log.Error(err)
 		return response.ErrorResponse("Internal error occurred")
 	}
@@ -319,13 +656,16 @@ func (service *OpportunityService) DeleteLikeOpportunity(userID, postID string)
 	return response.SuccessResponse(nil, "Successfully deleted liked opportunity")
 }
 
-func (service *OpportunityService) DeleteDislikeOpportunity(userID, postID string) *response.Response {
+func (service *OpportunityService) DeleteDislikeOpportunity(ctx context.Context, userID, postID string) *response.Response {
 	userUUID, postUUID, errResp := parseUUIDs(userID, postID)
 	if errResp != nil {
 		return errResp
 	}
 
-	if err := service.repo.DeleteDislikeOpportunity(userUUID, postUUID); err != nil {
+	if err := service.repo.DeleteDislikeOpportunity(ctx, userUUID, postUUID); err != nil {
+		if errorResponse := response.ContextError(err); errorResponse != nil {
+			return errorResponse
+		}
 		log.Error(err)
 		return response.ErrorResponse("Internal error occurred")
 	}
@@ -0,0 +1,40 @@
+package opportunity
+
+import (
+	"backend/internal/models"
+	"github.com/google/uuid"
+	"testing"
+)
+
+func TestBoostFollowedIsStable(t *testing.T) {
+	followedOrg := uuid.New()
+	otherOrg := uuid.New()
+
+	opportunities := []models.OpportunityModel{
+		{Title: "a", OrganisationUUID: &otherOrg},
+		{Title: "b", OrganisationUUID: &followedOrg},
+		{Title: "c"},
+		{Title: "d", OrganisationUUID: &followedOrg},
+		{Title: "e", OrganisationUUID: &otherOrg},
+	}
+
+	boostFollowed(opportunities, []models.OrganisationModel{{UUID: followedOrg}})
+
+	expected := []string{"b", "d", "a", "c", "e"}
+	for i, title := range expected {
+		if opportunities[i].Title != title {
+			t.Fatalf("position %d: expected %s got %s", i, title, opportunities[i].Title)
+		}
+	}
+}
+
+func TestBoostFollowedWithoutFollowing(t *testing.T) {
+	org := uuid.New()
+	opportunities := []models.OpportunityModel{{Title: "a"}, {Title: "b", OrganisationUUID: &org}}
+
+	boostFollowed(opportunities, nil)
+
+	if opportunities[0].Title != "a" || opportunities[1].Title != "b" {
+		t.Fatal("order changed without any followed organisations")
+	}
+}
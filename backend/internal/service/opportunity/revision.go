@@ -0,0 +1,238 @@
+package opportunity
+
+import (
+	"backend/internal/db/adapters/mysql"
+	"backend/internal/events"
+	"backend/internal/models"
+	response "backend/internal/utils/http"
+	"context"
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// SubmitDraft submits a draft opportunity for review
+func (service *OpportunityService) SubmitDraft(ctx context.Context, opportunityID string, user *models.UserInfoModel) *response.Response {
+	model, errorResponse := service.managedOpportunity(ctx, opportunityID, user)
+	if errorResponse != nil {
+		return errorResponse
+	}
+	if !model.Draft {
+		return response.ErrorResponse("Opportunity has already been submitted")
+	}
+
+	if err := service.repo.SubmitDraft(ctx, model.UUID); err != nil {
+		if errorResponse := response.ContextError(err); errorResponse != nil {
+			return errorResponse
+		}
+		return response.ErrorResponse("Internal error occurred")
+	}
+	return response.SuccessResponse(nil, "")
+}
+
+// GetRevisions lists every version of the opportunity, oldest first
+func (service *OpportunityService) GetRevisions(ctx context.Context, opportunityID string, user *models.UserInfoModel) *response.Response {
+	model, errorResponse := service.managedOpportunity(ctx, opportunityID, user)
+	if errorResponse != nil {
+		return errorResponse
+	}
+
+	revisions, err := service.repo.GetRevisions(ctx, model.UUID)
+	if errorResponse := response.ContextError(err); errorResponse != nil {
+		return errorResponse
+	}
+	if err != nil {
+		return response.ErrorResponse("Internal error occurred")
+	}
+	return response.SuccessResponse(revisions, "")
+}
+
+func (service *OpportunityService) GetRevision(ctx context.Context, opportunityID string, revision string, user *models.UserInfoModel) *response.Response {
+	model, errorResponse := service.managedOpportunity(ctx, opportunityID, user)
+	if errorResponse != nil {
+		return errorResponse
+	}
+
+	found, errorResponse := service.revision(ctx, model.UUID, revision)
+	if errorResponse != nil {
+		return errorResponse
+	}
+	return response.SuccessResponse(found, "")
+}
+
+// DiffRevisions returns the fields changed between two revisions. Without to it's compared to the latest
+func (service *OpportunityService) DiffRevisions(ctx context.Context, opportunityID string, from string, to string, user *models.UserInfoModel) *response.Response {
+	model, errorResponse := service.managedOpportunity(ctx, opportunityID, user)
+	if errorResponse != nil {
+		return errorResponse
+	}
+
+	older, errorResponse := service.revision(ctx, model.UUID, from)
+	if errorResponse != nil {
+		return errorResponse
+	}
+
+	var newer *models.OpportunityRevisionModel
+	if to == "" {
+		revisions, err := service.repo.GetRevisions(ctx, model.UUID)
+		if errorResponse := response.ContextError(err); errorResponse != nil {
+			return errorResponse
+		}
+		if err != nil {
+			return response.ErrorResponse("Internal error occurred")
+		}
+		newer = &revisions[len(revisions)-1]
+	} else if newer, errorResponse = service.revision(ctx, model.UUID, to); errorResponse != nil {
+		return errorResponse
+	}
+
+	return response.SuccessResponse(models.RevisionDiffModel{
+		From:    older.Revision,
+		To:      newer.Revision,
+		Changes: diffSnapshots(&older.Snapshot, &newer.Snapshot),
+	}, "")
+}
+
+// Rollback restores the opportunity to an earlier revision, recorded as a new revision by the admin
+func (service *OpportunityService) Rollback(ctx context.Context, opportunityID string, revision string, admin *models.UserInfoModel) *response.Response {
+	model, errorResponse := service.managedOpportunity(ctx, opportunityID, admin)
+	if errorResponse != nil {
+		return errorResponse
+	}
+
+	restored, errorResponse := service.revision(ctx, model.UUID, revision)
+	if errorResponse != nil {
+		return errorResponse
+	}
+
+	restored.Snapshot.Restore(model)
+	err := service.repo.Repository.WithTx(ctx, func(tx *mysql.Tx) error {
+		if err := service.repo.RollbackOpportunity(tx.Context(), model, admin.UUID, restored.Revision); err != nil {
+			return err
+		}
+
+		// The restored capacity may give the waitlist places
+		return service.fillFromWaitlist(tx.Context(), model.UUID)
+	})
+	if errorResponse := response.ContextError(err); errorResponse != nil {
+		return errorResponse
+	}
+	if err != nil {
+		log.Error(err)
+		return response.ErrorResponse("Internal error occurred")
+	}
+
+	service.indexOpportunity(ctx, model)
+	events.Default.Publish(ctx, events.TagsChanged{})
+	service.media.SignMedia(ctx, model.Media)
+
+	return response.SuccessResponse(model, "")
+}
+
+// managedOpportunity returns the opportunity, approved or not, if the user can manage it
+func (service *OpportunityService) managedOpportunity(ctx context.Context, opportunityID string, user *models.UserInfoModel) (*models.OpportunityModel, *response.Response) {
+	opportunityUUID, err := uuid.Parse(opportunityID)
+	if err != nil {
+		return nil, response.ErrorResponse("Unable to parse UUID")
+	}
+
+	model, err := service.repo.GetOpportunityAnyStatus(ctx, opportunityUUID)
+	if errorResponse := response.ContextError(err); errorResponse != nil {
+		return nil, errorResponse
+	}
+	if err != nil {
+		return nil, response.ErrorResponse("Internal error occurred")
+	}
+	if model == nil {
+		return nil, response.ErrorResponse("Opportunity not found")
+	}
+
+	if errorResponse := service.authoriseManage(ctx, model, user); errorResponse != nil {
+		return nil, errorResponse
+	}
+	return model, nil
+}
+
+func (service *OpportunityService) revision(ctx context.Context, opportunityUUID uuid.UUID, revision string) (*models.OpportunityRevisionModel, *response.Response) {
+	number, err := strconv.ParseInt(revision, 10, 64)
+	if err != nil {
+		return nil, response.ErrorResponse("Unable to parse revision")
+	}
+
+	found, err := service.repo.GetRevision(ctx, opportunityUUID, number)
+	if errorResponse := response.ContextError(err); errorResponse != nil {
+		return nil, errorResponse
+	}
+	if err != nil {
+		return nil, response.ErrorResponse("Internal error occurred")
+	}
+	if found == nil {
+		return nil, response.ErrorResponse("Revision not found")
+	}
+	return found, nil
+}
+
+// diffSnapshots compares the snapshots field by field, in the order they're declared
+func diffSnapshots(from, to *models.OpportunitySnapshot) []models.RevisionChange {
+	changes := make([]models.RevisionChange, 0)
+
+	fromValue, toValue := reflect.ValueOf(*from), reflect.ValueOf(*to)
+	for i := 0; i < fromValue.NumField(); i++ {
+		field := strings.Split(fromValue.Type().Field(i).Tag.Get("json"), ",")[0]
+
+		switch field {
+		case "tags":
+			if added, removed := difference(from.Tags, to.Tags); len(added)+len(removed) > 0 {
+				changes = append(changes, models.RevisionChange{Field: field, Added: added, Removed: removed})
+			}
+		case "media":
+			if added, removed := difference(mediaKeys(from.Media), mediaKeys(to.Media)); len(added)+len(removed) > 0 {
+				changes = append(changes, models.RevisionChange{Field: field, Added: added, Removed: removed})
+			}
+		default:
+			before, after := fromValue.Field(i).Interface(), toValue.Field(i).Interface()
+			if !reflect.DeepEqual(before, after) {
+				changes = append(changes, models.RevisionChange{Field: field, From: before, To: after})
+			}
+		}
+	}
+	return changes
+}
+
+// difference returns what's only in after and what's only in before, sorted
+func difference(before, after []string) (added []string, removed []string) {
+	inBefore, inAfter := map[string]bool{}, map[string]bool{}
+	for _, value := range before {
+		inBefore[value] = true
+	}
+	for _, value := range after {
+		inAfter[value] = true
+		if !inBefore[value] {
+			added = append(added, value)
+		}
+	}
+	for _, value := range before {
+		if !inAfter[value] {
+			removed = append(removed, value)
+		}
+	}
+	sort.Strings(added)
+	sort.Strings(removed)
+	return added, removed
+}
+
+// mediaKeys identifies media by its id, or its URL for media added before uploads were stored
+func mediaKeys(media []models.MediaModel) []string {
+	keys := make([]string, len(media))
+	for i, item := range media {
+		if item.ID != nil {
+			keys[i] = item.ID.String()
+		} else {
+			keys[i] = item.URL
+		}
+	}
+	return keys
+}
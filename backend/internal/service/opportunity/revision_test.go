@@ -0,0 +1,107 @@
+package opportunity
+
+import (
+	"backend/internal/models"
+	"encoding/json"
+	"github.com/google/uuid"
+	"testing"
+	"time"
+)
+
+func TestDiffSnapshots(t *testing.T) {
+	london, err := time.LoadLocation("Europe/London")
+	if err != nil {
+		t.Skip("no timezone data")
+	}
+	startsAt := time.Date(2025, 6, 1, 10, 0, 0, 0, london)
+	mediaID := uuid.New()
+
+	before := (&models.OpportunityModel{
+		Title:       "Beach clean",
+		Description: "Bring gloves",
+		Points:      10,
+		StartsAt:    &startsAt,
+		Tags:        &[]models.TagModel{{TagName: "beach"}, {TagName: "litter"}},
+		Media:       &[]models.MediaModel{{ID: &mediaID, URL: "https://signed.example.com"}},
+	}).Snapshot()
+
+	// The same time read back in UTC isn't a change
+	utcStart := startsAt.UTC()
+	after := (&models.OpportunityModel{
+		Title:       "Beach clean",
+		Description: "Bring gloves and a hat",
+		Points:      15,
+		StartsAt:    &utcStart,
+		Tags:        &[]models.TagModel{{TagName: "beach"}, {TagName: "recycling"}},
+		Media:       &[]models.MediaModel{},
+	}).Snapshot()
+
+	changes := diffSnapshots(&before, &after)
+	byField := map[string]models.RevisionChange{}
+	for _, change := range changes {
+		byField[change.Field] = change
+	}
+
+	if len(changes) != 4 {
+		t.Fatalf("expected description, points, tags and media to change, got %+v", changes)
+	}
+	if byField["points"].From != int64(10) || byField["points"].To != int64(15) {
+		t.Fatalf("unexpected points change %+v", byField["points"])
+	}
+	if tags := byField["tags"]; len(tags.Added) != 1 || tags.Added[0] != "recycling" || len(tags.Removed) != 1 || tags.Removed[0] != "litter" {
+		t.Fatalf("unexpected tags change %+v", tags)
+	}
+	if media := byField["media"]; len(media.Removed) != 1 || media.Removed[0] != mediaID.String() {
+		t.Fatalf("unexpected media change %+v", media)
+	}
+}
+
+func TestSnapshotRestoresThroughJSON(t *testing.T) {
+	capacity := int64(20)
+	deadline := time.Date(2025, 5, 30, 17, 0, 0, 0, time.UTC)
+	mediaID := uuid.New()
+
+	original := &models.OpportunityModel{
+		Title:    "Tree planting",
+		Points:   5,
+		Capacity: &capacity,
+		Deadline: &deadline,
+		Tags:     &[]models.TagModel{{ID: 3, TagName: "tree planting"}},
+		Media: &[]models.MediaModel{
+			{ID: &mediaID, Type: models.Image, URL: "https://signed.example.com"},
+			{Type: models.Video, URL: "https://videos.example.com/legacy.mp4"},
+		},
+	}
+
+	stored, err := json.Marshal(original.Snapshot())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var snapshot models.OpportunitySnapshot
+	if err := json.Unmarshal(stored, &snapshot); err != nil {
+		t.Fatal(err)
+	}
+
+	restored := &models.OpportunityModel{Title: "Edited", Points: 50}
+	snapshot.Restore(restored)
+
+	if restored.Title != "Tree planting" || restored.Points != 5 || *restored.Capacity != 20 || !restored.Deadline.Equal(deadline) {
+		t.Fatalf("unexpected restore %+v", restored)
+	}
+	if tags := *restored.Tags; len(tags) != 1 || tags[0].TagName != "tree planting" {
+		t.Fatalf("unexpected tags %+v", tags)
+	}
+
+	media := *restored.Media
+	if len(media) != 2 || *media[0].ID != mediaID || media[0].URL != "" || media[1].Type != models.Video || media[1].URL == "" {
+		t.Fatalf("expected signed urls to be dropped and legacy urls kept, got %+v", media)
+	}
+
+	if changes := diffSnapshots(&snapshot, ptr(restored.Snapshot())); len(changes) != 0 {
+		t.Fatalf("expected no changes after restoring, got %+v", changes)
+	}
+}
+
+func ptr[T any](value T) *T {
+	return &value
+}
@@ -0,0 +1,326 @@
+package opportunity
+
+import (
+	"backend/internal/db/repositories"
+	"backend/internal/models"
+	"backend/internal/schedule"
+	"backend/internal/utils/concurrency"
+	response "backend/internal/utils/http"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+	"strconv"
+	"time"
+)
+
+const (
+	// defaultOccurrenceWindow is how far ahead occurrences are listed when no end is given
+	defaultOccurrenceWindow = 90 * 24 * time.Hour
+	defaultOccurrenceLimit  = 50
+	maxOccurrenceLimit      = 366
+)
+
+// localTimeLayouts are accepted for times without an offset, which are read in the opportunities timezone
+var localTimeLayouts = []string{"2006-01-02T15:04:05", "2006-01-02T15:04", "2006-01-02 15:04", time.DateOnly}
+
+// applySchedule sets the opportunities schedule, deadline and capacity from the request and works out when it expires
+func applySchedule(request *models.CreateOpportunityRequest, model *models.OpportunityModel) error {
+	location, err := schedule.LoadLocation(request.TimeZone)
+	if err != nil {
+		return err
+	}
+	model.TimeZone = location.String()
+
+	if model.StartsAt, err = parseScheduleTime(request.StartsAt, location, "startsAt"); err != nil {
+		return err
+	}
+	if model.EndsAt, err = parseScheduleTime(request.EndsAt, location, "endsAt"); err != nil {
+		return err
+	}
+	if model.Deadline, err = parseScheduleTime(request.Deadline, location, "deadline"); err != nil {
+		return err
+	}
+
+	if request.Capacity != nil && *request.Capacity < 1 {
+		return errors.New("capacity must be at least 1")
+	}
+	model.Capacity = request.Capacity
+
+	if model.EndsAt != nil && model.StartsAt == nil {
+		return errors.New("endsAt needs a startsAt")
+	}
+
+	var recurrence *schedule.Recurrence
+	if request.Recurrence != "" {
+		if model.StartsAt == nil {
+			return errors.New("recurrence needs a startsAt")
+		}
+		if recurrence, err = schedule.ParseRecurrence(request.Recurrence); err != nil {
+			return err
+		}
+		model.Recurrence = recurrence.String()
+	}
+
+	model.ExpiresAt = model.Deadline
+	if model.StartsAt == nil {
+		return nil
+	}
+
+	opportunitySchedule := scheduleOf(model, location, recurrence)
+	if err := opportunitySchedule.Validate(); err != nil {
+		return err
+	}
+
+	// Opportunities close at the deadline or once the last occurrence has finished, whichever is first
+	if last := opportunitySchedule.Last(); last != nil {
+		end := last.End
+		if model.ExpiresAt == nil || end.Before(*model.ExpiresAt) {
+			model.ExpiresAt = &end
+		}
+	}
+
+	return nil
+}
+
+// parseScheduleTime parses an RFC 3339 time, or a local time in the location. Empty is nil
+func parseScheduleTime(raw string, location *time.Location, field string) (*time.Time, error) {
+	if raw == "" {
+		return nil, nil
+	}
+
+	if value, err := time.Parse(time.RFC3339, raw); err == nil {
+		value = value.In(location)
+		return &value, nil
+	}
+
+	for _, layout := range localTimeLayouts {
+		if value, err := time.ParseInLocation(layout, raw, location); err == nil {
+			return &value, nil
+		}
+	}
+
+	return nil, errors.New(field + " must be an RFC 3339 time or a local time like 2025-06-01T10:00")
+}
+
+// scheduleOf returns the opportunities schedule, the recurrence is parsed from the model if nil
+func scheduleOf(model *models.OpportunityModel, location *time.Location, recurrence *schedule.Recurrence) schedule.Schedule {
+	if recurrence == nil && model.Recurrence != "" {
+		parsed, err := schedule.ParseRecurrence(model.Recurrence)
+		if err != nil {
+			// Stored rules were validated when saved, an unparseable one is treated as not repeating
+			log.Error(err)
+		}
+		recurrence = parsed
+	}
+
+	opportunitySchedule := schedule.Schedule{Start: *model.StartsAt, Location: location, Recurrence: recurrence}
+	if model.EndsAt != nil {
+		opportunitySchedule.End = *model.EndsAt
+	}
+	return opportunitySchedule
+}
+
+// GetOccurrences lists when the opportunity happens between from (default now) and to (default 90 days after from)
+func (service *OpportunityService) GetOccurrences(ctx context.Context, opportunityID string, from string, to string, limit string) *response.Response {
+	model, errorResponse := service.existingOpportunity(ctx, opportunityID)
+	if errorResponse != nil {
+		return errorResponse
+	}
+
+	if model.StartsAt == nil {
+		return response.SuccessResponse([]schedule.Occurrence{}, "Opportunity has no set times")
+	}
+
+	location, err := schedule.LoadLocation(model.TimeZone)
+	if err != nil {
+		location = time.UTC
+	}
+
+	now := time.Now()
+	fromTime, err := parseScheduleTime(from, location, "from")
+	if err != nil {
+		return response.ErrorResponse(err.Error())
+	}
+	if fromTime == nil {
+		fromTime = &now
+	}
+
+	toTime, err := parseScheduleTime(to, location, "to")
+	if err != nil {
+		return response.ErrorResponse(err.Error())
+	}
+	if toTime == nil {
+		end := fromTime.Add(defaultOccurrenceWindow)
+		toTime = &end
+	}
+	if !toTime.After(*fromTime) {
+		return response.ErrorResponse("to must be after from")
+	}
+
+	count := defaultOccurrenceLimit
+	if limit != "" {
+		if count, err = strconv.Atoi(limit); err != nil || count < 1 || count > maxOccurrenceLimit {
+			return response.ErrorResponse("limit must be between 1 and " + strconv.Itoa(maxOccurrenceLimit))
+		}
+	}
+
+	occurrences := scheduleOf(model, location, nil).Occurrences(*fromTime, *toTime, count)
+	if occurrences == nil {
+		occurrences = []schedule.Occurrence{}
+	}
+
+	return response.SuccessResponse(occurrences, "")
+}
+
+// Apply applies the user to the opportunity, they're waitlisted if it's full
+func (service *OpportunityService) Apply(ctx context.Context, opportunityID string, user *models.UserInfoModel) *response.Response {
+	opportunityUUID, err := uuid.Parse(opportunityID)
+	if err != nil {
+		return response.ErrorResponse("Unable to parse UUID")
+	}
+
+	application, err := service.applicationRepo.Apply(ctx, opportunityUUID, user.UUID, time.Now())
+	if errorResponse := response.ContextError(err); errorResponse != nil {
+		return errorResponse
+	}
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return response.ErrorResponse("Opportunity not found")
+	case errors.Is(err, repositories.ErrApplicationsClosed):
+		return response.ErrorResponse("Opportunity is closed to applications")
+	case err != nil:
+		log.Error(err)
+		return response.ErrorResponse("Internal error occurred")
+	}
+
+	if application.Status == models.ApplicationWaitlisted {
+		return response.SuccessResponse(application, "Opportunity is full, you've been added to the waitlist")
+	}
+	return response.SuccessResponse(application, "")
+}
+
+// CancelApplication withdraws the user, their place goes to the next on the waitlist
+func (service *OpportunityService) CancelApplication(ctx context.Context, opportunityID string, user *models.UserInfoModel) *response.Response {
+	opportunityUUID, err := uuid.Parse(opportunityID)
+	if err != nil {
+		return response.ErrorResponse("Unable to parse UUID")
+	}
+
+	err = service.applicationRepo.Cancel(ctx, opportunityUUID, user.UUID)
+	if errorResponse := response.ContextError(err); errorResponse != nil {
+		return errorResponse
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		return response.ErrorResponse("No application to cancel")
+	}
+	if err != nil {
+		log.Error(err)
+		return response.ErrorResponse("Internal error occurred")
+	}
+
+	return response.SuccessResponse(nil, "Application cancelled")
+}
+
+// GetApplications lists the applications, only for the author, the organisations editors and admins
+func (service *OpportunityService) GetApplications(ctx context.Context, opportunityID string, user *models.UserInfoModel) *response.Response {
+	model, errorResponse := service.existingOpportunity(ctx, opportunityID)
+	if errorResponse != nil {
+		return errorResponse
+	}
+
+	if errorResponse := service.authoriseManage(ctx, model, user); errorResponse != nil {
+		return errorResponse
+	}
+
+	applications, err := service.applicationRepo.GetApplications(ctx, model.UUID)
+	if errorResponse := response.ContextError(err); errorResponse != nil {
+		return errorResponse
+	}
+	if err != nil {
+		return response.ErrorResponse("Internal error occurred")
+	}
+
+	summary := models.ApplicationSummaryModel{Capacity: model.Capacity, Applications: applications}
+	if summary.Applications == nil {
+		summary.Applications = []models.ApplicationModel{}
+	}
+	for _, application := range applications {
+		if application.Status == models.ApplicationConfirmed {
+			summary.Confirmed++
+		} else {
+			summary.Waitlisted++
+		}
+	}
+
+	return response.SuccessResponse(summary, "")
+}
+
+// authoriseManage checks the user posted the opportunity, can edit its organisation or is an admin
+func (service *OpportunityService) authoriseManage(ctx context.Context, model *models.OpportunityModel, user *models.UserInfoModel) *response.Response {
+	if user.Role == models.Admin || user.UUID == model.PostedByUUID {
+		return nil
+	}
+
+	if model.OrganisationUUID != nil {
+		member, err := service.organisationRepo.GetMember(ctx, *model.OrganisationUUID, user.UUID)
+		if errorResponse := response.ContextError(err); errorResponse != nil {
+			return errorResponse
+		}
+		if err != nil {
+			log.Error(err)
+			return response.ErrorResponse("Internal error occurred")
+		}
+		if member != nil && member.Role.CanEdit() {
+			return nil
+		}
+	}
+
+	return response.ErrorResponse("Insufficient permissions for this opportunity")
+}
+
+func (service *OpportunityService) existingOpportunity(ctx context.Context, opportunityID string) (*models.OpportunityModel, *response.Response) {
+	opportunityUUID, err := uuid.Parse(opportunityID)
+	if err != nil {
+		return nil, response.ErrorResponse("Unable to parse UUID")
+	}
+
+	model, err := service.GetOpportunity(ctx, opportunityUUID)
+	if errorResponse := response.ContextError(err); errorResponse != nil {
+		return nil, errorResponse
+	}
+	if err != nil {
+		log.Error(err)
+		return nil, response.ErrorResponse("Internal error occurred")
+	}
+	if model == nil {
+		return nil, response.ErrorResponse("Opportunity not found")
+	}
+
+	return model, nil
+}
+
+// CloseExpired closes every opportunity past its deadline or last occurrence
+func (service *OpportunityService) CloseExpired(ctx context.Context) error {
+	closed, err := service.repo.CloseExpired(ctx, time.Now())
+	if err != nil {
+		return fmt.Errorf("closing expired opportunities: %w", err)
+	}
+	if closed > 0 {
+		log.Infof("Closed %d expired opportunities", closed)
+	}
+	return nil
+}
+
+// AutoCloseJob closes expired opportunities every interval, starting straight away
+func (service *OpportunityService) AutoCloseJob(interval time.Duration) concurrency.Job {
+	return concurrency.Job{
+		Name:        "close-expired-opportunities",
+		Schedule:    concurrency.Every(interval),
+		Run:         service.CloseExpired,
+		Immediately: true,
+		Jitter:      interval / 10,
+	}
+}
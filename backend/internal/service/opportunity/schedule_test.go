@@ -0,0 +1,95 @@
+package opportunity
+
+import (
+	"backend/internal/models"
+	"testing"
+	"time"
+)
+
+func TestApplyScheduleReadsLocalTimesInTimeZone(t *testing.T) {
+	request := &models.CreateOpportunityRequest{
+		StartsAt: "2025-07-05T10:00",
+		EndsAt:   "2025-07-05T12:00",
+		TimeZone: "Europe/London",
+	}
+	model := &models.OpportunityModel{}
+
+	if err := applySchedule(request, model); err != nil {
+		t.Fatal(err)
+	}
+
+	// 10:00 BST is 09:00 UTC
+	if model.StartsAt.UTC().Hour() != 9 || model.TimeZone != "Europe/London" {
+		t.Fatalf("unexpected start %v in %s", model.StartsAt, model.TimeZone)
+	}
+	if model.ExpiresAt == nil || !model.ExpiresAt.Equal(*model.EndsAt) {
+		t.Fatalf("expected a one off opportunity to expire when it ends, got %v", model.ExpiresAt)
+	}
+}
+
+func TestApplyScheduleExpiresAtEarlierOfDeadlineAndLastOccurrence(t *testing.T) {
+	request := &models.CreateOpportunityRequest{
+		StartsAt:   "2025-07-05T10:00:00Z",
+		EndsAt:     "2025-07-05T12:00:00Z",
+		Recurrence: "FREQ=WEEKLY;COUNT=4",
+		Deadline:   "2025-08-01T00:00:00Z",
+	}
+	model := &models.OpportunityModel{}
+
+	if err := applySchedule(request, model); err != nil {
+		t.Fatal(err)
+	}
+
+	// The 4th Saturday finishes before the deadline
+	if model.ExpiresAt.Format(time.RFC3339) != "2025-07-26T12:00:00Z" {
+		t.Fatalf("expected to expire after the last occurrence, got %v", model.ExpiresAt)
+	}
+
+	// Repeating forever leaves only the deadline
+	request.Recurrence = "FREQ=WEEKLY"
+	if err := applySchedule(request, model); err != nil {
+		t.Fatal(err)
+	}
+	if model.ExpiresAt.Format(time.RFC3339) != "2025-08-01T00:00:00Z" || model.Recurrence != "FREQ=WEEKLY" {
+		t.Fatalf("expected to expire at the deadline, got %v", model.ExpiresAt)
+	}
+
+	request.Deadline = ""
+	if err := applySchedule(request, model); err != nil {
+		t.Fatal(err)
+	}
+	if model.ExpiresAt != nil {
+		t.Fatalf("expected an unbounded opportunity to never expire, got %v", model.ExpiresAt)
+	}
+}
+
+func TestApplyScheduleRejectsInvalid(t *testing.T) {
+	capacity := int64(0)
+	cases := map[string]models.CreateOpportunityRequest{
+		"unknown timezone":   {StartsAt: "2025-07-05T10:00", TimeZone: "Nowhere/Special"},
+		"end before start":   {StartsAt: "2025-07-05T10:00", EndsAt: "2025-07-05T09:00"},
+		"end without start":  {EndsAt: "2025-07-05T09:00"},
+		"recurring no start": {Recurrence: "FREQ=DAILY"},
+		"bad recurrence":     {StartsAt: "2025-07-05T10:00", Recurrence: "FREQ=HOURLY"},
+		"bad time":           {StartsAt: "next saturday"},
+		"zero capacity":      {Capacity: &capacity},
+	}
+
+	for name, request := range cases {
+		if err := applySchedule(&request, &models.OpportunityModel{}); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestIsOpen(t *testing.T) {
+	now := time.Now()
+	past, future := now.Add(-time.Hour), now.Add(time.Hour)
+
+	if !(&models.OpportunityModel{}).IsOpen(now) || !(&models.OpportunityModel{ExpiresAt: &future}).IsOpen(now) {
+		t.Fatal("expected open")
+	}
+	if (&models.OpportunityModel{ExpiresAt: &past}).IsOpen(now) || (&models.OpportunityModel{ClosedAt: &past}).IsOpen(now) {
+		t.Fatal("expected closed")
+	}
+}
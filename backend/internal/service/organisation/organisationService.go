@@ -0,0 +1,644 @@
+package organisation
+
+import (
+	"backend/internal/db/repositories"
+	"backend/internal/models"
+	"backend/internal/service/media"
+	response "backend/internal/utils/http"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// inviteTTL is how long an invite token can be accepted for
+const inviteTTL = 7 * 24 * time.Hour
+
+// recentReviews is how many reviews are included on the public profile
+const recentReviews = 5
+
+// maxReviews caps how many reviews can be fetched at once
+const maxReviews = 50
+
+// Service provides methods for managing organisations and their members
+type Service struct {
+	repo            *repositories.OrganisationRepository
+	userRepo        *repositories.UserRepository
+	opportunityRepo *repositories.OpportunityRepository
+	media           *media.Service
+}
+
+// NewOrganisationService creates a new instance of the organisation Service
+func NewOrganisationService(repo *repositories.OrganisationRepository, userRepo *repositories.UserRepository,
+	opportunityRepo *repositories.OpportunityRepository, media *media.Service) *Service {
+	return &Service{repo: repo, userRepo: userRepo, opportunityRepo: opportunityRepo, media: media}
+}
+
+// CreateInviteStatus is returned to the owner creating the invite. Token is only ever shown here
+type CreateInviteStatus struct {
+	Invite *models.OrganisationInviteModel `json:"invite"`
+	Token  string                          `json:"token"`
+}
+
+// CreateOrganisation creates an organisation with the requesting recruiter as its owner
+func (service *Service) CreateOrganisation(ctx context.Context, user *models.UserInfoModel, request models.CreateOrganisationRequest) *response.Response {
+	if user.Role != models.Recruiter && user.Role != models.Admin {
+		return response.ErrorResponse("Only recruiters can create organisations")
+	}
+
+	if strings.TrimSpace(request.Name) == "" {
+		return response.ErrorResponse("Organisation name not provided")
+	}
+
+	model := &models.OrganisationModel{
+		UUID:               uuid.New(),
+		Name:               strings.TrimSpace(request.Name),
+		Description:        request.Description,
+		LogoURL:            request.LogoURL,
+		Website:            request.Website,
+		VerificationStatus: models.VerificationPending,
+		CreatedAt:          time.Now(),
+	}
+
+	err := service.repo.CreateOrganisation(ctx, model, user.UUID)
+	if errorResponse := response.ContextError(err); errorResponse != nil {
+		return errorResponse
+	}
+	if err != nil {
+		log.Error(err)
+		return response.ErrorResponse("Internal error occurred whilst creating organisation")
+	}
+
+	return response.SuccessResponse(model, "")
+}
+
+// GetOrganisation returns the public profile of an organisation
+func (service *Service) GetOrganisation(ctx context.Context, organisationID string) *response.Response {
+	organisationUUID, err := uuid.Parse(organisationID)
+	if err != nil {
+		return response.ErrorResponse("Unable to parse organisation uuid")
+	}
+
+	model, err := service.repo.GetOrganisation(ctx, organisationUUID)
+	if errorResponse := response.ContextError(err); errorResponse != nil {
+		return errorResponse
+	}
+	if err != nil {
+		log.Error(err)
+		return response.ErrorResponse("Internal error occurred")
+	}
+
+	if model == nil {
+		return response.ErrorResponse("Organisation not found")
+	}
+
+	return response.SuccessResponse(model, "")
+}
+
+// UpdateOrganisation updates the organisations profile. Requires editor or owner
+func (service *Service) UpdateOrganisation(ctx context.Context, user *models.UserInfoModel, organisationID string, request models.CreateOrganisationRequest) *response.Response {
+	organisationUUID, errResp := service.authorise(ctx, user, organisationID, models.OrganisationEditor)
+	if errResp != nil {
+		return errResp
+	}
+
+	if strings.TrimSpace(request.Name) == "" {
+		return response.ErrorResponse("Organisation name not provided")
+	}
+
+	model, err := service.repo.GetOrganisation(ctx, organisationUUID)
+	if errorResponse := response.ContextError(err); errorResponse != nil {
+		return errorResponse
+	}
+	if err != nil || model == nil {
+		return response.ErrorResponse("Organisation not found")
+	}
+
+	model.Name = strings.TrimSpace(request.Name)
+	model.Description = request.Description
+	model.LogoURL = request.LogoURL
+	model.Website = request.Website
+
+	err = service.repo.UpdateOrganisation(ctx, model)
+	if errorResponse := response.ContextError(err); errorResponse != nil {
+		return errorResponse
+	}
+	if err != nil {
+		log.Error(err)
+		return response.ErrorResponse("Internal error occurred")
+	}
+
+	return response.SuccessResponse(model, "")
+}
+
+// GetMyOrganisations returns every organisation the user is a member of
+func (service *Service) GetMyOrganisations(ctx context.Context, user *models.UserInfoModel) *response.Response {
+	memberships, err := service.repo.GetOrganisationsByMember(ctx, user.UUID)
+	if errorResponse := response.ContextError(err); errorResponse != nil {
+		return errorResponse
+	}
+	if err != nil {
+		log.Error(err)
+		return response.ErrorResponse("Internal error occurred")
+	}
+
+	return response.SuccessResponse(memberships, "")
+}
+
+// GetMembers lists the members of an organisation. Only visible to members
+func (service *Service) GetMembers(ctx context.Context, user *models.UserInfoModel, organisationID string) *response.Response {
+	organisationUUID, errResp := service.authorise(ctx, user, organisationID, models.OrganisationViewer)
+	if errResp != nil {
+		return errResp
+	}
+
+	members, err := service.repo.GetMembers(ctx, organisationUUID)
+	if errorResponse := response.ContextError(err); errorResponse != nil {
+		return errorResponse
+	}
+	if err != nil {
+		log.Error(err)
+		return response.ErrorResponse("Internal error occurred")
+	}
+
+	return response.SuccessResponse(members, "")
+}
+
+// UpdateMemberRole changes a members role. Requires owner
+func (service *Service) UpdateMemberRole(ctx context.Context, user *models.UserInfoModel, organisationID string, memberID string, roleName string) *response.Response {
+	organisationUUID, errResp := service.authorise(ctx, user, organisationID, models.OrganisationOwner)
+	if errResp != nil {
+		return errResp
+	}
+
+	memberUUID, err := uuid.Parse(memberID)
+	if err != nil {
+		return response.ErrorResponse("Unable to parse member uuid")
+	}
+
+	role, err := models.ParseOrganisationRole(roleName)
+	if err != nil {
+		return response.ErrorResponse("Invalid role")
+	}
+
+	member, err := service.repo.GetMember(ctx, organisationUUID, memberUUID)
+	if errorResponse := response.ContextError(err); errorResponse != nil {
+		return errorResponse
+	}
+	if err != nil {
+		log.Error(err)
+		return response.ErrorResponse("Internal error occurred")
+	}
+
+	if member == nil {
+		return response.ErrorResponse("User is not a member of this organisation")
+	}
+
+	if member.Role == models.OrganisationOwner && role != models.OrganisationOwner {
+		if errResp := service.checkNotLastOwner(ctx, organisationUUID); errResp != nil {
+			return errResp
+		}
+	}
+
+	err = service.repo.UpdateMemberRole(ctx, organisationUUID, memberUUID, role)
+	if errorResponse := response.ContextError(err); errorResponse != nil {
+		return errorResponse
+	}
+	if err != nil {
+		log.Error(err)
+		return response.ErrorResponse("Internal error occurred")
+	}
+
+	member.Role = role
+	return response.SuccessResponse(member, "")
+}
+
+// RemoveMember removes a member from the organisation. Owners can remove anyone, members can remove themselves
+func (service *Service) RemoveMember(ctx context.Context, user *models.UserInfoModel, organisationID string, memberID string) *response.Response {
+	memberUUID, err := uuid.Parse(memberID)
+	if err != nil {
+		return response.ErrorResponse("Unable to parse member uuid")
+	}
+
+	required := models.OrganisationOwner
+	if memberUUID == user.UUID {
+		required = models.OrganisationViewer
+	}
+
+	organisationUUID, errResp := service.authorise(ctx, user, organisationID, required)
+	if errResp != nil {
+		return errResp
+	}
+
+	member, err := service.repo.GetMember(ctx, organisationUUID, memberUUID)
+	if errorResponse := response.ContextError(err); errorResponse != nil {
+		return errorResponse
+	}
+	if err != nil {
+		log.Error(err)
+		return response.ErrorResponse("Internal error occurred")
+	}
+
+	if member == nil {
+		return response.ErrorResponse("User is not a member of this organisation")
+	}
+
+	if member.Role == models.OrganisationOwner {
+		if errResp := service.checkNotLastOwner(ctx, organisationUUID); errResp != nil {
+			return errResp
+		}
+	}
+
+	err = service.repo.RemoveMember(ctx, organisationUUID, memberUUID)
+	if errorResponse := response.ContextError(err); errorResponse != nil {
+		return errorResponse
+	}
+	if err != nil {
+		log.Error(err)
+		return response.ErrorResponse("Internal error occurred")
+	}
+
+	return response.SuccessResponse(nil, "Member removed")
+}
+
+// CreateInvite invites an email address to join the organisation. Requires owner
+func (service *Service) CreateInvite(ctx context.Context, user *models.UserInfoModel, organisationID string, request models.CreateInviteRequest) *response.Response {
+	organisationUUID, errResp := service.authorise(ctx, user, organisationID, models.OrganisationOwner)
+	if errResp != nil {
+		return errResp
+	}
+
+	email := strings.ToLower(strings.TrimSpace(request.Email))
+	if email == "" || !strings.Contains(email, "@") {
+		return response.ErrorResponse("Invalid email")
+	}
+
+	role, err := models.ParseOrganisationRole(request.Role)
+	if err != nil {
+		return response.ErrorResponse("Invalid role")
+	}
+
+	token, err := generateInviteToken()
+	if err != nil {
+		log.Error(err)
+		return response.ErrorResponse("Internal error occurred")
+	}
+
+	invite := &models.OrganisationInviteModel{
+		UUID:             uuid.New(),
+		OrganisationUUID: organisationUUID,
+		Email:            email,
+		Role:             role,
+		InvitedByUUID:    user.UUID,
+		TokenHash:        hashInviteToken(token),
+		ExpiresAt:        time.Now().Add(inviteTTL),
+	}
+
+	err = service.repo.CreateInvite(ctx, invite)
+	if errorResponse := response.ContextError(err); errorResponse != nil {
+		return errorResponse
+	}
+	if err != nil {
+		log.Error(err)
+		return response.ErrorResponse("Internal error occurred whilst creating invite")
+	}
+
+	return response.SuccessResponse(CreateInviteStatus{Invite: invite, Token: token}, "")
+}
+
+// AcceptInvite adds the user to the organisation if the invite is valid and was sent to their email
+func (service *Service) AcceptInvite(ctx context.Context, user *models.UserInfoModel, token string) *response.Response {
+	if token == "" {
+		return response.ErrorResponse("Invite token not provided")
+	}
+
+	invite, err := service.repo.GetInviteByTokenHash(ctx, hashInviteToken(token))
+	if errorResponse := response.ContextError(err); errorResponse != nil {
+		return errorResponse
+	}
+	if err != nil {
+		log.Error(err)
+		return response.ErrorResponse("Internal error occurred")
+	}
+
+	if invite == nil || invite.AcceptedAt != nil || time.Now().After(invite.ExpiresAt) {
+		return response.ErrorResponse("Invite is invalid or has expired")
+	}
+
+	users, err := service.userRepo.GetUserByID(ctx, user.UUID)
+	if errorResponse := response.ContextError(err); errorResponse != nil {
+		return errorResponse
+	}
+	if err != nil {
+		log.Error(err)
+		return response.ErrorResponse("Internal error occurred")
+	}
+
+	if users == nil || len(*users) == 0 {
+		return response.ErrorResponse("User doesn't exist")
+	}
+
+	if !strings.EqualFold((*users)[0].Email, invite.Email) {
+		return response.ErrorResponse("Invite was sent to a different email")
+	}
+
+	err = service.repo.AcceptInvite(ctx, invite, user.UUID)
+	if errorResponse := response.ContextError(err); errorResponse != nil {
+		return errorResponse
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		return response.ErrorResponse("Invite is invalid or has expired")
+	}
+	if err != nil {
+		log.Error(err)
+		return response.ErrorResponse("Internal error occurred")
+	}
+
+	return response.SuccessResponse(invite, "Joined organisation")
+}
+
+// UpdateVerificationStatus sets whether an organisation is verified. Admin only
+func (service *Service) UpdateVerificationStatus(ctx context.Context, organisationID string, status string) *response.Response {
+	organisationUUID, err := uuid.Parse(organisationID)
+	if err != nil {
+		return response.ErrorResponse("Unable to parse organisation uuid")
+	}
+
+	verificationStatus, err := models.ParseVerificationStatus(status)
+	if err != nil {
+		return response.ErrorResponse("Invalid verification status")
+	}
+
+	err = service.repo.UpdateVerificationStatus(ctx, organisationUUID, verificationStatus)
+	if errorResponse := response.ContextError(err); errorResponse != nil {
+		return errorResponse
+	}
+	if err != nil {
+		log.Error(err)
+		return response.ErrorResponse("Internal error occurred")
+	}
+
+	return response.SuccessResponse(nil, "")
+}
+
+// GetProfile returns the public profile of an organisation: description, impact, active opportunities and reviews
+func (service *Service) GetProfile(ctx context.Context, organisationID string) *response.Response {
+	organisationUUID, err := uuid.Parse(organisationID)
+	if err != nil {
+		return response.ErrorResponse("Unable to parse organisation uuid")
+	}
+
+	organisation, err := service.repo.GetOrganisation(ctx, organisationUUID)
+	if errorResponse := response.ContextError(err); errorResponse != nil {
+		return errorResponse
+	}
+	if err != nil {
+		log.Error(err)
+		return response.ErrorResponse("Internal error occurred")
+	}
+
+	if organisation == nil {
+		return response.ErrorResponse("Organisation not found")
+	}
+
+	impact, err := service.repo.GetImpact(ctx, organisationUUID)
+	if errorResponse := response.ContextError(err); errorResponse != nil {
+		return errorResponse
+	}
+	if err != nil {
+		return response.ErrorResponse("Internal error occurred")
+	}
+
+	reviews, err := service.repo.GetReviews(ctx, organisationUUID, recentReviews)
+	if errorResponse := response.ContextError(err); errorResponse != nil {
+		return errorResponse
+	}
+	if err != nil {
+		return response.ErrorResponse("Internal error occurred")
+	}
+
+	opportunities, err := service.opportunityRepo.GetOpportunityByOrganisation(ctx, &organisationUUID)
+	if errorResponse := response.ContextError(err); errorResponse != nil {
+		return errorResponse
+	}
+	if err != nil {
+		log.Error(err)
+		return response.ErrorResponse("Internal error occurred")
+	}
+
+	active := []models.OpportunityModel{}
+	if opportunities != nil {
+		for _, opportunity := range *opportunities {
+			if opportunity.Approved {
+				active = append(active, opportunity)
+			}
+		}
+	}
+	service.media.SignOpportunities(ctx, active)
+
+	return response.SuccessResponse(models.OrganisationProfileModel{
+		Organisation:        *organisation,
+		Impact:              *impact,
+		ActiveOpportunities: active,
+		Reviews:             *reviews,
+	}, "")
+}
+
+// Follow follows an organisation. With notify the user gets a notification when it posts a new opportunity
+func (service *Service) Follow(ctx context.Context, user *models.UserInfoModel, organisationID string, notify bool) *response.Response {
+	organisationUUID, errResp := service.existingOrganisation(ctx, organisationID)
+	if errResp != nil {
+		return errResp
+	}
+
+	err := service.repo.Follow(ctx, organisationUUID, user.UUID, notify)
+	if errorResponse := response.ContextError(err); errorResponse != nil {
+		return errorResponse
+	}
+	if err != nil {
+		log.Error(err)
+		return response.ErrorResponse("Internal error occurred")
+	}
+
+	return response.SuccessResponse(nil, "Followed organisation")
+}
+
+func (service *Service) Unfollow(ctx context.Context, user *models.UserInfoModel, organisationID string) *response.Response {
+	organisationUUID, err := uuid.Parse(organisationID)
+	if err != nil {
+		return response.ErrorResponse("Unable to parse organisation uuid")
+	}
+
+	err = service.repo.Unfollow(ctx, organisationUUID, user.UUID)
+	if errorResponse := response.ContextError(err); errorResponse != nil {
+		return errorResponse
+	}
+	if err != nil {
+		log.Error(err)
+		return response.ErrorResponse("Internal error occurred")
+	}
+
+	return response.SuccessResponse(nil, "Unfollowed organisation")
+}
+
+// GetFollowing returns the organisations the user follows
+func (service *Service) GetFollowing(ctx context.Context, user *models.UserInfoModel) *response.Response {
+	organisations, err := service.repo.GetFollowedOrganisations(ctx, user.UUID)
+	if errorResponse := response.ContextError(err); errorResponse != nil {
+		return errorResponse
+	}
+	if err != nil {
+		return response.ErrorResponse("Internal error occurred")
+	}
+
+	return response.SuccessResponse(organisations, "")
+}
+
+// AddReview lets a student rate an organisation from 1 to 5. Reviewing again replaces their review
+func (service *Service) AddReview(ctx context.Context, user *models.UserInfoModel, organisationID string, request models.CreateReviewRequest) *response.Response {
+	if user.Role != models.Student {
+		return response.ErrorResponse("Only students can review organisations")
+	}
+
+	if request.Rating < 1 || request.Rating > 5 {
+		return response.ErrorResponse("Rating must be between 1 and 5")
+	}
+
+	organisationUUID, errResp := service.existingOrganisation(ctx, organisationID)
+	if errResp != nil {
+		return errResp
+	}
+
+	review := &models.OrganisationReviewModel{
+		OrganisationUUID: organisationUUID,
+		UserUUID:         user.UUID,
+		Username:         user.Username,
+		Rating:           request.Rating,
+		Comment:          strings.TrimSpace(request.Comment),
+		CreatedAt:        time.Now(),
+	}
+
+	err := service.repo.AddReview(ctx, review)
+	if errorResponse := response.ContextError(err); errorResponse != nil {
+		return errorResponse
+	}
+	if err != nil {
+		log.Error(err)
+		return response.ErrorResponse("Internal error occurred")
+	}
+
+	return response.SuccessResponse(review, "")
+}
+
+// GetReviews returns the review summary along with the latest limit reviews
+func (service *Service) GetReviews(ctx context.Context, organisationID string, limit string) *response.Response {
+	organisationUUID, err := uuid.Parse(organisationID)
+	if err != nil {
+		return response.ErrorResponse("Unable to parse organisation uuid")
+	}
+
+	limitInt := int64(recentReviews)
+	if limit != "" {
+		limitInt, err = strconv.ParseInt(limit, 10, 64)
+		if err != nil || limitInt <= 0 {
+			return response.ErrorResponse("limit is not a positive integer")
+		}
+	}
+
+	reviews, err := service.repo.GetReviews(ctx, organisationUUID, min(limitInt, maxReviews))
+	if errorResponse := response.ContextError(err); errorResponse != nil {
+		return errorResponse
+	}
+	if err != nil {
+		return response.ErrorResponse("Internal error occurred")
+	}
+
+	return response.SuccessResponse(reviews, "")
+}
+
+func (service *Service) existingOrganisation(ctx context.Context, organisationID string) (uuid.UUID, *response.Response) {
+	organisationUUID, err := uuid.Parse(organisationID)
+	if err != nil {
+		return uuid.Nil, response.ErrorResponse("Unable to parse organisation uuid")
+	}
+
+	organisation, err := service.repo.GetOrganisation(ctx, organisationUUID)
+	if errorResponse := response.ContextError(err); errorResponse != nil {
+		return uuid.Nil, errorResponse
+	}
+	if err != nil {
+		return uuid.Nil, response.ErrorResponse("Internal error occurred")
+	}
+
+	if organisation == nil {
+		return uuid.Nil, response.ErrorResponse("Organisation not found")
+	}
+
+	return organisationUUID, nil
+}
+
+// authorise checks the user has at least the required role in the organisation. Admins are always allowed
+func (service *Service) authorise(ctx context.Context, user *models.UserInfoModel, organisationID string, required models.OrganisationRole) (uuid.UUID, *response.Response) {
+	organisationUUID, err := uuid.Parse(organisationID)
+	if err != nil {
+		return uuid.Nil, response.ErrorResponse("Unable to parse organisation uuid")
+	}
+
+	if user.Role == models.Admin {
+		return organisationUUID, nil
+	}
+
+	member, err := service.repo.GetMember(ctx, organisationUUID, user.UUID)
+	if errorResponse := response.ContextError(err); errorResponse != nil {
+		return uuid.Nil, errorResponse
+	}
+	if err != nil {
+		log.Error(err)
+		return uuid.Nil, response.ErrorResponse("Internal error occurred")
+	}
+
+	if member == nil || member.Role < required {
+		return uuid.Nil, response.ErrorResponse("Insufficient permissions for this organisation")
+	}
+
+	return organisationUUID, nil
+}
+
+func (service *Service) checkNotLastOwner(ctx context.Context, organisationUUID uuid.UUID) *response.Response {
+	owners, err := service.repo.CountOwners(ctx, organisationUUID)
+	if errorResponse := response.ContextError(err); errorResponse != nil {
+		return errorResponse
+	}
+	if err != nil {
+		log.Error(err)
+		return response.ErrorResponse("Internal error occurred")
+	}
+
+	if owners <= 1 {
+		return response.ErrorResponse("Organisation must have at least one owner")
+	}
+
+	return nil
+}
+
+func generateInviteToken() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// hashInviteToken only the hash is stored so a leaked table can't be used to join organisations
+func hashInviteToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
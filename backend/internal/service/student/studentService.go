@@ -2,32 +2,74 @@ package student
 
 import (
 	"backend/internal/db/repositories"
+	"backend/internal/events"
 	"backend/internal/models"
+	"backend/internal/service/media"
 	response "backend/internal/utils/http"
+	"context"
+	"errors"
 	"github.com/google/uuid"
 	log "github.com/sirupsen/logrus"
 )
 
 type Service struct {
-	repo *repositories.StudentRepository
+	repo  *repositories.StudentRepository
+	media *media.Service
 }
 
 // NewStudentService creates a new instance of UserService.
-func NewStudentService(repo *repositories.StudentRepository) *Service {
-	return &Service{repo: repo}
+func NewStudentService(repo *repositories.StudentRepository, media *media.Service) *Service {
+	return &Service{repo: repo, media: media}
 }
 
-func (service *Service) UpdateStudentInfo(studentInfo models.StudentInfoModel) *response.Response {
-	err := service.repo.UpdateStudentInfo(studentInfo)
+// UpdateStudentInfo updates the users own info. ProfilePic must be an image they uploaded
+func (service *Service) UpdateStudentInfo(ctx context.Context, user *models.UserInfoModel, studentInfo models.StudentInfoModel) *response.Response {
+	studentInfo.StudentID = user.UUID
+
+	current, err := service.repo.GetUserInfo(ctx, user.UUID)
+	if errorResponse := response.ContextError(err); errorResponse != nil {
+		return errorResponse
+	}
 	if err != nil {
 		log.Error(err)
 		return response.ErrorResponse("Internal error occurred")
 	}
 
+	var currentPic string
+	if current != nil {
+		currentPic = current.ProfilePic
+	}
+
+	err = service.media.ResolveProfilePic(ctx, user.UUID, studentInfo.ProfilePic, currentPic)
+	if errorResponse := response.ContextError(err); errorResponse != nil {
+		return errorResponse
+	}
+	if errors.Is(err, media.ErrInvalidMedia) {
+		return response.ErrorResponse(err.Error())
+	}
+	if err != nil {
+		log.Error(err)
+		return response.ErrorResponse("Internal error occurred")
+	}
+
+	err = service.repo.UpdateStudentInfo(ctx, studentInfo)
+	if errorResponse := response.ContextError(err); errorResponse != nil {
+		return errorResponse
+	}
+	if repositories.IsTagError(err) {
+		return response.ErrorResponse(err.Error())
+	}
+	if err != nil {
+		log.Error(err)
+		return response.ErrorResponse("Internal error occurred")
+	}
+	// Liked and disliked tags count towards usage
+	events.Default.Publish(ctx, events.TagsChanged{})
+
 	return response.SuccessResponse(nil, "")
 }
 
-func (service *Service) GetStudentInfo(userID string) *response.Response {
+func (service *Service) GetStudentInfo(ctx context.Context, userID string) *response.Response {
 
 	if userID == "" {
 		return response.ErrorResponse("User UUID must be provided")
@@ -39,11 +81,14 @@ func (service *Service) GetStudentInfo(userID string) *response.Response {
 		return response.ErrorResponse("Unable to parse UUID")
 	}
 
-	return service.GetStudentInfoByUUID(userUUID)
+	return service.GetStudentInfoByUUID(ctx, userUUID)
 }
 
-func (service *Service) GetStudentInfoByUUID(userUUID uuid.UUID) *response.Response {
-	info, err := service.repo.GetUserInfo(userUUID)
+func (service *Service) GetStudentInfoByUUID(ctx context.Context, userUUID uuid.UUID) *response.Response {
+	info, err := service.repo.GetUserInfo(ctx, userUUID)
+	if errorResponse := response.ContextError(err); errorResponse != nil {
+		return errorResponse
+	}
 	if err != nil {
 		log.Error(err)
 		return response.ErrorResponse("Internal error occurred")
@@ -52,6 +97,7 @@ func (service *Service) GetStudentInfoByUUID(userUUID uuid.UUID) *response.Respo
 	if info == nil {
 		return response.ErrorResponse("User doesn't exist")
 	}
+	service.media.SignProfilePic(info)
 
 	return response.SuccessResponse(info, "")
 }
@@ -0,0 +1,131 @@
+package tag
+
+import (
+	"backend/internal/events"
+	"backend/internal/models"
+	"backend/internal/taxonomy"
+	"backend/internal/utils/concurrency"
+	response "backend/internal/utils/http"
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// Autocomplete and suggestions are served from the Service's index of the approved tags. It's rebuilt by its
+// IndexJob on every instance, soon after a TagsChanged event and every refreshInterval to pick up changes made on
+// other instances. Until the first build finishes nothing is completed or suggested
+
+// rebuildDelay is how often the job checks for changes, batching a burst of them, e.g. a recruiter editing several
+// opportunities, into one rebuild
+const rebuildDelay = 2 * time.Second
+
+// refreshInterval is how long the index is kept without a TagsChanged event here
+const refreshInterval = 5 * time.Minute
+
+var errLimit = errors.New("limit is not a positive integer")
+
+const (
+	defaultCompletions = 10
+	defaultSuggestions = 5
+	maxResults         = 50
+)
+
+// Subscribe marks the index as out of date when tags change. The returned function unsubscribes
+func (service *Service) Subscribe(bus *events.Bus) func() {
+	return events.Subscribe(bus, "tag-index", func(_ context.Context, _ events.TagsChanged) error {
+		service.stale.Store(true)
+		return nil
+	})
+}
+
+// IndexJob builds the index straight away then keeps it up to date. It runs on every instance as each has its own
+func (service *Service) IndexJob() concurrency.Job {
+	return concurrency.Job{
+		Name:        "rebuild-tag-index",
+		Schedule:    concurrency.Every(rebuildDelay),
+		Run:         service.refreshIndex,
+		Immediately: true,
+		PerInstance: true,
+	}
+}
+
+// refreshIndex rebuilds the index if it's out of date or older than refreshInterval
+func (service *Service) refreshIndex(ctx context.Context) error {
+	if service.index.Load() != nil && !service.stale.Load() && time.Since(service.indexedAt) < refreshInterval {
+		return nil
+	}
+	// Changes made during the rebuild mark it out of date again
+	service.stale.Store(false)
+	if err := service.rebuildIndex(ctx); err != nil {
+		service.stale.Store(true)
+		return err
+	}
+	return nil
+}
+
+// rebuildIndex replaces the index with one of the approved tags. On failure the old index is kept
+func (service *Service) rebuildIndex(ctx context.Context) error {
+	approved := true
+	tags, err := service.repo.GetTags(ctx, &approved)
+	if err != nil {
+		return fmt.Errorf("rebuilding the tag index: %w", err)
+	}
+
+	texts, err := service.repo.GetTaggedOpportunityTexts(ctx)
+	if err != nil {
+		return fmt.Errorf("rebuilding the tag index: %w", err)
+	}
+
+	indexed := make([]taxonomy.IndexedTag, len(tags))
+	for i, tag := range tags {
+		indexed[i] = taxonomy.IndexedTag{ID: tag.ID, Name: tag.TagName, Synonyms: tag.Synonyms, Usage: tag.Usage}
+	}
+	service.index.Store(taxonomy.BuildIndex(indexed, texts))
+	service.indexedAt = time.Now()
+	return nil
+}
+
+// Autocomplete returns approved tags starting with the prefix, the most used first
+func (service *Service) Autocomplete(prefix string, limit string) *response.Response {
+	limitInt, err := parseLimit(limit, defaultCompletions)
+	if err != nil {
+		return response.ErrorResponse(err.Error())
+	}
+
+	current := service.index.Load()
+	if current == nil {
+		return response.SuccessResponse(make([]taxonomy.Completion, 0), "")
+	}
+	return response.SuccessResponse(current.Complete(prefix, limitInt), "")
+}
+
+// SuggestTags proposes approved tags for a draft opportunity from its title and description
+func (service *Service) SuggestTags(request models.SuggestTagsRequest) *response.Response {
+	if request.Title == "" && request.Description == "" {
+		return response.ErrorResponse("Title or description must be provided")
+	}
+
+	limit := defaultSuggestions
+	if request.Limit > 0 {
+		limit = min(request.Limit, maxResults)
+	}
+
+	current := service.index.Load()
+	if current == nil {
+		return response.SuccessResponse(make([]taxonomy.Suggestion, 0), "")
+	}
+	return response.SuccessResponse(current.Suggest(request.Title+"\n"+request.Description, request.Tags, limit), "")
+}
+
+func parseLimit(limit string, fallback int) (int, error) {
+	if limit == "" {
+		return fallback, nil
+	}
+	parsed, err := strconv.Atoi(limit)
+	if err != nil || parsed <= 0 {
+		return 0, errLimit
+	}
+	return min(parsed, maxResults), nil
+}
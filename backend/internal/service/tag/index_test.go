@@ -0,0 +1,29 @@
+package tag
+
+import (
+	"backend/internal/events"
+	"backend/internal/taxonomy"
+	"context"
+	"testing"
+	"time"
+)
+
+func TestTagsChangedMarksIndexStale(t *testing.T) {
+	bus := events.NewBus()
+	t.Cleanup(func() { bus.Shutdown(context.Background()) })
+
+	// No repository, so refreshing must not rebuild
+	service := &Service{}
+	service.index.Store(taxonomy.BuildIndex(nil, nil))
+	service.indexedAt = time.Now()
+	if err := service.refreshIndex(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	unsubscribe := service.Subscribe(bus)
+	defer unsubscribe()
+	bus.Publish(context.Background(), events.TagsChanged{})
+	if !service.stale.Load() {
+		t.Fatal("expected the index to be out of date")
+	}
+}
@@ -0,0 +1,356 @@
+package tag
+
+import (
+	"backend/internal/db/repositories"
+	"backend/internal/events"
+	"backend/internal/models"
+	"backend/internal/taxonomy"
+	response "backend/internal/utils/http"
+	"context"
+	"errors"
+	log "github.com/sirupsen/logrus"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+	"unicode/utf8"
+)
+
+// Service manages the tag taxonomy, everything but listing is for admins
+type Service struct {
+	repo *repositories.TaxonomyRepository
+
+	// index serves autocomplete and suggestions, see IndexJob
+	index     atomic.Pointer[taxonomy.Index]
+	stale     atomic.Bool
+	indexedAt time.Time
+}
+
+// NewTagService creates a new instance of the tag Service
+func NewTagService(repo *repositories.TaxonomyRepository) *Service {
+	return &Service{repo: repo}
+}
+
+// GetTags returns the approved tags, e.g. for recruiters to pick from. With a category only tags in it or its
+// subcategories are returned
+func (service *Service) GetTags(ctx context.Context, category string) *response.Response {
+	approved := true
+	return service.listTags(ctx, &approved, category)
+}
+
+// GetPendingTags returns tags waiting to be approved, with how much they're used
+func (service *Service) GetPendingTags(ctx context.Context) *response.Response {
+	approved := false
+	return service.listTags(ctx, &approved, "")
+}
+
+func (service *Service) listTags(ctx context.Context, approved *bool, category string) *response.Response {
+	tags, err := service.repo.GetTags(ctx, approved)
+	if errorResponse := response.ContextError(err); errorResponse != nil {
+		return errorResponse
+	}
+	if err != nil {
+		return response.ErrorResponse("Internal error occurred")
+	}
+
+	if category == "" {
+		return response.SuccessResponse(tags, "")
+	}
+
+	categoryID, err := strconv.ParseInt(category, 10, 64)
+	if err != nil {
+		return response.ErrorResponse("category is not an id")
+	}
+
+	categories, err := service.repo.GetCategories(ctx)
+	if errorResponse := response.ContextError(err); errorResponse != nil {
+		return errorResponse
+	}
+	if err != nil {
+		return response.ErrorResponse("Internal error occurred")
+	}
+
+	within := descendants(categories, categoryID)
+	filtered := make([]models.TaxonomyTagModel, 0)
+	for _, tag := range tags {
+		if tag.CategoryID != nil && within[*tag.CategoryID] {
+			filtered = append(filtered, tag)
+		}
+	}
+	return response.SuccessResponse(filtered, "")
+}
+
+// GetCategories returns the categories as a tree
+func (service *Service) GetCategories(ctx context.Context) *response.Response {
+	categories, err := service.repo.GetCategories(ctx)
+	if errorResponse := response.ContextError(err); errorResponse != nil {
+		return errorResponse
+	}
+	if err != nil {
+		return response.ErrorResponse("Internal error occurred")
+	}
+	return response.SuccessResponse(buildTree(categories), "")
+}
+
+// CreateTag adds an approved tag
+func (service *Service) CreateTag(ctx context.Context, request models.CreateTagRequest) *response.Response {
+	name, err := taxonomy.Validate(request.TagName)
+	if err != nil {
+		return response.ErrorResponse(err.Error())
+	}
+
+	tagID, err := service.repo.CreateTag(ctx, name, request.CategoryID)
+	if err != nil {
+		return taxonomyError(err)
+	}
+	events.Default.Publish(ctx, events.TagsChanged{})
+	return service.getTag(ctx, tagID)
+}
+
+// UpdateTag renames, categorises or approves a tag. The category is replaced, so leaving it out uncategorises the
+// tag, whereas a missing name or approval is left as it is
+func (service *Service) UpdateTag(ctx context.Context, tagID string, request models.UpdateTagRequest) *response.Response {
+	id, err := strconv.ParseInt(tagID, 10, 64)
+	if err != nil {
+		return response.ErrorResponse("Unable to parse tag id")
+	}
+
+	current, err := service.repo.GetTag(ctx, id)
+	if err != nil {
+		return taxonomyError(err)
+	}
+
+	name := current.TagName
+	if request.TagName != "" {
+		if name, err = taxonomy.Validate(request.TagName); err != nil {
+			return response.ErrorResponse(err.Error())
+		}
+	}
+
+	approved := current.Approved
+	if request.Approved != nil {
+		approved = *request.Approved
+	}
+
+	if err := service.repo.UpdateTag(ctx, id, name, request.CategoryID, approved); err != nil {
+		return taxonomyError(err)
+	}
+	events.Default.Publish(ctx, events.TagsChanged{})
+	return service.getTag(ctx, id)
+}
+
+// DeleteTag removes a tag from every opportunity and student
+func (service *Service) DeleteTag(ctx context.Context, tagID string) *response.Response {
+	id, err := strconv.ParseInt(tagID, 10, 64)
+	if err != nil {
+		return response.ErrorResponse("Unable to parse tag id")
+	}
+
+	if err := service.repo.DeleteTag(ctx, id); err != nil {
+		return taxonomyError(err)
+	}
+	events.Default.Publish(ctx, events.TagsChanged{})
+	return response.SuccessResponse(nil, "")
+}
+
+// MergeTags merges the tag into another, e.g. "recyle" into "recycling"
+func (service *Service) MergeTags(ctx context.Context, tagID string, request models.MergeTagsRequest) *response.Response {
+	id, err := strconv.ParseInt(tagID, 10, 64)
+	if err != nil {
+		return response.ErrorResponse("Unable to parse tag id")
+	}
+	if id == request.Into {
+		return response.ErrorResponse("A tag can't be merged into itself")
+	}
+
+	if err := service.repo.MergeTags(ctx, id, request.Into); err != nil {
+		return taxonomyError(err)
+	}
+	events.Default.Publish(ctx, events.TagsChanged{})
+	return service.getTag(ctx, request.Into)
+}
+
+// AddSynonym makes another name resolve to the tag
+func (service *Service) AddSynonym(ctx context.Context, tagID string, request models.TagSynonymRequest) *response.Response {
+	id, err := strconv.ParseInt(tagID, 10, 64)
+	if err != nil {
+		return response.ErrorResponse("Unable to parse tag id")
+	}
+
+	synonym, err := taxonomy.Validate(request.Synonym)
+	if err != nil {
+		return response.ErrorResponse(err.Error())
+	}
+
+	if err := service.repo.AddSynonym(ctx, id, synonym); err != nil {
+		return taxonomyError(err)
+	}
+	events.Default.Publish(ctx, events.TagsChanged{})
+	return service.getTag(ctx, id)
+}
+
+func (service *Service) RemoveSynonym(ctx context.Context, synonym string) *response.Response {
+	if err := service.repo.RemoveSynonym(ctx, taxonomy.Normalise(synonym)); err != nil {
+		return taxonomyError(err)
+	}
+	events.Default.Publish(ctx, events.TagsChanged{})
+	return response.SuccessResponse(nil, "")
+}
+
+func (service *Service) CreateCategory(ctx context.Context, request models.TagCategoryRequest) *response.Response {
+	name, err := validateCategoryName(request.Name)
+	if err != nil {
+		return response.ErrorResponse(err.Error())
+	}
+
+	categoryID, err := service.repo.CreateCategory(ctx, name, request.ParentID)
+	if err != nil {
+		return taxonomyError(err)
+	}
+	return response.SuccessResponse(models.TagCategoryModel{ID: categoryID, Name: name, ParentID: request.ParentID}, "")
+}
+
+// UpdateCategory renames the category and moves it under ParentID, or to the top level without one
+func (service *Service) UpdateCategory(ctx context.Context, categoryID string, request models.TagCategoryRequest) *response.Response {
+	id, err := strconv.ParseInt(categoryID, 10, 64)
+	if err != nil {
+		return response.ErrorResponse("Unable to parse category id")
+	}
+
+	name, err := validateCategoryName(request.Name)
+	if err != nil {
+		return response.ErrorResponse(err.Error())
+	}
+
+	categories, err := service.repo.GetCategories(ctx)
+	if errorResponse := response.ContextError(err); errorResponse != nil {
+		return errorResponse
+	}
+	if err != nil {
+		return response.ErrorResponse("Internal error occurred")
+	}
+
+	nodes := make([]taxonomy.Category, len(categories))
+	for i, category := range categories {
+		nodes[i] = taxonomy.Category{ID: category.ID, ParentID: category.ParentID}
+	}
+	if taxonomy.CreatesCycle(nodes, id, request.ParentID) {
+		return response.ErrorResponse("A category can't be moved under itself or one of its subcategories")
+	}
+
+	if err := service.repo.UpdateCategory(ctx, id, name, request.ParentID); err != nil {
+		return taxonomyError(err)
+	}
+	return response.SuccessResponse(models.TagCategoryModel{ID: id, Name: name, ParentID: request.ParentID}, "")
+}
+
+// DeleteCategory deletes a category, its subcategories and tags move up to its parent
+func (service *Service) DeleteCategory(ctx context.Context, categoryID string) *response.Response {
+	id, err := strconv.ParseInt(categoryID, 10, 64)
+	if err != nil {
+		return response.ErrorResponse("Unable to parse category id")
+	}
+
+	if err := service.repo.DeleteCategory(ctx, id); err != nil {
+		return taxonomyError(err)
+	}
+	return response.SuccessResponse(nil, "")
+}
+
+func (service *Service) GetSettings(ctx context.Context) *response.Response {
+	settings, err := service.repo.GetSettings(ctx)
+	if errorResponse := response.ContextError(err); errorResponse != nil {
+		return errorResponse
+	}
+	if err != nil {
+		return response.ErrorResponse("Internal error occurred")
+	}
+	return response.SuccessResponse(settings, "")
+}
+
+// UpdateSettings locks or unlocks the vocabulary
+func (service *Service) UpdateSettings(ctx context.Context, settings models.TaxonomySettingsModel) *response.Response {
+	if err := service.repo.UpdateSettings(ctx, &settings); err != nil {
+		if errorResponse := response.ContextError(err); errorResponse != nil {
+			return errorResponse
+		}
+		return response.ErrorResponse("Internal error occurred")
+	}
+	return response.SuccessResponse(settings, "")
+}
+
+func (service *Service) getTag(ctx context.Context, tagID int64) *response.Response {
+	tag, err := service.repo.GetTag(ctx, tagID)
+	if err != nil {
+		return taxonomyError(err)
+	}
+	return response.SuccessResponse(tag, "")
+}
+
+// taxonomyError returns the message of errors caused by the request, and a generic one otherwise
+func taxonomyError(err error) *response.Response {
+	if errorResponse := response.ContextError(err); errorResponse != nil {
+		return errorResponse
+	}
+	if errors.Is(err, repositories.ErrTagExists) || errors.Is(err, repositories.ErrTagNotFound) ||
+		errors.Is(err, repositories.ErrCategoryExists) || errors.Is(err, repositories.ErrCategoryNotFound) {
+		return response.ErrorResponse(err.Error())
+	}
+	log.Error(err)
+	return response.ErrorResponse("Internal error occurred")
+}
+
+func validateCategoryName(name string) (string, error) {
+	name = strings.Join(strings.Fields(name), " ")
+	if name == "" {
+		return "", errors.New("category name is empty")
+	}
+	if utf8.RuneCountInString(name) > taxonomy.MaxNameLength {
+		return "", errors.New("category name is too long")
+	}
+	return name, nil
+}
+
+// buildTree nests the categories under their parents. Categories whose parent is missing are put at the top level
+func buildTree(categories []models.TagCategoryModel) []*models.TagCategoryModel {
+	nodes := make(map[int64]*models.TagCategoryModel, len(categories))
+	for i := range categories {
+		nodes[categories[i].ID] = &categories[i]
+	}
+
+	roots := make([]*models.TagCategoryModel, 0)
+	for i := range categories {
+		category := &categories[i]
+		if category.ParentID != nil {
+			if parent, ok := nodes[*category.ParentID]; ok && parent != category {
+				parent.Children = append(parent.Children, category)
+				continue
+			}
+		}
+		roots = append(roots, category)
+	}
+	return roots
+}
+
+// descendants returns the category and every category under it
+func descendants(categories []models.TagCategoryModel, categoryID int64) map[int64]bool {
+	children := map[int64][]int64{}
+	for _, category := range categories {
+		if category.ParentID != nil {
+			children[*category.ParentID] = append(children[*category.ParentID], category.ID)
+		}
+	}
+
+	within := map[int64]bool{}
+	queue := []int64{categoryID}
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+		if within[id] {
+			continue
+		}
+		within[id] = true
+		queue = append(queue, children[id]...)
+	}
+	return within
+}
@@ -0,0 +1,35 @@
+package tag
+
+import (
+	"backend/internal/models"
+	"testing"
+)
+
+func categories() []models.TagCategoryModel {
+	environment, waste, recycling := int64(1), int64(2), int64(3)
+	return []models.TagCategoryModel{
+		{ID: environment, Name: "Environment"},
+		{ID: waste, Name: "Waste", ParentID: &environment},
+		{ID: recycling, Name: "Recycling", ParentID: &waste},
+		{ID: 4, Name: "Community"},
+	}
+}
+
+func TestBuildTree(t *testing.T) {
+	roots := buildTree(categories())
+	if len(roots) != 2 || roots[0].Name != "Environment" || roots[1].Name != "Community" {
+		t.Fatalf("unexpected roots %+v", roots)
+	}
+
+	waste := roots[0].Children
+	if len(waste) != 1 || waste[0].Name != "Waste" || len(waste[0].Children) != 1 || waste[0].Children[0].Name != "Recycling" {
+		t.Fatalf("unexpected children %+v", waste)
+	}
+}
+
+func TestDescendants(t *testing.T) {
+	within := descendants(categories(), 2)
+	if !within[2] || !within[3] || within[1] || within[4] {
+		t.Fatalf("unexpected descendants %v", within)
+	}
+}
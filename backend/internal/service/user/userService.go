@@ -4,6 +4,7 @@ import (
 	"backend/internal/db/adapters/mysql"
 	"backend/internal/db/repositories"
 	"backend/internal/models"
+	"context"
 	"github.com/google/uuid"
 	log "github.com/sirupsen/logrus"
 )
@@ -35,13 +36,13 @@ func GetStudentModel(rawModel *models.RawUserRow) *models.StudentModel {
 		return nil
 	}
 
-	if !rawModel.Points.Valid {
+	if rawModel.Points == nil {
 		return nil
 	}
 
 	return &models.StudentModel{
 		UserInfoModel: GetUserInfo(rawModel),
-		Points:        rawModel.Points.Int64,
+		Points:        *rawModel.Points,
 	}
 }
 
@@ -51,27 +52,36 @@ func GetRecruiterModel(rawModel *models.RawUserRow) *models.RecruiterModel {
 		return nil
 	}
 
-	if !rawModel.OrganisationName.Valid || !rawModel.ApplicationStatus.Valid {
+	if rawModel.OrganisationName == nil || rawModel.ApplicationStatus == nil {
 		return nil
 	}
 
 	return &models.RecruiterModel{
 		UserInfoModel:     GetUserInfo(rawModel),
-		OrganisationName:  rawModel.OrganisationName.String,
-		ApplicationStatus: rawModel.ApplicationStatus.Bool,
+		OrganisationName:  *rawModel.OrganisationName,
+		ApplicationStatus: *rawModel.ApplicationStatus,
 	}
 }
 
-func (service *Service) GetRawUserByName(username string) (*models.RawUserRow, error) {
+// valueOf is the value of a column that may be NULL, the zero value if it is
+func valueOf[T any](value *T) T {
+	if value == nil {
+		var zero T
+		return zero
+	}
+	return *value
+}
+
+func (service *Service) GetRawUserByName(ctx context.Context, username string) (*models.RawUserRow, error) {
 	repository := service.repo
-	return repository.GetUserByName(username, mysql.QueryOptions{})
+	return repository.GetUserByName(ctx, username, mysql.QueryOptions{})
 }
 
 // GetUserByName retrieves a user by their username.
-func (service *Service) GetUserByName(username string) (interface{}, error) {
+func (service *Service) GetUserByName(ctx context.Context, username string) (interface{}, error) {
 	repository := service.repo
 
-	rawUser, err := repository.GetUserByName(username, mysql.QueryOptions{})
+	rawUser, err := repository.GetUserByName(ctx, username, mysql.QueryOptions{})
 	if err != nil {
 		return nil, err
 	}
@@ -86,14 +96,14 @@ func (service *Service) GetUserByName(username string) (interface{}, error) {
 	case models.Student:
 		return &models.StudentModel{
 			UserInfoModel: info,
-			Points:        rawUser.Points.Int64,
+			Points:        valueOf(rawUser.Points),
 		}, nil
 
 	case models.Recruiter:
 		return &models.RecruiterModel{
 			UserInfoModel:     info,
-			OrganisationName:  rawUser.OrganisationName.String,
-			ApplicationStatus: rawUser.ApplicationStatus.Bool,
+			OrganisationName:  valueOf(rawUser.OrganisationName),
+			ApplicationStatus: valueOf(rawUser.ApplicationStatus),
 		}, nil
 
 	default:
@@ -101,7 +111,7 @@ func (service *Service) GetUserByName(username string) (interface{}, error) {
 	}
 }
 
-func (service *Service) GetUser(request *models.GetUserRequest) *models.GetUserResponse {
+func (service *Service) GetUser(ctx context.Context, request *models.GetUserRequest) *models.GetUserResponse {
 
 	if request.UserUUID == "" && request.Username == "" {
 		return writeStatus(nil, "username/userID not provided", false)
@@ -112,7 +122,7 @@ func (service *Service) GetUser(request *models.GetUserRequest) *models.GetUserR
 		if err != nil {
 			return writeStatus(nil, "uuid couldn't be parsed", false)
 		}
-		user, err := service.GetRawUserByID(parsedUUID)
+		user, err := service.GetRawUserByID(ctx, parsedUUID)
 		if err != nil {
 			log.Errorf("error occured: %s", err)
 			return writeStatus(nil, "error occured fetching user", false)
@@ -128,7 +138,7 @@ func (service *Service) GetUser(request *models.GetUserRequest) *models.GetUserR
 		return writeStatus(nil, "invalid request. Username/UserID not defined", false)
 	}
 
-	user, err := service.GetRawUserByName(request.Username)
+	user, err := service.GetRawUserByName(ctx, request.Username)
 	if err != nil {
 		log.Errorf("error occured: %s", err)
 		return writeStatus(nil, "error occured fetching user", false)
@@ -149,9 +159,9 @@ func writeStatus(model *models.UserInfoModel, message string, success bool) *mod
 	}
 }
 
-func (service *Service) GetRawUserByID(userID uuid.UUID) (*models.RawUserRow, error) {
+func (service *Service) GetRawUserByID(ctx context.Context, userID uuid.UUID) (*models.RawUserRow, error) {
 	repository := service.repo
-	id, err := repository.GetUserByID(userID)
+	id, err := repository.GetUserByID(ctx, userID)
 	if err != nil {
 		return nil, err
 	}
@@ -162,11 +172,11 @@ func (service *Service) GetRawUserByID(userID uuid.UUID) (*models.RawUserRow, er
 }
 
 // GetUserByID retrieves a user by their UUID.
-func (service *Service) GetUserByID(userID uuid.UUID) (interface{}, error) {
+func (service *Service) GetUserByID(ctx context.Context, userID uuid.UUID) (interface{}, error) {
 
 	repository := service.repo
 
-	rawUser, err := repository.GetUserByID(userID)
+	rawUser, err := repository.GetUserByID(ctx, userID)
 	if err != nil {
 		return nil, err
 	}
@@ -183,14 +193,14 @@ func (service *Service) GetUserByID(userID uuid.UUID) (interface{}, error) {
 	case models.Student:
 		return &models.StudentModel{
 			UserInfoModel: info,
-			Points:        user[0].Points.Int64,
+			Points:        valueOf(user[0].Points),
 		}, nil
 
 	case models.Recruiter:
 		return &models.RecruiterModel{
 			UserInfoModel:     info,
-			OrganisationName:  user[0].OrganisationName.String,
-			ApplicationStatus: user[0].ApplicationStatus.Bool,
+			OrganisationName:  valueOf(user[0].OrganisationName),
+			ApplicationStatus: valueOf(user[0].ApplicationStatus),
 		}, nil
 
 	default:
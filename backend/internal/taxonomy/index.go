@@ -0,0 +1,233 @@
+package taxonomy
+
+import (
+	"backend/internal/search"
+	"math"
+	"sort"
+	"strings"
+)
+
+// IndexedTag is a tag offered by the Index
+type IndexedTag struct {
+	ID       int64
+	Name     string
+	Synonyms []string
+	// Usage is how many opportunities and students reference the tag, completions are ranked by it
+	Usage int64
+}
+
+// TaggedText is an existing opportunity's title and description with the tags it was given
+type TaggedText struct {
+	Text   string
+	TagIDs []int64
+}
+
+type Completion struct {
+	ID      int64  `json:"id"`
+	TagName string `json:"tagName"`
+	// Matched is the synonym the prefix matched, when it didn't match the name
+	Matched string `json:"matched,omitempty"`
+	Usage   int64  `json:"usage"`
+}
+
+type Suggestion struct {
+	ID      int64   `json:"id"`
+	TagName string  `json:"tagName"`
+	Score   float64 `json:"score"`
+	// Named is set when the tag's name is in the text
+	Named bool `json:"named"`
+}
+
+type completionKey struct {
+	key   string
+	tagID int64
+	// synonym is set when the key came from a synonym rather than the name
+	synonym string
+}
+
+// Index answers autocomplete and tag suggestions from memory. It is immutable once built, so it can be read
+// concurrently and replaced wholesale when tags change
+type Index struct {
+	tags map[int64]IndexedTag
+	// keys are sorted so completions of a prefix are a contiguous range. Every word of a name is a key, so "plant"
+	// completes "tree planting"
+	keys []completionKey
+	// cooccurrence counts, per term, the documents containing it that were given each tag
+	cooccurrence map[string]map[int64]int
+	// frequency is the number of documents containing each term
+	frequency map[string]int
+	documents int
+}
+
+// BuildIndex indexes the tags, and learns which terms suggest them from the tagged texts. Tags in the texts that
+// aren't in tags are ignored, so only the tags passed in are ever offered
+func BuildIndex(tags []IndexedTag, texts []TaggedText) *Index {
+	index := &Index{
+		tags:         make(map[int64]IndexedTag, len(tags)),
+		cooccurrence: map[string]map[int64]int{},
+		frequency:    map[string]int{},
+	}
+
+	for _, tag := range tags {
+		index.tags[tag.ID] = tag
+		index.addKeys(tag.ID, tag.Name, "")
+		for _, synonym := range tag.Synonyms {
+			index.addKeys(tag.ID, synonym, synonym)
+		}
+	}
+	sort.Slice(index.keys, func(i, j int) bool {
+		return index.keys[i].key < index.keys[j].key
+	})
+
+	for _, text := range texts {
+		index.documents++
+		for _, term := range terms(text.Text) {
+			index.frequency[term]++
+			for _, tagID := range text.TagIDs {
+				if _, ok := index.tags[tagID]; !ok {
+					continue
+				}
+				if index.cooccurrence[term] == nil {
+					index.cooccurrence[term] = map[int64]int{}
+				}
+				index.cooccurrence[term][tagID]++
+			}
+		}
+	}
+	return index
+}
+
+func (index *Index) addKeys(tagID int64, name string, synonym string) {
+	words := strings.Fields(name)
+	for i := range words {
+		index.keys = append(index.keys, completionKey{key: strings.Join(words[i:], " "), tagID: tagID, synonym: synonym})
+	}
+}
+
+// Complete returns up to limit tags with a name, synonym or word in either starting with the prefix, the most used
+// first
+func (index *Index) Complete(prefix string, limit int) []Completion {
+	completions := make([]Completion, 0)
+	prefix = Normalise(prefix)
+	if prefix == "" {
+		return completions
+	}
+
+	seen := map[int64]int{}
+	start := sort.Search(len(index.keys), func(i int) bool {
+		return index.keys[i].key >= prefix
+	})
+	for _, key := range index.keys[start:] {
+		if !strings.HasPrefix(key.key, prefix) {
+			break
+		}
+
+		if at, ok := seen[key.tagID]; ok {
+			// Prefer reporting the name over a synonym
+			if key.synonym == "" {
+				completions[at].Matched = ""
+			}
+			continue
+		}
+
+		tag := index.tags[key.tagID]
+		seen[key.tagID] = len(completions)
+		completions = append(completions, Completion{ID: tag.ID, TagName: tag.Name, Matched: key.synonym, Usage: tag.Usage})
+	}
+
+	sort.Slice(completions, func(i, j int) bool {
+		if completions[i].Usage != completions[j].Usage {
+			return completions[i].Usage > completions[j].Usage
+		}
+		return completions[i].TagName < completions[j].TagName
+	})
+	if len(completions) > limit {
+		completions = completions[:limit]
+	}
+	return completions
+}
+
+// Suggest proposes up to limit tags for a draft's text, leaving out the tags it already has. A term votes for the
+// tags it co-occurred with in proportion to how often, weighted by how rare the term is so words every opportunity
+// uses don't suggest anything. Tags named in the text are suggested first
+func (index *Index) Suggest(text string, existing []string, limit int) []Suggestion {
+	suggestions := make([]Suggestion, 0)
+
+	excluded := map[string]bool{}
+	for _, name := range existing {
+		excluded[Normalise(name)] = true
+	}
+
+	draft := terms(text)
+	scores := map[int64]float64{}
+	for _, term := range draft {
+		frequency := index.frequency[term]
+		if frequency == 0 {
+			continue
+		}
+		idf := math.Log(float64(index.documents) / float64(frequency))
+		for tagID, count := range index.cooccurrence[term] {
+			scores[tagID] += idf * float64(count) / float64(frequency)
+		}
+	}
+
+	present := map[string]bool{}
+	for _, term := range draft {
+		present[term] = true
+	}
+
+	for id, tag := range index.tags {
+		named := mentions(present, tag.Name)
+		if (!named && scores[id] <= 0) || excluded[tag.Name] || excludedSynonym(excluded, tag.Synonyms) {
+			continue
+		}
+		suggestions = append(suggestions, Suggestion{ID: id, TagName: tag.Name, Score: scores[id], Named: named})
+	}
+
+	sort.Slice(suggestions, func(i, j int) bool {
+		if suggestions[i].Named != suggestions[j].Named {
+			return suggestions[i].Named
+		}
+		if suggestions[i].Score != suggestions[j].Score {
+			return suggestions[i].Score > suggestions[j].Score
+		}
+		return suggestions[i].TagName < suggestions[j].TagName
+	})
+	if len(suggestions) > limit {
+		suggestions = suggestions[:limit]
+	}
+	return suggestions
+}
+
+// mentions reports whether every word of the name is in the text
+func mentions(present map[string]bool, name string) bool {
+	words := search.Tokenize(name)
+	for _, word := range words {
+		if !present[word] {
+			return false
+		}
+	}
+	return len(words) > 0
+}
+
+func excludedSynonym(excluded map[string]bool, synonyms []string) bool {
+	for _, synonym := range synonyms {
+		if excluded[synonym] {
+			return true
+		}
+	}
+	return false
+}
+
+// terms are the distinct words of the text, a document counts once per term however often it repeats it
+func terms(text string) []string {
+	seen := map[string]bool{}
+	var unique []string
+	for _, term := range search.Tokenize(text) {
+		if !seen[term] {
+			seen[term] = true
+			unique = append(unique, term)
+		}
+	}
+	return unique
+}
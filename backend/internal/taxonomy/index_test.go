@@ -0,0 +1,85 @@
+package taxonomy
+
+import "testing"
+
+var indexedTags = []IndexedTag{
+	{ID: 1, Name: "recycling", Synonyms: []string{"recyle"}, Usage: 4},
+	{ID: 2, Name: "tree planting", Usage: 9},
+	{ID: 3, Name: "beach", Usage: 2},
+	{ID: 4, Name: "reuse", Usage: 7},
+}
+
+func TestCompleteRanksByUsage(t *testing.T) {
+	index := BuildIndex(indexedTags, nil)
+
+	completions := index.Complete("Re", 10)
+	if len(completions) != 2 || completions[0].TagName != "reuse" || completions[1].TagName != "recycling" {
+		t.Fatalf("unexpected completions %v", completions)
+	}
+	if completions := index.Complete("re", 1); len(completions) != 1 || completions[0].TagName != "reuse" {
+		t.Fatalf("expected the limit to keep the most used, got %v", completions)
+	}
+}
+
+func TestCompleteMatchesWordsAndSynonyms(t *testing.T) {
+	index := BuildIndex(indexedTags, nil)
+
+	if completions := index.Complete("plan", 10); len(completions) != 1 || completions[0].TagName != "tree planting" {
+		t.Fatalf("expected a later word to match, got %v", completions)
+	}
+
+	completions := index.Complete("recy", 10)
+	if len(completions) != 1 || completions[0].TagName != "recycling" || completions[0].Matched != "" {
+		t.Fatalf("expected the name to be reported over the synonym, got %v", completions)
+	}
+	completions = index.Complete("recyl", 10)
+	if len(completions) != 1 || completions[0].Matched != "recyle" {
+		t.Fatalf("expected the synonym to be reported, got %v", completions)
+	}
+
+	if completions := index.Complete(" ", 10); len(completions) != 0 {
+		t.Fatalf("expected nothing for an empty prefix, got %v", completions)
+	}
+}
+
+func TestSuggestFromCooccurrence(t *testing.T) {
+	index := BuildIndex(indexedTags, []TaggedText{
+		{Text: "Sorting bottles and cans at the depot", TagIDs: []int64{1}},
+		{Text: "Collect bottles from the sand", TagIDs: []int64{1, 3}},
+		{Text: "Restoring the sand dunes", TagIDs: []int64{3}},
+		{Text: "Plant saplings in the park", TagIDs: []int64{2}},
+		{Text: "Volunteers needed in the park", TagIDs: []int64{2, 99}},
+	})
+
+	suggestions := index.Suggest("Help us sort bottles and cans", nil, 3)
+	if len(suggestions) == 0 || suggestions[0].TagName != "recycling" {
+		t.Fatalf("expected recycling to be suggested first, got %v", suggestions)
+	}
+	for _, suggestion := range suggestions {
+		if suggestion.TagName == "tree planting" {
+			t.Fatalf("expected unrelated tags not to be suggested, got %v", suggestions)
+		}
+	}
+
+	// Terms in every document say nothing about the tag
+	if suggestions := index.Suggest("the", nil, 3); len(suggestions) != 0 {
+		t.Fatalf("expected no suggestions, got %v", suggestions)
+	}
+}
+
+func TestSuggestPrefersNamedTagsAndSkipsExisting(t *testing.T) {
+	index := BuildIndex(indexedTags, []TaggedText{
+		{Text: "Bottles and cans", TagIDs: []int64{1}},
+		{Text: "Saplings", TagIDs: []int64{2}},
+	})
+
+	suggestions := index.Suggest("Tree planting and bottles", nil, 5)
+	if len(suggestions) != 2 || suggestions[0].TagName != "tree planting" || suggestions[1].TagName != "recycling" {
+		t.Fatalf("expected the named tag first, got %v", suggestions)
+	}
+
+	suggestions = index.Suggest("Tree planting and bottles", []string{"Tree Planting", "recyle"}, 5)
+	if len(suggestions) != 0 {
+		t.Fatalf("expected tags the draft has to be left out, got %v", suggestions)
+	}
+}
@@ -0,0 +1,90 @@
+package taxonomy
+
+import (
+	"backend/internal/search"
+	"errors"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// MaxNameLength is the length of TagsTable.tagName and TagCategoriesTable.name
+const MaxNameLength = 50
+
+// maxSuggestions is how many similar tags are suggested for a tag that isn't approved
+const maxSuggestions = 3
+
+var (
+	ErrEmptyName   = errors.New("tag name is empty")
+	ErrNameTooLong = errors.New("tag name is too long")
+)
+
+// Normalise is the form tags are stored and looked up in, so "Recycling", " recycling " and "#recycling" are the
+// same tag. Letters are lower cased, surrounding whitespace and hashes are removed and inner whitespace is collapsed
+func Normalise(name string) string {
+	name = strings.TrimLeft(strings.TrimSpace(name), "#")
+	name = strings.Join(strings.FieldsFunc(name, unicode.IsSpace), " ")
+	return strings.ToLower(name)
+}
+
+// Validate normalises the name, returning an error if it can't be a tag
+func Validate(name string) (string, error) {
+	normalised := Normalise(name)
+	if normalised == "" {
+		return "", ErrEmptyName
+	}
+	if utf8.RuneCountInString(normalised) > MaxNameLength {
+		return "", ErrNameTooLong
+	}
+	return normalised, nil
+}
+
+// Suggest returns the names in vocabulary closest to name, e.g. "recycling" for "recylcing", best first
+func Suggest(name string, vocabulary []string) []string {
+	type suggestion struct {
+		name    string
+		quality float64
+	}
+
+	normalised := Normalise(name)
+	var suggestions []suggestion
+	for _, candidate := range vocabulary {
+		if quality := search.MatchQuality(normalised, Normalise(candidate)); quality > 0 {
+			suggestions = append(suggestions, suggestion{candidate, quality})
+		}
+	}
+
+	sort.SliceStable(suggestions, func(i, j int) bool {
+		return suggestions[i].quality > suggestions[j].quality
+	})
+
+	names := make([]string, 0, min(len(suggestions), maxSuggestions))
+	for i := 0; i < len(suggestions) && i < maxSuggestions; i++ {
+		names = append(names, suggestions[i].name)
+	}
+	return names
+}
+
+// Category is a node in the category hierarchy
+type Category struct {
+	ID       int64
+	ParentID *int64
+}
+
+// CreatesCycle reports whether moving the category under parentID would make it its own ancestor
+func CreatesCycle(categories []Category, id int64, parentID *int64) bool {
+	parents := make(map[int64]*int64, len(categories))
+	for _, category := range categories {
+		parents[category.ID] = category.ParentID
+	}
+
+	// Bounded by the number of categories in case the stored hierarchy already has a cycle
+	for steps := 0; parentID != nil && steps <= len(categories); steps++ {
+		if *parentID == id {
+			return true
+		}
+		parentID = parents[*parentID]
+	}
+	return parentID != nil
+}
@@ -0,0 +1,62 @@
+package taxonomy
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestNormalise(t *testing.T) {
+	for input, expected := range map[string]string{
+		"Recycling":           "recycling",
+		"recycling ":          "recycling",
+		"  Beach\t Clean-up ": "beach clean-up",
+		"#Litter":             "litter",
+	} {
+		if normalised := Normalise(input); normalised != expected {
+			t.Errorf("Normalise(%q) = %q, expected %q", input, normalised, expected)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	if _, err := Validate(" # "); !errors.Is(err, ErrEmptyName) {
+		t.Fatalf("expected ErrEmptyName, got %v", err)
+	}
+	if _, err := Validate(strings.Repeat("a", MaxNameLength+1)); !errors.Is(err, ErrNameTooLong) {
+		t.Fatalf("expected ErrNameTooLong, got %v", err)
+	}
+	if name, err := Validate(" Tree Planting"); err != nil || name != "tree planting" {
+		t.Fatalf("unexpected %q %v", name, err)
+	}
+}
+
+func TestSuggest(t *testing.T) {
+	vocabulary := []string{"recycling", "beach", "reuse", "tree planting"}
+
+	suggestions := Suggest("recylcing", vocabulary)
+	if len(suggestions) == 0 || suggestions[0] != "recycling" {
+		t.Fatalf("expected recycling to be suggested, got %v", suggestions)
+	}
+
+	if suggestions := Suggest("astronomy", vocabulary); len(suggestions) != 0 {
+		t.Fatalf("expected no suggestions, got %v", suggestions)
+	}
+}
+
+func TestCreatesCycle(t *testing.T) {
+	one, two := int64(1), int64(2)
+	categories := []Category{{ID: 1}, {ID: 2, ParentID: &one}, {ID: 3, ParentID: &two}}
+
+	// 3 is under 2 which is under 1, so 1 can't move under 3
+	three := int64(3)
+	if !CreatesCycle(categories, 1, &three) {
+		t.Fatal("expected moving a category under its descendant to be a cycle")
+	}
+	if !CreatesCycle(categories, 2, &two) {
+		t.Fatal("expected a category under itself to be a cycle")
+	}
+	if CreatesCycle(categories, 3, &one) || CreatesCycle(categories, 1, nil) {
+		t.Fatal("expected valid moves not to be cycles")
+	}
+}
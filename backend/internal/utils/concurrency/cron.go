@@ -0,0 +1,217 @@
+package concurrency
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule is when a job runs
+type Schedule interface {
+	// Next returns the first run strictly after the time, zero if there are no more
+	Next(after time.Time) time.Time
+	String() string
+}
+
+// interval runs a job at a fixed interval. Runs are aligned to multiples of the interval, so every instance works out
+// the same due times and only one of them claims each run
+type interval time.Duration
+
+// Every returns a schedule running a job every d
+func Every(d time.Duration) Schedule {
+	return interval(d)
+}
+
+func (every interval) Next(after time.Time) time.Time {
+	return after.Truncate(time.Duration(every)).Add(time.Duration(every))
+}
+
+func (every interval) String() string {
+	return "every " + time.Duration(every).String()
+}
+
+// cronSearchLimit is how far ahead Next looks before deciding an expression such as 0 0 30 2 * never matches
+const cronSearchLimit = 5 * 366 * 24 * time.Hour
+
+// descriptors are the named expressions
+var descriptors = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+var monthNames = []string{"JAN", "FEB", "MAR", "APR", "MAY", "JUN", "JUL", "AUG", "SEP", "OCT", "NOV", "DEC"}
+var weekdayNames = []string{"SUN", "MON", "TUE", "WED", "THU", "FRI", "SAT"}
+
+// cronField is the range and names of one field of an expression
+type cronField struct {
+	name     string
+	min, max int
+	// names are the values from min onwards by name, e.g. JAN for 1
+	names []string
+}
+
+var cronFields = []cronField{
+	{name: "minute", min: 0, max: 59},
+	{name: "hour", min: 0, max: 23},
+	{name: "day of month", min: 1, max: 31},
+	{name: "month", min: 1, max: 12, names: monthNames},
+	// 7 is also Sunday
+	{name: "day of week", min: 0, max: 7, names: weekdayNames},
+}
+
+// CronSchedule is a standard five field cron expression, minute hour day-of-month month day-of-week, in the time
+// zone of the times it's given. Fields are *, numbers, ranges such as 1-5, steps such as */15 or 0-30/10, and
+// comma separated lists of those. Months and weekdays can be named, e.g. JAN or MON-FRI, and @daily, @hourly etc.
+// can stand for the whole expression. As in cron, when both days are restricted a day matching either runs
+type CronSchedule struct {
+	expression string
+	// Bit n of each field is set when the value n matches
+	minutes, hours, days, months, weekdays uint64
+	// anyDay and anyWeekday are set when the field is *
+	anyDay, anyWeekday bool
+}
+
+// ParseCron parses a cron expression
+func ParseCron(expression string) (*CronSchedule, error) {
+	trimmed := strings.TrimSpace(expression)
+	if descriptor, ok := descriptors[strings.ToLower(trimmed)]; ok {
+		trimmed = descriptor
+	}
+
+	fields := strings.Fields(trimmed)
+	if len(fields) != len(cronFields) {
+		return nil, fmt.Errorf("cron expression %q must have %d fields, not %d", expression, len(cronFields), len(fields))
+	}
+
+	bits := make([]uint64, len(fields))
+	for i, field := range fields {
+		var err error
+		if bits[i], err = cronFields[i].parse(field); err != nil {
+			return nil, fmt.Errorf("cron expression %q: %w", expression, err)
+		}
+	}
+
+	// Sunday is both 0 and 7
+	if bits[4]&(1<<7) != 0 {
+		bits[4] |= 1
+	}
+
+	return &CronSchedule{
+		expression: expression,
+		minutes:    bits[0],
+		hours:      bits[1],
+		days:       bits[2],
+		months:     bits[3],
+		weekdays:   bits[4],
+		anyDay:     fields[2] == "*",
+		anyWeekday: fields[4] == "*",
+	}, nil
+}
+
+// MustParseCron is ParseCron for expressions known to be valid, it panics otherwise
+func MustParseCron(expression string) *CronSchedule {
+	schedule, err := ParseCron(expression)
+	if err != nil {
+		panic(err)
+	}
+	return schedule
+}
+
+// parse returns the bits of the values the field matches
+func (field cronField) parse(text string) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(text, ",") {
+		rangeText, stepText, stepped := strings.Cut(part, "/")
+
+		step := 1
+		if stepped {
+			var err error
+			if step, err = strconv.Atoi(stepText); err != nil || step <= 0 {
+				return 0, fmt.Errorf("invalid step %q in %s", stepText, field.name)
+			}
+		}
+
+		low, high := field.min, field.max
+		if rangeText != "*" {
+			first, last, isRange := strings.Cut(rangeText, "-")
+			var err error
+			if low, err = field.value(first); err != nil {
+				return 0, err
+			}
+			high = low
+			if isRange {
+				if high, err = field.value(last); err != nil {
+					return 0, err
+				}
+			} else if stepped {
+				// 5/15 is 5-59/15
+				high = field.max
+			}
+			if high < low {
+				return 0, fmt.Errorf("range %q in %s ends before it starts", rangeText, field.name)
+			}
+		}
+
+		for value := low; value <= high; value += step {
+			bits |= 1 << value
+		}
+	}
+	return bits, nil
+}
+
+// value parses a number or name within the field's range
+func (field cronField) value(text string) (int, error) {
+	for i, name := range field.names {
+		if strings.EqualFold(text, name) {
+			return field.min + i, nil
+		}
+	}
+
+	value, err := strconv.Atoi(text)
+	if err != nil || value < field.min || value > field.max {
+		return 0, fmt.Errorf("%q isn't a valid %s, expected %d-%d", text, field.name, field.min, field.max)
+	}
+	return value, nil
+}
+
+// Next returns the first minute after the time matching the expression, zero if none does in the next five years
+func (cron *CronSchedule) Next(after time.Time) time.Time {
+	location := after.Location()
+	next := after.Truncate(time.Minute).Add(time.Minute)
+	limit := next.Add(cronSearchLimit)
+
+	for next.Before(limit) {
+		switch {
+		case cron.months&(1<<int(next.Month())) == 0:
+			next = time.Date(next.Year(), next.Month()+1, 1, 0, 0, 0, 0, location)
+		case !cron.matchesDay(next):
+			next = time.Date(next.Year(), next.Month(), next.Day()+1, 0, 0, 0, 0, location)
+		case cron.hours&(1<<next.Hour()) == 0:
+			next = time.Date(next.Year(), next.Month(), next.Day(), next.Hour()+1, 0, 0, 0, location)
+		case cron.minutes&(1<<next.Minute()) == 0:
+			next = next.Add(time.Minute)
+		default:
+			return next
+		}
+	}
+	return time.Time{}
+}
+
+func (cron *CronSchedule) matchesDay(t time.Time) bool {
+	day := cron.days&(1<<t.Day()) != 0
+	weekday := cron.weekdays&(1<<int(t.Weekday())) != 0
+	if cron.anyDay || cron.anyWeekday {
+		return day && weekday
+	}
+	return day || weekday
+}
+
+func (cron *CronSchedule) String() string {
+	return cron.expression
+}
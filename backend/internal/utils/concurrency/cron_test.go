@@ -0,0 +1,49 @@
+package concurrency
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCronNext(t *testing.T) {
+	london, err := time.LoadLocation("Europe/London")
+	if err != nil {
+		t.Skip("no timezone database: ", err)
+	}
+	from := time.Date(2025, 1, 31, 10, 7, 30, 0, time.UTC)
+
+	tests := []struct {
+		expression string
+		from       time.Time
+		expected   time.Time
+	}{
+		{"*/15 * * * *", from, time.Date(2025, 1, 31, 10, 15, 0, 0, time.UTC)},
+		{"0 9 * * MON-FRI", from, time.Date(2025, 2, 3, 9, 0, 0, 0, time.UTC)},
+		{"@daily", from, time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)},
+		{"30 2 29 feb *", from, time.Date(2028, 2, 29, 2, 30, 0, 0, time.UTC)},
+		// Either day matches when both are restricted
+		{"0 0 15 * 7", from, time.Date(2025, 2, 2, 0, 0, 0, 0, time.UTC)},
+		{"5/20 8-9 * * *", from, time.Date(2025, 2, 1, 8, 5, 0, 0, time.UTC)},
+		// 01:30 doesn't exist on the day the clocks go forward
+		{"30 1 * * *", time.Date(2025, 3, 29, 12, 0, 0, 0, london), time.Date(2025, 3, 31, 1, 30, 0, 0, london)},
+		{"0 0 30 2 *", from, time.Time{}},
+	}
+
+	for _, test := range tests {
+		schedule, err := ParseCron(test.expression)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if next := schedule.Next(test.from); !next.Equal(test.expected) {
+			t.Errorf("%s: expected %v, got %v", test.expression, test.expected, next)
+		}
+	}
+}
+
+func TestParseCronRejectsInvalidExpressions(t *testing.T) {
+	for _, expression := range []string{"* * * *", "60 * * * *", "* * 0 * *", "*/0 * * * *", "5-1 * * * *", "* * * * FUNDAY"} {
+		if _, err := ParseCron(expression); err == nil {
+			t.Errorf("expected %q to be rejected", expression)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package concurrency
+
+import (
+	"context"
+	"sync"
+)
+
+// Future is the result of a task that may not have finished yet
+type Future[T any] struct {
+	done  chan struct{}
+	once  sync.Once
+	value T
+	err   error
+}
+
+// NewFuture returns a future along with the function completing it. Only the first call to complete has any effect
+func NewFuture[T any]() (*Future[T], func(T, error)) {
+	future := &Future[T]{done: make(chan struct{})}
+	return future, func(value T, err error) {
+		future.once.Do(func() {
+			future.value, future.err = value, err
+			close(future.done)
+		})
+	}
+}
+
+// Resolved returns a future that has already completed
+func Resolved[T any](value T, err error) *Future[T] {
+	future, complete := NewFuture[T]()
+	complete(value, err)
+	return future
+}
+
+// Done is closed once the future has completed
+func (future *Future[T]) Done() <-chan struct{} {
+	return future.done
+}
+
+// Get waits for the result, giving up with ctx's error if ctx is done first. Giving up doesn't stop the task
+func (future *Future[T]) Get(ctx context.Context) (T, error) {
+	select {
+	case <-future.done:
+		return future.value, future.err
+	case <-ctx.Done():
+		var zero T
+		return zero, ctx.Err()
+	}
+}
+
+// Then calls the callback's Success or Error once the future completes, on a goroutine of its own
+func (future *Future[T]) Then(callback Callback[T]) {
+	go func() {
+		<-future.done
+		if future.err != nil {
+			if callback.Error != nil {
+				callback.Error(future.err)
+			}
+			return
+		}
+		if callback.Success != nil {
+			callback.Success(future.value)
+		}
+	}()
+}
@@ -0,0 +1,47 @@
+package concurrency
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestFutureGet(t *testing.T) {
+	future, complete := NewFuture[int]()
+
+	go func() {
+		complete(1, nil)
+		complete(2, errors.New("ignored"))
+	}()
+
+	value, err := future.Get(context.Background())
+	if value != 1 || err != nil {
+		t.Fatalf("expected the first result, got %d, %v", value, err)
+	}
+}
+
+func TestFutureGetGivesUpWithContext(t *testing.T) {
+	future, _ := NewFuture[int]()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	if _, err := future.Get(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected the context's error, got %v", err)
+	}
+}
+
+func TestFutureThen(t *testing.T) {
+	failed := errors.New("failed")
+	errs := make(chan error, 1)
+
+	Resolved(0, failed).Then(Callback[int]{
+		Error:   func(err error) { errs <- err },
+		Success: func(int) { t.Error("expected only Error to be called") },
+	})
+
+	if err := <-errs; err != failed {
+		t.Fatalf("expected the future's error, got %v", err)
+	}
+}
@@ -0,0 +1,334 @@
+package concurrency
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"math/rand/v2"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultJobTimeout bounds a run of a job that doesn't set its own Timeout
+const DefaultJobTimeout = 10 * time.Minute
+
+var (
+	ErrJobNotFound = errors.New("job not found")
+	ErrJobRunning  = errors.New("job is already running")
+	ErrJobExists   = errors.New("a job with that name already exists")
+)
+
+// Job is work run on a schedule
+type Job struct {
+	// Name identifies the job in its status and locks, it must be the same on every instance
+	Name     string
+	Schedule Schedule
+	Run      func(ctx context.Context) error
+	// Immediately runs the job once when it's added, as well as on its schedule
+	Immediately bool
+	// Jitter delays each scheduled run by a random amount up to this long, so instances and jobs due at the same
+	// moment don't all start at once
+	Jitter time.Duration
+	// Timeout cancels a run's context and is how long the job's lock is held at most, DefaultJobTimeout if unset
+	Timeout time.Duration
+	// PerInstance runs the job on every instance rather than sharing its runs through the Locker, for jobs that
+	// only change the instance's own state
+	PerInstance bool
+}
+
+// Locker takes locks shared by every instance, so a job only runs on one of them at a time
+type Locker interface {
+	// Lock takes the lock for up to ttl, acquired is false if something else holds it. unlock releases the lock
+	// early, it does nothing once the lock has expired
+	Lock(ctx context.Context, key string, ttl time.Duration) (unlock func(), acquired bool, err error)
+}
+
+// JobStatus is what a job has done on this instance and when it runs next
+type JobStatus struct {
+	Name     string `json:"name"`
+	Schedule string `json:"schedule"`
+	Running  bool   `json:"running"`
+	// NextRun is when the job is next due, before jitter. Nil if the schedule has no more runs
+	NextRun      *time.Time    `json:"nextRun,omitempty"`
+	LastRun      *time.Time    `json:"lastRun,omitempty"`
+	LastDuration time.Duration `json:"lastDuration,omitempty"`
+	// LastError is empty when the last run succeeded
+	LastError string `json:"lastError,omitempty"`
+	Runs      int64  `json:"runs"`
+	Failures  int64  `json:"failures"`
+	// Skipped counts the runs that were due while the job was still running, here or on another instance
+	Skipped int64 `json:"skipped"`
+}
+
+// Scheduler runs jobs on their schedules until stopped. Runs of a job never overlap: a run due while the last is
+// still going is skipped, and with a Locker the same goes for runs on other instances
+type Scheduler struct {
+	mutex  sync.Mutex
+	locker Locker
+	jobs   map[string]*scheduledJob
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	// runs tracks the job loops and runs in progress, for Stop to wait on
+	runs sync.WaitGroup
+}
+
+type scheduledJob struct {
+	Job
+	running atomic.Bool
+
+	mutex  sync.Mutex
+	status JobStatus
+}
+
+// DefaultScheduler runs the jobs added with AddJob
+var DefaultScheduler = NewScheduler()
+
+// AddJob adds the job to DefaultScheduler
+func AddJob(job Job) error {
+	return DefaultScheduler.Add(job)
+}
+
+func NewScheduler() *Scheduler {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Scheduler{jobs: map[string]*scheduledJob{}, ctx: ctx, cancel: cancel}
+}
+
+// SetLocker shares jobs with other instances through the locker, nil runs them on every instance
+func (scheduler *Scheduler) SetLocker(locker Locker) {
+	scheduler.mutex.Lock()
+	defer scheduler.mutex.Unlock()
+	scheduler.locker = locker
+}
+
+// Add starts running the job on its schedule
+func (scheduler *Scheduler) Add(job Job) error {
+	if job.Name == "" || job.Schedule == nil || job.Run == nil {
+		return errors.New("a job needs a name, schedule and function to run")
+	}
+	if job.Timeout <= 0 {
+		job.Timeout = DefaultJobTimeout
+	}
+
+	scheduler.mutex.Lock()
+	defer scheduler.mutex.Unlock()
+
+	if scheduler.ctx.Err() != nil {
+		return ErrNotRunning
+	}
+	if _, exists := scheduler.jobs[job.Name]; exists {
+		return fmt.Errorf("%w: %s", ErrJobExists, job.Name)
+	}
+
+	scheduled := &scheduledJob{Job: job, status: JobStatus{Name: job.Name, Schedule: job.Schedule.String()}}
+	scheduler.jobs[job.Name] = scheduled
+
+	scheduler.runs.Add(1)
+	go scheduler.loop(scheduled)
+	return nil
+}
+
+// Statuses returns the status of every job, by name
+func (scheduler *Scheduler) Statuses() []JobStatus {
+	scheduler.mutex.Lock()
+	statuses := make([]JobStatus, 0, len(scheduler.jobs))
+	for _, job := range scheduler.jobs {
+		statuses = append(statuses, job.snapshot())
+	}
+	scheduler.mutex.Unlock()
+
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].Name < statuses[j].Name
+	})
+	return statuses
+}
+
+// Trigger runs the job now, outside its schedule. The run happens in the background, ErrJobRunning is returned if
+// the job is already running here
+func (scheduler *Scheduler) Trigger(name string) error {
+	scheduler.mutex.Lock()
+	job, ok := scheduler.jobs[name]
+	stopped := scheduler.ctx.Err() != nil
+	if ok && !stopped {
+		scheduler.runs.Add(1)
+	}
+	scheduler.mutex.Unlock()
+
+	if stopped {
+		return ErrNotRunning
+	}
+	if !ok {
+		return ErrJobNotFound
+	}
+
+	if !job.running.CompareAndSwap(false, true) {
+		scheduler.runs.Done()
+		return ErrJobRunning
+	}
+
+	go func() {
+		defer scheduler.runs.Done()
+		scheduler.run(job, time.Time{})
+	}()
+	return nil
+}
+
+// Stop stops scheduling runs and cancels those in progress, waiting for them to return or ctx to be done
+func (scheduler *Scheduler) Stop(ctx context.Context) error {
+	scheduler.mutex.Lock()
+	scheduler.cancel()
+	scheduler.mutex.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		scheduler.runs.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// loop runs the job each time it's due until the scheduler stops. The next run is worked out once the last has
+// finished, so runs missed while it was going are skipped rather than made up
+func (scheduler *Scheduler) loop(job *scheduledJob) {
+	defer scheduler.runs.Done()
+
+	if job.Immediately {
+		scheduler.runDue(job, time.Now())
+	}
+
+	for {
+		due := job.Schedule.Next(time.Now())
+		job.update(func(status *JobStatus) {
+			status.NextRun = nil
+			if !due.IsZero() {
+				status.NextRun = &due
+			}
+		})
+		if due.IsZero() {
+			return
+		}
+
+		delay := time.Until(due)
+		if job.Jitter > 0 {
+			delay += rand.N(job.Jitter)
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-scheduler.ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+			scheduler.runDue(job, due)
+		}
+	}
+}
+
+// runDue runs the job for the time it was due, unless it's still running
+func (scheduler *Scheduler) runDue(job *scheduledJob, due time.Time) {
+	if !job.running.CompareAndSwap(false, true) {
+		job.update(func(status *JobStatus) { status.Skipped++ })
+		log.Warnf("Skipped job %s, the last run is still going", job.Name)
+		return
+	}
+	scheduler.run(job, due)
+}
+
+// run runs the job, which the caller has marked as running. Scheduled runs are due at a time, with a Locker the
+// first instance to lock that time runs it and the rest skip it. Triggered runs have no due time
+func (scheduler *Scheduler) run(job *scheduledJob, due time.Time) {
+	defer job.running.Store(false)
+
+	scheduler.mutex.Lock()
+	locker := scheduler.locker
+	scheduler.mutex.Unlock()
+
+	if locker != nil && !job.PerInstance {
+		unlock, acquired, err := scheduler.lock(locker, job, due)
+		if err != nil {
+			job.update(func(status *JobStatus) { status.LastError = err.Error() })
+			log.Errorf("Unable to lock job %s: %s", job.Name, err)
+			return
+		}
+		if !acquired {
+			return
+		}
+		defer unlock()
+	}
+
+	ctx, cancel := context.WithTimeout(scheduler.ctx, job.Timeout)
+	defer cancel()
+
+	started := time.Now()
+	job.update(func(status *JobStatus) { status.LastRun = &started })
+
+	err := runJob(ctx, job.Run)
+
+	job.update(func(status *JobStatus) {
+		status.LastDuration = time.Since(started)
+		status.Runs++
+		status.LastError = ""
+		if err != nil {
+			status.Failures++
+			status.LastError = err.Error()
+		}
+	})
+	if err != nil {
+		log.Errorf("Job %s failed: %s", job.Name, err)
+	}
+}
+
+// lock takes the job's lock for the run. A scheduled run first claims its due time until the run after, so an
+// instance whose clock is behind doesn't run it again once the first has finished
+func (scheduler *Scheduler) lock(locker Locker, job *scheduledJob, due time.Time) (unlock func(), acquired bool, err error) {
+	ctx, cancel := context.WithTimeout(scheduler.ctx, 10*time.Second)
+	defer cancel()
+
+	if !due.IsZero() {
+		claim := max(job.Schedule.Next(due).Sub(due), time.Second)
+		_, claimed, err := locker.Lock(ctx, fmt.Sprintf("jobs:%s:%d", job.Name, due.Unix()), claim)
+		if err != nil || !claimed {
+			return nil, false, err
+		}
+	}
+
+	unlock, acquired, err = locker.Lock(ctx, "jobs:"+job.Name, job.Timeout)
+	if err == nil && !acquired {
+		job.update(func(status *JobStatus) { status.Skipped++ })
+		log.Infof("Skipped job %s, it's running on another instance", job.Name)
+	}
+	return unlock, acquired, err
+}
+
+// runJob runs the job's function, returning a panic as an error
+func runJob(ctx context.Context, run func(ctx context.Context) error) (err error) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			err = fmt.Errorf("panicked: %v", recovered)
+		}
+	}()
+	return run(ctx)
+}
+
+func (job *scheduledJob) update(change func(status *JobStatus)) {
+	job.mutex.Lock()
+	defer job.mutex.Unlock()
+	change(&job.status)
+}
+
+func (job *scheduledJob) snapshot() JobStatus {
+	job.mutex.Lock()
+	defer job.mutex.Unlock()
+	status := job.status
+	status.Running = job.running.Load()
+	return status
+}
@@ -0,0 +1,163 @@
+package concurrency
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// memoryLocker is a Locker shared by schedulers in the same test, standing in for Redis
+type memoryLocker struct {
+	mutex sync.Mutex
+	held  map[string]bool
+}
+
+func (locker *memoryLocker) Lock(_ context.Context, key string, _ time.Duration) (func(), bool, error) {
+	locker.mutex.Lock()
+	defer locker.mutex.Unlock()
+	if locker.held[key] {
+		return nil, false, nil
+	}
+	locker.held[key] = true
+	return func() {
+		locker.mutex.Lock()
+		defer locker.mutex.Unlock()
+		delete(locker.held, key)
+	}, true, nil
+}
+
+func stopScheduler(t *testing.T, scheduler *Scheduler) {
+	t.Cleanup(func() {
+		if err := scheduler.Stop(context.Background()); err != nil {
+			t.Error(err)
+		}
+	})
+}
+
+func waitFor(t *testing.T, condition func() bool) {
+	t.Helper()
+	for deadline := time.Now().Add(2 * time.Second); !condition(); time.Sleep(time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out")
+		}
+	}
+}
+
+func TestSchedulerRecordsRuns(t *testing.T) {
+	scheduler := NewScheduler()
+	stopScheduler(t, scheduler)
+
+	failed := errors.New("failed")
+	var mutex sync.Mutex
+	runs := 0
+	err := scheduler.Add(Job{Name: "count", Schedule: Every(5 * time.Millisecond), Run: func(context.Context) error {
+		mutex.Lock()
+		defer mutex.Unlock()
+		if runs++; runs == 1 {
+			return failed
+		}
+		return nil
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := scheduler.Add(Job{Name: "count", Schedule: Every(time.Hour), Run: func(context.Context) error { return nil }}); !errors.Is(err, ErrJobExists) {
+		t.Fatalf("expected ErrJobExists, got %v", err)
+	}
+
+	waitFor(t, func() bool { return scheduler.Statuses()[0].Runs >= 2 })
+
+	status := scheduler.Statuses()[0]
+	if status.Failures != 1 || status.LastError != "" || status.LastRun == nil || status.NextRun == nil {
+		t.Fatalf("expected a failure then a success, got %+v", status)
+	}
+}
+
+func TestSchedulerDoesntOverlapRuns(t *testing.T) {
+	scheduler := NewScheduler()
+	stopScheduler(t, scheduler)
+
+	release := make(chan struct{})
+	err := scheduler.Add(Job{Name: "slow", Schedule: Every(time.Hour), Immediately: true, Run: func(ctx context.Context) error {
+		select {
+		case <-release:
+		case <-ctx.Done():
+		}
+		return nil
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	waitFor(t, func() bool { return scheduler.Statuses()[0].Running })
+	if err := scheduler.Trigger("slow"); !errors.Is(err, ErrJobRunning) {
+		t.Fatalf("expected ErrJobRunning, got %v", err)
+	}
+	close(release)
+
+	waitFor(t, func() bool { return !scheduler.Statuses()[0].Running })
+	if err := scheduler.Trigger("slow"); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, func() bool { return scheduler.Statuses()[0].Runs == 2 })
+
+	if err := scheduler.Trigger("missing"); !errors.Is(err, ErrJobNotFound) {
+		t.Fatalf("expected ErrJobNotFound, got %v", err)
+	}
+}
+
+func TestSchedulersShareRunsThroughLocker(t *testing.T) {
+	for _, schedule := range []Schedule{MustParseCron("* * * * *"), Every(time.Hour)} {
+		t.Run(schedule.String(), func(t *testing.T) {
+			locker := &memoryLocker{held: map[string]bool{}}
+			var mutex sync.Mutex
+			runs := 0
+
+			// Two instances work out the same run from clocks a little apart
+			due := schedule.Next(time.Now())
+			for i := range 2 {
+				scheduler := NewScheduler()
+				scheduler.SetLocker(locker)
+				stopScheduler(t, scheduler)
+
+				job := &scheduledJob{Job: Job{Name: "shared", Schedule: schedule, Timeout: time.Minute, Run: func(context.Context) error {
+					mutex.Lock()
+					defer mutex.Unlock()
+					runs++
+					return nil
+				}}}
+				scheduler.runDue(job, schedule.Next(due.Add(-time.Duration(i+1)*time.Second)))
+			}
+
+			if runs != 1 {
+				t.Fatalf("expected the run to happen on one instance, it happened on %d", runs)
+			}
+		})
+	}
+}
+
+func TestPerInstanceJobsRunOnEveryInstance(t *testing.T) {
+	locker := &memoryLocker{held: map[string]bool{}}
+	var runs atomic.Int32
+
+	schedule := Every(time.Hour)
+	due := schedule.Next(time.Now())
+	for range 2 {
+		scheduler := NewScheduler()
+		scheduler.SetLocker(locker)
+		stopScheduler(t, scheduler)
+
+		job := &scheduledJob{Job: Job{Name: "local", Schedule: schedule, PerInstance: true, Run: func(context.Context) error {
+			runs.Add(1)
+			return nil
+		}}}
+		scheduler.runDue(job, due)
+	}
+
+	if runs.Load() != 2 {
+		t.Fatalf("expected the run to happen on both instances, it happened on %d", runs.Load())
+	}
+}
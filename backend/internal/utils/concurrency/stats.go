@@ -0,0 +1,55 @@
+package concurrency
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// metrics are counted by the workers as tasks run
+type metrics struct {
+	active    atomic.Int64
+	completed atomic.Int64
+	panicked  atomic.Int64
+	rejected  atomic.Int64
+	cancelled atomic.Int64
+	// waited and ran are the total nanoseconds completed tasks spent queued and running
+	waited atomic.Int64
+	ran    atomic.Int64
+}
+
+// Stats are a snapshot of a pool's workload
+type Stats struct {
+	Workers int `json:"workers"`
+	// Active is how many workers are running a task
+	Active int64 `json:"active"`
+	// Queued is how many tasks are waiting for a worker
+	Queued int `json:"queued"`
+	// Completed counts the tasks that ran, including those that panicked
+	Completed int64 `json:"completed"`
+	Panicked  int64 `json:"panicked"`
+	// Rejected counts the tasks turned away because the queue was full
+	Rejected int64 `json:"rejected"`
+	// Cancelled counts the tasks skipped because their context was done before their turn
+	Cancelled int64 `json:"cancelled"`
+	// AverageWait is how long tasks waited for a worker and AverageRun how long they ran, on average
+	AverageWait time.Duration `json:"averageWait"`
+	AverageRun  time.Duration `json:"averageRun"`
+}
+
+// Stats returns the pool's current workload and what it's done so far
+func (pool *ThreadPool) Stats() Stats {
+	stats := Stats{
+		Workers:   pool.poolSize,
+		Active:    pool.metrics.active.Load(),
+		Queued:    len(pool.tasks),
+		Completed: pool.metrics.completed.Load(),
+		Panicked:  pool.metrics.panicked.Load(),
+		Rejected:  pool.metrics.rejected.Load(),
+		Cancelled: pool.metrics.cancelled.Load(),
+	}
+	if stats.Completed > 0 {
+		stats.AverageWait = time.Duration(pool.metrics.waited.Load() / stats.Completed)
+		stats.AverageRun = time.Duration(pool.metrics.ran.Load() / stats.Completed)
+	}
+	return stats
+}
@@ -1,14 +1,77 @@
 package concurrency
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	log "github.com/sirupsen/logrus"
+	"runtime/debug"
+	"sync"
+	"time"
 )
 
-// ThreadPool structure to handle tasks.
+var (
+	// ErrNotRunning is returned for tasks submitted before Start or after Stop
+	ErrNotRunning = errors.New("thread pool isn't running")
+	// ErrPoolFull is returned for tasks submitted to a full pool with the Reject policy
+	ErrPoolFull = errors.New("thread pool is full")
+)
+
+// ShutdownTimeout is how long ShutdownPool waits for tasks to finish
+var ShutdownTimeout = 30 * time.Second
+
+// Policy decides what happens to a task submitted while the queue is full
+type Policy int
+
+const (
+	// Block waits for room in the queue, or until the task's context is done
+	Block Policy = iota
+	// Reject fails with ErrPoolFull
+	Reject
+	// CallerRuns runs the task on the submitting goroutine, slowing the caller down to the pool's pace
+	CallerRuns
+)
+
+// Option configures a ThreadPool
+type Option func(pool *ThreadPool)
+
+// WithPolicy sets what happens to tasks submitted while the queue is full, Block by default
+func WithPolicy(policy Policy) Option {
+	return func(pool *ThreadPool) {
+		pool.policy = policy
+	}
+}
+
+// task is a submitted function and the context it was submitted with
+type task struct {
+	ctx context.Context
+	run func(ctx context.Context)
+	// skipped is called instead of run when ctx is done before the task's turn, it may be nil
+	skipped   func(err error)
+	submitted time.Time
+}
+
+// ThreadPool runs tasks on a fixed number of workers, queueing up to its buffer size of them
 type ThreadPool struct {
-	tasks     chan interface{}
-	isRunning bool
-	poolSize  int
+	tasks    chan task
+	poolSize int
+	policy   Policy
+
+	// lifecycle guards running, submissions hold it for reading while they join senders
+	lifecycle sync.RWMutex
+	running   bool
+	stopped   bool
+	workers   sync.WaitGroup
+	// done is closed by Stop to wake blocked senders, tasks is closed once the last sender has left
+	done    chan struct{}
+	senders sync.WaitGroup
+
+	// pending counts tasks submitted and not yet finished, idle is signalled when it reaches 0
+	pending int
+	mutex   sync.Mutex
+	idle    *sync.Cond
+
+	metrics metrics
 }
 
 // Callback structure to handle callbacks
@@ -18,44 +81,236 @@ type Callback[T any] struct {
 }
 
 // NewThreadPool constructor for creating a new thread pool with a given pool size.
-func NewThreadPool(poolSize int, bufferSize int) *ThreadPool {
-	return &ThreadPool{
-		poolSize: poolSize,
-		tasks:    make(chan interface{}, bufferSize),
+func NewThreadPool(poolSize int, bufferSize int, options ...Option) *ThreadPool {
+	pool := &ThreadPool{
+		poolSize: max(poolSize, 1),
+		tasks:    make(chan task, max(bufferSize, 0)),
+		done:     make(chan struct{}),
+	}
+	pool.idle = sync.NewCond(&pool.mutex)
+	for _, option := range options {
+		option(pool)
 	}
+	return pool
 }
 
-// Start initializes the thread pool and starts worker goroutines.
+// Start starts the workers. A pool can't be started again once stopped
 func (pool *ThreadPool) Start() {
+	pool.lifecycle.Lock()
+	defer pool.lifecycle.Unlock()
+
+	if pool.running || pool.stopped {
+		return
+	}
+
+	pool.workers.Add(pool.poolSize)
 	for i := 0; i < pool.poolSize; i++ {
-		go runThread(pool)
+		go pool.runThread()
 	}
+	pool.running = true
+}
 
-	pool.isRunning = true
+// Stop stops accepting tasks. Tasks already queued or running carry on, Wait for them to finish. Submissions
+// blocked waiting for room fail with ErrNotRunning. Stop doesn't block, so tasks may call it
+func (pool *ThreadPool) Stop() {
+	pool.lifecycle.Lock()
+	defer pool.lifecycle.Unlock()
+
+	if pool.stopped {
+		return
+	}
+	pool.stopped = true
+	close(pool.done)
+	if pool.running {
+		pool.running = false
+		// Closing tasks under a sender would panic, so the workers are let go once the senders have left
+		go func() {
+			pool.senders.Wait()
+			close(pool.tasks)
+		}()
+	}
 }
 
-// runThread continuously fetches and executes tasks from the tasks channel.
-func runThread(pool *ThreadPool) {
-	for task := range pool.tasks {
-		switch t := task.(type) {
-		case func():
-			t()
-		case func(any):
-			t(nil)
-		default:
-			fmt.Println("Unknown task type")
+// Wait waits until every task submitted so far has finished, or until ctx is done
+func (pool *ThreadPool) Wait(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		pool.mutex.Lock()
+		for pool.pending > 0 {
+			pool.idle.Wait()
 		}
+		pool.mutex.Unlock()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Shutdown stops the pool and waits for its tasks to finish, giving up when ctx is done
+func (pool *ThreadPool) Shutdown(ctx context.Context) error {
+	pool.Stop()
+	if err := pool.Wait(ctx); err != nil {
+		return err
 	}
+	pool.workers.Wait()
+	return nil
 }
 
-// Submit adds a new task to the thread pool.
-func (pool *ThreadPool) Submit(task interface{}) {
-	if !pool.isRunning {
+// ShutdownPool shuts the pool down, waiting up to ShutdownTimeout for its tasks and logging any that are left. The
+// pool may be nil
+func ShutdownPool(pool *ThreadPool) {
+	if pool == nil {
 		return
 	}
 
-	//Failsafe go-func TODO: remove this find another way
-	go func() {
-		pool.tasks <- task
+	ctx, cancel := context.WithTimeout(context.Background(), ShutdownTimeout)
+	defer cancel()
+	if err := pool.Shutdown(ctx); err != nil {
+		stats := pool.Stats()
+		log.Warnf("Thread pool shut down with %d tasks unfinished", stats.Queued+int(stats.Active))
+	}
+}
+
+// runThread runs tasks until the pool is stopped and its queue is empty
+func (pool *ThreadPool) runThread() {
+	defer pool.workers.Done()
+	for task := range pool.tasks {
+		pool.execute(task)
+	}
+}
+
+// execute runs the task unless its context is already done, recovering a panic so the worker carries on
+func (pool *ThreadPool) execute(task task) {
+	defer pool.finished()
+
+	if err := task.ctx.Err(); err != nil {
+		pool.metrics.cancelled.Add(1)
+		if task.skipped != nil {
+			task.skipped(err)
+		}
+		return
+	}
+
+	started := time.Now()
+	pool.metrics.waited.Add(int64(started.Sub(task.submitted)))
+	pool.metrics.active.Add(1)
+	defer func() {
+		pool.metrics.active.Add(-1)
+		pool.metrics.ran.Add(int64(time.Since(started)))
+		pool.metrics.completed.Add(1)
+
+		if recovered := recover(); recovered != nil {
+			pool.metrics.panicked.Add(1)
+			log.Errorf("Thread pool task panicked: %v\n%s", recovered, debug.Stack())
+		}
 	}()
+
+	task.run(task.ctx)
+}
+
+func (pool *ThreadPool) finished() {
+	pool.mutex.Lock()
+	pool.pending--
+	if pool.pending == 0 {
+		pool.idle.Broadcast()
+	}
+	pool.mutex.Unlock()
+}
+
+// Submit queues the task, see SubmitContext
+func (pool *ThreadPool) Submit(run func()) error {
+	return pool.SubmitContext(context.Background(), func(context.Context) { run() })
+}
+
+// SubmitContext queues the task to be run with ctx. A task whose context is done before a worker takes it isn't
+// run, and with the Block policy ctx also bounds waiting for room in the queue
+func (pool *ThreadPool) SubmitContext(ctx context.Context, run func(ctx context.Context)) error {
+	return pool.submit(task{ctx: ctx, run: run})
+}
+
+func (pool *ThreadPool) submit(submitted task) error {
+	ctx := submitted.ctx
+
+	// The lock is only held to join the senders. Waiting for room or running the task inline while holding it would
+	// deadlock a task, or any other goroutine, calling Stop
+	pool.lifecycle.RLock()
+	if !pool.running {
+		pool.lifecycle.RUnlock()
+		return ErrNotRunning
+	}
+	pool.senders.Add(1)
+	pool.lifecycle.RUnlock()
+
+	if err := ctx.Err(); err != nil {
+		pool.senders.Done()
+		return err
+	}
+
+	submitted.submitted = time.Now()
+	pool.mutex.Lock()
+	pool.pending++
+	pool.mutex.Unlock()
+
+	select {
+	case pool.tasks <- submitted:
+		pool.senders.Done()
+		return nil
+	default:
+	}
+
+	switch pool.policy {
+	case Reject:
+		pool.senders.Done()
+		pool.metrics.rejected.Add(1)
+		pool.finished()
+		return ErrPoolFull
+	case CallerRuns:
+		pool.senders.Done()
+		pool.execute(submitted)
+		return nil
+	}
+
+	defer pool.senders.Done()
+	select {
+	case pool.tasks <- submitted:
+		return nil
+	case <-ctx.Done():
+		pool.finished()
+		return ctx.Err()
+	case <-pool.done:
+		pool.finished()
+		return ErrNotRunning
+	}
+}
+
+// SubmitFuture runs fn on the pool and returns a future of its result. The future fails with the submission's
+// error if the task couldn't be queued, with ctx's error if ctx was done before fn's turn, and with an error if fn
+// panicked
+func SubmitFuture[T any](ctx context.Context, pool *ThreadPool, fn func(ctx context.Context) (T, error)) *Future[T] {
+	future, complete := NewFuture[T]()
+	var zero T
+
+	err := pool.submit(task{
+		ctx: ctx,
+		run: func(ctx context.Context) {
+			defer func() {
+				if recovered := recover(); recovered != nil {
+					complete(zero, fmt.Errorf("task panicked: %v", recovered))
+					// Passed on for the pool to log and count
+					panic(recovered)
+				}
+			}()
+			complete(fn(ctx))
+		},
+		skipped: func(err error) { complete(zero, err) },
+	})
+	if err != nil {
+		complete(zero, err)
+	}
+	return future
 }
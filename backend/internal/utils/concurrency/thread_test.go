@@ -1,7 +1,10 @@
 package concurrency
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"sync/atomic"
 	"testing"
 	"time"
 )
@@ -24,3 +27,184 @@ func Add() {
 
 	fmt.Println("1")
 }
+
+func TestShutdownDrainsQueuedTasks(t *testing.T) {
+	pool := NewThreadPool(1, 10)
+	pool.Start()
+
+	var ran atomic.Int32
+	for range 5 {
+		if err := pool.Submit(func() {
+			time.Sleep(time.Millisecond)
+			ran.Add(1)
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := pool.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if ran.Load() != 5 {
+		t.Fatalf("expected the queued tasks to run before shutting down, %d did", ran.Load())
+	}
+	if err := pool.Submit(func() {}); !errors.Is(err, ErrNotRunning) {
+		t.Fatalf("expected ErrNotRunning after Stop, got %v", err)
+	}
+}
+
+// fullPool returns a started pool whose single worker is busy and queue full until release is closed
+func fullPool(t *testing.T, policy Policy) (pool *ThreadPool, release chan struct{}) {
+	pool = NewThreadPool(1, 1, WithPolicy(policy))
+	pool.Start()
+	t.Cleanup(pool.Stop)
+
+	release = make(chan struct{})
+	started := make(chan struct{})
+	pool.Submit(func() {
+		close(started)
+		<-release
+	})
+	<-started
+	pool.Submit(func() {})
+	return pool, release
+}
+
+func TestFullPoolPolicies(t *testing.T) {
+	pool, release := fullPool(t, Reject)
+	if err := pool.Submit(func() { t.Error("expected the task to be rejected") }); !errors.Is(err, ErrPoolFull) {
+		t.Fatalf("expected ErrPoolFull, got %v", err)
+	}
+	if pool.Stats().Rejected != 1 {
+		t.Fatalf("expected the rejection to be counted, got %+v", pool.Stats())
+	}
+	close(release)
+
+	pool, release = fullPool(t, CallerRuns)
+	ran := false
+	if err := pool.Submit(func() { ran = true }); err != nil || !ran {
+		t.Fatalf("expected the task to run on the caller, got %v", err)
+	}
+	close(release)
+
+	pool, release = fullPool(t, Block)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := pool.SubmitContext(ctx, func(context.Context) {}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected to give up waiting for room, got %v", err)
+	}
+	close(release)
+}
+
+// within fails the test if fn doesn't return within a second
+func within(t *testing.T, what string, fn func()) {
+	t.Helper()
+	done := make(chan struct{})
+	go func() {
+		fn()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatalf("deadlocked: %s", what)
+	}
+}
+
+func TestTasksCanStopThePool(t *testing.T) {
+	pool, release := fullPool(t, CallerRuns)
+	within(t, "a task run on the caller stopping the pool", func() {
+		if err := pool.Submit(pool.Stop); err != nil {
+			t.Error(err)
+		}
+	})
+	close(release)
+	within(t, "shutting down", func() { _ = pool.Shutdown(context.Background()) })
+
+	pool = NewThreadPool(1, 1)
+	pool.Start()
+	stopped := make(chan struct{})
+	pool.Submit(func() {
+		pool.Stop()
+		close(stopped)
+	})
+	within(t, "a worker stopping the pool", func() { <-stopped })
+	within(t, "shutting down", func() { _ = pool.Shutdown(context.Background()) })
+}
+
+func TestStopReleasesBlockedSubmitters(t *testing.T) {
+	pool, release := fullPool(t, Block)
+
+	// Waits for room that never comes, as a worker's task submitting to its own full pool would
+	submitted := make(chan error, 1)
+	go func() {
+		submitted <- pool.Submit(func() {})
+	}()
+	time.Sleep(10 * time.Millisecond)
+
+	within(t, "stopping with a blocked submitter", pool.Stop)
+	within(t, "the blocked submitter giving up", func() {
+		if err := <-submitted; !errors.Is(err, ErrNotRunning) {
+			t.Errorf("expected ErrNotRunning, got %v", err)
+		}
+	})
+
+	close(release)
+	within(t, "shutting down", func() {
+		if err := pool.Shutdown(context.Background()); err != nil {
+			t.Error(err)
+		}
+	})
+}
+
+func TestPanicsDontStopWorkers(t *testing.T) {
+	pool := NewThreadPool(1, 1)
+	pool.Start()
+	defer pool.Stop()
+
+	pool.Submit(func() { panic("failed") })
+
+	value, err := SubmitFuture(context.Background(), pool, func(context.Context) (int, error) { return 1, nil }).
+		Get(context.Background())
+	if value != 1 || err != nil {
+		t.Fatalf("expected the worker to carry on after a panic, got %d, %v", value, err)
+	}
+
+	if _, err := SubmitFuture(context.Background(), pool, func(context.Context) (int, error) { panic("failed") }).
+		Get(context.Background()); err == nil {
+		t.Fatal("expected the panic as the future's error")
+	}
+	if err := pool.Wait(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if stats := pool.Stats(); stats.Panicked != 2 || stats.Completed != 3 {
+		t.Fatalf("expected 2 of 3 tasks to have panicked, got %+v", stats)
+	}
+}
+
+func TestSubmitFutureSkipsCancelledTasks(t *testing.T) {
+	pool := NewThreadPool(1, 1)
+	pool.Start()
+	defer pool.Stop()
+
+	release := make(chan struct{})
+	pool.Submit(func() { <-release })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	future := SubmitFuture(ctx, pool, func(context.Context) (int, error) {
+		t.Error("expected the task not to run")
+		return 0, nil
+	})
+	cancel()
+	close(release)
+
+	if _, err := future.Get(context.Background()); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	if err := pool.Wait(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if pool.Stats().Cancelled != 1 {
+		t.Fatalf("expected the skipped task to be counted, got %+v", pool.Stats())
+	}
+}
@@ -1,12 +1,20 @@
 package response
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 )
 
+// StatusClientClosedRequest is the non standard status logged when the client disconnected before the response
+const StatusClientClosedRequest = 499
+
 func WriteJson(w http.ResponseWriter, obj interface{}) {
 	w.Header().Set("Content-Type", "application/json")
+	if res, ok := obj.(*Response); ok && res != nil && res.status != 0 {
+		w.WriteHeader(res.status)
+	}
 	json.NewEncoder(w).Encode(obj)
 }
 
@@ -14,6 +22,8 @@ type Response struct {
 	Success bool        `json:"success"`
 	Data    interface{} `json:"data,omitempty"`
 	Message string      `json:"message,omitempty"`
+	// status is the HTTP status to write, 200 when unset
+	status int
 }
 
 func SuccessResponse(data interface{}, message string) *Response {
@@ -29,3 +39,15 @@ func ErrorResponse(message string) *Response {
 		Message: message,
 	}
 }
+
+// ContextError returns the response for a request that failed because the client went away or the database took
+// too long, nil for any other error
+func ContextError(err error) *Response {
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return &Response{Message: "Request timed out", status: http.StatusGatewayTimeout}
+	case errors.Is(err, context.Canceled):
+		return &Response{Message: "Request cancelled", status: StatusClientClosedRequest}
+	}
+	return nil
+}
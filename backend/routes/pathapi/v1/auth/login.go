@@ -46,7 +46,7 @@ func (path *LoginPath) Login(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	loginStatus := path.service.Login(req.Username, req.Password)
+	loginStatus := path.service.Login(r.Context(), req.Username, req.Password)
 	response.WriteJson(w, loginStatus)
 }
 
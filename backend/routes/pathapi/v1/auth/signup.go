@@ -52,7 +52,7 @@ func (path *SignupPath) Signup(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	status := path.service.Signup(req.Username, req.Password, req.Email, req.Role)
+	status := path.service.Signup(r.Context(), req.Username, req.Password, req.Email, req.Role)
 	response.WriteJson(w, status)
 }
 
@@ -0,0 +1,83 @@
+package auth
+
+import (
+	"backend/internal/db/adapters/mysql"
+	"backend/internal/db/adapters/neo4j"
+	"backend/internal/db/repositories"
+	"backend/internal/security"
+	"backend/internal/service/auth"
+	response "backend/internal/utils/http"
+	"backend/routes/pathapi"
+	"net/http"
+
+	"github.com/go-chi/chi"
+	log "github.com/sirupsen/logrus"
+)
+
+type SSOPath struct {
+	router  chi.Router
+	service *auth.SSOService
+}
+
+func (path *SSOPath) SetupComponents(sqlRepository *mysql.Repository, _ *neo4j.Repository) chi.Router {
+	r := chi.NewRouter()
+	path.router = r
+
+	userRepo, err := repositories.NewUserRepository(sqlRepository)
+	if err != nil {
+		return nil
+	}
+
+	identityRepo, err := repositories.NewIdentityRepository(sqlRepository)
+	if err != nil {
+		log.Error("Failed to initialize IdentityRepository: ", err)
+		return nil
+	}
+
+	path.service = auth.NewSSOService(userRepo, identityRepo)
+
+	r.Get("/{provider}/login", path.Login)
+	r.Get("/{provider}/link", path.Link)
+	r.Get("/{provider}/callback", path.Callback)
+	return r
+}
+
+// Login returns the provider authorization URL to start an SSO login
+func (path *SSOPath) Login(w http.ResponseWriter, r *http.Request) {
+	provider := chi.URLParam(r, "provider")
+
+	res := path.service.BeginLogin(r.Context(), provider, nil)
+	response.WriteJson(w, res)
+}
+
+// Link returns the provider authorization URL to link an SSO identity to the logged in account
+func (path *SSOPath) Link(w http.ResponseWriter, r *http.Request) {
+	userInfo, err := security.ExtractUserInfoFromJWT(r)
+	if err != nil {
+		response.WriteJson(w, response.ErrorResponse("Unauthorized"))
+		return
+	}
+
+	provider := chi.URLParam(r, "provider")
+
+	res := path.service.BeginLogin(r.Context(), provider, &userInfo.UUID)
+	response.WriteJson(w, res)
+}
+
+// Callback is the redirect URL registered with the provider
+func (path *SSOPath) Callback(w http.ResponseWriter, r *http.Request) {
+	provider := chi.URLParam(r, "provider")
+	query := r.URL.Query()
+
+	if providerError := query.Get("error"); providerError != "" {
+		response.WriteJson(w, response.ErrorResponse("Login cancelled: "+providerError))
+		return
+	}
+
+	status := path.service.Callback(r.Context(), provider, query.Get("state"), query.Get("code"))
+	response.WriteJson(w, status)
+}
+
+func SSORoute() pathapi.PathComponent {
+	return &SSOPath{}
+}
@@ -0,0 +1,127 @@
+package calendar
+
+import (
+	"backend/internal/db/adapters/mysql"
+	"backend/internal/db/adapters/neo4j"
+	"backend/internal/db/repositories"
+	"backend/internal/ical"
+	"backend/internal/models"
+	"backend/internal/security"
+	"backend/internal/service/calendar"
+	response "backend/internal/utils/http"
+	"backend/routes/pathapi"
+	"github.com/go-chi/chi"
+	log "github.com/sirupsen/logrus"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// routePrefix is where this route is mounted, feed URLs are built from it
+const routePrefix = "/api/v1/calendar"
+
+type Path struct {
+	router  chi.Router
+	service *calendar.Service
+}
+
+func (path *Path) SetupComponents(sqlRepository *mysql.Repository, _ *neo4j.Repository) chi.Router {
+	r := chi.NewRouter()
+	path.router = r
+
+	repo, err := repositories.NewCalendarRepository(sqlRepository)
+	if err != nil {
+		log.Error("Failed to initialize CalendarRepository: ", err)
+		return nil
+	}
+
+	opportunityRepo, err := repositories.NewOpportunityRepository(sqlRepository)
+	if err != nil {
+		log.Error("Failed to initialize OpportunityRepository: ", err)
+		return nil
+	}
+
+	path.service = calendar.NewCalendarService(repo, opportunityRepo)
+
+	r.Get("/opportunities/{uuid}.ics", path.GetOpportunityCalendar)
+	r.Post("/feed", path.CreateFeed)
+	r.Delete("/feed", path.RevokeFeed)
+	r.Get("/feed/{token}.ics", path.GetFeed)
+	return r
+}
+
+func (path *Path) GetOpportunityCalendar(w http.ResponseWriter, r *http.Request) {
+	calendar, errorResponse := path.service.OpportunityCalendar(r.Context(), chi.URLParam(r, "uuid"))
+	if errorResponse != nil {
+		response.WriteJson(w, errorResponse)
+		return
+	}
+
+	w.Header().Set("Content-Disposition", `attachment; filename="`+chi.URLParam(r, "uuid")+`.ics"`)
+	writeCalendar(w, calendar)
+}
+
+// CreateFeed returns a new feed URL for the user, replacing their previous one
+func (path *Path) CreateFeed(w http.ResponseWriter, r *http.Request) {
+	userInfo := authenticated(w, r)
+	if userInfo == nil {
+		return
+	}
+
+	response.WriteJson(w, path.service.CreateFeed(r.Context(), userInfo, baseURL(r)))
+}
+
+func (path *Path) RevokeFeed(w http.ResponseWriter, r *http.Request) {
+	userInfo := authenticated(w, r)
+	if userInfo == nil {
+		return
+	}
+
+	response.WriteJson(w, path.service.RevokeFeed(r.Context(), userInfo))
+}
+
+// GetFeed is fetched by calendar apps, which can't send a JWT so the token in the URL authenticates it
+func (path *Path) GetFeed(w http.ResponseWriter, r *http.Request) {
+	calendar, errorResponse := path.service.Feed(r.Context(), chi.URLParam(r, "token"))
+	if errorResponse != nil {
+		response.WriteJson(w, errorResponse)
+		return
+	}
+
+	// The URL is a credential, keep it out of shared caches
+	w.Header().Set("Cache-Control", "private, max-age=300")
+	writeCalendar(w, calendar)
+}
+
+func writeCalendar(w http.ResponseWriter, calendar *ical.Calendar) {
+	w.Header().Set("Content-Type", ical.ContentType)
+	if _, err := calendar.WriteTo(w); err != nil {
+		log.Error("Failed to write calendar: ", err)
+	}
+}
+
+// baseURL is where this route is reachable from outside, PUBLIC_URL if set otherwise the request's host
+func baseURL(r *http.Request) string {
+	if public := os.Getenv("PUBLIC_URL"); public != "" {
+		return strings.TrimSuffix(public, "/") + routePrefix
+	}
+
+	scheme := "http"
+	if r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host + routePrefix
+}
+
+func authenticated(w http.ResponseWriter, r *http.Request) *models.UserInfoModel {
+	userInfo, err := security.ExtractUserInfoFromJWT(r)
+	if err != nil || userInfo == nil {
+		response.WriteJson(w, response.ErrorResponse("Unauthorized"))
+		return nil
+	}
+	return userInfo
+}
+
+func Route() pathapi.PathComponent {
+	return &Path{}
+}
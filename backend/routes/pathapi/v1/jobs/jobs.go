@@ -0,0 +1,54 @@
+package jobs
+
+import (
+	"backend/internal/db/adapters/mysql"
+	"backend/internal/db/adapters/neo4j"
+	"backend/internal/middleware"
+	"backend/internal/utils/concurrency"
+	response "backend/internal/utils/http"
+	"backend/routes/pathapi"
+	"errors"
+	"github.com/go-chi/chi"
+	"net/http"
+)
+
+type Path struct {
+	router    chi.Router
+	scheduler *concurrency.Scheduler
+}
+
+func (path *Path) SetupComponents(_ *mysql.Repository, _ *neo4j.Repository) chi.Router {
+	r := chi.NewRouter()
+	path.router = r
+	path.scheduler = concurrency.DefaultScheduler
+
+	r.Use(middleware.CheckIfAdminUser)
+
+	r.Get("/", path.GetJobs)
+	r.Post("/{name}/run", path.RunJob)
+	return r
+}
+
+// GetJobs lists the scheduled jobs with when they last and next run, as seen by this instance
+func (path *Path) GetJobs(w http.ResponseWriter, r *http.Request) {
+	response.WriteJson(w, response.SuccessResponse(path.scheduler.Statuses(), ""))
+}
+
+// RunJob starts the job now rather than waiting for its schedule
+func (path *Path) RunJob(w http.ResponseWriter, r *http.Request) {
+	err := path.scheduler.Trigger(chi.URLParam(r, "name"))
+	switch {
+	case errors.Is(err, concurrency.ErrJobNotFound):
+		response.WriteJson(w, response.ErrorResponse("Job not found"))
+	case errors.Is(err, concurrency.ErrJobRunning):
+		response.WriteJson(w, response.ErrorResponse("Job is already running"))
+	case err != nil:
+		response.WriteJson(w, response.ErrorResponse(err.Error()))
+	default:
+		response.WriteJson(w, response.SuccessResponse(nil, ""))
+	}
+}
+
+func Route() pathapi.PathComponent {
+	return &Path{}
+}
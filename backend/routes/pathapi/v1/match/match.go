@@ -20,7 +20,7 @@ type Path struct {
 func (path *Path) SetupComponents(sql *mysql.Repository, repo *neo4j.Repository) chi.Router {
 	r := chi.NewRouter()
 
-	repository, err := repositories.NewMatchesRepository(repo)
+	repository, err := repositories.NewMatchesRepository(repo, sql)
 	if err != nil {
 		log.Fatal("Failed to initialize Matchese Repo: ", err)
 		return nil
@@ -46,7 +46,7 @@ func (path *Path) CreateMatch(writer http.ResponseWriter, request *http.Request)
 	uuid1 := query.Get("uuid1")
 	uuid2 := query.Get("uuid2")
 
-	res := path.service.Match(uuid1, uuid2)
+	res := path.service.Match(request.Context(), uuid1, uuid2)
 	response.WriteJson(writer, res)
 
 }
@@ -54,7 +54,7 @@ func (path *Path) CreateMatch(writer http.ResponseWriter, request *http.Request)
 func (path *Path) GetMatches(writer http.ResponseWriter, request *http.Request) {
 	uuidStr := chi.URLParam(request, "userID")
 
-	res := path.service.GetMatches(uuidStr)
+	res := path.service.GetMatches(request.Context(), uuidStr)
 
 	response.WriteJson(writer, res)
 
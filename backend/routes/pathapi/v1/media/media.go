@@ -0,0 +1,128 @@
+package media
+
+import (
+	"backend/internal/db/adapters/mysql"
+	"backend/internal/db/adapters/neo4j"
+	"backend/internal/db/repositories"
+	"backend/internal/models"
+	"backend/internal/security"
+	"backend/internal/service/media"
+	response "backend/internal/utils/http"
+	"backend/routes/pathapi"
+	"errors"
+	"github.com/go-chi/chi"
+	log "github.com/sirupsen/logrus"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// uploadField is the multipart form field the file is sent in
+const uploadField = "file"
+
+// multipartOverhead allows for the multipart headers and boundaries around the largest file
+const multipartOverhead = 1 << 20
+
+// processingWorkers is how many images are resized at once, decoding a camera photo takes a lot of memory
+const processingWorkers = 2
+
+type Path struct {
+	router  chi.Router
+	service *media.Service
+}
+
+func (path *Path) SetupComponents(sqlRepository *mysql.Repository, _ *neo4j.Repository) chi.Router {
+	r := chi.NewRouter()
+	path.router = r
+
+	repo, err := repositories.NewMediaRepository(sqlRepository)
+	if err != nil {
+		log.Error("Failed to initialize MediaRepository: ", err)
+		return nil
+	}
+
+	path.service = media.NewMediaService(repo)
+	path.service.StartProcessing(processingWorkers)
+
+	r.Post("/", path.Upload)
+	r.Get("/{uuid}", path.Download)
+	return r
+}
+
+// Upload accepts a multipart form with the file in the "file" field. The file is streamed rather than
+// buffered so large videos don't sit in memory
+func (path *Path) Upload(w http.ResponseWriter, r *http.Request) {
+	userInfo := authenticated(w, r)
+	if userInfo == nil {
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, media.MaxUploadSize+multipartOverhead)
+
+	reader, err := r.MultipartReader()
+	if err != nil {
+		response.WriteJson(w, response.ErrorResponse("Expected a multipart form"))
+		return
+	}
+
+	for {
+		part, err := reader.NextPart()
+		if errors.Is(err, io.EOF) {
+			response.WriteJson(w, response.ErrorResponse("No file provided"))
+			return
+		}
+		if err != nil {
+			response.WriteJson(w, response.ErrorResponse("Invalid multipart form"))
+			return
+		}
+
+		if part.FormName() == uploadField {
+			response.WriteJson(w, path.service.Upload(r.Context(), userInfo, part))
+			return
+		}
+	}
+}
+
+// Download serves media or one of its variants from a signed URL, supporting range requests so videos can be streamed
+func (path *Path) Download(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	file, content, errorResponse := path.service.Open(r.Context(), chi.URLParam(r, "uuid"), query.Get("variant"),
+		query.Get("expires"), query.Get("signature"))
+	if errorResponse != nil {
+		response.WriteJson(w, errorResponse)
+		return
+	}
+	defer content.Close()
+
+	w.Header().Set("Content-Type", file.ContentType)
+	// The content never changes for an ID, but the URL is a credential so only the client may cache it
+	w.Header().Set("Cache-Control", "private, max-age=3600, immutable")
+	w.Header().Set("ETag", strconv.Quote(file.SHA256))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Content-Security-Policy", "default-src 'none'; sandbox")
+
+	if seeker, ok := content.(io.ReadSeeker); ok {
+		http.ServeContent(w, r, "", file.CreatedAt.Truncate(time.Second), seeker)
+		return
+	}
+
+	w.Header().Set("Content-Length", strconv.FormatInt(file.Size, 10))
+	if _, err := io.Copy(w, content); err != nil {
+		log.Error("Failed to serve media: ", err)
+	}
+}
+
+func authenticated(w http.ResponseWriter, r *http.Request) *models.UserInfoModel {
+	userInfo, err := security.ExtractUserInfoFromJWT(r)
+	if err != nil || userInfo == nil {
+		response.WriteJson(w, response.ErrorResponse("Unauthorized"))
+		return nil
+	}
+	return userInfo
+}
+
+func Route() pathapi.PathComponent {
+	return &Path{}
+}
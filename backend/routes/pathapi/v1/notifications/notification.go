@@ -0,0 +1,65 @@
+package notifications
+
+import (
+	"backend/internal/db/adapters/mysql"
+	"backend/internal/db/adapters/neo4j"
+	"backend/internal/db/repositories"
+	"backend/internal/events"
+	"backend/internal/security"
+	"backend/internal/service/notification"
+	response "backend/internal/utils/http"
+	"backend/routes/pathapi"
+	"net/http"
+
+	"github.com/go-chi/chi"
+	log "github.com/sirupsen/logrus"
+)
+
+type Path struct {
+	router  chi.Router
+	service *notification.Service
+}
+
+func (path *Path) SetupComponents(sqlRepository *mysql.Repository, _ *neo4j.Repository) chi.Router {
+	r := chi.NewRouter()
+	path.router = r
+
+	repo, err := repositories.NewNotificationRepository(sqlRepository)
+	if err != nil {
+		log.Error("Failed to initialize NotificationRepository: ", err)
+		return nil
+	}
+
+	path.service = notification.NewNotificationService(repo)
+	path.service.Subscribe(events.Default)
+
+	r.Get("/", path.GetNotifications)
+	r.Put("/{notificationID}/read", path.MarkRead)
+	return r
+}
+
+// GetNotifications accepts an optional ?limit=
+func (path *Path) GetNotifications(w http.ResponseWriter, r *http.Request) {
+	userInfo, err := security.ExtractUserInfoFromJWT(r)
+	if err != nil {
+		response.WriteJson(w, response.ErrorResponse("Unauthorized"))
+		return
+	}
+
+	response.WriteJson(w, path.service.GetNotifications(r.Context(), userInfo, r.URL.Query().Get("limit")))
+}
+
+func (path *Path) MarkRead(w http.ResponseWriter, r *http.Request) {
+	userInfo, err := security.ExtractUserInfoFromJWT(r)
+	if err != nil {
+		response.WriteJson(w, response.ErrorResponse("Unauthorized"))
+		return
+	}
+
+	notificationID := chi.URLParam(r, "notificationID")
+	response.WriteJson(w, path.service.MarkRead(r.Context(), userInfo, notificationID))
+}
+
+func Route() pathapi.PathComponent {
+	return &Path{}
+}
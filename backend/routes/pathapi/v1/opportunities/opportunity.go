@@ -1,20 +1,35 @@
 package opportunities
 
 import (
+	"backend/internal/bulkimport"
 	"backend/internal/db/adapters/mysql"
 	"backend/internal/db/adapters/neo4j"
 	"backend/internal/db/repositories"
+	"backend/internal/geo"
 	"backend/internal/middleware"
 	"backend/internal/models"
+	"backend/internal/security"
+	"backend/internal/service/media"
 	"backend/internal/service/opportunity"
+	"backend/internal/utils/concurrency"
 	response "backend/internal/utils/http"
 	"backend/routes/pathapi"
+	"context"
 	"encoding/json"
 	"github.com/go-chi/chi"
 	"github.com/google/uuid"
 	log "github.com/sirupsen/logrus"
 	"net/http"
+	"os"
 	"strconv"
+	"time"
+)
+
+const (
+	// autoCloseInterval is how often opportunities past their deadline or last occurrence are closed
+	autoCloseInterval = time.Minute
+	// maxImportSize comfortably fits bulkimport.MaxRows rows with long descriptions
+	maxImportSize = 10 << 20
 )
 
 type Path struct {
@@ -30,10 +45,35 @@ func (path *Path) SetupComponents(repo *mysql.Repository, _ *neo4j.Repository) c
 		log.Fatal("Failed to initialize OpportunityRepository: ", err)
 	}
 
-	path.service = opportunity.NewOpportunityService(repository)
+	organisationRepository, err := repositories.NewOrganisationRepository(repo)
+	if err != nil {
+		log.Fatal("Failed to initialize OrganisationRepository: ", err)
+	}
+
+	searchRepository, err := repositories.NewSearchRepository(repo)
+	if err != nil {
+		log.Fatal("Failed to initialize SearchRepository: ", err)
+	}
+
+	applicationRepository, err := repositories.NewApplicationRepository(repo)
+	if err != nil {
+		log.Fatal("Failed to initialize ApplicationRepository: ", err)
+	}
+
+	mediaRepository, err := repositories.NewMediaRepository(repo)
+	if err != nil {
+		log.Fatal("Failed to initialize MediaRepository: ", err)
+	}
+
+	path.service = opportunity.NewOpportunityService(repository, organisationRepository, applicationRepository, searchRepository, geocoder(), media.NewMediaService(mediaRepository))
+	if err := concurrency.AddJob(path.service.AutoCloseJob(autoCloseInterval)); err != nil {
+		log.Error("Failed to schedule closing expired opportunities: ", err)
+	}
 
 	r.Get("/", path.GetOpportunities)
+	r.Get("/search", path.Search)
 	r.Post("/", path.CreateOpportunity)
+	r.Post("/import", path.Import)
 	r.Put("/", path.UpdateOpportunity)
 	r.Delete("/{uuid}", path.DeleteOpportunity)
 	r.Get("/{uuid}", path.GetByUUID)
@@ -44,6 +84,16 @@ func (path *Path) SetupComponents(repo *mysql.Repository, _ *neo4j.Repository) c
 	r.Delete("/dislikes/{userID}/{postID}", path.DeleteDislikeOpportunity)
 	r.With(middleware.CheckIfAdminUser).Put("/status", path.UpdateOpportunityStatus)
 	r.Get("/author/{authorID}", path.GetOpportunitiesByAuthor)
+	r.Get("/organisation/{organisationID}", path.GetOpportunitiesByOrganisation)
+	r.Get("/{uuid}/occurrences", path.GetOccurrences)
+	r.Post("/{uuid}/applications", path.Apply)
+	r.Delete("/{uuid}/applications", path.CancelApplication)
+	r.Get("/{uuid}/applications", path.GetApplications)
+	r.Post("/{uuid}/submit", path.SubmitDraft)
+	r.Get("/{uuid}/revisions", path.GetRevisions)
+	r.Get("/{uuid}/revisions/diff", path.DiffRevisions)
+	r.Get("/{uuid}/revisions/{revision}", path.GetRevision)
+	r.With(middleware.CheckIfAdminUser).Post("/{uuid}/revisions/{revision}/rollback", path.Rollback)
 
 	path.router = r
 	return r
@@ -56,7 +106,7 @@ func (path *Path) CreateOpportunity(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	status := path.service.CreateOpportunity(req)
+	status := path.service.CreateOpportunity(r.Context(), req)
 	response.WriteJson(w, status)
 }
 
@@ -66,7 +116,7 @@ func (path *Path) UpdateOpportunity(writer http.ResponseWriter, request *http.Re
 		response.WriteJson(writer, response.ErrorResponse("Invalid request body"))
 		return
 	}
-	res := path.service.UpdateOpportunity(req)
+	res := path.service.UpdateOpportunity(request.Context(), req)
 	response.WriteJson(writer, res)
 }
 
@@ -75,18 +125,25 @@ func (path *Path) GetOpportunities(w http.ResponseWriter, r *http.Request) {
 
 	switch {
 	case query.Has("from") && query.Has("limit") && query.Has("uuid"):
-		path.getPaginated(w, query.Get("from"), query.Get("limit"), query.Get("uuid"))
+		path.getPaginated(r.Context(), w, query.Get("from"), query.Get("limit"), query.Get("uuid"))
+	case opportunity.IsFilterQuery(query):
+		response.WriteJson(w, path.service.GetOpportunitiesFiltered(r.Context(), query))
 	case query.Has("tag"):
-		path.getByTag(w, query.Get("tag"))
+		path.getByTag(r.Context(), w, query.Get("tag"))
 	case query.Has("uuid"):
-		path.getByUUID(w, query.Get("uuid"))
+		path.getByUUID(r.Context(), w, query.Get("uuid"))
 	default:
-		response.WriteJson(w, response.ErrorResponse("Missing query: expected ?tag=, ?uuid=, or ?from=&limit="))
+		// No query lists the newest opportunities
+		response.WriteJson(w, path.service.GetOpportunitiesFiltered(r.Context(), query))
 	}
 }
 
-func (path *Path) getByTag(w http.ResponseWriter, tag string) {
-	opportunities, err := path.service.GetOpportunitiesByTag(tag)
+func (path *Path) getByTag(ctx context.Context, w http.ResponseWriter, tag string) {
+	opportunities, err := path.service.GetOpportunitiesByTag(ctx, tag)
+	if errorResponse := response.ContextError(err); errorResponse != nil {
+		response.WriteJson(w, errorResponse)
+		return
+	}
 	if err != nil {
 		log.Error("GetOpportunitiesByTag error: ", err)
 		response.WriteJson(w, response.ErrorResponse("Internal error occurred"))
@@ -102,17 +159,21 @@ func (path *Path) getByTag(w http.ResponseWriter, tag string) {
 
 func (path *Path) GetByUUID(writer http.ResponseWriter, request *http.Request) {
 	uuidStr := chi.URLParam(request, "uuid")
-	path.getByUUID(writer, uuidStr)
+	path.getByUUID(request.Context(), writer, uuidStr)
 }
 
-func (path *Path) getByUUID(w http.ResponseWriter, uuidStr string) {
+func (path *Path) getByUUID(ctx context.Context, w http.ResponseWriter, uuidStr string) {
 	id, err := uuid.Parse(uuidStr)
 	if err != nil {
 		response.WriteJson(w, response.ErrorResponse("Invalid UUID format"))
 		return
 	}
 
-	opp, err := path.service.GetOpportunity(id)
+	opp, err := path.service.GetOpportunity(ctx, id)
+	if errorResponse := response.ContextError(err); errorResponse != nil {
+		response.WriteJson(w, errorResponse)
+		return
+	}
 	if err != nil {
 		log.Error("GetOpportunity error: ", err)
 		response.WriteJson(w, response.ErrorResponse("Internal error occurred"))
@@ -126,7 +187,7 @@ func (path *Path) getByUUID(w http.ResponseWriter, uuidStr string) {
 	response.WriteJson(w, response.SuccessResponse([]models.OpportunityModel{*opp}, ""))
 }
 
-func (path *Path) getPaginated(w http.ResponseWriter, fromStr, limitStr, userID string) {
+func (path *Path) getPaginated(ctx context.Context, w http.ResponseWriter, fromStr, limitStr, userID string) {
 	from := fromStr
 	limit, err := strconv.Atoi(limitStr)
 	if err != nil || limit <= 0 {
@@ -140,7 +201,11 @@ func (path *Path) getPaginated(w http.ResponseWriter, fromStr, limitStr, userID
 		return
 	}
 
-	opportunities, lastIndex, err := path.service.GetOpportunitiesFrom(from, limitStr, userUUID)
+	opportunities, lastIndex, err := path.service.GetOpportunitiesFrom(ctx, from, limitStr, userUUID)
+	if errorResponse := response.ContextError(err); errorResponse != nil {
+		response.WriteJson(w, errorResponse)
+		return
+	}
 	if err != nil {
 		log.Error("Pagination error: ", err)
 		response.WriteJson(w, response.ErrorResponse("Failed to retrieve opportunities"))
@@ -168,7 +233,11 @@ func (path *Path) DeleteOpportunity(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := path.service.DeleteOpportunity(id); err != nil {
+	if err := path.service.DeleteOpportunity(r.Context(), id); err != nil {
+		if errorResponse := response.ContextError(err); errorResponse != nil {
+			response.WriteJson(w, errorResponse)
+			return
+		}
 		log.Error("DeleteOpportunity error: ", err)
 		response.WriteJson(w, response.ErrorResponse("Internal error occurred"))
 		return
@@ -179,28 +248,28 @@ func (path *Path) DeleteOpportunity(w http.ResponseWriter, r *http.Request) {
 func (path *Path) LikeOpportunity(w http.ResponseWriter, r *http.Request) {
 	userID := chi.URLParam(r, "userID")
 	postID := chi.URLParam(r, "postID")
-	res := path.service.LikeOpportunity(userID, postID)
+	res := path.service.LikeOpportunity(r.Context(), userID, postID)
 	response.WriteJson(w, res)
 }
 
 func (path *Path) DeleteLikeOpportunity(w http.ResponseWriter, r *http.Request) {
 	userID := chi.URLParam(r, "userID")
 	postID := chi.URLParam(r, "postID")
-	res := path.service.DeleteLikeOpportunity(userID, postID)
+	res := path.service.DeleteLikeOpportunity(r.Context(), userID, postID)
 	response.WriteJson(w, res)
 }
 
 func (path *Path) DislikeOpportunity(w http.ResponseWriter, r *http.Request) {
 	userID := chi.URLParam(r, "userID")
 	postID := chi.URLParam(r, "postID")
-	res := path.service.DislikeOpportunity(userID, postID)
+	res := path.service.DislikeOpportunity(r.Context(), userID, postID)
 	response.WriteJson(w, res)
 }
 
 func (path *Path) DeleteDislikeOpportunity(w http.ResponseWriter, r *http.Request) {
 	userID := chi.URLParam(r, "userID")
 	postID := chi.URLParam(r, "postID")
-	res := path.service.DeleteDislikeOpportunity(userID, postID)
+	res := path.service.DeleteDislikeOpportunity(r.Context(), userID, postID)
 	response.WriteJson(w, res)
 }
 
@@ -209,7 +278,7 @@ func (path *Path) GetOpportunityLikes(writer http.ResponseWriter, request *http.
 	query := request.URL.Query()
 	from := query.Get("from")
 	limit := query.Get("limit")
-	res := path.service.GetOpportunityByLikes(postID, from, limit) //TODO rename
+	res := path.service.GetOpportunityByLikes(request.Context(), postID, from, limit) //TODO rename
 
 	response.WriteJson(writer, res)
 
@@ -221,7 +290,7 @@ func (path *Path) UpdateOpportunityStatus(writer http.ResponseWriter, request *h
 	opportunityUUID := query.Get("uuid")
 	status := query.Get("status")
 
-	res := path.service.UpdateStatus(opportunityUUID, status)
+	res := path.service.UpdateStatus(request.Context(), opportunityUUID, status)
 
 	response.WriteJson(writer, res)
 }
@@ -229,11 +298,173 @@ func (path *Path) UpdateOpportunityStatus(writer http.ResponseWriter, request *h
 func (path *Path) GetOpportunitiesByAuthor(writer http.ResponseWriter, request *http.Request) {
 	userID := chi.URLParam(request, "authorID")
 
-	res := path.service.GetOpportunitiesByAuthor(userID)
+	res := path.service.GetOpportunitiesByAuthor(request.Context(), userID)
+
+	response.WriteJson(writer, res)
+}
+
+func (path *Path) GetOpportunitiesByOrganisation(writer http.ResponseWriter, request *http.Request) {
+	organisationID := chi.URLParam(request, "organisationID")
+
+	res := path.service.GetOpportunitiesByOrganisation(request.Context(), organisationID)
+
+	response.WriteJson(writer, res)
+}
+
+// Search expects ?q= and an optional ?limit=
+func (path *Path) Search(writer http.ResponseWriter, request *http.Request) {
+	query := request.URL.Query()
+
+	res := path.service.Search(request.Context(), query.Get("q"), query.Get("limit"))
+
+	response.WriteJson(writer, res)
+}
+
+// GetOccurrences expects optional ?from=, ?to= and ?limit=
+func (path *Path) GetOccurrences(writer http.ResponseWriter, request *http.Request) {
+	query := request.URL.Query()
+
+	res := path.service.GetOccurrences(request.Context(), chi.URLParam(request, "uuid"), query.Get("from"), query.Get("to"), query.Get("limit"))
 
 	response.WriteJson(writer, res)
 }
 
+func (path *Path) Apply(writer http.ResponseWriter, request *http.Request) {
+	user := authenticated(writer, request)
+	if user == nil {
+		return
+	}
+
+	response.WriteJson(writer, path.service.Apply(request.Context(), chi.URLParam(request, "uuid"), user))
+}
+
+func (path *Path) CancelApplication(writer http.ResponseWriter, request *http.Request) {
+	user := authenticated(writer, request)
+	if user == nil {
+		return
+	}
+
+	response.WriteJson(writer, path.service.CancelApplication(request.Context(), chi.URLParam(request, "uuid"), user))
+}
+
+func (path *Path) GetApplications(writer http.ResponseWriter, request *http.Request) {
+	user := authenticated(writer, request)
+	if user == nil {
+		return
+	}
+
+	response.WriteJson(writer, path.service.GetApplications(request.Context(), chi.URLParam(request, "uuid"), user))
+}
+
+// SubmitDraft submits a draft for review
+func (path *Path) SubmitDraft(writer http.ResponseWriter, request *http.Request) {
+	user := authenticated(writer, request)
+	if user == nil {
+		return
+	}
+
+	response.WriteJson(writer, path.service.SubmitDraft(request.Context(), chi.URLParam(request, "uuid"), user))
+}
+
+func (path *Path) GetRevisions(writer http.ResponseWriter, request *http.Request) {
+	user := authenticated(writer, request)
+	if user == nil {
+		return
+	}
+
+	response.WriteJson(writer, path.service.GetRevisions(request.Context(), chi.URLParam(request, "uuid"), user))
+}
+
+func (path *Path) GetRevision(writer http.ResponseWriter, request *http.Request) {
+	user := authenticated(writer, request)
+	if user == nil {
+		return
+	}
+
+	response.WriteJson(writer, path.service.GetRevision(request.Context(), chi.URLParam(request, "uuid"), chi.URLParam(request, "revision"), user))
+}
+
+// DiffRevisions expects ?from= and an optional ?to=, which defaults to the latest revision
+func (path *Path) DiffRevisions(writer http.ResponseWriter, request *http.Request) {
+	user := authenticated(writer, request)
+	if user == nil {
+		return
+	}
+
+	query := request.URL.Query()
+	response.WriteJson(writer, path.service.DiffRevisions(request.Context(), chi.URLParam(request, "uuid"), query.Get("from"), query.Get("to"), user))
+}
+
+// Rollback restores an earlier revision, admins only
+func (path *Path) Rollback(writer http.ResponseWriter, request *http.Request) {
+	user := authenticated(writer, request)
+	if user == nil {
+		return
+	}
+
+	response.WriteJson(writer, path.service.Rollback(request.Context(), chi.URLParam(request, "uuid"), chi.URLParam(request, "revision"), user))
+}
+
+// Import creates or updates opportunities from a CSV or JSON Lines body. The format comes from ?format= or
+// the Content-Type, nothing is saved unless ?commit=true and ?organisation= posts for an organisation
+func (path *Path) Import(writer http.ResponseWriter, request *http.Request) {
+	user := authenticated(writer, request)
+	if user == nil {
+		return
+	}
+
+	query := request.URL.Query()
+	format, err := bulkimport.ParseFormat(query.Get("format"), "", request.Header.Get("Content-Type"))
+	if err != nil {
+		response.WriteJson(writer, response.ErrorResponse(err.Error()))
+		return
+	}
+	commit, _ := strconv.ParseBool(query.Get("commit"))
+
+	request.Body = http.MaxBytesReader(writer, request.Body, maxImportSize)
+	rows, rowErrors, err := bulkimport.Parse(format, request.Body)
+	if err != nil {
+		response.WriteJson(writer, response.ErrorResponse(err.Error()))
+		return
+	}
+
+	report, err := path.service.ImportOpportunities(request.Context(), rows, rowErrors, user.UUID, query.Get("organisation"), !commit)
+	if err != nil {
+		response.WriteJson(writer, response.ErrorResponse(err.Error()))
+		return
+	}
+	response.WriteJson(writer, response.SuccessResponse(report, ""))
+}
+
+func authenticated(w http.ResponseWriter, r *http.Request) *models.UserInfoModel {
+	userInfo, err := security.ExtractUserInfoFromJWT(r)
+	if err != nil || userInfo == nil {
+		response.WriteJson(w, response.ErrorResponse("Unauthorized"))
+		return nil
+	}
+	return userInfo
+}
+
 func OpportunityRoute() pathapi.PathComponent {
 	return &Path{}
 }
+
+// geocoder loads the gazetteer from GEOCODER_GAZETTEER if set, falling back to the built in UK places
+func geocoder() geo.Geocoder {
+	file := os.Getenv("GEOCODER_GAZETTEER")
+	if file == "" {
+		return geo.DefaultGazetteer()
+	}
+
+	reader, err := os.Open(file)
+	if err != nil {
+		log.Fatal("Failed to open gazetteer: ", err)
+	}
+	defer reader.Close()
+
+	gazetteer, err := geo.LoadGazetteer(reader)
+	if err != nil {
+		log.Fatal("Failed to load gazetteer: ", err)
+	}
+	return gazetteer
+}
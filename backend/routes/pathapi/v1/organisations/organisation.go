@@ -0,0 +1,260 @@
+package organisations
+
+import (
+	"backend/internal/db/adapters/mysql"
+	"backend/internal/db/adapters/neo4j"
+	"backend/internal/db/repositories"
+	"backend/internal/middleware"
+	"backend/internal/models"
+	"backend/internal/security"
+	"backend/internal/service/media"
+	"backend/internal/service/organisation"
+	response "backend/internal/utils/http"
+	"backend/routes/pathapi"
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi"
+	log "github.com/sirupsen/logrus"
+)
+
+type Path struct {
+	router  chi.Router
+	service *organisation.Service
+}
+
+func (path *Path) SetupComponents(sqlRepository *mysql.Repository, _ *neo4j.Repository) chi.Router {
+	r := chi.NewRouter()
+	path.router = r
+
+	userRepo, err := repositories.NewUserRepository(sqlRepository)
+	if err != nil {
+		return nil
+	}
+
+	organisationRepo, err := repositories.NewOrganisationRepository(sqlRepository)
+	if err != nil {
+		log.Error("Failed to initialize OrganisationRepository: ", err)
+		return nil
+	}
+
+	opportunityRepo, err := repositories.NewOpportunityRepository(sqlRepository)
+	if err != nil {
+		log.Error("Failed to initialize OpportunityRepository: ", err)
+		return nil
+	}
+
+	mediaRepo, err := repositories.NewMediaRepository(sqlRepository)
+	if err != nil {
+		log.Error("Failed to initialize MediaRepository: ", err)
+		return nil
+	}
+
+	path.service = organisation.NewOrganisationService(organisationRepo, userRepo, opportunityRepo, media.NewMediaService(mediaRepo))
+
+	r.Post("/", path.CreateOrganisation)
+	r.Get("/mine", path.GetMyOrganisations)
+	r.Get("/following", path.GetFollowing)
+	r.Post("/invites/{token}/accept", path.AcceptInvite)
+	r.Get("/{organisationID}", path.GetOrganisation)
+	r.Put("/{organisationID}", path.UpdateOrganisation)
+	r.Get("/{organisationID}/profile", path.GetProfile)
+	r.Post("/{organisationID}/follow", path.Follow)
+	r.Delete("/{organisationID}/follow", path.Unfollow)
+	r.Get("/{organisationID}/reviews", path.GetReviews)
+	r.Post("/{organisationID}/reviews", path.AddReview)
+	r.Get("/{organisationID}/members", path.GetMembers)
+	r.Put("/{organisationID}/members/{userID}", path.UpdateMemberRole)
+	r.Delete("/{organisationID}/members/{userID}", path.RemoveMember)
+	r.Post("/{organisationID}/invites", path.CreateInvite)
+	r.With(middleware.CheckIfAdminUser).Put("/{organisationID}/verification", path.UpdateVerification)
+	return r
+}
+
+func (path *Path) CreateOrganisation(w http.ResponseWriter, r *http.Request) {
+	userInfo := authenticated(w, r)
+	if userInfo == nil {
+		return
+	}
+
+	var req models.CreateOrganisationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.WriteJson(w, response.ErrorResponse("Invalid request body"))
+		return
+	}
+
+	response.WriteJson(w, path.service.CreateOrganisation(r.Context(), userInfo, req))
+}
+
+func (path *Path) GetOrganisation(w http.ResponseWriter, r *http.Request) {
+	organisationID := chi.URLParam(r, "organisationID")
+	response.WriteJson(w, path.service.GetOrganisation(r.Context(), organisationID))
+}
+
+func (path *Path) UpdateOrganisation(w http.ResponseWriter, r *http.Request) {
+	userInfo := authenticated(w, r)
+	if userInfo == nil {
+		return
+	}
+
+	var req models.CreateOrganisationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.WriteJson(w, response.ErrorResponse("Invalid request body"))
+		return
+	}
+
+	organisationID := chi.URLParam(r, "organisationID")
+	response.WriteJson(w, path.service.UpdateOrganisation(r.Context(), userInfo, organisationID, req))
+}
+
+func (path *Path) GetMyOrganisations(w http.ResponseWriter, r *http.Request) {
+	userInfo := authenticated(w, r)
+	if userInfo == nil {
+		return
+	}
+
+	response.WriteJson(w, path.service.GetMyOrganisations(r.Context(), userInfo))
+}
+
+func (path *Path) GetMembers(w http.ResponseWriter, r *http.Request) {
+	userInfo := authenticated(w, r)
+	if userInfo == nil {
+		return
+	}
+
+	organisationID := chi.URLParam(r, "organisationID")
+	response.WriteJson(w, path.service.GetMembers(r.Context(), userInfo, organisationID))
+}
+
+// UpdateMemberRole expects ?role=viewer|editor|owner
+func (path *Path) UpdateMemberRole(w http.ResponseWriter, r *http.Request) {
+	userInfo := authenticated(w, r)
+	if userInfo == nil {
+		return
+	}
+
+	organisationID := chi.URLParam(r, "organisationID")
+	memberID := chi.URLParam(r, "userID")
+	role := r.URL.Query().Get("role")
+
+	response.WriteJson(w, path.service.UpdateMemberRole(r.Context(), userInfo, organisationID, memberID, role))
+}
+
+func (path *Path) RemoveMember(w http.ResponseWriter, r *http.Request) {
+	userInfo := authenticated(w, r)
+	if userInfo == nil {
+		return
+	}
+
+	organisationID := chi.URLParam(r, "organisationID")
+	memberID := chi.URLParam(r, "userID")
+
+	response.WriteJson(w, path.service.RemoveMember(r.Context(), userInfo, organisationID, memberID))
+}
+
+func (path *Path) CreateInvite(w http.ResponseWriter, r *http.Request) {
+	userInfo := authenticated(w, r)
+	if userInfo == nil {
+		return
+	}
+
+	var req models.CreateInviteRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.WriteJson(w, response.ErrorResponse("Invalid request body"))
+		return
+	}
+
+	organisationID := chi.URLParam(r, "organisationID")
+	response.WriteJson(w, path.service.CreateInvite(r.Context(), userInfo, organisationID, req))
+}
+
+func (path *Path) AcceptInvite(w http.ResponseWriter, r *http.Request) {
+	userInfo := authenticated(w, r)
+	if userInfo == nil {
+		return
+	}
+
+	token := chi.URLParam(r, "token")
+	response.WriteJson(w, path.service.AcceptInvite(r.Context(), userInfo, token))
+}
+
+// UpdateVerification expects ?status=pending|verified|rejected
+func (path *Path) UpdateVerification(w http.ResponseWriter, r *http.Request) {
+	organisationID := chi.URLParam(r, "organisationID")
+	status := r.URL.Query().Get("status")
+
+	response.WriteJson(w, path.service.UpdateVerificationStatus(r.Context(), organisationID, status))
+}
+
+func (path *Path) GetProfile(w http.ResponseWriter, r *http.Request) {
+	organisationID := chi.URLParam(r, "organisationID")
+	response.WriteJson(w, path.service.GetProfile(r.Context(), organisationID))
+}
+
+// Follow accepts ?notify=true to be notified of new opportunities
+func (path *Path) Follow(w http.ResponseWriter, r *http.Request) {
+	userInfo := authenticated(w, r)
+	if userInfo == nil {
+		return
+	}
+
+	organisationID := chi.URLParam(r, "organisationID")
+	notify, _ := strconv.ParseBool(r.URL.Query().Get("notify"))
+
+	response.WriteJson(w, path.service.Follow(r.Context(), userInfo, organisationID, notify))
+}
+
+func (path *Path) Unfollow(w http.ResponseWriter, r *http.Request) {
+	userInfo := authenticated(w, r)
+	if userInfo == nil {
+		return
+	}
+
+	organisationID := chi.URLParam(r, "organisationID")
+	response.WriteJson(w, path.service.Unfollow(r.Context(), userInfo, organisationID))
+}
+
+func (path *Path) GetFollowing(w http.ResponseWriter, r *http.Request) {
+	userInfo := authenticated(w, r)
+	if userInfo == nil {
+		return
+	}
+
+	response.WriteJson(w, path.service.GetFollowing(r.Context(), userInfo))
+}
+
+func (path *Path) GetReviews(w http.ResponseWriter, r *http.Request) {
+	organisationID := chi.URLParam(r, "organisationID")
+	response.WriteJson(w, path.service.GetReviews(r.Context(), organisationID, r.URL.Query().Get("limit")))
+}
+
+func (path *Path) AddReview(w http.ResponseWriter, r *http.Request) {
+	userInfo := authenticated(w, r)
+	if userInfo == nil {
+		return
+	}
+
+	var req models.CreateReviewRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.WriteJson(w, response.ErrorResponse("Invalid request body"))
+		return
+	}
+
+	organisationID := chi.URLParam(r, "organisationID")
+	response.WriteJson(w, path.service.AddReview(r.Context(), userInfo, organisationID, req))
+}
+
+// authenticated returns the user from the JWT or writes an error response and returns nil
+func authenticated(w http.ResponseWriter, r *http.Request) *models.UserInfoModel {
+	userInfo, err := security.ExtractUserInfoFromJWT(r)
+	if err != nil || userInfo == nil {
+		response.WriteJson(w, response.ErrorResponse("Unauthorized"))
+		return nil
+	}
+	return userInfo
+}
+
+func Route() pathapi.PathComponent {
+	return &Path{}
+}
@@ -6,6 +6,7 @@ import (
 	"backend/internal/db/repositories"
 	"backend/internal/models"
 	"backend/internal/security"
+	"backend/internal/service/media"
 	"backend/internal/service/student"
 	response "backend/internal/utils/http"
 	"backend/routes/pathapi"
@@ -30,7 +31,12 @@ func (path *Path) SetupComponents(sqlRepository *mysql.Repository, _ *neo4j.Repo
 	if err != nil {
 		return nil
 	}
-	path.service = student.NewStudentService(repository)
+
+	mediaRepository, err := repositories.NewMediaRepository(sqlRepository)
+	if err != nil {
+		return nil
+	}
+	path.service = student.NewStudentService(repository, media.NewMediaService(mediaRepository))
 	return r
 }
 
@@ -41,19 +47,25 @@ func (path *Path) GetCurrentUserInfo(writer http.ResponseWriter, request *http.R
 		return
 	}
 
-	returnMessage := path.service.GetStudentInfoByUUID(userInfo.UUID)
+	returnMessage := path.service.GetStudentInfoByUUID(request.Context(), userInfo.UUID)
 
 	response.WriteJson(writer, returnMessage)
 }
 
 func (path *Path) UpdateCurrentUserInfo(writer http.ResponseWriter, request *http.Request) {
+	userInfo, err := security.ExtractUserInfoFromJWT(request)
+	if err != nil {
+		response.WriteJson(writer, response.ErrorResponse("Unauthorized"))
+		return
+	}
+
 	var studentInfo models.StudentInfoModel
 	if err := json.NewDecoder(request.Body).Decode(&studentInfo); err != nil {
 		response.WriteJson(writer, response.ErrorResponse("Invalid request body"))
 		return
 	}
 
-	returnMessage := path.service.UpdateStudentInfo(studentInfo)
+	returnMessage := path.service.UpdateStudentInfo(request.Context(), userInfo, studentInfo)
 
 	response.WriteJson(writer, returnMessage)
 }
@@ -61,7 +73,7 @@ func (path *Path) UpdateCurrentUserInfo(writer http.ResponseWriter, request *htt
 func (path *Path) GetUser(writer http.ResponseWriter, request *http.Request) {
 	userID := chi.URLParam(request, "userID")
 
-	res := path.service.GetStudentInfo(userID)
+	res := path.service.GetStudentInfo(request.Context(), userID)
 	response.WriteJson(writer, res)
 }
 
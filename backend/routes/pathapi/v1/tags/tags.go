@@ -0,0 +1,180 @@
+package tags
+
+import (
+	"backend/internal/db/adapters/mysql"
+	"backend/internal/db/adapters/neo4j"
+	"backend/internal/db/repositories"
+	"backend/internal/events"
+	"backend/internal/middleware"
+	"backend/internal/models"
+	"backend/internal/service/tag"
+	"backend/internal/utils/concurrency"
+	response "backend/internal/utils/http"
+	"backend/routes/pathapi"
+	"encoding/json"
+	"github.com/go-chi/chi"
+	log "github.com/sirupsen/logrus"
+	"net/http"
+)
+
+type Path struct {
+	router  chi.Router
+	service *tag.Service
+}
+
+func (path *Path) SetupComponents(sqlRepository *mysql.Repository, _ *neo4j.Repository) chi.Router {
+	r := chi.NewRouter()
+	path.router = r
+
+	repo, err := repositories.NewTaxonomyRepository(sqlRepository)
+	if err != nil {
+		log.Error("Failed to initialize TaxonomyRepository: ", err)
+		return nil
+	}
+
+	path.service = tag.NewTagService(repo)
+	path.service.Subscribe(events.Default)
+	if err := concurrency.AddJob(path.service.IndexJob()); err != nil {
+		log.Error("Failed to schedule rebuilding the tag index: ", err)
+	}
+
+	r.Get("/", path.GetTags)
+	r.Get("/categories", path.GetCategories)
+	r.Get("/autocomplete", path.Autocomplete)
+	r.Post("/suggest", path.SuggestTags)
+
+	r.Group(func(admin chi.Router) {
+		admin.Use(middleware.CheckIfAdminUser)
+
+		admin.Get("/pending", path.GetPendingTags)
+		admin.Post("/", path.CreateTag)
+		admin.Put("/{tagID}", path.UpdateTag)
+		admin.Delete("/{tagID}", path.DeleteTag)
+		admin.Post("/{tagID}/merge", path.MergeTags)
+		admin.Post("/{tagID}/synonyms", path.AddSynonym)
+		admin.Delete("/synonyms/{synonym}", path.RemoveSynonym)
+
+		admin.Post("/categories", path.CreateCategory)
+		admin.Put("/categories/{categoryID}", path.UpdateCategory)
+		admin.Delete("/categories/{categoryID}", path.DeleteCategory)
+
+		admin.Get("/settings", path.GetSettings)
+		admin.Put("/settings", path.UpdateSettings)
+	})
+	return r
+}
+
+// GetTags lists the approved tags, optionally only those in ?category= and its subcategories
+func (path *Path) GetTags(w http.ResponseWriter, r *http.Request) {
+	response.WriteJson(w, path.service.GetTags(r.Context(), r.URL.Query().Get("category")))
+}
+
+func (path *Path) GetCategories(w http.ResponseWriter, r *http.Request) {
+	response.WriteJson(w, path.service.GetCategories(r.Context()))
+}
+
+// Autocomplete completes ?q= to approved tags, most used first, up to ?limit=
+func (path *Path) Autocomplete(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	response.WriteJson(w, path.service.Autocomplete(query.Get("q"), query.Get("limit")))
+}
+
+// SuggestTags suggests tags for a draft opportunity's title and description
+func (path *Path) SuggestTags(w http.ResponseWriter, r *http.Request) {
+	var req models.SuggestTagsRequest
+	if !decode(w, r, &req) {
+		return
+	}
+	response.WriteJson(w, path.service.SuggestTags(req))
+}
+
+// GetPendingTags lists the tags waiting for moderation
+func (path *Path) GetPendingTags(w http.ResponseWriter, r *http.Request) {
+	response.WriteJson(w, path.service.GetPendingTags(r.Context()))
+}
+
+func (path *Path) CreateTag(w http.ResponseWriter, r *http.Request) {
+	var req models.CreateTagRequest
+	if !decode(w, r, &req) {
+		return
+	}
+	response.WriteJson(w, path.service.CreateTag(r.Context(), req))
+}
+
+func (path *Path) UpdateTag(w http.ResponseWriter, r *http.Request) {
+	var req models.UpdateTagRequest
+	if !decode(w, r, &req) {
+		return
+	}
+	response.WriteJson(w, path.service.UpdateTag(r.Context(), chi.URLParam(r, "tagID"), req))
+}
+
+func (path *Path) DeleteTag(w http.ResponseWriter, r *http.Request) {
+	response.WriteJson(w, path.service.DeleteTag(r.Context(), chi.URLParam(r, "tagID")))
+}
+
+// MergeTags merges the tag in the path into the tag in the body's "into"
+func (path *Path) MergeTags(w http.ResponseWriter, r *http.Request) {
+	var req models.MergeTagsRequest
+	if !decode(w, r, &req) {
+		return
+	}
+	response.WriteJson(w, path.service.MergeTags(r.Context(), chi.URLParam(r, "tagID"), req))
+}
+
+func (path *Path) AddSynonym(w http.ResponseWriter, r *http.Request) {
+	var req models.TagSynonymRequest
+	if !decode(w, r, &req) {
+		return
+	}
+	response.WriteJson(w, path.service.AddSynonym(r.Context(), chi.URLParam(r, "tagID"), req))
+}
+
+func (path *Path) RemoveSynonym(w http.ResponseWriter, r *http.Request) {
+	response.WriteJson(w, path.service.RemoveSynonym(r.Context(), chi.URLParam(r, "synonym")))
+}
+
+func (path *Path) CreateCategory(w http.ResponseWriter, r *http.Request) {
+	var req models.TagCategoryRequest
+	if !decode(w, r, &req) {
+		return
+	}
+	response.WriteJson(w, path.service.CreateCategory(r.Context(), req))
+}
+
+func (path *Path) UpdateCategory(w http.ResponseWriter, r *http.Request) {
+	var req models.TagCategoryRequest
+	if !decode(w, r, &req) {
+		return
+	}
+	response.WriteJson(w, path.service.UpdateCategory(r.Context(), chi.URLParam(r, "categoryID"), req))
+}
+
+func (path *Path) DeleteCategory(w http.ResponseWriter, r *http.Request) {
+	response.WriteJson(w, path.service.DeleteCategory(r.Context(), chi.URLParam(r, "categoryID")))
+}
+
+func (path *Path) GetSettings(w http.ResponseWriter, r *http.Request) {
+	response.WriteJson(w, path.service.GetSettings(r.Context()))
+}
+
+// UpdateSettings locks or unlocks the vocabulary with {"vocabularyLocked": true}
+func (path *Path) UpdateSettings(w http.ResponseWriter, r *http.Request) {
+	var req models.TaxonomySettingsModel
+	if !decode(w, r, &req) {
+		return
+	}
+	response.WriteJson(w, path.service.UpdateSettings(r.Context(), req))
+}
+
+func decode(w http.ResponseWriter, r *http.Request, req any) bool {
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		response.WriteJson(w, response.ErrorResponse("Invalid request body"))
+		return false
+	}
+	return true
+}
+
+func Route() pathapi.PathComponent {
+	return &Path{}
+}
@@ -54,7 +54,7 @@ func (path *Path) GetUserByID(w http.ResponseWriter, r *http.Request) {
 	}
 
 	req := &models.GetUserRequest{UserUUID: idStr}
-	result := path.service.GetUser(req)
+	result := path.service.GetUser(r.Context(), req)
 	response.WriteJson(w, result)
 }
 
@@ -67,7 +67,7 @@ func (path *Path) GetUserByUsername(w http.ResponseWriter, r *http.Request) {
 	}
 
 	req := &models.GetUserRequest{Username: username}
-	result := path.service.GetUser(req)
+	result := path.service.GetUser(r.Context(), req)
 	response.WriteJson(w, result)
 }
 
@@ -79,7 +79,7 @@ func (path *Path) GetCurrentUser(writer http.ResponseWriter, request *http.Reque
 	}
 
 	req := &models.GetUserRequest{Username: userInfo.Username}
-	res := path.service.GetUser(req)
+	res := path.service.GetUser(request.Context(), req)
 	response.WriteJson(writer, res)
 }
 
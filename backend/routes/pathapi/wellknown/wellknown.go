@@ -0,0 +1,34 @@
+package wellknown
+
+import (
+	"backend/internal/db/adapters/mysql"
+	"backend/internal/db/adapters/neo4j"
+	"backend/internal/security"
+	response "backend/internal/utils/http"
+	"backend/routes/pathapi"
+	"github.com/go-chi/chi"
+	"net/http"
+)
+
+// Path serves the unversioned /.well-known documents
+type Path struct {
+	router chi.Router
+}
+
+func (path *Path) SetupComponents(_ *mysql.Repository, _ *neo4j.Repository) chi.Router {
+	r := chi.NewRouter()
+	r.Get("/jwks.json", path.GetJWKS)
+	path.router = r
+	return r
+}
+
+// GetJWKS serves the public keys other services can verify our tokens with
+func (path *Path) GetJWKS(writer http.ResponseWriter, request *http.Request) {
+	// Short cache so rotated keys are picked up quickly
+	writer.Header().Set("Cache-Control", "public, max-age=300")
+	response.WriteJson(writer, security.GetKeyring().JSONWebKeySet())
+}
+
+func Route() pathapi.PathComponent {
+	return &Path{}
+}
@@ -11,11 +11,49 @@ CREATE TABLE IF NOT EXISTS UserTable(
 
 INSERT INTO UserTable(uuid, username, email, hashed_pass, salt, role) VALUES ('f52a683e-1896-489b-8c78-d8f03b735e7a', 'admin', 'admin@gmail.com', '$2a$10$8uzZdQR66ZEoWa.iPF7n7ektlX2E.0LG8Fo.QFGz2cNXQ6NZ.L8WK', 'SUkgJGkyqbnHDJV3pwWiTg', 'Admin');
 
+-- SSO identities linked to users
+CREATE TABLE IF NOT EXISTS UserIdentityTable(
+    provider VARCHAR(50) NOT NULL,
+    subject VARCHAR(255) NOT NULL,
+    userUUID VARCHAR(36) NOT NULL,
+    email VARCHAR(255),
+    linkedAt DATETIME DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (provider, subject),
+    UNIQUE (provider, userUUID),
+    FOREIGN KEY (userUUID) REFERENCES UserTable(uuid) ON DELETE CASCADE
+);
+
 CREATE TABLE IF NOT EXISTS TagsTable (
      id INT AUTO_INCREMENT PRIMARY KEY,
      tagName VARCHAR(50) UNIQUE NOT NULL
 );
 
+CREATE TABLE IF NOT EXISTS TagCategoriesTable(
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    name VARCHAR(50) UNIQUE NOT NULL,
+    parentID INT NULL,
+    FOREIGN KEY (parentID) REFERENCES TagCategoriesTable(id) ON DELETE SET NULL
+);
+
+CREATE TABLE IF NOT EXISTS TagDetailsTable(
+    tagID INT NOT NULL PRIMARY KEY,
+    categoryID INT NULL,
+    approved BOOLEAN NOT NULL DEFAULT FALSE,
+    FOREIGN KEY (tagID) REFERENCES TagsTable(id) ON DELETE CASCADE,
+    FOREIGN KEY (categoryID) REFERENCES TagCategoriesTable(id) ON DELETE SET NULL
+);
+
+CREATE TABLE IF NOT EXISTS TagSynonymsTable(
+    synonym VARCHAR(50) NOT NULL PRIMARY KEY,
+    tagID INT NOT NULL,
+    FOREIGN KEY (tagID) REFERENCES TagsTable(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS TaxonomySettingsTable(
+    id INT NOT NULL PRIMARY KEY,
+    vocabularyLocked BOOLEAN NOT NULL DEFAULT FALSE
+);
+
 CREATE TABLE IF NOT EXISTS UserTagsLiked(
     uuid VARCHAR(36),
     tagID int,
@@ -54,6 +92,74 @@ CREATE TABLE IF NOT EXISTS StudentInfoTable(
 );
 
 
+-- Organisations. Recruiters are members with a role, opportunities belong to an organisation
+CREATE TABLE IF NOT EXISTS OrganisationTable(
+    uuid VARCHAR(36) PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,
+    description TEXT,
+    logoURL TEXT,
+    website VARCHAR(255),
+    verificationStatus ENUM('pending', 'verified', 'rejected') NOT NULL DEFAULT 'pending',
+    createdAt DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS OrganisationMembersTable(
+    organisationUUID VARCHAR(36) NOT NULL,
+    userUUID VARCHAR(36) NOT NULL,
+    role ENUM('viewer', 'editor', 'owner') NOT NULL,
+    joinedAt DATETIME DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (organisationUUID, userUUID),
+    FOREIGN KEY (organisationUUID) REFERENCES OrganisationTable(uuid) ON DELETE CASCADE,
+    FOREIGN KEY (userUUID) REFERENCES UserTable(uuid) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS OrganisationInvitesTable(
+    uuid VARCHAR(36) PRIMARY KEY,
+    organisationUUID VARCHAR(36) NOT NULL,
+    email VARCHAR(255) NOT NULL,
+    role ENUM('viewer', 'editor', 'owner') NOT NULL,
+    invitedByUUID VARCHAR(36) NOT NULL,
+    tokenHash VARCHAR(64) NOT NULL UNIQUE,
+    expiresAt DATETIME NOT NULL,
+    acceptedAt DATETIME NULL,
+    FOREIGN KEY (organisationUUID) REFERENCES OrganisationTable(uuid) ON DELETE CASCADE,
+    FOREIGN KEY (invitedByUUID) REFERENCES UserTable(uuid) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS OrganisationFollowersTable(
+    organisationUUID VARCHAR(36) NOT NULL,
+    userUUID VARCHAR(36) NOT NULL,
+    notify BOOL NOT NULL DEFAULT FALSE,
+    followedAt DATETIME DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (organisationUUID, userUUID),
+    FOREIGN KEY (organisationUUID) REFERENCES OrganisationTable(uuid) ON DELETE CASCADE,
+    FOREIGN KEY (userUUID) REFERENCES UserTable(uuid) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS OrganisationReviewsTable(
+    organisationUUID VARCHAR(36) NOT NULL,
+    userUUID VARCHAR(36) NOT NULL,
+    rating TINYINT NOT NULL,
+    comment TEXT,
+    createdAt DATETIME DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    PRIMARY KEY (organisationUUID, userUUID),
+    FOREIGN KEY (organisationUUID) REFERENCES OrganisationTable(uuid) ON DELETE CASCADE,
+    FOREIGN KEY (userUUID) REFERENCES UserTable(uuid) ON DELETE CASCADE
+);
+
+-- Notifications
+CREATE TABLE IF NOT EXISTS NotificationsTable(
+    uuid VARCHAR(36) PRIMARY KEY,
+    userUUID VARCHAR(36) NOT NULL,
+    notificationType VARCHAR(50) NOT NULL,
+    message TEXT NOT NULL,
+    referenceUUID VARCHAR(36) NULL,
+    createdAt DATETIME DEFAULT CURRENT_TIMESTAMP,
+    readAt DATETIME NULL,
+    UNIQUE (userUUID, notificationType, referenceUUID),
+    FOREIGN KEY (userUUID) REFERENCES UserTable(uuid) ON DELETE CASCADE
+);
+
 -- Opportunity stuff
 CREATE TABLE IF NOT EXISTS OpportunitiesTable (
     id INT AUTO_INCREMENT PRIMARY KEY,
@@ -67,10 +173,81 @@ CREATE TABLE IF NOT EXISTS OpportunitiesTable (
     createdAt DATETIME DEFAULT CURRENT_TIMESTAMP,
     updatedAt DATETIME DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
     approved BOOL DEFAULT FALSE,
-    FOREIGN KEY (postedByUUID) REFERENCES UserTable(uuid) ON DELETE CASCADE
+    organisationUUID VARCHAR(36) NULL,
+    latitude DOUBLE NULL,
+    longitude DOUBLE NULL,
+    addressLine VARCHAR(255) NULL,
+    city VARCHAR(100) NULL,
+    region VARCHAR(100) NULL,
+    postcode VARCHAR(20) NULL,
+    country CHAR(2) NULL,
+    isOnline BOOL NOT NULL DEFAULT FALSE,
+    coordinates POINT NOT NULL SRID 4326 DEFAULT (ST_SRID(POINT(0, 0), 4326)),
+    startsAt DATETIME NULL,
+    endsAt DATETIME NULL,
+    timeZone VARCHAR(64) NOT NULL DEFAULT 'UTC',
+    recurrence VARCHAR(255) NULL,
+    deadline DATETIME NULL,
+    capacity INT NULL,
+    expiresAt DATETIME NULL,
+    closedAt DATETIME NULL,
+    sequence INT NOT NULL DEFAULT 0,
+    draft BOOL NOT NULL DEFAULT FALSE,
+    FOREIGN KEY (postedByUUID) REFERENCES UserTable(uuid) ON DELETE CASCADE,
+    CONSTRAINT fk_opportunity_organisation FOREIGN KEY (organisationUUID) REFERENCES OrganisationTable(uuid) ON DELETE CASCADE
 );
 
+CREATE TABLE IF NOT EXISTS OpportunityRevisionsTable(
+    opportunityUUID VARCHAR(36) NOT NULL,
+    revision INT NOT NULL,
+    authorUUID VARCHAR(36) NOT NULL,
+    createdAt DATETIME NOT NULL,
+    rolledBackFrom INT NULL,
+    snapshot JSON NOT NULL,
+    PRIMARY KEY (opportunityUUID, revision),
+    FOREIGN KEY (opportunityUUID) REFERENCES OpportunitiesTable(uuid) ON DELETE CASCADE
+);
 
+CREATE TABLE IF NOT EXISTS OpportunityExternalRefsTable(
+    ownerUUID VARCHAR(36) NOT NULL,
+    externalRef VARCHAR(100) NOT NULL,
+    opportunityUUID VARCHAR(36) NOT NULL UNIQUE,
+    PRIMARY KEY (ownerUUID, externalRef),
+    FOREIGN KEY (opportunityUUID) REFERENCES OpportunitiesTable(uuid) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS OpportunityApplicationsTable(
+    opportunityUUID VARCHAR(36) NOT NULL,
+    userUUID VARCHAR(36) NOT NULL,
+    status ENUM('confirmed', 'waitlisted', 'cancelled') NOT NULL,
+    appliedAt DATETIME(6) NOT NULL,
+    PRIMARY KEY (opportunityUUID, userUUID),
+    FOREIGN KEY (opportunityUUID) REFERENCES OpportunitiesTable(uuid) ON DELETE CASCADE,
+    FOREIGN KEY (userUUID) REFERENCES UserTable(uuid) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS CalendarFeedTokensTable(
+    userUUID VARCHAR(36) NOT NULL PRIMARY KEY,
+    tokenHash VARCHAR(64) NOT NULL UNIQUE,
+    createdAt DATETIME DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (userUUID) REFERENCES UserTable(uuid) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS CalendarCancellationsTable(
+    userUUID VARCHAR(36) NOT NULL,
+    opportunityUUID VARCHAR(36) NOT NULL,
+    title VARCHAR(100) NOT NULL,
+    location VARCHAR(100),
+    startsAt DATETIME NOT NULL,
+    endsAt DATETIME NULL,
+    timeZone VARCHAR(64) NOT NULL DEFAULT 'UTC',
+    recurrence VARCHAR(255) NULL,
+    sequence INT NOT NULL DEFAULT 0,
+    createdAt DATETIME NOT NULL,
+    cancelledAt DATETIME NOT NULL,
+    PRIMARY KEY (userUUID, opportunityUUID),
+    FOREIGN KEY (userUUID) REFERENCES UserTable(uuid) ON DELETE CASCADE
+);
 
 CREATE TABLE IF NOT EXISTS OpportunityLikesTable (
     userUUID VARCHAR(36),
@@ -103,5 +280,33 @@ CREATE TABLE IF NOT EXISTS OpportunityMediaTable (
     opportunityUUID VARCHAR(36),
     mediaURL TEXT NOT NULL,
     mediaType VARCHAR(50) NOT NULL,
+    mediaUUID VARCHAR(36) NULL,
     FOREIGN KEY (opportunityUUID) REFERENCES OpportunitiesTable(uuid) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS MediaTable(
+    uuid VARCHAR(36) NOT NULL PRIMARY KEY,
+    sha256 CHAR(64) NOT NULL,
+    contentType VARCHAR(100) NOT NULL,
+    mediaType VARCHAR(50) NOT NULL,
+    size BIGINT NOT NULL,
+    uploadedByUUID VARCHAR(36) NOT NULL,
+    createdAt DATETIME DEFAULT CURRENT_TIMESTAMP,
+    orientation TINYINT NOT NULL DEFAULT 1
+);
+
+CREATE TABLE IF NOT EXISTS MediaVariantsTable(
+    mediaUUID VARCHAR(36) NOT NULL,
+    variant VARCHAR(20) NOT NULL,
+    status VARCHAR(10) NOT NULL,
+    sha256 CHAR(64) NULL,
+    contentType VARCHAR(100) NULL,
+    width INT NULL,
+    height INT NULL,
+    size BIGINT NULL,
+    attempts INT NOT NULL DEFAULT 0,
+    error VARCHAR(255) NULL,
+    updatedAt DATETIME DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (mediaUUID, variant),
+    FOREIGN KEY (mediaUUID) REFERENCES MediaTable(uuid) ON DELETE CASCADE
 );
\ No newline at end of file
@@ -12,6 +12,7 @@ import {
 } from 'react-native';
 import axios from 'axios';
 import * as SecureStore from 'expo-secure-store';
+import { variantURL } from '../../utils/media';
 
 const { width, height } = Dimensions.get('window');
 const CARD_WIDTH = width * 0.9;
@@ -59,7 +60,7 @@ const GreenCard = memo(({ item, onLike, onDislike, expanded, setExpanded }) => {
     >
       <Animated.View style={[styles.card, { height: animatedHeight }]}>
         <Image
-          source={{ uri: item.media[item.media.length - 1].URL }}
+          source={{ uri: variantURL(item.media[item.media.length - 1], 'feed') }}
           style={styles.cardImage}
           resizeMode="cover"
         />
@@ -145,7 +146,7 @@ const VolunteerPage = () => {
 
       const enhancedData = response.data.data.map(item => ({
         ...item,
-        imageUrl: item.media[item.media.length - 1]?.URL || '', 
+        imageUrl: variantURL(item.media[item.media.length - 1], 'feed') || '', 
       }));
 
       return enhancedData;
@@ -14,6 +14,7 @@ import * as SecureStore from 'expo-secure-store';
 import AsyncStorage from '@react-native-async-storage/async-storage';
 import { Ionicons } from '@expo/vector-icons';
 import config from '../../utils/config';
+import { mediaURL, variantURL } from '../../utils/media';
 export default function MatchesScreen() {
   const [loading, setLoading] = useState(true);
   const [matches, setMatches] = useState([]);
@@ -103,7 +104,7 @@ export default function MatchesScreen() {
               return {
                 ...student,
 
-                profilePic: studentData.data.profilePic
+                profilePic: mediaURL(studentData.data.profilePicURL)
               };
 
             } catch (error) {
@@ -181,7 +182,7 @@ export default function MatchesScreen() {
                 if (mediaItem) {
                   return {
                     ...recruiter,
-                    profilePic: mediaItem.URL
+                    profilePic: variantURL(mediaItem, 'thumbnail')
                   };
                 }
               }
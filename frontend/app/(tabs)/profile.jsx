@@ -17,10 +17,12 @@ import { useRouter } from 'expo-router';
 import * as ImagePicker from 'expo-image-picker';
 import AsyncStorage from '@react-native-async-storage/async-storage';
 import config from '../../utils/config';
+import { mediaURL, uploadMedia } from '../../utils/media';
 
 export default function ProfilePage() {
   const [loading, setLoading] = useState(true);
   const [profilePic, setProfilePic] = useState(null);
+  const [profilePicURL, setProfilePicURL] = useState(null);
   const [description, setDescription] = useState('');
   const [favoriteTags, setFavoriteTags] = useState([]);
   const [dislikedTags, setDislikedTags] = useState([]);
@@ -74,6 +76,7 @@ export default function ProfilePage() {
         setStudentEmail(data.studentEmail || '');
         setDescription(data.description || '');
         setProfilePic(data.profilePic || null);
+        setProfilePicURL(mediaURL(data.profilePicURL));
         setFavoriteTags(data.tagsLiked || []);
         setDislikedTags(data.tagsDisliked || []);
       } else {
@@ -95,9 +98,10 @@ export default function ProfilePage() {
     });
 
     if (!result.canceled) {
-      const uploadedUrl = await uploadProfilePic(result.assets[0].uri);
-      if (uploadedUrl) {
-        setProfilePic(uploadedUrl);
+      const uploaded = await uploadMedia(result.assets[0].uri, 'image/jpeg', 'profile.jpg');
+      if (uploaded) {
+        setProfilePic(uploaded.id);
+        setProfilePicURL(mediaURL(uploaded.URL));
       }
     }
   };
@@ -201,9 +205,9 @@ export default function ProfilePage() {
           <Text style={styles.title}>Edit Profile</Text>
           
           <View style={styles.profileImageContainer}>
-            {profilePic ? (
+            {profilePicURL ? (
               <Image
-                source={{ uri: profilePic }}
+                source={{ uri: profilePicURL }}
                 style={styles.profileImage}
               />
             ) : (
@@ -333,31 +337,6 @@ export default function ProfilePage() {
   );
 }
 
-const uploadProfilePic = async (imageUri) => {
-  const formData = new FormData();
-  const token = await AsyncStorage.getItem('userToken');
-
-  formData.append('file', {
-    uri: imageUri,
-    type: 'image/jpeg',
-    name: 'profile.jpg', //Only using jpeg for now
-  });
-  formData.append('upload_preset', 'profile-upload'); 
-  
-  try {
-    const res = await fetch('https://api.cloudinary.com/v1_1/dnz0ljksa/image/upload', {
-      method: 'POST',
-      body: formData,
-    });
-
-    const data = await res.json();
-    return data.secure_url;
-  } catch (err) {
-    console.error('Upload failed', err);
-    return null;
-  }
-};
-
 const styles = StyleSheet.create({
   container: {
     flex: 1,
@@ -14,6 +14,7 @@ import axios from 'axios';
 import * as SecureStore from 'expo-secure-store';
 import AsyncStorage from '@react-native-async-storage/async-storage';
 import config from '../../utils/config';
+import { mediaURL } from '../../utils/media';
 const { width, height } = Dimensions.get('window');
 const CARD_WIDTH = width * 0.9;
 const CARD_HEIGHT = height * 0.75; 
@@ -59,7 +60,7 @@ const StudentCard = memo(({ student, onLike, onDislike, expanded, setExpanded })
     >
       <Animated.View style={[styles.card, { height: animatedHeight }]}>
         <Image
-          source={{ uri: student.profilePic || 'https://via.placeholder.com/300' }}
+          source={{ uri: mediaURL(student.profilePicURL) || 'https://via.placeholder.com/300' }}
           style={styles.cardImage}
           resizeMode="cover"
         />
@@ -0,0 +1,39 @@
+import AsyncStorage from '@react-native-async-storage/async-storage';
+import config from './config';
+
+// Signed media URLs are relative unless the backend has PUBLIC_URL set
+export const mediaURL = (url) => {
+  if (!url) return null;
+  return url.startsWith('/') ? `${config.apiURL}${url}` : url;
+};
+
+// URL of a resized copy of an image ('thumbnail', 'feed' or 'large'), the original until the copy is ready
+export const variantURL = (media, variant) => {
+  if (!media) return null;
+  return mediaURL(media.variants?.[variant]?.URL || media.URL);
+};
+
+// Uploads a file to the backend, returning the stored media ({ id, URL, ... }) or null if it failed
+export const uploadMedia = async (uri, type, name) => {
+  const token = await AsyncStorage.getItem('token');
+  const formData = new FormData();
+  formData.append('file', { uri, type, name });
+
+  try {
+    const res = await fetch(`${config.apiURL}/api/v1/media`, {
+      method: 'POST',
+      headers: { 'Authorization': `Bearer ${token}` },
+      body: formData,
+    });
+
+    const result = await res.json();
+    if (!result.success) {
+      console.error('Upload failed', result.message);
+      return null;
+    }
+    return result.data;
+  } catch (err) {
+    console.error('Upload failed', err);
+    return null;
+  }
+};